@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// The backfill tool sets the raw event XDR of events indexed before it was stored, by re-reading their ledgers from
+// the same ledger backend configuration as the indexer. Without a range, it backfills every ledger with events
+// missing their XDR.
+//
+//	backfill
+//	backfill -from 1170134 -to 1170137
+//
+// It only sets missing event XDR, so it can safely run alongside the indexer and be restarted.
+func main() {
+	from := flag.Uint("from", 0, "first ledger to backfill, defaults to the first ledger with events missing their XDR")
+	to := flag.Uint("to", 0, "last ledger to backfill, defaults to -from")
+	flag.Parse()
+	if *to == 0 {
+		*to = *from
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	config, err := indexer.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "err", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(config.Log.NewLogger(os.Stderr))
+	governor.SetLogger(slog.Default())
+	governor.SetFieldLimits(config.FieldLimits())
+	governor.SetParticipationThreshold(config.ParticipationThreshold())
+
+	store, err := db.Open(ctx, config.DB.DBConfig())
+	if err != nil {
+		slog.Error("Failed to connect to database", "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	if err := db.RunMigrations(store.DB()); err != nil {
+		slog.Error("Database migration failed", "err", err)
+		os.Exit(1)
+	}
+
+	updated, err := indexer.BackfillEventXdr(ctx, store, config, uint32(*from), uint32(*to))
+	if err != nil {
+		slog.Error("Backfill failed", "err", err, "updated", updated)
+		store.Close()
+		os.Exit(1)
+	}
+	slog.Info("Backfill complete.", "updated", updated)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/script3/soroban-governor-backend/internal/api"
+	"github.com/script3/soroban-governor-backend/internal/config"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// The combined governor service runs the indexer and API in a single process, sharing one database connection pool.
+// This is intended for small deployments using sqlite, including in-memory sqlite.
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Starting governor service...")
+
+	slog.Info("Loading config...")
+	// Both services read the same environment variables, so a single config file configures both
+	config.LoadFile("./config/governor.cfg")
+	indexerConfig, err := indexer.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load indexer config", "err", err)
+		os.Exit(1)
+	}
+	apiConfig, err := api.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load api config", "err", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(indexerConfig.Log.NewLogger(os.Stderr))
+	governor.SetLogger(slog.Default())
+	governor.SetFieldLimits(indexerConfig.FieldLimits())
+	governor.SetParticipationThreshold(indexerConfig.ParticipationThreshold())
+	slog.Info("Config loaded.", "db_type", indexerConfig.DB.Type, "ledger_backend", indexerConfig.LedgerBackendType, "port", apiConfig.APIPort)
+
+	// Open a single database for both services. Unlike the standalone indexer, the pool is not limited to a
+	// single connection for sqlite, so API reads can run alongside indexer writes.
+	slog.Info("Setting up database...")
+	store, err := db.Open(ctx, indexerConfig.DB.DBConfig())
+	if err != nil {
+		slog.Error("Failed to connect to database", "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	// Apply any required database migrations
+	if err := db.RunMigrations(store.DB()); err != nil {
+		slog.Error("Database migration failed", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("Database setup complete.")
+
+	// If either service stops, stop the other
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() {
+		errs <- indexer.Run(ctx, store, indexerConfig)
+		cancel()
+	}()
+	go func() {
+		errs <- api.Serve(ctx, store, apiConfig)
+		cancel()
+	}()
+
+	failed := false
+	for range 2 {
+		if err := <-errs; err != nil {
+			slog.Error("Service failed", "err", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		store.Close()
+		os.Exit(1)
+	}
+	slog.Info("Governor service stopped.")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// govtool runs maintenance commands against the database configured for the indexer.
+//
+//	govtool export -contract C... -out events.jsonl
+//	govtool import -in events.jsonl -replay
+//	govtool replay -contract C... -before-ledger N
+//	govtool verify -contract C...
+//	govtool seed -contracts 3 -proposals 12 -seed 1
+//
+// export writes the history table as JSON lines, and import inserts them, so events can be moved between sqlite and
+// postgres, or shared as a reproducible dataset. Events that already exist are skipped on import.
+//
+// replay rebuilds the proposals, votes, and delegations of a contract from its latest snapshot before -before-ledger,
+// or its full history with -full, after a bug affecting the indexed data is fixed.
+//
+// verify compares the indexed proposals of a contract against the governor's storage, read from RPC_URL, and writes
+// a JSON report of any mismatches to stdout. It exits with status 1 if a mismatch is found, so it can run in CI. With
+// -record-gaps, the ledgers of missed on-chain changes are recorded as coverage gaps.
+//
+// seed fills an empty database with generated contracts, proposals, and votes, for developing against the API.
+func main() {
+	commands := map[string]func(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error{
+		"export": export,
+		"import": importEvents,
+		"replay": replay,
+		"verify": verify,
+		"seed":   seed,
+	}
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: govtool <export|import|replay|verify|seed> [flags]")
+		os.Exit(2)
+	}
+	command := commands[os.Args[1]]
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	config, err := indexer.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "err", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(config.Log.NewLogger(os.Stderr))
+	governor.SetLogger(slog.Default())
+	governor.SetFieldLimits(config.FieldLimits())
+	governor.SetParticipationThreshold(config.ParticipationThreshold())
+
+	store, err := db.Open(ctx, config.DB.DBConfig())
+	if err != nil {
+		slog.Error("Failed to connect to database", "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	if err := db.RunMigrations(store.DB()); err != nil {
+		slog.Error("Database migration failed", "err", err)
+		os.Exit(1)
+	}
+
+	if err := command(ctx, store, config, os.Args[2:]); err != nil {
+		slog.Error("Command failed", "command", os.Args[1], "err", err)
+		store.Close()
+		os.Exit(1)
+	}
+}
+
+// export writes the history table to -out, or stdout
+func export(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	contractId := flags.String("contract", "", "contract to export the events of, defaults to every contract")
+	out := flags.String("out", "", "file to write events to, defaults to stdout")
+	flags.Parse(args)
+
+	file := os.Stdout
+	if *out != "" {
+		var err error
+		if file, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	start := time.Now()
+	exported, err := indexer.ExportEvents(ctx, store, *contractId, file)
+	if err != nil {
+		return fmt.Errorf("export failed after %d events: %w", exported, err)
+	}
+	if *out != "" {
+		// the file is only complete once closed
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	slog.Info("Export complete.", "contract", *contractId, "events", exported, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// importEvents inserts the events in -in, or stdin, and optionally reindexes the imported contracts
+func importEvents(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "", "file to read events from, defaults to stdin")
+	replay := flags.Bool("replay", false, "reindex the imported contracts, rebuilding their proposals, votes, and delegations")
+	flags.Parse(args)
+
+	r := io.Reader(os.Stdin)
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	start := time.Now()
+	stats, err := indexer.ImportEvents(ctx, store, r)
+	if err != nil {
+		return fmt.Errorf("import failed after %d events: %w", stats.Events, err)
+	}
+	slog.Info("Import complete.", "events", stats.Events, "contracts", len(stats.Contracts), "duration", time.Since(start).Round(time.Millisecond))
+	if !*replay {
+		return nil
+	}
+
+	idx := indexer.NewIndexer(store)
+	for _, contractId := range stats.Contracts {
+		start := time.Now()
+		if err := idx.ReindexContract(ctx, contractId, nil); err != nil {
+			return err
+		}
+		slog.Info("Replay complete.", "contract", contractId, "duration", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// replay reindexes -contract from its latest snapshot before -before-ledger, or its full history with -full
+func replay(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	contractId := flags.String("contract", "", "contract to reindex")
+	beforeLedger := flags.Uint("before-ledger", 0, "replay from the latest snapshot before this ledger, such as the first ledger affected by a bug, or the latest snapshot if 0")
+	full := flags.Bool("full", false, "replay the full history instead of starting from a snapshot")
+	flags.Parse(args)
+	if *contractId == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	idx := indexer.NewIndexer(store)
+	var err error
+	if *full {
+		err = idx.ReindexContract(ctx, *contractId, nil)
+	} else {
+		err = idx.ReindexContractFromSnapshot(ctx, *contractId, uint32(*beforeLedger), nil)
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Replay complete.", "contract", *contractId, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// verify compares the indexed proposals of -contract against the governor's storage, and fails if they differ
+func verify(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	contractId := flags.String("contract", "", "contract to verify the proposals of")
+	all := flags.Bool("all", false, "also verify finished proposals, whose storage may have been archived")
+	recordGaps := flags.Bool("record-gaps", false, "record the missed ledgers found as coverage gaps")
+	flags.Parse(args)
+	if *contractId == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if len(config.RPCUrls) == 0 {
+		return fmt.Errorf("RPC_URL is not set")
+	}
+
+	start := time.Now()
+	report, err := indexer.VerifyContract(ctx, store, config.RPCUrls[0], *contractId, *all)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if *recordGaps {
+		if err := indexer.RecordCoverageGaps(ctx, store, report.CoverageGaps(), time.Now()); err != nil {
+			return err
+		}
+	}
+	slog.Info("Verify complete.", "contract", *contractId, "checked", report.Checked, "skipped", len(report.Skipped), "mismatches", len(report.Mismatches), "missed_ledgers", len(report.MissedLedgers), "duration", time.Since(start).Round(time.Millisecond))
+	if len(report.Mismatches) > 0 {
+		return fmt.Errorf("found %d mismatches", len(report.Mismatches))
+	}
+	return nil
+}
+
+// seed fills an empty database with generated data
+func seed(ctx context.Context, store *db.Store, config *indexer.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	contracts := flags.Int("contracts", 3, "number of governor contracts to generate")
+	proposals := flags.Int("proposals", 12, "number of proposals to generate for each contract, cycling through every status")
+	seed := flags.Uint64("seed", 1, "seed of the generated data, which is the same for the same seed")
+	ledger := flags.Uint("ledger", 1000000, "latest generated ledger, recorded as the last indexed ledger")
+	flags.Parse(args)
+
+	start := time.Now()
+	stats, err := indexer.Seed(ctx, store, indexer.SeedOptions{
+		Contracts: *contracts,
+		Proposals: *proposals,
+		Seed:      *seed,
+		Ledger:    uint32(*ledger),
+		Now:       start,
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Seed complete.", "contracts", stats.Contracts, "proposals", stats.Proposals, "votes", stats.Votes, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
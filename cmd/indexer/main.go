@@ -2,27 +2,23 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"log/slog"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
 	"github.com/script3/soroban-governor-backend/internal/indexer"
-	"github.com/sirupsen/logrus"
-
-	"github.com/stellar/go-stellar-sdk/ingest"
-	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
-	"github.com/stellar/go-stellar-sdk/network"
-	"github.com/stellar/go-stellar-sdk/support/log"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
 func main() {
-	ctx := context.Background()
-	source := "indexer"
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	slog.Info("Starting indexer service...")
 
@@ -32,132 +28,47 @@ func main() {
 		slog.Error("Failed to load config", "err", err)
 		os.Exit(1)
 	}
-	slog.Info("Config loaded.", "db_type", config.DBType, "ledger_backend", config.LedgerBackendType)
+	slog.SetDefault(config.Log.NewLogger(os.Stderr))
+	governor.SetLogger(slog.Default())
+	governor.SetFieldLimits(config.FieldLimits())
+	governor.SetParticipationThreshold(config.ParticipationThreshold())
+	slog.Info("Config loaded.", "db_type", config.DB.Type, "ledger_backend", config.LedgerBackendType)
 
 	slog.Info("Setting up database...")
-	// Create the database
-	database, err := sql.Open(config.DBType, config.DBConnectionString)
+	dbConfig := config.DB.DBConfig()
+	if config.DB.Type == "sqlite" && dbConfig.MaxOpenConns != 1 {
+		// sqlite only supports a single writer, and the indexer only writes
+		slog.Info("Using sqlite, limiting the indexer to a single database connection")
+		dbConfig.MaxOpenConns = 1
+	}
+	store, err := db.Open(ctx, dbConfig)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Failed to connect to database", "err", err)
+		os.Exit(1)
 	}
-	database.SetMaxOpenConns(config.DBMaxOpenConns)
-	database.SetMaxIdleConns(config.DBMaxIdleConns)
-	database.SetConnMaxLifetime(time.Duration(config.DBConnMaxLifetime) * time.Second)
-	defer database.Close()
+	defer store.Close()
 
 	// Apply any required database migrations
-	if err := db.RunMigrations(database); err != nil {
+	if err := db.RunMigrations(store.DB()); err != nil {
 		slog.Error("Database migration failed", "err", err)
 		os.Exit(1)
 	}
-
-	// Create the store
-	store := db.NewStore(database)
 	slog.Info("Database setup complete.")
 
-	// Get the latest ledger sequence from the RPC server
-	lastLedger, _, err := store.GetStatus(ctx, source)
-	if err != nil {
-		slog.Error("Failed to fetch last processed ledger", "err", err)
-		os.Exit(1)
-	}
-	startSeq := max(lastLedger, config.LedgerBackendStartSeq)
-	var networkPassphrase string
-	if config.Network == "public" {
-		networkPassphrase = network.PublicNetworkPassphrase
-	} else {
-		networkPassphrase = network.TestNetworkPassphrase
-	}
-
-	// Configure the RPC Ledger Backend
-	var backend ledgerbackend.LedgerBackend
-	switch config.LedgerBackendType {
-	case "core":
-		var defaultHistoryUrls []string
-		if config.Network == "public" {
-			defaultHistoryUrls = network.PublicNetworkhistoryArchiveURLs
-		} else {
-			defaultHistoryUrls = network.TestNetworkhistoryArchiveURLs
-		}
-		defaultParams := ledgerbackend.CaptiveCoreTomlParams{
-			NetworkPassphrase:  networkPassphrase,
-			HistoryArchiveURLs: defaultHistoryUrls,
-		}
-		captiveCoreToml, err := ledgerbackend.NewCaptiveCoreTomlFromFile(config.CoreConfigPath, defaultParams)
-		if err != nil {
-			slog.Error("Failed to load captive core toml", "err", err)
-			os.Exit(1)
-		}
-		captiveCoreConfig := ledgerbackend.CaptiveCoreConfig{
-			BinaryPath:         config.CoreBinaryPath,
-			NetworkPassphrase:  networkPassphrase,
-			HistoryArchiveURLs: defaultHistoryUrls,
-			Toml:               captiveCoreToml,
-		}
-		lg := log.New()
-		level, parseErr := logrus.ParseLevel(config.CoreLogLevel)
-		if parseErr != nil {
-			slog.Warn("Invalid CORE_LOG_LEVEL, defaulting to warn", "value", config.CoreLogLevel, "err", parseErr)
-			level = logrus.WarnLevel
-		}
-		lg.SetLevel(level)
-		captiveCoreConfig.Log = lg
-		backend, err = ledgerbackend.NewCaptive(captiveCoreConfig)
-		if err != nil {
-			slog.Error("Failed to create captive core backend", "err", err)
-			os.Exit(1)
-		}
-		defer backend.Close()
-	case "rpc":
-		backend = ledgerbackend.NewRPCLedgerBackend(ledgerbackend.RPCLedgerBackendOptions{
-			RPCServerURL: config.RPCUrl,
-		})
-		defer backend.Close()
-	default:
-		slog.Error("Unsupported LEDGER_BACKEND_TYPE", "type", config.LedgerBackendType)
-		os.Exit(1)
+	if config.MetricsPort != "" {
+		go func() {
+			// metrics are best effort, so a failed metrics server doesn't stop the indexer
+			if err := metrics.Serve(ctx, config.MetricsPort); err != nil {
+				slog.Error("Metrics server failed", "err", err)
+			}
+		}()
 	}
 
-	slog.Info("Setting up ledger ingestion service starting", "ledger", startSeq)
-	if err := backend.PrepareRange(ctx, ledgerbackend.UnboundedRange(startSeq)); err != nil {
-		slog.Error("Failed to prepare ledger range", "err", err)
+	if err := indexer.Run(ctx, store, config); err != nil {
+		slog.Error("Indexer failed", "err", err)
+		store.Close()
 		os.Exit(1)
 	}
-	slog.Info("Initial ledger range prepared.")
-
-	idx := indexer.NewIndexer(store)
-
-	slog.Info("Setup complete!")
-
-	seq := startSeq
-	for {
-		ledger, err := backend.GetLedger(ctx, seq)
-		if err != nil {
-			slog.Error("No more ledgers or error at sequence.", "ledger", seq, "err", err)
-			break
-		}
-		startTime := time.Now()
-
-		txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(networkPassphrase, ledger)
-		if err != nil {
-			slog.Error("Failed to create transaction reader", "ledger", seq, "err", err)
-			os.Exit(1)
-		}
-
-		scannedTxs, err := idx.ApplyLedger(ctx, txReader, ledger.LedgerSequence(), ledger.LedgerCloseTime())
-		if err != nil {
-			slog.Error("Failed to apply ledger", "ledger", seq, "err", err)
-		}
-
-		err = store.UpsertStatus(ctx, source, ledger.LedgerSequence(), ledger.LedgerCloseTime())
-		if err != nil {
-			slog.Error("Failed to update last processed ledger", "ledger", seq, "err", err)
-		}
-
-		elapsed := time.Since(startTime)
-		slog.Info("Ledger processed.", "ledger", ledger.LedgerSequence(), "txs", scannedTxs, "ms", elapsed.Milliseconds())
-		seq++
-	}
 
 	slog.Info("Indexer service stopped.")
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+)
+
+// The inspect tool parses governor events from a range of ledgers and prints them to stdout as JSON, one event per
+// line. It uses the same ledger backend configuration and parser as the indexer, but never touches the database.
+//
+//	inspect -from 1170134 -to 1170137
+//
+// With -record, ledgers containing governor events are saved as fixtures for the indexer tests.
+func main() {
+	from := flag.Uint("from", 0, "first ledger to inspect")
+	to := flag.Uint("to", 0, "last ledger to inspect, defaults to -from")
+	record := flag.String("record", "", "directory to save ledgers containing governor events to, as test fixtures")
+	flag.Parse()
+	if *from == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *to == 0 {
+		*to = *from
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	config, err := indexer.LoadConfig()
+	if err != nil {
+		slog.Error("Failed to load config", "err", err)
+		os.Exit(1)
+	}
+	// stdout is reserved for events
+	slog.SetDefault(config.Log.NewLogger(os.Stderr))
+	governor.SetLogger(slog.Default())
+	governor.SetFieldLimits(config.FieldLimits())
+	governor.SetParticipationThreshold(config.ParticipationThreshold())
+
+	stats, err := indexer.Inspect(ctx, config, uint32(*from), uint32(*to), os.Stdout, *record)
+	if err != nil {
+		slog.Error("Inspect failed", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("Inspect complete.", append([]any{"from", *from, "to", *to}, stats.LogAttrs()...)...)
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+// VOTES_CONTRACT_READ_TIMEOUT is the maximum duration of a call to the RPC server for a governor's votes contract
+const VOTES_CONTRACT_READ_TIMEOUT = 10 * time.Second
+
+// handleReindexContract starts a background job that rebuilds a contract's proposals and votes from its history.
+// With ?snapshot=true, only the events after the latest snapshot before ?before_ledger, if set, are replayed. Only one
+// reindex job runs at a time, as the status table flags a single contract as rebuilding, so a request made while
+// another is running is refused with a 409.
+//
+// If RPC_URL is set, the votes contract of a governor is then read from its contract instance, as it isn't in the
+// history, so delegations are indexed again after the governor's data was deleted.
+func (h *Handler) handleReindexContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	fromSnapshot := r.URL.Query().Get("snapshot") == "true"
+	var beforeLedger uint64
+	if beforeLedgerStr := r.URL.Query().Get("before_ledger"); beforeLedgerStr != "" {
+		var err error
+		beforeLedger, err = strconv.ParseUint(beforeLedgerStr, 10, 32)
+		if err != nil || !fromSnapshot {
+			respondError(w, http.StatusBadRequest, "invalid before_ledger")
+			return
+		}
+	}
+
+	job, ok := h.jobs.startExclusive("reindex", contractId)
+	if !ok {
+		respondError(w, http.StatusConflict, fmt.Sprintf("reindex job %s is already running for contract %s", job.Id, job.ContractId))
+		return
+	}
+	slog.Info("Starting reindex job", "job", job.Id, "contract", contractId, "snapshot", fromSnapshot, "before_ledger", beforeLedger)
+
+	// the job outlives the request, so it can't use the request context
+	go func() {
+		onProgress := func(replayed int, total int) {
+			h.jobs.progress(job.Id, replayed, total)
+		}
+		var err error
+		if fromSnapshot {
+			err = h.indexer.ReindexContractFromSnapshot(context.Background(), contractId, uint32(beforeLedger), onProgress)
+		} else {
+			err = h.indexer.ReindexContract(context.Background(), contractId, onProgress)
+		}
+		if err == nil && h.ledgerEntries != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), VOTES_CONTRACT_READ_TIMEOUT)
+			var votesId string
+			votesId, err = h.indexer.RebuildVotesContract(ctx, h.ledgerEntries, contractId)
+			cancel()
+			if votesId != "" {
+				slog.Info("Rebuilt votes contract", "job", job.Id, "contract", contractId, "votes_contract", votesId)
+			}
+		}
+		if err != nil {
+			slog.Error("Reindex job failed", "job", job.Id, "contract", contractId, "err", err)
+		}
+		h.jobs.finish(job.Id, err)
+	}()
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+// handleGetJob returns the current state of an admin job
+func (h *Handler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.get(r.PathValue("jobId"))
+	if !ok {
+		respondError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// DeleteContractResponse is the response body for a contract data deletion
+type DeleteContractResponse struct {
+	ContractId string           `json:"contract_id"`
+	Deleted    map[string]int64 `json:"deleted"`
+	Blocked    bool             `json:"blocked"`
+}
+
+// handleDeleteContract deletes all indexed data for a contract, and optionally adds it to the blocklist
+// so the indexer ignores any future events from it
+func (h *Handler) handleDeleteContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	block := r.URL.Query().Get("block") == "true"
+
+	if block {
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "deleted by admin"
+		}
+		// block before deleting so the indexer can't re-insert data in between
+		err := h.store.BlockContract(r.Context(), contractId, reason, time.Now().Unix())
+		if err != nil {
+			slog.Error("Failed to block contract", "contract", contractId, "error", err)
+			respondError(w, storeErrorStatus(err), "failed to block contract")
+			return
+		}
+		h.invalidateBlocklist()
+	}
+
+	deleted, err := h.store.DeleteContractData(r.Context(), contractId)
+	if err != nil {
+		slog.Error("Failed to delete contract data", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to delete contract data")
+		return
+	}
+	slog.Info("Deleted contract data", "contract", contractId, "deleted", deleted, "blocked", block)
+
+	respondJSON(w, http.StatusOK, DeleteContractResponse{
+		ContractId: contractId,
+		Deleted:    deleted,
+		Blocked:    block,
+	})
+}
+
+// BlockedContractResponse is a contract on the blocklist
+type BlockedContractResponse struct {
+	ContractId string `json:"contract_id"`
+	Reason     string `json:"reason"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// handleGetBlocklist lists the blocked contracts
+func (h *Handler) handleGetBlocklist(w http.ResponseWriter, r *http.Request) {
+	blocked, err := h.store.GetBlockedContracts(r.Context())
+	if err != nil {
+		slog.Error("Failed to get blocked contracts", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve blocklist")
+		return
+	}
+	response := make([]BlockedContractResponse, len(blocked))
+	for i, contract := range blocked {
+		response[i] = BlockedContractResponse{ContractId: contract.ContractId, Reason: contract.Reason, CreatedAt: contract.CreatedAt}
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// handleBlockContract adds a contract to the blocklist, with the optional reason query parameter, so the indexer
+// ignores its events and the API refuses requests for it. Its indexed data is kept, so it is served again if the
+// contract is unblocked. Blocking a contract that is already blocked keeps its original reason.
+func (h *Handler) handleBlockContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	if _, err := strkey.Decode(strkey.VersionByteContract, contractId); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "blocked by admin"
+	}
+
+	if err := h.store.BlockContract(r.Context(), contractId, reason, time.Now().Unix()); err != nil {
+		slog.Error("Failed to block contract", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to block contract")
+		return
+	}
+	h.invalidateBlocklist()
+	slog.Info("Blocked contract", "contract", contractId, "reason", reason)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUnblockContract removes a contract from the blocklist. Events the indexer skipped while the contract was
+// blocked are not indexed, so the contract should be reindexed from its history if they are needed.
+func (h *Handler) handleUnblockContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	err := h.store.UnblockContract(r.Context(), contractId)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "contract is not blocked")
+		return
+	} else if err != nil {
+		slog.Error("Failed to unblock contract", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to unblock contract")
+		return
+	}
+	h.invalidateBlocklist()
+	slog.Info("Unblocked contract", "contract", contractId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetFailedEvents lists the events from a contract that could not be indexed, oldest first. The optional
+// reason query parameter only lists failed events with that reason, such as unknown_event_type.
+func (h *Handler) handleGetFailedEvents(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	reason := r.URL.Query().Get("reason")
+
+	events, err := h.store.GetFailedEventsByContractId(r.Context(), contractId)
+	if err != nil {
+		slog.Error("Failed to get failed events", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve failed events")
+		return
+	}
+	if reason != "" {
+		events = slices.DeleteFunc(events, func(event *governor.FailedEvent) bool { return event.Reason != reason })
+	}
+	events = truncateList(w, r, events, h.maxListRows)
+
+	respondJSON(w, http.StatusOK, events)
+}
+
+// failedEventFilter returns the failed events selected by the contract_id, reason, and error query parameters.
+// error matches failed events whose error contains it.
+func failedEventFilter(r *http.Request) db.FailedEventFilter {
+	return db.FailedEventFilter{
+		ContractId:    r.URL.Query().Get("contract_id"),
+		Reason:        r.URL.Query().Get("reason"),
+		ErrorContains: r.URL.Query().Get("error"),
+	}
+}
+
+// handleListFailedEvents lists the events that could not be indexed across all contracts, oldest first, as a Page.
+// The optional contract_id, reason, and error query parameters filter the list, and ?unprocessed=true only lists
+// failed events that have not been reprocessed. The cursor of a page is the event id of the last failed event of the
+// previous page.
+func (h *Handler) handleListFailedEvents(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	filter := failedEventFilter(r)
+	filter.Unprocessed = r.URL.Query().Get("unprocessed") == "true"
+
+	// read one more failed event than the limit to know if there is a next page
+	events, err := h.store.GetFailedEvents(r.Context(), filter, cursor, limit+1)
+	if err != nil {
+		slog.Error("Failed to get failed events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve failed events")
+		return
+	}
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = events[len(events)-1].EventId
+	}
+	total, err := h.store.CountFailedEvents(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to count failed events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve failed events")
+		return
+	}
+	if events == nil {
+		events = []*governor.FailedEvent{}
+	}
+
+	respondJSON(w, http.StatusOK, Page[*governor.FailedEvent]{Data: events, Pagination: newPagination(limit, cursor, nextCursor, total)})
+}
+
+// ReprocessFailedEventsResponse is the result of a job reprocessing failed events
+type ReprocessFailedEventsResponse struct {
+	Reprocessed int `json:"reprocessed"`
+	Failed      int `json:"failed"`
+	Skipped     int `json:"skipped"`
+}
+
+// handleReprocessFailedEvents starts a background job that parses the failed events that have not been reprocessed
+// again, and applies those that parse. The optional contract_id, reason, and error query parameters select the failed
+// events to reprocess. The job's result is a ReprocessFailedEventsResponse. Only one reprocess job runs at a time, as
+// concurrent jobs would apply the same failed events, so a request made while another is running is refused with a
+// 409.
+func (h *Handler) handleReprocessFailedEvents(w http.ResponseWriter, r *http.Request) {
+	filter := failedEventFilter(r)
+	filter.Unprocessed = true
+	total, err := h.store.CountFailedEvents(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to count failed events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to reprocess failed events")
+		return
+	}
+
+	job, ok := h.jobs.startExclusive("reprocess", filter.ContractId)
+	if !ok {
+		respondError(w, http.StatusConflict, fmt.Sprintf("reprocess job %s is already running", job.Id))
+		return
+	}
+	slog.Info("Starting reprocess job", "job", job.Id, "filter", filter, "total", total)
+
+	// the job outlives the request, so it can't use the request context
+	go func() {
+		onProgress := func(stats indexer.ReprocessStats) {
+			h.jobs.progress(job.Id, stats.Reprocessed+stats.Failed+stats.Skipped, total)
+			h.jobs.setResult(job.Id, newReprocessFailedEventsResponse(stats))
+		}
+		stats, err := h.indexer.ReprocessFailedEvents(context.Background(), filter, onProgress)
+		h.jobs.setResult(job.Id, newReprocessFailedEventsResponse(stats))
+		if err != nil {
+			slog.Error("Reprocess job failed", "job", job.Id, "filter", filter, "reprocessed", stats.Reprocessed, "failed", stats.Failed, "error", err)
+		}
+		h.jobs.finish(job.Id, err)
+	}()
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+func newReprocessFailedEventsResponse(stats indexer.ReprocessStats) ReprocessFailedEventsResponse {
+	return ReprocessFailedEventsResponse{
+		Reprocessed: stats.Reprocessed,
+		Failed:      stats.Failed,
+		Skipped:     stats.Skipped,
+	}
+}
+
+// IndexerStatus is the last ledger processed by the indexer
+type IndexerStatus struct {
+	Ledger          uint32 `json:"ledger"`
+	LedgerCloseTime int64  `json:"ledger_close_time"`
+	LagSeconds      int64  `json:"lag_seconds"`
+}
+
+// CaptiveCoreStatus is the last status recorded from captive core. Captive core applies ledgers ahead of the
+// indexer while it catches up, so a ledger that advances while the indexer doesn't is warming up, not stuck.
+type CaptiveCoreStatus struct {
+	Ledger          uint32 `json:"ledger"`
+	LedgerCloseTime int64  `json:"ledger_close_time"`
+	State           string `json:"state"`
+	UpdatedAt       int64  `json:"updated_at"`
+	AgeSeconds      int64  `json:"age_seconds"`
+}
+
+// IngestionProgress is the progress of the indexer's current run, recorded as it processes ledgers. The rates are
+// over the last few minutes. TargetLedger is the latest ledger of the network, and is null with PercentComplete and
+// ETASeconds if the indexer doesn't know it. ETASeconds is also null while no ledgers are being processed.
+type IngestionProgress struct {
+	StartLedger      uint32   `json:"start_ledger"`
+	Ledger           uint32   `json:"ledger"`
+	TargetLedger     *uint32  `json:"target_ledger"`
+	Ledgers          int64    `json:"ledgers"`
+	Events           int64    `json:"events"`
+	LedgersPerSecond float64  `json:"ledgers_per_second"`
+	EventsPerSecond  float64  `json:"events_per_second"`
+	PercentComplete  *float64 `json:"percent_complete"`
+	ETASeconds       *int64   `json:"eta_seconds"`
+	StartedAt        int64    `json:"started_at"`
+	UpdatedAt        int64    `json:"updated_at"`
+}
+
+func newIngestionProgress(progress *db.IngestionProgress) *IngestionProgress {
+	response := &IngestionProgress{
+		StartLedger:      progress.StartLedger,
+		Ledger:           progress.Ledger,
+		Ledgers:          progress.Ledgers,
+		Events:           progress.Events,
+		LedgersPerSecond: progress.LedgersPerSecond,
+		EventsPerSecond:  progress.EventsPerSecond,
+		StartedAt:        progress.StartedAt,
+		UpdatedAt:        progress.UpdatedAt,
+	}
+	if percent, ok := progress.PercentComplete(); ok {
+		response.TargetLedger = &progress.TargetLedger
+		response.PercentComplete = &percent
+	}
+	if eta, ok := progress.ETA(); ok {
+		seconds := int64(eta.Round(time.Second).Seconds())
+		response.ETASeconds = &seconds
+	}
+	return response
+}
+
+// AdminStatusResponse is the response body for the indexer status. CaptiveCore is null unless the indexer uses
+// captive core with its HTTP server enabled, and Progress is null until the indexer has recorded its progress.
+type AdminStatusResponse struct {
+	Indexer     IndexerStatus      `json:"indexer"`
+	CaptiveCore *CaptiveCoreStatus `json:"captive_core"`
+	Progress    *IngestionProgress `json:"progress"`
+}
+
+// handleGetStatus returns the progress of the indexer, with its ingestion rate and ETA, and of captive core if the
+// indexer uses it
+func (h *Handler) handleGetStatus(w http.ResponseWriter, r *http.Request) {
+	now := h.indexStatus.now().Unix()
+	ledger, closeTime, err := h.store.GetStatus(r.Context(), indexer.STATUS_SOURCE)
+	if err != nil {
+		slog.Error("Failed to get indexer status", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve indexer status")
+		return
+	}
+	response := AdminStatusResponse{Indexer: IndexerStatus{Ledger: ledger, LedgerCloseTime: closeTime}}
+	if closeTime != 0 {
+		response.Indexer.LagSeconds = now - closeTime
+	}
+
+	core, err := h.store.GetSourceStatus(r.Context(), indexer.CAPTIVE_CORE_STATUS_SOURCE)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.Error("Failed to get captive core status", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve captive core status")
+		return
+	}
+	if core != nil {
+		response.CaptiveCore = &CaptiveCoreStatus{
+			Ledger:          core.LedgerSeq,
+			LedgerCloseTime: core.LedgerCloseTime,
+			State:           core.State,
+			UpdatedAt:       core.UpdatedAt,
+			AgeSeconds:      now - core.UpdatedAt,
+		}
+	}
+
+	progress, err := h.store.GetIngestionProgress(r.Context(), indexer.STATUS_SOURCE)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.Error("Failed to get ingestion progress", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve ingestion progress")
+		return
+	}
+	if progress != nil {
+		response.Progress = newIngestionProgress(progress)
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// IndexerErrorResponse is an error the indexer hit while processing a ledger or event. TxHash is empty for errors of
+// a whole ledger.
+type IndexerErrorResponse struct {
+	Seq       int64  `json:"seq"`
+	Ledger    uint32 `json:"ledger"`
+	TxHash    string `json:"tx_hash"`
+	Error     string `json:"error"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// handleGetIndexerErrors returns the indexer's latest processing errors, newest first, up to the limit query parameter
+func (h *Handler) handleGetIndexerErrors(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	indexerErrors, err := h.store.GetIndexerErrors(r.Context(), limit)
+	if err != nil {
+		slog.Error("Failed to get indexer errors", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve indexer errors")
+		return
+	}
+	response := make([]IndexerErrorResponse, len(indexerErrors))
+	for i, indexerError := range indexerErrors {
+		response[i] = IndexerErrorResponse{
+			Seq:       indexerError.Seq,
+			Ledger:    indexerError.LedgerSeq,
+			TxHash:    indexerError.TxHash,
+			Error:     indexerError.Message,
+			CreatedAt: indexerError.CreatedAt,
+		}
+	}
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// handleGetFailedTxEvents lists the governor events emitted by failed transactions, oldest first, as a Page. They are
+// only recorded when the indexer runs with INDEX_FAILED_TX_EVENTS, and never change proposals or votes. Events of
+// blocked contracts are never listed, and events of contracts that were not reviewed only with
+// ?include_unreviewed=true. The optional contract_id query parameter filters the list. The cursor of a page is the
+// event id of the last event of the previous page.
+func (h *Handler) handleGetFailedTxEvents(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	contractId := r.URL.Query().Get("contract_id")
+	unreviewed := includeUnreviewed(r)
+
+	// read one more event than the limit to know if there is a next page
+	events, err := h.store.GetFailedTxEvents(r.Context(), contractId, unreviewed, cursor, limit+1)
+	if err != nil {
+		slog.Error("Failed to get failed tx events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve failed tx events")
+		return
+	}
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = events[len(events)-1].EventId
+	}
+	total, err := h.store.CountFailedTxEvents(r.Context(), contractId, unreviewed)
+	if err != nil {
+		slog.Error("Failed to count failed tx events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve failed tx events")
+		return
+	}
+	if events == nil {
+		events = []*governor.FailedTxEvent{}
+	}
+
+	respondJSON(w, http.StatusOK, Page[*governor.FailedTxEvent]{Data: events, Pagination: newPagination(limit, cursor, nextCursor, total)})
+}
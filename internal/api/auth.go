@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// keySet is a set of bearer tokens. Tokens are stored hashed, so checking a token takes the same time regardless of
+// which token it matches, or how much of a token it shares.
+type keySet [][sha256.Size]byte
+
+func newKeySet(keys []string) keySet {
+	set := make(keySet, len(keys))
+	for i, key := range keys {
+		set[i] = sha256.Sum256([]byte(key))
+	}
+	return set
+}
+
+// contains returns true if the token is in the set. Every key is compared, so the time taken doesn't depend on which
+// key matched.
+func (set keySet) contains(token string) bool {
+	hash := sha256.Sum256([]byte(token))
+	found := 0
+	for _, key := range set {
+		found |= subtle.ConstantTimeCompare(hash[:], key[:])
+	}
+	return found == 1
+}
+
+// bearerToken returns the bearer token of the request, or false if it has none
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// respondUnauthorized responds that the request needs a valid bearer token
+func respondUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	respondError(w, http.StatusUnauthorized, message)
+}
+
+// requireAuth wraps a handler so every request needs a bearer token from the API keys or the admin tokens. The health
+// and status endpoints and CORS preflight requests are always allowed, so load balancers, uptime monitors, and
+// browsers can reach them.
+func (h *Handler) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || r.URL.Path == "/health" || strings.HasPrefix(r.URL.Path, "/health/") || r.URL.Path == "/status" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok || !(h.apiKeys.contains(token) || h.adminTokens.contains(token)) {
+			respondUnauthorized(w, "invalid or missing api key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin wraps a handler so it is only accessible with an admin bearer token. Requests without a known token are
+// unauthorized, and requests with an API key that isn't an admin token are forbidden.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(h.adminTokens) == 0 {
+			respondError(w, http.StatusForbidden, "admin endpoints are disabled")
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			respondUnauthorized(w, "invalid or missing admin token")
+			return
+		}
+		if h.adminTokens.contains(token) {
+			next(w, r)
+			return
+		}
+		if h.apiKeys.contains(token) {
+			respondError(w, http.StatusForbidden, "api key is not an admin token")
+			return
+		}
+		respondUnauthorized(w, "invalid or missing admin token")
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// rejectBlocked wraps a contract's handler so requests for a blocked contract are refused with a 410, rather than
+// serving the data indexed before it was blocked. Contracts are checked against the cached blocklist, so a contract
+// blocked by another process is refused within the blocklist refresh interval.
+func (h *Handler) rejectBlocked(next http.HandlerFunc) http.HandlerFunc {
+	if h.blocklist == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.hidesBlocked(r)(r.PathValue("contractId")) {
+			respondError(w, http.StatusGone, "contract is blocked")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// hidesBlocked returns a function reporting whether a contract is blocked, so its data is excluded from the response
+// to the request. Nothing is excluded if the handler doesn't cache the blocklist.
+func (h *Handler) hidesBlocked(r *http.Request) func(contractId string) bool {
+	if h.blocklist == nil {
+		return func(string) bool { return false }
+	}
+	if err := h.blocklist.Refresh(r.Context()); err != nil {
+		slog.Warn("Failed to refresh contract blocklist, using the last loaded blocklist", "error", err)
+	}
+	return h.blocklist.Contains
+}
+
+// invalidateBlocklist reloads the cached blocklist on the next request, after the blocklist is changed
+func (h *Handler) invalidateBlocklist() {
+	if h.blocklist != nil {
+		h.blocklist.Invalidate()
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIP returns the IP address of the client that sent a request. If the connection is from a trusted proxy,
+// X-Forwarded-For is walked from the right, the address the nearest proxy appended, and the client is the first
+// address that isn't a trusted proxy. The addresses left of it were sent by the client, and may be spoofed, so they
+// are never used. Otherwise, X-Forwarded-For is ignored and the client is the connection's address.
+//
+// If every address is a trusted proxy, the leftmost is the client. If an entry isn't an IP address, the walk stops at
+// the last trusted address, as nothing past it can be trusted.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	addr, ok := parseForwardedAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(addr, trusted) {
+		return addr.String()
+	}
+
+	// several X-Forwarded-For headers are one list, in the order they were received
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		hopAddr, ok := parseForwardedAddr(hop)
+		if !ok {
+			break
+		}
+		addr = hopAddr
+		if !isTrustedProxy(addr, trusted) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+// parseForwardedAddr parses an IP address, with or without a port, as in a connection's address or an
+// X-Forwarded-For entry. IPv4 addresses mapped to IPv6 are returned as IPv4.
+func parseForwardedAddr(val string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(val); err == nil {
+		val = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(val, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+var (
+	// PROPOSAL_CSV_HEADER is the header row of proposal CSV exports
+	PROPOSAL_CSV_HEADER = []string{"proposal_key", "contract_id", "proposal_id", "proposer", "status", "title", "description", "action", "vote_start", "vote_end", "votes_for", "votes_against", "votes_abstain", "execution_unlock", "execution_tx_hash", "truncated", "created_ledger", "updated_ledger", "updated_event_id", "updated_at", "flagged_low_participation", "action_type", "action_contract_id", "action_function", "source_account", "created_tx_hash"}
+	// VOTE_CSV_HEADER is the header row of vote CSV exports
+	VOTE_CSV_HEADER = []string{"tx_hash", "contract_id", "proposal_id", "voter", "support", "amount", "ledger_seq", "ledger_close_time", "reason"}
+)
+
+// wantsCSV returns true if the request asks for CSV with ?format=csv, or an Accept header of text/csv
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	for accept := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "text/csv" {
+			return true
+		}
+	}
+	return false
+}
+
+// csvStream writes CSV rows to a response as they are produced. The response headers and header row are written
+// with the first row, so an error before any row is written can still be reported with respondError.
+type csvStream struct {
+	w        http.ResponseWriter
+	writer   *csv.Writer
+	filename string
+	header   []string
+	started  bool
+}
+
+func newCSVStream(w http.ResponseWriter, filename string, header []string) *csvStream {
+	return &csvStream{w: w, writer: csv.NewWriter(w), filename: filename, header: header}
+}
+
+// start writes the response headers and the CSV header row, if not already written
+func (s *csvStream) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	s.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": s.filename}))
+	s.w.WriteHeader(http.StatusOK)
+	return s.writer.Write(s.header)
+}
+
+// write writes a row. Rows are buffered, and sent to the client whenever the buffer fills.
+func (s *csvStream) write(record []string) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	return s.writer.Write(record)
+}
+
+// close writes the header row if no rows were written, and flushes any buffered output
+func (s *csvStream) close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+func proposalCSVRecord(proposal *governor.Proposal) []string {
+	return []string{
+		proposal.ProposalKey,
+		proposal.ContractId,
+		strconv.FormatUint(uint64(proposal.ProposalId), 10),
+		proposal.Proposer,
+		strconv.FormatUint(uint64(proposal.Status), 10),
+		proposal.Title,
+		proposal.Description,
+		proposal.Action,
+		strconv.FormatUint(uint64(proposal.VoteStart), 10),
+		strconv.FormatUint(uint64(proposal.VoteEnd), 10),
+		proposal.VotesFor,
+		proposal.VotesAgainst,
+		proposal.VotesAbstain,
+		strconv.FormatUint(uint64(proposal.ExecutionUnlock), 10),
+		proposal.ExecutionTxHash,
+		strconv.FormatBool(proposal.Truncated),
+		strconv.FormatUint(uint64(proposal.CreatedLedger), 10),
+		strconv.FormatUint(uint64(proposal.UpdatedLedger), 10),
+		proposal.UpdatedEventId,
+		strconv.FormatInt(proposal.UpdatedAt, 10),
+		strconv.FormatBool(proposal.FlaggedLowParticipation),
+		proposal.ActionType,
+		proposal.ActionContractId,
+		proposal.ActionFunction,
+		proposal.SourceAccount,
+		proposal.CreatedTxHash,
+	}
+}
+
+func voteCSVRecord(vote *governor.Vote) []string {
+	return []string{
+		vote.TxHash,
+		vote.ContractId,
+		strconv.FormatUint(uint64(vote.ProposalId), 10),
+		vote.Voter,
+		strconv.FormatUint(uint64(vote.Support), 10),
+		vote.Amount,
+		strconv.FormatUint(uint64(vote.LedgerSeq), 10),
+		strconv.FormatInt(vote.LedgerCloseTime, 10),
+		vote.Reason,
+	}
+}
+
+// proposalsCSVFilename returns the export filename for the proposals of a contract
+func proposalsCSVFilename(contractId string) string {
+	return fmt.Sprintf("%s-proposals.csv", contractId)
+}
+
+// votesCSVFilename returns the export filename for the votes of a proposal
+func votesCSVFilename(contractId string, proposalId uint32) string {
+	return fmt.Sprintf("%s-%d-votes.csv", contractId, proposalId)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+)
+
+const (
+	// INDEX_STATUS_TTL is how long the latest indexed ledger is cached, so it isn't read from the database for every
+	// request
+	INDEX_STATUS_TTL = time.Second
+	// INDEX_STALE_CODE is the error code of requests refused because the index is stale
+	INDEX_STALE_CODE = "index_stale"
+	// REBUILDING_CODE is the error code of requests refused because their contract is being rebuilt
+	REBUILDING_CODE = "rebuilding"
+	// REBUILDING_MAX_AGE is how long a rebuilding flag is trusted, so a replay that crashed before clearing its flag
+	// doesn't mark or refuse requests forever
+	REBUILDING_MAX_AGE = time.Hour
+)
+
+// The values of API_REBUILDING_MODE
+const (
+	REBUILDING_MODE_SERVE       = "serve"
+	REBUILDING_MODE_UNAVAILABLE = "unavailable"
+)
+
+// indexStatus caches the latest ledger indexed, from the status table
+type indexStatus struct {
+	mu        sync.Mutex
+	store     Store
+	ledger    uint32
+	closeTime int64
+	expires   time.Time
+	// rebuilding is the contract being rebuilt, empty if none is
+	rebuilding        string
+	rebuildingSince   int64
+	rebuildingExpires time.Time
+	now               func() time.Time
+}
+
+func newIndexStatus(store Store) *indexStatus {
+	return &indexStatus{store: store, now: time.Now}
+}
+
+// get returns the latest indexed ledger and its close time, which are 0 if no ledger has been indexed. Concurrent
+// callers wait for a single read when the cache expires. Failed reads are not cached.
+func (s *indexStatus) get(ctx context.Context) (uint32, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now().Before(s.expires) {
+		return s.ledger, s.closeTime, nil
+	}
+	ledger, closeTime, err := s.store.GetStatus(ctx, indexer.STATUS_SOURCE)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.ledger, s.closeTime, s.expires = ledger, closeTime, s.now().Add(INDEX_STATUS_TTL)
+	return ledger, closeTime, nil
+}
+
+// getRebuilding returns the contract the indexer is rebuilding and the unix time the rebuild started, or an empty
+// contract id if none is, or if the flag is older than REBUILDING_MAX_AGE. The flag is cached for INDEX_STATUS_TTL.
+// Failed reads are not cached.
+func (s *indexStatus) getRebuilding(ctx context.Context) (string, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now().Before(s.rebuildingExpires) {
+		return s.rebuilding, s.rebuildingSince, nil
+	}
+	status, err := s.store.GetSourceStatus(ctx, indexer.REBUILDING_STATUS_SOURCE)
+	if errors.Is(err, db.ErrNotFound) {
+		status = &db.SourceStatus{}
+	} else if err != nil {
+		return "", 0, err
+	}
+	s.rebuilding, s.rebuildingSince = status.State, status.UpdatedAt
+	if s.rebuilding != "" && s.now().Sub(time.Unix(s.rebuildingSince, 0)) > REBUILDING_MAX_AGE {
+		slog.Warn("Ignoring an expired rebuilding flag", "contract", s.rebuilding, "since", s.rebuildingSince)
+		s.rebuilding, s.rebuildingSince = "", 0
+	}
+	s.rebuildingExpires = s.now().Add(INDEX_STATUS_TTL)
+	return s.rebuilding, s.rebuildingSince, nil
+}
+
+// withIndexStatus wraps a handler so every response has the X-Indexed-Ledger and X-Index-Lag-Seconds headers. If
+// maxStaleness is set, data requests are refused with a 503 once the latest indexed ledger closed longer than
+// maxStaleness ago, or if no ledger has been indexed. The health, status, metrics, and admin endpoints are always
+// served.
+func (h *Handler) withIndexStatus(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ledger, closeTime, err := h.indexStatus.get(r.Context())
+		if err != nil {
+			slog.Warn("Failed to get last indexed ledger for index headers", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		lag := h.indexStatus.now().Unix() - closeTime
+		w.Header().Set("X-Indexed-Ledger", strconv.FormatUint(uint64(ledger), 10))
+		if closeTime != 0 {
+			w.Header().Set("X-Index-Lag-Seconds", strconv.FormatInt(lag, 10))
+		}
+
+		maxStaleness := time.Duration(h.maxStaleness.Load())
+		if maxStaleness > 0 && enforcesFreshness(r) {
+			if closeTime == 0 {
+				respondErrorCode(w, http.StatusServiceUnavailable, INDEX_STALE_CODE, "index is stale, no ledger has been indexed")
+				return
+			}
+			if lag > int64(maxStaleness.Seconds()) {
+				respondErrorCode(w, http.StatusServiceUnavailable, INDEX_STALE_CODE,
+					fmt.Sprintf("index is stale, last indexed ledger %d closed %ds ago", ledger, lag))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRebuilding wraps a handler so every response has the X-Index-Rebuilding and X-Index-Rebuilding-Since headers
+// while the indexer rebuilds a contract. Reindexes and replays run in a single transaction, so requests are served
+// the data from before the rebuild until it is done. If rebuildingMode is REBUILDING_MODE_UNAVAILABLE, data requests
+// for the contract are refused with a 503 and a Retry-After instead.
+func (h *Handler) withRebuilding(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contractId, since, err := h.indexStatus.getRebuilding(r.Context())
+		if err != nil {
+			slog.Warn("Failed to get rebuilding flag for index headers", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if contractId == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-Index-Rebuilding", contractId)
+		w.Header().Set("X-Index-Rebuilding-Since", strconv.FormatInt(since, 10))
+		if h.rebuildingMode == REBUILDING_MODE_UNAVAILABLE && enforcesFreshness(r) && requestContractId(r) == contractId {
+			w.Header().Set("Retry-After", strconv.Itoa(h.rebuildingRetryAfter))
+			respondErrorCode(w, http.StatusServiceUnavailable, REBUILDING_CODE,
+				fmt.Sprintf("contract %s is being rebuilt since %d", contractId, since))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestContractId returns the contract id a request is for, the first segment of its path
+func requestContractId(r *http.Request) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(unversionedPath(r), "/"), "/")
+	return segment
+}
+
+// setMaxStaleness sets the staleness of the index at which data requests are refused, or 0 to always serve them
+func (h *Handler) setMaxStaleness(seconds int) {
+	h.maxStaleness.Store(int64(time.Duration(seconds) * time.Second))
+}
+
+// enforcesFreshness returns true if the request is for indexed data, which is refused when the index is stale
+func enforcesFreshness(r *http.Request) bool {
+	if r.Method == http.MethodOptions {
+		return false
+	}
+	switch r.URL.Path {
+	case "/health", "/status", "/metrics":
+		return false
+	}
+	return !strings.HasPrefix(unversionedPath(r), "/admin/")
+}
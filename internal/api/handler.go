@@ -1,55 +1,233 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/script3/soroban-governor-backend/internal/db"
 	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
 )
 
+const (
+	// DEFAULT_SERIES_BUCKET is the bucket size (in seconds) of vote series if no bucket is given
+	DEFAULT_SERIES_BUCKET = 3600
+	// MIN_SERIES_BUCKET and MAX_SERIES_BUCKET bound the bucket size (in seconds) of vote series
+	MIN_SERIES_BUCKET = 60
+	MAX_SERIES_BUCKET = 7 * 24 * 3600
+)
+
+const (
+	// DEFAULT_LIMIT is the number of items returned by list endpoints if no limit is given
+	DEFAULT_LIMIT = 50
+	// MAX_LIMIT is the maximum number of items returned by list endpoints
+	MAX_LIMIT = 200
+	// MAX_PROPOSAL_KEYS is the maximum number of proposals that can be looked up in one request
+	MAX_PROPOSAL_KEYS = 100
+	// MAX_VOTED_PROPOSALS is the maximum number of proposals that can be checked for a voter's votes in one request
+	MAX_VOTED_PROPOSALS = 100
+	// MAX_VOTED_BYTES is the maximum size of the request body listing the proposals to check for a voter's votes
+	MAX_VOTED_BYTES = 4 * 1024
+	// MAX_WITHIN_LEDGERS is the furthest ahead, in ledgers, proposals ending or becoming executable soon can be
+	// listed, about a week
+	MAX_WITHIN_LEDGERS = 7 * 24 * 3600 / ESTIMATED_LEDGER_CLOSE_SECONDS
+	// DEFAULT_EXECUTABLE_WITHIN_LEDGERS is how far ahead, in ledgers, proposals becoming executable are listed if no
+	// ?within_ledgers= is given, about an hour
+	DEFAULT_EXECUTABLE_WITHIN_LEDGERS = 3600 / ESTIMATED_LEDGER_CLOSE_SECONDS
+)
+
+// API_VERSION_PREFIX is the path prefix of the current version of the API. The same routes without the prefix are
+// deprecated aliases.
+const API_VERSION_PREFIX = "/v1"
+
 type Handler struct {
-	store  *db.Store
-	router *http.ServeMux
+	store       Store
+	indexer     *indexer.Indexer
+	jobs        *jobRegistry
+	adminTokens keySet
+	apiKeys     keySet
+	indexStatus *indexStatus
+	// network is nil unless RPC_URL is set
+	network *networkStatus
+	// rpcProposals is nil unless API_RPC_PROPOSAL_FALLBACK is set
+	rpcProposals *rpcProposals
+	// ledgerEntries reads governor instances after a reindex, and is nil unless RPC_URL is set
+	ledgerEntries indexer.LedgerEntryReader
+	counts        *countCache
+	tokens        *tokenCache
+	// blocklist and unreviewed cache the blocked contracts and the contracts that were not reviewed, whose data is
+	// hidden. They are nil for a Config without DB.BlocklistRefreshInterval or ReviewRefreshInterval respectively,
+	// which the loaded config always sets, so every contract is served.
+	blocklist  *indexer.Blocklist
+	unreviewed *unreviewedContracts
+	// maxStaleness is a time.Duration, and changes when the config is reloaded
+	maxStaleness atomic.Int64
+	// rebuildingMode is API_REBUILDING_MODE, and rebuildingRetryAfter the Retry-After in seconds of refused requests
+	rebuildingMode       string
+	rebuildingRetryAfter int
+	// maxListRows is API_MAX_LIST_ROWS, the most items returned by lists that aren't paginated
+	maxListRows int
+	// trustedProxies are API_TRUSTED_PROXIES, whose X-Forwarded-For entries are used to find the client IP
+	trustedProxies []netip.Prefix
+	timeouts       *requestTimeouts
+	router         *http.ServeMux
+	// routes are the API routes, mounted on router under API_VERSION_PREFIX and without a prefix
+	routes  *http.ServeMux
+	handler http.Handler
 }
 
-func NewHandler(store *db.Store) *Handler {
+// NewHandler creates a Handler backed by the database. Admin reindex jobs are run by an indexer sharing the same store.
+func NewHandler(store *db.Store, config *Config) *Handler {
+	return newHandler(store, indexer.NewIndexer(store), config)
+}
+
+func newHandler(store Store, idx *indexer.Indexer, config *Config) *Handler {
 	h := &Handler{
-		store:  store,
-		router: http.NewServeMux(),
+		store:                store,
+		indexer:              idx,
+		jobs:                 newJobRegistry(),
+		adminTokens:          newKeySet(config.AdminTokens),
+		apiKeys:              newKeySet(config.APIKeys),
+		indexStatus:          newIndexStatus(store),
+		network:              newNetworkStatus(config.RPCUrl),
+		rpcProposals:         newRPCProposals(config.RPCUrl, config.RPCProposalFallback),
+		counts:               newCountCache(),
+		tokens:               newTokenCache(store),
+		router:               http.NewServeMux(),
+		rebuildingMode:       config.RebuildingMode,
+		rebuildingRetryAfter: config.RebuildingRetryAfterSeconds,
+		maxListRows:          config.MaxListRows,
+		trustedProxies:       config.TrustedProxies,
+		timeouts:             newRequestTimeouts(config),
+	}
+	if config.RPCUrl != "" {
+		h.ledgerEntries = rpcclient.NewClient(config.RPCUrl, nil)
+	}
+	if config.DB.BlocklistRefreshInterval > 0 {
+		h.blocklist = indexer.NewBlocklist(store, time.Duration(config.DB.BlocklistRefreshInterval)*time.Second)
 	}
+	if config.ReviewRefreshInterval > 0 {
+		h.unreviewed = newUnreviewedContracts(store, time.Duration(config.ReviewRefreshInterval)*time.Second)
+	}
+	h.setMaxStaleness(config.MaxStalenessSeconds)
 	h.registerRoutes()
+	h.handler = h.withIndexStatus(h.withRebuilding(h.router))
+	if config.RequireAuth {
+		h.handler = h.requireAuth(h.handler)
+	}
+	h.handler = h.withTimeout(h.handler)
 	return h
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	timeStart := time.Now()
+	id := requestId(r)
 	defer func() {
 		duration := time.Since(timeStart)
-		slog.Info("Request complete", "method", r.Method, "path", r.URL.String(), "ms", duration.Milliseconds())
+		slog.Info("Request complete", "request_id", id, "client_ip", clientIP(r, h.trustedProxies), "method", r.Method, "path", r.URL.String(), "ms", duration.Milliseconds())
 	}()
+	w.Header().Set("X-Request-Id", id)
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id, X-Indexed-Ledger, X-Index-Lag-Seconds, X-Index-Rebuilding, X-Index-Rebuilding-Since, X-Truncated, Warning")
 	w.Header().Set("Access-Control-Max-Age", "86400")
 
-	h.router.ServeHTTP(w, r)
+	recoverPanic(h.handler, id).ServeHTTP(w, r)
 }
 
+// registerRoutes registers the unversioned service routes, and mounts the API routes under API_VERSION_PREFIX and
+// as deprecated aliases without a prefix
 func (h *Handler) registerRoutes() {
-	h.router.HandleFunc("OPTIONS /", h.handleOptions)
-
 	h.router.HandleFunc("GET /health", h.handleHealth)
-	h.router.HandleFunc("GET /{contractId}/proposals/{proposalId}", h.handleGetProposal)
+	h.router.HandleFunc("GET /status", h.handleGetIndexStatus)
+	h.router.HandleFunc("GET /status/gaps", h.handleGetCoverageGaps)
+	h.router.Handle("GET /metrics", metrics.Handler())
+
+	h.routes = h.newAPIRoutes()
+	h.router.Handle(API_VERSION_PREFIX+"/", versioned(http.StripPrefix(API_VERSION_PREFIX, h.routes)))
+	h.router.Handle("/", deprecated(h.routes))
+
+	// The transaction route overlaps GET /{contractId}/proposals/{proposalId}, which the API routes can't resolve, so
+	// it is registered on both prefixes here instead. Contract ids are never "transactions".
+	transactionEvents := h.withUnreviewed(h.handleGetTransactionEvents)
+	h.router.Handle("GET "+API_VERSION_PREFIX+"/transactions/{txHash}/events", versioned(transactionEvents))
+	h.router.Handle("GET /transactions/{txHash}/events", deprecated(transactionEvents))
+}
+
+// newAPIRoutes returns the API routes, relative to the prefix they are mounted under
+func (h *Handler) newAPIRoutes() *http.ServeMux {
+	routes := http.NewServeMux()
+	routes.HandleFunc("OPTIONS /", h.handleOptions)
+
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}", h.rejectBlocked(h.rejectUnreviewed(h.handleGetProposal)))
+	routes.HandleFunc("GET /{contractId}/proposals", h.rejectBlocked(h.rejectUnreviewed(h.handleGetProposals)))
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}/votes", h.rejectBlocked(h.rejectUnreviewed(h.handleGetVotes)))
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}/votes/{voter}", h.rejectBlocked(h.rejectUnreviewed(h.handleGetVote)))
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}/votes/summary", h.rejectBlocked(h.rejectUnreviewed(h.handleGetVoteSummary)))
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}/votes/series", h.rejectBlocked(h.rejectUnreviewed(h.handleGetVoteSeries)))
+	routes.HandleFunc("GET /{contractId}/proposals/{proposalId}/content", h.rejectBlocked(h.rejectUnreviewed(h.handleGetProposalContent)))
+	routes.HandleFunc("GET /{contractId}/events", h.rejectBlocked(h.rejectUnreviewed(h.handleGetEvents)))
+	routes.HandleFunc("POST /{contractId}/voters/{voter}/voted", h.rejectBlocked(h.rejectUnreviewed(h.handleGetVoted)))
+	routes.HandleFunc("GET /{contractId}/delegates/{address}", h.rejectBlocked(h.rejectUnreviewed(h.handleGetDelegates)))
+	routes.HandleFunc("GET /{contractId}/summary", h.rejectBlocked(h.rejectUnreviewed(h.handleGetContract)))
+	routes.HandleFunc("GET /events/recent", h.withUnreviewed(h.handleGetRecentEvents))
+	routes.HandleFunc("GET /contracts", h.handleGetContracts)
+	routes.HandleFunc("GET /proposals", h.withUnreviewed(h.handleGetProposalsByKeys))
+	routes.HandleFunc("GET /proposals/active", h.handleGetActiveProposals)
+	routes.HandleFunc("GET /proposals/ending", h.withUnreviewed(h.handleGetEndingProposals))
+	routes.HandleFunc("GET /proposals/executable", h.withUnreviewed(h.handleGetExecutableProposals))
+	routes.HandleFunc("GET /analytics/failed-tx-events", h.handleGetFailedTxEvents)
 
-	h.router.HandleFunc("GET /{contractId}/proposals", h.handleGetProposals)
-	h.router.HandleFunc("GET /{contractId}/proposals/{proposalId}/votes", h.handleGetVotes)
-	h.router.HandleFunc("GET /{contractId}/events", h.handleGetEvents)
+	routes.HandleFunc("POST /admin/contracts/{contractId}/reindex", h.requireAdmin(h.handleReindexContract))
+	routes.HandleFunc("DELETE /admin/contracts/{contractId}", h.requireAdmin(h.handleDeleteContract))
+	routes.HandleFunc("GET /admin/contracts/{contractId}/failed-events", h.requireAdmin(h.handleGetFailedEvents))
+	routes.HandleFunc("PUT /admin/contracts/{contractId}/metadata", h.requireAdmin(h.handleSetContractMetadata))
+	routes.HandleFunc("DELETE /admin/contracts/{contractId}/metadata", h.requireAdmin(h.handleDeleteContractMetadata))
+	routes.HandleFunc("GET /admin/blocklist", h.requireAdmin(h.handleGetBlocklist))
+	routes.HandleFunc("PUT /admin/blocklist/{contractId}", h.requireAdmin(h.handleBlockContract))
+	routes.HandleFunc("DELETE /admin/blocklist/{contractId}", h.requireAdmin(h.handleUnblockContract))
+	routes.HandleFunc("GET /admin/failed-events", h.requireAdmin(h.handleListFailedEvents))
+	routes.HandleFunc("POST /admin/failed-events/reprocess", h.requireAdmin(h.handleReprocessFailedEvents))
+	routes.HandleFunc("GET /admin/jobs/{jobId}", h.requireAdmin(h.handleGetJob))
+	routes.HandleFunc("GET /admin/status", h.requireAdmin(h.handleGetStatus))
+	routes.HandleFunc("GET /admin/errors", h.requireAdmin(h.handleGetIndexerErrors))
+	routes.HandleFunc("GET /admin/contracts/unreviewed", h.requireAdmin(h.handleGetUnreviewedContracts))
+	routes.HandleFunc("PUT /admin/contracts/{contractId}/reviewed", h.requireAdmin(h.handleReviewContract))
+	routes.HandleFunc("DELETE /admin/contracts/{contractId}/reviewed", h.requireAdmin(h.handleUnreviewContract))
+	return routes
+}
+
+// deprecated wraps the unversioned aliases of the API routes, marking responses as deprecated and linking to the
+// versioned route
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", API_VERSION_PREFIX, r.URL.EscapedPath()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unversionedPath returns the path of the request without API_VERSION_PREFIX
+func unversionedPath(r *http.Request) string {
+	if path, ok := strings.CutPrefix(r.URL.Path, API_VERSION_PREFIX); ok && strings.HasPrefix(path, "/") {
+		return path
+	}
+	return r.URL.Path
 }
 
 // handleOptions handles CORS preflight requests
@@ -57,14 +235,22 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// HealthResponse is the response body of a healthy service
+type HealthResponse struct {
+	// Status is the last ledger processed by the indexer
+	Status uint32 `json:"status"`
+	// HasGaps is true if ledgers were knowingly not indexed, see GET /status/gaps
+	HasGaps bool `json:"has_gaps"`
+}
+
 // handleHealth returns service health status
 func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
-	curUnix := time.Now().Unix()
+	curUnix := h.indexStatus.now().Unix()
 
 	lastLedger, lastClostTime, err := h.store.GetStatus(r.Context(), "indexer")
 	if err != nil {
 		slog.Error("Failed to get last indexed ledger", "error", err)
-		respondError(w, http.StatusInternalServerError, "failed to get health status")
+		respondError(w, storeErrorStatus(err), "failed to get health status")
 		return
 	}
 
@@ -74,10 +260,17 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, fmt.Sprintf("too long since last indexed ledger %d, closed %ds ago", lastLedger, curUnix-lastClostTime))
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]uint32{"status": lastLedger})
+	gaps, err := h.store.CountCoverageGaps(r.Context())
+	if err != nil {
+		slog.Error("Failed to count coverage gaps", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to get health status")
+		return
+	}
+	respondJSON(w, http.StatusOK, HealthResponse{Status: lastLedger, HasGaps: gaps > 0})
 }
 
-// handleGetProposal retrieves a single proposal by contract ID and proposal ID
+// handleGetProposal retrieves a single proposal by contract ID and proposal ID. With ?at_ledger, the proposal is
+// reconstructed as it was at the end of that ledger.
 func (h *Handler) handleGetProposal(w http.ResponseWriter, r *http.Request) {
 	contractId := r.PathValue("contractId")
 	proposalIdStr := r.PathValue("proposalId")
@@ -88,41 +281,405 @@ func (h *Handler) handleGetProposal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if atLedgerStr := r.URL.Query().Get("at_ledger"); atLedgerStr != "" {
+		atLedger, err := strconv.ParseUint(atLedgerStr, 10, 32)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid at_ledger")
+			return
+		}
+		h.getProposalAtLedger(w, r, contractId, uint32(proposalId), uint32(atLedger))
+		return
+	}
+
 	proposalKey := governor.EncodeProposalKey(contractId, uint32(proposalId))
 	proposal, err := h.store.GetProposal(r.Context(), proposalKey)
+	if errors.Is(err, db.ErrNotFound) && h.rpcProposals != nil {
+		h.getProposalFromRPC(w, r, contractId, uint32(proposalId))
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "proposal not found")
+		return
+	}
 	if err != nil {
 		slog.Error("Failed to get proposal", "error", err)
-		respondError(w, http.StatusInternalServerError, "failed to retrieve proposal")
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposal")
 		return
 	}
 
+	respondJSON(w, http.StatusOK, h.withResults(r.Context(), h.ledgerClock(r.Context()).newProposalResponse(proposal)))
+}
+
+// RPCProposal is a proposal read from the governor's storage as it is not indexed yet. Fields only known from events,
+// such as the creation ledger and transaction, are empty.
+type RPCProposal struct {
+	*ProposalResponse
+	Source string `json:"source"`
+}
+
+// getProposalFromRPC responds with a proposal that is not indexed yet, read from the governor's storage, without
+// writing it to the database. Only proposals of tracked contracts are read, so any contract id can't be used to call
+// the RPC server.
+func (h *Handler) getProposalFromRPC(w http.ResponseWriter, r *http.Request, contractId string, proposalId uint32) {
+	_, err := h.store.GetContract(r.Context(), contractId)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "proposal not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get contract", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposal")
+		return
+	}
+
+	proposal, err := h.rpcProposals.get(r.Context(), contractId, proposalId)
+	if err != nil {
+		slog.Warn("Failed to read proposal from RPC", "contract", contractId, "proposal", proposalId, "error", err)
+		respondError(w, http.StatusNotFound, "proposal not found")
+		return
+	}
 	if proposal == nil {
 		respondError(w, http.StatusNotFound, "proposal not found")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, proposal)
+	respondJSON(w, http.StatusOK, RPCProposal{
+		ProposalResponse: h.ledgerClock(r.Context()).newProposalResponse(proposal),
+		Source:           PROPOSAL_SOURCE_RPC,
+	})
+}
+
+// ReconstructedProposal is a proposal as it was at the end of a ledger, replayed from the event history
+type ReconstructedProposal struct {
+	*ProposalResponse
+	Reconstructed bool   `json:"reconstructed"`
+	AtLedger      uint32 `json:"at_ledger"`
+}
+
+// getProposalAtLedger responds with the state of a proposal at the end of ledger atLedger, reconstructed by
+// replaying the proposal's event history
+func (h *Handler) getProposalAtLedger(w http.ResponseWriter, r *http.Request, contractId string, proposalId uint32, atLedger uint32) {
+	events, err := h.store.GetEventsByProposal(r.Context(), contractId, proposalId)
+	if err != nil {
+		slog.Error("Failed to get proposal events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposal")
+		return
+	}
+	if len(events) == 0 {
+		respondError(w, http.StatusNotFound, "proposal not found")
+		return
+	}
+
+	proposal, err := governor.ReplayProposal(events, atLedger)
+	if errors.Is(err, governor.ErrProposalNotCreated) {
+		respondError(w, http.StatusNotFound, "proposal not created at ledger")
+		return
+	}
+	if errors.Is(err, governor.ErrIncompleteHistory) {
+		respondError(w, http.StatusGone, "proposal history is incomplete, unable to reconstruct proposal")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to reconstruct proposal", "contract", contractId, "proposal", proposalId, "ledger", atLedger, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to reconstruct proposal")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ReconstructedProposal{
+		ProposalResponse: h.ledgerClock(r.Context()).newProposalResponse(proposal),
+		Reconstructed:    true,
+		AtLedger:         atLedger,
+	})
+}
+
+// handleGetProposalContent retrieves the fetched IPFS content of a proposal's description
+func (h *Handler) handleGetProposalContent(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	proposalIdStr := r.PathValue("proposalId")
+
+	proposalId, err := strconv.ParseUint(proposalIdStr, 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid proposal_id")
+		return
+	}
+
+	proposalKey := governor.EncodeProposalKey(contractId, uint32(proposalId))
+	content, err := h.store.GetProposalContent(r.Context(), proposalKey)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "proposal content not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get proposal content", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposal content")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, content)
 }
 
-// handleGetProposals retrieves all proposals for a contract with pagination
+// handleGetProposals retrieves all proposals for a contract with pagination, as JSON or CSV, optionally sorted with
+// ?sort= by one of db.PROPOSAL_SORT_FIELDS, and filtered with ?action_type= by one of governor.ACTION_TYPES. With
+// ?view=summary, the JSON proposals are ProposalSummary.
 func (h *Handler) handleGetProposals(w http.ResponseWriter, r *http.Request) {
 	contractId := r.PathValue("contractId")
 
+	sort, err := parseSort(r, db.PROPOSAL_SORT_FIELDS)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var summary bool
+	switch r.URL.Query().Get("view") {
+	case "", "full":
+	case "summary":
+		summary = true
+	default:
+		respondError(w, http.StatusBadRequest, "invalid view, must be full or summary")
+		return
+	}
+
+	actionType := r.URL.Query().Get("action_type")
+	if actionType != "" && !slices.Contains(governor.ACTION_TYPES, actionType) {
+		respondError(w, http.StatusBadRequest, "invalid action_type, must be one of "+strings.Join(governor.ACTION_TYPES, ", "))
+		return
+	}
+	flagged := includeFlagged(r)
+
+	if wantsCSV(r) {
+		stream := newCSVStream(w, proposalsCSVFilename(contractId), PROPOSAL_CSV_HEADER)
+		err := h.store.EachProposalByContractId(r.Context(), contractId, sort, func(proposal *governor.Proposal) error {
+			if proposal.FlaggedLowParticipation && !flagged {
+				return nil
+			}
+			if actionType != "" && proposal.ActionType != actionType {
+				return nil
+			}
+			return stream.write(proposalCSVRecord(proposal))
+		})
+		finishCSV(w, stream, err, "proposals")
+		return
+	}
+
 	proposals, err := h.store.GetProposalsByContractId(
 		r.Context(),
 		contractId,
+		sort,
 	)
 	if err != nil {
 		slog.Error("Failed to get proposals", "error", err)
-		respondError(w, http.StatusInternalServerError, "failed to retrieve proposals")
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposals")
+		return
+	}
+	if !flagged {
+		proposals = withoutFlagged(proposals)
+	}
+	if actionType != "" {
+		proposals = slices.DeleteFunc(proposals, func(proposal *governor.Proposal) bool { return proposal.ActionType != actionType })
+	}
+	proposals = truncateList(w, r, proposals, h.maxListRows)
+
+	if summary {
+		respondJSON(w, http.StatusOK, h.ledgerClock(r.Context()).newProposalSummaries(proposals))
+		return
+	}
+	respondJSON(w, http.StatusOK, h.ledgerClock(r.Context()).newProposalResponses(proposals))
+}
+
+// includeFlagged returns false if the request excludes proposals flagged as low participation with
+// ?include_flagged=false. Flagged proposals are included by default.
+func includeFlagged(r *http.Request) bool {
+	return r.URL.Query().Get("include_flagged") != "false"
+}
+
+// hidesContract returns a function reporting whether the data of a contract is excluded from the response to the
+// request, as it is blocked or was not reviewed. See hidesBlocked and hidesUnreviewed.
+func (h *Handler) hidesContract(r *http.Request) func(contractId string) bool {
+	blocked, unreviewed := h.hidesBlocked(r), h.hidesUnreviewed(r)
+	return func(contractId string) bool { return blocked(contractId) || unreviewed(contractId) }
+}
+
+// hidesProposal returns a function reporting whether a proposal is excluded from the response to the request, as its
+// contract is blocked or was not reviewed. See hidesContract.
+func (h *Handler) hidesProposal(r *http.Request) func(proposal *governor.Proposal) bool {
+	hidden := h.hidesContract(r)
+	return func(proposal *governor.Proposal) bool { return hidden(proposal.ContractId) }
+}
+
+// withoutFlagged removes the proposals flagged as low participation
+func withoutFlagged(proposals []*governor.Proposal) []*governor.Proposal {
+	return slices.DeleteFunc(proposals, func(proposal *governor.Proposal) bool { return proposal.FlaggedLowParticipation })
+}
+
+// ProposalsPage is a page of proposals, the shape of the deprecated unversioned active proposals route. NextCursor
+// is empty on the last page.
+type ProposalsPage struct {
+	Proposals  []*ProposalResponse `json:"proposals"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// handleGetActiveProposals retrieves open proposals across all contracts, soonest closing first. Queued proposals
+// are included with ?include_queued=true, and proposals of contracts that were not reviewed with
+// ?include_unreviewed=true. Proposals of blocked contracts are never included. Under API_VERSION_PREFIX the page is
+// a Page with the total number of active proposals.
+func (h *Handler) handleGetActiveProposals(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	// 0 is open, and 1 is queued for execution
+	statuses := []uint32{0}
+	if r.URL.Query().Get("include_queued") == "true" {
+		statuses = append(statuses, 1)
+	}
+	flagged := includeFlagged(r)
+	unreviewed := includeUnreviewed(r)
+
+	// read one more proposal than the limit to know if there is a next page
+	proposals, err := h.store.GetProposalsByStatus(r.Context(), statuses, flagged, unreviewed, limit+1, cursor)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		respondError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get active proposals", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve active proposals")
+		return
+	}
+	nextCursor := ""
+	if len(proposals) > limit {
+		proposals = proposals[:limit]
+		nextCursor = db.EncodeProposalCursor(proposals[len(proposals)-1])
+	}
+	responses := h.ledgerClock(r.Context()).newProposalResponses(proposals)
+
+	if !isVersioned(r) {
+		respondJSON(w, http.StatusOK, ProposalsPage{Proposals: responses, NextCursor: nextCursor})
+		return
+	}
+
+	total, err := h.counts.get(r.Context(), fmt.Sprintf("proposals_by_status:%v:%v:%v", statuses, flagged, unreviewed), func(ctx context.Context) (int, error) {
+		return h.store.CountProposalsByStatus(ctx, statuses, flagged, unreviewed)
+	})
+	if err != nil {
+		slog.Error("Failed to count active proposals", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve active proposals")
+		return
+	}
+	respondJSON(w, http.StatusOK, Page[*ProposalResponse]{Data: responses, Pagination: newPagination(limit, cursor, nextCursor, total)})
+}
+
+// handleGetEndingProposals retrieves the open proposals across all contracts whose voting ends within the next
+// ?within_ledgers= ledgers after the latest indexed ledger, soonest ending first
+func (h *Handler) handleGetEndingProposals(w http.ResponseWriter, r *http.Request) {
+	within, err := strconv.ParseUint(r.URL.Query().Get("within_ledgers"), 10, 32)
+	if err != nil || within < 1 || within > MAX_WITHIN_LEDGERS {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid within_ledgers, must be from 1 to %d", MAX_WITHIN_LEDGERS))
+		return
+	}
+
+	clock := h.ledgerClock(r.Context())
+	if clock.closeTime == 0 {
+		respondError(w, http.StatusServiceUnavailable, "last indexed ledger is unavailable")
+		return
+	}
+
+	proposals, err := h.store.GetProposalsEndingBetween(r.Context(), clock.ledger+1, clock.ledger+uint32(within))
+	if err != nil {
+		slog.Error("Failed to get ending proposals", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve ending proposals")
+		return
+	}
+	if !includeFlagged(r) {
+		proposals = withoutFlagged(proposals)
+	}
+	proposals = slices.DeleteFunc(proposals, h.hidesProposal(r))
+	proposals = truncateList(w, r, proposals, h.maxListRows)
+
+	respondJSON(w, http.StatusOK, clock.newProposalResponses(proposals))
+}
+
+// ExecutableProposal is a successful proposal waiting to be executed. LedgersUntilExecutable is 0 if the proposal
+// can be executed now.
+type ExecutableProposal struct {
+	*ProposalResponse
+	LedgersUntilExecutable uint32 `json:"ledgers_until_executable"`
+}
+
+// handleGetExecutableProposals retrieves the successful proposals across all contracts that can be executed now, or
+// within the next ?within_ledgers= ledgers after the latest indexed ledger, soonest executable first
+func (h *Handler) handleGetExecutableProposals(w http.ResponseWriter, r *http.Request) {
+	within := uint64(DEFAULT_EXECUTABLE_WITHIN_LEDGERS)
+	if withinStr := r.URL.Query().Get("within_ledgers"); withinStr != "" {
+		var err error
+		within, err = strconv.ParseUint(withinStr, 10, 32)
+		if err != nil || within > MAX_WITHIN_LEDGERS {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid within_ledgers, must be from 0 to %d", MAX_WITHIN_LEDGERS))
+			return
+		}
+	}
+
+	clock := h.ledgerClock(r.Context())
+	if clock.closeTime == 0 {
+		respondError(w, http.StatusServiceUnavailable, "last indexed ledger is unavailable")
+		return
+	}
+
+	proposals, err := h.store.GetExecutableProposals(r.Context(), clock.ledger+uint32(within))
+	if err != nil {
+		slog.Error("Failed to get executable proposals", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve executable proposals")
+		return
+	}
+	if !includeFlagged(r) {
+		proposals = withoutFlagged(proposals)
+	}
+	proposals = slices.DeleteFunc(proposals, h.hidesProposal(r))
+	proposals = truncateList(w, r, proposals, h.maxListRows)
+
+	executable := make([]*ExecutableProposal, len(proposals))
+	for i, proposal := range proposals {
+		executable[i] = &ExecutableProposal{ProposalResponse: clock.newProposalResponse(proposal)}
+		if proposal.ExecutionUnlock > clock.ledger {
+			executable[i].LedgersUntilExecutable = proposal.ExecutionUnlock - clock.ledger
+		}
+	}
+	respondJSON(w, http.StatusOK, executable)
+}
+
+// handleGetProposalsByKeys retrieves up to MAX_PROPOSAL_KEYS proposals across contracts, given as comma-separated
+// proposal keys with ?keys=. Proposals are returned in the order of the keys, and keys without a proposal are omitted.
+func (h *Handler) handleGetProposalsByKeys(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range strings.SplitSeq(r.URL.Query().Get("keys"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		respondError(w, http.StatusBadRequest, "keys is required")
+		return
+	}
+	if len(keys) > MAX_PROPOSAL_KEYS {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("too many keys, at most %d are allowed", MAX_PROPOSAL_KEYS))
+		return
+	}
+
+	proposals, err := h.store.GetProposalsByKeys(r.Context(), keys)
+	if err != nil {
+		slog.Error("Failed to get proposals by keys", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve proposals")
 		return
 	}
+	proposals = slices.DeleteFunc(proposals, h.hidesProposal(r))
 
-	// Build response with pagination metadata
-	respondJSON(w, http.StatusOK, proposals)
+	respondJSON(w, http.StatusOK, h.ledgerClock(r.Context()).newProposalResponses(proposals))
 }
 
-// handleGetVotes retrieves all votes for a specific proposal with pagination
+// handleGetVotes retrieves all votes for a specific proposal with pagination, as JSON or CSV, optionally sorted with
+// ?sort= by one of db.VOTE_SORT_FIELDS
 func (h *Handler) handleGetVotes(w http.ResponseWriter, r *http.Request) {
 	contractId := r.PathValue("contractId")
 	proposalIdStr := r.PathValue("proposalId")
@@ -132,41 +689,467 @@ func (h *Handler) handleGetVotes(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "invalid proposal_id")
 		return
 	}
+	sort, err := parseSort(r, db.VOTE_SORT_FIELDS)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matchesSupport, err := parseSupportFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if wantsCSV(r) {
+		stream := newCSVStream(w, votesCSVFilename(contractId, uint32(proposalId)), VOTE_CSV_HEADER)
+		err := h.store.EachVoteByProposal(r.Context(), contractId, uint32(proposalId), sort, func(vote *governor.Vote) error {
+			if !matchesSupport(vote) {
+				return nil
+			}
+			return stream.write(voteCSVRecord(vote))
+		})
+		finishCSV(w, stream, err, "votes")
+		return
+	}
 
 	votes, err := h.store.GetVotesByProposal(
 		r.Context(),
 		contractId,
 		uint32(proposalId),
+		sort,
 	)
 	if err != nil {
 		slog.Error("Failed to get votes", "error", err)
-		respondError(w, http.StatusInternalServerError, "failed to retrieve votes")
+		respondError(w, storeErrorStatus(err), "failed to retrieve votes")
+		return
+	}
+	votes = slices.DeleteFunc(votes, func(vote *governor.Vote) bool { return !matchesSupport(vote) })
+	votes = truncateList(w, r, votes, h.maxListRows)
+
+	respondJSON(w, http.StatusOK, h.tokens.get(r.Context()).newVoteResponses(votes))
+}
+
+// parseSupportFilter parses the optional support query parameter, a numeric support value or a label: against, for,
+// abstain, or other to match support values with custom semantics. The returned func matches every vote if the
+// parameter is not set.
+func parseSupportFilter(r *http.Request) (func(vote *governor.Vote) bool, error) {
+	value := r.URL.Query().Get("support")
+	if value == "" {
+		return func(vote *governor.Vote) bool { return true }, nil
+	}
+	if strings.EqualFold(value, governor.VOTE_SUPPORT_LABEL_OTHER) {
+		return func(vote *governor.Vote) bool { return !governor.VoteSupport(vote.Support).Known() }, nil
+	}
+	support, err := governor.ParseVoteSupport(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(vote *governor.Vote) bool { return governor.VoteSupport(vote.Support) == support }, nil
+}
+
+// handleGetVote retrieves the latest vote of a voter on a proposal, which is the vote that counts
+func (h *Handler) handleGetVote(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	proposalIdStr := r.PathValue("proposalId")
+	voter := r.PathValue("voter")
+
+	proposalId, err := strconv.ParseUint(proposalIdStr, 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid proposal_id")
+		return
+	}
+
+	vote, err := h.store.GetVoteByProposalAndVoter(r.Context(), contractId, uint32(proposalId), voter)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "vote not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get vote", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve vote")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.tokens.get(r.Context()).newVoteResponse(vote))
+}
+
+// VotedResponse is whether a voter has voted on a proposal, and the support and amount of their latest vote if so
+type VotedResponse struct {
+	Voted        bool    `json:"voted"`
+	Support      *uint32 `json:"support"`
+	SupportLabel string  `json:"support_label,omitempty"`
+	Amount       string  `json:"amount,omitempty"`
+}
+
+// handleGetVoted returns whether a voter has voted on each of up to MAX_VOTED_PROPOSALS proposals, given as a JSON
+// array of proposal ids in the request body, as an object of proposal ids to a VotedResponse
+func (h *Handler) handleGetVoted(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	voter := r.PathValue("voter")
+
+	body, err := readBody(w, r, MAX_VOTED_BYTES)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	var proposalIds []uint32
+	if err := json.Unmarshal(body, &proposalIds); err != nil {
+		respondError(w, http.StatusBadRequest, "request body must be a JSON array of proposal ids")
+		return
+	}
+	if len(proposalIds) == 0 {
+		respondError(w, http.StatusBadRequest, "at least one proposal id is required")
+		return
+	}
+	if len(proposalIds) > MAX_VOTED_PROPOSALS {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("too many proposal ids, at most %d are allowed", MAX_VOTED_PROPOSALS))
+		return
+	}
+	slices.Sort(proposalIds)
+	proposalIds = slices.Compact(proposalIds)
+
+	votes, err := h.store.GetLatestVotesByVoter(r.Context(), contractId, voter, proposalIds)
+	if err != nil {
+		slog.Error("Failed to get votes by voter", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve votes")
+		return
+	}
+
+	voted := make(map[uint32]VotedResponse, len(proposalIds))
+	for _, proposalId := range proposalIds {
+		voted[proposalId] = VotedResponse{}
+	}
+	for _, vote := range votes {
+		voted[vote.ProposalId] = VotedResponse{
+			Voted:        true,
+			Support:      &vote.Support,
+			SupportLabel: governor.VoteSupport(vote.Support).Label(),
+			Amount:       vote.Amount,
+		}
+	}
+	respondJSON(w, http.StatusOK, voted)
+}
+
+// handleGetVoteSummary retrieves the number of voters, total, and largest vote for each support of a proposal
+func (h *Handler) handleGetVoteSummary(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	proposalIdStr := r.PathValue("proposalId")
+
+	proposalId, err := strconv.ParseUint(proposalIdStr, 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid proposal_id")
+		return
+	}
+
+	summary, err := h.store.GetVoteSummary(r.Context(), contractId, uint32(proposalId))
+	if err != nil {
+		slog.Error("Failed to get vote summary", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve vote summary")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, summary)
+}
+
+// VoteSeriesResponse is the voting activity of a proposal over time
+type VoteSeriesResponse struct {
+	// Bucket size (in seconds)
+	Bucket int64                       `json:"bucket"`
+	Points []*governor.VoteSeriesPoint `json:"points"`
+}
+
+// handleGetVoteSeries retrieves the votes of a proposal grouped into time buckets. The bucket size is clamped between
+// MIN_SERIES_BUCKET and MAX_SERIES_BUCKET.
+func (h *Handler) handleGetVoteSeries(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	proposalIdStr := r.PathValue("proposalId")
+
+	proposalId, err := strconv.ParseUint(proposalIdStr, 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid proposal_id")
+		return
+	}
+	bucket := int64(DEFAULT_SERIES_BUCKET)
+	if bucketStr := r.URL.Query().Get("bucket"); bucketStr != "" {
+		bucket, err = strconv.ParseInt(bucketStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid bucket")
+			return
+		}
+		bucket = min(max(bucket, MIN_SERIES_BUCKET), MAX_SERIES_BUCKET)
+	}
+
+	series, err := h.store.GetVoteSeries(r.Context(), contractId, uint32(proposalId), bucket)
+	if errors.Is(err, governor.ErrTooManyBuckets) {
+		respondError(w, http.StatusBadRequest, "too many buckets, use a larger bucket")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get vote series", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve vote series")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, votes)
+	respondJSON(w, http.StatusOK, VoteSeriesResponse{Bucket: bucket, Points: series})
 }
 
-// handleGetEvents retrieves all events for a contract with pagination
+// handleGetEvents retrieves all events for a contract with pagination, optionally sorted with ?sort= by one of
+// db.EVENT_SORT_FIELDS, and filtered with ?filter= by db.EVENT_FILTER_FIELDS
 func (h *Handler) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	contractId := r.PathValue("contractId")
 
-	events, err := h.store.GetEventsByContractId(
+	sort, err := parseSort(r, db.EVENT_SORT_FIELDS)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := db.ParseFilter(r.URL.Query().Get("filter"), db.EVENT_FILTER_FIELDS)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := h.store.FilterEventsByContractId(
 		r.Context(),
 		contractId,
+		filter,
+		sort,
 	)
+	if errors.Is(err, db.ErrInvalidFilter) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		slog.Error("Failed to get events", "error", err)
-		respondError(w, http.StatusInternalServerError, "failed to retrieve events")
+		respondError(w, storeErrorStatus(err), "failed to retrieve events")
+		return
+	}
+	events = truncateList(w, r, events, h.maxListRows)
+
+	respondJSON(w, http.StatusOK, withEventXdr(w, r, events))
+}
+
+// handleGetRecentEvents retrieves the newest events across all contracts, optionally filtered by event type. Events
+// of blocked contracts are never included, and events of contracts that were not reviewed only with
+// ?include_unreviewed=true, so a page may hold fewer than limit events.
+func (h *Handler) handleGetRecentEvents(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	eventType := r.URL.Query().Get("type")
+
+	events, err := h.store.GetRecentEvents(r.Context(), eventType, limit)
+	if err != nil {
+		slog.Error("Failed to get recent events", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve recent events")
+		return
+	}
+	hidden := h.hidesContract(r)
+	events = slices.DeleteFunc(events, func(event *governor.GovernorEvent) bool { return hidden(event.ContractId) })
+	if events == nil {
+		events = []*governor.GovernorEvent{}
+	}
+
+	respondJSON(w, http.StatusOK, withEventXdr(w, r, events))
+}
+
+// TransactionEventsResponse is what was indexed for a transaction: its governor events, and the vote and proposals
+// they produced
+type TransactionEventsResponse struct {
+	TxHash string                    `json:"tx_hash"`
+	Events []*governor.GovernorEvent `json:"events"`
+	// The vote cast in the transaction, or null if it did not cast one
+	Vote *VoteResponse `json:"vote"`
+	// The current state of the proposals the events applied to
+	Proposals []*ProposalResponse `json:"proposals"`
+}
+
+// handleGetTransactionEvents retrieves the governor events emitted in a transaction, with the vote and proposals they
+// produced, so a transaction hash from a user can be checked against what was indexed. The route is registered
+// outside the contract routes, so events of blocked contracts are left out here. A transaction that only emitted
+// events of blocked contracts is not found, nor one that only emitted events of contracts that were not reviewed,
+// unless requested with ?include_unreviewed=true.
+func (h *Handler) handleGetTransactionEvents(w http.ResponseWriter, r *http.Request) {
+	txHash := r.PathValue("txHash")
+
+	events, err := h.store.GetEventsByTxHash(r.Context(), txHash)
+	if err != nil {
+		slog.Error("Failed to get transaction events", "tx_hash", txHash, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve transaction events")
+		return
+	}
+	hidden := h.hidesContract(r)
+	events = slices.DeleteFunc(events, func(event *governor.GovernorEvent) bool { return hidden(event.ContractId) })
+	if len(events) == 0 {
+		respondError(w, http.StatusNotFound, "transaction not found")
+		return
+	}
+
+	response := TransactionEventsResponse{TxHash: txHash, Events: withEventXdr(w, r, events)}
+	vote, err := h.store.GetVote(r.Context(), txHash)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.Error("Failed to get transaction vote", "tx_hash", txHash, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve transaction events")
+		return
+	}
+	if vote != nil && !hidden(vote.ContractId) {
+		response.Vote = h.tokens.get(r.Context()).newVoteResponse(vote)
+	}
+
+	var keys []string
+	for _, event := range events {
+		if governor.IsDelegationEventType(event.EventType) {
+			continue
+		}
+		if key := governor.EncodeProposalKey(event.ContractId, event.ProposalId); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	proposals := []*governor.Proposal{}
+	if len(keys) > 0 {
+		proposals, err = h.store.GetProposalsByKeys(r.Context(), keys)
+		if err != nil {
+			slog.Error("Failed to get transaction proposals", "tx_hash", txHash, "error", err)
+			respondError(w, storeErrorStatus(err), "failed to retrieve transaction events")
+			return
+		}
+	}
+	response.Proposals = h.ledgerClock(r.Context()).newProposalResponses(proposals)
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// handleGetContracts lists the governors in the contracts registry, with their last activity and proposal count.
+// Blocked contracts are never listed, and contracts that were not reviewed only with ?include_unreviewed=true.
+func (h *Handler) handleGetContracts(w http.ResponseWriter, r *http.Request) {
+	contracts, err := h.store.GetContracts(r.Context())
+	if err != nil {
+		slog.Error("Failed to get contracts", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve contracts")
+		return
+	}
+	unreviewed := includeUnreviewed(r)
+	contracts = slices.DeleteFunc(contracts, func(contract *db.Contract) bool {
+		return contract.Blocked || (!contract.Reviewed && !unreviewed)
+	})
+	contracts = truncateList(w, r, contracts, h.maxListRows)
+
+	respondJSON(w, http.StatusOK, newContractResponses(contracts, h.indexStatus.now().Unix()))
+}
+
+// withEventXdr returns the events with their raw contract event XDR if the request asks for it, with
+// ?include_xdr=true or an Accept header of application/xdr, and without it otherwise. Events indexed before the XDR
+// was stored don't have it until they are backfilled.
+func withEventXdr(w http.ResponseWriter, r *http.Request, events []*governor.GovernorEvent) []*governor.GovernorEvent {
+	w.Header().Add("Vary", "Accept")
+	if r.URL.Query().Get("include_xdr") == "true" {
+		return events
+	}
+	for accept := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "application/xdr" {
+			return events
+		}
+	}
+	for _, event := range events {
+		event.EventXdr = ""
+	}
+	return events
+}
+
+// DelegatesResponse is the delegation state of an address in a votes contract
+type DelegatesResponse struct {
+	// The delegate changes that currently delegate to the address
+	Delegators []*governor.Delegation `json:"delegators"`
+	// Every delegate change made by the address, or to or from it as a delegate
+	History []*governor.Delegation `json:"history"`
+	// Warning names the lists truncated to API_MAX_LIST_ROWS, as the Warning header does. It is omitted if neither
+	// list was truncated.
+	Warning string `json:"warning,omitempty"`
+}
+
+// handleGetDelegates retrieves the current delegators of an address and its delegation history
+func (h *Handler) handleGetDelegates(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	address := r.PathValue("address")
+
+	delegators, err := h.store.GetDelegators(r.Context(), contractId, address)
+	if err != nil {
+		slog.Error("Failed to get delegators", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve delegators")
+		return
+	}
+	history, err := h.store.GetDelegationHistory(r.Context(), contractId, address)
+	if err != nil {
+		slog.Error("Failed to get delegation history", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve delegation history")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, events)
+	delegators, delegatorsWarning := truncate("delegators", delegators, h.maxListRows)
+	history, historyWarning := truncate("history", history, h.maxListRows)
+	respondJSON(w, http.StatusOK, DelegatesResponse{
+		Delegators: delegators,
+		History:    history,
+		Warning:    markTruncated(w, r, delegatorsWarning, historyWarning),
+	})
 }
 
-// ErrorResponse represents an API error response
+// finishCSV completes a CSV export. Errors before any row was written are reported as an error response, and errors
+// after are logged, as the response status was already sent.
+func finishCSV(w http.ResponseWriter, stream *csvStream, err error, name string) {
+	if err != nil && !stream.started {
+		slog.Error("Failed to export "+name, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve "+name)
+		return
+	}
+	if err == nil {
+		err = stream.close()
+	}
+	if err != nil {
+		slog.Error("Failed to stream "+name+" export", "error", err)
+	}
+}
+
+// parseLimit returns the "limit" query parameter, from 1 to MAX_LIMIT, or DEFAULT_LIMIT if it is not set
+func parseLimit(r *http.Request) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return DEFAULT_LIMIT, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > MAX_LIMIT {
+		return 0, fmt.Errorf("invalid limit, must be from 1 to %d", MAX_LIMIT)
+	}
+	return limit, nil
+}
+
+// parseSort returns the "sort" query parameter, a field optionally followed by ":asc" or ":desc", and ascending if no
+// direction is given. The field must be one of fields. The zero db.Sort is returned if the parameter is not set.
+func parseSort(r *http.Request, fields []string) (db.Sort, error) {
+	sortStr := r.URL.Query().Get("sort")
+	if sortStr == "" {
+		return db.Sort{}, nil
+	}
+	field, direction, _ := strings.Cut(sortStr, ":")
+	if !slices.Contains(fields, field) {
+		return db.Sort{}, fmt.Errorf("invalid sort, must be one of %s", strings.Join(fields, ", "))
+	}
+	switch direction {
+	case "", "asc":
+		return db.Sort{Field: field}, nil
+	case "desc":
+		return db.Sort{Field: field, Desc: true}, nil
+	}
+	return db.Sort{}, errors.New("invalid sort direction, must be asc or desc")
+}
+
+// ErrorResponse represents an API error response. Code is set for errors clients are expected to handle.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
 // respondJSON writes a JSON response
@@ -178,7 +1161,21 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
+// storeErrorStatus returns the response status for a failed store call. Database timeouts, and store calls cut off
+// by the request's deadline, are reported as 503 so clients know the request can be retried.
+func storeErrorStatus(err error) int {
+	if errors.Is(err, db.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
 // respondError writes an error response
 func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, ErrorResponse{Error: message})
 }
+
+// respondErrorCode writes an error response with an error code
+func respondErrorCode(w http.ResponseWriter, status int, code string, message string) {
+	respondJSON(w, status, ErrorResponse{Error: message, Code: code})
+}
@@ -0,0 +1,3800 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+)
+
+const (
+	testContractId = "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	testAdminToken = "test-admin-token"
+)
+
+var errDb = errors.New("db down")
+
+// testProposalEvents are the events of proposal 3, with a large vote cast just before voting closed
+var testProposalEvents = []*governor.GovernorEvent{
+	{
+		EventId:    "0000000429496729600-0000000000",
+		ContractId: testContractId,
+		EventType:  "proposal_created",
+		ProposalId: 3,
+		EventData:  `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"","vote_start":100,"vote_end":130}`,
+		TxHash:     "tx1",
+		LedgerSeq:  100,
+	},
+	{
+		EventId:    "0000000472446402560-0000000000",
+		ContractId: testContractId,
+		EventType:  "vote_cast",
+		ProposalId: 3,
+		EventData:  `{"voter":"GA","support":0,"amount":"5"}`,
+		TxHash:     "tx2",
+		LedgerSeq:  110,
+	},
+	{
+		EventId:    "0000000511101108224-0000000000",
+		ContractId: testContractId,
+		EventType:  "vote_cast",
+		ProposalId: 3,
+		EventData:  `{"voter":"GB","support":1,"amount":"1000000"}`,
+		TxHash:     "tx3",
+		LedgerSeq:  119,
+	},
+	{
+		EventId:    "0000000558345748480-0000000000",
+		ContractId: testContractId,
+		EventType:  "proposal_voting_closed",
+		ProposalId: 3,
+		EventData:  `{"status":1,"eta":140,"final_votes":{"for":"1000000","against":"5","abstain":"0"}}`,
+		TxHash:     "tx4",
+		LedgerSeq:  130,
+	},
+}
+
+func TestHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		store      *mockStore
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:   "health store error",
+			method: http.MethodGet,
+			path:   "/health",
+			store: &mockStore{
+				getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 0, 0, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to get health status",
+		},
+		{
+			name:   "transaction events store error",
+			method: http.MethodGet,
+			path:   "/transactions/cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970/events",
+			store: &mockStore{
+				getEventsByTxHash: func(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve transaction events",
+		},
+		{
+			name:   "coverage gaps store error",
+			method: http.MethodGet,
+			path:   "/status/gaps",
+			store: &mockStore{
+				getCoverageGaps: func(ctx context.Context) ([]*db.CoverageGap, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve coverage gaps",
+		},
+		{
+			name:   "get proposal store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3",
+			store: &mockStore{
+				getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposal",
+		},
+		{
+			name:   "get proposal not found",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3",
+			store: &mockStore{
+				getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+					return nil, db.ErrNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantError:  "proposal not found",
+		},
+		{
+			name:       "get proposal invalid at_ledger",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals/3?at_ledger=-1",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid at_ledger",
+		},
+		{
+			name:   "get proposal at ledger store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3?at_ledger=100",
+			store: &mockStore{
+				getEventsByProposal: func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposal",
+		},
+		{
+			name:   "get proposal at ledger not found",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3?at_ledger=100",
+			store: &mockStore{
+				getEventsByProposal: func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+					return nil, nil
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantError:  "proposal not found",
+		},
+		{
+			name:   "get proposal at ledger before created",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3?at_ledger=99",
+			store: &mockStore{
+				getEventsByProposal: func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+					return testProposalEvents, nil
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantError:  "proposal not created at ledger",
+		},
+		{
+			name:   "get proposal at ledger pruned history",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3?at_ledger=200",
+			store: &mockStore{
+				getEventsByProposal: func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+					return testProposalEvents[1:], nil
+				},
+			},
+			wantStatus: http.StatusGone,
+			wantError:  "proposal history is incomplete, unable to reconstruct proposal",
+		},
+		{
+			name:   "get proposal content not found",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/content",
+			store: &mockStore{
+				getProposalContent: func(ctx context.Context, proposalKey string) (*governor.ProposalContent, error) {
+					return nil, db.ErrNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantError:  "proposal content not found",
+		},
+		{
+			name:   "get proposal content store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/content",
+			store: &mockStore{
+				getProposalContent: func(ctx context.Context, proposalKey string) (*governor.ProposalContent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposal content",
+		},
+		{
+			name:   "get proposals store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals",
+			store: &mockStore{
+				getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposals",
+		},
+		{
+			name:   "get proposals store timeout",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals",
+			store: &mockStore{
+				getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+					return nil, fmt.Errorf("get proposals for contract %s: %w", contractId, db.ErrTimeout)
+				},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantError:  "failed to retrieve proposals",
+		},
+		{
+			name:   "get vote not found",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes/GA",
+			store: &mockStore{
+				getVoteByProposalAndVoter: func(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error) {
+					return nil, fmt.Errorf("get vote: %w", db.ErrNotFound)
+				},
+			},
+			wantStatus: http.StatusNotFound,
+			wantError:  "vote not found",
+		},
+		{
+			name:   "get vote store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes/GA",
+			store: &mockStore{
+				getVoteByProposalAndVoter: func(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve vote",
+		},
+		{
+			name:       "get proposals by keys without keys",
+			method:     http.MethodGet,
+			path:       "/proposals?keys=,",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "keys is required",
+		},
+		{
+			name:       "get proposals by keys too many keys",
+			method:     http.MethodGet,
+			path:       "/proposals?keys=" + strings.Repeat(testContractId+"-1,", MAX_PROPOSAL_KEYS+1),
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "too many keys, at most 100 are allowed",
+		},
+		{
+			name:   "get proposals by keys store error",
+			method: http.MethodGet,
+			path:   "/proposals?keys=" + testContractId + "-1",
+			store: &mockStore{
+				getProposalsByKeys: func(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposals",
+		},
+		{
+			name:   "get active proposals invalid cursor",
+			method: http.MethodGet,
+			path:   "/proposals/active?cursor=bad",
+			store: &mockStore{
+				getProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+					return nil, fmt.Errorf("get proposals by status: %w", db.ErrInvalidCursor)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid cursor",
+		},
+		{
+			name:   "get active proposals store error",
+			method: http.MethodGet,
+			path:   "/proposals/active",
+			store: &mockStore{
+				getProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve active proposals",
+		},
+		{
+			name:   "export proposals store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals?format=csv",
+			store: &mockStore{
+				eachProposalByContractId: func(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error {
+					return errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve proposals",
+		},
+		{
+			name:   "get votes store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes",
+			store: &mockStore{
+				getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve votes",
+		},
+		{
+			name:   "get vote summary store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes/summary",
+			store: &mockStore{
+				getVoteSummary: func(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve vote summary",
+		},
+		{
+			name:       "get vote series invalid bucket",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals/3/votes/series?bucket=hour",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid bucket",
+		},
+		{
+			name:   "get vote series too many buckets",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes/series?bucket=60",
+			store: &mockStore{
+				getVoteSeries: func(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+					return nil, fmt.Errorf("vote series: %w", governor.ErrTooManyBuckets)
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "too many buckets, use a larger bucket",
+		},
+		{
+			name:   "get vote series store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/proposals/3/votes/series",
+			store: &mockStore{
+				getVoteSeries: func(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve vote series",
+		},
+		{
+			name:   "get events store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/events",
+			store: &mockStore{
+				filterEventsByContractId: func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve events",
+		},
+		{
+			name:       "get proposals invalid sort field",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals?sort=title",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid sort, must be one of proposal_id, vote_end, status",
+		},
+		{
+			name:       "get proposals invalid view",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals?view=compact",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid view, must be full or summary",
+		},
+		{
+			name:       "get proposals invalid action type",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals?action_type=mint",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid action_type, must be one of calldata, upgrade, settings, council, snapshot, unknown",
+		},
+		{
+			name:       "get votes invalid sort direction",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/proposals/1/votes?sort=amount:up",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid sort direction, must be asc or desc",
+		},
+		{
+			name:       "get events invalid sort field",
+			method:     http.MethodGet,
+			path:       "/" + testContractId + "/events?sort=ledger_seq",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid sort, must be one of event_id",
+		},
+		{
+			name:       "get recent events invalid limit",
+			method:     http.MethodGet,
+			path:       "/events/recent?limit=201",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid limit, must be from 1 to 200",
+		},
+		{
+			name:   "get recent events store error",
+			method: http.MethodGet,
+			path:   "/events/recent",
+			store: &mockStore{
+				getRecentEvents: func(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve recent events",
+		},
+		{
+			name:   "get contracts store error",
+			method: http.MethodGet,
+			path:   "/contracts",
+			store: &mockStore{
+				getContracts: func(ctx context.Context) ([]*db.Contract, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve contracts",
+		},
+		{
+			name:   "get delegates store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/delegates/GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			store: &mockStore{
+				getDelegators: func(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve delegators",
+		},
+		{
+			name:   "get delegation history store timeout",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/delegates/GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			store: &mockStore{
+				getDelegators: func(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error) {
+					return nil, nil
+				},
+				getDelegationHistory: func(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error) {
+					return nil, db.ErrTimeout
+				},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantError:  "failed to retrieve delegation history",
+		},
+		{
+			name:   "delete contract block error",
+			method: http.MethodDelete,
+			path:   "/admin/contracts/" + testContractId + "?block=true",
+			store: &mockStore{
+				blockContract: func(ctx context.Context, contractId string, reason string, createdAt int64) error { return errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to block contract",
+		},
+		{
+			name:   "delete contract data error",
+			method: http.MethodDelete,
+			path:   "/admin/contracts/" + testContractId,
+			store: &mockStore{
+				deleteContractData: func(ctx context.Context, contractId string) (map[string]int64, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to delete contract data",
+		},
+		{
+			name:   "get failed events store error",
+			method: http.MethodGet,
+			path:   "/admin/contracts/" + testContractId + "/failed-events",
+			store: &mockStore{
+				getFailedEventsByContractId: func(ctx context.Context, contractId string) ([]*governor.FailedEvent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve failed events",
+		},
+		{
+			name:   "list failed events store error",
+			method: http.MethodGet,
+			path:   "/admin/failed-events",
+			store: &mockStore{
+				getFailedEvents: func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+					return nil, errDb
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve failed events",
+		},
+		{
+			name:   "get blocklist store error",
+			method: http.MethodGet,
+			path:   "/admin/blocklist",
+			store: &mockStore{
+				getBlockedContracts: func(ctx context.Context) ([]*db.BlockedContract, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve blocklist",
+		},
+		{
+			name:   "get contract store error",
+			method: http.MethodGet,
+			path:   "/" + testContractId + "/summary",
+			store: &mockStore{
+				getContract: func(ctx context.Context, contractId string) (*db.Contract, error) { return nil, errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to retrieve contract",
+		},
+		{
+			name:   "unblock contract store error",
+			method: http.MethodDelete,
+			path:   "/admin/blocklist/" + testContractId,
+			store: &mockStore{
+				unblockContract: func(ctx context.Context, contractId string) error { return errDb },
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "failed to unblock contract",
+		},
+		{
+			name:       "reindex invalid before ledger",
+			method:     http.MethodPost,
+			path:       "/admin/contracts/" + testContractId + "/reindex?snapshot=true&before_ledger=abc",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid before_ledger",
+		},
+		{
+			name:       "reindex before ledger without snapshot",
+			method:     http.MethodPost,
+			path:       "/admin/contracts/" + testContractId + "/reindex?before_ledger=1170234",
+			store:      &mockStore{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid before_ledger",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newHandler(tt.store, nil, &Config{AdminTokens: []string{testAdminToken}})
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(ErrorResponse{Error: tt.wantError}, resp); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestReindexConflict verifies a reindex job is refused while another is running, as they would clear each other's
+// rebuilding flag
+func TestReindexConflict(t *testing.T) {
+	handler := newHandler(&mockStore{}, nil, &Config{AdminTokens: []string{testAdminToken}})
+	otherId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	running, ok := handler.jobs.startExclusive("reindex", otherId)
+	if !ok {
+		t.Fatal("failed to start the first reindex job")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/contracts/"+testContractId+"/reindex", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := "reindex job " + running.Id + " is already running for contract " + otherId; resp.Error != want {
+		t.Errorf("got error %q, want %q", resp.Error, want)
+	}
+
+	// other kinds of jobs still run, and a reindex job starts once the running one finishes
+	if _, ok := handler.jobs.startExclusive("reprocess", ""); !ok {
+		t.Error("expected a job of another kind to start")
+	}
+	handler.jobs.finish(running.Id, nil)
+	if _, ok := handler.jobs.startExclusive("reindex", testContractId); !ok {
+		t.Error("expected a reindex job to start once the running one finished")
+	}
+}
+
+// TestJobRegistryEviction verifies the oldest finished jobs are dropped once more than MAX_FINISHED_JOBS have
+// finished, while running jobs are kept
+func TestJobRegistryEviction(t *testing.T) {
+	jobs := newJobRegistry()
+	running := jobs.start("reindex", testContractId)
+	var finished []Job
+	for range MAX_FINISHED_JOBS + 1 {
+		job := jobs.start("reprocess", "")
+		jobs.finish(job.Id, nil)
+		finished = append(finished, job)
+	}
+	// finishing a job again doesn't count it twice
+	jobs.finish(finished[len(finished)-1].Id, nil)
+
+	if _, ok := jobs.get(finished[0].Id); ok {
+		t.Error("expected the first finished job to be dropped")
+	}
+	for _, job := range finished[1:] {
+		if got, ok := jobs.get(job.Id); !ok || got.Status != JobSucceeded {
+			t.Fatalf("get(%s) = %+v, %v, want the finished job kept", job.Id, got, ok)
+		}
+	}
+	if got, ok := jobs.get(running.Id); !ok || got.Status != JobRunning {
+		t.Errorf("get(%s) = %+v, %v, want the running job kept", running.Id, got, ok)
+	}
+	if len(jobs.jobs) != MAX_FINISHED_JOBS+1 {
+		t.Errorf("got %d jobs, want %d", len(jobs.jobs), MAX_FINISHED_JOBS+1)
+	}
+}
+
+func TestGetFailedEvents(t *testing.T) {
+	events := []*governor.FailedEvent{
+		{EventId: "0005025687261941760-0000000000", ContractId: testContractId, EventType: "vote_cast", Reason: governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION},
+		{EventId: "0005025687261945856-0000000000", ContractId: testContractId, EventType: "proposal_queued", Reason: governor.FAILED_REASON_UNKNOWN_EVENT_TYPE},
+	}
+	store := &mockStore{
+		getFailedEventsByContractId: func(ctx context.Context, contractId string) ([]*governor.FailedEvent, error) {
+			return slices.Clone(events), nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+
+	tests := []struct {
+		query string
+		want  []*governor.FailedEvent
+	}{
+		{query: "", want: events},
+		{query: "?reason=" + governor.FAILED_REASON_UNKNOWN_EVENT_TYPE, want: events[1:]},
+		{query: "?reason=other", want: []*governor.FailedEvent{}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/contracts/"+testContractId+"/failed-events"+tt.query, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var got []*governor.FailedEvent
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("query %q mismatch (-want +got):\n%s", tt.query, diff)
+		}
+	}
+}
+
+func TestListFailedEvents(t *testing.T) {
+	events := []*governor.FailedEvent{
+		{EventId: "0005025687261941760-0000000000", ContractId: testContractId, EventType: "vote_cast", Reason: governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION},
+		{EventId: "0005025687261945856-0000000000", ContractId: testContractId, EventType: "proposal_queued", Reason: governor.FAILED_REASON_UNKNOWN_EVENT_TYPE},
+		{EventId: "0005025687261950000-0000000000", ContractId: testContractId, EventType: "vote_cast", Reason: governor.FAILED_REASON_INVALID_AMOUNT, Attempts: 2},
+	}
+	var gotFilter db.FailedEventFilter
+	store := &mockStore{
+		getFailedEvents: func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+			gotFilter = filter
+			i := 0
+			for i < len(events) && events[i].EventId <= afterEventId {
+				i++
+			}
+			return slices.Clone(events[i:min(i+limit, len(events))]), nil
+		},
+		countFailedEvents: func(ctx context.Context, filter db.FailedEventFilter) (int, error) { return len(events), nil },
+	}
+	handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+
+	get := func(query string) Page[*governor.FailedEvent] {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/failed-events"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %q: expected status %d, got %d", query, http.StatusOK, rec.Code)
+		}
+		var page Page[*governor.FailedEvent]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return page
+	}
+
+	page := get("?contract_id=" + testContractId + "&reason=invalid_amount&error=negative&unprocessed=true&limit=2")
+	wantFilter := db.FailedEventFilter{ContractId: testContractId, Reason: "invalid_amount", ErrorContains: "negative", Unprocessed: true}
+	if diff := cmp.Diff(wantFilter, gotFilter); diff != "" {
+		t.Errorf("filter mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(events[:2], page.Data); diff != "" {
+		t.Errorf("first page mismatch (-want +got):\n%s", diff)
+	}
+	if !page.Pagination.HasMore || page.Pagination.NextCursor == nil || *page.Pagination.NextCursor != events[1].EventId || page.Pagination.Total != 3 {
+		t.Fatalf("got pagination %+v, want a next page after %s of 3 failed events", page.Pagination, events[1].EventId)
+	}
+
+	page = get("?limit=2&cursor=" + *page.Pagination.NextCursor)
+	if diff := cmp.Diff(events[2:], page.Data); diff != "" {
+		t.Errorf("second page mismatch (-want +got):\n%s", diff)
+	}
+	if page.Pagination.HasMore || page.Pagination.NextCursor != nil {
+		t.Errorf("got pagination %+v, want the last page", page.Pagination)
+	}
+}
+
+func TestGetFailedTxEvents(t *testing.T) {
+	events := []*governor.FailedTxEvent{
+		{EventId: "0005025687261941760-0000000000", ContractId: testContractId, ProposalId: 1, EventType: "vote_cast", EventData: `{}`, ResultCode: "TxFailed"},
+		{EventId: "0005025687261945856-0000000000", ContractId: testContractId, ProposalId: 1, EventType: "vote_cast", EventData: `{}`, ResultCode: "TxFailed"},
+	}
+	var gotContractId string
+	var gotUnreviewed bool
+	store := &mockStore{
+		getFailedTxEvents: func(ctx context.Context, contractId string, includeUnreviewed bool, afterEventId string, limit int) ([]*governor.FailedTxEvent, error) {
+			gotContractId, gotUnreviewed = contractId, includeUnreviewed
+			i := 0
+			for i < len(events) && events[i].EventId <= afterEventId {
+				i++
+			}
+			return slices.Clone(events[i:min(i+limit, len(events))]), nil
+		},
+		countFailedTxEvents: func(ctx context.Context, contractId string, includeUnreviewed bool) (int, error) {
+			return len(events), nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	get := func(query string) Page[*governor.FailedTxEvent] {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/analytics/failed-tx-events"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %q: expected status %d, got %d", query, http.StatusOK, rec.Code)
+		}
+		var page Page[*governor.FailedTxEvent]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return page
+	}
+
+	page := get("?contract_id=" + testContractId + "&limit=1")
+	if gotContractId != testContractId || gotUnreviewed {
+		t.Errorf("got contract id %q including unreviewed contracts %v, want %q without", gotContractId, gotUnreviewed, testContractId)
+	}
+	if diff := cmp.Diff(events[:1], page.Data); diff != "" {
+		t.Errorf("first page mismatch (-want +got):\n%s", diff)
+	}
+	if !page.Pagination.HasMore || page.Pagination.NextCursor == nil || *page.Pagination.NextCursor != events[0].EventId || page.Pagination.Total != 2 {
+		t.Fatalf("got pagination %+v, want a next page after %s of 2 events", page.Pagination, events[0].EventId)
+	}
+
+	page = get("?limit=1&include_unreviewed=true&cursor=" + *page.Pagination.NextCursor)
+	if !gotUnreviewed {
+		t.Error("expected unreviewed contracts to be included with ?include_unreviewed=true")
+	}
+	if diff := cmp.Diff(events[1:], page.Data); diff != "" {
+		t.Errorf("second page mismatch (-want +got):\n%s", diff)
+	}
+	if page.Pagination.HasMore || page.Pagination.NextCursor != nil {
+		t.Errorf("got pagination %+v, want the last page", page.Pagination)
+	}
+}
+
+func TestReprocessFailedEvents(t *testing.T) {
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	store := db.NewStore(sqlDb)
+	// an event that can't be parsed, from a contract other than the one reprocessed
+	for i, contractId := range []string{testContractId, "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"} {
+		eventId, err := governor.EncodeEventId(5025687261941760, int32(i))
+		if err != nil {
+			t.Fatalf("failed to encode event id: %v", err)
+		}
+		err = store.InsertFailedEvent(t.Context(), &governor.FailedEvent{
+			EventId:    eventId,
+			ContractId: contractId,
+			EventType:  "vote_cast",
+			Reason:     governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+			Error:      "schema version 3: unknown governor event schema version",
+			EventXdr:   "AAAA",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := newHandler(store, indexer.NewIndexer(store), &Config{AdminTokens: []string{testAdminToken}})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/failed-events/reprocess?contract_id="+testContractId+"&error=schema+version+3", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	var job Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.Kind != "reprocess" || job.Status != JobRunning {
+		t.Fatalf("got job %+v, want a running reprocess job", job)
+	}
+
+	// the job runs in the background, so poll it until it finishes
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == JobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		req := httptest.NewRequest(http.MethodGet, "/admin/jobs/"+job.Id, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		job = Job{}
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+	}
+	if job.Status != JobSucceeded || job.Processed != 1 || job.Total != 1 {
+		t.Fatalf("got job %+v, want a succeeded job that processed 1 of 1 failed events", job)
+	}
+	want := map[string]any{"reprocessed": float64(0), "failed": float64(1), "skipped": float64(0)}
+	if diff := cmp.Diff(want, job.Result); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+
+	failed, err := store.GetFailedEvents(t.Context(), db.FailedEventFilter{}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 || failed[0].ContractId != testContractId || failed[0].Attempts != 1 || failed[1].Attempts != 0 {
+		t.Errorf("got failed events %+v, want one attempt at the event from %s", failed, testContractId)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	store := db.NewStore(sqlDb)
+	// an open proposal, with its event, and an event of a failed transaction
+	ctx := t.Context()
+	event := &governor.GovernorEvent{EventId: "0005025687261941760-0000000000", ContractId: testContractId, EventType: "proposal_created", ProposalId: 1, EventData: `{}`, TxHash: "tx1", LedgerSeq: 1170134, LedgerCloseTime: 1761053041}
+	if err := store.InsertEvent(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpsertContractActivity(ctx, testContractId, event.EventId, event.LedgerSeq, event.LedgerCloseTime, true); err != nil {
+		t.Fatal(err)
+	}
+	proposal := &governor.Proposal{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, VoteEnd: 1170300, VotesFor: "0", VotesAgainst: "0", VotesAbstain: "0"}
+	if err := store.UpsertProposal(ctx, proposal); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertFailedTxEvent(ctx, governor.NewFailedTxEvent(&governor.GovernorEvent{EventId: "0005025687261945856-0000000000", ContractId: testContractId, EventType: "vote_cast", EventData: `{}`, TxHash: "tx2", LedgerSeq: 1170134}, "TxFailed")); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{AdminTokens: []string{testAdminToken}}
+	config.DB.BlocklistRefreshInterval = 3600
+	handler := newHandler(store, indexer.NewIndexer(store), config)
+
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	// listed returns true if the contract is in a list across contracts
+	listed := func(path string) bool {
+		t.Helper()
+		rec := serve(http.MethodGet, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d", path, http.StatusOK, rec.Code)
+		}
+		return strings.Contains(rec.Body.String(), testContractId)
+	}
+	lists := []string{"/v1/contracts", "/v1/events/recent", "/v1/proposals/active", "/v1/proposals?keys=" + proposal.ProposalKey, "/v1/analytics/failed-tx-events"}
+	for _, path := range lists {
+		if !listed(path) {
+			t.Fatalf("GET %s: contract not listed before blocking", path)
+		}
+	}
+	if !listed("/v1/transactions/" + event.TxHash + "/events") {
+		t.Fatalf("transaction events not found before blocking")
+	}
+
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/proposals"); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d before blocking, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/admin/blocklist/not-a-contract"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d blocking an invalid contract id, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/admin/blocklist/"+testContractId+"?reason=spam"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d blocking the contract, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	// the cached blocklist is reloaded as soon as it changes, and every route of the contract is refused
+	for _, path := range []string{"/v1/" + testContractId + "/proposals", "/" + testContractId + "/proposals/3/votes", "/v1/" + testContractId + "/events"} {
+		rec := serve(http.MethodGet, path)
+		if rec.Code != http.StatusGone {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusGone, rec.Code)
+		}
+	}
+	// lists across contracts leave the contract out
+	for _, path := range lists {
+		if listed(path) {
+			t.Errorf("GET %s: blocked contract listed", path)
+		}
+	}
+	// a transaction that only emitted the contract's events is not found
+	if rec := serve(http.MethodGet, "/v1/transactions/"+event.TxHash+"/events"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a transaction of the blocked contract, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec := serve(http.MethodGet, "/v1/admin/blocklist")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d listing the blocklist, got %d", http.StatusOK, rec.Code)
+	}
+	var blocked []BlockedContractResponse
+	if err := json.NewDecoder(rec.Body).Decode(&blocked); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(blocked) != 1 || blocked[0].ContractId != testContractId || blocked[0].Reason != "spam" || blocked[0].CreatedAt == 0 {
+		t.Errorf("got blocklist %+v, want %s blocked for spam", blocked, testContractId)
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/admin/blocklist/"+testContractId); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d unblocking the contract, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/v1/admin/blocklist/"+testContractId); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d unblocking a contract that is not blocked, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/proposals"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d after unblocking, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestReviewContract(t *testing.T) {
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	store := db.NewStore(sqlDb)
+	// a contract registered by contract discovery, with an open proposal
+	if err := store.UpsertContractActivity(t.Context(), testContractId, "0000004294967296000-0000000000", 1000, 1761053046, false); err != nil {
+		t.Fatal(err)
+	}
+	proposal := &governor.Proposal{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, VoteEnd: 2000, VotesFor: "0", VotesAgainst: "0", VotesAbstain: "0"}
+	if err := store.UpsertProposal(t.Context(), proposal); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{AdminTokens: []string{testAdminToken}}
+	config.ReviewRefreshInterval = 3600
+	handler := newHandler(store, indexer.NewIndexer(store), config)
+
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	activeCount := func(path string) int {
+		t.Helper()
+		rec := serve(http.MethodGet, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d", path, http.StatusOK, rec.Code)
+		}
+		var page Page[*ProposalResponse]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return len(page.Data)
+	}
+
+	// the contract's data is hidden unless requested
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/proposals"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unreviewed contract, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/proposals?include_unreviewed=true"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d including unreviewed contracts, got %d", http.StatusOK, rec.Code)
+	}
+	if got := activeCount("/v1/proposals/active"); got != 0 {
+		t.Errorf("got %d active proposals, want the unreviewed contract's proposal hidden", got)
+	}
+	if got := activeCount("/v1/proposals/active?include_unreviewed=true"); got != 1 {
+		t.Errorf("got %d active proposals including unreviewed contracts, want 1", got)
+	}
+
+	if rec := serve(http.MethodPut, "/v1/admin/contracts/not-a-contract/reviewed"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d reviewing an invalid contract id, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/admin/contracts/CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC/reviewed"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d reviewing an unregistered contract, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/admin/contracts/"+testContractId+"/reviewed"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d reviewing the contract, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	// the cached unreviewed contracts are reloaded as soon as a contract is reviewed
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/proposals"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d after review, got %d", http.StatusOK, rec.Code)
+	}
+	if got := activeCount("/v1/proposals/active"); got != 1 {
+		t.Errorf("got %d active proposals after review, want 1", got)
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/admin/contracts/"+testContractId+"/reviewed"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d unreviewing the contract, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/v1/"+testContractId+"/summary"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d after unreviewing, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// TestReviewFailClosed verifies requests hiding unreviewed contracts are refused until the unreviewed contracts are
+// first loaded, rather than serving the data of every contract
+func TestReviewFailClosed(t *testing.T) {
+	loadErr := errors.New("database unavailable")
+	store := &mockStore{
+		getUnreviewedContractIds: func(ctx context.Context) ([]string, error) { return nil, loadErr },
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return nil, nil
+		},
+		getRecentEvents: func(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+			return nil, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{ReviewRefreshInterval: 3600})
+
+	serve := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	paths := []string{"/v1/" + testContractId + "/proposals", "/v1/events/recent"}
+	for _, path := range paths {
+		if got := serve(path); got != http.StatusServiceUnavailable {
+			t.Errorf("GET %s: expected status %d before the unreviewed contracts are loaded, got %d", path, http.StatusServiceUnavailable, got)
+		}
+		if got := serve(path + "?include_unreviewed=true"); got != http.StatusOK {
+			t.Errorf("GET %s: expected status %d including unreviewed contracts, got %d", path, http.StatusOK, got)
+		}
+	}
+
+	loadErr = nil
+	for _, path := range paths {
+		if got := serve(path); got != http.StatusOK {
+			t.Errorf("GET %s: expected status %d once the unreviewed contracts are loaded, got %d", path, http.StatusOK, got)
+		}
+	}
+}
+
+func TestContractMetadata(t *testing.T) {
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	store := db.NewStore(sqlDb)
+	handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+	otherId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	for _, contractId := range []string{testContractId, otherId} {
+		if err := store.UpsertContractActivity(t.Context(), contractId, "0000004294967296000-0000000000", 1000, 1761053046, true); err != nil {
+			t.Fatalf("failed to upsert contract activity: %v", err)
+		}
+	}
+
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	getMetadata := func(contractId string) *ContractMetadataResponse {
+		t.Helper()
+		rec := serve(http.MethodGet, "/v1/"+contractId+"/summary", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d getting the contract, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var contract ContractResponse
+		if err := json.NewDecoder(rec.Body).Decode(&contract); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return withoutUpdatedAt(t, contract.Metadata)
+	}
+
+	// unknown contracts have null metadata
+	if metadata := getMetadata(testContractId); metadata != nil {
+		t.Errorf("got metadata %+v before setting it, want nil", metadata)
+	}
+	if rec := serve(http.MethodGet, "/v1/CUNKNOWN/summary", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unregistered contract, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	invalid := []struct {
+		path string
+		body string
+	}{
+		{"/v1/admin/contracts/not-a-contract/metadata", `{"name":"Blend DAO"}`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"name":"Blend DAO"`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"name":"Blend DAO","icon":"https://blend.capital/icon.png"}`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"description":"no name"}`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"name":"Blend DAO","website":"blend.capital"}`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"name":"Blend DAO","links":{"x":"javascript:alert(1)"}}`},
+		{"/v1/admin/contracts/" + testContractId + "/metadata", `{"name":"` + strings.Repeat("a", MAX_METADATA_BYTES) + `"}`},
+	}
+	for _, tt := range invalid {
+		if rec := serve(http.MethodPut, tt.path, tt.body); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s with %.40s: expected status %d, got %d", tt.path, tt.body, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	body := `{"name":"Blend DAO","description":"Governs the Blend protocol","icon_url":"https://blend.capital/icon.png",
+		"website":"https://blend.capital","links":{"x":"https://x.com/blend"}}`
+	rec := serve(http.MethodPut, "/v1/admin/contracts/"+testContractId+"/metadata", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d setting metadata, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	metadata := getMetadata(testContractId)
+	want := &ContractMetadataResponse{
+		Name:        "Blend DAO",
+		Description: "Governs the Blend protocol",
+		IconUrl:     "https://blend.capital/icon.png",
+		Website:     "https://blend.capital",
+		Links:       map[string]string{"x": "https://x.com/blend"},
+	}
+	if diff := cmp.Diff(want, metadata); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	// the file only bootstraps contracts without metadata
+	path := filepath.Join(t.TempDir(), "contracts.json")
+	file := `{"` + testContractId + `":{"name":"From file"},"` + otherId + `":{"name":"Other DAO","website":"https://other.example"}}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadContractMetadataFile(t.Context(), store, path); err != nil {
+		t.Fatalf("LoadContractMetadataFile() error = %v", err)
+	}
+	rec = serve(http.MethodGet, "/v1/contracts", "")
+	var contracts []*ContractResponse
+	if err := json.NewDecoder(rec.Body).Decode(&contracts); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	var got []*ContractMetadataResponse
+	for _, contract := range contracts {
+		got = append(got, withoutUpdatedAt(t, contract.Metadata))
+	}
+	wantAll := []*ContractMetadataResponse{want, {Name: "Other DAO", Website: "https://other.example", Links: map[string]string{}}}
+	if diff := cmp.Diff(wantAll, got); diff != "" {
+		t.Errorf("contracts metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	// invalid files are rejected without setting any metadata
+	if err := os.WriteFile(path, []byte(`{"`+otherId+`":{"name":"Other"},"CUNKNOWN":{"name":"Unknown"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadContractMetadataFile(t.Context(), store, path); err == nil {
+		t.Errorf("LoadContractMetadataFile() with an invalid contract id succeeded, want error")
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/admin/contracts/"+testContractId+"/metadata", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d deleting metadata, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/v1/admin/contracts/"+testContractId+"/metadata", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d deleting missing metadata, got %d", http.StatusNotFound, rec.Code)
+	}
+	if metadata := getMetadata(testContractId); metadata != nil {
+		t.Errorf("got metadata %+v after deleting it, want nil", metadata)
+	}
+}
+
+// withoutUpdatedAt clears the time metadata was set, which is the current time, after checking it is set
+func withoutUpdatedAt(t *testing.T, metadata *ContractMetadataResponse) *ContractMetadataResponse {
+	t.Helper()
+	if metadata == nil {
+		return nil
+	}
+	if metadata.UpdatedAt == 0 {
+		t.Errorf("got metadata %+v without updated_at", metadata)
+	}
+	metadata.UpdatedAt = 0
+	return metadata
+}
+
+func TestFormattedAmounts(t *testing.T) {
+	otherId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	tokenReads := 0
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053046, nil },
+		getVoteTokens: func(ctx context.Context) ([]*db.VoteToken, error) {
+			tokenReads++
+			return []*db.VoteToken{{ContractId: testContractId, Symbol: "BLND", Decimals: 7}}, nil
+		},
+		getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+			contractId := testContractId
+			if proposalKey == governor.EncodeProposalKey(otherId, 1) {
+				contractId = otherId
+			}
+			return &governor.Proposal{ProposalKey: proposalKey, ContractId: contractId, ProposalId: 1, VotesFor: "20000000000", VotesAgainst: "15000", VotesAbstain: "0"}, nil
+		},
+		getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+			return []*governor.Vote{{ContractId: contractId, ProposalId: proposalId, Voter: "GVOTER", Amount: "12345678"}}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+	get := func(path string, v any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+
+	var proposal ProposalResponse
+	get("/v1/"+testContractId+"/proposals/1", &proposal)
+	wantAmounts := ProposalAmounts{TokenSymbol: "BLND", VotesForFormatted: "2000", VotesAgainstFormatted: "0.0015", VotesAbstainFormatted: "0"}
+	if diff := cmp.Diff(wantAmounts, proposal.ProposalAmounts); diff != "" {
+		t.Errorf("proposal amounts mismatch (-want +got):\n%s", diff)
+	}
+	var votes []*VoteResponse
+	get("/v1/"+testContractId+"/proposals/1/votes", &votes)
+	if len(votes) != 1 || votes[0].AmountFormatted != "1.2345678" || votes[0].TokenSymbol != "BLND" {
+		t.Errorf("got votes %+v, want 1.2345678 BLND", votes)
+	}
+
+	// amounts of governors whose token is unknown are only raw
+	var other ProposalResponse
+	get("/v1/"+otherId+"/proposals/1", &other)
+	if diff := cmp.Diff(ProposalAmounts{}, other.ProposalAmounts); diff != "" {
+		t.Errorf("proposal amounts without a token mismatch (-want +got):\n%s", diff)
+	}
+	votes = nil
+	get("/v1/"+otherId+"/proposals/1/votes", &votes)
+	if len(votes) != 1 || votes[0].AmountFormatted != "" || votes[0].TokenSymbol != "" {
+		t.Errorf("got votes %+v, want raw amounts", votes)
+	}
+
+	if tokenReads != 1 {
+		t.Errorf("read the vote tokens %d times, want 1 as they are cached", tokenReads)
+	}
+}
+
+func TestGetProposalContent(t *testing.T) {
+	content := &governor.ProposalContent{
+		ProposalKey: governor.EncodeProposalKey(testContractId, 3),
+		ContractId:  testContractId,
+		ProposalId:  3,
+		Uri:         "ipfs://bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy",
+		Status:      governor.CONTENT_STATUS_FETCHED,
+		Content:     "# Make me security council",
+		ContentType: "text/markdown",
+		FetchedAt:   1761053046,
+	}
+	store := &mockStore{
+		getProposalContent: func(ctx context.Context, proposalKey string) (*governor.ProposalContent, error) {
+			if proposalKey != content.ProposalKey {
+				return nil, db.ErrNotFound
+			}
+			return content, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals/3/content", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got *governor.ProposalContent
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if diff := cmp.Diff(content, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetRecentEvents(t *testing.T) {
+	events := []*governor.GovernorEvent{
+		{EventId: "0005025695851872256-0000000001", ContractId: testContractId, EventType: "proposal_created", ProposalId: 4},
+		{EventId: "0005025687261941760-0000000000", ContractId: "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC", EventType: "vote_cast", ProposalId: 2},
+	}
+	var gotType string
+	var gotLimit int
+	store := &mockStore{
+		getRecentEvents: func(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+			gotType, gotLimit = eventType, limit
+			if eventType == "proposal_canceled" {
+				return nil, nil
+			}
+			return events, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		query     string
+		wantType  string
+		wantLimit int
+		want      []*governor.GovernorEvent
+	}{
+		{query: "", wantLimit: DEFAULT_LIMIT, want: events},
+		{query: "?limit=2&type=proposal_created", wantType: "proposal_created", wantLimit: 2, want: events},
+		{query: "?type=proposal_canceled", wantType: "proposal_canceled", wantLimit: DEFAULT_LIMIT, want: []*governor.GovernorEvent{}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/events/recent"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if gotType != tt.wantType || gotLimit != tt.wantLimit {
+			t.Errorf("query %q: store called with (%q, %d), want (%q, %d)", tt.query, gotType, gotLimit, tt.wantType, tt.wantLimit)
+		}
+		var got []*governor.GovernorEvent
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("query %q mismatch (-want +got):\n%s", tt.query, diff)
+		}
+	}
+}
+
+func TestGetTransactionEvents(t *testing.T) {
+	const voteTx = "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db"
+	const createTx = "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970"
+	txEvents := map[string][]*governor.GovernorEvent{
+		voteTx: {
+			{EventId: "0005025695851872256-0000000000", ContractId: testContractId, EventType: "vote_cast", ProposalId: 3, TxHash: voteTx, EventXdr: "AAAA"},
+			{EventId: "0005025695851872256-0000000001", ContractId: testContractId, EventType: "delegate_votes_changed", TxHash: voteTx},
+		},
+		createTx: {
+			{EventId: "0005025687261941760-0000000000", ContractId: testContractId, EventType: "proposal_created", ProposalId: 4, TxHash: createTx},
+		},
+	}
+	vote := &governor.Vote{TxHash: voteTx, ContractId: testContractId, ProposalId: 3, Voter: "GA", Support: 1, Amount: "1", LedgerSeq: 990, LedgerCloseTime: 1761053041}
+	var gotKeys []string
+	store := &mockStore{
+		getEventsByTxHash: func(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error) {
+			return txEvents[txHash], nil
+		},
+		getVote: func(ctx context.Context, txHash string) (*governor.Vote, error) {
+			if txHash == voteTx {
+				return vote, nil
+			}
+			return nil, db.ErrNotFound
+		},
+		getProposalsByKeys: func(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error) {
+			gotKeys = proposalKeys
+			proposals := make([]*governor.Proposal, len(proposalKeys))
+			for i, key := range proposalKeys {
+				proposals[i] = &governor.Proposal{ProposalKey: key, ContractId: testContractId}
+			}
+			return proposals, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		name      string
+		txHash    string
+		wantKeys  []string
+		wantVote  bool
+		wantCount int
+	}{
+		{name: "vote", txHash: voteTx, wantKeys: []string{governor.EncodeProposalKey(testContractId, 3)}, wantVote: true, wantCount: 2},
+		{name: "proposal created", txHash: createTx, wantKeys: []string{governor.EncodeProposalKey(testContractId, 4)}, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/"+tt.txHash+"/events", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got TransactionEventsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.TxHash != tt.txHash || len(got.Events) != tt.wantCount {
+				t.Errorf("got tx %s with %d events, want %s with %d", got.TxHash, len(got.Events), tt.txHash, tt.wantCount)
+			}
+			for _, event := range got.Events {
+				if event.EventXdr != "" {
+					t.Errorf("event %s has xdr without include_xdr", event.EventId)
+				}
+			}
+			if (got.Vote != nil) != tt.wantVote {
+				t.Errorf("vote = %+v, want present %v", got.Vote, tt.wantVote)
+			}
+			if diff := cmp.Diff(tt.wantKeys, gotKeys); diff != "" {
+				t.Errorf("proposal keys mismatch (-want +got):\n%s", diff)
+			}
+			if len(got.Proposals) != len(tt.wantKeys) {
+				t.Errorf("got %d proposals, want %d", len(got.Proposals), len(tt.wantKeys))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/unknown/events", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown transaction: expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, API_VERSION_PREFIX+"/transactions/"+voteTx+"/events", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Errorf("versioned route: status %d, Deprecation %q, want 200 without Deprecation", rec.Code, rec.Header().Get("Deprecation"))
+	}
+}
+
+func TestGetProposalAtLedger(t *testing.T) {
+	store := &mockStore{
+		getEventsByProposal: func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+			return testProposalEvents, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		atLedger         uint32
+		wantStatus       uint32
+		wantVotesFor     string
+		wantVotesAgainst string
+	}{
+		{atLedger: 100, wantStatus: 0, wantVotesFor: "0", wantVotesAgainst: "0"},
+		{atLedger: 118, wantStatus: 0, wantVotesFor: "0", wantVotesAgainst: "5"},
+		{atLedger: 119, wantStatus: 0, wantVotesFor: "1000000", wantVotesAgainst: "5"},
+		{atLedger: 200, wantStatus: 1, wantVotesFor: "1000000", wantVotesAgainst: "5"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/proposals/3?at_ledger=%d", testContractId, tt.atLedger), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var got ReconstructedProposal
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !got.Reconstructed || got.AtLedger != tt.atLedger {
+			t.Errorf("at ledger %d: reconstructed = %v at %d, want true at %d", tt.atLedger, got.Reconstructed, got.AtLedger, tt.atLedger)
+		}
+		if got.Status != tt.wantStatus || got.VotesFor != tt.wantVotesFor || got.VotesAgainst != tt.wantVotesAgainst {
+			t.Errorf("at ledger %d: got status %d for %s against %s, want status %d for %s against %s", tt.atLedger,
+				got.Status, got.VotesFor, got.VotesAgainst, tt.wantStatus, tt.wantVotesFor, tt.wantVotesAgainst)
+		}
+	}
+}
+
+func TestGetProposalResults(t *testing.T) {
+	proposal := &governor.Proposal{
+		ProposalKey:  governor.EncodeProposalKey(testContractId, 1),
+		ContractId:   testContractId,
+		ProposalId:   1,
+		VotesFor:     "521734",
+		VotesAgainst: "478266",
+		VotesAbstain: "250000",
+		VoteEnd:      1170300,
+	}
+	countingType := governor.COUNTING_TYPE_FOR | governor.COUNTING_TYPE_AGAINST | governor.COUNTING_TYPE_ABSTAIN
+
+	tests := []struct {
+		name             string
+		settingsErr      error
+		wantCountingType *uint32
+		wantResults      *governor.ProposalResults
+	}{
+		{
+			name:             "settings known",
+			wantCountingType: &countingType,
+			wantResults: &governor.ProposalResults{
+				ForPercent:                 "52.1734",
+				AgainstPercent:             "47.8266",
+				AbstainCountedTowardQuorum: true,
+				QuorumVotes:                "1250000",
+				Margin:                     "43468",
+			},
+		},
+		{name: "settings not known", settingsErr: db.ErrNotFound},
+		{name: "settings can't be read", settingsErr: errors.New("db error")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+					return proposal, nil
+				},
+				getGovernorSettings: func(ctx context.Context, governorId string, ledgerSeq uint32) (*governor.Settings, error) {
+					if tt.settingsErr != nil {
+						return nil, tt.settingsErr
+					}
+					// the settings in effect when voting ended
+					if ledgerSeq != proposal.VoteEnd {
+						t.Errorf("got settings at ledger %d, want the vote end %d", ledgerSeq, proposal.VoteEnd)
+					}
+					return &governor.Settings{GovernorId: governorId, CountingType: countingType, Quorum: 500, VoteThreshold: 5100}, nil
+				},
+			}
+			handler := newHandler(store, nil, &Config{})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/"+testContractId+"/proposals/1", nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got ProposalResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantCountingType, got.CountingType); diff != "" {
+				t.Errorf("counting type mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantResults, got.Results); diff != "" {
+				t.Errorf("results mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetActiveProposals(t *testing.T) {
+	proposals := []*governor.Proposal{
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, Status: 1, VoteStart: 900, VoteEnd: 1000},
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, Status: 0, VoteStart: 2900, VoteEnd: 3000},
+	}
+	// ledger 1000 closed at 2025-10-21T13:25:00Z
+	responses := []*ProposalResponse{
+		{Proposal: proposals[0], VoteStartTimeEstimate: "2025-10-21T13:16:40Z", VoteEndTimeEstimate: "2025-10-21T13:25:00Z"},
+		{Proposal: proposals[1], VoteStartTimeEstimate: "2025-10-21T16:03:20Z", VoteEndTimeEstimate: "2025-10-21T16:11:40Z"},
+	}
+	var gotStatuses []uint32
+	var gotCursor string
+	store := &mockStore{
+		getProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+			gotStatuses, gotCursor = statuses, cursor
+			return proposals[:min(limit, len(proposals))], nil
+		},
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, nil },
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		query        string
+		wantStatuses []uint32
+		wantCursor   string
+		want         ProposalsPage
+	}{
+		{query: "", wantStatuses: []uint32{0}, want: ProposalsPage{Proposals: responses}},
+		{query: "?include_queued=true&limit=1", wantStatuses: []uint32{0, 1}, want: ProposalsPage{Proposals: responses[:1], NextCursor: "1000:" + proposals[0].ProposalKey}},
+		{query: "?include_queued=true&limit=1&cursor=1000:" + proposals[0].ProposalKey, wantStatuses: []uint32{0, 1}, wantCursor: "1000:" + proposals[0].ProposalKey, want: ProposalsPage{Proposals: responses[:1], NextCursor: "1000:" + proposals[0].ProposalKey}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/proposals/active"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if diff := cmp.Diff(tt.wantStatuses, gotStatuses); diff != "" {
+			t.Errorf("query %q statuses mismatch (-want +got):\n%s", tt.query, diff)
+		}
+		if gotCursor != tt.wantCursor {
+			t.Errorf("query %q: cursor = %q, want %q", tt.query, gotCursor, tt.wantCursor)
+		}
+		var got ProposalsPage
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("query %q mismatch (-want +got):\n%s", tt.query, diff)
+		}
+	}
+}
+
+// TestHumanReadableTimes checks the formatted and estimated times of proposals and votes, with ledger 1000 indexed at
+// 2025-10-21T13:25:00Z
+func TestPaginationEnvelope(t *testing.T) {
+	proposals := []*governor.Proposal{
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, Status: 0, VoteEnd: 1000},
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, Status: 0, VoteEnd: 3000},
+	}
+	counts := 0
+	store := &mockStore{
+		getProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+			if cursor != "" {
+				return proposals[1:], nil
+			}
+			return proposals[:min(limit, len(proposals))], nil
+		},
+		countProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error) {
+			counts++
+			return len(proposals), nil
+		},
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 0, 0, nil },
+	}
+	handler := newHandler(store, nil, &Config{})
+	cursor := db.EncodeProposalCursor(proposals[0])
+
+	tests := []struct {
+		query   string
+		wantIds []uint32
+		want    Pagination
+	}{
+		{query: "", wantIds: []uint32{2, 1}, want: Pagination{Limit: DEFAULT_LIMIT, Total: 2}},
+		{query: "?limit=1", wantIds: []uint32{2}, want: Pagination{Limit: 1, NextCursor: &cursor, Total: 2, HasMore: true}},
+		{query: "?limit=1&cursor=" + cursor, wantIds: []uint32{1}, want: Pagination{Limit: 1, Cursor: &cursor, Total: 2}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, API_VERSION_PREFIX+"/proposals/active"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %q: expected status %d, got %d", tt.query, http.StatusOK, rec.Code)
+		}
+		var got Page[*ProposalResponse]
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		gotIds := []uint32{}
+		for _, proposal := range got.Data {
+			gotIds = append(gotIds, proposal.ProposalId)
+		}
+		if diff := cmp.Diff(tt.wantIds, gotIds); diff != "" {
+			t.Errorf("query %q: proposals mismatch (-want +got):\n%s", tt.query, diff)
+		}
+		if diff := cmp.Diff(tt.want, got.Pagination); diff != "" {
+			t.Errorf("query %q: pagination mismatch (-want +got):\n%s", tt.query, diff)
+		}
+	}
+	if counts != 1 {
+		t.Errorf("counted %d times, want 1 as the total is cached", counts)
+	}
+
+	// the last page has a null next_cursor, and the unversioned alias keeps its shape
+	req := httptest.NewRequest(http.MethodGet, API_VERSION_PREFIX+"/proposals/active?limit=1&cursor="+cursor, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"next_cursor":null`) {
+		t.Errorf("expected a null next_cursor, got %s", rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/proposals/active", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var page ProposalsPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(page.Proposals) != 2 || page.NextCursor != "" {
+		t.Errorf("unversioned page = %d proposals with next cursor %q, want 2 and none", len(page.Proposals), page.NextCursor)
+	}
+}
+
+func TestProposalSummaryView(t *testing.T) {
+	proposal := &governor.Proposal{
+		ProposalKey:  governor.EncodeProposalKey(testContractId, 1),
+		ContractId:   testContractId,
+		ProposalId:   1,
+		Title:        "Upgrade",
+		Description:  strings.Repeat("é", DESCRIPTION_PREVIEW_LENGTH+50),
+		Action:       "AAAAAQ==",
+		VotesFor:     "170141183460469231731687303715884105727",
+		VotesAgainst: "3",
+		VotesAbstain: "0",
+	}
+	store := &mockStore{
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return []*governor.Proposal{proposal}, nil
+		},
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 0, 0, nil },
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals?view=summary", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var got []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 proposal, got %d", len(got))
+	}
+	for _, field := range []string{"Description", "Action"} {
+		if _, ok := got[0][field]; ok {
+			t.Errorf("summary includes %s", field)
+		}
+	}
+	if want := strings.Repeat("é", DESCRIPTION_PREVIEW_LENGTH); got[0]["description_preview"] != want {
+		t.Errorf("description_preview = %q, want %q", got[0]["description_preview"], want)
+	}
+	if want := "170141183460469231731687303715884105730"; got[0]["votes_total"] != want {
+		t.Errorf("votes_total = %v, want %s", got[0]["votes_total"], want)
+	}
+	if got[0]["Title"] != proposal.Title || got[0]["VotesFor"] != proposal.VotesFor {
+		t.Errorf("summary = %v, want the title and tallies of the proposal", got[0])
+	}
+}
+
+func TestEventXdr(t *testing.T) {
+	newEvents := func() []*governor.GovernorEvent {
+		return []*governor.GovernorEvent{
+			{EventId: "0005025695851884544-0000000000", ContractId: testContractId, ProposalId: 3, EventType: "proposal_canceled", EventData: "{}", EventXdr: "AAAA"},
+			{EventId: "0005025695851884544-0000000001", ContractId: testContractId, ProposalId: 4, EventType: "proposal_canceled", EventData: "{}"},
+		}
+	}
+	store := &mockStore{
+		filterEventsByContractId: func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+			return newEvents(), nil
+		},
+		getRecentEvents: func(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+			return newEvents(), nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		path    string
+		accept  string
+		wantXdr []string
+	}{
+		{path: "/" + testContractId + "/events", wantXdr: []string{"", ""}},
+		{path: "/" + testContractId + "/events?include_xdr=true", wantXdr: []string{"AAAA", ""}},
+		{path: "/" + testContractId + "/events", accept: "application/json, application/xdr", wantXdr: []string{"AAAA", ""}},
+		{path: "/events/recent", wantXdr: []string{"", ""}},
+		{path: "/events/recent?include_xdr=true", wantXdr: []string{"AAAA", ""}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("%s: Content-Type = %q, want application/json", tt.path, got)
+		}
+		var got []map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		var gotXdr []string
+		for _, event := range got {
+			eventXdr, _ := event["EventXdr"].(string)
+			gotXdr = append(gotXdr, eventXdr)
+		}
+		if diff := cmp.Diff(tt.wantXdr, gotXdr); diff != "" {
+			t.Errorf("%s accept %q: event xdr mismatch (-want +got):\n%s", tt.path, tt.accept, diff)
+		}
+	}
+}
+
+func TestHumanReadableTimes(t *testing.T) {
+	proposals := map[string]*governor.Proposal{
+		governor.EncodeProposalKey(testContractId, 2): {ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, VoteStart: 900, VoteEnd: 1000},
+		governor.EncodeProposalKey(testContractId, 3): {ProposalKey: governor.EncodeProposalKey(testContractId, 3), ContractId: testContractId, ProposalId: 3, Status: 1, ExecutionUnlock: 1100},
+		governor.EncodeProposalKey(testContractId, 4): {ProposalKey: governor.EncodeProposalKey(testContractId, 4), ContractId: testContractId, ProposalId: 4, Status: 1, ExecutionUnlock: 1000},
+	}
+	votes := []*governor.Vote{
+		{TxHash: "tx1", ContractId: testContractId, ProposalId: 2, Voter: "GA", Support: 1, Amount: "1", LedgerSeq: 990, LedgerCloseTime: 1761053041, Reason: "too risky"},
+	}
+	newStore := func(statusErr error) *mockStore {
+		return &mockStore{
+			getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+				return proposals[proposalKey], nil
+			},
+			getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+				return votes, nil
+			},
+			getVoteByProposalAndVoter: func(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error) {
+				return votes[0], nil
+			},
+			getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, statusErr },
+		}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		statusErr error
+		want      map[string]any
+	}{
+		{
+			name: "proposal",
+			path: "/" + testContractId + "/proposals/2",
+			want: map[string]any{"vote_start_time_estimate": "2025-10-21T13:16:40Z", "vote_end_time_estimate": "2025-10-21T13:25:00Z"},
+		},
+		{
+			name: "queued proposal",
+			path: "/" + testContractId + "/proposals/3",
+			want: map[string]any{"execution_unlock_time_estimate": "2025-10-21T13:33:20Z", "executable_now": nil},
+		},
+		{
+			name: "executable proposal",
+			path: "/" + testContractId + "/proposals/4",
+			want: map[string]any{"execution_unlock_time_estimate": nil, "executable_now": true},
+		},
+		{
+			name:      "proposal without status",
+			path:      "/" + testContractId + "/proposals/4",
+			statusErr: errDb,
+			want:      map[string]any{"vote_start_time_estimate": nil, "vote_end_time_estimate": nil, "executable_now": nil},
+		},
+		{
+			name: "vote",
+			path: "/" + testContractId + "/proposals/2/votes/GA",
+			want: map[string]any{"Voter": "GA", "Reason": "too risky", "ledger_close_time_iso": "2025-10-21T13:24:01Z"},
+		},
+		{
+			name: "votes",
+			path: "/" + testContractId + "/proposals/2/votes",
+			want: map[string]any{"LedgerCloseTime": float64(1761053041), "ledger_close_time_iso": "2025-10-21T13:24:01Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newHandler(newStore(tt.statusErr), nil, &Config{})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			var body any
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			// lists are checked by their first item
+			if list, ok := body.([]any); ok && len(list) > 0 {
+				body = list[0]
+			}
+			got, ok := body.(map[string]any)
+			if !ok {
+				t.Fatalf("expected an object, got %v", body)
+			}
+			for key, want := range tt.want {
+				if diff := cmp.Diff(want, got[key]); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", key, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexStatus(t *testing.T) {
+	now := time.Unix(1761053160, 0)
+	statusReads := 0
+	var statusLedger uint32 = 1000
+	var statusCloseTime int64 = 1761053100
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) {
+			statusReads++
+			return statusLedger, statusCloseTime, nil
+		},
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return nil, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{MaxStalenessSeconds: 30})
+	handler.indexStatus.now = func() time.Time { return now }
+
+	tests := []struct {
+		name       string
+		path       string
+		closeTime  int64
+		wantStatus int
+		wantLag    string
+	}{
+		{name: "stale", path: "/" + testContractId + "/proposals", closeTime: 1761053100, wantStatus: http.StatusServiceUnavailable, wantLag: "60"},
+		{name: "stale admin", path: "/admin/jobs/1", closeTime: 1761053100, wantStatus: http.StatusForbidden, wantLag: "60"},
+		{name: "fresh", path: "/" + testContractId + "/proposals", closeTime: 1761053140, wantStatus: http.StatusOK, wantLag: "20"},
+		{name: "nothing indexed", path: "/" + testContractId + "/proposals", closeTime: 0, wantStatus: http.StatusServiceUnavailable, wantLag: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCloseTime = tt.closeTime
+			// expire the cached status
+			handler.indexStatus.expires = time.Time{}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("X-Indexed-Ledger"); got != "1000" {
+				t.Errorf("X-Indexed-Ledger = %q, want 1000", got)
+			}
+			if got := rec.Header().Get("X-Index-Lag-Seconds"); got != tt.wantLag {
+				t.Errorf("X-Index-Lag-Seconds = %q, want %q", got, tt.wantLag)
+			}
+			if tt.wantStatus != http.StatusServiceUnavailable {
+				return
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != INDEX_STALE_CODE {
+				t.Errorf("code = %q, want %q", resp.Code, INDEX_STALE_CODE)
+			}
+		})
+	}
+
+	// the status is cached until INDEX_STATUS_TTL has passed
+	statusReads = 0
+	statusLedger = 1001
+	req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Indexed-Ledger"); got != "1000" || statusReads != 0 {
+		t.Errorf("X-Indexed-Ledger = %q after %d reads, want cached 1000", got, statusReads)
+	}
+
+	// a reloaded threshold applies to the next request
+	handler.setMaxStaleness(120)
+	statusCloseTime = 1761053100
+	handler.indexStatus.expires = time.Time{}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d after raising the threshold, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestRebuilding(t *testing.T) {
+	now := time.Unix(1761053160, 0)
+	otherId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	rebuilding := &db.SourceStatus{State: testContractId, UpdatedAt: 1761053100}
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) {
+			return 1000, 1761053150, nil
+		},
+		getSourceStatus: func(ctx context.Context, source string) (*db.SourceStatus, error) {
+			if source != indexer.REBUILDING_STATUS_SOURCE || rebuilding == nil {
+				return nil, db.ErrNotFound
+			}
+			return rebuilding, nil
+		},
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return nil, nil
+		},
+	}
+
+	tests := []struct {
+		name           string
+		mode           string
+		path           string
+		rebuilding     *db.SourceStatus
+		wantStatus     int
+		wantRebuilding string
+	}{
+		{name: "serve", mode: REBUILDING_MODE_SERVE, path: "/" + testContractId + "/proposals", rebuilding: rebuilding, wantStatus: http.StatusOK, wantRebuilding: testContractId},
+		{name: "unavailable", mode: REBUILDING_MODE_UNAVAILABLE, path: "/" + testContractId + "/proposals", rebuilding: rebuilding, wantStatus: http.StatusServiceUnavailable, wantRebuilding: testContractId},
+		{name: "unavailable versioned", mode: REBUILDING_MODE_UNAVAILABLE, path: API_VERSION_PREFIX + "/" + testContractId + "/proposals", rebuilding: rebuilding, wantStatus: http.StatusServiceUnavailable, wantRebuilding: testContractId},
+		{name: "unavailable other contract", mode: REBUILDING_MODE_UNAVAILABLE, path: "/" + otherId + "/proposals", rebuilding: rebuilding, wantStatus: http.StatusOK, wantRebuilding: testContractId},
+		{name: "unavailable metrics", mode: REBUILDING_MODE_UNAVAILABLE, path: "/metrics", rebuilding: rebuilding, wantStatus: http.StatusOK, wantRebuilding: testContractId},
+		{name: "cleared", mode: REBUILDING_MODE_UNAVAILABLE, path: "/" + testContractId + "/proposals", rebuilding: &db.SourceStatus{UpdatedAt: 1761053150}, wantStatus: http.StatusOK},
+		{name: "never rebuilt", mode: REBUILDING_MODE_UNAVAILABLE, path: "/" + testContractId + "/proposals", wantStatus: http.StatusOK},
+		{name: "expired", mode: REBUILDING_MODE_UNAVAILABLE, path: "/" + testContractId + "/proposals", rebuilding: &db.SourceStatus{State: testContractId, UpdatedAt: now.Add(-REBUILDING_MAX_AGE).Unix() - 1}, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebuilding = tt.rebuilding
+			handler := newHandler(store, nil, &Config{RebuildingMode: tt.mode, RebuildingRetryAfterSeconds: 30})
+			handler.indexStatus.now = func() time.Time { return now }
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("X-Index-Rebuilding"); got != tt.wantRebuilding {
+				t.Errorf("X-Index-Rebuilding = %q, want %q", got, tt.wantRebuilding)
+			}
+			wantSince := ""
+			if tt.wantRebuilding != "" {
+				wantSince = "1761053100"
+			}
+			if got := rec.Header().Get("X-Index-Rebuilding-Since"); got != wantSince {
+				t.Errorf("X-Index-Rebuilding-Since = %q, want %q", got, wantSince)
+			}
+			if tt.wantStatus != http.StatusServiceUnavailable {
+				return
+			}
+			if got := rec.Header().Get("Retry-After"); got != "30" {
+				t.Errorf("Retry-After = %q, want 30", got)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != REBUILDING_CODE {
+				t.Errorf("code = %q, want %q", resp.Code, REBUILDING_CODE)
+			}
+		})
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	proposal := &governor.Proposal{ProposalKey: governor.EncodeProposalKey(testContractId, 3), ContractId: testContractId, ProposalId: 3, Status: 1, VoteEnd: 1000, ExecutionUnlock: 1100}
+	store := &mockStore{
+		getStatus:         func(ctx context.Context, source string) (uint32, int64, error) { return 1000, time.Now().Unix(), nil },
+		getProposal:       func(ctx context.Context, proposalKey string) (*governor.Proposal, error) { return proposal, nil },
+		countCoverageGaps: func(ctx context.Context) (int, error) { return 0, nil },
+		getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+			return []*governor.Vote{{TxHash: "tx1", ContractId: testContractId, ProposalId: 3, Voter: "GA", Support: 1, Amount: "1", LedgerSeq: 990, LedgerCloseTime: 1761053041}}, nil
+		},
+		filterEventsByContractId: func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+			return testProposalEvents, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+
+	paths := []string{
+		"/" + testContractId + "/proposals/3",
+		"/" + testContractId + "/proposals/3/votes",
+		"/" + testContractId + "/events",
+		"/admin/jobs/unknown",
+		"/" + testContractId + "/unknown",
+	}
+	for _, path := range paths {
+		serve := func(path string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec
+		}
+		versioned := serve(API_VERSION_PREFIX + path)
+		alias := serve(path)
+
+		if versioned.Code != alias.Code {
+			t.Errorf("%s: status %d, alias status %d", path, versioned.Code, alias.Code)
+		}
+		if diff := cmp.Diff(versioned.Body.String(), alias.Body.String()); diff != "" {
+			t.Errorf("%s: alias body mismatch (-versioned +alias):\n%s", path, diff)
+		}
+		if got := versioned.Header().Get("Deprecation"); got != "" {
+			t.Errorf("%s: versioned Deprecation = %q, want none", path, got)
+		}
+		if got := alias.Header().Get("Deprecation"); got != "true" {
+			t.Errorf("%s: alias Deprecation = %q, want true", path, got)
+		}
+		wantLink := "<" + API_VERSION_PREFIX + path + `>; rel="successor-version"`
+		if got := alias.Header().Get("Link"); got != wantLink {
+			t.Errorf("%s: alias Link = %q, want %q", path, got, wantLink)
+		}
+	}
+
+	// the service routes are not versioned
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Errorf("/health: status %d, Deprecation %q, want 200 without Deprecation", rec.Code, rec.Header().Get("Deprecation"))
+	}
+}
+
+func TestAuth(t *testing.T) {
+	store := &mockStore{
+		getStatus:         func(ctx context.Context, source string) (uint32, int64, error) { return 1000, time.Now().Unix(), nil },
+		countCoverageGaps: func(ctx context.Context) (int, error) { return 0, nil },
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return nil, nil
+		},
+	}
+	const apiKey = "test-api-key"
+
+	tests := []struct {
+		name       string
+		config     *Config
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "public", config: &Config{}, path: "/" + testContractId + "/proposals", wantStatus: http.StatusOK},
+		{name: "admin disabled", config: &Config{}, path: "/admin/jobs/1", token: testAdminToken, wantStatus: http.StatusForbidden},
+		{name: "admin without token", config: &Config{AdminTokens: []string{testAdminToken}}, path: "/admin/jobs/1", wantStatus: http.StatusUnauthorized},
+		{name: "admin with unknown token", config: &Config{AdminTokens: []string{testAdminToken}}, path: "/admin/jobs/1", token: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "admin with api key", config: &Config{AdminTokens: []string{testAdminToken}, APIKeys: []string{apiKey}}, path: "/admin/jobs/1", token: apiKey, wantStatus: http.StatusForbidden},
+		{name: "admin with second token", config: &Config{AdminTokens: []string{"other", testAdminToken}}, path: "/admin/jobs/1", token: testAdminToken, wantStatus: http.StatusNotFound},
+		{name: "required without token", config: &Config{APIKeys: []string{apiKey}, RequireAuth: true}, path: "/" + testContractId + "/proposals", wantStatus: http.StatusUnauthorized},
+		{name: "required with api key", config: &Config{APIKeys: []string{apiKey}, RequireAuth: true}, path: "/" + testContractId + "/proposals", token: apiKey, wantStatus: http.StatusOK},
+		{name: "required with admin token", config: &Config{AdminTokens: []string{testAdminToken}, RequireAuth: true}, path: "/" + testContractId + "/proposals", token: testAdminToken, wantStatus: http.StatusOK},
+		{name: "required health", config: &Config{APIKeys: []string{apiKey}, RequireAuth: true}, path: "/health", wantStatus: http.StatusOK},
+		{name: "required status", config: &Config{APIKeys: []string{apiKey}, RequireAuth: true}, path: "/status", wantStatus: http.StatusOK},
+		{name: "required admin without token", config: &Config{AdminTokens: []string{testAdminToken}, RequireAuth: true}, path: "/admin/jobs/1", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newHandler(store, nil, tt.config)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); (got != "") != (tt.wantStatus == http.StatusUnauthorized) {
+				t.Errorf("WWW-Authenticate = %q with status %d", got, rec.Code)
+			}
+		})
+	}
+}
+
+func TestIncludeFlagged(t *testing.T) {
+	newProposals := func() []*governor.Proposal {
+		return []*governor.Proposal{
+			{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, Status: 1, FlaggedLowParticipation: true},
+			{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, Status: 1},
+		}
+	}
+	var gotIncludeFlagged []bool
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, nil },
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return newProposals(), nil
+		},
+		getProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+			gotIncludeFlagged = append(gotIncludeFlagged, includeFlagged)
+			return nil, nil
+		},
+		countProposalsByStatus: func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error) {
+			gotIncludeFlagged = append(gotIncludeFlagged, includeFlagged)
+			return 0, nil
+		},
+		getExecutableProposals: func(ctx context.Context, ledger uint32) ([]*governor.Proposal, error) {
+			return newProposals(), nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		path    string
+		wantIds []uint32
+	}{
+		{path: "/v1/" + testContractId + "/proposals", wantIds: []uint32{1, 2}},
+		{path: "/v1/" + testContractId + "/proposals?include_flagged=false", wantIds: []uint32{2}},
+		{path: "/v1/" + testContractId + "/proposals?include_flagged=false&view=summary", wantIds: []uint32{2}},
+		{path: "/v1/proposals/executable?include_flagged=true", wantIds: []uint32{1, 2}},
+		{path: "/v1/proposals/executable?include_flagged=false", wantIds: []uint32{2}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+		}
+		var got []struct{ ProposalId uint32 }
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("GET %s: failed to decode response: %v", tt.path, err)
+		}
+		var gotIds []uint32
+		for _, proposal := range got {
+			gotIds = append(gotIds, proposal.ProposalId)
+		}
+		if diff := cmp.Diff(tt.wantIds, gotIds); diff != "" {
+			t.Errorf("GET %s: proposal ids mismatch (-want +got):\n%s", tt.path, diff)
+		}
+	}
+
+	// active proposals are paginated, so flagged proposals are excluded by the store
+	for _, query := range []string{"", "?include_flagged=false"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/proposals/active"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+	if diff := cmp.Diff([]bool{true, true, false, false}, gotIncludeFlagged); diff != "" {
+		t.Errorf("includeFlagged mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestActionTypeFilter(t *testing.T) {
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, nil },
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			return []*governor.Proposal{
+				{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, ActionType: governor.ACTION_TYPE_COUNCIL},
+				{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, ActionType: governor.ACTION_TYPE_CALLDATA, ActionContractId: testContractId, ActionFunction: "transfer"},
+				{ProposalKey: governor.EncodeProposalKey(testContractId, 3), ContractId: testContractId, ProposalId: 3, ActionType: governor.ACTION_TYPE_UPGRADE, FlaggedLowParticipation: true},
+			}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		path    string
+		wantIds []uint32
+	}{
+		{path: "/v1/" + testContractId + "/proposals?action_type=council", wantIds: []uint32{1}},
+		{path: "/v1/" + testContractId + "/proposals?action_type=calldata&view=summary", wantIds: []uint32{2}},
+		{path: "/v1/" + testContractId + "/proposals?action_type=upgrade&include_flagged=false", wantIds: nil},
+		{path: "/v1/" + testContractId + "/proposals?action_type=settings", wantIds: nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+		}
+		var got []struct {
+			ProposalId       uint32
+			ActionType       string
+			ActionContractId string
+			ActionFunction   string
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("GET %s: failed to decode response: %v", tt.path, err)
+		}
+		var gotIds []uint32
+		for _, proposal := range got {
+			gotIds = append(gotIds, proposal.ProposalId)
+			if proposal.ProposalId == 2 && (proposal.ActionContractId != testContractId || proposal.ActionFunction != "transfer") {
+				t.Errorf("GET %s: got calldata %s.%s, want %s.transfer", tt.path, proposal.ActionContractId, proposal.ActionFunction, testContractId)
+			}
+		}
+		if diff := cmp.Diff(tt.wantIds, gotIds); diff != "" {
+			t.Errorf("GET %s: proposal ids mismatch (-want +got):\n%s", tt.path, diff)
+		}
+	}
+}
+
+func TestGetEndingProposals(t *testing.T) {
+	proposal := &governor.Proposal{ProposalKey: governor.EncodeProposalKey(testContractId, 4), ContractId: testContractId, ProposalId: 4, VoteEnd: 1050}
+	var gotFrom, gotTo uint32
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, nil },
+		getProposalsEndingBetween: func(ctx context.Context, fromLedger uint32, toLedger uint32) ([]*governor.Proposal, error) {
+			gotFrom, gotTo = fromLedger, toLedger
+			return []*governor.Proposal{proposal}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/proposals/ending?within_ledgers=100", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if gotFrom != 1001 || gotTo != 1100 {
+		t.Errorf("got ledgers %d to %d, want 1001 to 1100", gotFrom, gotTo)
+	}
+	var got []*ProposalResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// ledger 1000 closed at 2025-10-21T13:25:00Z
+	want := []*ProposalResponse{{Proposal: proposal, VoteStartTimeEstimate: "2025-10-21T12:01:40Z", VoteEndTimeEstimate: "2025-10-21T13:29:10Z"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, query := range []string{"", "?within_ledgers=0", "?within_ledgers=abc", fmt.Sprintf("?within_ledgers=%d", MAX_WITHIN_LEDGERS+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/proposals/ending"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	unindexed := newHandler(&mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 0, 0, nil },
+	}, nil, &Config{})
+	req = httptest.NewRequest(http.MethodGet, "/proposals/ending?within_ledgers=100", nil)
+	rec = httptest.NewRecorder()
+	unindexed.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d with no indexed ledger, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestGetExecutableProposals(t *testing.T) {
+	proposals := []*governor.Proposal{
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1, Status: 1, ExecutionUnlock: 900},
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, Status: 1, ExecutionUnlock: 1060},
+	}
+	var gotLedger uint32
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053100, nil },
+		getExecutableProposals: func(ctx context.Context, ledger uint32) ([]*governor.Proposal, error) {
+			gotLedger = ledger
+			return proposals, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		query      string
+		wantLedger uint32
+	}{
+		{query: "", wantLedger: 1000 + DEFAULT_EXECUTABLE_WITHIN_LEDGERS},
+		{query: "?within_ledgers=0", wantLedger: 1000},
+		{query: "?within_ledgers=100", wantLedger: 1100},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/proposals/executable"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %q: expected status %d, got %d", tt.query, http.StatusOK, rec.Code)
+		}
+		if gotLedger != tt.wantLedger {
+			t.Errorf("query %q: ledger = %d, want %d", tt.query, gotLedger, tt.wantLedger)
+		}
+		var got []*ExecutableProposal
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		// ledger 1000 closed at 2025-10-21T13:25:00Z
+		want := []*ExecutableProposal{
+			{ProposalResponse: &ProposalResponse{Proposal: proposals[0], VoteStartTimeEstimate: "2025-10-21T12:01:40Z", VoteEndTimeEstimate: "2025-10-21T12:01:40Z", ExecutableNow: true}},
+			{ProposalResponse: &ProposalResponse{Proposal: proposals[1], VoteStartTimeEstimate: "2025-10-21T12:01:40Z", VoteEndTimeEstimate: "2025-10-21T12:01:40Z", ExecutionUnlockTimeEstimate: "2025-10-21T13:30:00Z"}, LedgersUntilExecutable: 60},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("query %q: mismatch (-want +got):\n%s", tt.query, diff)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/proposals/executable?within_ledgers=%d", MAX_WITHIN_LEDGERS+1), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestGetProposalsByKeys(t *testing.T) {
+	proposals := map[string]*governor.Proposal{
+		governor.EncodeProposalKey(testContractId, 1): {ProposalKey: governor.EncodeProposalKey(testContractId, 1), ContractId: testContractId, ProposalId: 1},
+		governor.EncodeProposalKey(testContractId, 7): {ProposalKey: governor.EncodeProposalKey(testContractId, 7), ContractId: testContractId, ProposalId: 7},
+	}
+	var gotKeys []string
+	store := &mockStore{
+		getProposalsByKeys: func(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error) {
+			gotKeys = proposalKeys
+			var found []*governor.Proposal
+			for _, key := range proposalKeys {
+				if proposal, ok := proposals[key]; ok {
+					found = append(found, proposal)
+				}
+			}
+			return found, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		keys     string
+		wantKeys []string
+		want     []uint32
+	}{
+		{keys: testContractId + "-7," + testContractId + "-1", wantKeys: []string{testContractId + "-7", testContractId + "-1"}, want: []uint32{7, 1}},
+		{keys: " " + testContractId + "-2 ,," + testContractId + "-1", wantKeys: []string{testContractId + "-2", testContractId + "-1"}, want: []uint32{1}},
+		{keys: testContractId + "-2", wantKeys: []string{testContractId + "-2"}, want: []uint32{}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/proposals?keys="+url.QueryEscape(tt.keys), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if diff := cmp.Diff(tt.wantKeys, gotKeys); diff != "" {
+			t.Errorf("keys %q: store keys mismatch (-want +got):\n%s", tt.keys, diff)
+		}
+		var got []*ProposalResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		gotIds := []uint32{}
+		for _, proposal := range got {
+			gotIds = append(gotIds, proposal.ProposalId)
+		}
+		if diff := cmp.Diff(tt.want, gotIds); diff != "" {
+			t.Errorf("keys %q: proposals mismatch (-want +got):\n%s", tt.keys, diff)
+		}
+	}
+}
+
+func TestListSort(t *testing.T) {
+	var gotSort db.Sort
+	store := &mockStore{
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			gotSort = sort
+			return nil, nil
+		},
+		eachProposalByContractId: func(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error {
+			gotSort = sort
+			return nil
+		},
+		getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+			gotSort = sort
+			return nil, nil
+		},
+		filterEventsByContractId: func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+			gotSort = sort
+			return nil, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		path string
+		want db.Sort
+	}{
+		{path: "/" + testContractId + "/proposals", want: db.Sort{}},
+		{path: "/" + testContractId + "/proposals?sort=vote_end", want: db.Sort{Field: "vote_end"}},
+		{path: "/" + testContractId + "/proposals?sort=status:desc", want: db.Sort{Field: "status", Desc: true}},
+		{path: "/" + testContractId + "/proposals?sort=vote_end:asc&format=csv", want: db.Sort{Field: "vote_end"}},
+		{path: "/" + testContractId + "/proposals/1/votes?sort=amount:desc", want: db.Sort{Field: "amount", Desc: true}},
+		{path: "/" + testContractId + "/events?sort=event_id:desc", want: db.Sort{Field: "event_id", Desc: true}},
+	}
+	for _, tt := range tests {
+		gotSort = db.Sort{Field: "unset"}
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+		}
+		if diff := cmp.Diff(tt.want, gotSort); diff != "" {
+			t.Errorf("%s: sort mismatch (-want +got):\n%s", tt.path, diff)
+		}
+	}
+}
+
+func TestGetVoteSeriesBucket(t *testing.T) {
+	var gotBucket int64
+	store := &mockStore{
+		getVoteSeries: func(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+			gotBucket = bucketSize
+			return []*governor.VoteSeriesPoint{}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{query: "", want: DEFAULT_SERIES_BUCKET},
+		{query: "?bucket=900", want: 900},
+		{query: "?bucket=1", want: MIN_SERIES_BUCKET},
+		{query: "?bucket=-5", want: MIN_SERIES_BUCKET},
+		{query: "?bucket=99999999", want: MAX_SERIES_BUCKET},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals/3/votes/series"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var got VoteSeriesResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if gotBucket != tt.want || got.Bucket != tt.want {
+			t.Errorf("query %q: bucket = (%d, %d), want %d", tt.query, gotBucket, got.Bucket, tt.want)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	proposals := []*governor.Proposal{
+		{ProposalKey: governor.EncodeProposalKey(testContractId, 2), ContractId: testContractId, ProposalId: 2, Title: "Make me, security council", Description: "plz \"now\"", VotesFor: "1", VotesAgainst: "0", VotesAbstain: "0", Truncated: true, ActionType: governor.ACTION_TYPE_UNKNOWN, CreatedLedger: 1170134, UpdatedLedger: 1170136, UpdatedEventId: "0005025695851884544-0000000000", UpdatedAt: 1761053100},
+	}
+	votes := []*governor.Vote{
+		{TxHash: "tx2", ContractId: testContractId, ProposalId: 2, Voter: "GB", Support: 0, Amount: "20000000000", LedgerSeq: 1170136, LedgerCloseTime: 1761053046, Reason: "too risky"},
+		{TxHash: "tx1", ContractId: testContractId, ProposalId: 2, Voter: "GA", Support: 1, Amount: "1", LedgerSeq: 1170134, LedgerCloseTime: 1761053041},
+	}
+	store := &mockStore{
+		eachProposalByContractId: func(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error {
+			for _, proposal := range proposals {
+				if err := fn(proposal); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		eachVoteByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error {
+			if proposalId != 2 {
+				return nil
+			}
+			for _, vote := range votes {
+				if err := fn(vote); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		name            string
+		path            string
+		accept          string
+		wantDisposition string
+		wantBody        string
+	}{
+		{
+			name:            "proposals",
+			path:            "/" + testContractId + "/proposals?format=csv",
+			wantDisposition: `attachment; filename=` + testContractId + `-proposals.csv`,
+			wantBody: "proposal_key,contract_id,proposal_id,proposer,status,title,description,action,vote_start,vote_end,votes_for,votes_against,votes_abstain,execution_unlock,execution_tx_hash,truncated,created_ledger,updated_ledger,updated_event_id,updated_at,flagged_low_participation,action_type,action_contract_id,action_function,source_account,created_tx_hash\n" +
+				testContractId + "-2," + testContractId + `,2,,0,"Make me, security council","plz ""now""",,0,0,1,0,0,0,,true,1170134,1170136,0005025695851884544-0000000000,1761053100,false,unknown,,,,` + "\n",
+		},
+		{
+			name:            "proposals with action type",
+			path:            "/" + testContractId + "/proposals?format=csv&action_type=council",
+			wantDisposition: `attachment; filename=` + testContractId + `-proposals.csv`,
+			wantBody:        "proposal_key,contract_id,proposal_id,proposer,status,title,description,action,vote_start,vote_end,votes_for,votes_against,votes_abstain,execution_unlock,execution_tx_hash,truncated,created_ledger,updated_ledger,updated_event_id,updated_at,flagged_low_participation,action_type,action_contract_id,action_function,source_account,created_tx_hash\n",
+		},
+		{
+			name:            "votes",
+			path:            "/" + testContractId + "/proposals/2/votes",
+			accept:          "text/csv",
+			wantDisposition: `attachment; filename=` + testContractId + `-2-votes.csv`,
+			wantBody: "tx_hash,contract_id,proposal_id,voter,support,amount,ledger_seq,ledger_close_time,reason\n" +
+				"tx2," + testContractId + ",2,GB,0,20000000000,1170136,1761053046,too risky\n" +
+				"tx1," + testContractId + ",2,GA,1,1,1170134,1761053041,\n",
+		},
+		{
+			name:            "no votes",
+			path:            "/" + testContractId + "/proposals/3/votes?format=csv",
+			wantDisposition: `attachment; filename=` + testContractId + `-3-votes.csv`,
+			wantBody:        "tx_hash,contract_id,proposal_id,voter,support,amount,ledger_seq,ledger_close_time,reason\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/csv", got)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.wantDisposition)
+			}
+			if diff := cmp.Diff(tt.wantBody, rec.Body.String()); diff != "" {
+				t.Errorf("body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	handler := newHandler(&mockStore{}, nil, &Config{})
+	handler.router.HandleFunc("GET /test/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("test panic")
+	})
+	handler.router.HandleFunc("GET /test/panic-streaming", func(w http.ResponseWriter, r *http.Request) {
+		stream := newCSVStream(w, "test.csv", []string{"a"})
+		stream.write([]string{"1"})
+		stream.close()
+		panic("test panic")
+	})
+	panics := counterValue(t, handler, "governor_api_handler_panics_total")
+
+	req := httptest.NewRequest(http.MethodGet, "/test/panic", nil)
+	req.Header.Set("X-Request-Id", "test-request")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if got := rec.Header().Get("X-Request-Id"); got != "test-request" {
+		t.Errorf("X-Request-Id = %q, want test-request", got)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if diff := cmp.Diff(ErrorResponse{Error: "internal server error"}, resp); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+
+	// a started response can't be replaced with an error, so the connection is aborted
+	func() {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("streaming panic = %v, want http.ErrAbortHandler", p)
+			}
+		}()
+		req := httptest.NewRequest(http.MethodGet, "/test/panic-streaming", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if got := counterValue(t, handler, "governor_api_handler_panics_total"); got != panics+2 {
+		t.Errorf("panics = %v, want %v", got, panics+2)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	// a load balancer's subnet and a CDN's range
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("173.245.48.0/20"), netip.MustParsePrefix("2400:cb00::/32")}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		trusted    []netip.Prefix
+		want       string
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "no trusted proxies", remoteAddr: "10.0.0.5:51234", xff: []string{"203.0.113.7"}, want: "10.0.0.5"},
+		{name: "spoofed from untrusted connection", remoteAddr: "198.51.100.9:51234", xff: []string{"203.0.113.7"}, trusted: trusted, want: "198.51.100.9"},
+		{name: "behind load balancer", remoteAddr: "10.0.0.5:51234", xff: []string{"203.0.113.7"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "behind cdn and load balancer", remoteAddr: "10.0.0.5:51234", xff: []string{"203.0.113.7, 173.245.48.12"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "spoofed behind cdn and load balancer", remoteAddr: "10.0.0.5:51234", xff: []string{"1.2.3.4, 10.0.0.9, 203.0.113.7, 173.245.48.12"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "several headers", remoteAddr: "10.0.0.5:51234", xff: []string{"1.2.3.4, 203.0.113.7", "173.245.48.12"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "ipv6 client behind ipv6 cdn", remoteAddr: "10.0.0.5:51234", xff: []string{"2001:db8::7, 2400:cb00::1"}, trusted: trusted, want: "2001:db8::7"},
+		{name: "entries with ports", remoteAddr: "10.0.0.5:51234", xff: []string{"[2001:db8::7]:443, 173.245.48.12:8080"}, trusted: trusted, want: "2001:db8::7"},
+		{name: "only trusted proxies", remoteAddr: "10.0.0.5:51234", xff: []string{"10.0.0.8, 173.245.48.12"}, trusted: trusted, want: "10.0.0.8"},
+		{name: "invalid entry", remoteAddr: "10.0.0.5:51234", xff: []string{"203.0.113.7, unknown, 173.245.48.12"}, trusted: trusted, want: "173.245.48.12"},
+		{name: "ipv4 mapped connection", remoteAddr: "[::ffff:10.0.0.5]:51234", xff: []string{"203.0.113.7"}, trusted: trusted, want: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				req.Header.Add("X-Forwarded-For", xff)
+			}
+			if got := clientIP(req, tt.trusted); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// the request log has the client IP, not a spoofed X-Forwarded-For
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	handler := newHandler(&mockStore{}, nil, &Config{TrustedProxies: trusted})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "198.51.100.9:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if !strings.Contains(logs.String(), "client_ip=198.51.100.9") {
+		t.Errorf("request log missing the connection's IP:\n%s", logs.String())
+	}
+}
+
+// writeTestCert writes a new self-signed certificate for localhost and its key to dir, and returns the certificate
+func writeTestCert(t *testing.T, dir string, serial int64) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestCert(t, dir, 1)
+	certs, err := newCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(newHandler(&mockStore{}, nil, &Config{}))
+	server.TLS = certs.tlsConfig()
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	// get fetches /metrics over a new connection trusting root, and returns the certificate served
+	get := func(root *x509.Certificate) (*x509.Certificate, error) {
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+		// httptest serves its own certificate without SNI, so the server name is sent
+		transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}, ForceAttemptHTTP2: true}
+		defer transport.CloseIdleConnections()
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/metrics")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+			t.Errorf("got status %d over %s, want %d over HTTP/2", resp.StatusCode, resp.Proto, http.StatusOK)
+		}
+		return resp.TLS.PeerCertificates[0], nil
+	}
+	if served, err := get(first); err != nil || served.SerialNumber.Int64() != 1 {
+		t.Fatalf("got certificate %v, err %v, want the first certificate", served, err)
+	}
+
+	// a renewed certificate is served once reloaded
+	second := writeTestCert(t, dir, 2)
+	if err := certs.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if served, err := get(second); err != nil || served.SerialNumber.Int64() != 2 {
+		t.Fatalf("got certificate %v, err %v, want the renewed certificate", served, err)
+	}
+
+	// a certificate that fails to load keeps the current certificate
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.reload(); err == nil {
+		t.Error("expected reloading an invalid key to fail")
+	}
+	if served, err := get(second); err != nil || served.SerialNumber.Int64() != 2 {
+		t.Fatalf("got certificate %v, err %v, want the renewed certificate", served, err)
+	}
+}
+
+func TestRedirectToTLS(t *testing.T) {
+	tests := []struct {
+		tlsPort string
+		target  string
+		want    string
+	}{
+		{tlsPort: "8443", target: "http://api.example.com:8080/v1/proposals/active?limit=10", want: "https://api.example.com:8443/v1/proposals/active?limit=10"},
+		{tlsPort: "443", target: "http://api.example.com/health", want: "https://api.example.com/health"},
+		{tlsPort: "443", target: "http://[2001:db8::1]:80/health", want: "https://[2001:db8::1]/health"},
+		{tlsPort: "8443", target: "http://[2001:db8::1]:80/health", want: "https://[2001:db8::1]:8443/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			redirectToTLS(tt.tlsPort).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, nil))
+			if rec.Code != http.StatusPermanentRedirect {
+				t.Errorf("expected status %d, got %d", http.StatusPermanentRedirect, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// counterValue returns the value of a counter from the metrics endpoint
+func counterValue(t *testing.T, handler *Handler, name string) float64 {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	for line := range strings.SplitSeq(rec.Body.String(), "\n") {
+		if valueStr, ok := strings.CutPrefix(line, name+" "); ok {
+			value, err := strconv.ParseFloat(valueStr, 64)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", line, err)
+			}
+			return value
+		}
+	}
+	t.Fatalf("metrics response missing %s", name)
+	return 0
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	handler := newHandler(&mockStore{}, nil, &Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "governor_indexer_ledgers_processed_total") {
+		t.Errorf("metrics response missing indexer metrics:\n%s", body)
+	}
+}
+
+func TestGetAdminStatus(t *testing.T) {
+	now := time.Unix(1761053100, 0)
+	target := uint32(1999)
+	percent := 25.0
+	eta := int64(60)
+	tests := []struct {
+		name     string
+		core     *db.SourceStatus
+		progress *db.IngestionProgress
+		want     AdminStatusResponse
+	}{
+		{
+			name: "rpc backend",
+			want: AdminStatusResponse{Indexer: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}},
+		},
+		{
+			name:     "backfilling",
+			progress: &db.IngestionProgress{Source: indexer.STATUS_SOURCE, StartLedger: 1000, Ledger: 1249, TargetLedger: 1999, Ledgers: 250, Events: 40, LedgersPerSecond: 12.5, EventsPerSecond: 2, StartedAt: 1761053080, UpdatedAt: 1761053100},
+			want: AdminStatusResponse{
+				Indexer:  IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54},
+				Progress: &IngestionProgress{StartLedger: 1000, Ledger: 1249, TargetLedger: &target, Ledgers: 250, Events: 40, LedgersPerSecond: 12.5, EventsPerSecond: 2, PercentComplete: &percent, ETASeconds: &eta, StartedAt: 1761053080, UpdatedAt: 1761053100},
+			},
+		},
+		{
+			name:     "target unknown",
+			progress: &db.IngestionProgress{Source: indexer.STATUS_SOURCE, StartLedger: 1000, Ledger: 1249, Ledgers: 250, LedgersPerSecond: 12.5, StartedAt: 1761053080, UpdatedAt: 1761053100},
+			want: AdminStatusResponse{
+				Indexer:  IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54},
+				Progress: &IngestionProgress{StartLedger: 1000, Ledger: 1249, Ledgers: 250, LedgersPerSecond: 12.5, StartedAt: 1761053080, UpdatedAt: 1761053100},
+			},
+		},
+		{
+			name: "captive core catching up",
+			core: &db.SourceStatus{LedgerSeq: 1200, LedgerCloseTime: 1761054046, State: "Catching up", UpdatedAt: 1761053090},
+			want: AdminStatusResponse{
+				Indexer:     IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54},
+				CaptiveCore: &CaptiveCoreStatus{Ledger: 1200, LedgerCloseTime: 1761054046, State: "Catching up", UpdatedAt: 1761053090, AgeSeconds: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getStatus: func(ctx context.Context, source string) (uint32, int64, error) {
+					return 1000, 1761053046, nil
+				},
+				getSourceStatus: func(ctx context.Context, source string) (*db.SourceStatus, error) {
+					if tt.core == nil || source != indexer.CAPTIVE_CORE_STATUS_SOURCE {
+						return nil, db.ErrNotFound
+					}
+					return tt.core, nil
+				},
+				getIngestionProgress: func(ctx context.Context, source string) (*db.IngestionProgress, error) {
+					if tt.progress == nil || source != indexer.STATUS_SOURCE {
+						return nil, db.ErrNotFound
+					}
+					return tt.progress, nil
+				},
+			}
+			handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+			handler.indexStatus.now = func() time.Time { return now }
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/status", nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got AdminStatusResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("status mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetIndexerErrors(t *testing.T) {
+	var gotLimit int
+	store := &mockStore{
+		getStatus: func(ctx context.Context, source string) (uint32, int64, error) {
+			return 1000, 1761053046, nil
+		},
+		getIndexerErrors: func(ctx context.Context, limit int) ([]*db.IndexerError, error) {
+			gotLimit = limit
+			return []*db.IndexerError{
+				{Seq: 2, LedgerSeq: 1001, Message: "failed to read ledger transaction", CreatedAt: 1761053050},
+				{Seq: 1, LedgerSeq: 1000, TxHash: "tx_1", Message: "failed applying event", CreatedAt: 1761053046},
+			}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantLimit  int
+	}{
+		{query: "", wantStatus: http.StatusOK, wantLimit: DEFAULT_LIMIT},
+		{query: "?limit=100", wantStatus: http.StatusOK, wantLimit: 100},
+		{query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{query: "?limit=1000", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			gotLimit = 0
+			req := httptest.NewRequest(http.MethodGet, "/admin/errors"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []IndexerErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			want := []IndexerErrorResponse{
+				{Seq: 2, Ledger: 1001, Error: "failed to read ledger transaction", CreatedAt: 1761053050},
+				{Seq: 1, Ledger: 1000, TxHash: "tx_1", Error: "failed applying event", CreatedAt: 1761053046},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// the errors are only listed for admins
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/errors", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestGetIndexStatus(t *testing.T) {
+	now := time.Unix(1761053100, 0)
+	ptr := func(n uint32) *uint32 { return &n }
+	errRPC := errors.New("rpc down")
+	tests := []struct {
+		name        string
+		network     func(ctx context.Context) (uint32, error)
+		indexLedger uint32
+		gaps        int
+		want        StatusResponse
+	}{
+		{
+			name:        "without rpc",
+			indexLedger: 1000,
+			want:        StatusResponse{IndexerStatus: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}},
+		},
+		{
+			name:        "behind the network",
+			network:     func(ctx context.Context) (uint32, error) { return 1012, nil },
+			indexLedger: 1000,
+			want:        StatusResponse{IndexerStatus: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}, NetworkLedger: ptr(1012), BacklogLedgers: ptr(12)},
+		},
+		{
+			name:        "network behind the indexer",
+			network:     func(ctx context.Context) (uint32, error) { return 998, nil },
+			indexLedger: 1000,
+			want:        StatusResponse{IndexerStatus: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}, NetworkLedger: ptr(998), BacklogLedgers: ptr(0)},
+		},
+		{
+			name:        "rpc unreachable",
+			network:     func(ctx context.Context) (uint32, error) { return 0, errRPC },
+			indexLedger: 1000,
+			want:        StatusResponse{IndexerStatus: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}},
+		},
+		{
+			name:    "nothing indexed",
+			network: func(ctx context.Context) (uint32, error) { return 1012, nil },
+			want:    StatusResponse{NetworkLedger: ptr(1012), BacklogLedgers: ptr(1012)},
+		},
+		{
+			name:        "with coverage gaps",
+			indexLedger: 1000,
+			gaps:        2,
+			want:        StatusResponse{IndexerStatus: IndexerStatus{Ledger: 1000, LedgerCloseTime: 1761053046, LagSeconds: 54}, HasGaps: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getStatus: func(ctx context.Context, source string) (uint32, int64, error) {
+					if tt.indexLedger == 0 {
+						return 0, 0, nil
+					}
+					return tt.indexLedger, 1761053046, nil
+				},
+				countCoverageGaps: func(ctx context.Context) (int, error) { return tt.gaps, nil },
+			}
+			// stale data is refused, but the status is still served
+			handler := newHandler(store, nil, &Config{MaxStalenessSeconds: 10})
+			handler.indexStatus.now = func() time.Time { return now }
+			if tt.network != nil {
+				handler.network = &networkStatus{latestLedger: tt.network, now: func() time.Time { return now }}
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got StatusResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("status mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetCoverageGaps(t *testing.T) {
+	tests := []struct {
+		name string
+		gaps []*db.CoverageGap
+		want []CoverageGapResponse
+	}{
+		{name: "no gaps", want: []CoverageGapResponse{}},
+		{
+			name: "gaps",
+			gaps: []*db.CoverageGap{
+				{FromLedger: 2000, ToLedger: 2000, Reason: "verify", CreatedAt: 1761053046},
+				{FromLedger: 999001, ToLedger: 1000059, Reason: "rpc_retention", CreatedAt: 1761053100},
+			},
+			want: []CoverageGapResponse{
+				{FromLedger: 2000, ToLedger: 2000, Reason: "verify", CreatedAt: 1761053046},
+				{FromLedger: 999001, ToLedger: 1000059, Reason: "rpc_retention", CreatedAt: 1761053100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getStatus:         func(ctx context.Context, source string) (uint32, int64, error) { return 1000, time.Now().Unix(), nil },
+				getCoverageGaps:   func(ctx context.Context) ([]*db.CoverageGap, error) { return tt.gaps, nil },
+				countCoverageGaps: func(ctx context.Context) (int, error) { return len(tt.gaps), nil },
+			}
+			handler := newHandler(store, nil, &Config{})
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/gaps", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got []CoverageGapResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("gaps mismatch (-want +got):\n%s", diff)
+			}
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			var health HealthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+				t.Fatalf("failed to decode health response: %v", err)
+			}
+			if diff := cmp.Diff(HealthResponse{Status: 1000, HasGaps: len(tt.gaps) > 0}, health); diff != "" {
+				t.Errorf("health mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNetworkStatusCache(t *testing.T) {
+	now := time.Unix(1761053100, 0)
+	calls := 0
+	latest := uint32(1000)
+	var err error
+	status := &networkStatus{
+		latestLedger: func(ctx context.Context) (uint32, error) {
+			calls++
+			return latest, err
+		},
+		now: func() time.Time { return now },
+	}
+
+	steps := []struct {
+		advance   time.Duration
+		latest    uint32
+		err       error
+		want      uint32
+		wantErr   bool
+		wantCalls int
+	}{
+		{latest: 1000, want: 1000, wantCalls: 1},
+		{advance: NETWORK_STATUS_TTL - time.Second, latest: 1001, want: 1000, wantCalls: 1},
+		{advance: time.Second, latest: 1002, want: 1002, wantCalls: 2},
+		// failed calls are cached briefly
+		{advance: NETWORK_STATUS_TTL, err: errors.New("rpc down"), wantErr: true, wantCalls: 3},
+		{advance: NETWORK_STATUS_FAILURE_TTL - time.Second, latest: 1003, wantErr: true, wantCalls: 3},
+		{advance: time.Second, latest: 1003, want: 1003, wantCalls: 4},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		latest, err = step.latest, step.err
+		got, gotErr := status.get(t.Context())
+		if (gotErr != nil) != step.wantErr || got != step.want || calls != step.wantCalls {
+			t.Errorf("step %d: get() = %d, %v after %d calls, want %d, error %v after %d calls", i, got, gotErr, calls, step.want, step.wantErr, step.wantCalls)
+		}
+	}
+}
+
+// TestNetworkStatusConcurrent verifies concurrent callers share a single call to the RPC server, and a caller whose
+// context is done doesn't wait for it
+func TestNetworkStatusConcurrent(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	status := &networkStatus{
+		latestLedger: func(ctx context.Context) (uint32, error) {
+			calls.Add(1)
+			<-release
+			return 1000, nil
+		},
+		now: time.Now,
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := status.get(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("get() with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if got, err := status.get(t.Context()); err != nil || got != 1000 {
+				t.Errorf("get() = %d, %v, want 1000", got, err)
+			}
+		})
+	}
+	close(release)
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("got %d calls to the RPC server, want 1", got)
+	}
+}
+
+func TestGetContracts(t *testing.T) {
+	now := time.Unix(1761053100, 0)
+	unreviewedId := "CAS3J7GYLGXMF6TDJBBYYSE3HQ6BBSMLNUQ34T6TZMYMW2EVH34XOWMA"
+	contracts := []*db.Contract{
+		{ContractId: testContractId, LastEventLedger: 1000, LastEventCloseTime: 1761053046, ProposalCount: 4, Reviewed: true, Metadata: &db.ContractMetadata{ContractId: testContractId, Name: "Blend DAO", UpdatedAt: 1761053000}, Events: db.EventCounts{Seen: 10, Parsed: 9, Failed: 1}},
+		{ContractId: "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC", LastEventLedger: 400, LastEventCloseTime: 1761050046, Blocked: true, Reviewed: true},
+		{ContractId: unreviewedId, LastEventLedger: 1000, LastEventCloseTime: 1761053046, ProposalCount: 1},
+	}
+	responses := []*ContractResponse{
+		{ContractId: testContractId, LastEventLedger: 1000, LastEventCloseTime: 1761053046, LastEventCloseTimeIso: "2025-10-21T13:24:06Z", LastEventAgeSeconds: 54, ProposalCount: 4, Reviewed: true, Metadata: &ContractMetadataResponse{Name: "Blend DAO", Links: map[string]string{}, UpdatedAt: 1761053000}, Events: ContractEventsResponse{Seen: 10, Parsed: 9, Failed: 1}},
+		{ContractId: "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC", LastEventLedger: 400, LastEventCloseTime: 1761050046, LastEventCloseTimeIso: "2025-10-21T12:34:06Z", LastEventAgeSeconds: 3054, Blocked: true, Reviewed: true},
+		{ContractId: unreviewedId, LastEventLedger: 1000, LastEventCloseTime: 1761053046, LastEventCloseTimeIso: "2025-10-21T13:24:06Z", LastEventAgeSeconds: 54, ProposalCount: 1},
+	}
+	tests := []struct {
+		name      string
+		path      string
+		contracts []*db.Contract
+		want      []*ContractResponse
+	}{
+		{name: "no contracts", path: "/v1/contracts", want: []*ContractResponse{}},
+		// blocked contracts are never listed
+		{name: "contracts", path: "/v1/contracts", contracts: contracts, want: responses[:1]},
+		{name: "include unreviewed", path: "/v1/contracts?include_unreviewed=true", contracts: contracts, want: []*ContractResponse{responses[0], responses[2]}},
+		{name: "review queue", path: "/v1/admin/contracts/unreviewed", contracts: contracts, want: responses[2:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getStatus: func(ctx context.Context, source string) (uint32, int64, error) { return 1000, 1761053046, nil },
+				getContracts: func(ctx context.Context) ([]*db.Contract, error) {
+					return slices.Clone(tt.contracts), nil
+				},
+			}
+			handler := newHandler(store, nil, &Config{AdminTokens: []string{testAdminToken}})
+			handler.indexStatus.now = func() time.Time { return now }
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var got []*ContractResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("contracts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetVotesSupport(t *testing.T) {
+	votes := []*governor.Vote{
+		{TxHash: "tx1", Voter: "GA", Support: 0, Amount: "10"},
+		{TxHash: "tx2", Voter: "GB", Support: 1, Amount: "20"},
+		{TxHash: "tx3", Voter: "GC", Support: 2, Amount: "30"},
+		{TxHash: "tx4", Voter: "GD", Support: 5, Amount: "40"},
+	}
+	store := &mockStore{
+		getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+			return slices.Clone(votes), nil
+		},
+		eachVoteByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error {
+			for _, vote := range votes {
+				if err := fn(vote); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{name: "all", query: "", wantStatus: http.StatusOK, want: []string{"tx1:0:against", "tx2:1:for", "tx3:2:abstain", "tx4:5:other"}},
+		{name: "numeric", query: "?support=1", wantStatus: http.StatusOK, want: []string{"tx2:1:for"}},
+		{name: "label", query: "?support=Against", wantStatus: http.StatusOK, want: []string{"tx1:0:against"}},
+		{name: "other", query: "?support=other", wantStatus: http.StatusOK, want: []string{"tx4:5:other"}},
+		{name: "unknown numeric", query: "?support=5", wantStatus: http.StatusOK, want: []string{"tx4:5:other"}},
+		{name: "invalid", query: "?support=yes", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals/1/votes"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []struct {
+				TxHash       string
+				Support      uint32
+				SupportLabel string `json:"support_label"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var gotVotes []string
+			for _, vote := range got {
+				gotVotes = append(gotVotes, fmt.Sprintf("%s:%d:%s", vote.TxHash, vote.Support, vote.SupportLabel))
+			}
+			if diff := cmp.Diff(tt.want, gotVotes); diff != "" {
+				t.Errorf("votes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// the filter also applies to CSV exports
+	req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals/1/votes?format=csv&support=abstain", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "tx3") || strings.Contains(body, "tx1") || strings.Contains(body, "tx4") {
+		t.Errorf("expected only tx3 in csv, got:\n%s", body)
+	}
+}
+
+func TestGetVoted(t *testing.T) {
+	var gotProposalIds []uint32
+	store := &mockStore{
+		getLatestVotesByVoter: func(ctx context.Context, contractId string, voter string, proposalIds []uint32) ([]*governor.Vote, error) {
+			if contractId != testContractId || voter != "GA" {
+				t.Errorf("got votes for %s by %s, want %s by GA", contractId, voter, testContractId)
+			}
+			gotProposalIds = proposalIds
+			return []*governor.Vote{
+				{TxHash: "tx1", ProposalId: 1, Voter: "GA", Support: 1, Amount: "100"},
+				{TxHash: "tx3", ProposalId: 3, Voter: "GA", Support: 7, Amount: "300"},
+			}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+	path := "/v1/" + testContractId + "/voters/GA/voted"
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`[3, 2, 1, 3]`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if diff := cmp.Diff([]uint32{1, 2, 3}, gotProposalIds); diff != "" {
+		t.Errorf("proposal ids mismatch (-want +got):\n%s", diff)
+	}
+	var got map[string]VotedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for1, other := uint32(1), uint32(7)
+	want := map[string]VotedResponse{
+		"1": {Voted: true, Support: &for1, SupportLabel: "for", Amount: "100"},
+		"2": {Voted: false},
+		"3": {Voted: true, Support: &other, SupportLabel: "other", Amount: "300"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("voted mismatch (-want +got):\n%s", diff)
+	}
+
+	tooMany := "[" + strings.Repeat("1,", MAX_VOTED_PROPOSALS) + "1]"
+	for _, body := range []string{``, `[]`, `{"1": true}`, `[-1]`, tooMany} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected status %d, got %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestGetEventsFilter(t *testing.T) {
+	var gotFilter db.Filter
+	store := &mockStore{
+		filterEventsByContractId: func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+			gotFilter = filter
+			return nil, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+
+	tests := []struct {
+		filter     string
+		wantStatus int
+		want       db.Filter
+	}{
+		{filter: "", wantStatus: http.StatusOK},
+		{
+			filter:     "event_type = vote_cast AND amount > 1000000",
+			wantStatus: http.StatusOK,
+			want: db.Filter{
+				{Field: "event_type", Op: "=", Values: []string{"vote_cast"}},
+				{Field: "amount", Op: ">", Values: []string{"1000000"}},
+			},
+		},
+		{filter: "event_data = x", wantStatus: http.StatusBadRequest},
+		{filter: "proposal_id = 1; DROP TABLE history", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		gotFilter = nil
+		req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/events?filter="+url.QueryEscape(tt.filter), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%q: expected status %d, got %d", tt.filter, tt.wantStatus, rec.Code)
+		}
+		if diff := cmp.Diff(tt.want, gotFilter); diff != "" {
+			t.Errorf("%q: filter mismatch (-want +got):\n%s", tt.filter, diff)
+		}
+	}
+
+	// values the store rejects for their field are also a 400
+	store.filterEventsByContractId = func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+		return nil, fmt.Errorf("filter proposal_id = one: %w", db.ErrInvalidFilter)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/"+testContractId+"/events?filter="+url.QueryEscape("proposal_id = one"), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestGetProposalRPCFallback(t *testing.T) {
+	now := time.Unix(1761053046, 0)
+	onChain := &governor.Proposal{
+		ProposalKey:  governor.EncodeProposalKey(testContractId, 4),
+		ContractId:   testContractId,
+		ProposalId:   4,
+		Title:        "New proposal",
+		VoteStart:    1000,
+		VoteEnd:      2000,
+		VotesFor:     "0",
+		VotesAgainst: "0",
+		VotesAbstain: "0",
+	}
+	reads := 0
+	var readErr error
+	store := &mockStore{
+		getProposal: func(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+			return nil, db.ErrNotFound
+		},
+		getContract: func(ctx context.Context, contractId string) (*db.Contract, error) {
+			if contractId != testContractId {
+				return nil, db.ErrNotFound
+			}
+			return &db.Contract{ContractId: contractId}, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+	handler.rpcProposals = &rpcProposals{
+		read: func(ctx context.Context, contractId string, proposalId uint32) (*governor.Proposal, error) {
+			reads++
+			if readErr != nil {
+				return nil, readErr
+			}
+			if proposalId != 4 {
+				return nil, nil
+			}
+			return onChain, nil
+		},
+		cache: make(map[string]rpcProposalEntry),
+		now:   func() time.Time { return now },
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/v1/" + testContractId + "/proposals/4")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var got struct {
+		ProposalId uint32
+		Title      string
+		Source     string `json:"source"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.ProposalId != 4 || got.Title != "New proposal" || got.Source != PROPOSAL_SOURCE_RPC {
+		t.Errorf("got proposal %d %q from %q, want 4 \"New proposal\" from rpc", got.ProposalId, got.Title, got.Source)
+	}
+
+	// the proposal is cached, as is the absence of a proposal
+	get("/v1/" + testContractId + "/proposals/4")
+	for range 2 {
+		if rec := get("/v1/" + testContractId + "/proposals/5"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for a proposal missing on-chain, got %d", http.StatusNotFound, rec.Code)
+		}
+	}
+	if reads != 2 {
+		t.Errorf("got %d reads, want 2", reads)
+	}
+
+	// untracked contracts are never read from RPC
+	otherId := "CBWH54OKUK6U2J2A4J2REKRNN6RS2LM6DWXB4PAJCC3UAY4PC3VCSWOJ"
+	if rec := get("/v1/" + otherId + "/proposals/4"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an untracked contract, got %d", http.StatusNotFound, rec.Code)
+	}
+	if reads != 2 {
+		t.Errorf("got %d reads after an untracked contract, want 2", reads)
+	}
+
+	// failed reads are a 404, and not cached
+	now = now.Add(RPC_PROPOSAL_TTL)
+	readErr = errors.New("rpc unavailable")
+	if rec := get("/v1/" + testContractId + "/proposals/4"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a failed read, got %d", http.StatusNotFound, rec.Code)
+	}
+	readErr = nil
+	if rec := get("/v1/" + testContractId + "/proposals/4"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d after a failed read, got %d", http.StatusOK, rec.Code)
+	}
+	if reads != 4 {
+		t.Errorf("got %d reads, want 4", reads)
+	}
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	var exportErr error
+	var exportHasDeadline bool
+	store := &mockStore{
+		// a slow store, that only returns when the request is cancelled
+		getProposalsByContractId: func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		// a slow export, that streams past the list route's timeout
+		eachProposalByContractId: func(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error {
+			_, exportHasDeadline = ctx.Deadline()
+			time.Sleep(150 * time.Millisecond)
+			exportErr = ctx.Err()
+			return fn(&governor.Proposal{ContractId: contractId, ProposalId: 1})
+		},
+		getContracts: func(ctx context.Context) ([]*db.Contract, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok || time.Until(deadline) < 30*time.Second {
+				t.Errorf("expected the default timeout of a minute, got deadline %v (set %v)", deadline, ok)
+			}
+			return nil, nil
+		},
+	}
+	handler := newHandler(store, nil, &Config{})
+	handler.timeouts = &requestTimeouts{
+		request: time.Minute,
+		export:  0,
+		routes:  map[string]time.Duration{"GET /{contractId}/proposals": 50 * time.Millisecond},
+	}
+
+	for _, prefix := range []string{"", API_VERSION_PREFIX} {
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, prefix+"/"+testContractId+"/proposals", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503 at the route's deadline, got %d: %s", prefix, rec.Code, rec.Body.String())
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+			t.Errorf("%s: expected the request cut off at 50ms, took %v", prefix, elapsed)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+testContractId+"/proposals?format=csv", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the export to complete, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if exportErr != nil || exportHasDeadline {
+		t.Errorf("expected the export without a deadline, got err %v, deadline %v", exportErr, exportHasDeadline)
+	}
+	if lines := strings.Count(rec.Body.String(), "\n"); lines != 2 {
+		t.Errorf("expected the header and one proposal, got %d lines: %s", lines, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/contracts", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected contracts, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	handler := newHandler(&mockStore{}, nil, &Config{})
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodGet, path: "/health", want: "GET /health"},
+		{method: http.MethodGet, path: "/" + testContractId + "/proposals", want: "GET /{contractId}/proposals"},
+		{method: http.MethodGet, path: API_VERSION_PREFIX + "/" + testContractId + "/proposals/1/votes", want: "GET /{contractId}/proposals/{proposalId}/votes"},
+		{method: http.MethodGet, path: API_VERSION_PREFIX + "/transactions/abc/events", want: "GET /transactions/{txHash}/events"},
+		{method: http.MethodGet, path: "/transactions/abc/events", want: "GET /transactions/{txHash}/events"},
+		{method: http.MethodPost, path: API_VERSION_PREFIX + "/admin/failed-events/reprocess", want: "POST /admin/failed-events/reprocess"},
+	}
+	for _, tt := range tests {
+		if got := handler.routePattern(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("routePattern(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateLists(t *testing.T) {
+	votes := []*governor.Vote{
+		{TxHash: "tx1", ContractId: testContractId, ProposalId: 1, Voter: "voter1", Support: 1, Amount: "100"},
+		{TxHash: "tx2", ContractId: testContractId, ProposalId: 1, Voter: "voter2", Support: 0, Amount: "200"},
+		{TxHash: "tx3", ContractId: testContractId, ProposalId: 1, Voter: "voter3", Support: 1, Amount: "300"},
+	}
+	store := &mockStore{
+		getVotesByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+			return slices.Clone(votes), nil
+		},
+		eachVoteByProposal: func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error {
+			for _, vote := range votes {
+				if err := fn(vote); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		maxListRows   int
+		wantVotes     int
+		wantTruncated bool
+	}{
+		{name: "truncated", path: "/v1/" + testContractId + "/proposals/1/votes", maxListRows: 2, wantVotes: 2, wantTruncated: true},
+		{name: "legacy alias truncated", path: "/" + testContractId + "/proposals/1/votes", maxListRows: 2, wantVotes: 2, wantTruncated: true},
+		{name: "filtered under the limit", path: "/v1/" + testContractId + "/proposals/1/votes?support=for", maxListRows: 2, wantVotes: 2},
+		{name: "at the limit", path: "/v1/" + testContractId + "/proposals/1/votes", maxListRows: 3, wantVotes: 3},
+		{name: "no limit", path: "/v1/" + testContractId + "/proposals/1/votes", maxListRows: 0, wantVotes: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newHandler(store, nil, &Config{MaxListRows: tt.maxListRows})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var got []*VoteResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(got) != tt.wantVotes {
+				t.Errorf("expected %d votes, got %d", tt.wantVotes, len(got))
+			}
+			truncated := rec.Header().Get("X-Truncated") == "true"
+			if truncated != tt.wantTruncated {
+				t.Errorf("X-Truncated = %q, want truncated %v", rec.Header().Get("X-Truncated"), tt.wantTruncated)
+			}
+			if warning := rec.Header().Get("Warning"); truncated && !strings.HasPrefix(warning, `199 - "list truncated to 2 of 3 items`) {
+				t.Errorf("unexpected Warning header %q", warning)
+			}
+		})
+	}
+
+	// CSV exports are streamed whole
+	handler := newHandler(store, nil, &Config{MaxListRows: 2})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/"+testContractId+"/proposals/1/votes?format=csv", nil))
+	if lines := strings.Count(rec.Body.String(), "\n"); lines != 4 || rec.Header().Get("X-Truncated") != "" {
+		t.Errorf("expected the header and 3 votes untruncated, got %d lines, X-Truncated %q", lines, rec.Header().Get("X-Truncated"))
+	}
+
+	// a response with several lists names every truncated list once, in the header and the body
+	delegations := []*governor.Delegation{{Delegator: "delegator1"}, {Delegator: "delegator2"}, {Delegator: "delegator3"}}
+	store.getDelegators = func(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error) {
+		return slices.Clone(delegations), nil
+	}
+	store.getDelegationHistory = func(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error) {
+		return slices.Clone(delegations), nil
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/"+testContractId+"/delegates/delegate1", nil))
+	var delegates DelegatesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&delegates); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	wantWarning := "delegators truncated to 2 of 3 items, history truncated to 2 of 3 items, see API_MAX_LIST_ROWS"
+	if len(delegates.Delegators) != 2 || len(delegates.History) != 2 || delegates.Warning != wantWarning {
+		t.Errorf("got %d delegators and %d history entries with warning %q, want 2 and 2 with %q", len(delegates.Delegators), len(delegates.History), delegates.Warning, wantWarning)
+	}
+	if got := rec.Header().Values("Warning"); len(got) != 1 || got[0] != `199 - "`+wantWarning+`"` {
+		t.Errorf("got Warning headers %q, want one naming both lists", got)
+	}
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+	"github.com/script3/soroban-governor-backend/internal/ledgerfixture"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/network"
+)
+
+// fixtureContractId is the governor contract of the ledger fixtures in the indexer's testdata
+const fixtureContractId = "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+
+// TestWireFormat is the contract test of the /v1 wire format. The recorded ledger fixtures are indexed into a sqlite
+// store, and the full JSON of each response is compared, so a field that is added, removed, renamed or encoded
+// differently fails it. A change that fails it is a change to the API, and needs a new API version if it breaks
+// clients.
+func TestWireFormat(t *testing.T) {
+	indexedAfter := time.Now().Unix()
+	store, closeTime := replayLedgerFixtures(t)
+	handler := newHandler(store, indexer.NewIndexer(store), &Config{})
+	// the fixtures were recorded in 2025, so the clock is pinned shortly after the last of them closed
+	handler.indexStatus.now = func() time.Time { return time.Unix(closeTime+5, 0) }
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "health",
+			path: "/health",
+			want: `{
+				"status": 1170140,
+				"has_gaps": false
+			}`,
+		},
+		{
+			name: "proposal",
+			path: "/v1/" + fixtureContractId + "/proposals/1",
+			want: `{
+				"ProposalKey": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB-1",
+				"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+				"ProposalId": 1,
+				"Proposer": "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				"Status": 4,
+				"Title": "Make me security council",
+				"Description": "plz",
+				"Action": "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				"ActionType": "council",
+				"ActionContractId": "",
+				"ActionFunction": "",
+				"VoteStart": 1159020,
+				"VoteEnd": 1176300,
+				"VotesFor": "1230000000",
+				"VotesAgainst": "20000000000",
+				"VotesAbstain": "0",
+				"ExecutionUnlock": 0,
+				"ExecutionTxHash": "8172628e3b2da329cd1f43854ebe1badb4b91330d35038dd103ae14188bcad5a",
+				"Truncated": false,
+				"FlaggedLowParticipation": false,
+				"CreatedLedger": 1170134,
+				"CreatedTxHash": "18663550f9c9ea1375de182b044073b7b65d2277c280b78a04ddc26fbe9a264e",
+				"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+				"UpdatedLedger": 1170140,
+				"UpdatedEventId": "0005025713031745536-0000000000",
+				"UpdatedAt": 0,
+				"vote_start_time_estimate": "2025-10-20T21:57:45Z",
+				"vote_end_time_estimate": "2025-10-21T21:57:45Z"
+			}`,
+		},
+		{
+			name: "votes",
+			path: "/v1/" + fixtureContractId + "/proposals/1/votes",
+			want: `[
+				{
+					"TxHash": "90b9fcd255ca8d9a8682d3bbf1a1c1afcf3c2b5a5704cfb87a3b1b92e5c017ee",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 1,
+					"Voter": "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+					"Support": 0,
+					"Amount": "20000000000",
+					"LedgerSeq": 1170136,
+					"LedgerCloseTime": 1761053046,
+					"Reason": "",
+					"support_label": "against",
+					"ledger_close_time_iso": "2025-10-21T13:24:06Z"
+				}
+			]`,
+		},
+		{
+			name: "vote summary",
+			path: "/v1/" + fixtureContractId + "/proposals/1/votes/summary",
+			want: `{
+				"for": {
+					"voters": 0,
+					"total": "0",
+					"max": "0"
+				},
+				"against": {
+					"voters": 1,
+					"total": "20000000000",
+					"max": "20000000000"
+				},
+				"abstain": {
+					"voters": 0,
+					"total": "0",
+					"max": "0"
+				}
+			}`,
+		},
+		{
+			name: "events",
+			path: "/v1/" + fixtureContractId + "/events",
+			want: `[
+				{
+					"EventId": "0005025687261941760-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 1,
+					"EventType": "proposal_created",
+					"EventData": "{\"proposer\":\"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q\",\"title\":\"Make me security council\",\"desc\":\"plz\",\"action\":\"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl\",\"vote_start\":1159020,\"vote_end\":1176300}",
+					"TxHash": "18663550f9c9ea1375de182b044073b7b65d2277c280b78a04ddc26fbe9a264e",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170134,
+					"LedgerCloseTime": 1761053041,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025687261945856-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 2,
+					"EventType": "proposal_created",
+					"EventData": "{\"proposer\":\"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q\",\"title\":\"Make me security council\",\"desc\":\"plz\",\"action\":\"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl\",\"vote_start\":1159020,\"vote_end\":1176300}",
+					"TxHash": "f4acaca732d89ec24932d0274a04f6330f2845e5af4782bd12d834d23f7fae49",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170134,
+					"LedgerCloseTime": 1761053041,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025687261954048-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 3,
+					"EventType": "proposal_created",
+					"EventData": "{\"proposer\":\"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q\",\"title\":\"Make me security council\",\"desc\":\"plz\",\"action\":\"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl\",\"vote_start\":1159020,\"vote_end\":1176300}",
+					"TxHash": "7cddc74d0eb76e765767ea06718c52f5d218216fd463a6d8faeff32de1239db0",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170134,
+					"LedgerCloseTime": 1761053041,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025695851876352-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 1,
+					"EventType": "vote_cast",
+					"EventData": "{\"voter\":\"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q\",\"support\":0,\"amount\":\"20000000000\"}",
+					"TxHash": "90b9fcd255ca8d9a8682d3bbf1a1c1afcf3c2b5a5704cfb87a3b1b92e5c017ee",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170136,
+					"LedgerCloseTime": 1761053046,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025695851884544-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 3,
+					"EventType": "proposal_canceled",
+					"EventData": "{}",
+					"TxHash": "6e4b3a112a3cdf2a084cea980f6a5ca8a64a901f393b156d1c313f44af08cafb",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170136,
+					"LedgerCloseTime": 1761053046,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025700146843648-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 1,
+					"EventType": "proposal_voting_closed",
+					"EventData": "{\"status\":1,\"eta\":0,\"final_votes\":{\"for\":\"1230000000\",\"against\":\"20000000000\",\"abstain\":\"0\"}}",
+					"TxHash": "237a40cad3da7e77d9a72cca4441a014c908d9bf4ccad32c691079633de64c4f",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170137,
+					"LedgerCloseTime": 1761053050,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025700146843648-0000000001",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 2,
+					"EventType": "proposal_expired",
+					"EventData": "{}",
+					"TxHash": "237a40cad3da7e77d9a72cca4441a014c908d9bf4ccad32c691079633de64c4f",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170137,
+					"LedgerCloseTime": 1761053050,
+					"SchemaVersion": 1
+				},
+				{
+					"EventId": "0005025713031745536-0000000000",
+					"ContractId": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+					"ProposalId": 1,
+					"EventType": "proposal_executed",
+					"EventData": "{}",
+					"TxHash": "8172628e3b2da329cd1f43854ebe1badb4b91330d35038dd103ae14188bcad5a",
+					"SourceAccount": "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4",
+					"LedgerSeq": 1170140,
+					"LedgerCloseTime": 1761053065,
+					"SchemaVersion": 1
+				}
+			]`,
+		},
+		{
+			name: "contract summary",
+			path: "/v1/" + fixtureContractId + "/summary",
+			want: `{
+				"contract_id": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+				"last_event_ledger": 1170140,
+				"last_event_close_time": 1761053065,
+				"last_event_close_time_iso": "2025-10-21T13:24:25Z",
+				"last_event_age_seconds": 5,
+				"proposal_count": 3,
+				"blocked": false,
+				"reviewed": true,
+				"metadata": null,
+				"token": null,
+				"events": {
+					"seen": 8,
+					"parsed": 8,
+					"failed": 0
+				}
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", contentType)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("failed to decode expected response: %v", err)
+			}
+			zeroUpdatedAt(t, got, indexedAfter)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// replayLedgerFixtures applies the indexer's ledger fixtures to a new sqlite store, recording the indexer status and
+// the event counts of each contract after each ledger as the indexer does, and returns the store and the close time
+// of the last ledger
+func replayLedgerFixtures(t *testing.T) (*db.Store, int64) {
+	t.Helper()
+	ctx := t.Context()
+
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	store := db.NewStore(sqlDb)
+	idx := indexer.NewIndexer(store)
+
+	backend, err := ledgerfixture.NewBackend(filepath.Join("..", "indexer", "testdata", "ledgers"))
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	defer backend.Close()
+	seqs := backend.Sequences()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seqs[0], seqs[len(seqs)-1])); err != nil {
+		t.Fatalf("failed to prepare range: %v", err)
+	}
+
+	var closeTime int64
+	for _, seq := range seqs {
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatalf("failed to get ledger %d: %v", seq, err)
+		}
+		txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, ledger)
+		if err != nil {
+			t.Fatalf("failed to create transaction reader for ledger %d: %v", seq, err)
+		}
+		closeTime = ledger.LedgerCloseTime()
+		stats, err := idx.ApplyLedger(ctx, txReader, seq, closeTime)
+		if err != nil {
+			t.Fatalf("ApplyLedger(%d) error = %v", seq, err)
+		}
+		// record the event counts of each contract, as the indexer's run loop does
+		idx.RecordContractEvents(ctx, stats.Contracts)
+		if err := store.UpsertStatus(ctx, indexer.STATUS_SOURCE, seq, closeTime); err != nil {
+			t.Fatalf("failed to record status of ledger %d: %v", seq, err)
+		}
+	}
+	return store, closeTime
+}
+
+// zeroUpdatedAt sets each UpdatedAt of a decoded response to 0, after checking it is no earlier than after. UpdatedAt
+// is the time a proposal was indexed, by the clock of the machine running the test.
+func zeroUpdatedAt(t *testing.T, v any, after int64) {
+	t.Helper()
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if key != "UpdatedAt" {
+				zeroUpdatedAt(t, value, after)
+				continue
+			}
+			if updatedAt, ok := value.(float64); !ok || int64(updatedAt) < after {
+				t.Errorf("expected UpdatedAt at or after %d, got %v", after, value)
+			}
+			v[key] = float64(0)
+		}
+	case []any:
+		for _, value := range v {
+			zeroUpdatedAt(t, value, after)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job tracks the progress of a long running admin operation
+type Job struct {
+	Id         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	ContractId string    `json:"contract_id"`
+	Status     JobStatus `json:"status"`
+	Processed  int       `json:"processed"`
+	Total      int       `json:"total"`
+	// Result is the outcome of the job so far, for kinds of jobs that report more than their progress, such as the
+	// ReprocessFailedEventsResponse of a reprocess job
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// MAX_FINISHED_JOBS is the number of finished jobs kept by the job registry. Once more jobs have finished, the ones
+// that finished first are dropped, and are no longer found. Running jobs are always kept.
+const MAX_FINISHED_JOBS = 100
+
+// jobRegistry is an in-memory registry of admin jobs. Jobs do not survive a restart of the API service.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// finished are the ids of the finished jobs, in the order they finished
+	finished []string
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]*Job)}
+}
+
+// start registers a new running job and returns a copy of it
+func (r *jobRegistry) start(kind string, contractId string) Job {
+	job := newJob(kind, contractId)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.Id] = job
+	return *job
+}
+
+// startExclusive registers a new running job like start, unless a job of the same kind is running, in which case a
+// copy of the running job is returned with false
+func (r *jobRegistry) startExclusive(kind string, contractId string) (Job, bool) {
+	job := newJob(kind, contractId)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, running := range r.jobs {
+		if running.Kind == kind && running.Status == JobRunning {
+			return *running, false
+		}
+	}
+	r.jobs[job.Id] = job
+	return *job, true
+}
+
+func newJob(kind string, contractId string) *Job {
+	idBytes := make([]byte, 16)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(idBytes)
+
+	return &Job{
+		Id:         hex.EncodeToString(idBytes),
+		Kind:       kind,
+		ContractId: contractId,
+		Status:     JobRunning,
+		StartedAt:  time.Now().UTC(),
+	}
+}
+
+// progress updates the progress counters of a running job
+func (r *jobRegistry) progress(id string, processed int, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; ok {
+		job.Processed = processed
+		job.Total = total
+	}
+}
+
+// setResult updates the result of a running job
+func (r *jobRegistry) setResult(id string, result any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; ok {
+		job.Result = result
+	}
+}
+
+// finish marks a job as succeeded, or failed if err is not nil, and drops the oldest finished jobs beyond
+// MAX_FINISHED_JOBS
+func (r *jobRegistry) finish(id string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.Status != JobRunning {
+		return
+	}
+	r.finished = append(r.finished, id)
+	for len(r.finished) > MAX_FINISHED_JOBS {
+		delete(r.jobs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobSucceeded
+	}
+}
+
+// get returns a copy of the job with the given id
+func (r *jobRegistry) get(id string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+// MAX_METADATA_BYTES is the maximum size of a contract metadata request body
+const MAX_METADATA_BYTES = 64 * 1024
+
+// MAX_METADATA_LINKS is the maximum number of links in a contract's metadata
+const MAX_METADATA_LINKS = 20
+
+// ContractMetadataRequest is the request body setting a contract's metadata, and the value of each contract in the
+// CONTRACT_METADATA_FILE
+type ContractMetadataRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	IconUrl     string            `json:"icon_url"`
+	Website     string            `json:"website"`
+	Links       map[string]string `json:"links"`
+}
+
+// validate returns an error describing the first invalid field. The name is required, and every URL must be an
+// absolute http or https URL.
+func (req *ContractMetadataRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if req.IconUrl != "" && !isHTTPURL(req.IconUrl) {
+		return errors.New("icon_url must be an http or https URL")
+	}
+	if req.Website != "" && !isHTTPURL(req.Website) {
+		return errors.New("website must be an http or https URL")
+	}
+	if len(req.Links) > MAX_METADATA_LINKS {
+		return fmt.Errorf("links must have at most %d entries", MAX_METADATA_LINKS)
+	}
+	for name, link := range req.Links {
+		if name == "" || !isHTTPURL(link) {
+			return fmt.Errorf("link %q must be a named http or https URL", name)
+		}
+	}
+	return nil
+}
+
+// toMetadata returns the metadata of contractId set by the request at updatedAt
+func (req *ContractMetadataRequest) toMetadata(contractId string, updatedAt int64) *db.ContractMetadata {
+	return &db.ContractMetadata{
+		ContractId:  contractId,
+		Name:        req.Name,
+		Description: req.Description,
+		IconUrl:     req.IconUrl,
+		Website:     req.Website,
+		Links:       req.Links,
+		UpdatedAt:   updatedAt,
+	}
+}
+
+// isHTTPURL returns true if val is an absolute http or https URL
+func isHTTPURL(val string) bool {
+	u, err := url.Parse(val)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// decodeStrict decodes the JSON value in data into v, rejecting unknown fields so misspelled fields aren't dropped
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// handleGetContract returns a governor in the contracts registry, with its metadata
+func (h *Handler) handleGetContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	contract, err := h.store.GetContract(r.Context(), contractId)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "contract not found")
+		return
+	} else if err != nil {
+		slog.Error("Failed to get contract", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve contract")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newContractResponse(contract, h.indexStatus.now().Unix()))
+}
+
+// handleSetContractMetadata replaces the metadata of a contract with the request body. Metadata can be set for a
+// contract before it is registered.
+func (h *Handler) handleSetContractMetadata(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	if _, err := strkey.Decode(strkey.VersionByteContract, contractId); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+	body, err := readBody(w, r, MAX_METADATA_BYTES)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	var req ContractMetadataRequest
+	if err := decodeStrict(body, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid metadata: "+err.Error())
+		return
+	}
+	if err := req.validate(); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid metadata: "+err.Error())
+		return
+	}
+
+	metadata := req.toMetadata(contractId, time.Now().Unix())
+	if err := h.store.UpsertContractMetadata(r.Context(), metadata, true); err != nil {
+		slog.Error("Failed to set contract metadata", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to set contract metadata")
+		return
+	}
+	slog.Info("Set contract metadata", "contract", contractId, "name", metadata.Name)
+
+	respondJSON(w, http.StatusOK, newContractMetadataResponse(metadata))
+}
+
+// handleDeleteContractMetadata deletes the metadata of a contract
+func (h *Handler) handleDeleteContractMetadata(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("contractId")
+	err := h.store.DeleteContractMetadata(r.Context(), contractId)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "contract has no metadata")
+		return
+	} else if err != nil {
+		slog.Error("Failed to delete contract metadata", "contract", contractId, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to delete contract metadata")
+		return
+	}
+	slog.Info("Deleted contract metadata", "contract", contractId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readBody reads the request body, failing if it is larger than maxBytes
+func readBody(w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, error) {
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, maxBytes)); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// ContractMetadataStore is the subset of db.Store used to bootstrap contract metadata
+type ContractMetadataStore interface {
+	UpsertContractMetadata(ctx context.Context, metadata *db.ContractMetadata, replace bool) error
+}
+
+// LoadContractMetadataFile sets the metadata of each contract in the JSON file at path, an object of contract ids
+// to ContractMetadataRequest values. Contracts that already have metadata, such as metadata set through the admin
+// endpoints, are left unchanged, so the file only bootstraps the registry. The file is validated before any
+// metadata is set.
+func LoadContractMetadataFile(ctx context.Context, store ContractMetadataStore, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read contract metadata file: %w", err)
+	}
+	var contracts map[string]ContractMetadataRequest
+	if err := decodeStrict(data, &contracts); err != nil {
+		return fmt.Errorf("decode contract metadata file %s: %w", path, err)
+	}
+	for contractId, req := range contracts {
+		if _, err := strkey.Decode(strkey.VersionByteContract, contractId); err != nil {
+			return fmt.Errorf("contract metadata file %s: invalid contract id %q", path, contractId)
+		}
+		if err := req.validate(); err != nil {
+			return fmt.Errorf("contract metadata file %s: contract %s: %w", path, contractId, err)
+		}
+	}
+
+	now := time.Now().Unix()
+	for contractId, req := range contracts {
+		if err := store.UpsertContractMetadata(ctx, req.toMetadata(contractId, now), false); err != nil {
+			return err
+		}
+	}
+	slog.Info("Loaded contract metadata file", "path", path, "contracts", len(contracts))
+	return nil
+}
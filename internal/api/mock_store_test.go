@@ -0,0 +1,375 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+var errUnexpectedCall = errors.New("unexpected store call")
+
+// mockStore is a Store whose methods are set per test. Methods that are not set return errUnexpectedCall.
+type mockStore struct {
+	filterEventsByContractId    func(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error)
+	getRecentEvents             func(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error)
+	getEventsByProposal         func(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error)
+	getEventsByTxHash           func(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error)
+	getFailedEventsByContractId func(ctx context.Context, contractId string) ([]*governor.FailedEvent, error)
+	getFailedEvents             func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error)
+	countFailedEvents           func(ctx context.Context, filter db.FailedEventFilter) (int, error)
+	getFailedTxEvents           func(ctx context.Context, contractId string, includeUnreviewed bool, afterEventId string, limit int) ([]*governor.FailedTxEvent, error)
+	countFailedTxEvents         func(ctx context.Context, contractId string, includeUnreviewed bool) (int, error)
+	getStatus                   func(ctx context.Context, source string) (uint32, int64, error)
+	getSourceStatus             func(ctx context.Context, source string) (*db.SourceStatus, error)
+	getIngestionProgress        func(ctx context.Context, source string) (*db.IngestionProgress, error)
+	getCoverageGaps             func(ctx context.Context) ([]*db.CoverageGap, error)
+	getIndexerErrors            func(ctx context.Context, limit int) ([]*db.IndexerError, error)
+	countCoverageGaps           func(ctx context.Context) (int, error)
+	getProposal                 func(ctx context.Context, proposalKey string) (*governor.Proposal, error)
+	getProposalsByContractId    func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error)
+	eachProposalByContractId    func(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error
+	getProposalsByStatus        func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error)
+	countProposalsByStatus      func(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error)
+	getProposalsEndingBetween   func(ctx context.Context, fromLedger uint32, toLedger uint32) ([]*governor.Proposal, error)
+	getExecutableProposals      func(ctx context.Context, ledger uint32) ([]*governor.Proposal, error)
+	getProposalsByKeys          func(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error)
+	getProposalContent          func(ctx context.Context, proposalKey string) (*governor.ProposalContent, error)
+	getVote                     func(ctx context.Context, txHash string) (*governor.Vote, error)
+	getVoteByProposalAndVoter   func(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error)
+	getLatestVotesByVoter       func(ctx context.Context, contractId string, voter string, proposalIds []uint32) ([]*governor.Vote, error)
+	getVotesByProposal          func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error)
+	eachVoteByProposal          func(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error
+	getVoteSummary              func(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error)
+	getVoteSeries               func(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error)
+	getDelegators               func(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error)
+	getDelegationHistory        func(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error)
+	deleteContractData          func(ctx context.Context, contractId string) (map[string]int64, error)
+	getContracts                func(ctx context.Context) ([]*db.Contract, error)
+	getContract                 func(ctx context.Context, contractId string) (*db.Contract, error)
+	getGovernorSettings         func(ctx context.Context, governorId string, ledgerSeq uint32) (*governor.Settings, error)
+	getVoteTokens               func(ctx context.Context) ([]*db.VoteToken, error)
+	setContractReviewed         func(ctx context.Context, contractId string, reviewed bool) error
+	getUnreviewedContractIds    func(ctx context.Context) ([]string, error)
+	upsertContractMetadata      func(ctx context.Context, metadata *db.ContractMetadata, replace bool) error
+	deleteContractMetadata      func(ctx context.Context, contractId string) error
+	blockContract               func(ctx context.Context, contractId string, reason string, createdAt int64) error
+	unblockContract             func(ctx context.Context, contractId string) error
+	getBlockedContracts         func(ctx context.Context) ([]*db.BlockedContract, error)
+}
+
+func (m *mockStore) FilterEventsByContractId(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error) {
+	if m.filterEventsByContractId == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.filterEventsByContractId(ctx, contractId, filter, sort)
+}
+
+func (m *mockStore) GetRecentEvents(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+	if m.getRecentEvents == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getRecentEvents(ctx, eventType, limit)
+}
+
+func (m *mockStore) GetEventsByProposal(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+	if m.getEventsByProposal == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getEventsByProposal(ctx, contractId, proposalId)
+}
+
+func (m *mockStore) GetEventsByTxHash(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error) {
+	if m.getEventsByTxHash == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getEventsByTxHash(ctx, txHash)
+}
+
+func (m *mockStore) GetFailedEventsByContractId(ctx context.Context, contractId string) ([]*governor.FailedEvent, error) {
+	if m.getFailedEventsByContractId == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getFailedEventsByContractId(ctx, contractId)
+}
+
+func (m *mockStore) GetFailedEvents(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+	if m.getFailedEvents == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getFailedEvents(ctx, filter, afterEventId, limit)
+}
+
+func (m *mockStore) CountFailedEvents(ctx context.Context, filter db.FailedEventFilter) (int, error) {
+	if m.countFailedEvents == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.countFailedEvents(ctx, filter)
+}
+
+func (m *mockStore) GetFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool, afterEventId string, limit int) ([]*governor.FailedTxEvent, error) {
+	if m.getFailedTxEvents == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getFailedTxEvents(ctx, contractId, includeUnreviewed, afterEventId, limit)
+}
+
+func (m *mockStore) CountFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool) (int, error) {
+	if m.countFailedTxEvents == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.countFailedTxEvents(ctx, contractId, includeUnreviewed)
+}
+
+func (m *mockStore) GetStatus(ctx context.Context, source string) (uint32, int64, error) {
+	if m.getStatus == nil {
+		return 0, 0, errUnexpectedCall
+	}
+	return m.getStatus(ctx, source)
+}
+
+func (m *mockStore) GetSourceStatus(ctx context.Context, source string) (*db.SourceStatus, error) {
+	if m.getSourceStatus == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getSourceStatus(ctx, source)
+}
+
+func (m *mockStore) GetIngestionProgress(ctx context.Context, source string) (*db.IngestionProgress, error) {
+	if m.getIngestionProgress == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getIngestionProgress(ctx, source)
+}
+
+func (m *mockStore) GetCoverageGaps(ctx context.Context) ([]*db.CoverageGap, error) {
+	if m.getCoverageGaps == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getCoverageGaps(ctx)
+}
+
+func (m *mockStore) GetIndexerErrors(ctx context.Context, limit int) ([]*db.IndexerError, error) {
+	if m.getIndexerErrors == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getIndexerErrors(ctx, limit)
+}
+
+func (m *mockStore) CountCoverageGaps(ctx context.Context) (int, error) {
+	if m.countCoverageGaps == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.countCoverageGaps(ctx)
+}
+
+func (m *mockStore) GetProposal(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+	if m.getProposal == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposal(ctx, proposalKey)
+}
+
+func (m *mockStore) GetProposalsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+	if m.getProposalsByContractId == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalsByContractId(ctx, contractId, sort)
+}
+
+func (m *mockStore) EachProposalByContractId(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error {
+	if m.eachProposalByContractId == nil {
+		return errUnexpectedCall
+	}
+	return m.eachProposalByContractId(ctx, contractId, sort, fn)
+}
+
+func (m *mockStore) GetProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+	if m.getProposalsByStatus == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalsByStatus(ctx, statuses, includeFlagged, includeUnreviewed, limit, cursor)
+}
+
+func (m *mockStore) CountProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error) {
+	if m.countProposalsByStatus == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.countProposalsByStatus(ctx, statuses, includeFlagged, includeUnreviewed)
+}
+
+func (m *mockStore) GetProposalsEndingBetween(ctx context.Context, fromLedger uint32, toLedger uint32) ([]*governor.Proposal, error) {
+	if m.getProposalsEndingBetween == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalsEndingBetween(ctx, fromLedger, toLedger)
+}
+
+func (m *mockStore) GetExecutableProposals(ctx context.Context, ledger uint32) ([]*governor.Proposal, error) {
+	if m.getExecutableProposals == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getExecutableProposals(ctx, ledger)
+}
+
+func (m *mockStore) GetProposalsByKeys(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error) {
+	if m.getProposalsByKeys == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalsByKeys(ctx, proposalKeys)
+}
+
+func (m *mockStore) GetProposalContent(ctx context.Context, proposalKey string) (*governor.ProposalContent, error) {
+	if m.getProposalContent == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalContent(ctx, proposalKey)
+}
+
+func (m *mockStore) GetVote(ctx context.Context, txHash string) (*governor.Vote, error) {
+	if m.getVote == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVote(ctx, txHash)
+}
+
+func (m *mockStore) GetVoteByProposalAndVoter(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error) {
+	if m.getVoteByProposalAndVoter == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVoteByProposalAndVoter(ctx, contractId, proposalId, voter)
+}
+
+func (m *mockStore) GetLatestVotesByVoter(ctx context.Context, contractId string, voter string, proposalIds []uint32) ([]*governor.Vote, error) {
+	if m.getLatestVotesByVoter == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getLatestVotesByVoter(ctx, contractId, voter, proposalIds)
+}
+
+func (m *mockStore) GetVotesByProposal(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error) {
+	if m.getVotesByProposal == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVotesByProposal(ctx, contractId, proposalId, sort)
+}
+
+func (m *mockStore) EachVoteByProposal(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error {
+	if m.eachVoteByProposal == nil {
+		return errUnexpectedCall
+	}
+	return m.eachVoteByProposal(ctx, contractId, proposalId, sort, fn)
+}
+
+func (m *mockStore) GetVoteSummary(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error) {
+	if m.getVoteSummary == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVoteSummary(ctx, contractId, proposalId)
+}
+
+func (m *mockStore) GetVoteSeries(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+	if m.getVoteSeries == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVoteSeries(ctx, contractId, proposalId, bucketSize)
+}
+
+func (m *mockStore) GetDelegators(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error) {
+	if m.getDelegators == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getDelegators(ctx, contractId, delegate)
+}
+
+func (m *mockStore) GetDelegationHistory(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error) {
+	if m.getDelegationHistory == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getDelegationHistory(ctx, contractId, address)
+}
+
+func (m *mockStore) DeleteContractData(ctx context.Context, contractId string) (map[string]int64, error) {
+	if m.deleteContractData == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.deleteContractData(ctx, contractId)
+}
+
+func (m *mockStore) GetContracts(ctx context.Context) ([]*db.Contract, error) {
+	if m.getContracts == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getContracts(ctx)
+}
+
+func (m *mockStore) GetContract(ctx context.Context, contractId string) (*db.Contract, error) {
+	if m.getContract == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getContract(ctx, contractId)
+}
+
+func (m *mockStore) GetGovernorSettings(ctx context.Context, governorId string, ledgerSeq uint32) (*governor.Settings, error) {
+	if m.getGovernorSettings == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getGovernorSettings(ctx, governorId, ledgerSeq)
+}
+
+func (m *mockStore) GetVoteTokens(ctx context.Context) ([]*db.VoteToken, error) {
+	if m.getVoteTokens == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVoteTokens(ctx)
+}
+
+func (m *mockStore) SetContractReviewed(ctx context.Context, contractId string, reviewed bool) error {
+	if m.setContractReviewed == nil {
+		return errUnexpectedCall
+	}
+	return m.setContractReviewed(ctx, contractId, reviewed)
+}
+
+func (m *mockStore) GetUnreviewedContractIds(ctx context.Context) ([]string, error) {
+	if m.getUnreviewedContractIds == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getUnreviewedContractIds(ctx)
+}
+
+func (m *mockStore) UpsertContractMetadata(ctx context.Context, metadata *db.ContractMetadata, replace bool) error {
+	if m.upsertContractMetadata == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertContractMetadata(ctx, metadata, replace)
+}
+
+func (m *mockStore) DeleteContractMetadata(ctx context.Context, contractId string) error {
+	if m.deleteContractMetadata == nil {
+		return errUnexpectedCall
+	}
+	return m.deleteContractMetadata(ctx, contractId)
+}
+
+func (m *mockStore) BlockContract(ctx context.Context, contractId string, reason string, createdAt int64) error {
+	if m.blockContract == nil {
+		return errUnexpectedCall
+	}
+	return m.blockContract(ctx, contractId, reason, createdAt)
+}
+
+func (m *mockStore) UnblockContract(ctx context.Context, contractId string) error {
+	if m.unblockContract == nil {
+		return errUnexpectedCall
+	}
+	return m.unblockContract(ctx, contractId)
+}
+
+func (m *mockStore) GetBlockedContracts(ctx context.Context) ([]*db.BlockedContract, error) {
+	if m.getBlockedContracts == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getBlockedContracts(ctx)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// COUNT_CACHE_TTL is how long the total of a paginated list is cached, so paging through a list doesn't count it
+// for every page
+const COUNT_CACHE_TTL = 5 * time.Second
+
+// Pagination describes a page of a list. Cursor is the cursor the page was requested with, and NextCursor is the
+// cursor of the next page, both null if there is none.
+type Pagination struct {
+	Limit      int     `json:"limit"`
+	Cursor     *string `json:"cursor"`
+	NextCursor *string `json:"next_cursor"`
+	Total      int     `json:"total"`
+	HasMore    bool    `json:"has_more"`
+}
+
+// Page is a page of a list, the shape of paginated list responses under API_VERSION_PREFIX
+type Page[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// newPagination returns the pagination of a page requested with limit and cursor. nextCursor is empty on the last
+// page.
+func newPagination(limit int, cursor string, nextCursor string, total int) Pagination {
+	pagination := Pagination{Limit: limit, Total: total, HasMore: nextCursor != ""}
+	if cursor != "" {
+		pagination.Cursor = &cursor
+	}
+	if nextCursor != "" {
+		pagination.NextCursor = &nextCursor
+	}
+	return pagination
+}
+
+// truncateList returns at most max items of a list that isn't paginated, or every item if max is 0. A truncated list
+// is marked with an X-Truncated header and a Warning header, as the list's JSON array has no room for a warning field.
+// Responses holding several lists truncate them with truncate and mark them once with markTruncated instead.
+func truncateList[T any](w http.ResponseWriter, r *http.Request, items []T, max int) []T {
+	items, warning := truncate("list", items, max)
+	markTruncated(w, r, warning)
+	return items
+}
+
+// truncate returns at most max items of the list called name, or every item if max is 0, and a warning naming the
+// list if it was truncated, or "" if not
+func truncate[T any](name string, items []T, max int) ([]T, string) {
+	if max <= 0 || len(items) <= max {
+		return items, ""
+	}
+	return items[:max], fmt.Sprintf("%s truncated to %d of %d items", name, max, len(items))
+}
+
+// markTruncated marks a response with an X-Truncated header and a single Warning header naming every truncated list,
+// and returns the warning, or "" if no list was truncated. Empty warnings are skipped.
+func markTruncated(w http.ResponseWriter, r *http.Request, warnings ...string) string {
+	warnings = slices.DeleteFunc(warnings, func(warning string) bool { return warning == "" })
+	if len(warnings) == 0 {
+		return ""
+	}
+	warning := strings.Join(warnings, ", ") + ", see API_MAX_LIST_ROWS"
+	slog.Warn("List truncated", "path", r.URL.Path, "warning", warning)
+	w.Header().Set("X-Truncated", "true")
+	w.Header().Set("Warning", fmt.Sprintf(`199 - "%s"`, warning))
+	return warning
+}
+
+type countEntry struct {
+	count   int
+	expires time.Time
+}
+
+// countCache caches the totals of paginated lists for COUNT_CACHE_TTL, keyed by the list and its filters
+type countCache struct {
+	mu      sync.Mutex
+	entries map[string]countEntry
+	now     func() time.Time
+}
+
+func newCountCache() *countCache {
+	return &countCache{entries: make(map[string]countEntry), now: time.Now}
+}
+
+// get returns the cached count for key, or calls count and caches the result. Expired entries are dropped when a
+// new count is cached, so the cache only holds lists counted in the last COUNT_CACHE_TTL. Failed counts are not
+// cached.
+func (c *countCache) get(ctx context.Context, key string, count func(ctx context.Context) (int, error)) (int, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.count, nil
+	}
+
+	total, err := count(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = countEntry{count: total, expires: now.Add(COUNT_CACHE_TTL)}
+	return total, nil
+}
+
+type versionedKey struct{}
+
+// versioned wraps the API routes mounted under API_VERSION_PREFIX, so handlers can respond with the current shapes
+func versioned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionedKey{}, true)))
+	})
+}
+
+// isVersioned returns true if the request was made under API_VERSION_PREFIX, rather than to a deprecated alias
+func isVersioned(r *http.Request) bool {
+	v, _ := r.Context().Value(versionedKey{}).(bool)
+	return v
+}
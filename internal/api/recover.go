@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+// MAX_REQUEST_ID_LENGTH is the longest X-Request-Id accepted from a client. Longer or missing IDs are replaced.
+const MAX_REQUEST_ID_LENGTH = 128
+
+// requestId returns the client's X-Request-Id, or a new random ID if it has none
+func requestId(r *http.Request) string {
+	if id := r.Header.Get("X-Request-Id"); id != "" && len(id) <= MAX_REQUEST_ID_LENGTH {
+		return id
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// responseWriter records whether the response headers have been written, so a panic can still be reported to the
+// client if they haven't
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client, for streamed responses
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoverPanic wraps a handler so a panic is logged with its stack trace and reported as a 500. If the response
+// was already started, such as a streamed export, the connection is aborted instead, so the client doesn't mistake a
+// partial response for a complete one.
+func recoverPanic(next http.Handler, requestId string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			metrics.HandlerPanics.Inc()
+			slog.Error("Handler panicked", "request_id", requestId, "method", r.Method, "path", r.URL.String(), "panic", p, "stack", string(debug.Stack()))
+			if rw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			respondError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
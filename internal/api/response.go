@@ -0,0 +1,377 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+const (
+	// ESTIMATED_LEDGER_CLOSE_SECONDS is the assumed time between ledgers, used to estimate when a ledger closes
+	ESTIMATED_LEDGER_CLOSE_SECONDS = 5
+	// DESCRIPTION_PREVIEW_LENGTH is the number of characters of a proposal's description included in its summary
+	DESCRIPTION_PREVIEW_LENGTH = 200
+)
+
+// VoteResponse is a vote with the label of its support, its ledger close time formatted as RFC3339, and its amount as
+// a number of vote tokens if the governor's vote token is known
+type VoteResponse struct {
+	*governor.Vote
+	// SupportLabel is "against", "for", "abstain", or "other" for support values with custom semantics
+	SupportLabel       string `json:"support_label"`
+	LedgerCloseTimeIso string `json:"ledger_close_time_iso"`
+	AmountFormatted    string `json:"amount_formatted,omitempty"`
+	TokenSymbol        string `json:"token_symbol,omitempty"`
+}
+
+// ProposalResponse is a proposal with the estimated times its voting period starts and ends, as RFC3339. The
+// estimates are extrapolated from the latest indexed ledger, and are omitted if no ledger has been indexed.
+type ProposalResponse struct {
+	*governor.Proposal
+	VoteStartTimeEstimate string `json:"vote_start_time_estimate,omitempty"`
+	VoteEndTimeEstimate   string `json:"vote_end_time_estimate,omitempty"`
+	// For queued proposals, the estimated time the proposal can be executed, or ExecutableNow if the execution
+	// unlock ledger has already been indexed
+	ExecutionUnlockTimeEstimate string `json:"execution_unlock_time_estimate,omitempty"`
+	ExecutableNow               bool   `json:"executable_now,omitempty"`
+	ProposalAmounts
+	// CountingType is the governor's counting type, and Results the proposal's tallies with it applied. Both are
+	// omitted if the governor's settings are not known.
+	CountingType *uint32                   `json:"counting_type,omitempty"`
+	Results      *governor.ProposalResults `json:"results,omitempty"`
+}
+
+// ProposalAmounts are the tallies of a proposal as a number of vote tokens, omitted if the governor's vote token is
+// not known
+type ProposalAmounts struct {
+	TokenSymbol           string `json:"token_symbol,omitempty"`
+	VotesForFormatted     string `json:"votes_for_formatted,omitempty"`
+	VotesAgainstFormatted string `json:"votes_against_formatted,omitempty"`
+	VotesAbstainFormatted string `json:"votes_abstain_formatted,omitempty"`
+}
+
+// newProposalAmounts returns the tallies of the proposal formatted with the vote tokens
+func (t voteTokens) newProposalAmounts(proposal *governor.Proposal) ProposalAmounts {
+	if t[proposal.ContractId] == nil {
+		return ProposalAmounts{}
+	}
+	return ProposalAmounts{
+		TokenSymbol:           t.symbol(proposal.ContractId),
+		VotesForFormatted:     t.format(proposal.ContractId, proposal.VotesFor),
+		VotesAgainstFormatted: t.format(proposal.ContractId, proposal.VotesAgainst),
+		VotesAbstainFormatted: t.format(proposal.ContractId, proposal.VotesAbstain),
+	}
+}
+
+// ledgerClock estimates the close time of a ledger from the latest indexed ledger, assuming every ledger takes
+// ESTIMATED_LEDGER_CLOSE_SECONDS to close. Proposal responses are also given the tallies formatted with tokens.
+type ledgerClock struct {
+	ledger    uint32
+	closeTime int64
+	tokens    voteTokens
+}
+
+// ledgerClock returns a clock based on the latest ledger indexed. If the status can't be read, the clock is empty and
+// no estimates are made, as they are not worth failing the request for.
+func (h *Handler) ledgerClock(ctx context.Context) ledgerClock {
+	tokens := h.tokens.get(ctx)
+	ledger, closeTime, err := h.indexStatus.get(ctx)
+	if err != nil {
+		slog.Warn("Failed to get last indexed ledger for time estimates", "error", err)
+		return ledgerClock{tokens: tokens}
+	}
+	return ledgerClock{ledger: ledger, closeTime: closeTime, tokens: tokens}
+}
+
+// estimate returns the estimated close time of a ledger as RFC3339, or an empty string if the clock is empty
+func (c ledgerClock) estimate(ledger uint32) string {
+	if c.closeTime == 0 {
+		return ""
+	}
+	return formatTime(c.closeTime + (int64(ledger)-int64(c.ledger))*ESTIMATED_LEDGER_CLOSE_SECONDS)
+}
+
+// newProposalResponse returns the proposal with the estimated times of its voting period and execution unlock
+func (c ledgerClock) newProposalResponse(proposal *governor.Proposal) *ProposalResponse {
+	response := &ProposalResponse{
+		Proposal:              proposal,
+		VoteStartTimeEstimate: c.estimate(proposal.VoteStart),
+		VoteEndTimeEstimate:   c.estimate(proposal.VoteEnd),
+		ProposalAmounts:       c.tokens.newProposalAmounts(proposal),
+	}
+	// status 1 is queued for execution
+	if proposal.Status == 1 && proposal.ExecutionUnlock != 0 && c.closeTime != 0 {
+		if c.ledger >= proposal.ExecutionUnlock {
+			response.ExecutableNow = true
+		} else {
+			response.ExecutionUnlockTimeEstimate = c.estimate(proposal.ExecutionUnlock)
+		}
+	}
+	return response
+}
+
+// newProposalResponses returns the proposals with the estimated times of their voting periods. The result is never
+// nil, so it is encoded as an empty list.
+func (c ledgerClock) newProposalResponses(proposals []*governor.Proposal) []*ProposalResponse {
+	responses := make([]*ProposalResponse, len(proposals))
+	for i, proposal := range proposals {
+		responses[i] = c.newProposalResponse(proposal)
+	}
+	return responses
+}
+
+// withResults sets the counting type and results of a proposal response from the settings its governor had at the
+// proposal's vote end, so a settings change doesn't change how proposals already decided are counted. They are omitted
+// if the settings haven't been read yet or can't be, as they are not worth failing the request for.
+func (h *Handler) withResults(ctx context.Context, response *ProposalResponse) *ProposalResponse {
+	settings, err := h.store.GetGovernorSettings(ctx, response.ContractId, response.VoteEnd)
+	if errors.Is(err, db.ErrNotFound) {
+		return response
+	}
+	if err != nil {
+		slog.Warn("Failed to get governor settings for proposal results", "contract", response.ContractId, "error", err)
+		return response
+	}
+	results, err := governor.NewProposalResults(response.Proposal, settings.CountingType)
+	if err != nil {
+		slog.Warn("Invalid vote tally in proposal results", "proposal_key", response.ProposalKey, "error", err)
+		return response
+	}
+	response.CountingType = &settings.CountingType
+	response.Results = results
+	return response
+}
+
+// ProposalSummary is a proposal without its description and action, which can be large, for listing proposals. The
+// description is replaced by its first DESCRIPTION_PREVIEW_LENGTH characters, and the total of the votes is added.
+type ProposalSummary struct {
+	ProposalKey             string
+	ContractId              string
+	ProposalId              uint32
+	Proposer                string
+	Status                  uint32
+	Title                   string
+	DescriptionPreview      string `json:"description_preview"`
+	VoteStart               uint32
+	VoteEnd                 uint32
+	VotesFor                string
+	VotesAgainst            string
+	VotesAbstain            string
+	VotesTotal              string `json:"votes_total"`
+	ExecutionUnlock         uint32
+	ExecutionTxHash         string
+	Truncated               bool
+	FlaggedLowParticipation bool
+	ActionType              string
+	ActionContractId        string
+	ActionFunction          string
+	CreatedLedger           uint32
+	CreatedTxHash           string
+	SourceAccount           string
+	UpdatedLedger           uint32
+	UpdatedEventId          string
+	UpdatedAt               int64
+
+	VoteStartTimeEstimate       string `json:"vote_start_time_estimate,omitempty"`
+	VoteEndTimeEstimate         string `json:"vote_end_time_estimate,omitempty"`
+	ExecutionUnlockTimeEstimate string `json:"execution_unlock_time_estimate,omitempty"`
+	ExecutableNow               bool   `json:"executable_now,omitempty"`
+	ProposalAmounts
+}
+
+// newProposalSummaries returns the summaries of the proposals, with the same time estimates as newProposalResponses.
+// The result is never nil, so it is encoded as an empty list.
+func (c ledgerClock) newProposalSummaries(proposals []*governor.Proposal) []*ProposalSummary {
+	summaries := make([]*ProposalSummary, len(proposals))
+	for i, proposal := range proposals {
+		response := c.newProposalResponse(proposal)
+		summaries[i] = &ProposalSummary{
+			ProposalKey:                 proposal.ProposalKey,
+			ContractId:                  proposal.ContractId,
+			ProposalId:                  proposal.ProposalId,
+			Proposer:                    proposal.Proposer,
+			Status:                      proposal.Status,
+			Title:                       proposal.Title,
+			DescriptionPreview:          descriptionPreview(proposal.Description),
+			VoteStart:                   proposal.VoteStart,
+			VoteEnd:                     proposal.VoteEnd,
+			VotesFor:                    proposal.VotesFor,
+			VotesAgainst:                proposal.VotesAgainst,
+			VotesAbstain:                proposal.VotesAbstain,
+			VotesTotal:                  votesTotal(proposal),
+			ExecutionUnlock:             proposal.ExecutionUnlock,
+			ExecutionTxHash:             proposal.ExecutionTxHash,
+			Truncated:                   proposal.Truncated,
+			FlaggedLowParticipation:     proposal.FlaggedLowParticipation,
+			ActionType:                  proposal.ActionType,
+			ActionContractId:            proposal.ActionContractId,
+			ActionFunction:              proposal.ActionFunction,
+			CreatedLedger:               proposal.CreatedLedger,
+			CreatedTxHash:               proposal.CreatedTxHash,
+			SourceAccount:               proposal.SourceAccount,
+			UpdatedLedger:               proposal.UpdatedLedger,
+			UpdatedEventId:              proposal.UpdatedEventId,
+			UpdatedAt:                   proposal.UpdatedAt,
+			VoteStartTimeEstimate:       response.VoteStartTimeEstimate,
+			VoteEndTimeEstimate:         response.VoteEndTimeEstimate,
+			ExecutionUnlockTimeEstimate: response.ExecutionUnlockTimeEstimate,
+			ExecutableNow:               response.ExecutableNow,
+			ProposalAmounts:             response.ProposalAmounts,
+		}
+	}
+	return summaries
+}
+
+// descriptionPreview returns the first DESCRIPTION_PREVIEW_LENGTH characters of a description
+func descriptionPreview(description string) string {
+	count := 0
+	for i := range description {
+		if count == DESCRIPTION_PREVIEW_LENGTH {
+			return description[:i]
+		}
+		count++
+	}
+	return description
+}
+
+// votesTotal returns the sum of the votes for, against, and abstaining on a proposal. Tallies are validated before
+// they are written, so an invalid tally is only logged, and the total is left empty.
+func votesTotal(proposal *governor.Proposal) string {
+	// the total of three i128 tallies may exceed an i128, so it is summed without bigmath.AddAmount
+	total := new(big.Int)
+	for _, tally := range []string{proposal.VotesFor, proposal.VotesAgainst, proposal.VotesAbstain} {
+		val, err := bigmath.ParseAmount(tally)
+		if err != nil {
+			slog.Warn("Invalid vote tally in proposal summary", "proposal_key", proposal.ProposalKey, "error", err)
+			return ""
+		}
+		total.Add(total, val)
+	}
+	return total.String()
+}
+
+// newVoteResponse returns the vote with its ledger close time and amount formatted
+func (t voteTokens) newVoteResponse(vote *governor.Vote) *VoteResponse {
+	return &VoteResponse{
+		Vote:               vote,
+		SupportLabel:       governor.VoteSupport(vote.Support).Label(),
+		LedgerCloseTimeIso: formatTime(vote.LedgerCloseTime),
+		AmountFormatted:    t.format(vote.ContractId, vote.Amount),
+		TokenSymbol:        t.symbol(vote.ContractId),
+	}
+}
+
+// newVoteResponses returns the votes with their ledger close times and amounts formatted. The result is never nil,
+// so it is encoded as an empty list.
+func (t voteTokens) newVoteResponses(votes []*governor.Vote) []*VoteResponse {
+	responses := make([]*VoteResponse, len(votes))
+	for i, vote := range votes {
+		responses[i] = t.newVoteResponse(vote)
+	}
+	return responses
+}
+
+// ContractResponse is a governor in the contracts registry, with the age of its most recent applied event, so a
+// governor that stopped producing data stands out
+type ContractResponse struct {
+	ContractId            string `json:"contract_id"`
+	LastEventLedger       uint32 `json:"last_event_ledger"`
+	LastEventCloseTime    int64  `json:"last_event_close_time"`
+	LastEventCloseTimeIso string `json:"last_event_close_time_iso"`
+	LastEventAgeSeconds   int64  `json:"last_event_age_seconds"`
+	ProposalCount         int    `json:"proposal_count"`
+	Blocked               bool   `json:"blocked"`
+	// Reviewed is false for a contract found by contract discovery that was not approved yet
+	Reviewed bool `json:"reviewed"`
+	// Metadata is null if no metadata was set for the contract
+	Metadata *ContractMetadataResponse `json:"metadata"`
+	// Token is null if the contract's vote token has not been read
+	Token *VoteTokenResponse `json:"token"`
+	// Events counts the governor events of the contract by whether they parsed, so a contract whose events stopped
+	// parsing stands out
+	Events ContractEventsResponse `json:"events"`
+}
+
+// ContractEventsResponse counts the governor events of a contract by whether they parsed
+type ContractEventsResponse struct {
+	Seen   int64 `json:"seen"`
+	Parsed int64 `json:"parsed"`
+	Failed int64 `json:"failed"`
+}
+
+// VoteTokenResponse is the token a governor's votes are counted in
+type VoteTokenResponse struct {
+	Symbol   string `json:"symbol"`
+	Decimals uint32 `json:"decimals"`
+}
+
+// ContractMetadataResponse describes a governor for display
+type ContractMetadataResponse struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	IconUrl     string            `json:"icon_url"`
+	Website     string            `json:"website"`
+	Links       map[string]string `json:"links"`
+	UpdatedAt   int64             `json:"updated_at"`
+}
+
+// newContractResponse returns a registered contract with the age of its last event at now
+func newContractResponse(contract *db.Contract, now int64) *ContractResponse {
+	response := &ContractResponse{
+		ContractId:            contract.ContractId,
+		LastEventLedger:       contract.LastEventLedger,
+		LastEventCloseTime:    contract.LastEventCloseTime,
+		LastEventCloseTimeIso: formatTime(contract.LastEventCloseTime),
+		LastEventAgeSeconds:   now - contract.LastEventCloseTime,
+		ProposalCount:         contract.ProposalCount,
+		Blocked:               contract.Blocked,
+		Reviewed:              contract.Reviewed,
+		Metadata:              newContractMetadataResponse(contract.Metadata),
+		Events:                ContractEventsResponse(contract.Events),
+	}
+	if contract.Token != nil {
+		response.Token = &VoteTokenResponse{Symbol: contract.Token.Symbol, Decimals: contract.Token.Decimals}
+	}
+	return response
+}
+
+// newContractResponses returns the registered contracts with the age of their last event at now. The result is
+// never nil, so it is encoded as an empty list.
+func newContractResponses(contracts []*db.Contract, now int64) []*ContractResponse {
+	responses := make([]*ContractResponse, len(contracts))
+	for i, contract := range contracts {
+		responses[i] = newContractResponse(contract, now)
+	}
+	return responses
+}
+
+// newContractMetadataResponse returns the metadata, or nil if metadata is nil. Links are never nil, so they are
+// encoded as an empty object.
+func newContractMetadataResponse(metadata *db.ContractMetadata) *ContractMetadataResponse {
+	if metadata == nil {
+		return nil
+	}
+	links := metadata.Links
+	if links == nil {
+		links = map[string]string{}
+	}
+	return &ContractMetadataResponse{
+		Name:        metadata.Name,
+		Description: metadata.Description,
+		IconUrl:     metadata.IconUrl,
+		Website:     metadata.Website,
+		Links:       links,
+		UpdatedAt:   metadata.UpdatedAt,
+	}
+}
+
+// formatTime formats unix seconds as RFC3339 in UTC
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/strkey"
+)
+
+// unreviewedContracts is an in-memory copy of the registered contracts that were not reviewed, so requests can be
+// checked without a database read. It is reloaded by refresh at most once every interval, so contracts registered or
+// approved by another process are picked up within the interval. It is safe for concurrent use.
+type unreviewedContracts struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+
+	mu          sync.RWMutex
+	contracts   map[string]bool
+	refreshedAt time.Time
+}
+
+func newUnreviewedContracts(store Store, interval time.Duration) *unreviewedContracts {
+	return &unreviewedContracts{store: store, interval: interval, now: time.Now}
+}
+
+// refresh reloads the unreviewed contracts if they were last loaded more than interval ago. If reloading fails, the
+// previous contracts are kept and the error is returned.
+func (u *unreviewedContracts) refresh(ctx context.Context) error {
+	u.mu.RLock()
+	stale := u.contracts == nil || !u.now().Before(u.refreshedAt.Add(u.interval))
+	u.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	ids, err := u.store.GetUnreviewedContractIds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load unreviewed contracts: %w", err)
+	}
+	contracts := make(map[string]bool, len(ids))
+	for _, contractId := range ids {
+		contracts[contractId] = true
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.contracts = contracts
+	u.refreshedAt = u.now()
+	return nil
+}
+
+// invalidate reloads the unreviewed contracts on the next refresh, so a review made by this process is applied
+// immediately
+func (u *unreviewedContracts) invalidate() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.refreshedAt = time.Time{}
+}
+
+// contains returns true if the contract was not reviewed. Every contract is reported as unreviewed until the
+// unreviewed contracts are first loaded, so their data is never served by mistake.
+func (u *unreviewedContracts) contains(contractId string) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.contracts == nil || u.contracts[contractId]
+}
+
+// loaded returns true once the unreviewed contracts were loaded
+func (u *unreviewedContracts) loaded() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.contracts != nil
+}
+
+// includeUnreviewed returns true if the request includes the data of contracts that were not reviewed with
+// ?include_unreviewed=true. They are excluded by default.
+func includeUnreviewed(r *http.Request) bool {
+	return r.URL.Query().Get("include_unreviewed") == "true"
+}
+
+// hidesUnreviewed returns a function reporting whether the data of a contract is excluded from the response to the
+// request, as it was not reviewed. Nothing is excluded if the request includes unreviewed contracts, or if the
+// handler doesn't cache them, and everything is until they are first loaded.
+func (h *Handler) hidesUnreviewed(r *http.Request) func(contractId string) bool {
+	if h.unreviewed == nil || includeUnreviewed(r) {
+		return func(string) bool { return false }
+	}
+	if err := h.unreviewed.refresh(r.Context()); err != nil {
+		slog.Warn("Failed to refresh unreviewed contracts, using the last loaded contracts", "error", err)
+	}
+	return h.unreviewed.contains
+}
+
+// withUnreviewed wraps a handler that hides the data of contracts that were not reviewed, so its requests are
+// refused with a 503 until the unreviewed contracts are first loaded, instead of serving or hiding every contract.
+// Requests that include unreviewed contracts are always served.
+func (h *Handler) withUnreviewed(next http.HandlerFunc) http.HandlerFunc {
+	if h.unreviewed == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if includeUnreviewed(r) {
+			next(w, r)
+			return
+		}
+		if err := h.unreviewed.refresh(r.Context()); err != nil && !h.unreviewed.loaded() {
+			slog.Error("Failed to load unreviewed contracts", "error", err)
+			respondError(w, http.StatusServiceUnavailable, "unreviewed contracts are not loaded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rejectUnreviewed wraps a contract's handler so requests for a contract that was not reviewed are refused with a
+// 404, unless the request includes unreviewed contracts. See withUnreviewed.
+func (h *Handler) rejectUnreviewed(next http.HandlerFunc) http.HandlerFunc {
+	if h.unreviewed == nil {
+		return next
+	}
+	return h.withUnreviewed(func(w http.ResponseWriter, r *http.Request) {
+		if h.hidesUnreviewed(r)(r.PathValue("contractId")) {
+			respondError(w, http.StatusNotFound, "contract is not reviewed")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// handleGetUnreviewedContracts lists the registered contracts waiting to be reviewed, found by contract discovery
+func (h *Handler) handleGetUnreviewedContracts(w http.ResponseWriter, r *http.Request) {
+	contracts, err := h.store.GetContracts(r.Context())
+	if err != nil {
+		slog.Error("Failed to get contracts", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve contracts")
+		return
+	}
+	var unreviewed []*db.Contract
+	for _, contract := range contracts {
+		if !contract.Reviewed {
+			unreviewed = append(unreviewed, contract)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, newContractResponses(unreviewed, h.indexStatus.now().Unix()))
+}
+
+// handleReviewContract approves a registered contract, so the API serves its data to every request
+func (h *Handler) handleReviewContract(w http.ResponseWriter, r *http.Request) {
+	h.setContractReviewed(w, r, true)
+}
+
+// handleUnreviewContract returns a registered contract to the review queue, so the API hides its data again
+func (h *Handler) handleUnreviewContract(w http.ResponseWriter, r *http.Request) {
+	h.setContractReviewed(w, r, false)
+}
+
+func (h *Handler) setContractReviewed(w http.ResponseWriter, r *http.Request, reviewed bool) {
+	contractId := r.PathValue("contractId")
+	if _, err := strkey.Decode(strkey.VersionByteContract, contractId); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+
+	err := h.store.SetContractReviewed(r.Context(), contractId, reviewed)
+	if errors.Is(err, db.ErrNotFound) {
+		respondError(w, http.StatusNotFound, "contract is not registered")
+		return
+	} else if err != nil {
+		slog.Error("Failed to set contract reviewed", "contract", contractId, "reviewed", reviewed, "error", err)
+		respondError(w, storeErrorStatus(err), "failed to review contract")
+		return
+	}
+	h.invalidateUnreviewed()
+	slog.Info("Set contract reviewed", "contract", contractId, "reviewed", reviewed)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidateUnreviewed reloads the cached unreviewed contracts on the next request, after a contract is reviewed
+func (h *Handler) invalidateUnreviewed() {
+	if h.unreviewed != nil {
+		h.unreviewed.invalidate()
+	}
+}
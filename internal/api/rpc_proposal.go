@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// RPC_PROPOSAL_TTL is how long a proposal read from the RPC server, or its absence, is cached, so a UI polling
+	// for a new proposal doesn't call the RPC server for every request
+	RPC_PROPOSAL_TTL = 10 * time.Second
+	// RPC_PROPOSAL_TIMEOUT is the maximum duration of a call to the RPC server for a proposal
+	RPC_PROPOSAL_TIMEOUT = 3 * time.Second
+	// RPC_PROPOSAL_CACHE_SIZE is the maximum number of proposals cached. Expired proposals are dropped once it is
+	// reached, and the cache is cleared if they all are still fresh.
+	RPC_PROPOSAL_CACHE_SIZE = 1000
+	// PROPOSAL_SOURCE_RPC marks a proposal read from the governor's storage instead of the database
+	PROPOSAL_SOURCE_RPC = "rpc"
+)
+
+// rpcProposals reads proposals the indexer hasn't seen yet from the governor's storage, through an RPC server
+type rpcProposals struct {
+	mu    sync.Mutex
+	read  func(ctx context.Context, contractId string, proposalId uint32) (*governor.Proposal, error)
+	cache map[string]rpcProposalEntry
+	now   func() time.Time
+}
+
+// rpcProposalEntry is a cached proposal, nil if the governor has no such proposal
+type rpcProposalEntry struct {
+	proposal *governor.Proposal
+	expires  time.Time
+}
+
+// newRPCProposals returns an rpcProposals reading from the RPC server at rpcURL, or nil if enabled is false or rpcURL
+// is empty
+func newRPCProposals(rpcURL string, enabled bool) *rpcProposals {
+	if !enabled || rpcURL == "" {
+		return nil
+	}
+	client := rpcclient.NewClient(rpcURL, nil)
+	return &rpcProposals{
+		read: func(ctx context.Context, contractId string, proposalId uint32) (*governor.Proposal, error) {
+			return readProposalFromRPC(ctx, client, contractId, proposalId)
+		},
+		cache: make(map[string]rpcProposalEntry),
+		now:   time.Now,
+	}
+}
+
+// get returns a proposal from the governor's storage, or nil if the governor has no such proposal. Proposals and
+// their absence are cached for RPC_PROPOSAL_TTL. Failed calls are not cached.
+func (p *rpcProposals) get(ctx context.Context, contractId string, proposalId uint32) (*governor.Proposal, error) {
+	proposalKey := governor.EncodeProposalKey(contractId, proposalId)
+	p.mu.Lock()
+	entry, ok := p.cache[proposalKey]
+	p.mu.Unlock()
+	if ok && p.now().Before(entry.expires) {
+		return entry.proposal, nil
+	}
+
+	// the lock isn't held while calling the RPC server, so a slow call doesn't block other proposals
+	ctx, cancel := context.WithTimeout(ctx, RPC_PROPOSAL_TIMEOUT)
+	defer cancel()
+	proposal, err := p.read(ctx, contractId, proposalId)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if len(p.cache) >= RPC_PROPOSAL_CACHE_SIZE {
+		for key, cached := range p.cache {
+			if !now.Before(cached.expires) {
+				delete(p.cache, key)
+			}
+		}
+		if len(p.cache) >= RPC_PROPOSAL_CACHE_SIZE {
+			clear(p.cache)
+		}
+	}
+	p.cache[proposalKey] = rpcProposalEntry{proposal: proposal, expires: now.Add(RPC_PROPOSAL_TTL)}
+	return proposal, nil
+}
+
+// readProposalFromRPC reads the config, data, and vote count entries of a proposal with getLedgerEntries, and
+// returns the proposal, or nil if the governor has no config or data entry for it. Both durabilities are requested,
+// so the storage layout of the governor release doesn't matter.
+func readProposalFromRPC(ctx context.Context, client *rpcclient.Client, contractId string, proposalId uint32) (*governor.Proposal, error) {
+	var keys []string
+	variants := make(map[string]string)
+	for _, variant := range []string{governor.PROPOSAL_CONFIG_KEY, governor.PROPOSAL_DATA_KEY, governor.PROPOSAL_VOTE_COUNT_KEY} {
+		for _, durability := range []xdr.ContractDataDurability{xdr.ContractDataDurabilityPersistent, xdr.ContractDataDurabilityTemporary} {
+			key, err := governor.ProposalStorageKey(contractId, variant, proposalId, durability)
+			if err != nil {
+				return nil, err
+			}
+			keyXdr, err := xdr.MarshalBase64(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode ledger key: %w", err)
+			}
+			keys = append(keys, keyXdr)
+			variants[keyXdr] = variant
+		}
+	}
+
+	resp, err := client.GetLedgerEntries(ctx, protocol.GetLedgerEntriesRequest{Keys: keys})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	values := make(map[string]xdr.ScVal)
+	for _, result := range resp.Entries {
+		variant, ok := variants[result.KeyXDR]
+		if !ok {
+			return nil, fmt.Errorf("RPC server returned an entry for an unrequested key %s", result.KeyXDR)
+		}
+		var data xdr.LedgerEntryData
+		if err := xdr.SafeUnmarshalBase64(result.DataXDR, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode %s entry of proposal %d: %w", variant, proposalId, err)
+		}
+		contractData, ok := data.GetContractData()
+		if !ok {
+			return nil, fmt.Errorf("%s entry of proposal %d is not contract data", variant, proposalId)
+		}
+		values[variant] = contractData.Val
+	}
+
+	config, hasConfig := values[governor.PROPOSAL_CONFIG_KEY]
+	data, hasData := values[governor.PROPOSAL_DATA_KEY]
+	if !hasConfig || !hasData {
+		return nil, nil
+	}
+	var voteCount *governor.VoteCount
+	if val, ok := values[governor.PROPOSAL_VOTE_COUNT_KEY]; ok {
+		if voteCount, err = governor.NewVoteCountFromXDR(val); err != nil {
+			return nil, fmt.Errorf("failed to parse vote count of proposal %d: %w", proposalId, err)
+		}
+	}
+	proposal, err := governor.NewProposalFromStorage(contractId, proposalId, config, data, voteCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proposal %d: %w", proposalId, err)
+	}
+	return proposal, nil
+}
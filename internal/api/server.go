@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+// SHUTDOWN_TIMEOUT is how long in-flight requests are given to complete when the server shuts down
+const SHUTDOWN_TIMEOUT = 30 * time.Second
+
+// Serve runs the API server on the configured port until ctx is cancelled, then shuts it down gracefully. If a TLS
+// certificate is configured, the server serves HTTPS with HTTP/2, and plain HTTP on the redirect port, if set, is
+// redirected to it.
+//
+// Serve returns nil if it stopped because ctx was cancelled.
+func Serve(ctx context.Context, store *db.Store, config *Config) error {
+	if config.ContractMetadataFile != "" {
+		if err := LoadContractMetadataFile(ctx, store, config.ContractMetadataFile); err != nil {
+			return fmt.Errorf("failed to load contract metadata: %w", err)
+		}
+	}
+	handler := NewHandler(store, config)
+
+	server := &http.Server{
+		Addr:        fmt.Sprintf(":%s", config.APIPort),
+		Handler:     handler,
+		ReadTimeout: 15 * time.Second,
+		// responses are bounded by the timeout of their route instead, see API_REQUEST_TIMEOUT_SECONDS
+		IdleTimeout: 60 * time.Second,
+	}
+	// certs is nil unless the server serves TLS
+	var certs *certReloader
+	if config.TLSCertFile != "" {
+		var err error
+		certs, err = newCertReloader(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = certs.tlsConfig()
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+	}
+	servers := []*http.Server{server}
+	if config.TLSRedirectPort != "" {
+		servers = append(servers, &http.Server{
+			Addr:         fmt.Sprintf(":%s", config.TLSRedirectPort),
+			Handler:      redirectToTLS(config.APIPort),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		})
+	}
+	go watchReload(ctx, *config, handler, certs)
+
+	serveErr := make(chan error, len(servers))
+	go func() {
+		if certs == nil {
+			slog.Info("API server listening", "port", config.APIPort)
+			serveErr <- server.ListenAndServe()
+			return
+		}
+		slog.Info("API server listening with TLS", "port", config.APIPort)
+		serveErr <- server.ListenAndServeTLS("", "")
+	}()
+	for _, redirect := range servers[1:] {
+		go func() {
+			slog.Info("Redirecting HTTP to HTTPS", "port", config.TLSRedirectPort)
+			serveErr <- redirect.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		for _, s := range servers {
+			s.Close()
+		}
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("server forced to shutdown: %w", err)
+		}
+	}
+	for range servers {
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/indexer"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+)
+
+const (
+	// NETWORK_STATUS_TTL is how long the network's latest ledger is cached, so the RPC server isn't called for every
+	// status request
+	NETWORK_STATUS_TTL = 5 * time.Second
+	// NETWORK_STATUS_FAILURE_TTL is how long a failure to get the network's latest ledger is cached, so an RPC outage
+	// doesn't make every status request wait for NETWORK_STATUS_TIMEOUT
+	NETWORK_STATUS_FAILURE_TTL = 2 * time.Second
+	// NETWORK_STATUS_TIMEOUT is the maximum duration of a call to the RPC server for the network's latest ledger
+	NETWORK_STATUS_TIMEOUT = 3 * time.Second
+)
+
+// networkStatus caches the network's latest ledger, from the health of an RPC server
+type networkStatus struct {
+	mu           sync.Mutex
+	latestLedger func(ctx context.Context) (uint32, error)
+	ledger       uint32
+	err          error
+	expires      time.Time
+	// pending is closed when the call in progress, if any, is done
+	pending chan struct{}
+	now     func() time.Time
+}
+
+// newNetworkStatus returns a networkStatus reading the health of the RPC server at rpcURL, or nil if rpcURL is empty
+func newNetworkStatus(rpcURL string) *networkStatus {
+	if rpcURL == "" {
+		return nil
+	}
+	client := rpcclient.NewClient(rpcURL, nil)
+	return &networkStatus{
+		latestLedger: func(ctx context.Context) (uint32, error) {
+			health, err := client.GetHealth(ctx)
+			if err != nil {
+				return 0, err
+			}
+			return health.LatestLedger, nil
+		},
+		now: time.Now,
+	}
+}
+
+// get returns the network's latest ledger. Concurrent callers wait for a single call when the cache expires, without
+// holding the lock, so a caller whose context is done stops waiting. Failed calls are cached for
+// NETWORK_STATUS_FAILURE_TTL.
+func (s *networkStatus) get(ctx context.Context) (uint32, error) {
+	s.mu.Lock()
+	if s.now().Before(s.expires) {
+		defer s.mu.Unlock()
+		return s.ledger, s.err
+	}
+	pending := s.pending
+	if pending == nil {
+		pending = make(chan struct{})
+		s.pending = pending
+		// the call is shared by every waiting caller, so it isn't cancelled with the context of the first
+		go s.fetch(context.WithoutCancel(ctx), pending)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-pending:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ledger, s.err
+}
+
+// fetch calls the RPC server for the network's latest ledger, caches the result, and closes pending
+func (s *networkStatus) fetch(ctx context.Context, pending chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, NETWORK_STATUS_TIMEOUT)
+	defer cancel()
+	ledger, err := s.latestLedger(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ttl := NETWORK_STATUS_TTL
+	if err != nil {
+		ledger, ttl = 0, NETWORK_STATUS_FAILURE_TTL
+	}
+	s.ledger, s.err, s.expires = ledger, err, s.now().Add(ttl)
+	s.pending = nil
+	close(pending)
+}
+
+// StatusResponse is the response body for the service status. NetworkLedger and BacklogLedgers are null unless
+// RPC_URL is set and the RPC server could be reached.
+type StatusResponse struct {
+	IndexerStatus
+	// NetworkLedger is the latest ledger of the network
+	NetworkLedger *uint32 `json:"network_ledger"`
+	// BacklogLedgers is the number of ledgers the indexer is behind the network
+	BacklogLedgers *uint32 `json:"backlog_ledgers"`
+	// HasGaps is true if ledgers were knowingly not indexed, see GET /status/gaps
+	HasGaps bool `json:"has_gaps"`
+}
+
+// handleGetIndexStatus returns the progress of the indexer and, if RPC_URL is set, how far it is behind the network.
+// Unlike /health, it doesn't judge whether the indexer is healthy.
+func (h *Handler) handleGetIndexStatus(w http.ResponseWriter, r *http.Request) {
+	now := h.indexStatus.now().Unix()
+	ledger, closeTime, err := h.store.GetStatus(r.Context(), indexer.STATUS_SOURCE)
+	if err != nil {
+		slog.Error("Failed to get indexer status", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve indexer status")
+		return
+	}
+	gaps, err := h.store.CountCoverageGaps(r.Context())
+	if err != nil {
+		slog.Error("Failed to count coverage gaps", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve indexer status")
+		return
+	}
+	response := StatusResponse{IndexerStatus: IndexerStatus{Ledger: ledger, LedgerCloseTime: closeTime}, HasGaps: gaps > 0}
+	if closeTime != 0 {
+		response.LagSeconds = now - closeTime
+	}
+
+	if h.network != nil {
+		networkLedger, err := h.network.get(r.Context())
+		if err != nil {
+			slog.Warn("Failed to get the network's latest ledger", "error", err)
+		} else {
+			backlog := uint32(0)
+			if networkLedger > ledger {
+				backlog = networkLedger - ledger
+			}
+			response.NetworkLedger, response.BacklogLedgers = &networkLedger, &backlog
+		}
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// CoverageGapResponse is a range of ledgers, inclusive, the indexer knowingly did not index
+type CoverageGapResponse struct {
+	FromLedger uint32 `json:"from_ledger"`
+	ToLedger   uint32 `json:"to_ledger"`
+	Reason     string `json:"reason"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// handleGetCoverageGaps returns the ranges of ledgers the indexer knowingly did not index, so clients can tell
+// missing data from ledgers without governor events
+func (h *Handler) handleGetCoverageGaps(w http.ResponseWriter, r *http.Request) {
+	gaps, err := h.store.GetCoverageGaps(r.Context())
+	if err != nil {
+		slog.Error("Failed to get coverage gaps", "error", err)
+		respondError(w, storeErrorStatus(err), "failed to retrieve coverage gaps")
+		return
+	}
+	response := make([]CoverageGapResponse, len(gaps))
+	for i, gap := range gaps {
+		response[i] = CoverageGapResponse{FromLedger: gap.FromLedger, ToLedger: gap.ToLedger, Reason: gap.Reason, CreatedAt: gap.CreatedAt}
+	}
+	respondJSON(w, http.StatusOK, response)
+}
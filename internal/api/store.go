@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+var _ Store = (*db.Store)(nil)
+
+// Store is the subset of db.Store used by the API handlers
+type Store interface {
+	FilterEventsByContractId(ctx context.Context, contractId string, filter db.Filter, sort db.Sort) ([]*governor.GovernorEvent, error)
+	GetRecentEvents(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error)
+	GetEventsByProposal(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error)
+	GetEventsByTxHash(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error)
+	GetFailedEventsByContractId(ctx context.Context, contractId string) ([]*governor.FailedEvent, error)
+	GetFailedEvents(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error)
+	CountFailedEvents(ctx context.Context, filter db.FailedEventFilter) (int, error)
+	GetFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool, afterEventId string, limit int) ([]*governor.FailedTxEvent, error)
+	CountFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool) (int, error)
+
+	GetStatus(ctx context.Context, source string) (uint32, int64, error)
+	GetSourceStatus(ctx context.Context, source string) (*db.SourceStatus, error)
+	GetIngestionProgress(ctx context.Context, source string) (*db.IngestionProgress, error)
+	GetCoverageGaps(ctx context.Context) ([]*db.CoverageGap, error)
+	GetIndexerErrors(ctx context.Context, limit int) ([]*db.IndexerError, error)
+	CountCoverageGaps(ctx context.Context) (int, error)
+
+	GetProposal(ctx context.Context, proposalKey string) (*governor.Proposal, error)
+	GetProposalsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error)
+	EachProposalByContractId(ctx context.Context, contractId string, sort db.Sort, fn func(proposal *governor.Proposal) error) error
+	GetProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error)
+	CountProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error)
+	GetProposalsEndingBetween(ctx context.Context, fromLedger uint32, toLedger uint32) ([]*governor.Proposal, error)
+	GetExecutableProposals(ctx context.Context, ledger uint32) ([]*governor.Proposal, error)
+	GetProposalsByKeys(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error)
+	GetProposalContent(ctx context.Context, proposalKey string) (*governor.ProposalContent, error)
+
+	GetVote(ctx context.Context, txHash string) (*governor.Vote, error)
+	GetVoteByProposalAndVoter(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error)
+	GetLatestVotesByVoter(ctx context.Context, contractId string, voter string, proposalIds []uint32) ([]*governor.Vote, error)
+	GetVotesByProposal(ctx context.Context, contractId string, proposalId uint32, sort db.Sort) ([]*governor.Vote, error)
+	EachVoteByProposal(ctx context.Context, contractId string, proposalId uint32, sort db.Sort, fn func(vote *governor.Vote) error) error
+	GetVoteSummary(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error)
+	GetVoteSeries(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error)
+
+	GetDelegators(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error)
+	GetDelegationHistory(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error)
+
+	GetContracts(ctx context.Context) ([]*db.Contract, error)
+	GetContract(ctx context.Context, contractId string) (*db.Contract, error)
+	GetVoteTokens(ctx context.Context) ([]*db.VoteToken, error)
+	GetGovernorSettings(ctx context.Context, governorId string, ledgerSeq uint32) (*governor.Settings, error)
+	SetContractReviewed(ctx context.Context, contractId string, reviewed bool) error
+	GetUnreviewedContractIds(ctx context.Context) ([]string, error)
+	UpsertContractMetadata(ctx context.Context, metadata *db.ContractMetadata, replace bool) error
+	DeleteContractMetadata(ctx context.Context, contractId string) error
+	DeleteContractData(ctx context.Context, contractId string) (map[string]int64, error)
+	BlockContract(ctx context.Context, contractId string, reason string, createdAt int64) error
+	UnblockContract(ctx context.Context, contractId string) error
+	GetBlockedContracts(ctx context.Context) ([]*db.BlockedContract, error)
+}
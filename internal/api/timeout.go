@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TIMEOUT_WRITE_GRACE is how long past a request's deadline its response can still be written, so the 503 of a
+// request that timed out reaches the client
+const TIMEOUT_WRITE_GRACE = 5 * time.Second
+
+// STREAMED_ROUTES are the routes that stream their response as a CSV export if it is asked for, which are given the
+// export timeout
+var STREAMED_ROUTES = []string{
+	"GET /{contractId}/proposals",
+	"GET /{contractId}/proposals/{proposalId}/votes",
+}
+
+// requestTimeouts are the deadlines of requests, by route
+type requestTimeouts struct {
+	// request is the timeout of routes without an override, export the timeout of CSV exports, and routes the
+	// overrides of single routes. A timeout of 0 is no limit.
+	request time.Duration
+	export  time.Duration
+	routes  map[string]time.Duration
+}
+
+func newRequestTimeouts(config *Config) *requestTimeouts {
+	timeouts := &requestTimeouts{
+		request: time.Duration(config.RequestTimeoutSeconds) * time.Second,
+		export:  time.Duration(config.ExportTimeoutSeconds) * time.Second,
+		routes:  make(map[string]time.Duration, len(config.RouteTimeouts)),
+	}
+	for route, seconds := range config.RouteTimeouts {
+		timeouts.routes[route] = time.Duration(seconds) * time.Second
+	}
+	return timeouts
+}
+
+// get returns the timeout of a request to route
+func (t *requestTimeouts) get(r *http.Request, route string) time.Duration {
+	if wantsCSV(r) && slices.Contains(STREAMED_ROUTES, route) {
+		return t.export
+	}
+	if timeout, ok := t.routes[route]; ok {
+		return timeout
+	}
+	return t.request
+}
+
+// withTimeout wraps a handler so each request's context has the deadline of its route, which the store honors by
+// cancelling its queries. The response must be written by TIMEOUT_WRITE_GRACE past the deadline, so a slow client
+// can't hold the connection either. http.TimeoutHandler isn't used, as it buffers the response, breaking streaming.
+func (h *Handler) withTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := h.timeouts.get(r, h.routePattern(r))
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+		// writers that don't support deadlines, such as in tests, are only bounded by the context
+		_ = http.NewResponseController(w).SetWriteDeadline(deadline.Add(TIMEOUT_WRITE_GRACE))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// routePattern returns the pattern of the route a request is routed to, without API_VERSION_PREFIX, such as
+// "GET /{contractId}/proposals", or "" if it matches no route
+func (h *Handler) routePattern(r *http.Request) string {
+	_, pattern := h.router.Handler(r)
+	if pattern != "/" && pattern != API_VERSION_PREFIX+"/" {
+		return strings.Replace(pattern, " "+API_VERSION_PREFIX+"/", " /", 1)
+	}
+	// the API routes are mounted under both prefixes, and are matched without them
+	apiURL := *r.URL
+	apiURL.Path = unversionedPath(r)
+	apiURL.RawPath = ""
+	apiReq := *r
+	apiReq.URL = &apiURL
+	_, pattern = h.routes.Handler(&apiReq)
+	return pattern
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+// TOKEN_CACHE_TTL is how long the vote tokens read by the indexer are cached
+const TOKEN_CACHE_TTL = 30 * time.Second
+
+// voteTokens are the vote tokens of governors, by contract id, used to format vote amounts. Governors whose token
+// has not been read are missing, and their amounts are not formatted.
+type voteTokens map[string]*db.VoteToken
+
+// format returns an amount voted on a governor as a number of its vote tokens, or an empty string if its token is
+// unknown. Amounts are validated before they are written, so an invalid amount is only logged.
+func (t voteTokens) format(contractId string, amount string) string {
+	token := t[contractId]
+	if token == nil {
+		return ""
+	}
+	formatted, err := bigmath.FormatAmount(amount, token.Decimals)
+	if err != nil {
+		slog.Warn("Failed to format vote amount", "contract", contractId, "amount", amount, "error", err)
+		return ""
+	}
+	return formatted
+}
+
+// symbol returns the symbol of a governor's vote token, or an empty string if its token is unknown
+func (t voteTokens) symbol(contractId string) string {
+	if token := t[contractId]; token != nil {
+		return token.Symbol
+	}
+	return ""
+}
+
+// tokenCache caches the vote tokens for TOKEN_CACHE_TTL, as they rarely change once read
+type tokenCache struct {
+	store   Store
+	mu      sync.Mutex
+	tokens  voteTokens
+	expires time.Time
+	now     func() time.Time
+}
+
+func newTokenCache(store Store) *tokenCache {
+	return &tokenCache{store: store, now: time.Now}
+}
+
+// get returns the cached vote tokens, reloading them if they expired. If they can't be reloaded, the previous tokens
+// are returned, as formatted amounts are not worth failing the request for.
+func (c *tokenCache) get(ctx context.Context) voteTokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now().Before(c.expires) {
+		return c.tokens
+	}
+
+	tokens, err := c.store.GetVoteTokens(ctx)
+	if err != nil {
+		slog.Warn("Failed to get vote tokens for formatting amounts", "error", err)
+		return c.tokens
+	}
+	c.tokens = make(voteTokens, len(tokens))
+	for _, token := range tokens {
+		c.tokens[token.ContractId] = token
+	}
+	c.expires = c.now().Add(TOKEN_CACHE_TTL)
+	return c.tokens
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"log/slog"
+	"net/netip"
+)
+
+// API is the configuration for the API service
+type API struct {
+	DB  DB
+	Log Log
+
+	// API_PORT (string) default 8080
+	// The port number for the API server to listen on, with TLS if API_TLS_CERT_FILE is set.
+	APIPort string
+	// API_TLS_CERT_FILE (string) default ""
+	// The path of the PEM certificate the API server serves TLS and HTTP/2 with, including any intermediate
+	// certificates. Requires API_TLS_KEY_FILE. The certificate and key are reloaded on SIGHUP, so a renewed certificate
+	// is served without a restart. If not set, the API server serves plain HTTP, for deployments that terminate TLS in
+	// a proxy.
+	TLSCertFile string
+	// API_TLS_KEY_FILE (string) default ""
+	// The path of the PEM private key of API_TLS_CERT_FILE.
+	TLSKeyFile string
+	// API_TLS_REDIRECT_PORT (string) default ""
+	// A port to serve plain HTTP on, redirecting every request to https on API_PORT. Requires API_TLS_CERT_FILE. If
+	// not set, only API_PORT is listened on.
+	TLSRedirectPort string
+
+	// API_REQUEST_TIMEOUT_SECONDS (int) default 15
+	// The time (in seconds) a request is given to complete before its context is cancelled, ending the store calls it
+	// is waiting on with a 503. It also bounds writing the response. Set to 0 for no limit.
+	RequestTimeoutSeconds int
+	// API_EXPORT_TIMEOUT_SECONDS (int) default 300
+	// The time (in seconds) a streamed CSV export is given to complete, instead of API_REQUEST_TIMEOUT_SECONDS. Set to
+	// 0 for no limit.
+	ExportTimeoutSeconds int
+	// API_ROUTE_TIMEOUTS (comma-separated route=seconds) default ""
+	// Timeouts (in seconds) of single routes, overriding API_REQUEST_TIMEOUT_SECONDS, such as
+	// GET /{contractId}/proposals=5. Routes are written as they are registered, with their method and without the
+	// /v1 prefix. A timeout of 0 is no limit. CSV exports of a route use API_EXPORT_TIMEOUT_SECONDS.
+	RouteTimeouts map[string]int
+
+	// API_ADMIN_TOKEN (comma-separated strings) default ""
+	// The bearer tokens that can access the /admin endpoints. If not set, the admin endpoints are disabled.
+	AdminTokens []string
+	// API_KEYS (comma-separated strings) default ""
+	// The bearer tokens that can access the non-admin endpoints if API_REQUIRE_AUTH is set. Admin tokens can also
+	// access them.
+	APIKeys []string
+	// API_REQUIRE_AUTH (bool) default false
+	// Whether every endpoint other than /health and /status requires a bearer token from API_KEYS or API_ADMIN_TOKEN.
+	RequireAuth bool
+	// API_MAX_STALENESS_SECONDS (int) default 0
+	// The maximum time (in seconds) since the last indexed ledger closed before data requests are refused with a 503.
+	// Set to 0 to always serve data, however stale.
+	MaxStalenessSeconds int
+	// API_MAX_LIST_ROWS (int) default 1000
+	// The most items returned by the list endpoints that aren't paginated, such as a contract's proposals or a
+	// proposal's votes. Longer lists are truncated, and marked with X-Truncated and Warning headers. CSV exports are
+	// not truncated. Set to 0 to return whole lists.
+	MaxListRows int
+	// API_REBUILDING_MODE (string) default serve
+	// How requests for a contract the indexer is rebuilding with a reindex or replay are handled, one of serve or
+	// unavailable. With serve, the data from before the rebuild is returned, marked with X-Index-Rebuilding headers.
+	// With unavailable, the requests are refused with a 503 and a Retry-After until the rebuild is done.
+	RebuildingMode string
+	// API_REBUILDING_RETRY_AFTER_SECONDS (int) default 30
+	// The Retry-After (in seconds) of requests refused while a contract is rebuilt, if API_REBUILDING_MODE is
+	// unavailable.
+	RebuildingRetryAfterSeconds int
+	// API_REVIEW_REFRESH_INTERVAL (int) default 30
+	// How often (in seconds) the in-memory copy of the contracts waiting for review is reloaded. Contracts registered
+	// by the indexer's contract discovery are hidden within this interval, and contracts reviewed through another API
+	// instance are served within it. Requests that hide unreviewed contracts are refused with a 503 until the copy is
+	// first loaded.
+	ReviewRefreshInterval int
+	// RPC_URL (comma-separated strings) default ""
+	// The RPC server the network's latest ledger is read from, to report the indexer's backlog on /status. If several
+	// URLs are set, as for the indexer, the first is used. If not set, /status only reports the indexer's progress.
+	RPCUrl string
+	// API_RPC_PROPOSAL_FALLBACK (bool) default false
+	// Whether proposals of tracked governors that are not indexed yet, such as proposals created moments ago, are read
+	// from the governor's storage through RPC_URL instead of returning a 404. Requires RPC_URL to be set.
+	RPCProposalFallback bool
+	// API_TRUSTED_PROXIES (comma-separated CIDR prefixes) default ""
+	// The proxies in front of the API, such as a load balancer's subnet and a CDN's ranges, whose X-Forwarded-For
+	// entries are trusted. The client IP of a request, used in the request log, is the first address not in these
+	// prefixes, walking X-Forwarded-For from the connection back towards the client. If not set, X-Forwarded-For is
+	// ignored and the client IP is the connection's address.
+	TrustedProxies []netip.Prefix
+	// CONTRACT_METADATA_FILE (string) default ""
+	// The path of a JSON file of contract metadata, an object of contract ids to metadata, loaded at startup.
+	// Contracts that already have metadata, such as metadata set through the admin endpoints, are left unchanged.
+	// If not set, metadata is only set through the admin endpoints.
+	ContractMetadataFile string
+}
+
+// LoadAPI loads the API configuration from environment variables. All invalid variables
+// are reported in the returned error.
+func LoadAPI() (*API, error) {
+	l := &loader{}
+	c := &API{}
+	c.DB = loadDB(l)
+	c.Log = loadLog(l)
+	c.APIPort = l.port("API_PORT", "8080")
+	c.TLSCertFile = l.string("API_TLS_CERT_FILE", "")
+	c.TLSKeyFile = l.string("API_TLS_KEY_FILE", "")
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		l.fail("API_TLS_CERT_FILE", "and API_TLS_KEY_FILE must be set together")
+	}
+	c.TLSRedirectPort = l.port("API_TLS_REDIRECT_PORT", "")
+	if c.TLSRedirectPort != "" && c.TLSCertFile == "" {
+		l.fail("API_TLS_REDIRECT_PORT", "requires API_TLS_CERT_FILE to be set")
+	} else if c.TLSRedirectPort != "" && c.TLSRedirectPort == c.APIPort {
+		l.fail("API_TLS_REDIRECT_PORT", "must differ from API_PORT, got %s", c.TLSRedirectPort)
+	}
+	c.RequestTimeoutSeconds = l.int("API_REQUEST_TIMEOUT_SECONDS", 15, 0)
+	c.ExportTimeoutSeconds = l.int("API_EXPORT_TIMEOUT_SECONDS", 300, 0)
+	c.RouteTimeouts = l.intMap("API_ROUTE_TIMEOUTS", 0)
+	c.MaxStalenessSeconds = l.int("API_MAX_STALENESS_SECONDS", 0, 0)
+	c.MaxListRows = l.int("API_MAX_LIST_ROWS", 1000, 0)
+	c.RebuildingMode = l.oneOf("API_REBUILDING_MODE", "serve", "serve", "unavailable")
+	c.RebuildingRetryAfterSeconds = l.int("API_REBUILDING_RETRY_AFTER_SECONDS", 30, 1)
+	c.ReviewRefreshInterval = l.int("API_REVIEW_REFRESH_INTERVAL", 30, 1)
+	c.AdminTokens = l.list("API_ADMIN_TOKEN")
+	if len(c.AdminTokens) == 0 {
+		slog.Info("API_ADMIN_TOKEN not set, admin endpoints are disabled")
+	}
+	c.APIKeys = l.list("API_KEYS")
+	if urls := splitList(l.string("RPC_URL", "")); len(urls) > 0 {
+		c.RPCUrl = urls[0]
+		l.checkURL("RPC_URL", c.RPCUrl)
+	}
+	c.RPCProposalFallback = l.bool("API_RPC_PROPOSAL_FALLBACK", false)
+	if c.RPCProposalFallback && c.RPCUrl == "" {
+		l.fail("API_RPC_PROPOSAL_FALLBACK", "requires RPC_URL to be set")
+	}
+	c.TrustedProxies = l.prefixes("API_TRUSTED_PROXIES")
+	c.ContractMetadataFile = l.string("CONTRACT_METADATA_FILE", "")
+	c.RequireAuth = l.bool("API_REQUIRE_AUTH", false)
+	if c.RequireAuth && len(c.APIKeys) == 0 && len(c.AdminTokens) == 0 {
+		l.fail("API_REQUIRE_AUTH", "requires API_KEYS or API_ADMIN_TOKEN to be set")
+	}
+
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
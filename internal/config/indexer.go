@@ -0,0 +1,266 @@
+package config
+
+import (
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+// PUBLIC_SOROBAN_LEDGER is the ledger where Soroban was enabled on the public network, the default start ledger there
+const PUBLIC_SOROBAN_LEDGER = 50457424
+
+// Indexer is the configuration for the indexer service
+type Indexer struct {
+	DB  DB
+	Log Log
+
+	// NETWORK (string) default "testnet"
+	// The Stellar network to connect to. Supported values are "public" and "testnet".
+	Network string
+
+	// LEDGER_BACKEND_TYPE (string) default "rpc"
+	// The type of ledger source to use for the indexer. Supported values are "rpc" and "core".
+	// Core will use a captive core instance, and will expect a core config file to be present.
+	// If using captive core, it is recommended to also persist the core database to the same volume
+	LedgerBackendType string
+
+	// LEDGER_BACKEND_START_SEQ (int) default 50457424 on public, unset on testnet
+	// The ledger sequence number to start indexing from, if no previous state is found in the database.
+	// This must be greater than the genesis ledger of the network being indexed. On public, it defaults to the
+	// ledger where Soroban was enabled. On testnet, which is reset periodically, it defaults to the oldest ledger
+	// retained by the RPC server at RPC_URL, read at startup. With the "rpc" backend, the indexer refuses to start
+	// from a ledger older than the RPC server retains, unless INDEXER_ALLOW_GAP is set.
+	LedgerBackendStartSeq uint32
+
+	// INDEXER_ALLOW_GAP (bool) default false
+	// If true, and the ledger to start or resume indexing from is older than the RPC server at RPC_URL retains, the
+	// indexer skips to the oldest retained ledger instead of refusing to start. The skipped ledgers are recorded in
+	// the status table, and events in them are never indexed.
+	IndexerAllowGap bool
+
+	// RPC_URL (comma-separated strings) default "https://soroban-testnet.stellar.org"
+	// The URLs of the Stellar RPC servers to connect to, if using "rpc" as the ledger backend. If more than one URL
+	// is set, ledgers are read from the first healthy server, failing over to the next after repeated errors.
+	RPCUrls []string
+
+	// RPC_POLL_INTERVAL (int) default 2
+	// How often (in seconds) the RPC server is polled for the next ledger once the indexer has caught up to the
+	// latest ledger, if using "rpc" as the ledger backend.
+	RPCPollInterval int
+
+	// CORE_CONFIG_PATH (string) default "/config/stellar-core.cfg"
+	// The file path to the stellar-core config file, if using "core" as the ledger backend.
+	// CORE_CONFIG_PATH=/mount/stellar-core.cfg
+	CoreConfigPath string
+
+	// CORE_BINARY_PATH (string) default "/usr/bin/stellar-core"
+	// The file path to the stellar-core binary, if using "core" as the ledger backend.
+	CoreBinaryPath string
+
+	// CORE_STORAGE_PATH (string) default ""
+	// The directory captive core stores its state in, if using "core" as the ledger backend. The directory must
+	// exist and be writable. If it is persisted across restarts, captive core resumes from its last closed ledger
+	// instead of catching up from the history archives. If not set, the working directory is used.
+	CoreStoragePath string
+
+	// CORE_LOG_LEVEL (string) default "warn"
+	// The log level for captive-core output. Accepts any logrus level: "panic", "fatal", "error",
+	// "warn", "info", "debug", "trace". Captive-core output is never more verbose than LOG_LEVEL.
+	CoreLogLevel string
+
+	// HISTORY_RETENTION_LEDGERS (int) default 0
+	// The number of ledgers of raw events to retain in the history table. Older events are periodically
+	// deleted. Proposals and votes are never deleted. If 0, the full history is retained.
+	// Note that contracts can't be fully reindexed once their history has been pruned, only from a later snapshot.
+	HistoryRetentionLedgers uint32
+
+	// HISTORY_PRUNE_INTERVAL_LEDGERS (int) default 720
+	// How often, in ledgers, to prune the history table if HISTORY_RETENTION_LEDGERS is set.
+	HistoryPruneIntervalLedgers uint32
+
+	// SNAPSHOT_INTERVAL_EVENTS (int) default 10000
+	// How often, in governor events, to snapshot the proposals of contracts with new events, so contracts can be
+	// reindexed from a snapshot instead of their full history. If 0, no snapshots are taken.
+	SnapshotIntervalEvents int
+
+	// SNAPSHOT_RETAIN (int) default 3
+	// The number of snapshots kept for each contract. Older snapshots are deleted.
+	SnapshotRetain int
+
+	// APPLY_BATCHED (bool) default false
+	// Whether the events of each proposal in a ledger are applied together, reading and writing the proposal once
+	// and inserting its votes in bulk, rather than one event at a time. The result is the same, but ledgers with
+	// many votes on a proposal are applied with far fewer database round trips.
+	ApplyBatched bool
+
+	// INDEXER_LOCK_KEY (int) default 1
+	// The key of the lock ensuring only one indexer writes to the database. For postgres, this is the advisory
+	// lock key, and standby instances wait for the lock to be released. For sqlite, a second instance refuses to start.
+	// Only change this if the database is shared with another application using advisory locks.
+	IndexerLockKey int64
+
+	// INDEXER_LOCK_POLL_INTERVAL (int) default 5
+	// How often (in seconds) the indexer lock is checked, and how often a standby instance tries to acquire it.
+	IndexerLockPollInterval int
+
+	// METRICS_PORT (string) default ""
+	// The port to serve Prometheus metrics on at /metrics. If not set, metrics are not served.
+	// The combined governor service serves metrics on the API port instead.
+	MetricsPort string
+
+	// PROPOSAL_TITLE_MAX_BYTES (int) default 256
+	// The maximum length (in bytes) of a proposal title. Longer titles are truncated when ingested, and the
+	// proposal is flagged as truncated.
+	ProposalTitleMaxBytes int
+
+	// PROPOSAL_DESCRIPTION_MAX_BYTES (int) default 16384
+	// The maximum length (in bytes) of a proposal description. Longer descriptions are truncated when ingested,
+	// and the proposal is flagged as truncated.
+	ProposalDescriptionMaxBytes int
+
+	// PROPOSAL_ACTION_MAX_BYTES (int) default 8192
+	// The maximum length (in bytes) of a proposal action, as a base64-encoded XDR string. Longer actions are
+	// dropped when ingested, as a truncated action can't be decoded, and the proposal is flagged as truncated.
+	ProposalActionMaxBytes int
+
+	// IPFS_GATEWAY_URL (string) default ""
+	// The URL of the IPFS HTTP gateway used to fetch proposal descriptions of the form ipfs://<cid>, for example
+	// "https://ipfs.io". Content is fetched from <IPFS_GATEWAY_URL>/ipfs/<cid>. If not set, content is not fetched.
+	IpfsGatewayUrl string
+
+	// IPFS_FETCH_TIMEOUT (int) default 10
+	// The maximum duration (in seconds) of a single IPFS content fetch.
+	IpfsFetchTimeout int
+
+	// IPFS_MAX_CONTENT_BYTES (int) default 1048576
+	// The maximum size (in bytes) of fetched IPFS content. Larger content is not stored, and is not retried.
+	IpfsMaxContentBytes int
+
+	// VOTE_TOKEN_FETCH (bool) default true
+	// Whether the symbol and decimals of each governor's vote token are read from the first RPC server at RPC_URL,
+	// by simulating calls to its votes contract, so the API can format vote amounts. Disable for air-gapped
+	// deployments, in which case the API only returns raw amounts.
+	VoteTokenFetch bool
+
+	// LOW_PARTICIPATION_MIN_VOTERS (int) default 1
+	// Proposals whose voting closes with fewer distinct voters than this, and with a total amount voted below
+	// LOW_PARTICIPATION_MIN_AMOUNT, are flagged as low participation. Set to 0 to never flag proposals.
+	LowParticipationMinVoters int
+
+	// LOW_PARTICIPATION_MIN_AMOUNT (string) default "1"
+	// The total amount voted for, against, and abstaining, in the token's smallest unit, below which proposals with
+	// fewer than LOW_PARTICIPATION_MIN_VOTERS voters are flagged as low participation.
+	LowParticipationMinAmount string
+
+	// PARSE_FAILURE_WARN_PERCENT (int) default 10
+	// The percentage of a registered contract's last 100 governor events that must fail to parse, or be recorded as
+	// failed events, before the indexer logs a warning, such as when the contract was upgraded to a release the
+	// indexer doesn't support. Set to 0 to never warn.
+	ParseFailureWarnPercent int
+
+	// INDEX_FAILED_TX_EVENTS (bool) default false
+	// Whether the governor events of tracked contracts emitted by failed transactions, such as a vote rejected by the
+	// contract, are recorded in a separate table for analytics. They never change proposals or votes.
+	IndexFailedTxEvents bool
+
+	// INDEXER_ERROR_LOG_SIZE (int) default 1000
+	// The number of recent processing errors, such as events that failed to apply, kept in the indexer_errors table
+	// and listed by the API's GET /admin/errors. Older errors are deleted. Set to 0 to only log errors.
+	ErrorLogSize int
+
+	// EVENT_ORDER_STRICT (bool) default false
+	// Whether a governor event older than the last event applied for its contract, that isn't in the history yet,
+	// stops the indexer instead of only being logged and counted by governor_indexer_events_out_of_order_total.
+	// Events are always applied in event id order, so this only happens if the ingestion pipeline reorders them.
+	EventOrderStrict bool
+
+	// CONTRACT_DISCOVERY (bool) default false
+	// Whether governors registered by the indexer are unreviewed until an operator approves them with the API's
+	// PUT /admin/contracts/{contractId}/reviewed. Their events are indexed from the first one, but the API hides them
+	// unless requested with ?include_unreviewed=true. Otherwise every governor is trusted once registered.
+	ContractDiscovery bool
+
+	// PROGRESS_LOG_LEDGERS (int) default 1000
+	// The number of ledgers between ingestion progress logs, with the rate over the last 5 minutes, and the percent
+	// complete and ETA to the latest ledger of the first RPC_URL. Set to 0 to only log the summary when the indexer
+	// stops. The progress is recorded for the API's GET /admin/status and metrics either way.
+	ProgressLogLedgers int
+}
+
+// FieldLimits returns the governor.FieldLimits applied to ingested proposals
+func (c Indexer) FieldLimits() governor.FieldLimits {
+	return governor.FieldLimits{
+		Title:       c.ProposalTitleMaxBytes,
+		Description: c.ProposalDescriptionMaxBytes,
+		Action:      c.ProposalActionMaxBytes,
+	}
+}
+
+// ParticipationThreshold returns the governor.ParticipationThreshold applied when a proposal's voting closes
+func (c Indexer) ParticipationThreshold() governor.ParticipationThreshold {
+	return governor.ParticipationThreshold{
+		MinVoters: c.LowParticipationMinVoters,
+		MinAmount: c.LowParticipationMinAmount,
+	}
+}
+
+// LoadIndexer loads the indexer configuration from environment variables. All invalid variables
+// are reported in the returned error.
+func LoadIndexer() (*Indexer, error) {
+	l := &loader{}
+	c := &Indexer{}
+	c.DB = loadDB(l)
+	c.Log = loadLog(l)
+	c.Network = l.oneOf("NETWORK", "testnet", "public", "testnet")
+	c.LedgerBackendType = l.oneOf("LEDGER_BACKEND_TYPE", "rpc", "rpc", "core")
+	// 0 is resolved from the RPC server at startup
+	defaultStartSeq := uint32(0)
+	if c.Network == "public" {
+		defaultStartSeq = PUBLIC_SOROBAN_LEDGER
+	}
+	c.LedgerBackendStartSeq = l.uint32("LEDGER_BACKEND_START_SEQ", defaultStartSeq, 2)
+	c.IndexerAllowGap = l.bool("INDEXER_ALLOW_GAP", false)
+	if c.LedgerBackendType == "rpc" {
+		c.RPCUrls = l.urls("RPC_URL", "https://soroban-testnet.stellar.org")
+	} else {
+		c.RPCUrls = splitList(l.string("RPC_URL", "https://soroban-testnet.stellar.org"))
+	}
+	c.RPCPollInterval = l.int("RPC_POLL_INTERVAL", 2, 1)
+	c.CoreConfigPath = l.string("CORE_CONFIG_PATH", "/config/stellar-core.cfg")
+	c.CoreBinaryPath = l.string("CORE_BINARY_PATH", "/usr/bin/stellar-core")
+	c.CoreStoragePath = l.string("CORE_STORAGE_PATH", "")
+	c.CoreLogLevel = l.oneOf("CORE_LOG_LEVEL", "warn", "panic", "fatal", "error", "warn", "warning", "info", "debug", "trace")
+	c.HistoryRetentionLedgers = l.uint32("HISTORY_RETENTION_LEDGERS", 0, 0)
+	c.HistoryPruneIntervalLedgers = l.uint32("HISTORY_PRUNE_INTERVAL_LEDGERS", 720, 1)
+	c.SnapshotIntervalEvents = l.int("SNAPSHOT_INTERVAL_EVENTS", 10000, 0)
+	c.SnapshotRetain = l.int("SNAPSHOT_RETAIN", 3, 1)
+	c.ApplyBatched = l.bool("APPLY_BATCHED", false)
+	c.IndexerLockKey = l.int64("INDEXER_LOCK_KEY", 1)
+	c.IndexerLockPollInterval = l.int("INDEXER_LOCK_POLL_INTERVAL", 5, 1)
+	c.MetricsPort = l.port("METRICS_PORT", "")
+	c.ProposalTitleMaxBytes = l.int("PROPOSAL_TITLE_MAX_BYTES", governor.DEFAULT_FIELD_LIMITS.Title, 1)
+	c.ProposalDescriptionMaxBytes = l.int("PROPOSAL_DESCRIPTION_MAX_BYTES", governor.DEFAULT_FIELD_LIMITS.Description, 1)
+	c.ProposalActionMaxBytes = l.int("PROPOSAL_ACTION_MAX_BYTES", governor.DEFAULT_FIELD_LIMITS.Action, 1)
+	c.IpfsGatewayUrl = l.string("IPFS_GATEWAY_URL", "")
+	if c.IpfsGatewayUrl != "" {
+		c.IpfsGatewayUrl = l.url("IPFS_GATEWAY_URL", "")
+	}
+	c.IpfsFetchTimeout = l.int("IPFS_FETCH_TIMEOUT", 10, 1)
+	c.IpfsMaxContentBytes = l.int("IPFS_MAX_CONTENT_BYTES", 1024*1024, 1)
+	c.VoteTokenFetch = l.bool("VOTE_TOKEN_FETCH", true)
+	c.LowParticipationMinVoters = l.int("LOW_PARTICIPATION_MIN_VOTERS", governor.DEFAULT_PARTICIPATION_THRESHOLD.MinVoters, 0)
+	c.LowParticipationMinAmount = l.string("LOW_PARTICIPATION_MIN_AMOUNT", governor.DEFAULT_PARTICIPATION_THRESHOLD.MinAmount)
+	if _, err := bigmath.ParseAmount(c.LowParticipationMinAmount); err != nil {
+		l.fail("LOW_PARTICIPATION_MIN_AMOUNT", "must be an i128 amount, got %q", c.LowParticipationMinAmount)
+	}
+	c.ParseFailureWarnPercent = l.int("PARSE_FAILURE_WARN_PERCENT", 10, 0)
+	c.IndexFailedTxEvents = l.bool("INDEX_FAILED_TX_EVENTS", false)
+	c.ErrorLogSize = l.int("INDEXER_ERROR_LOG_SIZE", 1000, 0)
+	c.EventOrderStrict = l.bool("EVENT_ORDER_STRICT", false)
+	c.ContractDiscovery = l.bool("CONTRACT_DISCOVERY", false)
+	c.ProgressLogLedgers = l.int("PROGRESS_LOG_LEDGERS", 1000, 0)
+
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+)
+
+// Log is the logging configuration shared by all services
+type Log struct {
+	// LOG_LEVEL (string) default "info"
+	// The minimum level of logs to output. Supported values are "debug", "info", "warn", and "error".
+	Level string
+	// LOG_FORMAT (string) default "text"
+	// The format of log output. Supported values are "text" and "json".
+	Format string
+}
+
+// SlogLevel returns the slog level for the configured LOG_LEVEL
+func (c Log) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// logLevel is the level of the loggers created by NewLogger, so it can be changed when the config is reloaded
+var logLevel = new(slog.LevelVar)
+
+// NewLogger creates a logger writing to w in the configured format, at the configured level. Every logger created
+// by NewLogger shares the level set by the last call to NewLogger or ApplyLevel.
+func (c Log) NewLogger(w io.Writer) *slog.Logger {
+	c.ApplyLevel()
+	opts := &slog.HandlerOptions{Level: logLevel}
+	if c.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ApplyLevel sets the level of the loggers created by NewLogger to the configured level
+func (c Log) ApplyLevel() {
+	logLevel.Set(c.SlogLevel())
+}
+
+func loadLog(l *loader) Log {
+	c := Log{}
+	c.Level = l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error")
+	c.Format = l.oneOf("LOG_FORMAT", "text", "text", "json")
+	return c
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+)
+
+// SECRET_FIELDS are the config fields whose values are never logged
+var SECRET_FIELDS = []string{"DB.ConnectionString", "AdminTokens", "APIKeys"}
+
+// Change is a config field that differs between two loads of a config, named by its path, e.g. "Log.Level"
+type Change struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// Diff returns the fields that differ between two configs of the same type, in field order
+func Diff[T any](old *T, new *T) []Change {
+	return diff("", reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem())
+}
+
+func diff(prefix string, old reflect.Value, new reflect.Value) []Change {
+	var changes []Change
+	for i := range old.NumField() {
+		field := prefix + old.Type().Field(i).Name
+		oldVal, newVal := old.Field(i), new.Field(i)
+		if oldVal.Kind() == reflect.Struct {
+			changes = append(changes, diff(field+".", oldVal, newVal)...)
+			continue
+		}
+		if !reflect.DeepEqual(oldVal.Interface(), newVal.Interface()) {
+			changes = append(changes, Change{Field: field, Old: oldVal.Interface(), New: newVal.Interface()})
+		}
+	}
+	return changes
+}
+
+// Reload reloads the config files, then loads the config with load, and logs how it differs from current. Changes
+// to the reloadable fields are logged as applied, and the caller applies them from the returned config. Changes to
+// other fields are logged as ignored, as they only take effect on a restart.
+func Reload[T any](current *T, load func() (*T, error), reloadable ...string) (*T, error) {
+	ReloadFiles()
+	next, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Diff(current, next)
+	if len(changes) == 0 {
+		slog.Info("Config reloaded, nothing changed.")
+	}
+	for _, change := range changes {
+		old, new := change.Old, change.New
+		if slices.Contains(SECRET_FIELDS, change.Field) {
+			old, new = "[redacted]", "[redacted]"
+		}
+		if slices.Contains(reloadable, change.Field) {
+			slog.Info("Config setting reloaded", "field", change.Field, "old", fmt.Sprint(old), "new", fmt.Sprint(new))
+		} else {
+			slog.Warn("Config setting changed, but can't be reloaded; restart to apply it", "field", change.Field, "old", fmt.Sprint(old), "new", fmt.Sprint(new))
+		}
+	}
+	return next, nil
+}
+
+// WatchReload calls reload each time the process receives SIGHUP, until ctx is cancelled
+func WatchReload(ctx context.Context, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("SIGHUP received, reloading config...")
+			reload()
+		}
+	}
+}
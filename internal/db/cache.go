@@ -0,0 +1,176 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+const (
+	// PROPOSAL_CACHE_CHANNEL is the postgres NOTIFY channel used to invalidate cached proposals in other processes
+	PROPOSAL_CACHE_CHANNEL = "proposal_cache"
+	// PROPOSAL_CACHE_PURGE is the invalidation payload that removes every cached proposal
+	PROPOSAL_CACHE_PURGE = "*"
+)
+
+// proposalCache is an LRU cache of proposals by proposal key. Entries expire after ttl, which bounds how stale a
+// proposal can be if an invalidation is missed.
+type proposalCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+type proposalCacheEntry struct {
+	key      string
+	proposal governor.Proposal
+	expires  time.Time
+}
+
+func newProposalCache(size int, ttl time.Duration) *proposalCache {
+	return &proposalCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached proposal, or false if it is not cached or has expired
+func (c *proposalCache) get(key string) (*governor.Proposal, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*proposalCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	proposal := entry.proposal
+	return &proposal, true
+}
+
+// put caches a copy of the proposal, evicting the least recently used proposal if the cache is full
+func (c *proposalCache) put(proposal *governor.Proposal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &proposalCacheEntry{key: proposal.ProposalKey, proposal: *proposal, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*proposalCacheEntry).key)
+	}
+}
+
+// remove removes a proposal from the cache. PROPOSAL_CACHE_PURGE removes every proposal.
+func (c *proposalCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key == PROPOSAL_CACHE_PURGE {
+		clear(c.entries)
+		c.order.Init()
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+type txInvalidationsKey struct{}
+
+// invalidateProposal removes a written proposal from the cache. Within a transaction, it is removed again once the
+// transaction commits, as a concurrent read may have cached the old row in the meantime. For postgres, other
+// processes are notified when the write commits. PROPOSAL_CACHE_PURGE invalidates every proposal.
+func (store *Store) invalidateProposal(ctx context.Context, key string) error {
+	if store.proposals == nil {
+		return nil
+	}
+	store.proposals.remove(key)
+	if keys, ok := ctx.Value(txInvalidationsKey{}).(*[]string); ok {
+		*keys = append(*keys, key)
+	}
+	if store.notifyProposals {
+		// NOTIFY is transactional, so is only delivered if the write commits
+		_, err := store.exec(ctx, "SELECT pg_notify($1, $2)", PROPOSAL_CACHE_CHANNEL, key)
+		if err != nil {
+			return fmt.Errorf("notify proposal cache %s: %w", key, timeoutErr(ctx, err))
+		}
+	}
+	return nil
+}
+
+// listenProposalInvalidations removes proposals written by other processes from the cache, until ctx is cancelled.
+// The listener holds one connection from the pool. If the connection drops, the cache is purged, as invalidations
+// may have been missed, and the listener reconnects.
+func (store *Store) listenProposalInvalidations(ctx context.Context) {
+	delay := PING_INITIAL_DELAY
+	for {
+		err := store.waitProposalInvalidations(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		store.proposals.remove(PROPOSAL_CACHE_PURGE)
+		slog.Warn("Proposal cache listener disconnected, retrying", "retry_in", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, PING_MAX_DELAY)
+	}
+}
+
+// waitProposalInvalidations listens on PROPOSAL_CACHE_CHANNEL and applies invalidations until the connection fails
+func (store *Store) waitProposalInvalidations(ctx context.Context) error {
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get listener connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		if _, err := pgxConn.Conn().Exec(ctx, "LISTEN "+PROPOSAL_CACHE_CHANNEL); err != nil {
+			return errors.Join(fmt.Errorf("listen %s: %w", PROPOSAL_CACHE_CHANNEL, err), driver.ErrBadConn)
+		}
+		slog.Info("Listening for proposal cache invalidations", "channel", PROPOSAL_CACHE_CHANNEL)
+		for {
+			notification, err := pgxConn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				// the connection is still listening, so discard it rather than returning it to the pool
+				return errors.Join(fmt.Errorf("wait for notification: %w", err), driver.ErrBadConn)
+			}
+			store.proposals.remove(notification.Payload)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestProposalCache(t *testing.T) {
+	now := time.Unix(1761053041, 0)
+	cache := newProposalCache(2, 5*time.Second)
+	cache.now = func() time.Time { return now }
+
+	newProposal := func(key string) *governor.Proposal {
+		return &governor.Proposal{ProposalKey: key, VotesFor: "0", VotesAgainst: "0", VotesAbstain: "0"}
+	}
+	cache.put(newProposal("a"))
+	cache.put(newProposal("b"))
+
+	// get returns a copy, so callers can't modify the cached proposal
+	got, ok := cache.get("a")
+	if !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	got.VotesFor = "100"
+	got, _ = cache.get("a")
+	if diff := cmp.Diff(newProposal("a"), got); diff != "" {
+		t.Errorf("check 1: mismatch (-want +got):\n%s", diff)
+	}
+
+	// b is the least recently used, so is evicted
+	cache.put(newProposal("c"))
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("check 2: expected b to be evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Errorf("check 2: expected a to be cached")
+	}
+
+	cache.remove("a")
+	if _, ok := cache.get("a"); ok {
+		t.Errorf("check 3: expected a to be removed")
+	}
+
+	now = now.Add(5 * time.Second)
+	if _, ok := cache.get("c"); ok {
+		t.Errorf("check 4: expected c to expire")
+	}
+
+	cache.put(newProposal("d"))
+	cache.remove(PROPOSAL_CACHE_PURGE)
+	if _, ok := cache.get("d"); ok {
+		t.Errorf("check 5: expected d to be purged")
+	}
+}
+
+func TestGetProposalCached(t *testing.T) {
+	store := setupStore(t)
+	store.proposals = newProposalCache(10, time.Minute)
+	ctx := t.Context()
+
+	proposal := &governor.Proposal{
+		ProposalKey:  governor.EncodeProposalKey("CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", 3),
+		ContractId:   "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+		ProposalId:   3,
+		VotesFor:     "0",
+		VotesAgainst: "0",
+		VotesAbstain: "0",
+	}
+	if err := store.InsertProposal(ctx, proposal); err != nil {
+		t.Fatalf("failed to insert proposal: %v", err)
+	}
+	if _, err := store.GetProposal(ctx, proposal.ProposalKey); err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if _, ok := store.proposals.get(proposal.ProposalKey); !ok {
+		t.Fatalf("check 1: expected proposal to be cached")
+	}
+
+	// writes invalidate the cache
+	updated := *proposal
+	updated.VotesFor = "100"
+	if err := store.UpdateProposal(ctx, &updated, 1); err != nil {
+		t.Fatalf("failed to update proposal: %v", err)
+	}
+	got, err := store.GetProposal(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if diff := cmp.Diff(&updated, got); diff != "" {
+		t.Errorf("check 2: mismatch (-want +got):\n%s", diff)
+	}
+
+	// reads in a transaction bypass the cache, and writes are invalidated again on commit
+	errRollback := errors.New("rollback")
+	err = store.WithTx(ctx, func(ctx context.Context) error {
+		updated.VotesFor = "200"
+		if err := store.UpsertProposal(ctx, &updated); err != nil {
+			return err
+		}
+		store.proposals.put(proposal)
+		got, err := store.GetProposal(ctx, proposal.ProposalKey)
+		if err != nil {
+			return err
+		}
+		if got.VotesFor != "200" {
+			t.Errorf("check 3: expected uncached read in transaction, got votes for %s", got.VotesFor)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if _, ok := store.proposals.get(proposal.ProposalKey); ok {
+		t.Errorf("check 4: expected proposal to be invalidated on commit")
+	}
+
+	// rolled back writes are not invalidated again
+	store.proposals.put(&updated)
+	err = store.WithTx(ctx, func(ctx context.Context) error {
+		_, err := store.DeleteProposalsByContractId(ctx, proposal.ContractId)
+		if err != nil {
+			return err
+		}
+		store.proposals.put(&updated)
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected rollback, got %v", err)
+	}
+	if _, ok := store.proposals.get(proposal.ProposalKey); !ok {
+		t.Errorf("check 5: expected proposal to stay cached after rollback")
+	}
+}
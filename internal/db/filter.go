@@ -0,0 +1,275 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidFilter is returned when a filter can't be parsed, or uses a field or operator a list query can't be
+// filtered by
+var ErrInvalidFilter = errors.New("invalid filter")
+
+const (
+	// MAX_FILTER_CONDITIONS is the maximum number of conditions combined in a filter
+	MAX_FILTER_CONDITIONS = 10
+	// MAX_FILTER_VALUES is the maximum number of values of an IN condition
+	MAX_FILTER_VALUES = 100
+)
+
+// The operators of filter conditions
+const (
+	FILTER_OP_EQ = "="
+	FILTER_OP_NE = "!="
+	FILTER_OP_GT = ">"
+	FILTER_OP_LT = "<"
+	FILTER_OP_IN = "IN"
+)
+
+// filterOpChars are the characters of the comparison operators
+const filterOpChars = "=!<>"
+
+// EVENT_FILTER_FIELDS are the fields events for a contract can be filtered by. amount, support, and voter are read
+// from the event data, so only match events with those fields, such as vote_cast events.
+var EVENT_FILTER_FIELDS = []string{
+	"event_type", "proposal_id", "tx_hash", "source_account", "ledger_seq", "ledger_close_time",
+	"amount", "support", "voter",
+}
+
+// Condition compares a field to one value, or to a list of values with FILTER_OP_IN
+type Condition struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+// Filter is a list of conditions a row must all match. The nil Filter matches every row.
+type Filter []Condition
+
+// ParseFilter parses a filter expression of conditions combined with AND, such as
+// "event_type = vote_cast AND amount > 1000000 AND proposal_id IN (1, 2)". Each condition is a field from fields, an
+// operator of =, !=, >, <, or IN, and a value, or a parenthesized list of values for IN. Values are words of letters,
+// digits, and underscores, and keywords are case insensitive. An empty expression is the nil Filter.
+func ParseFilter(expr string, fields []string) (Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	var filter Filter
+	for len(tokens) > 0 {
+		if len(filter) > 0 {
+			if !strings.EqualFold(tokens[0], "AND") {
+				return nil, fmt.Errorf("expected AND, got %q: %w", tokens[0], ErrInvalidFilter)
+			}
+			tokens = tokens[1:]
+		}
+		var condition Condition
+		condition, tokens, err = parseCondition(tokens, fields)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, condition)
+		if len(filter) > MAX_FILTER_CONDITIONS {
+			return nil, fmt.Errorf("more than %d conditions: %w", MAX_FILTER_CONDITIONS, ErrInvalidFilter)
+		}
+	}
+	return filter, nil
+}
+
+// parseCondition parses the condition at the start of tokens, and returns the tokens after it
+func parseCondition(tokens []string, fields []string) (Condition, []string, error) {
+	if len(tokens) < 3 {
+		return Condition{}, nil, fmt.Errorf("incomplete condition %q: %w", strings.Join(tokens, " "), ErrInvalidFilter)
+	}
+	condition := Condition{Field: tokens[0], Op: strings.ToUpper(tokens[1])}
+	if !slices.Contains(fields, condition.Field) {
+		return Condition{}, nil, fmt.Errorf("unknown field %q, must be one of %s: %w", condition.Field, strings.Join(fields, ", "), ErrInvalidFilter)
+	}
+	switch condition.Op {
+	case FILTER_OP_EQ, FILTER_OP_NE, FILTER_OP_GT, FILTER_OP_LT:
+		if !isFilterWord(tokens[2]) {
+			return Condition{}, nil, fmt.Errorf("invalid value %q for %s: %w", tokens[2], condition.Field, ErrInvalidFilter)
+		}
+		condition.Values = []string{tokens[2]}
+		return condition, tokens[3:], nil
+	case FILTER_OP_IN:
+		rest := tokens[2:]
+		if rest[0] != "(" {
+			return Condition{}, nil, fmt.Errorf("expected ( after IN, got %q: %w", rest[0], ErrInvalidFilter)
+		}
+		rest = rest[1:]
+		for len(rest) > 0 && isFilterWord(rest[0]) {
+			condition.Values = append(condition.Values, rest[0])
+			rest = rest[1:]
+			if len(rest) > 0 && rest[0] == "," {
+				rest = rest[1:]
+			} else {
+				break
+			}
+		}
+		if len(rest) == 0 || rest[0] != ")" || len(condition.Values) == 0 {
+			return Condition{}, nil, fmt.Errorf("invalid IN list for %s: %w", condition.Field, ErrInvalidFilter)
+		}
+		if len(condition.Values) > MAX_FILTER_VALUES {
+			return Condition{}, nil, fmt.Errorf("more than %d values for %s: %w", MAX_FILTER_VALUES, condition.Field, ErrInvalidFilter)
+		}
+		return condition, rest[1:], nil
+	}
+	return Condition{}, nil, fmt.Errorf("unknown operator %q, must be one of =, !=, >, <, IN: %w", tokens[1], ErrInvalidFilter)
+}
+
+// tokenizeFilter splits a filter expression into words, operators, parentheses, and commas
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, string(r))
+			i++
+		case strings.ContainsRune(filterOpChars, r):
+			start := i
+			for i < len(runes) && strings.ContainsRune(filterOpChars, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case isFilterWordRune(r):
+			start := i
+			for i < len(runes) && isFilterWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected character %q: %w", r, ErrInvalidFilter)
+		}
+	}
+	return tokens, nil
+}
+
+func isFilterWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+func isFilterWord(token string) bool {
+	return token != "" && strings.IndexFunc(token, func(r rune) bool { return !isFilterWordRune(r) }) == -1
+}
+
+// filterKind is how the values of a filter field are parsed and compared
+type filterKind int
+
+const (
+	// filterText fields are compared as text, and only support = and != and IN
+	filterText filterKind = iota
+	// filterInteger fields are compared as 64-bit integers
+	filterInteger
+	// filterAmount fields are non-negative i128 amounts stored as text
+	filterAmount
+)
+
+// filterColumn is the SQL expression of a filter field and how its values are compared
+type filterColumn struct {
+	expr string
+	kind filterKind
+}
+
+// filterWhere returns the SQL conditions of filter, each prefixed with AND, with its arguments numbered from offset+1.
+// columns are the SQL expressions of each filterable field, and with the operators, are the only text from the filter
+// used in the conditions, so a filter can't inject SQL.
+func filterWhere(filter Filter, columns map[string]filterColumn, offset int) (string, []any, error) {
+	var where strings.Builder
+	var args []any
+	param := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", offset+len(args))
+	}
+	for _, condition := range filter {
+		column, ok := columns[condition.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("filter by %q: %w", condition.Field, ErrInvalidFilter)
+		}
+		if !slices.Contains([]string{FILTER_OP_EQ, FILTER_OP_NE, FILTER_OP_GT, FILTER_OP_LT, FILTER_OP_IN}, condition.Op) {
+			return "", nil, fmt.Errorf("filter by %q with %q: %w", condition.Field, condition.Op, ErrInvalidFilter)
+		}
+		if condition.Op != FILTER_OP_IN && len(condition.Values) != 1 || len(condition.Values) == 0 {
+			return "", nil, fmt.Errorf("filter by %q with %d values: %w", condition.Field, len(condition.Values), ErrInvalidFilter)
+		}
+		if column.kind == filterText && (condition.Op == FILTER_OP_GT || condition.Op == FILTER_OP_LT) {
+			return "", nil, fmt.Errorf("filter %s %s: only =, !=, and IN compare text: %w", condition.Field, condition.Op, ErrInvalidFilter)
+		}
+		values := make([]any, len(condition.Values))
+		for i, value := range condition.Values {
+			parsed, err := parseFilterValue(column.kind, value)
+			if err != nil {
+				return "", nil, fmt.Errorf("filter %s %s %q: %w", condition.Field, condition.Op, value, ErrInvalidFilter)
+			}
+			values[i] = parsed
+		}
+
+		where.WriteString(" AND ")
+		switch {
+		case condition.Op == FILTER_OP_IN:
+			params := make([]string, len(values))
+			for i, value := range values {
+				params[i] = param(value)
+			}
+			fmt.Fprintf(&where, "%s IN (%s)", column.expr, strings.Join(params, ", "))
+		case column.kind == filterAmount && condition.Op != FILTER_OP_EQ && condition.Op != FILTER_OP_NE:
+			// amounts are canonical non-negative integers as text, so compare by length, then as text
+			value := values[0].(string)
+			length := param(int64(len(value)))
+			fmt.Fprintf(&where, "(LENGTH(%s) %s %s OR (LENGTH(%s) = %s AND %s %s %s))",
+				column.expr, condition.Op, length, column.expr, length, column.expr, condition.Op, param(value))
+		default:
+			fmt.Fprintf(&where, "%s %s %s", column.expr, condition.Op, param(values[0]))
+		}
+	}
+	return where.String(), args, nil
+}
+
+// parseFilterValue parses a filter value as the argument for a field of kind
+func parseFilterValue(kind filterKind, value string) (any, error) {
+	switch kind {
+	case filterInteger:
+		return strconv.ParseInt(value, 10, 64)
+	case filterAmount:
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok || amount.Sign() < 0 {
+			return nil, errors.New("not a non-negative integer")
+		}
+		// written without leading zeros, as amounts are stored
+		return amount.String(), nil
+	}
+	return value, nil
+}
+
+// eventFilterWhere returns the SQL conditions of a filter on history events, with arguments numbered from offset+1
+func (store *Store) eventFilterWhere(filter Filter, offset int) (string, []any, error) {
+	dataField := func(name string) string {
+		if store.isSqlite() {
+			return fmt.Sprintf("json_extract(event_data, '$.%s')", name)
+		}
+		return fmt.Sprintf("(event_data->>'%s')", name)
+	}
+	support := dataField("support")
+	if !store.isSqlite() {
+		support = fmt.Sprintf("CAST(%s AS BIGINT)", support)
+	}
+	return filterWhere(filter, map[string]filterColumn{
+		"event_type":        {expr: "event_type", kind: filterText},
+		"proposal_id":       {expr: "proposal_id", kind: filterInteger},
+		"tx_hash":           {expr: "tx_hash", kind: filterText},
+		"source_account":    {expr: "source_account", kind: filterText},
+		"ledger_seq":        {expr: "ledger_seq", kind: filterInteger},
+		"ledger_close_time": {expr: "ledger_close_time", kind: filterInteger},
+		"amount":            {expr: dataField("amount"), kind: filterAmount},
+		"support":           {expr: support, kind: filterInteger},
+		"voter":             {expr: dataField("voter"), kind: filterText},
+	}, offset)
+}
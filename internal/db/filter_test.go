@@ -0,0 +1,158 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    Filter
+		wantErr bool
+	}{
+		{name: "empty", expr: "", want: nil},
+		{name: "whitespace", expr: "   ", want: nil},
+		{name: "one condition", expr: "event_type = vote_cast", want: Filter{{Field: "event_type", Op: "=", Values: []string{"vote_cast"}}}},
+		{name: "without spaces", expr: "amount>1000000", want: Filter{{Field: "amount", Op: ">", Values: []string{"1000000"}}}},
+		{
+			name: "combined",
+			expr: "event_type != proposal_created and ledger_close_time < 1761053046 AND proposal_id in (1, 2,3)",
+			want: Filter{
+				{Field: "event_type", Op: "!=", Values: []string{"proposal_created"}},
+				{Field: "ledger_close_time", Op: "<", Values: []string{"1761053046"}},
+				{Field: "proposal_id", Op: "IN", Values: []string{"1", "2", "3"}},
+			},
+		},
+		{name: "unknown field", expr: "event_data = x", wantErr: true},
+		{name: "unknown operator", expr: "amount >= 1", wantErr: true},
+		{name: "like", expr: "event_type LIKE vote", wantErr: true},
+		{name: "or", expr: "proposal_id = 1 OR proposal_id = 2", wantErr: true},
+		{name: "missing value", expr: "proposal_id =", wantErr: true},
+		{name: "missing and", expr: "proposal_id = 1 proposal_id = 2", wantErr: true},
+		{name: "trailing and", expr: "proposal_id = 1 AND", wantErr: true},
+		{name: "quote", expr: "event_type = 'vote_cast'", wantErr: true},
+		{name: "semicolon", expr: "proposal_id = 1; DROP TABLE history", wantErr: true},
+		{name: "comment", expr: "proposal_id = 1 --", wantErr: true},
+		{name: "empty in", expr: "proposal_id IN ()", wantErr: true},
+		{name: "unclosed in", expr: "proposal_id IN (1, 2", wantErr: true},
+		{name: "in without parentheses", expr: "proposal_id IN 1", wantErr: true},
+		{name: "too many conditions", expr: strings.Repeat("proposal_id = 1 AND ", MAX_FILTER_CONDITIONS) + "proposal_id = 1", wantErr: true},
+		{name: "too many values", expr: "proposal_id IN (" + strings.Repeat("1, ", MAX_FILTER_VALUES) + "1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.expr, EVENT_FILTER_FIELDS)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Fatalf("expected ErrInvalidFilter, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterEventsByContractId(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	newEvent := func(i int64, eventType string, proposalId uint32, eventData string, closeTime int64) *governor.GovernorEvent {
+		return &governor.GovernorEvent{
+			EventId:         mustEncodeEventId(t, i, 0),
+			ContractId:      contractId,
+			ProposalId:      proposalId,
+			EventType:       eventType,
+			EventData:       eventData,
+			TxHash:          fmt.Sprintf("tx_%03d", i),
+			SourceAccount:   "GA",
+			LedgerSeq:       uint32(5000 + i),
+			LedgerCloseTime: closeTime,
+			SchemaVersion:   governor.SCHEMA_V1,
+		}
+	}
+	events := []*governor.GovernorEvent{
+		newEvent(1, "proposal_created", 1, `{}`, 1000),
+		newEvent(2, "vote_cast", 1, `{"voter":"GA","support":1,"amount":"900"}`, 2000),
+		// longer than 900 but smaller as text
+		newEvent(3, "vote_cast", 1, `{"voter":"GB","support":0,"amount":"10000000"}`, 3000),
+		newEvent(4, "vote_cast", 2, `{"voter":"GC","support":2,"amount":"170141183460469231731687303715884105727"}`, 4000),
+		newEvent(5, "proposal_voting_closed", 2, `{"status":1,"final_quorum":"0"}`, 5000),
+	}
+	for _, event := range events {
+		if err := store.InsertEvent(ctx, event); err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+	}
+	// events of other contracts are never returned
+	other := *events[1]
+	other.EventId, other.ContractId, other.TxHash = mustEncodeEventId(t, 6, 0), "other", "tx_other"
+	if err := store.InsertEvent(ctx, &other); err != nil {
+		t.Fatalf("failed to insert event: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want []*governor.GovernorEvent
+	}{
+		{expr: "", want: events},
+		{expr: "event_type = vote_cast", want: events[1:4]},
+		{expr: "event_type != vote_cast", want: []*governor.GovernorEvent{events[0], events[4]}},
+		{expr: "event_type = vote_cast AND amount > 1000", want: events[2:4]},
+		{expr: "amount < 10000000", want: events[1:2]},
+		{expr: "amount > 0999", want: events[2:4]},
+		{expr: "amount = 10000000", want: events[2:3]},
+		{expr: "amount IN (900, 10000000)", want: events[1:3]},
+		{expr: "support = 0", want: events[2:3]},
+		{expr: "voter IN (GA, GC)", want: []*governor.GovernorEvent{events[1], events[3]}},
+		{expr: "proposal_id = 2", want: events[3:5]},
+		{expr: "ledger_close_time > 1000 AND ledger_close_time < 4000", want: events[1:3]},
+		{expr: "ledger_seq IN (5001, 5005)", want: []*governor.GovernorEvent{events[0], events[4]}},
+		{expr: "tx_hash = tx_002", want: events[1:2]},
+		{expr: "source_account = GB", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr, EVENT_FILTER_FIELDS)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			got, err := store.FilterEventsByContractId(ctx, contractId, filter, Sort{})
+			if err != nil {
+				t.Fatalf("failed to filter events: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// values are checked against the field, and filters not built by ParseFilter are still checked
+	invalid := []Filter{
+		{{Field: "proposal_id", Op: "=", Values: []string{"one"}}},
+		{{Field: "amount", Op: ">", Values: []string{"-1"}}},
+		{{Field: "event_type", Op: ">", Values: []string{"vote_cast"}}},
+		{{Field: "event_data", Op: "=", Values: []string{"{}"}}},
+		{{Field: "proposal_id", Op: "= 1 OR 1 =", Values: []string{"1"}}},
+		{{Field: "proposal_id", Op: "=", Values: []string{"1", "2"}}},
+		{{Field: "proposal_id", Op: "IN"}},
+	}
+	for _, filter := range invalid {
+		if _, err := store.FilterEventsByContractId(ctx, contractId, filter, Sort{}); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%v: expected ErrInvalidFilter, got %v", filter, err)
+		}
+	}
+}
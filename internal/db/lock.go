@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+const LOCK_TABLE_NAME = "indexer_lock"
+
+// ErrLockHeld is returned when the indexer lock is held by another running instance
+var ErrLockHeld = errors.New("indexer lock is held by another instance")
+
+// Lock is an exclusive lock ensuring only one indexer writes to the database.
+//
+// The lock is checked every poll interval. If it is lost, for example because the database connection
+// holding it dropped, the Lost channel is closed and the holder must stop writing.
+type Lock struct {
+	store        *Store
+	key          int64
+	owner        string
+	pollInterval time.Duration
+
+	// conn is the session holding the postgres advisory lock, or nil for sqlite
+	conn *sql.Conn
+
+	lost    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// AcquireIndexerLock takes the indexer lock identified by key.
+//
+// For postgres, a session scoped advisory lock is used. If another instance holds it, this blocks, polling every
+// pollInterval, until the lock is acquired or ctx is done. The advisory lock is released by postgres when the
+// holder's connection closes, so a standby takes over within one poll interval of the leader dying.
+//
+// sqlite databases can't be shared across hosts, so there is no standby. Instead, a lock row with a heartbeat is
+// written, and ErrLockHeld is returned if another instance has refreshed the heartbeat within the last
+// three poll intervals.
+func (store *Store) AcquireIndexerLock(ctx context.Context, key int64, pollInterval time.Duration) (*Lock, error) {
+	ownerBytes := make([]byte, 8)
+	if _, err := rand.Read(ownerBytes); err != nil {
+		return nil, fmt.Errorf("generate lock owner: %w", err)
+	}
+	lock := &Lock{
+		store:        store,
+		key:          key,
+		owner:        hex.EncodeToString(ownerBytes),
+		pollInterval: pollInterval,
+		lost:         make(chan struct{}),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	var err error
+	if store.isSqlite() {
+		err = lock.acquireRow(ctx)
+	} else {
+		err = lock.acquireAdvisory(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go lock.monitor()
+	return lock, nil
+}
+
+// Lost returns a channel that is closed if the lock is lost
+func (lock *Lock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// Release stops monitoring the lock and releases it
+func (lock *Lock) Release(ctx context.Context) error {
+	close(lock.stop)
+	<-lock.stopped
+
+	if lock.conn != nil {
+		_, err := lock.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lock.key)
+		closeErr := lock.conn.Close()
+		if err != nil {
+			return fmt.Errorf("release advisory lock %d: %w", lock.key, err)
+		}
+		return closeErr
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE lock_key = $1 AND owner = $2`, LOCK_TABLE_NAME)
+	_, err := lock.store.exec(ctx, query, lock.key, lock.owner)
+	if err != nil {
+		return fmt.Errorf("release lock row %d: %w", lock.key, err)
+	}
+	return nil
+}
+
+// acquireAdvisory waits for the postgres advisory lock on a dedicated connection
+func (lock *Lock) acquireAdvisory(ctx context.Context) error {
+	conn, err := lock.store.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection for advisory lock: %w", err)
+	}
+
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lock.key).Scan(&acquired)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("try advisory lock %d: %w", lock.key, err)
+		}
+		if acquired {
+			lock.conn = conn
+			slog.Info("Acquired indexer lock", "key", lock.key)
+			return nil
+		}
+
+		slog.Info("Indexer lock held by another instance, waiting", "key", lock.key, "retry_in", lock.pollInterval)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return ctx.Err()
+		case <-time.After(lock.pollInterval):
+		}
+	}
+}
+
+// acquireRow writes the sqlite lock row, unless another owner has a live heartbeat
+func (lock *Lock) acquireRow(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	staleBefore := now - 3*lock.pollInterval.Milliseconds()
+
+	return lock.store.WithTx(ctx, func(ctx context.Context) error {
+		var owner string
+		var heartbeat int64
+		query := fmt.Sprintf(`SELECT owner, heartbeat FROM %s WHERE lock_key = $1`, LOCK_TABLE_NAME)
+		err := lock.store.conn(ctx).QueryRowContext(ctx, query, lock.key).Scan(&owner, &heartbeat)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("get lock row %d: %w", lock.key, err)
+		}
+		if err == nil && heartbeat > staleBefore {
+			return fmt.Errorf("lock %d last refreshed by %s at %d: %w", lock.key, owner, heartbeat, ErrLockHeld)
+		}
+		if err == nil {
+			slog.Warn("Taking over stale indexer lock", "key", lock.key, "previous_owner", owner, "heartbeat", heartbeat)
+		}
+
+		query = fmt.Sprintf(`
+			INSERT INTO %s (lock_key, owner, heartbeat)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (lock_key) DO UPDATE SET owner = EXCLUDED.owner, heartbeat = EXCLUDED.heartbeat
+		`, LOCK_TABLE_NAME)
+		_, err = lock.store.exec(ctx, query, lock.key, lock.owner, now)
+		if err != nil {
+			return fmt.Errorf("write lock row %d: %w", lock.key, err)
+		}
+		slog.Info("Acquired indexer lock", "key", lock.key, "owner", lock.owner)
+		return nil
+	})
+}
+
+// monitor checks the lock every poll interval until Release is called, and closes the lost channel if it is lost
+func (lock *Lock) monitor() {
+	defer close(lock.stopped)
+	ticker := time.NewTicker(lock.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), lock.pollInterval)
+		err := lock.check(ctx)
+		cancel()
+		if err != nil {
+			slog.Error("Indexer lock lost", "key", lock.key, "err", err)
+			close(lock.lost)
+			return
+		}
+	}
+}
+
+// check verifies the lock is still held. For sqlite, this also refreshes the heartbeat.
+func (lock *Lock) check(ctx context.Context) error {
+	if lock.conn != nil {
+		// the advisory lock lives as long as the session, so the lock is held while the connection is alive
+		return lock.conn.PingContext(ctx)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET heartbeat = $1 WHERE lock_key = $2 AND owner = $3`, LOCK_TABLE_NAME)
+	result, err := lock.store.exec(ctx, query, time.Now().UnixMilli(), lock.key, lock.owner)
+	if err != nil {
+		return fmt.Errorf("refresh lock row %d: %w", lock.key, err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("refresh lock row %d: %w", lock.key, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("lock row %d taken over by another instance", lock.key)
+	}
+	return nil
+}
+
+// isSqlite returns true if the store is backed by sqlite
+func (store *Store) isSqlite() bool {
+	return isSqliteDB(store.db)
+}
+
+// isSqliteDB returns true if db is a sqlite database
+func isSqliteDB(db *sql.DB) bool {
+	_, ok := db.Driver().(*sqlite.Driver)
+	return ok
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexerLockSqlite(t *testing.T) {
+	ctx := t.Context()
+	dsn := "file:" + filepath.Join(t.TempDir(), "gov.db")
+	store, err := Open(ctx, Config{Type: "sqlite", ConnectionString: dsn, MaxOpenConns: 1, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := RunMigrations(store.DB()); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	pollInterval := 50 * time.Millisecond
+	lock, err := store.AcquireIndexerLock(ctx, 1, pollInterval)
+	if err != nil {
+		t.Fatalf("AcquireIndexerLock() error = %v", err)
+	}
+
+	// a second instance refuses to start while the heartbeat is live
+	time.Sleep(4 * pollInterval)
+	_, err = store.AcquireIndexerLock(ctx, 1, pollInterval)
+	if !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+
+	// a different key is independent
+	other, err := store.AcquireIndexerLock(ctx, 2, pollInterval)
+	if err != nil {
+		t.Fatalf("AcquireIndexerLock() with other key error = %v", err)
+	}
+	if err := other.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	// the lock can be acquired after release
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	lock, err = store.AcquireIndexerLock(ctx, 1, pollInterval)
+	if err != nil {
+		t.Fatalf("AcquireIndexerLock() after release error = %v", err)
+	}
+
+	// simulate the holder dying by stopping its heartbeat without releasing the lock
+	close(lock.stop)
+	<-lock.stopped
+	time.Sleep(4 * pollInterval)
+
+	takeover, err := store.AcquireIndexerLock(ctx, 1, pollInterval)
+	if err != nil {
+		t.Fatalf("AcquireIndexerLock() for stale lock error = %v", err)
+	}
+	defer takeover.Release(ctx)
+
+	// the previous holder detects the lock was lost
+	if err := lock.check(ctx); err == nil {
+		t.Errorf("expected previous holder to detect lost lock")
+	}
+	select {
+	case <-takeover.Lost():
+		t.Errorf("expected new holder to keep the lock")
+	default:
+	}
+}
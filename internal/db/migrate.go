@@ -5,15 +5,24 @@ import (
 	"embed"
 	"fmt"
 	"log/slog"
+	"path"
 	"sort"
+	"strings"
 )
 
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
+// RunMigrations applies any migrations that haven't been applied yet, in filename order. Migrations named
+// "<name>.sqlite.sql" or "<name>.pgx.sql" only apply to that driver, and are skipped for the other.
 func RunMigrations(db *sql.DB) error {
 	slog.Info("Applying database migrations...\n")
 
+	driver := "pgx"
+	if isSqliteDB(db) {
+		driver = "sqlite"
+	}
+
 	// Create migrations tracking table
 	_, err := db.Exec(`
         CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -33,7 +42,7 @@ func RunMigrations(db *sql.DB) error {
 	// Sort migrations by filename
 	var migrations []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() && migrationApplies(entry.Name(), driver) {
 			migrations = append(migrations, entry.Name())
 		}
 	}
@@ -78,3 +87,9 @@ func RunMigrations(db *sql.DB) error {
 	slog.Info("Database migrations complete.")
 	return nil
 }
+
+// migrationApplies returns true if the migration file applies to driver, see RunMigrations
+func migrationApplies(filename string, driver string) bool {
+	dialect := strings.TrimPrefix(path.Ext(strings.TrimSuffix(filename, ".sql")), ".")
+	return dialect == "" || dialect == driver
+}
@@ -0,0 +1,6 @@
+-- Create contract blocklist table for contracts the indexer should ignore
+CREATE TABLE IF NOT EXISTS contract_blocklist (
+    contract_id TEXT PRIMARY KEY,
+    reason TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
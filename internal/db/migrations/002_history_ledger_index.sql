@@ -0,0 +1,2 @@
+-- Index history by ledger to support pruning old events
+CREATE INDEX IF NOT EXISTS idx_history_ledger_seq ON history(ledger_seq);
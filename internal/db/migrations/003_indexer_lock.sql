@@ -0,0 +1,6 @@
+-- Create indexer lock table, used on sqlite to detect a second indexer writing to the same database
+CREATE TABLE IF NOT EXISTS indexer_lock (
+    lock_key BIGINT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    heartbeat BIGINT NOT NULL -- unix milliseconds
+);
@@ -0,0 +1,2 @@
+-- Add a version to proposals, incremented on every update, for optimistic concurrency control
+ALTER TABLE proposals ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,18 @@
+-- Record the schema version each event was parsed with. Events indexed before versioning are v1.
+ALTER TABLE history ADD COLUMN schema_version INTEGER NOT NULL DEFAULT 1;
+
+-- Create failed events table, storing governor events the indexer could not index
+-- ref /internal/governor/failed_event.go: FailedEvent
+CREATE TABLE IF NOT EXISTS failed_events (
+    event_id TEXT PRIMARY KEY,
+    contract_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    error TEXT NOT NULL,
+    event_xdr TEXT NOT NULL,
+    tx_hash TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL,
+    ledger_close_time BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_events_contract_ledger ON failed_events(contract_id, ledger_seq DESC);
@@ -0,0 +1,24 @@
+-- Create delegations table to track delegate changes in votes contracts
+-- ref /internal/governor/delegation.go: Delegation
+CREATE TABLE IF NOT EXISTS delegations (
+    event_id TEXT PRIMARY KEY,
+    contract_id TEXT NOT NULL,
+    delegator TEXT NOT NULL,
+    from_delegate TEXT NOT NULL,
+    delegate TEXT NOT NULL,
+    tx_hash TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL,
+    ledger_close_time BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegations_contract_delegate ON delegations(contract_id, delegate);
+CREATE INDEX IF NOT EXISTS idx_delegations_contract_delegator ON delegations(contract_id, delegator);
+
+-- Create votes contracts table, mapping each governor to the votes contract read from its settings
+CREATE TABLE IF NOT EXISTS votes_contracts (
+    governor_id TEXT PRIMARY KEY,
+    votes_id TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_votes_contracts_votes_id ON votes_contracts(votes_id);
@@ -0,0 +1,2 @@
+-- Flag proposals whose title, description, or action was truncated to the field limits at ingest time
+ALTER TABLE proposals ADD COLUMN truncated BOOLEAN NOT NULL DEFAULT FALSE;
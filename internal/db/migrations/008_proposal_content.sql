@@ -0,0 +1,17 @@
+-- Create proposal content table to cache the off-chain content of proposals with an IPFS description
+-- ref /internal/governor/content.go: ProposalContent
+CREATE TABLE IF NOT EXISTS proposal_content (
+    proposal_key TEXT PRIMARY KEY,
+    contract_id TEXT NOT NULL,
+    proposal_id INTEGER NOT NULL,
+    uri TEXT NOT NULL,
+    status TEXT NOT NULL,
+    content TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    next_attempt_at BIGINT NOT NULL,
+    last_error TEXT NOT NULL,
+    fetched_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_proposal_content_contract_id ON proposal_content(contract_id);
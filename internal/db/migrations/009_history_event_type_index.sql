@@ -0,0 +1,2 @@
+-- Index history by event type to support the recent activity feed filtered by event type
+CREATE INDEX IF NOT EXISTS idx_history_event_type_event_id ON history(event_type, event_id);
@@ -0,0 +1,2 @@
+-- Index proposals by status and voting end to support listing open proposals across all contracts
+CREATE INDEX IF NOT EXISTS idx_proposals_status_vote_end ON proposals(status, vote_end, proposal_key);
@@ -0,0 +1,2 @@
+-- Index history by proposal to support reconstructing a proposal from its events
+CREATE INDEX IF NOT EXISTS idx_history_contract_proposal_event_id ON history(contract_id, proposal_id, event_id);
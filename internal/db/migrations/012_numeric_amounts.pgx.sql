@@ -0,0 +1,18 @@
+-- Mirror the i128 amounts stored as TEXT into NUMERIC columns, so they can be sorted and aggregated in SQL. Only
+-- applied on postgres, as sqlite stores integers above 2^63 as lossy floats.
+ALTER TABLE votes ADD COLUMN amount_numeric NUMERIC(39,0);
+UPDATE votes SET amount_numeric = CAST(amount AS NUMERIC(39,0));
+ALTER TABLE votes ALTER COLUMN amount_numeric SET NOT NULL;
+
+ALTER TABLE proposals
+    ADD COLUMN votes_for_numeric NUMERIC(39,0),
+    ADD COLUMN votes_against_numeric NUMERIC(39,0),
+    ADD COLUMN votes_abstain_numeric NUMERIC(39,0);
+UPDATE proposals SET
+    votes_for_numeric = CAST(votes_for AS NUMERIC(39,0)),
+    votes_against_numeric = CAST(votes_against AS NUMERIC(39,0)),
+    votes_abstain_numeric = CAST(votes_abstain AS NUMERIC(39,0));
+ALTER TABLE proposals
+    ALTER COLUMN votes_for_numeric SET NOT NULL,
+    ALTER COLUMN votes_against_numeric SET NOT NULL,
+    ALTER COLUMN votes_abstain_numeric SET NOT NULL;
@@ -0,0 +1,24 @@
+-- Record the ledger a proposal was created at, and the last event that changed it, to trace how a proposal reached
+-- its current state. updated_at is the unix time the indexer last wrote the proposal.
+ALTER TABLE proposals ADD COLUMN created_ledger INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proposals ADD COLUMN updated_ledger INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proposals ADD COLUMN updated_event_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE proposals ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0;
+
+-- Backfill from the event history, where it hasn't been pruned. The latest event of a proposal is assumed to have
+-- changed it, which is wrong for events that did not apply, such as a vote cast after voting closed.
+UPDATE proposals SET
+    created_ledger = COALESCE((
+        SELECT MIN(ledger_seq) FROM history
+        WHERE history.contract_id = proposals.contract_id
+            AND history.proposal_id = proposals.proposal_id
+            AND history.event_type = 'proposal_created'
+    ), 0),
+    updated_event_id = COALESCE((
+        SELECT MAX(event_id) FROM history
+        WHERE history.contract_id = proposals.contract_id
+            AND history.proposal_id = proposals.proposal_id
+            AND history.event_type IN ('proposal_created', 'proposal_canceled', 'proposal_voting_closed', 'proposal_executed', 'proposal_expired', 'vote_cast')
+    ), '');
+UPDATE proposals SET
+    updated_ledger = COALESCE((SELECT ledger_seq FROM history WHERE history.event_id = proposals.updated_event_id), 0);
@@ -0,0 +1,3 @@
+-- Index votes by voter to support looking up a voter's vote on a proposal. Not unique, as a voter can vote again, and
+-- only their latest vote counts.
+CREATE INDEX IF NOT EXISTS idx_votes_contract_proposal_voter ON votes(contract_id, proposal_id, voter, ledger_seq);
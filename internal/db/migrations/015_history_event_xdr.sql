@@ -0,0 +1,3 @@
+-- Keep the raw contract event of each governor event, as base64 XDR, so clients can parse events themselves. Nullable,
+-- as events indexed before this migration don't have it until they are backfilled.
+ALTER TABLE history ADD COLUMN event_xdr TEXT;
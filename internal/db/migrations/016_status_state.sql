@@ -0,0 +1,4 @@
+-- Record a state and the unix time a status was written, for sources such as captive core whose progress is reported
+-- while no ledger is processed. Nullable, as the indexer's own status only records the last processed ledger.
+ALTER TABLE status ADD COLUMN state TEXT;
+ALTER TABLE status ADD COLUMN updated_at BIGINT;
@@ -0,0 +1,12 @@
+-- Create snapshots table storing the proposals of a contract after a ledger was processed, so the contract can be
+-- reindexed by replaying only the events after the snapshot. event_id is the last event of the contract included.
+CREATE TABLE IF NOT EXISTS snapshots (
+    contract_id TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL,
+    event_id TEXT NOT NULL,
+    proposals TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (contract_id, ledger_seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_snapshots_ledger ON snapshots(ledger_seq);
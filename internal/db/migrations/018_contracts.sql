@@ -0,0 +1,15 @@
+-- Create contracts table registering each governor that emitted an event, with its most recent applied event, so
+-- contracts can be listed without scanning the history table
+CREATE TABLE IF NOT EXISTS contracts (
+    contract_id TEXT PRIMARY KEY,
+    last_event_ledger INTEGER NOT NULL,
+    last_event_close_time BIGINT NOT NULL
+);
+
+-- Register the governors already in the history. Delegation events are emitted by votes contracts.
+INSERT INTO contracts (contract_id, last_event_ledger, last_event_close_time)
+SELECT contract_id, MAX(ledger_seq), MAX(ledger_close_time)
+FROM history
+WHERE event_type NOT IN ('delegate_changed', 'delegate_votes_changed')
+GROUP BY contract_id
+ON CONFLICT (contract_id) DO NOTHING;
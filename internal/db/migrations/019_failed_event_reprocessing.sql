@@ -0,0 +1,4 @@
+-- Track the reprocessing of failed events. attempts counts the failed attempts at reprocessing an event, and
+-- processed_at is the unix time it was reprocessed and applied. Nullable, as most failed events are never reprocessed.
+ALTER TABLE failed_events ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE failed_events ADD COLUMN processed_at BIGINT;
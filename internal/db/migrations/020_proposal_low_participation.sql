@@ -0,0 +1,12 @@
+-- Flag proposals whose voting closed with participation below the indexer's threshold, so clients can hide spam
+ALTER TABLE proposals ADD COLUMN flagged_low_participation BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Backfill with the default threshold, flagging proposals that closed without any votes. Every status other than
+-- open and canceled is reached by voting closing. Reindex a contract to apply another threshold.
+UPDATE proposals SET flagged_low_participation = TRUE
+WHERE status IN (1, 2, 3, 4)
+    AND votes_for = '0' AND votes_against = '0' AND votes_abstain = '0'
+    AND NOT EXISTS (
+        SELECT 1 FROM votes
+        WHERE votes.contract_id = proposals.contract_id AND votes.proposal_id = proposals.proposal_id
+    );
@@ -0,0 +1,11 @@
+-- Create contract_metadata table describing governors for display, such as their DAO name and icon, so frontends
+-- don't need to map contract ids to names themselves. links is a JSON object of link names to URLs.
+CREATE TABLE IF NOT EXISTS contract_metadata (
+    contract_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    icon_url TEXT NOT NULL,
+    website TEXT NOT NULL,
+    links TEXT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
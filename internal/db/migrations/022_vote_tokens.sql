@@ -0,0 +1,4 @@
+-- Add the symbol and decimals of each governor's vote token to the contracts registry, read from the votes contract
+-- by the indexer, so amounts can be formatted. Both are NULL until the token is read.
+ALTER TABLE contracts ADD COLUMN token_symbol TEXT;
+ALTER TABLE contracts ADD COLUMN token_decimals INTEGER;
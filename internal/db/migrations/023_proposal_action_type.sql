@@ -0,0 +1,7 @@
+-- Classify each proposal's action, and record the contract and function called by calldata actions, so clients can
+-- find proposals changing the security council or upgrading the governor
+ALTER TABLE proposals ADD COLUMN action_type TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE proposals ADD COLUMN action_contract_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE proposals ADD COLUMN action_function TEXT NOT NULL DEFAULT '';
+
+-- Actions are decoded from XDR by the indexer, so existing proposals stay unknown until their contract is reindexed
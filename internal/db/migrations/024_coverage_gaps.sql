@@ -0,0 +1,9 @@
+-- Create coverage_gaps table recording the ranges of ledgers the indexer knowingly did not index, such as ledgers
+-- skipped with INDEXER_ALLOW_GAP, so holes in coverage aren't silent. Ranges are inclusive.
+CREATE TABLE IF NOT EXISTS coverage_gaps (
+    from_ledger INTEGER NOT NULL,
+    to_ledger INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (from_ledger, to_ledger)
+);
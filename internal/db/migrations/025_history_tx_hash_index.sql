@@ -0,0 +1,2 @@
+-- Index history by transaction hash to support looking up the events of a transaction
+CREATE INDEX IF NOT EXISTS idx_history_tx_hash ON history(tx_hash);
@@ -0,0 +1,16 @@
+-- Record the source account of the transaction that emitted each event, and the transaction that created each proposal
+-- with its source account, so explorers can link to the creating transaction and identify the submitting wallet
+ALTER TABLE history ADD COLUMN source_account TEXT NOT NULL DEFAULT '';
+ALTER TABLE proposals ADD COLUMN source_account TEXT NOT NULL DEFAULT '';
+ALTER TABLE proposals ADD COLUMN created_tx_hash TEXT NOT NULL DEFAULT '';
+
+-- The creating transaction is kept in the history, but source accounts are read from transaction envelopes, so
+-- existing events and proposals have none until their contract is reindexed
+UPDATE proposals SET created_tx_hash = COALESCE((
+    SELECT history.tx_hash FROM history
+    WHERE history.contract_id = proposals.contract_id
+        AND history.proposal_id = proposals.proposal_id
+        AND history.event_type = 'proposal_created'
+    ORDER BY history.event_id ASC
+    LIMIT 1
+), '');
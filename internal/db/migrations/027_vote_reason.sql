@@ -0,0 +1,3 @@
+-- Record the reason given with a vote, for governors whose vote_cast events include one. Votes without a reason,
+-- including those indexed before it was stored, are NULL.
+ALTER TABLE votes ADD COLUMN reason TEXT;
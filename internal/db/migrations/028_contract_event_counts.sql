@@ -0,0 +1,5 @@
+-- Count the governor events of each registered contract that were parsed or failed to parse, so a contract whose
+-- events stop parsing, such as after an upgrade, stands out
+ALTER TABLE contracts ADD COLUMN events_seen BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE contracts ADD COLUMN events_parsed BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE contracts ADD COLUMN events_failed BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Store event data as JSONB, so postgres rejects data that is not valid JSON and it can be queried with the JSON
+-- operators. Only applied on postgres, as sqlite has no JSON column type. The conversion fails if an existing row holds
+-- invalid JSON.
+--
+-- JSONB can't store a string with a \u0000 escape, which older releases wrote for NUL characters in titles,
+-- descriptions, and reasons, so they are removed first, as they now are at parse time. Escaped backslashes are swapped
+-- for a control character, which valid JSON text never holds, so a "\\u0000" string is left as is.
+UPDATE history
+SET event_data = replace(replace(replace(event_data, '\\', chr(1)), '\u0000', ''), chr(1), '\\')
+WHERE strpos(event_data, '\u0000') > 0;
+
+ALTER TABLE history ALTER COLUMN event_data TYPE JSONB USING event_data::JSONB;
@@ -0,0 +1,18 @@
+-- Create failed_tx_events table, storing the governor events of tracked contracts emitted by failed transactions when
+-- INDEX_FAILED_TX_EVENTS is enabled. Kept apart from history, as the transactions' changes were rolled back.
+-- ref /internal/governor/failed_tx_event.go: FailedTxEvent
+CREATE TABLE IF NOT EXISTS failed_tx_events (
+    event_id TEXT PRIMARY KEY,
+    contract_id TEXT NOT NULL,
+    proposal_id INTEGER NOT NULL,
+    event_type TEXT NOT NULL,
+    event_data TEXT NOT NULL,
+    tx_hash TEXT NOT NULL,
+    source_account TEXT NOT NULL,
+    result_code TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL,
+    ledger_close_time BIGINT NOT NULL,
+    event_xdr TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_tx_events_contract ON failed_tx_events(contract_id, event_id);
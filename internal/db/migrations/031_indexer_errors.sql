@@ -0,0 +1,9 @@
+-- Create indexer_errors table, a log of the indexer's recent processing errors so operators can see them through the
+-- API without access to its logs. seq orders the errors, and only the latest rows are kept, as a ring buffer.
+CREATE TABLE IF NOT EXISTS indexer_errors (
+    seq BIGINT PRIMARY KEY,
+    ledger_seq INTEGER NOT NULL,
+    tx_hash TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Record the id of the most recent event applied for each registered contract, so the indexer can tell when it is
+-- asked to apply an event older than events it already applied
+ALTER TABLE contracts ADD COLUMN last_event_id TEXT NOT NULL DEFAULT '';
+
+-- Backfill from the event history, where it hasn't been pruned. Delegation events are emitted by votes contracts.
+UPDATE contracts SET
+    last_event_id = COALESCE((
+        SELECT MAX(event_id) FROM history
+        WHERE history.contract_id = contracts.contract_id
+            AND history.event_type NOT IN ('delegate_changed', 'delegate_votes_changed')
+    ), '');
@@ -0,0 +1,4 @@
+-- Flag whether each registered contract was reviewed by an operator. With contract discovery, the indexer registers
+-- new governors as unreviewed, and the API hides them until they are approved. Contracts already registered were
+-- trusted, so they are reviewed.
+ALTER TABLE contracts ADD COLUMN reviewed BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,14 @@
+-- Create ingestion_progress table recording the progress of each source ingesting ledgers, such as the indexer
+-- catching up, so its rate and ETA can be read through the API. target_ledger is 0 if the end of the range is unknown.
+CREATE TABLE IF NOT EXISTS ingestion_progress (
+    source TEXT PRIMARY KEY,
+    start_ledger INTEGER NOT NULL,
+    ledger INTEGER NOT NULL,
+    target_ledger INTEGER NOT NULL,
+    ledgers BIGINT NOT NULL,
+    events BIGINT NOT NULL,
+    ledgers_per_second DOUBLE PRECISION NOT NULL,
+    events_per_second DOUBLE PRECISION NOT NULL,
+    started_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
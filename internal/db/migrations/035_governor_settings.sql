@@ -0,0 +1,12 @@
+-- Create governor settings table, recording the settings of each governor that decide how its proposals' votes are
+-- counted, read from the governor's instance storage. Settings are kept by the ledger they were read at, so a
+-- proposal is counted with the settings in effect when its voting ended.
+-- ref /internal/governor/settings.go: Settings
+CREATE TABLE IF NOT EXISTS governor_settings (
+    governor_id TEXT NOT NULL,
+    ledger_seq INTEGER NOT NULL,
+    counting_type INTEGER NOT NULL,
+    quorum INTEGER NOT NULL,
+    vote_threshold INTEGER NOT NULL,
+    PRIMARY KEY (governor_id, ledger_seq)
+);
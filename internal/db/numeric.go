@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// On postgres, i128 amounts are mirrored from their TEXT columns into NUMERIC(39,0) columns, so they can be sorted,
+// summed, and compared in SQL. The mirrors are written with the TEXT values, and were backfilled by
+// 012_numeric_amounts.pgx.sql. sqlite has no exact type for i128s, so doesn't have the mirrors, and amounts are
+// aggregated in Go instead.
+//
+// The mirrors are set from the same arguments as their TEXT columns. Each argument is cast to TEXT before NUMERIC, so
+// postgres infers the argument as TEXT rather than NUMERIC.
+const (
+	// VOTES_NUMERIC_COLUMNS are the NUMERIC mirrors of the votes table
+	VOTES_NUMERIC_COLUMNS = "amount_numeric"
+	// VOTES_NUMERIC_VALUES are the values of VOTES_NUMERIC_COLUMNS, from the arguments of voteArgs
+	VOTES_NUMERIC_VALUES = "$6::TEXT::NUMERIC(39,0)"
+	// PROPOSALS_NUMERIC_COLUMNS are the NUMERIC mirrors of the proposals table
+	PROPOSALS_NUMERIC_COLUMNS = "votes_for_numeric, votes_against_numeric, votes_abstain_numeric"
+	// PROPOSALS_NUMERIC_VALUES are the values of PROPOSALS_NUMERIC_COLUMNS, from the arguments of proposalArgs
+	PROPOSALS_NUMERIC_VALUES = "$11::TEXT::NUMERIC(39,0), $12::TEXT::NUMERIC(39,0), $13::TEXT::NUMERIC(39,0)"
+)
+
+// hasNumericAmounts returns true if amounts are mirrored into NUMERIC columns, which is only the case on postgres
+func (store *Store) hasNumericAmounts() bool {
+	return !store.isSqlite()
+}
+
+// insertColumns returns the columns and values of an insert, including the NUMERIC mirrors if the store has them
+func (store *Store) insertColumns(columns string, values string, numericColumns string, numericValues string) (string, string) {
+	if !store.hasNumericAmounts() {
+		return columns, values
+	}
+	return columns + ", " + numericColumns, values + ", " + numericValues
+}
+
+// getVoteSummaryNumeric aggregates the votes for a proposal in SQL, see GetVoteSummary
+func (store *Store) getVoteSummaryNumeric(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error) {
+	ctx, cancel := store.withReadTimeout(ctx)
+	defer cancel()
+
+	// only the latest vote of each voter is counted, as in governor.NewVoteSummary
+	query := fmt.Sprintf(`
+		SELECT support, COUNT(*), SUM(amount_numeric)::TEXT, MAX(amount_numeric)::TEXT
+		FROM (
+			SELECT DISTINCT ON (voter) support, amount_numeric
+			FROM %s
+			WHERE contract_id = $1 AND proposal_id = $2
+			ORDER BY voter, ledger_seq DESC
+		) latest
+		GROUP BY support
+	`, VOTES_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId, proposalId)
+	if err != nil {
+		return nil, fmt.Errorf("summarize votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	empty := governor.VoteBucket{Total: "0", Max: "0"}
+	summary := &governor.VoteSummary{For: empty, Against: empty, Abstain: empty}
+	for rows.Next() {
+		var support uint32
+		var bucket governor.VoteBucket
+		if err := rows.Scan(&support, &bucket.Voters, &bucket.Total, &bucket.Max); err != nil {
+			return nil, fmt.Errorf("summarize votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+		}
+		addRows(ctx, 1)
+		// support is 0 for against, 1 for for, and 2 for abstain
+		switch support {
+		case 0:
+			summary.Against = bucket
+		case 1:
+			summary.For = bucket
+		case 2:
+			summary.Abstain = bucket
+		default:
+			return nil, fmt.Errorf("summarize votes for proposal %s-%d: invalid support value %d", contractId, proposalId, support)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("summarize votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	return summary, nil
+}
+
+// getVoteSeriesNumeric totals the votes for a proposal by bucket and support in SQL, see GetVoteSeries
+func (store *Store) getVoteSeriesNumeric(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+	ctx, cancel := store.withReadTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		SELECT ledger_close_time - ledger_close_time %% $3 AS bucket_start, support, COUNT(*), SUM(amount_numeric)::TEXT
+		FROM %s
+		WHERE contract_id = $1 AND proposal_id = $2
+		GROUP BY bucket_start, support
+	`, VOTES_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId, proposalId, bucketSize)
+	if err != nil {
+		return nil, fmt.Errorf("vote series for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	buckets, err := scanRows(ctx, rows, func(bucket *governor.VoteSeriesBucket) []any {
+		return []any{&bucket.Start, &bucket.Support, &bucket.Count, &bucket.Amount}
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("vote series for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	series, err := governor.NewVoteSeriesFromBuckets(buckets, bucketSize)
+	if err != nil {
+		return nil, fmt.Errorf("vote series for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	return series, nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// setupPostgresStore creates a store in a new schema of the postgres database at TEST_POSTGRES_URL, and skips the
+// test if it isn't set. The schema is dropped when the test finishes.
+func setupPostgresStore(t testing.TB) *Store {
+	t.Helper()
+
+	baseUrl := os.Getenv("TEST_POSTGRES_URL")
+	if baseUrl == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	admin, err := sql.Open("pgx", baseUrl)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	connUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		t.Fatalf("failed to parse TEST_POSTGRES_URL: %v", err)
+	}
+	query := connUrl.Query()
+	query.Set("search_path", schema)
+	connUrl.RawQuery = query.Encode()
+	db, err := sql.Open("pgx", connUrl.String())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return NewStore(db)
+}
+
+func TestMigrationApplies(t *testing.T) {
+	tests := []struct {
+		filename string
+		driver   string
+		want     bool
+	}{
+		{filename: "000_init.sql", driver: "sqlite", want: true},
+		{filename: "000_init.sql", driver: "pgx", want: true},
+		{filename: "012_numeric_amounts.pgx.sql", driver: "pgx", want: true},
+		{filename: "012_numeric_amounts.pgx.sql", driver: "sqlite", want: false},
+		{filename: "013_example.sqlite.sql", driver: "pgx", want: false},
+	}
+	for _, tt := range tests {
+		if got := migrationApplies(tt.filename, tt.driver); got != tt.want {
+			t.Errorf("migrationApplies(%q, %q) = %v, want %v", tt.filename, tt.driver, got, tt.want)
+		}
+	}
+}
+
+// TestNumericAmounts checks the vote summary and series match on both dialects, and that the postgres NUMERIC
+// mirrors are written with the amounts
+func TestNumericAmounts(t *testing.T) {
+	dialects := []struct {
+		name  string
+		setup func(t testing.TB) *Store
+	}{
+		{name: "sqlite", setup: setupStore},
+		{name: "postgres", setup: setupPostgresStore},
+	}
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	// amounts above 2^63 can't be summed as integers by sqlite
+	large := "170141183460469231731687303715884105727"
+	votes := []*governor.Vote{
+		{TxHash: "tx1", ContractId: contractId, ProposalId: 3, Voter: "GA", Support: 1, Amount: large, LedgerSeq: 100, LedgerCloseTime: 3700},
+		{TxHash: "tx2", ContractId: contractId, ProposalId: 3, Voter: "GB", Support: 1, Amount: large, LedgerSeq: 101, LedgerCloseTime: 3800},
+		{TxHash: "tx3", ContractId: contractId, ProposalId: 3, Voter: "GC", Support: 0, Amount: "50", LedgerSeq: 102, LedgerCloseTime: 10900},
+		// GC changes their vote, so only this vote is summarized for GC
+		{TxHash: "tx4", ContractId: contractId, ProposalId: 3, Voter: "GC", Support: 2, Amount: "70", LedgerSeq: 103, LedgerCloseTime: 11000},
+	}
+	wantSummary, err := governor.NewVoteSummary(votes)
+	if err != nil {
+		t.Fatalf("NewVoteSummary returned error: %v", err)
+	}
+	wantSeries, err := governor.NewVoteSeries(votes, 3600)
+	if err != nil {
+		t.Fatalf("NewVoteSeries returned error: %v", err)
+	}
+
+	for _, dialect := range dialects {
+		t.Run(dialect.name, func(t *testing.T) {
+			store := dialect.setup(t)
+			ctx := t.Context()
+
+			for _, vote := range votes {
+				if err := store.InsertVote(ctx, vote); err != nil {
+					t.Fatalf("failed to insert vote: %v", err)
+				}
+			}
+			summary, err := store.GetVoteSummary(ctx, contractId, 3)
+			if err != nil {
+				t.Fatalf("GetVoteSummary returned error: %v", err)
+			}
+			if diff := cmp.Diff(wantSummary, summary); diff != "" {
+				t.Errorf("summary mismatch (-want +got):\n%s", diff)
+			}
+			series, err := store.GetVoteSeries(ctx, contractId, 3, 3600)
+			if err != nil {
+				t.Fatalf("GetVoteSeries returned error: %v", err)
+			}
+			if diff := cmp.Diff(wantSeries, series); diff != "" {
+				t.Errorf("series mismatch (-want +got):\n%s", diff)
+			}
+			empty, err := store.GetVoteSummary(ctx, contractId, 4)
+			if err != nil {
+				t.Fatalf("GetVoteSummary returned error: %v", err)
+			}
+			wantEmpty, _ := governor.NewVoteSummary(nil)
+			if diff := cmp.Diff(wantEmpty, empty); diff != "" {
+				t.Errorf("empty summary mismatch (-want +got):\n%s", diff)
+			}
+
+			proposal := &governor.Proposal{
+				ProposalKey:  governor.EncodeProposalKey(contractId, 3),
+				ContractId:   contractId,
+				ProposalId:   3,
+				VotesFor:     large,
+				VotesAgainst: "0",
+				VotesAbstain: "0",
+			}
+			if err := store.InsertProposal(ctx, proposal); err != nil {
+				t.Fatalf("failed to insert proposal: %v", err)
+			}
+			proposal.VotesAgainst = "50"
+			if err := store.UpdateProposal(ctx, proposal, 1); err != nil {
+				t.Fatalf("failed to update proposal: %v", err)
+			}
+			proposal.VotesAbstain = "70"
+			if err := store.UpsertProposal(ctx, proposal); err != nil {
+				t.Fatalf("failed to upsert proposal: %v", err)
+			}
+
+			if !store.hasNumericAmounts() {
+				return
+			}
+			var mismatched int
+			err = store.db.QueryRowContext(ctx, `
+				SELECT
+					(SELECT COUNT(*) FROM votes WHERE amount_numeric::TEXT <> amount) +
+					(SELECT COUNT(*) FROM proposals WHERE votes_for_numeric::TEXT <> votes_for
+						OR votes_against_numeric::TEXT <> votes_against OR votes_abstain_numeric::TEXT <> votes_abstain)
+			`).Scan(&mismatched)
+			if err != nil {
+				t.Fatalf("failed to compare numeric amounts: %v", err)
+			}
+			if mismatched != 0 {
+				t.Errorf("%d rows have numeric amounts that don't match their text amounts", mismatched)
+			}
+		})
+	}
+}
+
+// TestEventDataJSONB checks event data is stored as JSONB on postgres, and decodes to the data it was written with
+func TestEventDataJSONB(t *testing.T) {
+	store := setupPostgresStore(t)
+	ctx := t.Context()
+
+	var dataType string
+	err := store.db.QueryRowContext(ctx, `
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'history' AND column_name = 'event_data'
+	`).Scan(&dataType)
+	if err != nil {
+		t.Fatalf("failed to get event_data type: %v", err)
+	}
+	if dataType != "jsonb" {
+		t.Errorf("event_data type = %q, want jsonb", dataType)
+	}
+
+	want := governor.VoteCastData{Voter: "GA", Support: 1, Amount: "170141183460469231731687303715884105727", Reason: "yes"}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("failed to marshal event data: %v", err)
+	}
+	event := &governor.GovernorEvent{
+		EventId:    mustEncodeEventId(t, 1, 0),
+		ContractId: "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+		ProposalId: 3,
+		EventType:  "vote_cast",
+		EventData:  string(data),
+	}
+	if err := store.InsertEvent(ctx, event); err != nil {
+		t.Fatalf("failed to insert event: %v", err)
+	}
+	retrieved, err := store.GetEvent(ctx, event.EventId)
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	// JSONB doesn't keep the layout of the data, so compare it decoded
+	var got governor.VoteCastData
+	if err := json.Unmarshal([]byte(retrieved.EventData), &got); err != nil {
+		t.Fatalf("failed to unmarshal event data %q: %v", retrieved.EventData, err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("event data mismatch (-want +got):\n%s", diff)
+	}
+}
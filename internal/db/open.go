@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// SQLITE_BUSY_TIMEOUT_MS is how long a sqlite connection waits for a lock before returning SQLITE_BUSY
+	SQLITE_BUSY_TIMEOUT_MS = 5000
+	// BUSY_RETRIES is the number of times a write is retried if sqlite still reports SQLITE_BUSY
+	BUSY_RETRIES = 5
+	// BUSY_RETRY_DELAY is the delay before the first busy retry, doubled on each attempt
+	BUSY_RETRY_DELAY = 50 * time.Millisecond
+
+	// PING_INITIAL_DELAY is the delay before the first connection retry, doubled on each attempt
+	PING_INITIAL_DELAY = 500 * time.Millisecond
+	// PING_MAX_DELAY is the maximum delay between connection retries
+	PING_MAX_DELAY = 10 * time.Second
+)
+
+// Config defines how to connect to the database
+type Config struct {
+	// The database driver type, "sqlite" or "pgx". "postgres" is accepted as an alias for "pgx".
+	Type string
+	// The driver specific connection string
+	ConnectionString string
+	// The maximum number of open connections
+	MaxOpenConns int
+	// The maximum number of idle connections
+	MaxIdleConns int
+	// The maximum lifetime of a connection
+	ConnMaxLifetime time.Duration
+	// How long to keep retrying the initial connection before giving up
+	ConnectTimeout time.Duration
+	// The maximum duration of a single read query. If 0, DEFAULT_READ_TIMEOUT is used.
+	ReadTimeout time.Duration
+	// The maximum duration of a single write statement. If 0, DEFAULT_WRITE_TIMEOUT is used.
+	WriteTimeout time.Duration
+	// The maximum number of proposals cached by GetProposal. If 0, the cache is disabled.
+	ProposalCacheSize int
+	// How long a proposal is cached for
+	ProposalCacheTTL time.Duration
+}
+
+// Open connects to the database described by cfg and returns a Store.
+//
+// The database type is validated, and for sqlite, the connection string is amended so every connection in the pool
+// uses WAL mode, waits for locks with a busy timeout, enforces foreign keys, and takes the write lock when a
+// transaction begins. This allows the API and indexer to share a database file without "database is locked" errors.
+//
+// The database is pinged with exponential backoff until it is reachable or cfg.ConnectTimeout elapses, so services
+// can start before the database is ready.
+//
+// Each store operation is bounded by cfg.ReadTimeout or cfg.WriteTimeout, and returns an error wrapping ErrTimeout
+// if it is exceeded.
+//
+// If cfg.ProposalCacheSize is set, proposal reads are cached. For postgres, proposal writes are published with
+// NOTIFY, and a listener holding one pooled connection invalidates proposals written by other processes.
+func Open(ctx context.Context, cfg Config) (*Store, error) {
+	driver, err := normalizeDriver(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	connectionString := cfg.ConnectionString
+	if driver == "sqlite" {
+		connectionString = sqliteDSN(connectionString)
+	}
+
+	database, err := sql.Open(driver, connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	database.SetMaxOpenConns(cfg.MaxOpenConns)
+	database.SetMaxIdleConns(cfg.MaxIdleConns)
+	database.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if driver == "sqlite" && isSqliteMemory(connectionString) {
+		// Each connection to ":memory:" opens a new, empty database, which is deleted when the connection closes.
+		// Limit the pool to a single connection that is never closed, so everything using the store (e.g. the
+		// indexer and API in the combined binary) sees the same database for the life of the process.
+		// A shared cache ("file::memory:?cache=shared") would allow multiple connections, but it uses table
+		// level locking, so API reads fail with SQLITE_LOCKED while the indexer is writing.
+		slog.Info("Using an in-memory sqlite database, limiting the pool to a single connection")
+		database.SetMaxOpenConns(1)
+		database.SetMaxIdleConns(1)
+		database.SetConnMaxLifetime(0)
+		database.SetConnMaxIdleTime(0)
+	}
+
+	err = pingWithBackoff(ctx, database, cfg.ConnectTimeout, PING_INITIAL_DELAY, PING_MAX_DELAY)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	store := NewStore(database)
+	if cfg.ReadTimeout > 0 {
+		store.readTimeout = cfg.ReadTimeout
+	}
+	if cfg.WriteTimeout > 0 {
+		store.writeTimeout = cfg.WriteTimeout
+	}
+	if cfg.ProposalCacheSize > 0 {
+		store.proposals = newProposalCache(cfg.ProposalCacheSize, cfg.ProposalCacheTTL)
+		if driver == "pgx" && cfg.MaxOpenConns == 1 {
+			slog.Warn("Proposal cache listener needs a dedicated connection, so proposals written by other processes are only refreshed after the cache TTL")
+			store.notifyProposals = true
+		} else if driver == "pgx" {
+			// other processes sharing the database may write proposals, so listen for their invalidations
+			store.notifyProposals = true
+			listenerCtx, cancel := context.WithCancel(context.Background())
+			store.stopListener = cancel
+			go store.listenProposalInvalidations(listenerCtx)
+		}
+	}
+	return store, nil
+}
+
+// normalizeDriver validates the database type and returns the registered driver name for it
+func normalizeDriver(dbType string) (string, error) {
+	switch dbType {
+	case "sqlite":
+		return "sqlite", nil
+	case "pgx", "postgres":
+		return "pgx", nil
+	default:
+		return "", fmt.Errorf("unsupported database type %q, expected \"sqlite\" or \"pgx\"", dbType)
+	}
+}
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// pingWithBackoff pings the database until it succeeds or the timeout elapses, doubling the delay between attempts
+// from initialDelay up to maxDelay
+func pingWithBackoff(ctx context.Context, db pinger, timeout time.Duration, initialDelay time.Duration, maxDelay time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("Database not reachable, retrying", "attempt", attempt, "retry_in", delay, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, errors.Join(err, ctx.Err()))
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// sqliteDSN adds the pragmas and transaction lock mode the store expects to a sqlite connection string.
+// Parameters already present in the connection string are not overridden.
+func sqliteDSN(dsn string) string {
+	params := []struct {
+		key   string
+		param string
+	}{
+		{"busy_timeout", "_pragma=busy_timeout(" + strconv.Itoa(SQLITE_BUSY_TIMEOUT_MS) + ")"},
+		{"journal_mode", "_pragma=journal_mode(WAL)"},
+		{"foreign_keys", "_pragma=foreign_keys(1)"},
+		{"_txlock", "_txlock=immediate"},
+	}
+
+	var added []string
+	for _, p := range params {
+		if !strings.Contains(dsn, p.key) {
+			added = append(added, p.param)
+		}
+	}
+	if len(added) == 0 {
+		return dsn
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(added, "&")
+}
+
+// isSqliteMemory returns true if the sqlite connection string refers to an in-memory database
+func isSqliteMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.HasPrefix(dsn, "file::memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// isBusy returns true if the error is a sqlite SQLITE_BUSY or SQLITE_LOCKED error
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// mask out the extended result code
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// retryBusy calls fn, retrying with exponential backoff while it returns a sqlite busy error
+func retryBusy(ctx context.Context, fn func() error) error {
+	delay := BUSY_RETRY_DELAY
+	err := fn()
+	for attempt := 0; attempt < BUSY_RETRIES && isBusy(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+		err = fn()
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestSqliteDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{
+			name: "in memory",
+			dsn:  ":memory:",
+			want: ":memory:?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate",
+		},
+		{
+			name: "file with existing params",
+			dsn:  "file:./gov.db?cache=shared",
+			want: "file:./gov.db?cache=shared&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate",
+		},
+		{
+			name: "does not override configured params",
+			dsn:  "file:./gov.db?_pragma=busy_timeout(100)&_pragma=journal_mode(DELETE)",
+			want: "file:./gov.db?_pragma=busy_timeout(100)&_pragma=journal_mode(DELETE)&_pragma=foreign_keys(1)&_txlock=immediate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sqliteDSN(tt.dsn)
+			if got != tt.want {
+				t.Errorf("\nResult = %v\nWant = %v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSqliteConcurrentReadWrite simulates the API and indexer sharing a sqlite file
+func TestSqliteConcurrentReadWrite(t *testing.T) {
+	ctx := t.Context()
+	dsn := "file:" + filepath.Join(t.TempDir(), "gov.db")
+
+	writer, err := Open(ctx, Config{Type: "sqlite", ConnectionString: dsn, MaxOpenConns: 1, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to open writer database: %v", err)
+	}
+	t.Cleanup(func() { writer.Close() })
+	if err := RunMigrations(writer.DB()); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	var journalMode string
+	if err := writer.DB().QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Fatalf("expected wal journal mode, got %s", journalMode)
+	}
+
+	reader, err := Open(ctx, Config{Type: "sqlite", ConnectionString: dsn, MaxOpenConns: 8, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to open reader database: %v", err)
+	}
+	t.Cleanup(func() { reader.Close() })
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	// a second writer, like an admin operation from the API, competes for the write lock
+	for w := range 2 {
+		store := writer
+		if w == 1 {
+			store = reader
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				eventId, err := governor.EncodeEventId(int64(i), int32(w))
+				if err != nil {
+					t.Errorf("failed to encode event id: %v", err)
+					return
+				}
+				event := &governor.GovernorEvent{
+					EventId:    eventId,
+					ContractId: contractId,
+					EventType:  "proposal_canceled",
+					EventData:  "{}",
+					TxHash:     fmt.Sprintf("tx_%d_%d", w, i),
+					LedgerSeq:  uint32(i),
+				}
+				if err := store.InsertEvent(ctx, event); err != nil {
+					errs <- fmt.Errorf("insert event: %w", err)
+					return
+				}
+				if err := store.UpsertStatus(ctx, "indexer", uint32(i), int64(i)); err != nil {
+					errs <- fmt.Errorf("upsert status: %w", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if _, err := reader.GetEventsByContractId(ctx, contractId, Sort{}); err != nil {
+					errs <- fmt.Errorf("get events: %w", err)
+					return
+				}
+				if _, _, err := reader.GetStatus(ctx, "indexer"); err != nil {
+					errs <- fmt.Errorf("get status: %w", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	events, err := reader.GetEventsByContractId(ctx, contractId, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(events) != 400 {
+		t.Errorf("expected 400 events, got %d", len(events))
+	}
+}
+
+// TestOpenSqliteMemory verifies all users of an in-memory store share the same database
+func TestOpenSqliteMemory(t *testing.T) {
+	ctx := t.Context()
+	store, err := Open(ctx, Config{Type: "sqlite", ConnectionString: ":memory:", MaxOpenConns: 8, MaxIdleConns: 0, ConnMaxLifetime: time.Millisecond, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := RunMigrations(store.DB()); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	if err := store.UpsertStatus(ctx, "indexer", 123, 456); err != nil {
+		t.Fatalf("failed to upsert status: %v", err)
+	}
+	// outlive the configured connection lifetime, which must not apply to in-memory databases
+	time.Sleep(10 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ledgerSeq, _, err := store.GetStatus(ctx, "indexer")
+			if err != nil {
+				errs <- err
+			} else if ledgerSeq != 123 {
+				errs <- fmt.Errorf("expected ledger 123, got %d", ledgerSeq)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestOpenInvalidType(t *testing.T) {
+	_, err := Open(t.Context(), Config{Type: "postgress", ConnectionString: ":memory:"})
+	if err == nil {
+		t.Fatalf("expected error for invalid database type")
+	}
+}
+
+// fakePinger fails the first failures pings
+type fakePinger struct {
+	failures int
+	calls    int
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestPingWithBackoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		timeout   time.Duration
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "succeeds immediately",
+			failures:  0,
+			timeout:   time.Second,
+			wantCalls: 1,
+			wantErr:   false,
+		},
+		{
+			name:      "succeeds after retries",
+			failures:  3,
+			timeout:   time.Second,
+			wantCalls: 4,
+			wantErr:   false,
+		},
+		{
+			// attempts are made at 0, 10, 30, 70, and 110ms before the 130ms timeout
+			name:      "fails after timeout",
+			failures:  100,
+			timeout:   130 * time.Millisecond,
+			wantCalls: 5,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePinger{failures: tt.failures}
+			err := pingWithBackoff(t.Context(), p, tt.timeout, 10*time.Millisecond, 40*time.Millisecond)
+			if err != nil && !tt.wantErr {
+				t.Fatalf("pingWithBackoff() error = %v", err)
+			} else if err == nil && tt.wantErr {
+				t.Fatalf("pingWithBackoff() expected error but got none")
+			}
+			if tt.wantErr && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected deadline exceeded error, got %v", err)
+			}
+			if p.calls != tt.wantCalls {
+				t.Errorf("expected %d ping attempts, got %d", tt.wantCalls, p.calls)
+			}
+		})
+	}
+}
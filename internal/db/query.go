@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+// Query names label the store's query metrics. A query is named after the store method that runs it, and keeps its
+// name when its SQL changes, so dashboards don't break.
+const (
+	// History table
+	QUERY_INSERT_EVENT                 = "insert_event"
+	QUERY_INSERT_EVENTS                = "insert_events"
+	QUERY_GET_EVENT                    = "get_event"
+	QUERY_GET_EVENTS_BY_CONTRACT_ID    = "get_events_by_contract_id"
+	QUERY_FILTER_EVENTS_BY_CONTRACT_ID = "filter_events_by_contract_id"
+	QUERY_GET_RECENT_EVENTS            = "get_recent_events"
+	QUERY_GET_EVENTS_BY_PROPOSAL       = "get_events_by_proposal"
+	QUERY_GET_EVENTS_BY_TX_HASH        = "get_events_by_tx_hash"
+	QUERY_GET_EVENTS_AFTER             = "get_events_after"
+	QUERY_GET_MISSING_EVENT_XDR_RANGE  = "get_missing_event_xdr_range"
+	QUERY_SET_EVENT_XDR                = "set_event_xdr"
+	QUERY_DELETE_EVENTS_BY_CONTRACT_ID = "delete_events_by_contract_id"
+	QUERY_PRUNE_HISTORY                = "prune_history"
+
+	// Failed events table
+	QUERY_INSERT_FAILED_EVENT                 = "insert_failed_event"
+	QUERY_GET_FAILED_EVENTS_BY_CONTRACT_ID    = "get_failed_events_by_contract_id"
+	QUERY_GET_FAILED_EVENTS                   = "get_failed_events"
+	QUERY_COUNT_FAILED_EVENTS                 = "count_failed_events"
+	QUERY_MARK_FAILED_EVENT_PROCESSED         = "mark_failed_event_processed"
+	QUERY_RECORD_FAILED_EVENT_ATTEMPT         = "record_failed_event_attempt"
+	QUERY_DELETE_FAILED_EVENTS_BY_CONTRACT_ID = "delete_failed_events_by_contract_id"
+
+	// Failed transaction events table
+	QUERY_INSERT_FAILED_TX_EVENT                 = "insert_failed_tx_event"
+	QUERY_GET_FAILED_TX_EVENTS                   = "get_failed_tx_events"
+	QUERY_COUNT_FAILED_TX_EVENTS                 = "count_failed_tx_events"
+	QUERY_DELETE_FAILED_TX_EVENTS_BY_CONTRACT_ID = "delete_failed_tx_events_by_contract_id"
+
+	// Status table
+	QUERY_UPSERT_STATUS        = "upsert_status"
+	QUERY_GET_STATUS           = "get_status"
+	QUERY_UPSERT_SOURCE_STATUS = "upsert_source_status"
+	QUERY_GET_SOURCE_STATUS    = "get_source_status"
+
+	// Coverage gaps table
+	QUERY_INSERT_COVERAGE_GAP = "insert_coverage_gap"
+	QUERY_GET_COVERAGE_GAPS   = "get_coverage_gaps"
+	QUERY_COUNT_COVERAGE_GAPS = "count_coverage_gaps"
+
+	// Indexer errors table
+	QUERY_INSERT_INDEXER_ERROR = "insert_indexer_error"
+	QUERY_GET_INDEXER_ERRORS   = "get_indexer_errors"
+
+	// Ingestion progress table
+	QUERY_UPSERT_INGESTION_PROGRESS = "upsert_ingestion_progress"
+	QUERY_GET_INGESTION_PROGRESS    = "get_ingestion_progress"
+
+	// Proposals table
+	QUERY_UPSERT_PROPOSAL                 = "upsert_proposal"
+	QUERY_GET_PROPOSAL                    = "get_proposal"
+	QUERY_INSERT_PROPOSAL                 = "insert_proposal"
+	QUERY_UPDATE_PROPOSAL                 = "update_proposal"
+	QUERY_GET_PROPOSAL_VERSION            = "get_proposal_version"
+	QUERY_GET_PROPOSALS_BY_CONTRACT_ID    = "get_proposals_by_contract_id"
+	QUERY_EACH_PROPOSAL_BY_CONTRACT_ID    = "each_proposal_by_contract_id"
+	QUERY_GET_PROPOSALS_BY_STATUS         = "get_proposals_by_status"
+	QUERY_COUNT_PROPOSALS_BY_STATUS       = "count_proposals_by_status"
+	QUERY_GET_PROPOSALS_ENDING_BETWEEN    = "get_proposals_ending_between"
+	QUERY_GET_EXECUTABLE_PROPOSALS        = "get_executable_proposals"
+	QUERY_GET_PROPOSALS_BY_KEYS           = "get_proposals_by_keys"
+	QUERY_DELETE_PROPOSALS_BY_CONTRACT_ID = "delete_proposals_by_contract_id"
+
+	// Votes table
+	QUERY_INSERT_VOTE                    = "insert_vote"
+	QUERY_INSERT_VOTES                   = "insert_votes"
+	QUERY_GET_VOTES_BY_TX_HASHES         = "get_votes_by_tx_hashes"
+	QUERY_GET_VOTE                       = "get_vote"
+	QUERY_GET_VOTE_BY_PROPOSAL_AND_VOTER = "get_vote_by_proposal_and_voter"
+	QUERY_GET_LATEST_VOTES_BY_VOTER      = "get_latest_votes_by_voter"
+	QUERY_GET_VOTES_BY_PROPOSAL          = "get_votes_by_proposal"
+	QUERY_EACH_VOTE_BY_PROPOSAL          = "each_vote_by_proposal"
+	QUERY_GET_VOTE_SUMMARY               = "get_vote_summary"
+	QUERY_COUNT_VOTERS                   = "count_voters"
+	QUERY_GET_VOTE_SERIES                = "get_vote_series"
+	QUERY_DELETE_VOTES_BY_CONTRACT_ID    = "delete_votes_by_contract_id"
+	QUERY_DELETE_VOTES_AFTER_LEDGER      = "delete_votes_after_ledger"
+
+	// Delegations table
+	QUERY_INSERT_DELEGATION                 = "insert_delegation"
+	QUERY_GET_DELEGATORS                    = "get_delegators"
+	QUERY_GET_DELEGATION_HISTORY            = "get_delegation_history"
+	QUERY_DELETE_DELEGATIONS_BY_CONTRACT_ID = "delete_delegations_by_contract_id"
+	QUERY_DELETE_DELEGATIONS_AFTER_LEDGER   = "delete_delegations_after_ledger"
+
+	// Votes contracts table
+	QUERY_UPSERT_VOTES_CONTRACT  = "upsert_votes_contract"
+	QUERY_IS_VOTES_CONTRACT      = "is_votes_contract"
+	QUERY_GET_TRACKED_CONTRACTS  = "get_tracked_contracts"
+	QUERY_DELETE_VOTES_CONTRACTS = "delete_votes_contracts"
+
+	// Governor settings table
+	QUERY_UPSERT_GOVERNOR_SETTINGS = "upsert_governor_settings"
+	QUERY_GET_GOVERNOR_SETTINGS    = "get_governor_settings"
+	QUERY_DELETE_GOVERNOR_SETTINGS = "delete_governor_settings"
+
+	// Proposal content table
+	QUERY_UPSERT_PROPOSAL_CONTENT                = "upsert_proposal_content"
+	QUERY_GET_PROPOSAL_CONTENT                   = "get_proposal_content"
+	QUERY_GET_PROPOSAL_CONTENT_TO_FETCH          = "get_proposal_content_to_fetch"
+	QUERY_DELETE_PROPOSAL_CONTENT_BY_CONTRACT_ID = "delete_proposal_content_by_contract_id"
+
+	// Snapshots table
+	QUERY_INSERT_SNAPSHOT                 = "insert_snapshot"
+	QUERY_GET_LATEST_SNAPSHOT             = "get_latest_snapshot"
+	QUERY_GET_SNAPSHOT_LEDGER             = "get_snapshot_ledger"
+	QUERY_GET_LAST_EVENT_IDS              = "get_last_event_ids"
+	QUERY_PRUNE_SNAPSHOTS                 = "prune_snapshots"
+	QUERY_DELETE_SNAPSHOTS_BY_CONTRACT_ID = "delete_snapshots_by_contract_id"
+
+	// Contracts table
+	QUERY_UPSERT_CONTRACT_ACTIVITY    = "upsert_contract_activity"
+	QUERY_GET_CONTRACT_LAST_EVENT_ID  = "get_contract_last_event_id"
+	QUERY_ADD_CONTRACT_EVENT_COUNTS   = "add_contract_event_counts"
+	QUERY_GET_CONTRACTS               = "get_contracts"
+	QUERY_GET_CONTRACT                = "get_contract"
+	QUERY_SET_CONTRACT_REVIEWED       = "set_contract_reviewed"
+	QUERY_GET_UNREVIEWED_CONTRACT_IDS = "get_unreviewed_contract_ids"
+	QUERY_DELETE_CONTRACT             = "delete_contract"
+	QUERY_GET_VOTE_TOKENS_TO_FETCH    = "get_vote_tokens_to_fetch"
+	QUERY_SET_VOTE_TOKEN              = "set_vote_token"
+	QUERY_GET_VOTE_TOKENS             = "get_vote_tokens"
+
+	// Contract metadata table
+	QUERY_UPSERT_CONTRACT_METADATA = "upsert_contract_metadata"
+	QUERY_DELETE_CONTRACT_METADATA = "delete_contract_metadata"
+
+	// Contract blocklist table
+	QUERY_BLOCK_CONTRACT        = "block_contract"
+	QUERY_UNBLOCK_CONTRACT      = "unblock_contract"
+	QUERY_GET_BLOCKED_CONTRACTS = "get_blocked_contracts"
+	QUERY_IS_CONTRACT_BLOCKED   = "is_contract_blocked"
+)
+
+type queryStatsKey struct{}
+
+// queryStats are the rows returned or affected by the queries of a store method, recorded with its duration when
+// the method returns
+type queryStats struct {
+	name  string
+	start time.Time
+	rows  int64
+}
+
+// observeQuery starts a named query, and returns a context counting the rows of the query, and a func to call when
+// the query is done, which records its duration and rows. A store method called with the context records its own
+// query, so its rows aren't counted twice.
+func observeQuery(ctx context.Context, name string) (context.Context, func()) {
+	stats := &queryStats{name: name, start: time.Now()}
+	return context.WithValue(ctx, queryStatsKey{}, stats), func() {
+		metrics.QueryDuration.WithLabelValues(stats.name).Observe(time.Since(stats.start).Seconds())
+		metrics.QueryRows.WithLabelValues(stats.name).Add(float64(stats.rows))
+	}
+}
+
+// addRows counts rows returned or affected by the query of ctx, if any
+func addRows(ctx context.Context, rows int64) {
+	if stats, ok := ctx.Value(queryStatsKey{}).(*queryStats); ok {
+		stats.rows += rows
+	}
+}
+
+// readQuery starts a named read query. It returns a context that expires after the store's read timeout, and a func
+// to call when the query is done, which cancels the context and records the query's metrics.
+func (store *Store) readQuery(ctx context.Context, name string) (context.Context, func()) {
+	ctx, record := observeQuery(ctx, name)
+	ctx, cancel := store.withReadTimeout(ctx)
+	return ctx, func() {
+		cancel()
+		record()
+	}
+}
+
+// writeQuery starts a named write query, as readQuery, with the store's write timeout
+func (store *Store) writeQuery(ctx context.Context, name string) (context.Context, func()) {
+	ctx, record := observeQuery(ctx, name)
+	ctx, cancel := store.withWriteTimeout(ctx)
+	return ctx, func() {
+		cancel()
+		record()
+	}
+}
+
+// countedRow is the result of a single-row query, counted as a row returned once it is scanned
+type countedRow struct {
+	ctx context.Context
+	row *sql.Row
+}
+
+func (r countedRow) Scan(dest ...any) error {
+	if err := r.row.Scan(dest...); err != nil {
+		return err
+	}
+	addRows(r.ctx, 1)
+	return nil
+}
+
+// queryRow runs a query expected to return at most one row
+func (store *Store) queryRow(ctx context.Context, query string, args ...any) countedRow {
+	return countedRow{ctx: ctx, row: store.conn(ctx).QueryRowContext(ctx, query, args...)}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidSort is returned when a list query is sorted by a field it can't be sorted by
+var ErrInvalidSort = errors.New("invalid sort")
+
+var (
+	// PROPOSAL_SORT_FIELDS are the fields proposals for a contract can be sorted by
+	PROPOSAL_SORT_FIELDS = []string{"proposal_id", "vote_end", "status"}
+	// VOTE_SORT_FIELDS are the fields votes for a proposal can be sorted by
+	VOTE_SORT_FIELDS = []string{"ledger_seq", "amount"}
+	// EVENT_SORT_FIELDS are the fields events for a contract can be sorted by
+	EVENT_SORT_FIELDS = []string{"event_id"}
+)
+
+// Sort is the order of a list query. The zero Sort is the query's default order.
+type Sort struct {
+	// Field is one of the sortable fields of the query, e.g. one of PROPOSAL_SORT_FIELDS
+	Field string
+	Desc  bool
+}
+
+// orderBy returns the ORDER BY clause for sort, or def if sort is the zero Sort. columns are the SQL expressions of each
+// sortable field, and are the only text from the sort used in the clause, so a field can't inject SQL. Rows with the
+// same field are ordered by tiebreak, if set, so the order is stable.
+func orderBy(sort Sort, def string, columns map[string][]string, tiebreak string) (string, error) {
+	if sort.Field == "" {
+		return "ORDER BY " + def, nil
+	}
+	expressions, ok := columns[sort.Field]
+	if !ok {
+		return "", fmt.Errorf("sort by %q: %w", sort.Field, ErrInvalidSort)
+	}
+	if tiebreak != "" && !slices.Contains(expressions, tiebreak) {
+		expressions = append(slices.Clone(expressions), tiebreak)
+	}
+	direction := " ASC"
+	if sort.Desc {
+		direction = " DESC"
+	}
+	return "ORDER BY " + strings.Join(expressions, direction+", ") + direction, nil
+}
+
+// proposalOrderBy returns the ORDER BY clause of proposals for a contract, by proposal_id DESC by default
+func (store *Store) proposalOrderBy(sort Sort) (string, error) {
+	return orderBy(sort, "proposal_id DESC", map[string][]string{
+		"proposal_id": {"proposal_id"},
+		"vote_end":    {"vote_end"},
+		"status":      {"status"},
+	}, "proposal_id")
+}
+
+// voteOrderBy returns the ORDER BY clause of votes for a proposal, by ledger_seq DESC by default. Amounts are text, so
+// on sqlite they are sorted by length, then as text, which is numeric order for non-negative integers.
+func (store *Store) voteOrderBy(sort Sort) (string, error) {
+	amount := []string{"amount_numeric"}
+	if !store.hasNumericAmounts() {
+		amount = []string{"LENGTH(amount)", "amount"}
+	}
+	return orderBy(sort, "ledger_seq DESC", map[string][]string{
+		"ledger_seq": {"ledger_seq"},
+		"amount":     amount,
+	}, "tx_hash")
+}
+
+// eventOrderBy returns the ORDER BY clause of events for a contract, by event_id ASC by default
+func (store *Store) eventOrderBy(sort Sort) (string, error) {
+	return orderBy(sort, "event_id ASC", map[string][]string{
+		"event_id": {"event_id"},
+	}, "")
+}
@@ -3,24 +3,369 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+const (
+	// DEFAULT_READ_TIMEOUT is the default maximum duration of a single read query
+	DEFAULT_READ_TIMEOUT = 5 * time.Second
+	// DEFAULT_WRITE_TIMEOUT is the default maximum duration of a single write statement, including busy retries
+	DEFAULT_WRITE_TIMEOUT = 10 * time.Second
+	// SQLITE_MAX_QUERY_PARAMS is the maximum number of parameters bound to a single sqlite statement, its default limit
+	SQLITE_MAX_QUERY_PARAMS = 999
+	// POSTGRES_MAX_QUERY_PARAMS is the maximum number of parameters bound to a single postgres statement
+	POSTGRES_MAX_QUERY_PARAMS = 65535
+)
+
+var (
+	// ErrNotFound is returned when a requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a row was modified by another writer since it was read
+	ErrConflict = errors.New("conflicting update")
+	// ErrInvalidCursor is returned when a pagination cursor can't be decoded
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrTimeout is returned when a store operation exceeds its deadline. It wraps context.DeadlineExceeded.
+	ErrTimeout = fmt.Errorf("database operation timed out: %w", context.DeadlineExceeded)
+	// ErrNoTransaction is returned when a savepoint is started with a context that isn't bound to a transaction
+	ErrNoTransaction = errors.New("no transaction")
 )
 
 type Store struct {
-	db *sql.DB
+	db           *sql.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	// proposals caches GetProposal reads, or is nil if the cache is disabled
+	proposals *proposalCache
+	// notifyProposals is true if proposal writes are published to other processes with postgres NOTIFY
+	notifyProposals bool
+	// stopListener stops the proposal cache listener, if running
+	stopListener context.CancelFunc
+	// savepoints numbers the savepoints started, so each has a unique name
+	savepoints atomic.Uint64
 }
 
 func NewStore(db *sql.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, readTimeout: DEFAULT_READ_TIMEOUT, writeTimeout: DEFAULT_WRITE_TIMEOUT}
+}
+
+// DB returns the underlying database
+func (store *Store) DB() *sql.DB {
+	return store.db
+}
+
+// Close closes the underlying database
+func (store *Store) Close() error {
+	if store.stopListener != nil {
+		store.stopListener()
+	}
+	return store.db.Close()
+}
+
+// querier is the subset of methods shared by *sql.DB and *sql.Tx used by the store
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction bound to ctx by WithTx, or the database if there is none
+func (store *Store) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return store.db
+}
+
+// exec executes a write statement, counting the rows it affects as rows of the query of ctx. Outside of a
+// transaction, writes that fail because a sqlite database is locked by another connection are retried.
+func (store *Store) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	var err error
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		result, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		err = retryBusy(ctx, func() error {
+			var err error
+			result, err = store.db.ExecContext(ctx, query, args...)
+			return err
+		})
+	}
+	if err != nil {
+		return result, err
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		addRows(ctx, affected)
+	}
+	return result, nil
+}
+
+// withReadTimeout returns a context that expires after the store's read timeout. A timeout of 0 disables it.
+func (store *Store) withReadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if store.readTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, store.readTimeout)
+}
+
+// withWriteTimeout returns a context that expires after the store's write timeout. A timeout of 0 disables it.
+func (store *Store) withWriteTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if store.writeTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, store.writeTimeout)
+}
+
+// timeoutErr wraps err with ErrTimeout if ctx's deadline has been exceeded. Drivers don't consistently
+// return context.DeadlineExceeded when a query is interrupted, so the context is checked directly.
+func timeoutErr(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, ErrTimeout) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// SCAN_CHUNK_SIZE is the number of rows allocated at once when scanning list queries
+const SCAN_CHUNK_SIZE = 256
+
+// rowScanner scans rows into values of T. The scan destinations are built once and reused for every row, and rows
+// are copied into chunks of SCAN_CHUNK_SIZE values, so a large result is allocated per chunk rather than per row.
+type rowScanner[T any] struct {
+	row   T
+	dest  []any
+	chunk []T
+}
+
+// newRowScanner creates a rowScanner. fields returns the scan destinations for the selected columns of a row.
+func newRowScanner[T any](fields func(row *T) []any) *rowScanner[T] {
+	s := &rowScanner[T]{}
+	s.dest = fields(&s.row)
+	return s
+}
+
+// scan scans the current row, and returns a pointer to a copy of it
+func (s *rowScanner[T]) scan(rows *sql.Rows) (*T, error) {
+	if err := rows.Scan(s.dest...); err != nil {
+		return nil, err
+	}
+	if len(s.chunk) == cap(s.chunk) {
+		s.chunk = make([]T, 0, SCAN_CHUNK_SIZE)
+	}
+	s.chunk = append(s.chunk, s.row)
+	return &s.chunk[len(s.chunk)-1], nil
+}
+
+// scanRows scans every row, counting them as rows returned by the query of ctx. The result is preallocated for
+// sizeHint rows, and is nil if there are no rows.
+func scanRows[T any](ctx context.Context, rows *sql.Rows, fields func(row *T) []any, sizeHint int) ([]*T, error) {
+	var result []*T
+	if sizeHint > 0 {
+		result = make([]*T, 0, sizeHint)
+	}
+	scanner := newRowScanner(fields)
+	for rows.Next() {
+		row, err := scanner.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	addRows(ctx, int64(len(result)))
+	if len(result) == 0 {
+		return nil, rows.Err()
+	}
+	return result, rows.Err()
+}
+
+// placeholders returns the comma-separated placeholders of count parameters, numbered from offset+1
+func placeholders(offset int, count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", offset+i+1)
+	}
+	return strings.Join(params, ", ")
+}
+
+// maxQueryParams returns the maximum number of parameters bound to a single statement by the database
+func (store *Store) maxQueryParams() int {
+	if store.isSqlite() {
+		return SQLITE_MAX_QUERY_PARAMS
+	}
+	return POSTGRES_MAX_QUERY_PARAMS
+}
+
+// insertRows inserts rows with multi-row statements, each binding at most maxQueryParams parameters. insert is the
+// statement, with a %s verb for the rows' values. values returns the values of a row, with rowParams parameters
+// numbered from offset+1, and args returns its arguments.
+func insertRows[T any](ctx context.Context, store *Store, insert string, rows []T, rowParams int, values func(offset int) string, args func(row T) []any) error {
+	for chunk := range slices.Chunk(rows, store.maxQueryParams()/rowParams) {
+		chunkValues := make([]string, len(chunk))
+		chunkArgs := make([]any, 0, len(chunk)*rowParams)
+		for i, row := range chunk {
+			chunkValues[i] = "(" + values(len(chunkArgs)) + ")"
+			chunkArgs = append(chunkArgs, args(row)...)
+		}
+		if _, err := store.exec(ctx, fmt.Sprintf(insert, strings.Join(chunkValues, ", ")), chunkArgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countRows returns the result of a COUNT query, used as a size hint for scanRows. The count isn't counted as a row
+// returned by the query.
+func (store *Store) countRows(ctx context.Context, query string, args ...any) (int, error) {
+	var count int
+	err := store.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// WithTx runs fn inside a database transaction. Store methods called with the context passed to fn
+// are executed within the transaction. The transaction is committed if fn returns nil, and rolled back otherwise.
+//
+// If ctx is already bound to a transaction, fn joins the existing transaction.
+func (store *Store) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	var tx *sql.Tx
+	err := retryBusy(ctx, func() error {
+		var err error
+		tx, err = store.db.BeginTx(ctx, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	// proposals written in the transaction, which are invalidated again once it commits
+	var invalidations []string
+	// functions run by AfterTx once the transaction ends
+	var after []func(ctx context.Context)
+	defer func() {
+		for _, fn := range after {
+			fn(ctx)
+		}
+	}()
+	txCtx := context.WithValue(context.WithValue(ctx, txKey{}, tx), txInvalidationsKey{}, &invalidations)
+	txCtx = context.WithValue(txCtx, txAfterKey{}, &after)
+	if err := fn(txCtx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	for _, key := range invalidations {
+		store.proposals.remove(key)
+	}
+	return nil
+}
+
+type txAfterKey struct{}
+
+// AfterTx runs fn once the transaction bound to ctx by WithTx ends, whether it commits or rolls back, with a context
+// bound to no transaction. Writes that must outlive a failed transaction, such as the indexer's error log, are made
+// this way: they aren't rolled back with it, and aren't rejected by a postgres transaction aborted by the failure. If
+// ctx isn't bound to a transaction, fn runs immediately.
+func (store *Store) AfterTx(ctx context.Context, fn func(ctx context.Context)) {
+	if after, ok := ctx.Value(txAfterKey{}).(*[]func(ctx context.Context)); ok {
+		*after = append(*after, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// Savepoint is a point in a transaction it can be rolled back to, undoing the statements executed since without
+// ending the transaction. A statement that fails on postgres aborts the transaction until it is rolled back to a
+// savepoint, so savepoints let a transaction continue past a failed statement on both databases.
+//
+// A Savepoint must be ended with either Release or Rollback.
+type Savepoint struct {
+	tx   *sql.Tx
+	name string
+}
+
+// BeginSavepoint starts a savepoint in the transaction bound to ctx by WithTx. It returns ErrNoTransaction if ctx
+// isn't bound to a transaction.
+func (store *Store) BeginSavepoint(ctx context.Context) (*Savepoint, error) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	if !ok {
+		return nil, ErrNoTransaction
+	}
+	ctx, cancel := store.withWriteTimeout(ctx)
+	defer cancel()
+
+	savepoint := &Savepoint{tx: tx, name: fmt.Sprintf("sp_%d", store.savepoints.Add(1))}
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint.name); err != nil {
+		return nil, timeoutErr(ctx, fmt.Errorf("begin savepoint: %w", err))
+	}
+	return savepoint, nil
+}
+
+// Release ends the savepoint, keeping the statements executed since it was started in the transaction
+func (savepoint *Savepoint) Release(ctx context.Context) error {
+	if _, err := savepoint.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint.name); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// Rollback undoes the statements executed since the savepoint was started, and ends it. The transaction continues.
+func (savepoint *Savepoint) Rollback(ctx context.Context) error {
+	if _, err := savepoint.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint.name); err != nil {
+		return fmt.Errorf("rollback to savepoint: %w", err)
+	}
+	return savepoint.Release(ctx)
+}
+
+// WithSavepoint runs fn inside a savepoint of the transaction bound to ctx. The savepoint is released if fn returns
+// nil. Otherwise, the statements executed by fn are rolled back and fn's error is returned, and the transaction can
+// continue.
+//
+// If ctx isn't bound to a transaction, fn runs in its own transaction with WithTx.
+func (store *Store) WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); !ok {
+		return store.WithTx(ctx, fn)
+	}
+
+	savepoint, err := store.BeginSavepoint(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(ctx); err != nil {
+		if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return savepoint.Release(ctx)
 }
 
 //********** History Table **********//
 
 const (
 	HISTORY_TABLE_NAME = "history"
-	HISTORY_COLUMNS    = "event_id, contract_id, proposal_id, event_type, event_data, tx_hash, ledger_seq, ledger_close_time"
+	HISTORY_COLUMNS    = "event_id, contract_id, proposal_id, event_type, event_data, tx_hash, ledger_seq, ledger_close_time, schema_version, event_xdr, source_account"
+	// HISTORY_SELECT_COLUMNS are HISTORY_COLUMNS as read. event_xdr is NULL for events indexed before it was stored,
+	// which is read as an empty string.
+	HISTORY_SELECT_COLUMNS = "event_id, contract_id, proposal_id, event_type, event_data, tx_hash, ledger_seq, ledger_close_time, schema_version, COALESCE(event_xdr, ''), source_account"
 )
 
 func historyArgs(event *governor.GovernorEvent) []any {
@@ -33,12 +378,15 @@ func historyArgs(event *governor.GovernorEvent) []any {
 		event.TxHash,
 		event.LedgerSeq,
 		event.LedgerCloseTime,
+		event.SchemaVersion,
+		sql.NullString{String: event.EventXdr, Valid: event.EventXdr != ""},
+		event.SourceAccount,
 	}
 }
 
-func scanHistoryEvent(scanner interface{ Scan(...any) error }) (*governor.GovernorEvent, error) {
-	event := &governor.GovernorEvent{}
-	err := scanner.Scan(
+// historyEventFields returns the scan destinations for HISTORY_SELECT_COLUMNS
+func historyEventFields(event *governor.GovernorEvent) []any {
+	return []any{
 		&event.EventId,
 		&event.ContractId,
 		&event.ProposalId,
@@ -47,334 +395,3204 @@ func scanHistoryEvent(scanner interface{ Scan(...any) error }) (*governor.Govern
 		&event.TxHash,
 		&event.LedgerSeq,
 		&event.LedgerCloseTime,
-	)
+		&event.SchemaVersion,
+		&event.EventXdr,
+		&event.SourceAccount,
+	}
+}
+
+func scanHistoryEvent(scanner interface{ Scan(...any) error }) (*governor.GovernorEvent, error) {
+	event := &governor.GovernorEvent{}
+	err := scanner.Scan(historyEventFields(event)...)
 	return event, err
 }
 
-// InsertEvent inserts a new governor event into the history table
+// InsertEvent inserts a new governor event into the history table. Events whose data doesn't decode into the data of
+// their event type are rejected with an error wrapping governor.ErrInvalidEventData.
 func (store *Store) InsertEvent(ctx context.Context, event *governor.GovernorEvent) error {
+	if err := governor.ValidateEventData(event.EventType, event.EventData); err != nil {
+		return fmt.Errorf("insert event %s: %w", event.EventId, err)
+	}
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_EVENT)
+	defer done()
+
 	query := fmt.Sprintf(`
         INSERT INTO %s (%s) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
         ON CONFLICT (event_id) DO NOTHING`,
 		HISTORY_TABLE_NAME, HISTORY_COLUMNS,
 	)
 
-	_, err := store.db.ExecContext(
+	_, err := store.exec(
 		ctx,
 		query,
 		historyArgs(event)...,
 	)
 
-	return err
+	if err != nil {
+		return fmt.Errorf("insert event %s: %w", event.EventId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// InsertEvents inserts governor events into the history table with multi-row statements. Events that already exist
+// are skipped, as with InsertEvent. If any event has invalid data, none of the events are inserted.
+func (store *Store) InsertEvents(ctx context.Context, events []*governor.GovernorEvent) error {
+	for _, event := range events {
+		if err := governor.ValidateEventData(event.EventType, event.EventData); err != nil {
+			return fmt.Errorf("insert event %s: %w", event.EventId, err)
+		}
+	}
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_EVENTS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES %%s
+		ON CONFLICT (event_id) DO NOTHING
+	`, HISTORY_TABLE_NAME, HISTORY_COLUMNS)
+
+	rowParams := len(historyArgs(&governor.GovernorEvent{}))
+	values := func(offset int) string { return placeholders(offset, rowParams) }
+	if err := insertRows(ctx, store, query, events, rowParams, values, historyArgs); err != nil {
+		return fmt.Errorf("insert %d events: %w", len(events), timeoutErr(ctx, err))
+	}
+	return nil
 }
 
-// GetEventById retrieves a single event by its ID
+// GetEvent retrieves a single event by its ID, or ErrNotFound if it does not exist
 func (store *Store) GetEvent(ctx context.Context, eventId string) (*governor.GovernorEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_EVENT)
+	defer done()
+
 	query := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
 		WHERE event_id = $1
-	`, HISTORY_COLUMNS, HISTORY_TABLE_NAME)
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME)
 
-	event, err := scanHistoryEvent(store.db.QueryRowContext(ctx, query, eventId))
-	if err == sql.ErrNoRows {
-		return nil, nil
+	event, err := scanHistoryEvent(store.queryRow(ctx, query, eventId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get event %s: %w", eventId, ErrNotFound)
 	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get event %s: %w", eventId, timeoutErr(ctx, err))
 	}
 
 	return event, nil
 }
 
-// GetEventsByContractId retrieves events with pagination
+// GetEventsByContractId retrieves events with pagination, ordered by sort, or by event_id ASC by default. Events can be
+// sorted by EVENT_SORT_FIELDS.
 // TODO: add pagination
 func (store *Store) GetEventsByContractId(
 	ctx context.Context,
 	contractId string,
+	sort Sort,
+) ([]*governor.GovernorEvent, error) {
+	return store.filterEvents(ctx, QUERY_GET_EVENTS_BY_CONTRACT_ID, contractId, nil, sort)
+}
+
+// FilterEventsByContractId retrieves the events of a contract matching filter, ordered by sort, or by event_id ASC by
+// default. Events can be filtered by EVENT_FILTER_FIELDS, and sorted by EVENT_SORT_FIELDS.
+func (store *Store) FilterEventsByContractId(
+	ctx context.Context,
+	contractId string,
+	filter Filter,
+	sort Sort,
 ) ([]*governor.GovernorEvent, error) {
+	return store.filterEvents(ctx, QUERY_FILTER_EVENTS_BY_CONTRACT_ID, contractId, filter, sort)
+}
+
+// filterEvents runs the query of FilterEventsByContractId, named name
+func (store *Store) filterEvents(ctx context.Context, name string, contractId string, filter Filter, sort Sort) ([]*governor.GovernorEvent, error) {
+	order, err := store.eventOrderBy(sort)
+	if err != nil {
+		return nil, fmt.Errorf("get events for contract %s: %w", contractId, err)
+	}
+	where, filterArgs, err := store.eventFilterWhere(filter, 1)
+	if err != nil {
+		return nil, fmt.Errorf("get events for contract %s: %w", contractId, err)
+	}
+	args := append([]any{contractId}, filterArgs...)
+
+	ctx, done := store.readQuery(ctx, name)
+	defer done()
+
+	count, err := store.countRows(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE contract_id = $1%s", HISTORY_TABLE_NAME, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("count events for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+
 	query := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE contract_id = $1
-		ORDER BY event_id ASC
-	`, HISTORY_COLUMNS, HISTORY_TABLE_NAME)
+		WHERE contract_id = $1%s
+		%s
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME, where, order)
 
-	rows, err := store.db.QueryContext(ctx, query, contractId)
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get events for contract %s: %w", contractId, timeoutErr(ctx, err))
 	}
 	defer rows.Close()
 
-	var events []*governor.GovernorEvent
-	for rows.Next() {
-		event, err := scanHistoryEvent(rows)
-		if err != nil {
-			return nil, err
-		}
-		events = append(events, event)
+	events, err := scanRows(ctx, rows, historyEventFields, count)
+	if err != nil {
+		return nil, fmt.Errorf("get events for contract %s: %w", contractId, timeoutErr(ctx, err))
 	}
+	return events, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+// GetRecentEvents retrieves the newest limit events across all contracts, newest first. If eventType is not empty,
+// only events of that type are returned.
+func (store *Store) GetRecentEvents(ctx context.Context, eventType string, limit int) ([]*governor.GovernorEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_RECENT_EVENTS)
+	defer done()
+
+	filter := ""
+	args := []any{limit}
+	if eventType != "" {
+		filter = "WHERE event_type = $2"
+		args = append(args, eventType)
+	}
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY event_id DESC
+		LIMIT $1
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME, filter)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get recent events: %w", timeoutErr(ctx, err))
 	}
+	defer rows.Close()
 
+	events, err := scanRows(ctx, rows, historyEventFields, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get recent events: %w", timeoutErr(ctx, err))
+	}
 	return events, nil
 }
 
-//********** Status Table Methods **********//
-
-// UpsertStatus updates the last processed ledger data in the status table
-func (store *Store) UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error {
-	query := `
-		INSERT INTO status (source, ledger_seq, ledger_close_time)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (source) DO UPDATE SET ledger_seq = EXCLUDED.ledger_seq, ledger_close_time = EXCLUDED.ledger_close_time
-	`
-	_, err := store.db.ExecContext(ctx, query, source, ledgerSeq, ledgerCloseTime)
-	return err
-}
+// GetEventsByProposal retrieves all events of a proposal, in the order they were applied
+func (store *Store) GetEventsByProposal(ctx context.Context, contractId string, proposalId uint32) ([]*governor.GovernorEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_EVENTS_BY_PROPOSAL)
+	defer done()
 
-// GetStatus returns the last processed ledger data for the given source
-func (store *Store) GetStatus(ctx context.Context, source string) (uint32, int64, error) {
-	query := `SELECT ledger_seq, ledger_close_time FROM status WHERE source = $1`
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND proposal_id = $2
+		ORDER BY event_id ASC
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME)
 
-	var ledgerSeq uint32
-	var ledgerCloseTime int64
-	err := store.db.QueryRowContext(ctx, query, source).Scan(&ledgerSeq, &ledgerCloseTime)
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId, proposalId)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, 0, nil
-		}
-		return 0, 0, err
+		return nil, fmt.Errorf("get events for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
 	}
+	defer rows.Close()
 
-	return ledgerSeq, ledgerCloseTime, nil
+	events, err := scanRows(ctx, rows, historyEventFields, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get events for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	return events, nil
 }
 
-//********** Proposals Table **********//
+// GetEventsByTxHash retrieves all events emitted in a transaction, across contracts, in the order they were applied
+func (store *Store) GetEventsByTxHash(ctx context.Context, txHash string) ([]*governor.GovernorEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_EVENTS_BY_TX_HASH)
+	defer done()
 
-const (
-	PROPOSALS_TABLE_NAME = "proposals"
-	PROPOSALS_COLUMNS    = "proposal_key, contract_id, proposal_id, proposer, status, title, description, action, vote_start, vote_end, votes_for, votes_against, votes_abstain, execution_unlock, execution_tx_hash"
-)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE tx_hash = $1
+		ORDER BY event_id ASC
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME)
 
-func proposalArgs(proposal *governor.Proposal) []any {
-	return []any{
-		proposal.ProposalKey,
-		proposal.ContractId,
-		proposal.ProposalId,
-		proposal.Proposer,
-		proposal.Status,
-		proposal.Title,
-		proposal.Description,
-		proposal.Action,
-		proposal.VoteStart,
-		proposal.VoteEnd,
-		proposal.VotesFor,
-		proposal.VotesAgainst,
-		proposal.VotesAbstain,
-		proposal.ExecutionUnlock,
-		proposal.ExecutionTxHash,
+	rows, err := store.conn(ctx).QueryContext(ctx, query, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("get events for transaction %s: %w", txHash, timeoutErr(ctx, err))
 	}
-}
+	defer rows.Close()
 
-func scanProposal(scanner interface{ Scan(...any) error }) (*governor.Proposal, error) {
-	proposal := &governor.Proposal{}
-	err := scanner.Scan(
-		&proposal.ProposalKey,
-		&proposal.ContractId,
-		&proposal.ProposalId,
-		&proposal.Proposer,
-		&proposal.Status,
-		&proposal.Title,
-		&proposal.Description,
-		&proposal.Action,
-		&proposal.VoteStart,
-		&proposal.VoteEnd,
-		&proposal.VotesFor,
-		&proposal.VotesAgainst,
-		&proposal.VotesAbstain,
-		&proposal.ExecutionUnlock,
-		&proposal.ExecutionTxHash,
-	)
-	return proposal, err
+	events, err := scanRows(ctx, rows, historyEventFields, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get events for transaction %s: %w", txHash, timeoutErr(ctx, err))
+	}
+	return events, nil
 }
 
-// UpsertProposal inserts or updates a proposal in the proposals table
-// For updates, it ignores fixed fields, and only updates mutable fields (votes_*, execution_*, status)
-func (store *Store) UpsertProposal(ctx context.Context, proposal *governor.Proposal) error {
-	// @dev note: doesn't update proposal_key, contract_id, proposal_id on conflict
-	// to prevent changing primary identifiers
+// GetEventsAfter retrieves up to limit events with an event ID after afterEventId, in event order, so the history
+// table can be read in pages without holding a query open. If contractId is empty, events of every contract are
+// returned. Pass the event ID of the last event returned to get the next page.
+func (store *Store) GetEventsAfter(ctx context.Context, contractId string, afterEventId string, limit int) ([]*governor.GovernorEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_EVENTS_AFTER)
+	defer done()
+
+	filter := ""
+	args := []any{afterEventId, limit}
+	if contractId != "" {
+		filter = "AND contract_id = $3"
+		args = append(args, contractId)
+	}
 	query := fmt.Sprintf(`
-		INSERT INTO %s (%s) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		ON CONFLICT (proposal_key) 
-		DO UPDATE SET 
-			status = EXCLUDED.status,
-			votes_for = EXCLUDED.votes_for,
-			votes_against = EXCLUDED.votes_against,
-			votes_abstain = EXCLUDED.votes_abstain,
-			execution_unlock = EXCLUDED.execution_unlock,
-			execution_tx_hash = EXCLUDED.execution_tx_hash
-		`, PROPOSALS_TABLE_NAME, PROPOSALS_COLUMNS)
+		SELECT %s
+		FROM %s
+		WHERE event_id > $1 %s
+		ORDER BY event_id ASC
+		LIMIT $2
+	`, HISTORY_SELECT_COLUMNS, HISTORY_TABLE_NAME, filter)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get events after %s: %w", afterEventId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	events, err := scanRows(ctx, rows, historyEventFields, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get events after %s: %w", afterEventId, timeoutErr(ctx, err))
+	}
+	return events, nil
+}
+
+// GetMissingEventXdrRange returns the first and last ledger with events indexed before their event XDR was stored,
+// or 0 and 0 if every event has it
+func (store *Store) GetMissingEventXdrRange(ctx context.Context) (uint32, uint32, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_MISSING_EVENT_XDR_RANGE)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(MIN(ledger_seq), 0), COALESCE(MAX(ledger_seq), 0)
+		FROM %s
+		WHERE event_xdr IS NULL
+	`, HISTORY_TABLE_NAME)
+
+	var from, to uint32
+	if err := store.queryRow(ctx, query).Scan(&from, &to); err != nil {
+		return 0, 0, fmt.Errorf("get missing event xdr range: %w", timeoutErr(ctx, err))
+	}
+	return from, to, nil
+}
+
+// SetEventXdr backfills the event XDR of an event indexed before it was stored. Events that already have their
+// event XDR are not changed. Returns true if the event was updated.
+func (store *Store) SetEventXdr(ctx context.Context, eventId string, eventXdr string) (bool, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_SET_EVENT_XDR)
+	defer done()
+
+	query := fmt.Sprintf(`UPDATE %s SET event_xdr = $2 WHERE event_id = $1 AND event_xdr IS NULL`, HISTORY_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, eventId, eventXdr)
+	if err != nil {
+		return false, fmt.Errorf("set event xdr %s: %w", eventId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("set event xdr %s: %w", eventId, err)
+	}
+	return updated > 0, nil
+}
+
+// DeleteEventsByContractId deletes all events for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteEventsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_EVENTS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, HISTORY_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete events for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// PruneHistory deletes up to batchSize events emitted before the given ledger sequence, and returns the number of rows deleted
+func (store *Store) PruneHistory(ctx context.Context, beforeLedger uint32, batchSize int) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_PRUNE_HISTORY)
+	defer done()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE event_id IN (
+			SELECT event_id
+			FROM %s
+			WHERE ledger_seq < $1
+			ORDER BY event_id ASC
+			LIMIT $2
+		)
+	`, HISTORY_TABLE_NAME, HISTORY_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, beforeLedger, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("prune history before ledger %d: %w", beforeLedger, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Failed Events Table **********//
+
+const (
+	FAILED_EVENTS_TABLE_NAME = "failed_events"
+	FAILED_EVENTS_COLUMNS    = "event_id, contract_id, event_type, reason, error, event_xdr, tx_hash, ledger_seq, ledger_close_time"
+	// FAILED_EVENTS_SELECT_COLUMNS are FAILED_EVENTS_COLUMNS with the reprocessing state, which is not inserted
+	FAILED_EVENTS_SELECT_COLUMNS = FAILED_EVENTS_COLUMNS + ", attempts, COALESCE(processed_at, 0)"
+)
+
+// InsertFailedEvent records an event the indexer could not index. Recording an already failed event is a no-op.
+func (store *Store) InsertFailedEvent(ctx context.Context, event *governor.FailedEvent) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_FAILED_EVENT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id) DO NOTHING
+	`, FAILED_EVENTS_TABLE_NAME, FAILED_EVENTS_COLUMNS)
 
-	_, err := store.db.ExecContext(
+	_, err := store.exec(
 		ctx,
 		query,
-		proposalArgs(proposal)...,
+		event.EventId,
+		event.ContractId,
+		event.EventType,
+		event.Reason,
+		event.Error,
+		event.EventXdr,
+		event.TxHash,
+		event.LedgerSeq,
+		event.LedgerCloseTime,
 	)
+	if err != nil {
+		return fmt.Errorf("insert failed event %s: %w", event.EventId, timeoutErr(ctx, err))
+	}
+	return nil
+}
 
-	return err
+// failedEventFields returns the scan destinations for FAILED_EVENTS_SELECT_COLUMNS
+func failedEventFields(event *governor.FailedEvent) []any {
+	return []any{
+		&event.EventId,
+		&event.ContractId,
+		&event.EventType,
+		&event.Reason,
+		&event.Error,
+		&event.EventXdr,
+		&event.TxHash,
+		&event.LedgerSeq,
+		&event.LedgerCloseTime,
+		&event.Attempts,
+		&event.ProcessedAt,
+	}
 }
 
-// GetProposal retrieves a proposal by its unique proposal key
-func (store *Store) GetProposal(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+// GetFailedEventsByContractId retrieves the failed events for a contract, oldest first
+func (store *Store) GetFailedEventsByContractId(ctx context.Context, contractId string) ([]*governor.FailedEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_FAILED_EVENTS_BY_CONTRACT_ID)
+	defer done()
+
 	query := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE proposal_key = $1
-	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+		WHERE contract_id = $1
+		ORDER BY event_id ASC
+	`, FAILED_EVENTS_SELECT_COLUMNS, FAILED_EVENTS_TABLE_NAME)
 
-	proposal, err := scanProposal(store.db.QueryRowContext(ctx, query, proposalKey))
-	if err == sql.ErrNoRows {
-		return nil, nil
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId)
+	if err != nil {
+		return nil, fmt.Errorf("get failed events for contract %s: %w", contractId, timeoutErr(ctx, err))
 	}
+	defer rows.Close()
+
+	events, err := scanRows(ctx, rows, failedEventFields, 0)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get failed events for contract %s: %w", contractId, timeoutErr(ctx, err))
 	}
+	return events, nil
+}
 
-	return proposal, nil
+// FailedEventFilter selects failed events. Empty fields match every failed event.
+type FailedEventFilter struct {
+	ContractId string
+	Reason     string
+	// ErrorContains matches failed events whose error contains the substring, case sensitive
+	ErrorContains string
+	// Unprocessed only matches failed events that have not been reprocessed
+	Unprocessed bool
 }
 
-// GetProposalsByContract retrieves all proposals for a given contract ID
-// TODO: add pagination
-func (store *Store) GetProposalsByContractId(ctx context.Context, contractId string) ([]*governor.Proposal, error) {
+// failedEventsWhere returns the conditions of filter, with parameters numbered after args, and the arguments with the
+// filter's appended
+func (store *Store) failedEventsWhere(filter FailedEventFilter, args []any) (string, []any) {
+	conditions := []string{"TRUE"}
+	if filter.ContractId != "" {
+		args = append(args, filter.ContractId)
+		conditions = append(conditions, fmt.Sprintf("contract_id = $%d", len(args)))
+	}
+	if filter.Reason != "" {
+		args = append(args, filter.Reason)
+		conditions = append(conditions, fmt.Sprintf("reason = $%d", len(args)))
+	}
+	if filter.ErrorContains != "" {
+		// match the substring literally, rather than with LIKE, so % and _ in it are not wildcards
+		args = append(args, filter.ErrorContains)
+		if store.isSqlite() {
+			conditions = append(conditions, fmt.Sprintf("instr(error, $%d) > 0", len(args)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("strpos(error, $%d) > 0", len(args)))
+		}
+	}
+	if filter.Unprocessed {
+		conditions = append(conditions, "processed_at IS NULL")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetFailedEvents retrieves up to limit failed events matching filter, oldest first. If afterEventId is not empty,
+// only failed events after it are returned, so the event id of the last failed event of a page is the cursor of the
+// next page.
+func (store *Store) GetFailedEvents(ctx context.Context, filter FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_FAILED_EVENTS)
+	defer done()
+
+	where, args := store.failedEventsWhere(filter, []any{afterEventId, limit})
 	query := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE contract_id = $1
-		ORDER BY proposal_id DESC
-	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+		WHERE event_id > $1 AND %s
+		ORDER BY event_id ASC
+		LIMIT $2
+	`, FAILED_EVENTS_SELECT_COLUMNS, FAILED_EVENTS_TABLE_NAME, where)
 
-	rows, err := store.db.QueryContext(ctx, query, contractId)
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get failed events: %w", timeoutErr(ctx, err))
 	}
 	defer rows.Close()
 
-	var proposals []*governor.Proposal
-	for rows.Next() {
-		proposal, err := scanProposal(rows)
-		if err != nil {
-			return nil, err
-		}
-		proposals = append(proposals, proposal)
+	events, err := scanRows(ctx, rows, failedEventFields, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get failed events: %w", timeoutErr(ctx, err))
 	}
+	return events, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+// CountFailedEvents returns the number of failed events matching filter
+func (store *Store) CountFailedEvents(ctx context.Context, filter FailedEventFilter) (int, error) {
+	ctx, done := store.readQuery(ctx, QUERY_COUNT_FAILED_EVENTS)
+	defer done()
+
+	where, args := store.failedEventsWhere(filter, nil)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, FAILED_EVENTS_TABLE_NAME, where)
+
+	var count int
+	if err := store.queryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count failed events: %w", timeoutErr(ctx, err))
 	}
+	return count, nil
+}
 
-	return proposals, nil
+// MarkFailedEventProcessed records that a failed event was reprocessed and applied at processedAt. Failed events that
+// don't exist are rejected with ErrNotFound.
+func (store *Store) MarkFailedEventProcessed(ctx context.Context, eventId string, processedAt int64) error {
+	ctx, done := store.writeQuery(ctx, QUERY_MARK_FAILED_EVENT_PROCESSED)
+	defer done()
+
+	query := fmt.Sprintf(`UPDATE %s SET processed_at = $2 WHERE event_id = $1`, FAILED_EVENTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, eventId, processedAt)
+	if err != nil {
+		return fmt.Errorf("mark failed event %s processed: %w", eventId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("mark failed event %s processed: %w", eventId, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("mark failed event %s processed: %w", eventId, ErrNotFound)
+	}
+	return nil
 }
 
-//********** Votes Table **********//
+// RecordFailedEventAttempt records a failed attempt at reprocessing a failed event, incrementing its attempts and
+// replacing its error with the error of the attempt. Failed events that don't exist are rejected with ErrNotFound.
+func (store *Store) RecordFailedEventAttempt(ctx context.Context, eventId string, attemptErr string) error {
+	ctx, done := store.writeQuery(ctx, QUERY_RECORD_FAILED_EVENT_ATTEMPT)
+	defer done()
 
-const (
-	VOTES_TABLE_NAME = "votes"
-	VOTES_COLUMNS    = "tx_hash, contract_id, proposal_id, voter, support, amount, ledger_seq, ledger_close_time"
-)
+	query := fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, error = $2 WHERE event_id = $1`, FAILED_EVENTS_TABLE_NAME)
 
-func voteArgs(vote *governor.Vote) []any {
-	return []any{
-		vote.TxHash,
-		vote.ContractId,
-		vote.ProposalId,
-		vote.Voter,
-		vote.Support,
-		vote.Amount,
-		vote.LedgerSeq,
-		vote.LedgerCloseTime,
+	result, err := store.exec(ctx, query, eventId, attemptErr)
+	if err != nil {
+		return fmt.Errorf("record failed event %s attempt: %w", eventId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("record failed event %s attempt: %w", eventId, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("record failed event %s attempt: %w", eventId, ErrNotFound)
 	}
+	return nil
 }
 
-func scanVote(scanner interface{ Scan(...any) error }) (*governor.Vote, error) {
-	vote := &governor.Vote{}
-	err := scanner.Scan(
-		&vote.TxHash,
-		&vote.ContractId,
-		&vote.ProposalId,
-		&vote.Voter,
-		&vote.Support,
-		&vote.Amount,
-		&vote.LedgerSeq,
-		&vote.LedgerCloseTime,
-	)
-	return vote, err
+// DeleteFailedEventsByContractId deletes all failed events for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteFailedEventsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_FAILED_EVENTS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, FAILED_EVENTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete failed events for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
 }
 
-func (store *Store) InsertVote(ctx context.Context, vote *governor.Vote) error {
+//********** Failed Transaction Events Table **********//
+
+const (
+	FAILED_TX_EVENTS_TABLE_NAME = "failed_tx_events"
+	FAILED_TX_EVENTS_COLUMNS    = "event_id, contract_id, proposal_id, event_type, event_data, tx_hash, source_account, result_code, ledger_seq, ledger_close_time, event_xdr"
+)
+
+// InsertFailedTxEvent records a governor event emitted by a failed transaction. Recording an already recorded event
+// is a no-op.
+func (store *Store) InsertFailedTxEvent(ctx context.Context, event *governor.FailedTxEvent) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_FAILED_TX_EVENT)
+	defer done()
+
 	query := fmt.Sprintf(`
-		INSERT INTO %s (%s) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (tx_hash) DO NOTHING
-		`, VOTES_TABLE_NAME, VOTES_COLUMNS)
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (event_id) DO NOTHING
+	`, FAILED_TX_EVENTS_TABLE_NAME, FAILED_TX_EVENTS_COLUMNS)
 
-	_, err := store.db.ExecContext(
+	_, err := store.exec(
 		ctx,
 		query,
-		voteArgs(vote)...,
+		event.EventId,
+		event.ContractId,
+		event.ProposalId,
+		event.EventType,
+		event.EventData,
+		event.TxHash,
+		event.SourceAccount,
+		event.ResultCode,
+		event.LedgerSeq,
+		event.LedgerCloseTime,
+		event.EventXdr,
 	)
+	if err != nil {
+		return fmt.Errorf("insert failed tx event %s: %w", event.EventId, timeoutErr(ctx, err))
+	}
+	return nil
+}
 
-	return err
+// failedTxEventFields returns the scan destinations for FAILED_TX_EVENTS_COLUMNS
+func failedTxEventFields(event *governor.FailedTxEvent) []any {
+	return []any{
+		&event.EventId,
+		&event.ContractId,
+		&event.ProposalId,
+		&event.EventType,
+		&event.EventData,
+		&event.TxHash,
+		&event.SourceAccount,
+		&event.ResultCode,
+		&event.LedgerSeq,
+		&event.LedgerCloseTime,
+		&event.EventXdr,
+	}
 }
 
-func (store *Store) GetVote(ctx context.Context, txHash string) (*governor.Vote, error) {
+// failedTxEventsWhere returns the condition matching the events of contractId, or every event if it is empty, with
+// its parameter numbered after args, and the arguments with contractId appended. Events of blocked contracts never
+// match, and events of registered contracts that were not reviewed only match if includeUnreviewed is true.
+func failedTxEventsWhere(contractId string, includeUnreviewed bool, args []any) (string, []any) {
+	where := UNBLOCKED_CONTRACT_FILTER
+	if !includeUnreviewed {
+		where += " AND " + REVIEWED_CONTRACT_FILTER
+	}
+	if contractId == "" {
+		return where, args
+	}
+	args = append(args, contractId)
+	return fmt.Sprintf("contract_id = $%d AND %s", len(args), where), args
+}
+
+// GetFailedTxEvents retrieves up to limit events emitted by failed transactions, oldest first, for contractId or for
+// every contract if it is empty, except blocked contracts, and registered contracts that were not reviewed unless
+// includeUnreviewed is true. If afterEventId is not empty, only events after it are returned, so the event id of the
+// last event of a page is the cursor of the next page.
+func (store *Store) GetFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool, afterEventId string, limit int) ([]*governor.FailedTxEvent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_FAILED_TX_EVENTS)
+	defer done()
+
+	where, args := failedTxEventsWhere(contractId, includeUnreviewed, []any{afterEventId, limit})
 	query := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE tx_hash = $1
-	`, VOTES_COLUMNS, VOTES_TABLE_NAME)
+		WHERE event_id > $1 AND %s
+		ORDER BY event_id ASC
+		LIMIT $2
+	`, FAILED_TX_EVENTS_COLUMNS, FAILED_TX_EVENTS_TABLE_NAME, where)
 
-	vote, err := scanVote(store.db.QueryRowContext(ctx, query, txHash))
-	if err == sql.ErrNoRows {
-		return nil, nil
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get failed tx events: %w", timeoutErr(ctx, err))
 	}
+	defer rows.Close()
+
+	events, err := scanRows(ctx, rows, failedTxEventFields, limit)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get failed tx events: %w", timeoutErr(ctx, err))
 	}
+	return events, nil
+}
 
-	return vote, nil
+// CountFailedTxEvents returns the number of events emitted by failed transactions for contractId, or for every
+// contract if it is empty, except blocked contracts, and registered contracts that were not reviewed unless
+// includeUnreviewed is true
+func (store *Store) CountFailedTxEvents(ctx context.Context, contractId string, includeUnreviewed bool) (int, error) {
+	ctx, done := store.readQuery(ctx, QUERY_COUNT_FAILED_TX_EVENTS)
+	defer done()
+
+	where, args := failedTxEventsWhere(contractId, includeUnreviewed, nil)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, FAILED_TX_EVENTS_TABLE_NAME, where)
+
+	var count int
+	if err := store.queryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count failed tx events: %w", timeoutErr(ctx, err))
+	}
+	return count, nil
 }
 
-func (store *Store) GetVotesByProposal(ctx context.Context, contractId string, proposalId uint32) ([]*governor.Vote, error) {
-	query := fmt.Sprintf(`
-		SELECT %s
-		FROM %s
-		WHERE contract_id = $1 AND proposal_id = $2
-		ORDER BY ledger_seq DESC
-	`, VOTES_COLUMNS, VOTES_TABLE_NAME)
+// DeleteFailedTxEventsByContractId deletes all events emitted by failed transactions for a given contract ID, and
+// returns the number of rows deleted
+func (store *Store) DeleteFailedTxEventsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_FAILED_TX_EVENTS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, FAILED_TX_EVENTS_TABLE_NAME)
 
-	rows, err := store.db.QueryContext(ctx, query, contractId, proposalId)
+	result, err := store.exec(ctx, query, contractId)
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("delete failed tx events for contract %s: %w", contractId, timeoutErr(ctx, err))
 	}
-	defer rows.Close()
+	return result.RowsAffected()
+}
 
-	var votes []*governor.Vote
-	for rows.Next() {
-		vote, err := scanVote(rows)
-		if err != nil {
-			return nil, err
-		}
-		votes = append(votes, vote)
+//********** Status Table Methods **********//
+
+// UpsertStatus updates the last processed ledger data in the status table
+func (store *Store) UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_STATUS)
+	defer done()
+
+	query := `
+		INSERT INTO status (source, ledger_seq, ledger_close_time)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source) DO UPDATE SET ledger_seq = EXCLUDED.ledger_seq, ledger_close_time = EXCLUDED.ledger_close_time
+	`
+	_, err := store.exec(ctx, query, source, ledgerSeq, ledgerCloseTime)
+	if err != nil {
+		return fmt.Errorf("upsert status %s: %w", source, timeoutErr(ctx, err))
 	}
+	return nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+// GetStatus returns the last processed ledger data for the given source
+func (store *Store) GetStatus(ctx context.Context, source string) (uint32, int64, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_STATUS)
+	defer done()
+
+	query := `SELECT ledger_seq, ledger_close_time FROM status WHERE source = $1`
+
+	var ledgerSeq uint32
+	var ledgerCloseTime int64
+	err := store.queryRow(ctx, query, source).Scan(&ledgerSeq, &ledgerCloseTime)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("get status %s: %w", source, timeoutErr(ctx, err))
 	}
 
-	return votes, nil
+	return ledgerSeq, ledgerCloseTime, nil
+}
+
+// SourceStatus is the status of a source that reports its progress, such as captive core
+type SourceStatus struct {
+	LedgerSeq       uint32
+	LedgerCloseTime int64
+	State           string
+	// UpdatedAt is the unix time the status was written
+	UpdatedAt int64
+}
+
+// UpsertSourceStatus updates the status of a source, including its state
+func (store *Store) UpsertSourceStatus(ctx context.Context, source string, status SourceStatus) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_SOURCE_STATUS)
+	defer done()
+
+	query := `
+		INSERT INTO status (source, ledger_seq, ledger_close_time, state, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (source) DO UPDATE SET ledger_seq = EXCLUDED.ledger_seq, ledger_close_time = EXCLUDED.ledger_close_time,
+			state = EXCLUDED.state, updated_at = EXCLUDED.updated_at
+	`
+	_, err := store.exec(ctx, query, source, status.LedgerSeq, status.LedgerCloseTime, status.State, status.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("upsert source status %s: %w", source, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetSourceStatus returns the status of a source, or ErrNotFound if the source has not reported a status
+func (store *Store) GetSourceStatus(ctx context.Context, source string) (*SourceStatus, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_SOURCE_STATUS)
+	defer done()
+
+	query := `SELECT ledger_seq, ledger_close_time, COALESCE(state, ''), COALESCE(updated_at, 0) FROM status WHERE source = $1`
+
+	var status SourceStatus
+	err := store.queryRow(ctx, query, source).Scan(&status.LedgerSeq, &status.LedgerCloseTime, &status.State, &status.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("get source status %s: %w", source, ErrNotFound)
+		}
+		return nil, fmt.Errorf("get source status %s: %w", source, timeoutErr(ctx, err))
+	}
+	return &status, nil
+}
+
+//********** Coverage Gaps Table **********//
+
+const COVERAGE_GAPS_TABLE_NAME = "coverage_gaps"
+
+// CoverageGap is a range of ledgers, inclusive, the indexer knowingly did not index
+type CoverageGap struct {
+	FromLedger uint32
+	ToLedger   uint32
+	// Why the ledgers were not indexed
+	Reason string
+	// The time (in seconds since epoch) the gap was recorded
+	CreatedAt int64
+}
+
+// InsertCoverageGap records a range of ledgers that was not indexed. Recording a range already recorded is a no-op,
+// so the first reason is kept.
+func (store *Store) InsertCoverageGap(ctx context.Context, gap *CoverageGap) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_COVERAGE_GAP)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (from_ledger, to_ledger, reason, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (from_ledger, to_ledger) DO NOTHING
+	`, COVERAGE_GAPS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, gap.FromLedger, gap.ToLedger, gap.Reason, gap.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert coverage gap %d-%d: %w", gap.FromLedger, gap.ToLedger, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetCoverageGaps returns every recorded coverage gap, ordered by from_ledger ascending
+func (store *Store) GetCoverageGaps(ctx context.Context) ([]*CoverageGap, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_COVERAGE_GAPS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT from_ledger, to_ledger, reason, created_at
+		FROM %s
+		ORDER BY from_ledger ASC, to_ledger ASC
+	`, COVERAGE_GAPS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get coverage gaps: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	gaps, err := scanRows(ctx, rows, func(gap *CoverageGap) []any {
+		return []any{&gap.FromLedger, &gap.ToLedger, &gap.Reason, &gap.CreatedAt}
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get coverage gaps: %w", timeoutErr(ctx, err))
+	}
+	return gaps, nil
+}
+
+// CountCoverageGaps returns the number of recorded coverage gaps
+func (store *Store) CountCoverageGaps(ctx context.Context) (int, error) {
+	ctx, done := store.readQuery(ctx, QUERY_COUNT_COVERAGE_GAPS)
+	defer done()
+
+	var count int
+	if err := store.queryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", COVERAGE_GAPS_TABLE_NAME)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count coverage gaps: %w", timeoutErr(ctx, err))
+	}
+	return count, nil
+}
+
+//********** Indexer Errors Table **********//
+
+const INDEXER_ERRORS_TABLE_NAME = "indexer_errors"
+
+// IndexerError is an error the indexer hit while processing a ledger or event
+type IndexerError struct {
+	// Seq orders errors by when they were recorded. It is set by InsertIndexerError.
+	Seq       int64
+	LedgerSeq uint32
+	// TxHash is empty for errors of a whole ledger
+	TxHash  string
+	Message string
+	// The time (in seconds since epoch) the error was recorded
+	CreatedAt int64
+}
+
+// InsertIndexerError records an error of the indexer, then deletes the oldest errors so only the latest keep are
+// kept. An error recorded at the same time as another writer's is dropped rather than failing, so recording errors
+// never fails the transaction bound to ctx because of a conflict.
+func (store *Store) InsertIndexerError(ctx context.Context, indexerError *IndexerError, keep int) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_INDEXER_ERROR)
+	defer done()
+
+	// WHERE true keeps sqlite from parsing ON CONFLICT as a join constraint of the SELECT
+	query := fmt.Sprintf(`
+		INSERT INTO %s (seq, ledger_seq, tx_hash, message, created_at)
+		SELECT COALESCE(MAX(seq), 0) + 1, $1, $2, $3, $4 FROM %s WHERE true
+		ON CONFLICT (seq) DO NOTHING
+	`, INDEXER_ERRORS_TABLE_NAME, INDEXER_ERRORS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, indexerError.LedgerSeq, indexerError.TxHash, indexerError.Message, indexerError.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert indexer error for ledger %d: %w", indexerError.LedgerSeq, timeoutErr(ctx, err))
+	}
+
+	query = fmt.Sprintf(`
+		DELETE FROM %s WHERE seq <= (SELECT MAX(seq) FROM %s) - $1
+	`, INDEXER_ERRORS_TABLE_NAME, INDEXER_ERRORS_TABLE_NAME)
+
+	_, err = store.exec(ctx, query, keep)
+	if err != nil {
+		return fmt.Errorf("prune indexer errors: %w", timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetIndexerErrors returns the latest limit errors of the indexer, newest first
+func (store *Store) GetIndexerErrors(ctx context.Context, limit int) ([]*IndexerError, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_INDEXER_ERRORS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT seq, ledger_seq, tx_hash, message, created_at
+		FROM %s
+		ORDER BY seq DESC
+		LIMIT $1
+	`, INDEXER_ERRORS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get indexer errors: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	indexerErrors, err := scanRows(ctx, rows, func(indexerError *IndexerError) []any {
+		return []any{&indexerError.Seq, &indexerError.LedgerSeq, &indexerError.TxHash, &indexerError.Message, &indexerError.CreatedAt}
+	}, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get indexer errors: %w", timeoutErr(ctx, err))
+	}
+	return indexerErrors, nil
+}
+
+//********** Ingestion Progress Table **********//
+
+const INGESTION_PROGRESS_TABLE_NAME = "ingestion_progress"
+
+// IngestionProgress is the progress of a source ingesting a range of ledgers, such as the indexer catching up
+type IngestionProgress struct {
+	Source string
+	// StartLedger is the first ledger ingested, and Ledger the last one
+	StartLedger uint32
+	Ledger      uint32
+	// TargetLedger is the last ledger of the range, or the latest ledger of the network, and 0 if it is unknown
+	TargetLedger uint32
+	// Ledgers and Events are the number of ledgers and governor events processed since StartLedger
+	Ledgers int64
+	Events  int64
+	// LedgersPerSecond and EventsPerSecond are the recent rates of ingestion
+	LedgersPerSecond float64
+	EventsPerSecond  float64
+	// The times (in seconds since epoch) the ingestion started and the progress was recorded
+	StartedAt int64
+	UpdatedAt int64
+}
+
+// PercentComplete returns the percentage of the ledgers up to TargetLedger that were ingested, and false if
+// TargetLedger is unknown
+func (p *IngestionProgress) PercentComplete() (float64, bool) {
+	if p.TargetLedger == 0 {
+		return 0, false
+	}
+	if p.Ledger >= p.TargetLedger || p.TargetLedger < p.StartLedger {
+		return 100, true
+	}
+	if p.Ledger < p.StartLedger {
+		return 0, true
+	}
+	return 100 * float64(p.Ledger-p.StartLedger+1) / float64(p.TargetLedger-p.StartLedger+1), true
+}
+
+// ETA returns the time left to ingest the ledgers up to TargetLedger at the recent rate, and false if TargetLedger
+// is unknown or nothing was ingested recently
+func (p *IngestionProgress) ETA() (time.Duration, bool) {
+	if p.TargetLedger == 0 {
+		return 0, false
+	}
+	if p.Ledger >= p.TargetLedger {
+		return 0, true
+	}
+	if p.LedgersPerSecond <= 0 {
+		return 0, false
+	}
+	seconds := float64(p.TargetLedger-p.Ledger) / p.LedgersPerSecond
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// UpsertIngestionProgress records the progress of a source, replacing its previous progress
+func (store *Store) UpsertIngestionProgress(ctx context.Context, progress *IngestionProgress) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_INGESTION_PROGRESS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (source, start_ledger, ledger, target_ledger, ledgers, events, ledgers_per_second,
+			events_per_second, started_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (source) DO UPDATE SET start_ledger = EXCLUDED.start_ledger, ledger = EXCLUDED.ledger,
+			target_ledger = EXCLUDED.target_ledger, ledgers = EXCLUDED.ledgers, events = EXCLUDED.events,
+			ledgers_per_second = EXCLUDED.ledgers_per_second, events_per_second = EXCLUDED.events_per_second,
+			started_at = EXCLUDED.started_at, updated_at = EXCLUDED.updated_at
+	`, INGESTION_PROGRESS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, progress.Source, progress.StartLedger, progress.Ledger, progress.TargetLedger,
+		progress.Ledgers, progress.Events, progress.LedgersPerSecond, progress.EventsPerSecond, progress.StartedAt,
+		progress.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("upsert ingestion progress %s: %w", progress.Source, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetIngestionProgress returns the progress of a source, or ErrNotFound if the source has not recorded its progress
+func (store *Store) GetIngestionProgress(ctx context.Context, source string) (*IngestionProgress, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_INGESTION_PROGRESS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT source, start_ledger, ledger, target_ledger, ledgers, events, ledgers_per_second, events_per_second,
+			started_at, updated_at
+		FROM %s
+		WHERE source = $1
+	`, INGESTION_PROGRESS_TABLE_NAME)
+
+	var p IngestionProgress
+	err := store.queryRow(ctx, query, source).Scan(&p.Source, &p.StartLedger, &p.Ledger, &p.TargetLedger,
+		&p.Ledgers, &p.Events, &p.LedgersPerSecond, &p.EventsPerSecond, &p.StartedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get ingestion progress %s: %w", source, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ingestion progress %s: %w", source, timeoutErr(ctx, err))
+	}
+	return &p, nil
+}
+
+//********** Proposals Table **********//
+
+const (
+	PROPOSALS_TABLE_NAME = "proposals"
+	PROPOSALS_COLUMNS    = "proposal_key, contract_id, proposal_id, proposer, status, title, description, action, vote_start, vote_end, votes_for, votes_against, votes_abstain, execution_unlock, execution_tx_hash, truncated, created_ledger, updated_ledger, updated_event_id, updated_at, flagged_low_participation, action_type, action_contract_id, action_function, source_account, created_tx_hash"
+)
+
+func proposalArgs(proposal *governor.Proposal) []any {
+	return []any{
+		proposal.ProposalKey,
+		proposal.ContractId,
+		proposal.ProposalId,
+		proposal.Proposer,
+		proposal.Status,
+		proposal.Title,
+		proposal.Description,
+		proposal.Action,
+		proposal.VoteStart,
+		proposal.VoteEnd,
+		proposal.VotesFor,
+		proposal.VotesAgainst,
+		proposal.VotesAbstain,
+		proposal.ExecutionUnlock,
+		proposal.ExecutionTxHash,
+		proposal.Truncated,
+		proposal.CreatedLedger,
+		proposal.UpdatedLedger,
+		proposal.UpdatedEventId,
+		proposal.UpdatedAt,
+		proposal.FlaggedLowParticipation,
+		proposal.ActionType,
+		proposal.ActionContractId,
+		proposal.ActionFunction,
+		proposal.SourceAccount,
+		proposal.CreatedTxHash,
+	}
+}
+
+// proposalFields returns the scan destinations for PROPOSALS_COLUMNS
+func proposalFields(proposal *governor.Proposal) []any {
+	return []any{
+		&proposal.ProposalKey,
+		&proposal.ContractId,
+		&proposal.ProposalId,
+		&proposal.Proposer,
+		&proposal.Status,
+		&proposal.Title,
+		&proposal.Description,
+		&proposal.Action,
+		&proposal.VoteStart,
+		&proposal.VoteEnd,
+		&proposal.VotesFor,
+		&proposal.VotesAgainst,
+		&proposal.VotesAbstain,
+		&proposal.ExecutionUnlock,
+		&proposal.ExecutionTxHash,
+		&proposal.Truncated,
+		&proposal.CreatedLedger,
+		&proposal.UpdatedLedger,
+		&proposal.UpdatedEventId,
+		&proposal.UpdatedAt,
+		&proposal.FlaggedLowParticipation,
+		&proposal.ActionType,
+		&proposal.ActionContractId,
+		&proposal.ActionFunction,
+		&proposal.SourceAccount,
+		&proposal.CreatedTxHash,
+	}
+}
+
+// scanProposal scans a row of PROPOSALS_COLUMNS into a proposal, followed by any extra selected columns
+func scanProposal(scanner interface{ Scan(...any) error }, extra ...any) (*governor.Proposal, error) {
+	proposal := &governor.Proposal{}
+	err := scanner.Scan(append(proposalFields(proposal), extra...)...)
+	return proposal, err
+}
+
+// UpsertProposal inserts or updates a proposal in the proposals table
+// For updates, it ignores fixed fields, and only updates mutable fields (votes_*, execution_*, status)
+// Proposals with invalid vote tallies are rejected with governor.ErrInvalidAmount.
+func (store *Store) UpsertProposal(ctx context.Context, proposal *governor.Proposal) error {
+	if err := proposal.Validate(); err != nil {
+		return fmt.Errorf("upsert proposal %s: %w", proposal.ProposalKey, err)
+	}
+
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_PROPOSAL)
+	defer done()
+
+	columns, values := store.insertColumns(
+		PROPOSALS_COLUMNS,
+		"$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26",
+		PROPOSALS_NUMERIC_COLUMNS,
+		PROPOSALS_NUMERIC_VALUES,
+	)
+	numericUpdates := ""
+	if store.hasNumericAmounts() {
+		numericUpdates = `,
+			votes_for_numeric = EXCLUDED.votes_for_numeric,
+			votes_against_numeric = EXCLUDED.votes_against_numeric,
+			votes_abstain_numeric = EXCLUDED.votes_abstain_numeric`
+	}
+
+	// @dev note: doesn't update proposal_key, contract_id, proposal_id on conflict
+	// to prevent changing primary identifiers
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s) 
+		VALUES (%s)
+		ON CONFLICT (proposal_key) 
+		DO UPDATE SET 
+			version = %s.version + 1,
+			status = EXCLUDED.status,
+			votes_for = EXCLUDED.votes_for,
+			votes_against = EXCLUDED.votes_against,
+			votes_abstain = EXCLUDED.votes_abstain,
+			execution_unlock = EXCLUDED.execution_unlock,
+			execution_tx_hash = EXCLUDED.execution_tx_hash,
+			updated_ledger = EXCLUDED.updated_ledger,
+			updated_event_id = EXCLUDED.updated_event_id,
+			updated_at = EXCLUDED.updated_at,
+			flagged_low_participation = EXCLUDED.flagged_low_participation%s
+		`, PROPOSALS_TABLE_NAME, columns, values, PROPOSALS_TABLE_NAME, numericUpdates)
+
+	_, err := store.exec(
+		ctx,
+		query,
+		proposalArgs(proposal)...,
+	)
+
+	if err != nil {
+		return fmt.Errorf("upsert proposal %s: %w", proposal.ProposalKey, timeoutErr(ctx, err))
+	}
+	return store.invalidateProposal(ctx, proposal.ProposalKey)
+}
+
+// GetProposal retrieves a proposal by its unique proposal key, or ErrNotFound if it does not exist.
+//
+// If the proposal cache is enabled, reads outside of a transaction are served from the cache. Cached proposals are
+// invalidated when they are written, and expire after the cache TTL.
+func (store *Store) GetProposal(ctx context.Context, proposalKey string) (*governor.Proposal, error) {
+	_, inTx := ctx.Value(txKey{}).(*sql.Tx)
+	cached := store.proposals != nil && !inTx
+	if cached {
+		if proposal, ok := store.proposals.get(proposalKey); ok {
+			metrics.ProposalCacheHits.Inc()
+			return proposal, nil
+		}
+		metrics.ProposalCacheMisses.Inc()
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSAL)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE proposal_key = $1
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+
+	proposal, err := scanProposal(store.queryRow(ctx, query, proposalKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get proposal %s: %w", proposalKey, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proposal %s: %w", proposalKey, timeoutErr(ctx, err))
+	}
+
+	if cached {
+		store.proposals.put(proposal)
+	}
+	return proposal, nil
+}
+
+// InsertProposal inserts a new proposal into the proposals table, or returns ErrConflict if it already exists.
+// Proposals with invalid vote tallies are rejected with governor.ErrInvalidAmount.
+func (store *Store) InsertProposal(ctx context.Context, proposal *governor.Proposal) error {
+	if err := proposal.Validate(); err != nil {
+		return fmt.Errorf("insert proposal %s: %w", proposal.ProposalKey, err)
+	}
+
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_PROPOSAL)
+	defer done()
+
+	columns, values := store.insertColumns(
+		PROPOSALS_COLUMNS,
+		"$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26",
+		PROPOSALS_NUMERIC_COLUMNS,
+		PROPOSALS_NUMERIC_VALUES,
+	)
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s) 
+		VALUES (%s)
+		ON CONFLICT (proposal_key) DO NOTHING
+		`, PROPOSALS_TABLE_NAME, columns, values)
+
+	result, err := store.exec(ctx, query, proposalArgs(proposal)...)
+	if err != nil {
+		return fmt.Errorf("insert proposal %s: %w", proposal.ProposalKey, timeoutErr(ctx, err))
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("insert proposal %s: %w", proposal.ProposalKey, err)
+	}
+	if inserted == 0 {
+		return fmt.Errorf("insert proposal %s: %w", proposal.ProposalKey, ErrConflict)
+	}
+	return nil
+}
+
+// UpdateProposal updates the mutable fields of a proposal, if its version still matches the version it was read at.
+// Returns ErrConflict if the proposal has been modified since. Proposals with invalid vote tallies are rejected with
+// governor.ErrInvalidAmount.
+func (store *Store) UpdateProposal(ctx context.Context, proposal *governor.Proposal, version int64) error {
+	if err := proposal.Validate(); err != nil {
+		return fmt.Errorf("update proposal %s: %w", proposal.ProposalKey, err)
+	}
+
+	ctx, done := store.writeQuery(ctx, QUERY_UPDATE_PROPOSAL)
+	defer done()
+
+	numericUpdates := ""
+	if store.hasNumericAmounts() {
+		numericUpdates = `,
+			votes_for_numeric = $2::TEXT::NUMERIC(39,0),
+			votes_against_numeric = $3::TEXT::NUMERIC(39,0),
+			votes_abstain_numeric = $4::TEXT::NUMERIC(39,0)`
+	}
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET
+			version = version + 1,
+			status = $1,
+			votes_for = $2,
+			votes_against = $3,
+			votes_abstain = $4,
+			execution_unlock = $5,
+			execution_tx_hash = $6,
+			updated_ledger = $9,
+			updated_event_id = $10,
+			updated_at = $11,
+			flagged_low_participation = $12%s
+		WHERE proposal_key = $7 AND version = $8
+		`, PROPOSALS_TABLE_NAME, numericUpdates)
+
+	result, err := store.exec(
+		ctx,
+		query,
+		proposal.Status,
+		proposal.VotesFor,
+		proposal.VotesAgainst,
+		proposal.VotesAbstain,
+		proposal.ExecutionUnlock,
+		proposal.ExecutionTxHash,
+		proposal.ProposalKey,
+		version,
+		proposal.UpdatedLedger,
+		proposal.UpdatedEventId,
+		proposal.UpdatedAt,
+		proposal.FlaggedLowParticipation,
+	)
+	if err != nil {
+		return fmt.Errorf("update proposal %s: %w", proposal.ProposalKey, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update proposal %s: %w", proposal.ProposalKey, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("update proposal %s at version %d: %w", proposal.ProposalKey, version, ErrConflict)
+	}
+	return store.invalidateProposal(ctx, proposal.ProposalKey)
+}
+
+// GetProposalVersion retrieves a proposal and its current version, for use with UpdateProposal.
+// Returns ErrNotFound if the proposal does not exist.
+func (store *Store) GetProposalVersion(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSAL_VERSION)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s, version
+		FROM %s
+		WHERE proposal_key = $1
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+
+	var version int64
+	proposal, err := scanProposal(store.queryRow(ctx, query, proposalKey), &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, fmt.Errorf("get proposal %s: %w", proposalKey, ErrNotFound)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("get proposal %s: %w", proposalKey, timeoutErr(ctx, err))
+	}
+
+	return proposal, version, nil
+}
+
+// GetProposalsByContract retrieves all proposals for a given contract ID
+// TODO: add pagination
+func (store *Store) GetProposalsByContractId(ctx context.Context, contractId string, sort Sort) ([]*governor.Proposal, error) {
+	order, err := store.proposalOrderBy(sort)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals for contract %s: %w", contractId, err)
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSALS_BY_CONTRACT_ID)
+	defer done()
+
+	count, err := store.countRows(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE contract_id = $1", PROPOSALS_TABLE_NAME), contractId)
+	if err != nil {
+		return nil, fmt.Errorf("count proposals for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1
+		%s
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME, order)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	proposals, err := scanRows(ctx, rows, proposalFields, count)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return proposals, nil
+}
+
+// EachProposalByContractId calls fn with each proposal for a contract as it is read, in the order of
+// GetProposalsByContractId, and stops at the first error returned by fn. The read timeout doesn't apply, as the
+// duration depends on fn, so the query is only cancelled with ctx.
+func (store *Store) EachProposalByContractId(ctx context.Context, contractId string, sort Sort, fn func(proposal *governor.Proposal) error) error {
+	order, err := store.proposalOrderBy(sort)
+	if err != nil {
+		return fmt.Errorf("stream proposals for contract %s: %w", contractId, err)
+	}
+	ctx, done := observeQuery(ctx, QUERY_EACH_PROPOSAL_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1
+		%s
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME, order)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId)
+	if err != nil {
+		return fmt.Errorf("stream proposals for contract %s: %w", contractId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		proposal, err := scanProposal(rows)
+		if err != nil {
+			return fmt.Errorf("stream proposals for contract %s: %w", contractId, err)
+		}
+		addRows(ctx, 1)
+		if err := fn(proposal); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("stream proposals for contract %s: %w", contractId, err)
+	}
+	return nil
+}
+
+// EncodeProposalCursor returns the cursor to pass to GetProposalsByStatus to get the proposals after proposal
+func EncodeProposalCursor(proposal *governor.Proposal) string {
+	return fmt.Sprintf("%d:%s", proposal.VoteEnd, proposal.ProposalKey)
+}
+
+// GetProposalsByStatus retrieves up to limit proposals across all contracts with one of the given statuses, ordered
+// by vote_end ascending, then by proposal key. If cursor is not empty, only proposals after the cursor are returned,
+// see EncodeProposalCursor. Invalid cursors are rejected with ErrInvalidCursor. Proposals flagged as low
+// participation are only returned if includeFlagged is true, and proposals of contracts that were not reviewed only
+// if includeUnreviewed is true. Proposals of blocked contracts are never returned.
+func (store *Store) GetProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool, limit int, cursor string) ([]*governor.Proposal, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	args := []any{limit}
+	placeholders := make([]string, len(statuses))
+	for i, status := range statuses {
+		args = append(args, status)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+	after := ""
+	if cursor != "" {
+		voteEndStr, proposalKey, ok := strings.Cut(cursor, ":")
+		voteEnd, err := strconv.ParseUint(voteEndStr, 10, 32)
+		if !ok || err != nil || proposalKey == "" {
+			return nil, fmt.Errorf("get proposals by status: %q: %w", cursor, ErrInvalidCursor)
+		}
+		args = append(args, uint32(voteEnd), proposalKey)
+		after = fmt.Sprintf("AND (vote_end > $%d OR (vote_end = $%d AND proposal_key > $%d))", len(args)-1, len(args)-1, len(args))
+	}
+	if !includeFlagged {
+		after += " AND flagged_low_participation = FALSE"
+	}
+	if !includeUnreviewed {
+		after += " AND " + REVIEWED_CONTRACT_FILTER
+	}
+	after += " AND " + UNBLOCKED_CONTRACT_FILTER
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSALS_BY_STATUS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE status IN (%s) %s
+		ORDER BY vote_end ASC, proposal_key ASC
+		LIMIT $1
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME, strings.Join(placeholders, ", "), after)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals by status: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	proposals, err := scanRows(ctx, rows, proposalFields, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals by status: %w", timeoutErr(ctx, err))
+	}
+	return proposals, nil
+}
+
+// CountProposalsByStatus returns the number of proposals across all contracts with one of the given statuses.
+// Proposals flagged as low participation are only counted if includeFlagged is true, and proposals of contracts that
+// were not reviewed only if includeUnreviewed is true. Proposals of blocked contracts are never counted.
+func (store *Store) CountProposalsByStatus(ctx context.Context, statuses []uint32, includeFlagged bool, includeUnreviewed bool) (int, error) {
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	args := make([]any, len(statuses))
+	placeholders := make([]string, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_COUNT_PROPOSALS_BY_STATUS)
+	defer done()
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE status IN (%s)", PROPOSALS_TABLE_NAME, strings.Join(placeholders, ", "))
+	if !includeFlagged {
+		query += " AND flagged_low_participation = FALSE"
+	}
+	if !includeUnreviewed {
+		query += " AND " + REVIEWED_CONTRACT_FILTER
+	}
+	query += " AND " + UNBLOCKED_CONTRACT_FILTER
+	var count int
+	if err := store.queryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count proposals by status: %w", timeoutErr(ctx, err))
+	}
+	return count, nil
+}
+
+// GetProposalsEndingBetween retrieves the open proposals across all contracts whose voting ends between fromLedger
+// and toLedger, inclusive, ordered by vote_end ascending, then by proposal key
+func (store *Store) GetProposalsEndingBetween(ctx context.Context, fromLedger uint32, toLedger uint32) ([]*governor.Proposal, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSALS_ENDING_BETWEEN)
+	defer done()
+
+	// status 0 is open
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE status = 0 AND vote_end BETWEEN $1 AND $2
+		ORDER BY vote_end ASC, proposal_key ASC
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, fromLedger, toLedger)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals ending between %d and %d: %w", fromLedger, toLedger, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	proposals, err := scanRows(ctx, rows, proposalFields, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals ending between %d and %d: %w", fromLedger, toLedger, timeoutErr(ctx, err))
+	}
+	return proposals, nil
+}
+
+// GetExecutableProposals retrieves the successful proposals across all contracts that have not been executed and
+// can be executed by ledger, as their execution unlock is at or before it, ordered by execution_unlock ascending,
+// then by proposal key. Proposals without an execution unlock have nothing to execute, and are omitted.
+func (store *Store) GetExecutableProposals(ctx context.Context, ledger uint32) ([]*governor.Proposal, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_EXECUTABLE_PROPOSALS)
+	defer done()
+
+	// status 1 is successful and waiting to be executed, and status 4 once executed
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE status = 1 AND execution_unlock > 0 AND execution_unlock <= $1
+		ORDER BY execution_unlock ASC, proposal_key ASC
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, ledger)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals executable by %d: %w", ledger, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	proposals, err := scanRows(ctx, rows, proposalFields, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals executable by %d: %w", ledger, timeoutErr(ctx, err))
+	}
+	return proposals, nil
+}
+
+// GetProposalsByKeys retrieves the proposals with the given keys, in the order of the keys. Keys without a proposal
+// are omitted, and duplicate keys are returned once.
+func (store *Store) GetProposalsByKeys(ctx context.Context, proposalKeys []string) ([]*governor.Proposal, error) {
+	if len(proposalKeys) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(proposalKeys))
+	placeholders := make([]string, len(proposalKeys))
+	for i, proposalKey := range proposalKeys {
+		args[i] = proposalKey
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSALS_BY_KEYS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE proposal_key IN (%s)
+	`, PROPOSALS_COLUMNS, PROPOSALS_TABLE_NAME, strings.Join(placeholders, ", "))
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get proposals by keys: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	found, err := scanRows(ctx, rows, proposalFields, len(proposalKeys))
+	if err != nil {
+		return nil, fmt.Errorf("get proposals by keys: %w", timeoutErr(ctx, err))
+	}
+	byKey := make(map[string]*governor.Proposal, len(found))
+	for _, proposal := range found {
+		byKey[proposal.ProposalKey] = proposal
+	}
+	var proposals []*governor.Proposal
+	for _, proposalKey := range proposalKeys {
+		if proposal, ok := byKey[proposalKey]; ok {
+			proposals = append(proposals, proposal)
+			delete(byKey, proposalKey)
+		}
+	}
+	return proposals, nil
+}
+
+// DeleteProposalsByContractId deletes all proposals for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteProposalsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_PROPOSALS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, PROPOSALS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete proposals for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	// deletes are rare admin operations, so the whole cache is invalidated
+	if err := store.invalidateProposal(ctx, PROPOSAL_CACHE_PURGE); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+//********** Votes Table **********//
+
+const (
+	VOTES_TABLE_NAME = "votes"
+	VOTES_COLUMNS    = "tx_hash, contract_id, proposal_id, voter, support, amount, ledger_seq, ledger_close_time, reason"
+	// VOTES_SELECT_COLUMNS are VOTES_COLUMNS as read. reason is NULL for votes without one, which is read as an
+	// empty string.
+	VOTES_SELECT_COLUMNS = "tx_hash, contract_id, proposal_id, voter, support, amount, ledger_seq, ledger_close_time, COALESCE(reason, '')"
+)
+
+func voteArgs(vote *governor.Vote) []any {
+	return []any{
+		vote.TxHash,
+		vote.ContractId,
+		vote.ProposalId,
+		vote.Voter,
+		vote.Support,
+		vote.Amount,
+		vote.LedgerSeq,
+		vote.LedgerCloseTime,
+		sql.NullString{String: vote.Reason, Valid: vote.Reason != ""},
+	}
+}
+
+// voteFields returns the scan destinations for VOTES_SELECT_COLUMNS
+func voteFields(vote *governor.Vote) []any {
+	return []any{
+		&vote.TxHash,
+		&vote.ContractId,
+		&vote.ProposalId,
+		&vote.Voter,
+		&vote.Support,
+		&vote.Amount,
+		&vote.LedgerSeq,
+		&vote.LedgerCloseTime,
+		&vote.Reason,
+	}
+}
+
+func scanVote(scanner interface{ Scan(...any) error }) (*governor.Vote, error) {
+	vote := &governor.Vote{}
+	err := scanner.Scan(voteFields(vote)...)
+	return vote, err
+}
+
+// InsertVote inserts a new vote into the votes table. Inserting a vote for an existing tx hash is a no-op.
+func (store *Store) InsertVote(ctx context.Context, vote *governor.Vote) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_VOTE)
+	defer done()
+
+	columns, values := store.insertColumns(VOTES_COLUMNS, "$1, $2, $3, $4, $5, $6, $7, $8, $9", VOTES_NUMERIC_COLUMNS, VOTES_NUMERIC_VALUES)
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s) 
+		VALUES (%s)
+		ON CONFLICT (tx_hash) DO NOTHING
+		`, VOTES_TABLE_NAME, columns, values)
+
+	_, err := store.exec(
+		ctx,
+		query,
+		voteArgs(vote)...,
+	)
+
+	if err != nil {
+		return fmt.Errorf("insert vote %s: %w", vote.TxHash, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// InsertVotes inserts votes into the votes table with multi-row statements. Votes for existing tx hashes are
+// skipped, as with InsertVote.
+func (store *Store) InsertVotes(ctx context.Context, votes []*governor.Vote) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_VOTES)
+	defer done()
+
+	columns, _ := store.insertColumns(VOTES_COLUMNS, "", VOTES_NUMERIC_COLUMNS, "")
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES %%s
+		ON CONFLICT (tx_hash) DO NOTHING
+	`, VOTES_TABLE_NAME, columns)
+
+	rowParams := len(voteArgs(&governor.Vote{}))
+	values := func(offset int) string {
+		// the NUMERIC mirror of the amount reuses the row's amount parameter
+		_, values := store.insertColumns(VOTES_COLUMNS, placeholders(offset, rowParams), VOTES_NUMERIC_COLUMNS, fmt.Sprintf("$%d::TEXT::NUMERIC(39,0)", offset+6))
+		return values
+	}
+	if err := insertRows(ctx, store, query, votes, rowParams, values, voteArgs); err != nil {
+		return fmt.Errorf("insert %d votes: %w", len(votes), timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetVotesByTxHashes retrieves the votes cast in the given transactions. Transactions without a vote are omitted.
+func (store *Store) GetVotesByTxHashes(ctx context.Context, txHashes []string) ([]*governor.Vote, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTES_BY_TX_HASHES)
+	defer done()
+
+	var votes []*governor.Vote
+	for chunk := range slices.Chunk(txHashes, store.maxQueryParams()) {
+		args := make([]any, len(chunk))
+		for i, txHash := range chunk {
+			args[i] = txHash
+		}
+		query := fmt.Sprintf(`
+			SELECT %s
+			FROM %s
+			WHERE tx_hash IN (%s)
+		`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME, placeholders(0, len(chunk)))
+
+		rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("get votes by tx hashes: %w", timeoutErr(ctx, err))
+		}
+		found, err := scanRows(ctx, rows, voteFields, len(chunk))
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("get votes by tx hashes: %w", timeoutErr(ctx, err))
+		}
+		votes = append(votes, found...)
+	}
+	return votes, nil
+}
+
+// GetVote retrieves the vote cast in the given transaction, or ErrNotFound if it does not exist
+func (store *Store) GetVote(ctx context.Context, txHash string) (*governor.Vote, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTE)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE tx_hash = $1
+	`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME)
+
+	vote, err := scanVote(store.queryRow(ctx, query, txHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get vote %s: %w", txHash, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get vote %s: %w", txHash, timeoutErr(ctx, err))
+	}
+
+	return vote, nil
+}
+
+// GetVoteByProposalAndVoter retrieves the latest vote of a voter on a proposal, which is the vote that counts, or
+// ErrNotFound if they have not voted
+func (store *Store) GetVoteByProposalAndVoter(ctx context.Context, contractId string, proposalId uint32, voter string) (*governor.Vote, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTE_BY_PROPOSAL_AND_VOTER)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND proposal_id = $2 AND voter = $3
+		ORDER BY ledger_seq DESC
+		LIMIT 1
+	`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME)
+
+	vote, err := scanVote(store.queryRow(ctx, query, contractId, proposalId, voter))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get vote by %s for proposal %s-%d: %w", voter, contractId, proposalId, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get vote by %s for proposal %s-%d: %w", voter, contractId, proposalId, timeoutErr(ctx, err))
+	}
+
+	return vote, nil
+}
+
+// GetLatestVotesByVoter retrieves the latest vote of a voter on each of the given proposals of a contract, ordered by
+// proposal id. Proposals the voter has not voted on are omitted.
+func (store *Store) GetLatestVotesByVoter(ctx context.Context, contractId string, voter string, proposalIds []uint32) ([]*governor.Vote, error) {
+	if len(proposalIds) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(proposalIds)+2)
+	args = append(args, contractId, voter)
+	for _, proposalId := range proposalIds {
+		args = append(args, proposalId)
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_LATEST_VOTES_BY_VOTER)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND voter = $2 AND proposal_id IN (%s)
+		ORDER BY proposal_id ASC, ledger_seq DESC
+	`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME, placeholders(2, len(proposalIds)))
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get votes by %s for contract %s: %w", voter, contractId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	found, err := scanRows(ctx, rows, voteFields, len(proposalIds))
+	if err != nil {
+		return nil, fmt.Errorf("get votes by %s for contract %s: %w", voter, contractId, timeoutErr(ctx, err))
+	}
+	// a voter can vote again, so only keep the first vote of each proposal, which is the latest
+	var votes []*governor.Vote
+	for _, vote := range found {
+		if len(votes) == 0 || votes[len(votes)-1].ProposalId != vote.ProposalId {
+			votes = append(votes, vote)
+		}
+	}
+	return votes, nil
+}
+
+// GetVotesByProposal retrieves all votes for a proposal, ordered by sort, or by ledger_seq DESC by default. Votes can be
+// sorted by VOTE_SORT_FIELDS.
+func (store *Store) GetVotesByProposal(ctx context.Context, contractId string, proposalId uint32, sort Sort) ([]*governor.Vote, error) {
+	order, err := store.voteOrderBy(sort)
+	if err != nil {
+		return nil, fmt.Errorf("get votes for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTES_BY_PROPOSAL)
+	defer done()
+
+	count, err := store.countRows(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE contract_id = $1 AND proposal_id = $2", VOTES_TABLE_NAME), contractId, proposalId)
+	if err != nil {
+		return nil, fmt.Errorf("count votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND proposal_id = $2
+		%s
+	`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME, order)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId, proposalId)
+	if err != nil {
+		return nil, fmt.Errorf("get votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	votes, err := scanRows(ctx, rows, voteFields, count)
+	if err != nil {
+		return nil, fmt.Errorf("get votes for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	return votes, nil
+}
+
+// EachVoteByProposal calls fn with each vote for a proposal as it is read, in the order of GetVotesByProposal, and
+// stops at the first error returned by fn. The read timeout doesn't apply, as the duration depends on fn, so the
+// query is only cancelled with ctx.
+func (store *Store) EachVoteByProposal(ctx context.Context, contractId string, proposalId uint32, sort Sort, fn func(vote *governor.Vote) error) error {
+	order, err := store.voteOrderBy(sort)
+	if err != nil {
+		return fmt.Errorf("stream votes for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	ctx, done := observeQuery(ctx, QUERY_EACH_VOTE_BY_PROPOSAL)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND proposal_id = $2
+		%s
+	`, VOTES_SELECT_COLUMNS, VOTES_TABLE_NAME, order)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, contractId, proposalId)
+	if err != nil {
+		return fmt.Errorf("stream votes for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		vote, err := scanVote(rows)
+		if err != nil {
+			return fmt.Errorf("stream votes for proposal %s-%d: %w", contractId, proposalId, err)
+		}
+		addRows(ctx, 1)
+		if err := fn(vote); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("stream votes for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	return nil
+}
+
+// GetVoteSummary aggregates the votes for a proposal by support, see governor.NewVoteSummary. On postgres, amounts
+// are summed in SQL with their NUMERIC mirrors. On sqlite, amounts are only stored as text to hold i128s, so are
+// summed in Go.
+func (store *Store) GetVoteSummary(ctx context.Context, contractId string, proposalId uint32) (*governor.VoteSummary, error) {
+	ctx, done := observeQuery(ctx, QUERY_GET_VOTE_SUMMARY)
+	defer done()
+	if store.hasNumericAmounts() {
+		return store.getVoteSummaryNumeric(ctx, contractId, proposalId)
+	}
+	votes, err := store.GetVotesByProposal(ctx, contractId, proposalId, Sort{})
+	if err != nil {
+		return nil, err
+	}
+	summary, err := governor.NewVoteSummary(votes)
+	if err != nil {
+		return nil, fmt.Errorf("summarize votes for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	return summary, nil
+}
+
+// CountVoters returns the number of distinct voters that voted on a proposal
+func (store *Store) CountVoters(ctx context.Context, contractId string, proposalId uint32) (int, error) {
+	ctx, done := store.readQuery(ctx, QUERY_COUNT_VOTERS)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT COUNT(DISTINCT voter) FROM %s WHERE contract_id = $1 AND proposal_id = $2`, VOTES_TABLE_NAME)
+	var count int
+	if err := store.queryRow(ctx, query, contractId, proposalId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count voters for proposal %s-%d: %w", contractId, proposalId, timeoutErr(ctx, err))
+	}
+	return count, nil
+}
+
+// GetVoteSeries groups the votes for a proposal into buckets of bucketSize seconds, see governor.NewVoteSeries. As
+// with GetVoteSummary, buckets are totalled in SQL on postgres, and in Go on sqlite.
+func (store *Store) GetVoteSeries(ctx context.Context, contractId string, proposalId uint32, bucketSize int64) ([]*governor.VoteSeriesPoint, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("vote series for proposal %s-%d: invalid bucket size %d", contractId, proposalId, bucketSize)
+	}
+	ctx, done := observeQuery(ctx, QUERY_GET_VOTE_SERIES)
+	defer done()
+	if store.hasNumericAmounts() {
+		return store.getVoteSeriesNumeric(ctx, contractId, proposalId, bucketSize)
+	}
+	votes, err := store.GetVotesByProposal(ctx, contractId, proposalId, Sort{})
+	if err != nil {
+		return nil, err
+	}
+	series, err := governor.NewVoteSeries(votes, bucketSize)
+	if err != nil {
+		return nil, fmt.Errorf("vote series for proposal %s-%d: %w", contractId, proposalId, err)
+	}
+	return series, nil
+}
+
+// DeleteVotesByContractId deletes all votes for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteVotesByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_VOTES_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, VOTES_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete votes for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// DeleteVotesAfterLedger deletes the votes for a given contract ID cast after a ledger, and returns the number of rows
+// deleted
+func (store *Store) DeleteVotesAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_VOTES_AFTER_LEDGER)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1 AND ledger_seq > $2`, VOTES_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, ledgerSeq)
+	if err != nil {
+		return 0, fmt.Errorf("delete votes for contract %s after ledger %d: %w", contractId, ledgerSeq, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Delegations Table **********//
+
+const (
+	DELEGATIONS_TABLE_NAME = "delegations"
+	DELEGATIONS_COLUMNS    = "event_id, contract_id, delegator, from_delegate, delegate, tx_hash, ledger_seq, ledger_close_time"
+)
+
+func scanDelegation(scanner interface{ Scan(...any) error }) (*governor.Delegation, error) {
+	delegation := &governor.Delegation{}
+	err := scanner.Scan(
+		&delegation.EventId,
+		&delegation.ContractId,
+		&delegation.Delegator,
+		&delegation.FromDelegate,
+		&delegation.Delegate,
+		&delegation.TxHash,
+		&delegation.LedgerSeq,
+		&delegation.LedgerCloseTime,
+	)
+	return delegation, err
+}
+
+// InsertDelegation inserts a delegate change into the delegations table. Inserting an existing change is a no-op.
+func (store *Store) InsertDelegation(ctx context.Context, delegation *governor.Delegation) error {
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_DELEGATION)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (event_id) DO NOTHING
+	`, DELEGATIONS_TABLE_NAME, DELEGATIONS_COLUMNS)
+
+	_, err := store.exec(
+		ctx,
+		query,
+		delegation.EventId,
+		delegation.ContractId,
+		delegation.Delegator,
+		delegation.FromDelegate,
+		delegation.Delegate,
+		delegation.TxHash,
+		delegation.LedgerSeq,
+		delegation.LedgerCloseTime,
+	)
+	if err != nil {
+		return fmt.Errorf("insert delegation %s: %w", delegation.EventId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetDelegators retrieves the current delegators of a delegate, which are the delegators whose latest
+// delegate change was to the delegate, oldest first
+func (store *Store) GetDelegators(ctx context.Context, contractId string, delegate string) ([]*governor.Delegation, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s d
+		WHERE contract_id = $1 AND delegate = $2 AND event_id = (
+			SELECT MAX(event_id)
+			FROM %s
+			WHERE contract_id = $1 AND delegator = d.delegator
+		)
+		ORDER BY event_id ASC
+	`, DELEGATIONS_COLUMNS, DELEGATIONS_TABLE_NAME, DELEGATIONS_TABLE_NAME)
+
+	delegations, err := store.queryDelegations(ctx, QUERY_GET_DELEGATORS, query, contractId, delegate)
+	if err != nil {
+		return nil, fmt.Errorf("get delegators of %s for contract %s: %w", delegate, contractId, err)
+	}
+	return delegations, nil
+}
+
+// GetDelegationHistory retrieves every delegate change made by or involving an address as the previous or new
+// delegate, oldest first
+func (store *Store) GetDelegationHistory(ctx context.Context, contractId string, address string) ([]*governor.Delegation, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE contract_id = $1 AND (delegator = $2 OR from_delegate = $2 OR delegate = $2)
+		ORDER BY event_id ASC
+	`, DELEGATIONS_COLUMNS, DELEGATIONS_TABLE_NAME)
+
+	delegations, err := store.queryDelegations(ctx, QUERY_GET_DELEGATION_HISTORY, query, contractId, address)
+	if err != nil {
+		return nil, fmt.Errorf("get delegation history of %s for contract %s: %w", address, contractId, err)
+	}
+	return delegations, nil
+}
+
+func (store *Store) queryDelegations(ctx context.Context, name string, query string, args ...any) ([]*governor.Delegation, error) {
+	ctx, done := store.readQuery(ctx, name)
+	defer done()
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, timeoutErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var delegations []*governor.Delegation
+	for rows.Next() {
+		delegation, err := scanDelegation(rows)
+		if err != nil {
+			return nil, timeoutErr(ctx, err)
+		}
+		delegations = append(delegations, delegation)
+	}
+	addRows(ctx, int64(len(delegations)))
+
+	if err := rows.Err(); err != nil {
+		return nil, timeoutErr(ctx, err)
+	}
+	return delegations, nil
+}
+
+// DeleteDelegationsByContractId deletes all delegations for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteDelegationsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_DELEGATIONS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, DELEGATIONS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete delegations for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// DeleteDelegationsAfterLedger deletes the delegations for a given contract ID made after a ledger, and returns the
+// number of rows deleted
+func (store *Store) DeleteDelegationsAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_DELEGATIONS_AFTER_LEDGER)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1 AND ledger_seq > $2`, DELEGATIONS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, ledgerSeq)
+	if err != nil {
+		return 0, fmt.Errorf("delete delegations for contract %s after ledger %d: %w", contractId, ledgerSeq, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Votes Contracts Table **********//
+
+const VOTES_CONTRACTS_TABLE_NAME = "votes_contracts"
+
+// UpsertVotesContract records the votes contract of a governor
+func (store *Store) UpsertVotesContract(ctx context.Context, governorId string, votesId string, ledgerSeq uint32) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_VOTES_CONTRACT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (governor_id, votes_id, ledger_seq)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (governor_id) DO UPDATE SET votes_id = EXCLUDED.votes_id, ledger_seq = EXCLUDED.ledger_seq
+	`, VOTES_CONTRACTS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, governorId, votesId, ledgerSeq)
+	if err != nil {
+		return fmt.Errorf("upsert votes contract for %s: %w", governorId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// IsVotesContract returns true if the contract is the votes contract of a known governor
+func (store *Store) IsVotesContract(ctx context.Context, contractId string) (bool, error) {
+	ctx, done := store.readQuery(ctx, QUERY_IS_VOTES_CONTRACT)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE votes_id = $1)`, VOTES_CONTRACTS_TABLE_NAME)
+
+	var exists bool
+	if err := store.queryRow(ctx, query, contractId).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check votes contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return exists, nil
+}
+
+// DeleteVotesContracts deletes the votes contract recorded for a governor, and the governors recorded with it as their
+// votes contract, and returns the number of rows deleted
+func (store *Store) DeleteVotesContracts(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_VOTES_CONTRACTS)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE governor_id = $1 OR votes_id = $1`, VOTES_CONTRACTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete votes contracts for %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// GetTrackedContracts returns the contracts the indexer tracks, which are the governors with indexed proposals and
+// the governors and votes contracts recorded in the votes contracts table
+func (store *Store) GetTrackedContracts(ctx context.Context) ([]string, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_TRACKED_CONTRACTS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT contract_id FROM %s
+		UNION SELECT governor_id FROM %s
+		UNION SELECT votes_id FROM %s
+	`, PROPOSALS_TABLE_NAME, VOTES_CONTRACTS_TABLE_NAME, VOTES_CONTRACTS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get tracked contracts: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	contracts, err := scanRows(ctx, rows, func(contractId *string) []any { return []any{contractId} }, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get tracked contracts: %w", timeoutErr(ctx, err))
+	}
+	ids := make([]string, len(contracts))
+	for i, contractId := range contracts {
+		ids[i] = *contractId
+	}
+	return ids, nil
+}
+
+//********** Governor Settings Table **********//
+
+const GOVERNOR_SETTINGS_TABLE_NAME = "governor_settings"
+
+// UpsertGovernorSettings records the settings of a governor read at a ledger. Settings read again at the same ledger,
+// as when a ledger is retried or a later transaction of the ledger changes them, replace the earlier ones.
+func (store *Store) UpsertGovernorSettings(ctx context.Context, settings *governor.Settings) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_GOVERNOR_SETTINGS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (governor_id, ledger_seq, counting_type, quorum, vote_threshold)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (governor_id, ledger_seq) DO UPDATE SET counting_type = EXCLUDED.counting_type,
+			quorum = EXCLUDED.quorum, vote_threshold = EXCLUDED.vote_threshold
+	`, GOVERNOR_SETTINGS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, settings.GovernorId, settings.LedgerSeq, settings.CountingType, settings.Quorum, settings.VoteThreshold)
+	if err != nil {
+		return fmt.Errorf("upsert settings for %s: %w", settings.GovernorId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetGovernorSettings returns the settings of a governor in effect at a ledger, the latest read at or before it, or
+// ErrNotFound if none were recorded. Settings are only known from the first ledger they were read at, so for an
+// earlier ledger the first settings recorded are returned.
+func (store *Store) GetGovernorSettings(ctx context.Context, governorId string, ledgerSeq uint32) (*governor.Settings, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_GOVERNOR_SETTINGS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT governor_id, counting_type, quorum, vote_threshold, ledger_seq
+		FROM %s
+		WHERE governor_id = $1
+		ORDER BY CASE WHEN ledger_seq <= $2 THEN -ledger_seq ELSE ledger_seq END
+		LIMIT 1
+	`, GOVERNOR_SETTINGS_TABLE_NAME)
+
+	var s governor.Settings
+	err := store.queryRow(ctx, query, governorId, ledgerSeq).Scan(&s.GovernorId, &s.CountingType, &s.Quorum, &s.VoteThreshold, &s.LedgerSeq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get settings for %s: %w", governorId, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get settings for %s: %w", governorId, timeoutErr(ctx, err))
+	}
+	return &s, nil
+}
+
+// DeleteGovernorSettings deletes the settings recorded for a governor, and returns the number of rows deleted
+func (store *Store) DeleteGovernorSettings(ctx context.Context, governorId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_GOVERNOR_SETTINGS)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE governor_id = $1`, GOVERNOR_SETTINGS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, governorId)
+	if err != nil {
+		return 0, fmt.Errorf("delete settings for %s: %w", governorId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Proposal Content Table **********//
+
+const (
+	PROPOSAL_CONTENT_TABLE_NAME = "proposal_content"
+	PROPOSAL_CONTENT_COLUMNS    = "proposal_key, contract_id, proposal_id, uri, status, content, content_type, attempts, next_attempt_at, last_error, fetched_at"
+)
+
+func scanProposalContent(scanner interface{ Scan(...any) error }) (*governor.ProposalContent, error) {
+	content := &governor.ProposalContent{}
+	err := scanner.Scan(
+		&content.ProposalKey,
+		&content.ContractId,
+		&content.ProposalId,
+		&content.Uri,
+		&content.Status,
+		&content.Content,
+		&content.ContentType,
+		&content.Attempts,
+		&content.NextAttemptAt,
+		&content.LastError,
+		&content.FetchedAt,
+	)
+	return content, err
+}
+
+// UpsertProposalContent inserts or replaces the content of a proposal
+func (store *Store) UpsertProposalContent(ctx context.Context, content *governor.ProposalContent) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_PROPOSAL_CONTENT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (proposal_key)
+		DO UPDATE SET
+			uri = EXCLUDED.uri,
+			status = EXCLUDED.status,
+			content = EXCLUDED.content,
+			content_type = EXCLUDED.content_type,
+			attempts = EXCLUDED.attempts,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			last_error = EXCLUDED.last_error,
+			fetched_at = EXCLUDED.fetched_at
+	`, PROPOSAL_CONTENT_TABLE_NAME, PROPOSAL_CONTENT_COLUMNS)
+
+	_, err := store.exec(
+		ctx,
+		query,
+		content.ProposalKey,
+		content.ContractId,
+		content.ProposalId,
+		content.Uri,
+		content.Status,
+		content.Content,
+		content.ContentType,
+		content.Attempts,
+		content.NextAttemptAt,
+		content.LastError,
+		content.FetchedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert proposal content %s: %w", content.ProposalKey, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetProposalContent retrieves the content of a proposal by its proposal key, or ErrNotFound if it does not exist
+func (store *Store) GetProposalContent(ctx context.Context, proposalKey string) (*governor.ProposalContent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSAL_CONTENT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE proposal_key = $1
+	`, PROPOSAL_CONTENT_COLUMNS, PROPOSAL_CONTENT_TABLE_NAME)
+
+	content, err := scanProposalContent(store.queryRow(ctx, query, proposalKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get proposal content %s: %w", proposalKey, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get proposal content %s: %w", proposalKey, timeoutErr(ctx, err))
+	}
+	return content, nil
+}
+
+// GetProposalContentToFetch retrieves up to limit proposals with an IPFS description whose content is due to be
+// fetched at time now, oldest first. Proposals without any content yet are returned as pending content with no
+// attempts.
+func (store *Store) GetProposalContentToFetch(ctx context.Context, now int64, limit int) ([]*governor.ProposalContent, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_PROPOSAL_CONTENT_TO_FETCH)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT p.proposal_key, p.contract_id, p.proposal_id, p.description, '%s',
+			COALESCE(c.content, ''), COALESCE(c.content_type, ''), COALESCE(c.attempts, 0),
+			COALESCE(c.next_attempt_at, 0), COALESCE(c.last_error, ''), COALESCE(c.fetched_at, 0)
+		FROM %s p
+		LEFT JOIN %s c ON c.proposal_key = p.proposal_key
+		WHERE p.description LIKE '%s%%'
+			AND (c.proposal_key IS NULL OR (c.status = '%s' AND c.next_attempt_at <= $1))
+		ORDER BY COALESCE(c.next_attempt_at, 0) ASC, p.proposal_key ASC
+		LIMIT $2
+	`, governor.CONTENT_STATUS_PENDING, PROPOSALS_TABLE_NAME, PROPOSAL_CONTENT_TABLE_NAME,
+		governor.IPFS_URI_PREFIX, governor.CONTENT_STATUS_PENDING)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get proposal content to fetch: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	var contents []*governor.ProposalContent
+	for rows.Next() {
+		content, err := scanProposalContent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get proposal content to fetch: %w", timeoutErr(ctx, err))
+		}
+		contents = append(contents, content)
+	}
+	addRows(ctx, int64(len(contents)))
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get proposal content to fetch: %w", timeoutErr(ctx, err))
+	}
+	return contents, nil
+}
+
+// DeleteProposalContentByContractId deletes all proposal content for a given contract ID, and returns the number of
+// rows deleted
+func (store *Store) DeleteProposalContentByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_PROPOSAL_CONTENT_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, PROPOSAL_CONTENT_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete proposal content for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Snapshots Table **********//
+
+const SNAPSHOTS_TABLE_NAME = "snapshots"
+
+// Snapshot is the state of a contract's proposals after a ledger was processed
+type Snapshot struct {
+	ContractId string
+	LedgerSeq  uint32
+	// EventId is the last event of the contract included in the snapshot, or "" if it had no events
+	EventId   string
+	Proposals []*governor.Proposal
+	// CreatedAt is the unix time the snapshot was taken
+	CreatedAt int64
+}
+
+// InsertSnapshot stores a snapshot, replacing any snapshot of the contract at the same ledger
+func (store *Store) InsertSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	proposals, err := json.Marshal(snapshot.Proposals)
+	if err != nil {
+		return fmt.Errorf("insert snapshot for contract %s: %w", snapshot.ContractId, err)
+	}
+
+	ctx, done := store.writeQuery(ctx, QUERY_INSERT_SNAPSHOT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (contract_id, ledger_seq, event_id, proposals, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (contract_id, ledger_seq) DO UPDATE SET
+			event_id = EXCLUDED.event_id, proposals = EXCLUDED.proposals, created_at = EXCLUDED.created_at
+	`, SNAPSHOTS_TABLE_NAME)
+
+	_, err = store.exec(ctx, query, snapshot.ContractId, snapshot.LedgerSeq, snapshot.EventId, string(proposals), snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert snapshot for contract %s: %w", snapshot.ContractId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetLatestSnapshot returns the latest snapshot of a contract taken before beforeLedger, or the latest snapshot if
+// beforeLedger is 0. Returns ErrNotFound if there is no such snapshot.
+func (store *Store) GetLatestSnapshot(ctx context.Context, contractId string, beforeLedger uint32) (*Snapshot, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_LATEST_SNAPSHOT)
+	defer done()
+
+	filter := ""
+	args := []any{contractId}
+	if beforeLedger > 0 {
+		filter = "AND ledger_seq < $2"
+		args = append(args, beforeLedger)
+	}
+	query := fmt.Sprintf(`
+		SELECT contract_id, ledger_seq, event_id, proposals, created_at
+		FROM %s
+		WHERE contract_id = $1 %s
+		ORDER BY ledger_seq DESC
+		LIMIT 1
+	`, SNAPSHOTS_TABLE_NAME, filter)
+
+	snapshot := &Snapshot{}
+	var proposals string
+	err := store.queryRow(ctx, query, args...).Scan(
+		&snapshot.ContractId,
+		&snapshot.LedgerSeq,
+		&snapshot.EventId,
+		&proposals,
+		&snapshot.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get snapshot for contract %s: %w", contractId, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	if err := json.Unmarshal([]byte(proposals), &snapshot.Proposals); err != nil {
+		return nil, fmt.Errorf("decode snapshot for contract %s at ledger %d: %w", contractId, snapshot.LedgerSeq, err)
+	}
+	return snapshot, nil
+}
+
+// GetSnapshotLedger returns the ledger of the latest snapshot of any contract, or 0 if there are no snapshots
+func (store *Store) GetSnapshotLedger(ctx context.Context) (uint32, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_SNAPSHOT_LEDGER)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(ledger_seq), 0) FROM %s`, SNAPSHOTS_TABLE_NAME)
+
+	var ledgerSeq uint32
+	if err := store.queryRow(ctx, query).Scan(&ledgerSeq); err != nil {
+		return 0, fmt.Errorf("get snapshot ledger: %w", timeoutErr(ctx, err))
+	}
+	return ledgerSeq, nil
+}
+
+// GetLastEventIds returns the last event id of each contract with events emitted after afterLedger, up to and
+// including toLedger, by contract id
+func (store *Store) GetLastEventIds(ctx context.Context, afterLedger uint32, toLedger uint32) (map[string]string, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_LAST_EVENT_IDS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT contract_id, MAX(event_id)
+		FROM %s
+		WHERE ledger_seq > $1 AND ledger_seq <= $2
+		GROUP BY contract_id
+	`, HISTORY_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, afterLedger, toLedger)
+	if err != nil {
+		return nil, fmt.Errorf("get last event ids: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	eventIds := make(map[string]string)
+	for rows.Next() {
+		var contractId, eventId string
+		if err := rows.Scan(&contractId, &eventId); err != nil {
+			return nil, fmt.Errorf("get last event ids: %w", timeoutErr(ctx, err))
+		}
+		eventIds[contractId] = eventId
+		addRows(ctx, 1)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get last event ids: %w", timeoutErr(ctx, err))
+	}
+	return eventIds, nil
+}
+
+// PruneSnapshots deletes all but the latest keep snapshots of a contract, and returns the number of rows deleted
+func (store *Store) PruneSnapshots(ctx context.Context, contractId string, keep int) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_PRUNE_SNAPSHOTS)
+	defer done()
+
+	// the subquery is NULL if the contract has no more than keep snapshots, so nothing is deleted
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE contract_id = $1 AND ledger_seq <= (
+			SELECT ledger_seq FROM %[1]s WHERE contract_id = $1 ORDER BY ledger_seq DESC LIMIT 1 OFFSET $2
+		)
+	`, SNAPSHOTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, keep)
+	if err != nil {
+		return 0, fmt.Errorf("prune snapshots for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// DeleteSnapshotsByContractId deletes all snapshots for a given contract ID, and returns the number of rows deleted
+func (store *Store) DeleteSnapshotsByContractId(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_SNAPSHOTS_BY_CONTRACT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, SNAPSHOTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete snapshots for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+//********** Contract Data **********//
+
+// DeleteContractData deletes all history, failed events, failed tx events, proposals, votes, delegations, proposal
+// content, snapshots, governor settings, and votes contracts for a given contract ID, and removes the contract and its
+// metadata from the contracts registry, in a single transaction.
+// Returns the number of rows deleted per table name.
+func (store *Store) DeleteContractData(ctx context.Context, contractId string) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	err := store.WithTx(ctx, func(ctx context.Context) error {
+		count, err := store.DeleteEventsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete history: %w", err)
+		}
+		deleted[HISTORY_TABLE_NAME] = count
+
+		count, err = store.DeleteFailedEventsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete failed events: %w", err)
+		}
+		deleted[FAILED_EVENTS_TABLE_NAME] = count
+
+		count, err = store.DeleteFailedTxEventsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete failed tx events: %w", err)
+		}
+		deleted[FAILED_TX_EVENTS_TABLE_NAME] = count
+
+		count, err = store.DeleteProposalsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete proposals: %w", err)
+		}
+		deleted[PROPOSALS_TABLE_NAME] = count
+
+		count, err = store.DeleteVotesByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete votes: %w", err)
+		}
+		deleted[VOTES_TABLE_NAME] = count
+
+		count, err = store.DeleteDelegationsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete delegations: %w", err)
+		}
+		deleted[DELEGATIONS_TABLE_NAME] = count
+
+		count, err = store.DeleteProposalContentByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete proposal content: %w", err)
+		}
+		deleted[PROPOSAL_CONTENT_TABLE_NAME] = count
+
+		count, err = store.DeleteSnapshotsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete snapshots: %w", err)
+		}
+		deleted[SNAPSHOTS_TABLE_NAME] = count
+
+		count, err = store.DeleteGovernorSettings(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete governor settings: %w", err)
+		}
+		deleted[GOVERNOR_SETTINGS_TABLE_NAME] = count
+
+		count, err = store.DeleteVotesContracts(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete votes contracts: %w", err)
+		}
+		deleted[VOTES_CONTRACTS_TABLE_NAME] = count
+
+		count, err = store.deleteContractMetadata(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete contract metadata: %w", err)
+		}
+		deleted[CONTRACT_METADATA_TABLE_NAME] = count
+
+		count, err = store.DeleteContract(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("delete contract: %w", err)
+		}
+		deleted[CONTRACTS_TABLE_NAME] = count
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+//********** Contracts Table **********//
+
+const CONTRACTS_TABLE_NAME = "contracts"
+
+// REVIEWED_CONTRACT_FILTER is a condition on the contract_id column of a table, excluding the rows of registered
+// contracts that were not reviewed. Contracts that are not registered are kept.
+var REVIEWED_CONTRACT_FILTER = fmt.Sprintf(`contract_id NOT IN (SELECT contract_id FROM %s WHERE reviewed = FALSE)`, CONTRACTS_TABLE_NAME)
+
+// Contract is a governor in the contracts registry, with its most recent applied event
+type Contract struct {
+	ContractId         string
+	LastEventLedger    uint32
+	LastEventCloseTime int64
+	ProposalCount      int
+	Blocked            bool
+	// Reviewed is false for a contract registered by contract discovery that an operator has not approved yet
+	Reviewed bool
+	// Metadata is nil if no metadata was set for the contract
+	Metadata *ContractMetadata
+	// Token is nil if the contract's vote token has not been read
+	Token *VoteToken
+	// Events counts the governor events of the contract parsed by the indexer since it was registered
+	Events EventCounts
+}
+
+// EventCounts counts the governor events of a contract, which are events laid out like governor events, by whether
+// they parsed
+type EventCounts struct {
+	Seen   int64
+	Parsed int64
+	Failed int64
+}
+
+// UpsertContractActivity registers a contract, or records a more recent event for a registered contract. Events
+// older than the contract's last event, by event id, are ignored, so replaying history doesn't move it back.
+// A contract is registered as reviewed or not, and the flag of a registered contract is never changed.
+func (store *Store) UpsertContractActivity(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_CONTRACT_ACTIVITY)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (contract_id, last_event_id, last_event_ledger, last_event_close_time, reviewed)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (contract_id) DO UPDATE SET
+			last_event_id = EXCLUDED.last_event_id,
+			last_event_ledger = EXCLUDED.last_event_ledger,
+			last_event_close_time = EXCLUDED.last_event_close_time
+		WHERE %[1]s.last_event_id < EXCLUDED.last_event_id
+	`, CONTRACTS_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, contractId, eventId, ledgerSeq, ledgerCloseTime, reviewed)
+	if err != nil {
+		return fmt.Errorf("upsert activity for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// GetContractLastEventId returns the id of the most recent event applied for a registered contract, or ErrNotFound
+// if the contract is not registered
+func (store *Store) GetContractLastEventId(ctx context.Context, contractId string) (string, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_CONTRACT_LAST_EVENT_ID)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT last_event_id FROM %s WHERE contract_id = $1`, CONTRACTS_TABLE_NAME)
+
+	var eventId string
+	err := store.queryRow(ctx, query, contractId).Scan(&eventId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get last event of contract %s: %w", contractId, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get last event of contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return eventId, nil
+}
+
+// AddContractEventCounts adds to the governor event counts of a registered contract, and returns false if the
+// contract is not registered, in which case the counts are dropped
+func (store *Store) AddContractEventCounts(ctx context.Context, contractId string, counts EventCounts) (bool, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_ADD_CONTRACT_EVENT_COUNTS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET
+			events_seen = events_seen + $2,
+			events_parsed = events_parsed + $3,
+			events_failed = events_failed + $4
+		WHERE contract_id = $1
+	`, CONTRACTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, counts.Seen, counts.Parsed, counts.Failed)
+	if err != nil {
+		return false, fmt.Errorf("add event counts for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("add event counts for contract %s: %w", contractId, err)
+	}
+	return updated > 0, nil
+}
+
+// CONTRACTS_SELECT is the select list and joins of a registered contract, with its number of proposals, whether it
+// is on the blocklist, its metadata, its vote token, its event counts, and whether it was reviewed. Read with
+// contractRow.
+var CONTRACTS_SELECT = fmt.Sprintf(`
+		SELECT c.contract_id, c.last_event_ledger, c.last_event_close_time, COALESCE(p.proposal_count, 0),
+			b.contract_id IS NOT NULL, m.contract_id IS NOT NULL, COALESCE(m.name, ''), COALESCE(m.description, ''),
+			COALESCE(m.icon_url, ''), COALESCE(m.website, ''), COALESCE(m.links, ''), COALESCE(m.updated_at, 0),
+			c.token_decimals IS NOT NULL, COALESCE(c.token_symbol, ''), COALESCE(c.token_decimals, 0),
+			c.events_seen, c.events_parsed, c.events_failed, c.reviewed
+		FROM %s c
+		LEFT JOIN (SELECT contract_id, COUNT(*) AS proposal_count FROM %s GROUP BY contract_id) p ON p.contract_id = c.contract_id
+		LEFT JOIN %s b ON b.contract_id = c.contract_id
+		LEFT JOIN %s m ON m.contract_id = c.contract_id
+	`, CONTRACTS_TABLE_NAME, PROPOSALS_TABLE_NAME, BLOCKLIST_TABLE_NAME, CONTRACT_METADATA_TABLE_NAME)
+
+// contractRow is a row of CONTRACTS_SELECT
+type contractRow struct {
+	contract    Contract
+	hasMetadata bool
+	metadata    ContractMetadata
+	links       string
+	hasToken    bool
+	token       VoteToken
+}
+
+func (row *contractRow) fields() []any {
+	return []any{
+		&row.contract.ContractId, &row.contract.LastEventLedger, &row.contract.LastEventCloseTime,
+		&row.contract.ProposalCount, &row.contract.Blocked, &row.hasMetadata, &row.metadata.Name,
+		&row.metadata.Description, &row.metadata.IconUrl, &row.metadata.Website, &row.links, &row.metadata.UpdatedAt,
+		&row.hasToken, &row.token.Symbol, &row.token.Decimals,
+		&row.contract.Events.Seen, &row.contract.Events.Parsed, &row.contract.Events.Failed, &row.contract.Reviewed,
+	}
+}
+
+// toContract returns the contract of the row, with its metadata and vote token if it has them
+func (row *contractRow) toContract() (*Contract, error) {
+	contract := row.contract
+	if row.hasToken {
+		token := row.token
+		token.ContractId = contract.ContractId
+		contract.Token = &token
+	}
+	if row.hasMetadata {
+		metadata := row.metadata
+		metadata.ContractId = contract.ContractId
+		if err := json.Unmarshal([]byte(row.links), &metadata.Links); err != nil {
+			return nil, fmt.Errorf("decode links of contract %s: %w", contract.ContractId, err)
+		}
+		contract.Metadata = &metadata
+	}
+	return &contract, nil
+}
+
+// GetContracts returns the registered contracts ordered by contract id, with their number of proposals, whether
+// they are on the blocklist, their metadata, their vote token, their event counts, and whether they were reviewed
+func (store *Store) GetContracts(ctx context.Context) ([]*Contract, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_CONTRACTS)
+	defer done()
+
+	query := CONTRACTS_SELECT + `ORDER BY c.contract_id`
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get contracts: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	contractRows, err := scanRows(ctx, rows, (*contractRow).fields, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get contracts: %w", timeoutErr(ctx, err))
+	}
+	var contracts []*Contract
+	for _, row := range contractRows {
+		contract, err := row.toContract()
+		if err != nil {
+			return nil, fmt.Errorf("get contracts: %w", err)
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts, nil
+}
+
+// GetContract returns a registered contract, as described by GetContracts, or ErrNotFound if it is not registered
+func (store *Store) GetContract(ctx context.Context, contractId string) (*Contract, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_CONTRACT)
+	defer done()
+
+	query := CONTRACTS_SELECT + `WHERE c.contract_id = $1`
+
+	var row contractRow
+	err := store.queryRow(ctx, query, contractId).Scan(row.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get contract %s: %w", contractId, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	contract, err := row.toContract()
+	if err != nil {
+		return nil, fmt.Errorf("get contract %s: %w", contractId, err)
+	}
+	return contract, nil
+}
+
+// SetContractReviewed sets whether a registered contract was reviewed. Contracts that are not registered are
+// rejected with ErrNotFound.
+func (store *Store) SetContractReviewed(ctx context.Context, contractId string, reviewed bool) error {
+	ctx, done := store.writeQuery(ctx, QUERY_SET_CONTRACT_REVIEWED)
+	defer done()
+
+	query := fmt.Sprintf(`UPDATE %s SET reviewed = $2 WHERE contract_id = $1`, CONTRACTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, reviewed)
+	if err != nil {
+		return fmt.Errorf("set reviewed for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set reviewed for contract %s: %w", contractId, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("set reviewed for contract %s: %w", contractId, ErrNotFound)
+	}
+	return nil
+}
+
+// GetUnreviewedContractIds returns the ids of the registered contracts that were not reviewed, ordered by contract id
+func (store *Store) GetUnreviewedContractIds(ctx context.Context) ([]string, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_UNREVIEWED_CONTRACT_IDS)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT contract_id FROM %s WHERE reviewed = FALSE ORDER BY contract_id`, CONTRACTS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get unreviewed contracts: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	contracts, err := scanRows(ctx, rows, func(contractId *string) []any { return []any{contractId} }, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get unreviewed contracts: %w", timeoutErr(ctx, err))
+	}
+	ids := make([]string, len(contracts))
+	for i, contractId := range contracts {
+		ids[i] = *contractId
+	}
+	return ids, nil
+}
+
+// DeleteContract removes a contract from the registry, and returns the number of rows deleted
+func (store *Store) DeleteContract(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_CONTRACT)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, CONTRACTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return result.RowsAffected()
+}
+
+// VoteToken is the token a governor's votes are counted in, read from its votes contract, so vote amounts can be
+// formatted as a number of tokens
+type VoteToken struct {
+	// ContractId is the governor
+	ContractId string
+	// VotesId is the governor's votes contract. It is only set by GetVoteTokensToFetch.
+	VotesId  string
+	Symbol   string
+	Decimals uint32
+}
+
+// GetVoteTokensToFetch returns up to limit registered governors with a known votes contract whose vote token has
+// not been read, ordered by contract id
+func (store *Store) GetVoteTokensToFetch(ctx context.Context, limit int) ([]*VoteToken, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTE_TOKENS_TO_FETCH)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT c.contract_id, v.votes_id
+		FROM %s c
+		JOIN %s v ON v.governor_id = c.contract_id
+		WHERE c.token_decimals IS NULL
+		ORDER BY c.contract_id
+		LIMIT $1
+	`, CONTRACTS_TABLE_NAME, VOTES_CONTRACTS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get vote tokens to fetch: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	tokens, err := scanRows(ctx, rows, func(t *VoteToken) []any {
+		return []any{&t.ContractId, &t.VotesId}
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get vote tokens to fetch: %w", timeoutErr(ctx, err))
+	}
+	return tokens, nil
+}
+
+// SetVoteToken records the vote token of a registered contract. Contracts that are not registered are rejected with
+// ErrNotFound.
+func (store *Store) SetVoteToken(ctx context.Context, contractId string, symbol string, decimals uint32) error {
+	ctx, done := store.writeQuery(ctx, QUERY_SET_VOTE_TOKEN)
+	defer done()
+
+	query := fmt.Sprintf(`UPDATE %s SET token_symbol = $2, token_decimals = $3 WHERE contract_id = $1`, CONTRACTS_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId, symbol, decimals)
+	if err != nil {
+		return fmt.Errorf("set vote token for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set vote token for contract %s: %w", contractId, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("set vote token for contract %s: %w", contractId, ErrNotFound)
+	}
+	return nil
+}
+
+// GetVoteTokens returns the vote tokens that have been read, ordered by contract id
+func (store *Store) GetVoteTokens(ctx context.Context) ([]*VoteToken, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_VOTE_TOKENS)
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT contract_id, COALESCE(token_symbol, ''), token_decimals
+		FROM %s
+		WHERE token_decimals IS NOT NULL
+		ORDER BY contract_id
+	`, CONTRACTS_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get vote tokens: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	tokens, err := scanRows(ctx, rows, func(t *VoteToken) []any {
+		return []any{&t.ContractId, &t.Symbol, &t.Decimals}
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get vote tokens: %w", timeoutErr(ctx, err))
+	}
+	return tokens, nil
+}
+
+//********** Contract Metadata Table **********//
+
+const CONTRACT_METADATA_TABLE_NAME = "contract_metadata"
+
+// ContractMetadata describes a governor for display. Metadata can be set for any contract, including contracts that
+// are not registered yet.
+type ContractMetadata struct {
+	ContractId  string
+	Name        string
+	Description string
+	IconUrl     string
+	Website     string
+	// Links are other links, such as social media accounts, by name
+	Links map[string]string
+	// The time (in seconds since epoch) the metadata was last set
+	UpdatedAt int64
+}
+
+// UpsertContractMetadata sets the metadata of a contract. If replace is false, a contract that already has metadata
+// is left unchanged.
+func (store *Store) UpsertContractMetadata(ctx context.Context, metadata *ContractMetadata, replace bool) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UPSERT_CONTRACT_METADATA)
+	defer done()
+
+	links, err := json.Marshal(metadata.Links)
+	if err != nil {
+		return fmt.Errorf("encode links of contract %s: %w", metadata.ContractId, err)
+	}
+	conflict := `DO NOTHING`
+	if replace {
+		conflict = `DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			icon_url = EXCLUDED.icon_url,
+			website = EXCLUDED.website,
+			links = EXCLUDED.links,
+			updated_at = EXCLUDED.updated_at`
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (contract_id, name, description, icon_url, website, links, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (contract_id) %s
+	`, CONTRACT_METADATA_TABLE_NAME, conflict)
+
+	_, err = store.exec(ctx, query, metadata.ContractId, metadata.Name, metadata.Description, metadata.IconUrl,
+		metadata.Website, string(links), metadata.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("upsert metadata for contract %s: %w", metadata.ContractId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// DeleteContractMetadata deletes the metadata of a contract. Contracts without metadata are rejected with
+// ErrNotFound.
+func (store *Store) DeleteContractMetadata(ctx context.Context, contractId string) error {
+	deleted, err := store.deleteContractMetadata(ctx, contractId)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("delete metadata for contract %s: %w", contractId, ErrNotFound)
+	}
+	return nil
+}
+
+// deleteContractMetadata deletes the metadata of a contract, and returns the number of rows deleted
+func (store *Store) deleteContractMetadata(ctx context.Context, contractId string) (int64, error) {
+	ctx, done := store.writeQuery(ctx, QUERY_DELETE_CONTRACT_METADATA)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, CONTRACT_METADATA_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return 0, fmt.Errorf("delete metadata for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete metadata for contract %s: %w", contractId, err)
+	}
+	return deleted, nil
+}
+
+//********** Contract Blocklist Table **********//
+
+const BLOCKLIST_TABLE_NAME = "contract_blocklist"
+
+// UNBLOCKED_CONTRACT_FILTER is a condition on the contract_id column of a table, excluding the rows of blocked
+// contracts
+var UNBLOCKED_CONTRACT_FILTER = fmt.Sprintf(`contract_id NOT IN (SELECT contract_id FROM %s)`, BLOCKLIST_TABLE_NAME)
+
+// BlockedContract is a contract on the blocklist, whose events the indexer ignores
+type BlockedContract struct {
+	ContractId string
+	// Why the contract was blocked
+	Reason string
+	// The time (in seconds since epoch) the contract was blocked
+	CreatedAt int64
+}
+
+// BlockContract adds a contract to the blocklist. Blocking an already blocked contract is a no-op.
+func (store *Store) BlockContract(ctx context.Context, contractId string, reason string, createdAt int64) error {
+	ctx, done := store.writeQuery(ctx, QUERY_BLOCK_CONTRACT)
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (contract_id, reason, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (contract_id) DO NOTHING
+	`, BLOCKLIST_TABLE_NAME)
+
+	_, err := store.exec(ctx, query, contractId, reason, createdAt)
+	if err != nil {
+		return fmt.Errorf("block contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return nil
+}
+
+// UnblockContract removes a contract from the blocklist. Contracts that are not blocked are rejected with ErrNotFound.
+func (store *Store) UnblockContract(ctx context.Context, contractId string) error {
+	ctx, done := store.writeQuery(ctx, QUERY_UNBLOCK_CONTRACT)
+	defer done()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE contract_id = $1`, BLOCKLIST_TABLE_NAME)
+
+	result, err := store.exec(ctx, query, contractId)
+	if err != nil {
+		return fmt.Errorf("unblock contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unblock contract %s: %w", contractId, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("unblock contract %s: %w", contractId, ErrNotFound)
+	}
+	return nil
+}
+
+// GetBlockedContracts retrieves every contract on the blocklist, ordered by contract id
+func (store *Store) GetBlockedContracts(ctx context.Context) ([]*BlockedContract, error) {
+	ctx, done := store.readQuery(ctx, QUERY_GET_BLOCKED_CONTRACTS)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT contract_id, reason, created_at FROM %s ORDER BY contract_id ASC`, BLOCKLIST_TABLE_NAME)
+
+	rows, err := store.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("get blocked contracts: %w", timeoutErr(ctx, err))
+	}
+	defer rows.Close()
+
+	blocked, err := scanRows(ctx, rows, func(contract *BlockedContract) []any {
+		return []any{&contract.ContractId, &contract.Reason, &contract.CreatedAt}
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get blocked contracts: %w", timeoutErr(ctx, err))
+	}
+	return blocked, nil
+}
+
+// IsContractBlocked returns true if the contract is on the blocklist
+func (store *Store) IsContractBlocked(ctx context.Context, contractId string) (bool, error) {
+	ctx, done := store.readQuery(ctx, QUERY_IS_CONTRACT_BLOCKED)
+	defer done()
+
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE contract_id = $1)`, BLOCKLIST_TABLE_NAME)
+
+	var blocked bool
+	err := store.queryRow(ctx, query, contractId).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("check blocklist for contract %s: %w", contractId, timeoutErr(ctx, err))
+	}
+	return blocked, nil
 }
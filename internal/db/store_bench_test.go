@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// BENCH_ROWS is the number of rows returned by each list query benchmark
+const BENCH_ROWS = 50000
+
+const benchContractId = "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+
+// setupBenchStore creates a store with BENCH_ROWS votes, history events, and proposals for benchContractId
+func setupBenchStore(b *testing.B) *Store {
+	b.Helper()
+	store := setupStore(b)
+	err := store.WithTx(b.Context(), func(ctx context.Context) error {
+		for i := range BENCH_ROWS {
+			vote := &governor.Vote{
+				TxHash:          fmt.Sprintf("%064x", i),
+				ContractId:      benchContractId,
+				ProposalId:      1,
+				Voter:           fmt.Sprintf("G%055d", i),
+				Support:         uint32(i % 3),
+				Amount:          "20000000000",
+				LedgerSeq:       uint32(1170000 + i),
+				LedgerCloseTime: int64(1761053041 + i*5),
+			}
+			if err := store.InsertVote(ctx, vote); err != nil {
+				return err
+			}
+			eventId, err := governor.EncodeEventId(int64(i), 0)
+			if err != nil {
+				return err
+			}
+			event := &governor.GovernorEvent{
+				EventId:         eventId,
+				ContractId:      benchContractId,
+				EventType:       "vote_cast",
+				ProposalId:      1,
+				EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"20000000000"}`,
+				TxHash:          vote.TxHash,
+				LedgerSeq:       vote.LedgerSeq,
+				LedgerCloseTime: vote.LedgerCloseTime,
+				SchemaVersion:   1,
+			}
+			if err := store.InsertEvent(ctx, event); err != nil {
+				return err
+			}
+			proposal := &governor.Proposal{
+				ProposalKey:  governor.EncodeProposalKey(benchContractId, uint32(i)),
+				ContractId:   benchContractId,
+				ProposalId:   uint32(i),
+				Proposer:     "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Title:        "Make me security council",
+				Description:  "plz",
+				VotesFor:     "0",
+				VotesAgainst: "0",
+				VotesAbstain: "0",
+			}
+			if err := store.InsertProposal(ctx, proposal); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatalf("failed to insert bench data: %v", err)
+	}
+	return store
+}
+
+func BenchmarkListQueries(b *testing.B) {
+	store := setupBenchStore(b)
+	ctx := b.Context()
+
+	b.Run("GetVotesByProposal", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			votes, err := store.GetVotesByProposal(ctx, benchContractId, 1, Sort{})
+			if err != nil || len(votes) != BENCH_ROWS {
+				b.Fatalf("got %d votes, err %v", len(votes), err)
+			}
+		}
+	})
+	b.Run("GetEventsByContractId", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			events, err := store.GetEventsByContractId(ctx, benchContractId, Sort{})
+			if err != nil || len(events) != BENCH_ROWS {
+				b.Fatalf("got %d events, err %v", len(events), err)
+			}
+		}
+	})
+	b.Run("GetProposalsByContractId", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			proposals, err := store.GetProposalsByContractId(ctx, benchContractId, Sort{})
+			if err != nil || len(proposals) != BENCH_ROWS {
+				b.Fatalf("got %d proposals, err %v", len(proposals), err)
+			}
+		}
+	})
+}
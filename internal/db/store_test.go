@@ -1,16 +1,33 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
 	_ "modernc.org/sqlite"
 )
 
+// mustEncodeEventId encodes an event id, failing the test if it can't be encoded
+func mustEncodeEventId(t testing.TB, toid int64, eventIndex int32) string {
+	t.Helper()
+	eventId, err := governor.EncodeEventId(toid, eventIndex)
+	if err != nil {
+		t.Fatalf("failed to encode event id: %v", err)
+	}
+	return eventId
+}
+
 // setupStore creates an in-memory SQLite database for testing
-func setupStore(t *testing.T) *Store {
+func setupStore(t testing.TB) *Store {
 	t.Helper()
 
 	// Create in-memory database
@@ -44,8 +61,11 @@ func TestHistoryTable(t *testing.T) {
 			ProposalId:      3,
 			EventData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
 			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			SourceAccount:   "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
 			LedgerSeq:       1170134,
 			LedgerCloseTime: 1761053041,
+			SchemaVersion:   1,
+			EventXdr:        "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAgAAAA8AAAARcHJvcG9zYWxfY2FuY2VsZWQAAAAAAAADAAAAAwAAAAE=",
 		},
 		{
 			EventId:         "0005025695851872256-0000000001",
@@ -56,6 +76,7 @@ func TestHistoryTable(t *testing.T) {
 			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
 			LedgerSeq:       1170136,
 			LedgerCloseTime: 1761053046,
+			SchemaVersion:   1,
 		},
 		{
 			EventId:         "0005025695851872256-0000000000",
@@ -66,6 +87,7 @@ func TestHistoryTable(t *testing.T) {
 			TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
 			LedgerSeq:       1170136,
 			LedgerCloseTime: 1761053046,
+			SchemaVersion:   2,
 		},
 		{
 			EventId:         "0005025700146839602-0000000003",
@@ -76,15 +98,16 @@ func TestHistoryTable(t *testing.T) {
 			TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
 			LedgerSeq:       1170137,
 			LedgerCloseTime: 1761053050,
+			SchemaVersion:   1,
 		},
 	}
 
 	duplicateEvent := &governor.GovernorEvent{
 		EventId:         events[0].EventId,
 		ContractId:      "bad",
-		EventType:       "bad",
+		EventType:       "proposal_canceled",
 		ProposalId:      99,
-		EventData:       `bad`,
+		EventData:       `{}`,
 		TxHash:          "bad",
 		LedgerSeq:       0,
 		LedgerCloseTime: 0,
@@ -121,7 +144,7 @@ func TestHistoryTable(t *testing.T) {
 	}
 
 	// test get events by contract id
-	retrievedEvents, err := store.GetEventsByContractId(ctx, events[1].ContractId)
+	retrievedEvents, err := store.GetEventsByContractId(ctx, events[1].ContractId, Sort{})
 	if err != nil {
 		t.Fatalf("failed to get events by contract id: %v", err)
 	}
@@ -134,6 +157,405 @@ func TestHistoryTable(t *testing.T) {
 	if diff := cmp.Diff(events[1], retrievedEvents[1]); diff != "" {
 		t.Errorf("check 3b: mismatch (-want +got):\n%s", diff)
 	}
+
+	// test get recent events across contracts
+	recentEvents, err := store.GetRecentEvents(ctx, "", 3)
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[3], events[1], events[2]}, recentEvents); diff != "" {
+		t.Errorf("check 4a: mismatch (-want +got):\n%s", diff)
+	}
+	recentEvents, err = store.GetRecentEvents(ctx, "proposal_created", 3)
+	if err != nil {
+		t.Fatalf("failed to get recent events by type: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[0]}, recentEvents); diff != "" {
+		t.Errorf("check 4b: mismatch (-want +got):\n%s", diff)
+	}
+
+	// test paging through events in event order
+	pagedEvents, err := store.GetEventsAfter(ctx, "", events[0].EventId, 2)
+	if err != nil {
+		t.Fatalf("failed to get events after: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[2], events[1]}, pagedEvents); diff != "" {
+		t.Errorf("check 4c: mismatch (-want +got):\n%s", diff)
+	}
+	pagedEvents, err = store.GetEventsAfter(ctx, events[0].ContractId, "", 10)
+	if err != nil {
+		t.Fatalf("failed to get events after by contract id: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[0], events[3]}, pagedEvents); diff != "" {
+		t.Errorf("check 4d: mismatch (-want +got):\n%s", diff)
+	}
+
+	// test get events by proposal
+	proposalEvents, err := store.GetEventsByProposal(ctx, events[1].ContractId, 2)
+	if err != nil {
+		t.Fatalf("failed to get events by proposal: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[2], events[1]}, proposalEvents); diff != "" {
+		t.Errorf("check 5: mismatch (-want +got):\n%s", diff)
+	}
+
+	// test get events by transaction hash, across contracts
+	txEvents, err := store.GetEventsByTxHash(ctx, events[0].TxHash)
+	if err != nil {
+		t.Fatalf("failed to get events by tx hash: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{events[0], events[1]}, txEvents); diff != "" {
+		t.Errorf("check 5b: mismatch (-want +got):\n%s", diff)
+	}
+	txEvents, err = store.GetEventsByTxHash(ctx, "unknown")
+	if err != nil {
+		t.Fatalf("failed to get events by tx hash: %v", err)
+	}
+	if len(txEvents) != 0 {
+		t.Errorf("check 5c: got %d events for an unknown tx hash, want 0", len(txEvents))
+	}
+
+	// test backfilling event xdr, which only sets missing xdr
+	from, to, err := store.GetMissingEventXdrRange(ctx)
+	if err != nil {
+		t.Fatalf("failed to get missing event xdr range: %v", err)
+	}
+	if from != 1170136 || to != 1170137 {
+		t.Errorf("check 6a: missing event xdr range = %d to %d, want 1170136 to 1170137", from, to)
+	}
+	for _, tt := range []struct {
+		eventId     string
+		wantUpdated bool
+	}{
+		{eventId: events[0].EventId, wantUpdated: false},
+		{eventId: events[1].EventId, wantUpdated: true},
+		{eventId: events[1].EventId, wantUpdated: false},
+		{eventId: "0000000000000000000-0000000000", wantUpdated: false},
+	} {
+		updated, err := store.SetEventXdr(ctx, tt.eventId, "backfilled")
+		if err != nil {
+			t.Fatalf("failed to set event xdr: %v", err)
+		}
+		if updated != tt.wantUpdated {
+			t.Errorf("check 6b: set event xdr of %s updated = %v, want %v", tt.eventId, updated, tt.wantUpdated)
+		}
+	}
+	retrieved, err = store.GetEvent(ctx, events[1].EventId)
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	if retrieved.EventXdr != "backfilled" {
+		t.Errorf("check 6c: event xdr = %q, want backfilled", retrieved.EventXdr)
+	}
+	retrieved, err = store.GetEvent(ctx, events[0].EventId)
+	if err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	if retrieved.EventXdr != events[0].EventXdr {
+		t.Errorf("check 6d: event xdr = %q, want it unchanged", retrieved.EventXdr)
+	}
+}
+
+func TestInsertEvents(t *testing.T) {
+	// the number of events inserted by each statement
+	chunk := SQLITE_MAX_QUERY_PARAMS / len(historyArgs(&governor.GovernorEvent{}))
+	for _, count := range []int{0, 1, chunk - 1, chunk, chunk + 1, 2*chunk + 1} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			store := setupStore(t)
+			ctx := t.Context()
+
+			var events []*governor.GovernorEvent
+			for i := range count {
+				events = append(events, &governor.GovernorEvent{
+					EventId:         mustEncodeEventId(t, int64(i), 0),
+					ContractId:      "contract_123",
+					ProposalId:      1,
+					EventType:       "vote_cast",
+					EventData:       fmt.Sprintf(`{"voter":"user_%03d","support":1,"amount":"1000"}`, i),
+					TxHash:          fmt.Sprintf("tx_%03d", i),
+					LedgerSeq:       5000,
+					LedgerCloseTime: 1761053046,
+					SchemaVersion:   governor.SCHEMA_V1,
+					EventXdr:        "AAAA",
+				})
+			}
+			// events without xdr are stored without it
+			if count > 1 {
+				events[1].EventXdr = ""
+			}
+
+			// events already inserted, including the last of a chunk, are left unchanged
+			want := slices.Clone(events)
+			for _, i := range []int{0, chunk} {
+				if i >= count {
+					continue
+				}
+				existing := *events[i]
+				existing.EventData = "{}"
+				if err := store.InsertEvent(ctx, &existing); err != nil {
+					t.Fatalf("failed to insert event: %v", err)
+				}
+				want[i] = &existing
+			}
+			if err := store.InsertEvents(ctx, events); err != nil {
+				t.Fatalf("failed to insert events: %v", err)
+			}
+
+			retrievedEvents, err := store.GetEventsAfter(ctx, "", "", 1000)
+			if err != nil {
+				t.Fatalf("failed to get events: %v", err)
+			}
+			if diff := cmp.Diff(want, retrievedEvents); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInsertEventInvalidData(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	valid := &governor.GovernorEvent{
+		EventId:    mustEncodeEventId(t, 1, 0),
+		ContractId: "contract_123",
+		ProposalId: 1,
+		EventType:  "vote_cast",
+		EventData:  `{"voter":"user_001","support":1,"amount":"1000"}`,
+	}
+	invalid := &governor.GovernorEvent{
+		EventId:    mustEncodeEventId(t, 2, 0),
+		ContractId: "contract_123",
+		ProposalId: 1,
+		EventType:  "vote_cast",
+		EventData:  `{"voter":"user_002","support":1,"amount":1000}`,
+	}
+
+	if err := store.InsertEvent(ctx, invalid); !errors.Is(err, governor.ErrInvalidEventData) {
+		t.Errorf("InsertEvent() error = %v, want ErrInvalidEventData", err)
+	}
+	// the batch is rejected, so the valid event isn't stored either
+	if err := store.InsertEvents(ctx, []*governor.GovernorEvent{valid, invalid}); !errors.Is(err, governor.ErrInvalidEventData) {
+		t.Errorf("InsertEvents() error = %v, want ErrInvalidEventData", err)
+	}
+	for _, event := range []*governor.GovernorEvent{valid, invalid} {
+		if _, err := store.GetEvent(ctx, event.EventId); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetEvent(%s) error = %v, want ErrNotFound", event.EventId, err)
+		}
+	}
+}
+
+func TestFailedEventsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	events := []*governor.FailedEvent{
+		{
+			EventId:         "0005025695851872256-0000000001",
+			ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+			EventType:       "vote_cast",
+			Reason:          governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+			Error:           "schema version 3: unknown governor event schema version",
+			EventXdr:        "AAAA",
+			TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
+			LedgerSeq:       1170136,
+			LedgerCloseTime: 1761053046,
+		},
+		{
+			EventId:         "0005025687261941760-0000000000",
+			ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+			EventType:       "proposal_created",
+			Reason:          governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+			Error:           "schema version 4: unknown governor event schema version",
+			EventXdr:        "BBBB",
+			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+		},
+		{
+			EventId:         "0005025687261941760-0000000001",
+			ContractId:      "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+			EventType:       "vote_cast",
+			Reason:          governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+			Error:           "schema version 3: unknown governor event schema version",
+			EventXdr:        "CCCC",
+			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+		},
+	}
+	for _, event := range events {
+		if err := store.InsertFailedEvent(ctx, event); err != nil {
+			t.Fatalf("failed to insert failed event: %v", err)
+		}
+	}
+
+	// inserting the same event again does nothing
+	duplicate := *events[0]
+	duplicate.Error = "bad"
+	if err := store.InsertFailedEvent(ctx, &duplicate); err != nil {
+		t.Fatalf("failed to insert duplicate failed event: %v", err)
+	}
+
+	got, err := store.GetFailedEventsByContractId(ctx, events[0].ContractId)
+	if err != nil {
+		t.Fatalf("failed to get failed events: %v", err)
+	}
+	want := []*governor.FailedEvent{events[1], events[0]}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// reprocessing state
+	if err := store.MarkFailedEventProcessed(ctx, events[1].EventId, 1761060000); err != nil {
+		t.Fatalf("failed to mark failed event processed: %v", err)
+	}
+	if err := store.RecordFailedEventAttempt(ctx, events[2].EventId, "schema version 3: 100% unknown"); err != nil {
+		t.Fatalf("failed to record failed event attempt: %v", err)
+	}
+	if err := store.MarkFailedEventProcessed(ctx, "0000000000000000000-0000000000", 1761060000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MarkFailedEventProcessed() for a missing event error = %v, want ErrNotFound", err)
+	}
+	if err := store.RecordFailedEventAttempt(ctx, "0000000000000000000-0000000000", "bad"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RecordFailedEventAttempt() for a missing event error = %v, want ErrNotFound", err)
+	}
+	processed, attempted := *events[1], *events[2]
+	processed.ProcessedAt = 1761060000
+	attempted.Attempts = 1
+	attempted.Error = "schema version 3: 100% unknown"
+
+	tests := []struct {
+		name   string
+		filter FailedEventFilter
+		after  string
+		limit  int
+		want   []*governor.FailedEvent
+		// count is the number of failed events matching the filter, on every page
+		count int
+	}{
+		{name: "all", limit: 10, want: []*governor.FailedEvent{&processed, &attempted, events[0]}, count: 3},
+		{name: "page", limit: 2, want: []*governor.FailedEvent{&processed, &attempted}, count: 3},
+		{name: "next page", after: attempted.EventId, limit: 2, want: []*governor.FailedEvent{events[0]}, count: 3},
+		{name: "contract", filter: FailedEventFilter{ContractId: events[0].ContractId}, limit: 10, want: []*governor.FailedEvent{&processed, events[0]}, count: 2},
+		{name: "reason", filter: FailedEventFilter{Reason: governor.FAILED_REASON_INVALID_AMOUNT}, limit: 10, count: 0},
+		{name: "error", filter: FailedEventFilter{ErrorContains: "version 3"}, limit: 10, want: []*governor.FailedEvent{&attempted, events[0]}, count: 2},
+		// the substring is matched literally
+		{name: "error with wildcard", filter: FailedEventFilter{ErrorContains: "100%"}, limit: 10, want: []*governor.FailedEvent{&attempted}, count: 1},
+		{name: "error with no wildcard match", filter: FailedEventFilter{ErrorContains: "version_3"}, limit: 10, count: 0},
+		{name: "unprocessed", filter: FailedEventFilter{Unprocessed: true}, limit: 10, want: []*governor.FailedEvent{&attempted, events[0]}, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetFailedEvents(ctx, tt.filter, tt.after, tt.limit)
+			if err != nil {
+				t.Fatalf("failed to get failed events: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			count, err := store.CountFailedEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("failed to count failed events: %v", err)
+			}
+			if count != tt.count {
+				t.Errorf("got count %d, want %d", count, tt.count)
+			}
+		})
+	}
+}
+
+func TestFailedTxEventsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	events := []*governor.FailedTxEvent{
+		{
+			EventId:         "0005025695851872256-0000000000",
+			ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+			ProposalId:      3,
+			EventType:       "vote_cast",
+			EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"100"}`,
+			TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
+			SourceAccount:   "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			ResultCode:      "TxFailed",
+			LedgerSeq:       1170136,
+			LedgerCloseTime: 1761053046,
+			EventXdr:        "AAAA",
+		},
+		{
+			EventId:         "0005025687261941760-0000000000",
+			ContractId:      "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+			ProposalId:      1,
+			EventType:       "vote_cast",
+			EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"5"}`,
+			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			SourceAccount:   "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			ResultCode:      "TxFailed",
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+			EventXdr:        "BBBB",
+		},
+	}
+	for _, event := range events {
+		if err := store.InsertFailedTxEvent(ctx, event); err != nil {
+			t.Fatalf("failed to insert failed tx event: %v", err)
+		}
+	}
+	// inserting the same event again does nothing
+	duplicate := *events[0]
+	duplicate.ResultCode = "TxBadSeq"
+	if err := store.InsertFailedTxEvent(ctx, &duplicate); err != nil {
+		t.Fatalf("failed to insert duplicate failed tx event: %v", err)
+	}
+
+	// the second event's contract was registered by contract discovery and not reviewed
+	if err := store.UpsertContractActivity(ctx, events[1].ContractId, events[1].EventId, events[1].LedgerSeq, events[1].LedgerCloseTime, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		contractId string
+		unreviewed bool
+		after      string
+		limit      int
+		want       []*governor.FailedTxEvent
+		count      int
+	}{
+		{name: "all", unreviewed: true, limit: 10, want: []*governor.FailedTxEvent{events[1], events[0]}, count: 2},
+		{name: "page", unreviewed: true, limit: 1, want: []*governor.FailedTxEvent{events[1]}, count: 2},
+		{name: "next page", unreviewed: true, after: events[1].EventId, limit: 1, want: []*governor.FailedTxEvent{events[0]}, count: 2},
+		{name: "contract", contractId: events[0].ContractId, limit: 10, want: []*governor.FailedTxEvent{events[0]}, count: 1},
+		{name: "reviewed", limit: 10, want: []*governor.FailedTxEvent{events[0]}, count: 1},
+		{name: "unreviewed contract", contractId: events[1].ContractId, limit: 10, want: nil, count: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetFailedTxEvents(ctx, tt.contractId, tt.unreviewed, tt.after, tt.limit)
+			if err != nil {
+				t.Fatalf("failed to get failed tx events: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			count, err := store.CountFailedTxEvents(ctx, tt.contractId, tt.unreviewed)
+			if err != nil {
+				t.Fatalf("failed to count failed tx events: %v", err)
+			}
+			if count != tt.count {
+				t.Errorf("got count %d, want %d", count, tt.count)
+			}
+		})
+	}
+
+	// failed tx events are never part of the event history
+	history, err := store.GetEventsByContractId(ctx, events[0].ContractId, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected no history events, got %d", len(history))
+	}
 }
 
 func TestStatusTable(t *testing.T) {
@@ -189,6 +611,134 @@ func TestStatusTable(t *testing.T) {
 	if timestamp != 2345678 {
 		t.Errorf("expected initial ledger_close_time 2345678, got %d", timestamp)
 	}
+
+	// the indexer status has no state
+	status, err := store.GetSourceStatus(ctx, source)
+	if err != nil {
+		t.Fatalf("GetSourceStatus() error = %v", err)
+	}
+	if diff := cmp.Diff(&SourceStatus{LedgerSeq: 2000, LedgerCloseTime: 2345678}, status); diff != "" {
+		t.Errorf("GetSourceStatus() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := store.GetSourceStatus(ctx, "captive_core"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSourceStatus() missing source error = %v, want ErrNotFound", err)
+	}
+	for _, want := range []*SourceStatus{
+		{LedgerSeq: 100, LedgerCloseTime: 1000, State: "Catching up", UpdatedAt: 1761053046},
+		{LedgerSeq: 150, LedgerCloseTime: 1250, State: "Synced!", UpdatedAt: 1761053056},
+	} {
+		if err := store.UpsertSourceStatus(ctx, "captive_core", *want); err != nil {
+			t.Fatalf("UpsertSourceStatus() error = %v", err)
+		}
+		got, err := store.GetSourceStatus(ctx, "captive_core")
+		if err != nil {
+			t.Fatalf("GetSourceStatus() error = %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetSourceStatus() mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestIndexerErrorsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	indexerErrors, err := store.GetIndexerErrors(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetIndexerErrors() error = %v", err)
+	}
+	if len(indexerErrors) != 0 {
+		t.Errorf("expected no indexer errors, got %d", len(indexerErrors))
+	}
+
+	// only the latest 3 errors are kept
+	var want []*IndexerError
+	for i := range 5 {
+		indexerError := &IndexerError{LedgerSeq: uint32(1000 + i), TxHash: fmt.Sprintf("tx_%d", i), Message: fmt.Sprintf("error %d", i), CreatedAt: int64(1761053046 + i)}
+		if err := store.InsertIndexerError(ctx, indexerError, 3); err != nil {
+			t.Fatalf("InsertIndexerError() error = %v", err)
+		}
+		recorded := *indexerError
+		recorded.Seq = int64(i + 1)
+		want = append([]*IndexerError{&recorded}, want...)
+	}
+	indexerErrors, err = store.GetIndexerErrors(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetIndexerErrors() error = %v", err)
+	}
+	if diff := cmp.Diff(want[:3], indexerErrors); diff != "" {
+		t.Errorf("GetIndexerErrors() mismatch (-want +got):\n%s", diff)
+	}
+	indexerErrors, err = store.GetIndexerErrors(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetIndexerErrors() error = %v", err)
+	}
+	if diff := cmp.Diff(want[:2], indexerErrors); diff != "" {
+		t.Errorf("GetIndexerErrors() limited mismatch (-want +got):\n%s", diff)
+	}
+
+	// a smaller size drops the older errors on the next insert, and errors of a whole ledger have no tx hash
+	if err := store.InsertIndexerError(ctx, &IndexerError{LedgerSeq: 1005, Message: "failed to read ledger", CreatedAt: 1761053051}, 1); err != nil {
+		t.Fatalf("InsertIndexerError() error = %v", err)
+	}
+	indexerErrors, err = store.GetIndexerErrors(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetIndexerErrors() error = %v", err)
+	}
+	if diff := cmp.Diff([]*IndexerError{{Seq: 6, LedgerSeq: 1005, Message: "failed to read ledger", CreatedAt: 1761053051}}, indexerErrors); diff != "" {
+		t.Errorf("GetIndexerErrors() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIngestionProgressTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	if _, err := store.GetIngestionProgress(ctx, "indexer"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIngestionProgress() before any progress error = %v, want ErrNotFound", err)
+	}
+	progress := &IngestionProgress{Source: "indexer", StartLedger: 1000, Ledger: 1249, TargetLedger: 1999, Ledgers: 250, Events: 12, LedgersPerSecond: 12.5, EventsPerSecond: 0.75, StartedAt: 1761053000, UpdatedAt: 1761053020}
+	for _, ledger := range []uint32{1099, 1249} {
+		progress.Ledger, progress.Ledgers = ledger, int64(ledger-progress.StartLedger+1)
+		if err := store.UpsertIngestionProgress(ctx, progress); err != nil {
+			t.Fatalf("UpsertIngestionProgress() error = %v", err)
+		}
+	}
+	got, err := store.GetIngestionProgress(ctx, "indexer")
+	if err != nil {
+		t.Fatalf("GetIngestionProgress() error = %v", err)
+	}
+	if diff := cmp.Diff(progress, got); diff != "" {
+		t.Errorf("GetIngestionProgress() mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name        string
+		progress    IngestionProgress
+		wantPercent float64
+		wantEta     time.Duration
+		wantOk      bool
+	}{
+		{name: "in progress", progress: *progress, wantPercent: 25, wantEta: 60 * time.Second, wantOk: true},
+		{name: "unknown target", progress: IngestionProgress{StartLedger: 1000, Ledger: 1249, LedgersPerSecond: 12.5}},
+		{name: "complete", progress: IngestionProgress{StartLedger: 1000, Ledger: 2000, TargetLedger: 2000}, wantPercent: 100, wantOk: true},
+	}
+	for _, tt := range tests {
+		percent, ok := tt.progress.PercentComplete()
+		if percent != tt.wantPercent || ok != tt.wantOk {
+			t.Errorf("%s: PercentComplete() = %v, %t, want %v, %t", tt.name, percent, ok, tt.wantPercent, tt.wantOk)
+		}
+		eta, ok := tt.progress.ETA()
+		if eta != tt.wantEta || ok != tt.wantOk {
+			t.Errorf("%s: ETA() = %v, %t, want %v, %t", tt.name, eta, ok, tt.wantEta, tt.wantOk)
+		}
+	}
+	stalled := IngestionProgress{StartLedger: 1000, Ledger: 1249, TargetLedger: 2000}
+	if _, ok := stalled.ETA(); ok {
+		t.Errorf("ETA() without a recent rate = true, want false")
+	}
 }
 
 func TestProposalsTable(t *testing.T) {
@@ -212,6 +762,10 @@ func TestProposalsTable(t *testing.T) {
 			VotesAbstain:    "0",
 			ExecutionUnlock: 0,
 			ExecutionTxHash: "",
+			CreatedLedger:   900,
+			UpdatedLedger:   900,
+			UpdatedEventId:  "0000003865470566400-0000000000",
+			UpdatedAt:       1761053041,
 		},
 		{
 			ProposalKey:     "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC-1",
@@ -231,21 +785,31 @@ func TestProposalsTable(t *testing.T) {
 			ExecutionTxHash: "",
 		},
 		{
-			ProposalKey:     "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB-0",
-			ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
-			ProposalId:      0,
-			Proposer:        "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
-			Status:          1,
-			Title:           "Teapot",
-			Description:     "Is a teapot",
-			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
-			VoteStart:       400,
-			VoteEnd:         800,
-			VotesFor:        "1212341314",
-			VotesAgainst:    "94895",
-			VotesAbstain:    "8234",
-			ExecutionUnlock: 12300,
-			ExecutionTxHash: "",
+			ProposalKey:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB-0",
+			ContractId:       "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+			ProposalId:       0,
+			Proposer:         "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
+			Status:           1,
+			Title:            "Teapot",
+			Description:      "Is a teapot",
+			Action:           "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:       governor.ACTION_TYPE_CALLDATA,
+			ActionContractId: "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+			ActionFunction:   "transfer",
+			VoteStart:        400,
+			VoteEnd:          800,
+			VotesFor:         "1212341314",
+			VotesAgainst:     "94895",
+			VotesAbstain:     "8234",
+			ExecutionUnlock:  12300,
+			ExecutionTxHash:  "",
+			Truncated:        true,
+			CreatedLedger:    350,
+			CreatedTxHash:    "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			SourceAccount:    "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			UpdatedLedger:    810,
+			UpdatedEventId:   "0000003478923509760-0000000001",
+			UpdatedAt:        1761053046,
 		},
 	}
 
@@ -282,6 +846,10 @@ func TestProposalsTable(t *testing.T) {
 		VotesAbstain:    "2000",
 		ExecutionUnlock: 4000,
 		ExecutionTxHash: "pretend_tx_hash",
+		CreatedLedger:   99,
+		UpdatedLedger:   3000,
+		UpdatedEventId:  "0000012884901888000-0000000000",
+		UpdatedAt:       1761053050,
 	}
 	expectedProposal0 := &governor.Proposal{
 		ProposalKey:     proposals[0].ProposalKey,
@@ -299,6 +867,10 @@ func TestProposalsTable(t *testing.T) {
 		VotesAbstain:    newProposal0.VotesAbstain,
 		ExecutionUnlock: newProposal0.ExecutionUnlock,
 		ExecutionTxHash: newProposal0.ExecutionTxHash,
+		CreatedLedger:   proposals[0].CreatedLedger,
+		UpdatedLedger:   newProposal0.UpdatedLedger,
+		UpdatedEventId:  newProposal0.UpdatedEventId,
+		UpdatedAt:       newProposal0.UpdatedAt,
 	}
 	err = store.UpsertProposal(ctx, newProposal0)
 	if err != nil {
@@ -313,7 +885,7 @@ func TestProposalsTable(t *testing.T) {
 	}
 
 	// Verify get proposals by contract id
-	retrievedProposals, err := store.GetProposalsByContractId(ctx, proposals[1].ContractId)
+	retrievedProposals, err := store.GetProposalsByContractId(ctx, proposals[1].ContractId, Sort{})
 	if err != nil {
 		t.Fatalf("failed to get proposals by contract id: %v", err)
 	}
@@ -326,58 +898,519 @@ func TestProposalsTable(t *testing.T) {
 	if diff := cmp.Diff(expectedProposal0, retrievedProposals[1]); diff != "" {
 		t.Errorf("check 3b: mismatch (-want +got):\n%s", diff)
 	}
+
+	// verify EachProposalByContractId streams the same proposals
+	var streamedProposals []*governor.Proposal
+	err = store.EachProposalByContractId(ctx, proposals[1].ContractId, Sort{}, func(proposal *governor.Proposal) error {
+		streamedProposals = append(streamedProposals, proposal)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to stream proposals by contract id: %v", err)
+	}
+	if diff := cmp.Diff(retrievedProposals, streamedProposals); diff != "" {
+		t.Errorf("check 3c: mismatch (-want +got):\n%s", diff)
+	}
 }
 
-func TestVotesTable(t *testing.T) {
+func TestGetProposalsByStatus(t *testing.T) {
 	store := setupStore(t)
 	ctx := t.Context()
 
-	contractId := "contract_123"
-	proposalId := uint32(1)
-
-	// Insert multiple votes
-	votes := []*governor.Vote{
-		{
-			TxHash:          "tx_vote_001",
-			ContractId:      contractId,
-			ProposalId:      proposalId,
-			Voter:           "user_abc",
-			Support:         1,
-			Amount:          "1000",
-			LedgerSeq:       5000,
-			LedgerCloseTime: 1761053046,
-		},
-		{
-			TxHash:          "tx_vote_002",
-			ContractId:      contractId,
-			ProposalId:      proposalId,
-			Voter:           "user_def",
-			Support:         2,
-			Amount:          "500",
-			LedgerSeq:       5100,
-			LedgerCloseTime: 1761054046,
-		},
-		{
-			TxHash:          "tx_vote_003",
-			ContractId:      contractId,
-			ProposalId:      2, // Different proposal
-			Voter:           "user_ghi",
-			Support:         3,
-			Amount:          "750",
-			LedgerSeq:       5200,
-			LedgerCloseTime: 1761055046,
-		},
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherContractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	newProposal := func(contractId string, proposalId uint32, status uint32, voteEnd uint32) *governor.Proposal {
+		return &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(contractId, proposalId),
+			ContractId:   contractId,
+			ProposalId:   proposalId,
+			Status:       status,
+			VoteEnd:      voteEnd,
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
 	}
-
-	for _, vote := range votes {
-		if err := store.InsertVote(ctx, vote); err != nil {
-			t.Fatalf("failed to insert vote: %v", err)
+	proposals := []*governor.Proposal{
+		newProposal(contractId, 1, 0, 3000),
+		newProposal(contractId, 2, 1, 1000),
+		newProposal(contractId, 3, 2, 500),
+		newProposal(otherContractId, 1, 0, 2000),
+		newProposal(otherContractId, 2, 0, 3000),
+	}
+	proposals[1].FlaggedLowParticipation = true
+	for _, proposal := range proposals {
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
 		}
 	}
+	if err := store.UpsertContractActivity(ctx, otherContractId, mustEncodeEventId(t, 1000<<32, 0), 1000, 1761051500, false); err != nil {
+		t.Fatalf("failed to upsert contract activity: %v", err)
+	}
 
-	// test GetVote
-	retrievedVote, err := store.GetVote(ctx, votes[1].TxHash)
-	if err != nil {
+	tests := []struct {
+		name              string
+		statuses          []uint32
+		excludeFlagged    bool
+		excludeUnreviewed bool
+		limit             int
+		cursor            string
+		want              []*governor.Proposal
+	}{
+		{name: "open", statuses: []uint32{0}, limit: 10, want: []*governor.Proposal{proposals[3], proposals[0], proposals[4]}},
+		{name: "open or queued", statuses: []uint32{0, 1}, limit: 10, want: []*governor.Proposal{proposals[1], proposals[3], proposals[0], proposals[4]}},
+		{name: "first page", statuses: []uint32{0, 1}, limit: 2, want: []*governor.Proposal{proposals[1], proposals[3]}},
+		{name: "next page", statuses: []uint32{0, 1}, limit: 2, cursor: EncodeProposalCursor(proposals[3]), want: []*governor.Proposal{proposals[0], proposals[4]}},
+		{name: "next page within vote_end", statuses: []uint32{0}, limit: 2, cursor: EncodeProposalCursor(proposals[0]), want: []*governor.Proposal{proposals[4]}},
+		{name: "open or queued without flagged", statuses: []uint32{0, 1}, excludeFlagged: true, limit: 10, want: []*governor.Proposal{proposals[3], proposals[0], proposals[4]}},
+		{name: "open without unreviewed", statuses: []uint32{0}, excludeUnreviewed: true, limit: 10, want: []*governor.Proposal{proposals[0]}},
+		{name: "no statuses", limit: 10},
+	}
+	for _, tt := range tests {
+		got, err := store.GetProposalsByStatus(ctx, tt.statuses, !tt.excludeFlagged, !tt.excludeUnreviewed, tt.limit, tt.cursor)
+		if err != nil {
+			t.Fatalf("%s: failed to get proposals by status: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	_, err := store.GetProposalsByStatus(ctx, []uint32{0}, true, true, 10, "bad")
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	ending := []struct {
+		from uint32
+		to   uint32
+		want []*governor.Proposal
+	}{
+		{from: 0, to: 5000, want: []*governor.Proposal{proposals[3], proposals[0], proposals[4]}},
+		{from: 2000, to: 2999, want: []*governor.Proposal{proposals[3]}},
+		{from: 2001, to: 3000, want: []*governor.Proposal{proposals[0], proposals[4]}},
+		{from: 500, to: 1999},
+	}
+	for _, tt := range ending {
+		got, err := store.GetProposalsEndingBetween(ctx, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("failed to get proposals ending between %d and %d: %v", tt.from, tt.to, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ending between %d and %d: mismatch (-want +got):\n%s", tt.from, tt.to, diff)
+		}
+	}
+
+	counts := []struct {
+		statuses          []uint32
+		excludeFlagged    bool
+		excludeUnreviewed bool
+		want              int
+	}{
+		{statuses: []uint32{0}, want: 3},
+		{statuses: []uint32{0, 1}, want: 4},
+		{statuses: []uint32{0, 1}, excludeFlagged: true, want: 3},
+		{statuses: []uint32{0, 1}, excludeUnreviewed: true, want: 2},
+		{statuses: []uint32{5}, want: 0},
+		{want: 0},
+	}
+	for _, tt := range counts {
+		got, err := store.CountProposalsByStatus(ctx, tt.statuses, !tt.excludeFlagged, !tt.excludeUnreviewed)
+		if err != nil {
+			t.Fatalf("failed to count proposals by status %v: %v", tt.statuses, err)
+		}
+		if got != tt.want {
+			t.Errorf("count of statuses %v = %d, want %d", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestGetExecutableProposals(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	newProposal := func(proposalId uint32, status uint32, executionUnlock uint32) *governor.Proposal {
+		return &governor.Proposal{
+			ProposalKey:     governor.EncodeProposalKey(contractId, proposalId),
+			ContractId:      contractId,
+			ProposalId:      proposalId,
+			Status:          status,
+			ExecutionUnlock: executionUnlock,
+			VotesFor:        "0",
+			VotesAgainst:    "0",
+			VotesAbstain:    "0",
+		}
+	}
+	proposals := []*governor.Proposal{
+		newProposal(1, 1, 2000),
+		newProposal(2, 1, 1000),
+		newProposal(3, 4, 500),
+		newProposal(4, 1, 0),
+		newProposal(5, 3, 800),
+	}
+	for _, proposal := range proposals {
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+	}
+
+	tests := []struct {
+		ledger uint32
+		want   []*governor.Proposal
+	}{
+		{ledger: 999},
+		{ledger: 1000, want: []*governor.Proposal{proposals[1]}},
+		{ledger: 5000, want: []*governor.Proposal{proposals[1], proposals[0]}},
+	}
+	for _, tt := range tests {
+		got, err := store.GetExecutableProposals(ctx, tt.ledger)
+		if err != nil {
+			t.Fatalf("failed to get proposals executable by %d: %v", tt.ledger, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("executable by %d: mismatch (-want +got):\n%s", tt.ledger, diff)
+		}
+	}
+}
+
+func TestGetProposalsByKeys(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherContractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	var proposals []*governor.Proposal
+	for _, key := range []struct {
+		contractId string
+		proposalId uint32
+	}{{contractId, 1}, {contractId, 7}, {otherContractId, 2}} {
+		proposal := &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(key.contractId, key.proposalId),
+			ContractId:   key.contractId,
+			ProposalId:   key.proposalId,
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
+		if err := store.InsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		want []*governor.Proposal
+	}{
+		{
+			name: "request order",
+			keys: []string{proposals[2].ProposalKey, proposals[0].ProposalKey, proposals[1].ProposalKey},
+			want: []*governor.Proposal{proposals[2], proposals[0], proposals[1]},
+		},
+		{
+			name: "missing and duplicate keys",
+			keys: []string{proposals[1].ProposalKey, governor.EncodeProposalKey(contractId, 2), proposals[1].ProposalKey},
+			want: []*governor.Proposal{proposals[1]},
+		},
+		{name: "no keys"},
+	}
+	for _, tt := range tests {
+		got, err := store.GetProposalsByKeys(ctx, tt.keys)
+		if err != nil {
+			t.Fatalf("%s: failed to get proposals by keys: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestListSort(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	newProposal := func(proposalId uint32, status uint32, voteEnd uint32) *governor.Proposal {
+		return &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(contractId, proposalId),
+			ContractId:   contractId,
+			ProposalId:   proposalId,
+			Status:       status,
+			VoteEnd:      voteEnd,
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
+	}
+	proposals := []*governor.Proposal{
+		newProposal(1, 2, 3000),
+		newProposal(2, 0, 1000),
+		newProposal(3, 0, 2000),
+	}
+	for _, proposal := range proposals {
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+	}
+
+	newVote := func(txHash string, amount string, ledgerSeq uint32) *governor.Vote {
+		return &governor.Vote{
+			TxHash:     txHash,
+			ContractId: contractId,
+			ProposalId: 1,
+			Voter:      "voter_" + txHash,
+			Support:    1,
+			Amount:     amount,
+			LedgerSeq:  ledgerSeq,
+		}
+	}
+	// amounts of different lengths, which sort differently as text
+	votes := []*governor.Vote{
+		newVote("tx_a", "900", 100),
+		newVote("tx_b", "10000000000000000000000000000", 101),
+		newVote("tx_c", "1000", 102),
+		newVote("tx_d", "900", 103),
+	}
+	for _, vote := range votes {
+		if err := store.InsertVote(ctx, vote); err != nil {
+			t.Fatalf("failed to insert vote: %v", err)
+		}
+	}
+
+	proposalTests := []struct {
+		name string
+		sort Sort
+		want []*governor.Proposal
+	}{
+		{name: "default", want: []*governor.Proposal{proposals[2], proposals[1], proposals[0]}},
+		{name: "proposal_id asc", sort: Sort{Field: "proposal_id"}, want: []*governor.Proposal{proposals[0], proposals[1], proposals[2]}},
+		{name: "vote_end asc", sort: Sort{Field: "vote_end"}, want: []*governor.Proposal{proposals[1], proposals[2], proposals[0]}},
+		{name: "vote_end desc", sort: Sort{Field: "vote_end", Desc: true}, want: []*governor.Proposal{proposals[0], proposals[2], proposals[1]}},
+		{name: "status asc", sort: Sort{Field: "status"}, want: []*governor.Proposal{proposals[1], proposals[2], proposals[0]}},
+		{name: "status desc", sort: Sort{Field: "status", Desc: true}, want: []*governor.Proposal{proposals[0], proposals[2], proposals[1]}},
+	}
+	for _, tt := range proposalTests {
+		got, err := store.GetProposalsByContractId(ctx, contractId, tt.sort)
+		if err != nil {
+			t.Fatalf("%s: failed to get proposals: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	voteTests := []struct {
+		name string
+		sort Sort
+		want []*governor.Vote
+	}{
+		{name: "default", want: []*governor.Vote{votes[3], votes[2], votes[1], votes[0]}},
+		{name: "ledger_seq asc", sort: Sort{Field: "ledger_seq"}, want: []*governor.Vote{votes[0], votes[1], votes[2], votes[3]}},
+		{name: "amount asc", sort: Sort{Field: "amount"}, want: []*governor.Vote{votes[0], votes[3], votes[2], votes[1]}},
+		{name: "amount desc", sort: Sort{Field: "amount", Desc: true}, want: []*governor.Vote{votes[1], votes[2], votes[3], votes[0]}},
+	}
+	for _, tt := range voteTests {
+		got, err := store.GetVotesByProposal(ctx, contractId, 1, tt.sort)
+		if err != nil {
+			t.Fatalf("%s: failed to get votes: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	invalid := Sort{Field: "voter; DROP TABLE votes"}
+	if _, err := store.GetProposalsByContractId(ctx, contractId, invalid); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort for proposals, got %v", err)
+	}
+	if _, err := store.GetVotesByProposal(ctx, contractId, 1, invalid); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort for votes, got %v", err)
+	}
+	if _, err := store.GetEventsByContractId(ctx, contractId, Sort{Field: "amount"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort for events, got %v", err)
+	}
+}
+
+func TestProposalVersion(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	proposal := &governor.Proposal{
+		ProposalKey:     "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC-0",
+		ContractId:      "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+		ProposalId:      0,
+		Proposer:        "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
+		Status:          0,
+		Title:           "Unicorns are real",
+		Description:     "They live in the clouds",
+		Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+		VoteStart:       1000,
+		VoteEnd:         2000,
+		VotesFor:        "0",
+		VotesAgainst:    "0",
+		VotesAbstain:    "0",
+		ExecutionUnlock: 0,
+		ExecutionTxHash: "",
+	}
+
+	err := store.InsertProposal(ctx, proposal)
+	if err != nil {
+		t.Fatalf("failed to insert proposal: %v", err)
+	}
+	err = store.InsertProposal(ctx, proposal)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict inserting existing proposal, got %v", err)
+	}
+
+	// two writers read the same version
+	first, version, err := store.GetProposalVersion(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("expected version 1, got %d", version)
+	}
+	if diff := cmp.Diff(proposal, first); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	second, _, err := store.GetProposalVersion(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+
+	// only the first update succeeds
+	first.VotesFor = "100"
+	first.UpdatedLedger = 1500
+	first.UpdatedEventId = "0000006442450944000-0000000000"
+	first.UpdatedAt = 1761053041
+	err = store.UpdateProposal(ctx, first, version)
+	if err != nil {
+		t.Fatalf("failed to update proposal: %v", err)
+	}
+	second.VotesFor = "200"
+	err = store.UpdateProposal(ctx, second, version)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict for stale update, got %v", err)
+	}
+
+	retrieved, version, err := store.GetProposalVersion(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("expected version 2, got %d", version)
+	}
+	if diff := cmp.Diff(first, retrieved); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// upserts also invalidate readers
+	err = store.UpsertProposal(ctx, retrieved)
+	if err != nil {
+		t.Fatalf("failed to upsert proposal: %v", err)
+	}
+	err = store.UpdateProposal(ctx, retrieved, version)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict after upsert, got %v", err)
+	}
+
+	_, _, err = store.GetProposalVersion(ctx, "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC-1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestProposalInvalidTallies(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	proposal := &governor.Proposal{
+		ProposalKey:  "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC-0",
+		ContractId:   "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+		ProposalId:   0,
+		VotesFor:     "0",
+		VotesAgainst: "-1",
+		VotesAbstain: "0",
+	}
+	if err := store.InsertProposal(ctx, proposal); !errors.Is(err, governor.ErrInvalidAmount) {
+		t.Errorf("InsertProposal() error = %v, want %v", err, governor.ErrInvalidAmount)
+	}
+	if err := store.UpsertProposal(ctx, proposal); !errors.Is(err, governor.ErrInvalidAmount) {
+		t.Errorf("UpsertProposal() error = %v, want %v", err, governor.ErrInvalidAmount)
+	}
+
+	proposal.VotesAgainst = "0"
+	if err := store.InsertProposal(ctx, proposal); err != nil {
+		t.Fatalf("failed to insert proposal: %v", err)
+	}
+	// one more than the largest i128
+	proposal.VotesFor = "170141183460469231731687303715884105728"
+	if err := store.UpdateProposal(ctx, proposal, 1); !errors.Is(err, governor.ErrInvalidAmount) {
+		t.Errorf("UpdateProposal() error = %v, want %v", err, governor.ErrInvalidAmount)
+	}
+
+	got, err := store.GetProposal(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if got.VotesFor != "0" {
+		t.Errorf("got votes_for %s, want the invalid tally not persisted", got.VotesFor)
+	}
+}
+
+func TestVotesTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "contract_123"
+	proposalId := uint32(1)
+
+	// Insert multiple votes
+	votes := []*governor.Vote{
+		{
+			TxHash:          "tx_vote_001",
+			ContractId:      contractId,
+			ProposalId:      proposalId,
+			Voter:           "user_abc",
+			Support:         1,
+			Amount:          "1000",
+			LedgerSeq:       5000,
+			LedgerCloseTime: 1761053046,
+		},
+		{
+			TxHash:          "tx_vote_002",
+			ContractId:      contractId,
+			ProposalId:      proposalId,
+			Voter:           "user_def",
+			Support:         2,
+			Amount:          "500",
+			LedgerSeq:       5100,
+			LedgerCloseTime: 1761054046,
+			Reason:          "too risky",
+		},
+		{
+			TxHash:          "tx_vote_003",
+			ContractId:      contractId,
+			ProposalId:      2, // Different proposal
+			Voter:           "user_ghi",
+			Support:         3,
+			Amount:          "750",
+			LedgerSeq:       5200,
+			LedgerCloseTime: 1761055046,
+		},
+	}
+
+	for _, vote := range votes {
+		if err := store.InsertVote(ctx, vote); err != nil {
+			t.Fatalf("failed to insert vote: %v", err)
+		}
+	}
+
+	// test GetVote
+	retrievedVote, err := store.GetVote(ctx, votes[1].TxHash)
+	if err != nil {
 		t.Fatalf("failed to get vote: %v", err)
 	}
 	if diff := cmp.Diff(votes[1], retrievedVote); diff != "" {
@@ -407,7 +1440,7 @@ func TestVotesTable(t *testing.T) {
 	}
 
 	// test GetVotesByProposal
-	retrievedVotes, err := store.GetVotesByProposal(ctx, contractId, proposalId)
+	retrievedVotes, err := store.GetVotesByProposal(ctx, contractId, proposalId, Sort{})
 	if err != nil {
 		t.Fatalf("failed to get votes by proposal: %v", err)
 	}
@@ -421,4 +1454,1361 @@ func TestVotesTable(t *testing.T) {
 		t.Errorf("check 3b: mismatch (-want +got):\n%s", diff)
 	}
 
+	// test EachVoteByProposal streams the same votes
+	var streamedVotes []*governor.Vote
+	err = store.EachVoteByProposal(ctx, contractId, proposalId, Sort{}, func(vote *governor.Vote) error {
+		streamedVotes = append(streamedVotes, vote)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to stream votes by proposal: %v", err)
+	}
+	if diff := cmp.Diff(retrievedVotes, streamedVotes); diff != "" {
+		t.Errorf("check 3c: mismatch (-want +got):\n%s", diff)
+	}
+	errStop := errors.New("stop")
+	streamed := 0
+	err = store.EachVoteByProposal(ctx, contractId, proposalId, Sort{}, func(vote *governor.Vote) error {
+		streamed++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || streamed != 1 {
+		t.Errorf("expected streaming to stop after 1 vote with errStop, got %d votes and %v", streamed, err)
+	}
+
+	// test GetVoteSummary
+	summary, err := store.GetVoteSummary(ctx, contractId, proposalId)
+	if err != nil {
+		t.Fatalf("failed to get vote summary: %v", err)
+	}
+	wantSummary := &governor.VoteSummary{
+		For:     governor.VoteBucket{Voters: 1, Total: "1000", Max: "1000"},
+		Against: governor.VoteBucket{Voters: 0, Total: "0", Max: "0"},
+		Abstain: governor.VoteBucket{Voters: 1, Total: "500", Max: "500"},
+	}
+	if diff := cmp.Diff(wantSummary, summary); diff != "" {
+		t.Errorf("check 4: mismatch (-want +got):\n%s", diff)
+	}
+
+	// test GetVoteByProposalAndVoter returns the latest vote of the voter
+	revote := *votes[0]
+	revote.TxHash, revote.Support, revote.LedgerSeq = "tx_vote_004", 0, 5300
+	if err := store.InsertVote(ctx, &revote); err != nil {
+		t.Fatalf("failed to insert vote: %v", err)
+	}
+	retrievedVote, err = store.GetVoteByProposalAndVoter(ctx, contractId, proposalId, votes[0].Voter)
+	if err != nil {
+		t.Fatalf("failed to get vote by voter: %v", err)
+	}
+	if diff := cmp.Diff(&revote, retrievedVote); diff != "" {
+		t.Errorf("check 5a: mismatch (-want +got):\n%s", diff)
+	}
+	_, err = store.GetVoteByProposalAndVoter(ctx, contractId, 2, votes[0].Voter)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("check 5b: expected ErrNotFound, got %v", err)
+	}
+
+	// test GetLatestVotesByVoter returns the latest vote of the voter on each proposal they voted on
+	latestVotes, err := store.GetLatestVotesByVoter(ctx, contractId, votes[0].Voter, []uint32{2, proposalId})
+	if err != nil {
+		t.Fatalf("failed to get latest votes by voter: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.Vote{&revote}, latestVotes); diff != "" {
+		t.Errorf("check 5c: mismatch (-want +got):\n%s", diff)
+	}
+
+	// test CountVoters counts a voter that voted again once
+	voters, err := store.CountVoters(ctx, contractId, proposalId)
+	if err != nil {
+		t.Fatalf("failed to count voters: %v", err)
+	}
+	if voters != 2 {
+		t.Errorf("check 6a: expected 2 voters, got %d", voters)
+	}
+	voters, err = store.CountVoters(ctx, contractId, 99)
+	if err != nil || voters != 0 {
+		t.Errorf("check 6b: expected 0 voters on a proposal without votes, got %d, %v", voters, err)
+	}
+}
+
+func TestInsertVotes(t *testing.T) {
+	// the number of votes inserted by each statement
+	chunk := SQLITE_MAX_QUERY_PARAMS / len(voteArgs(&governor.Vote{}))
+	for _, count := range []int{0, 1, chunk - 1, chunk, chunk + 1, 2*chunk + 1} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			store := setupStore(t)
+			ctx := t.Context()
+
+			var votes []*governor.Vote
+			var txHashes []string
+			for i := range count {
+				vote := &governor.Vote{
+					TxHash:          fmt.Sprintf("tx_vote_%03d", i),
+					ContractId:      "contract_123",
+					ProposalId:      1,
+					Voter:           fmt.Sprintf("user_%03d", i),
+					Support:         uint32(i % 3),
+					Amount:          fmt.Sprintf("%d000", i+1),
+					LedgerSeq:       5000,
+					LedgerCloseTime: 1761053046,
+				}
+				if i%2 == 1 {
+					vote.Reason = fmt.Sprintf("reason %d", i)
+				}
+				votes = append(votes, vote)
+				txHashes = append(txHashes, vote.TxHash)
+			}
+
+			// votes already inserted, including the last of a chunk, are left unchanged
+			want := slices.Clone(votes)
+			for _, i := range []int{0, chunk - 1} {
+				if i >= count {
+					continue
+				}
+				existing := *votes[i]
+				existing.Amount = "1"
+				if err := store.InsertVote(ctx, &existing); err != nil {
+					t.Fatalf("failed to insert vote: %v", err)
+				}
+				want[i] = &existing
+			}
+			if err := store.InsertVotes(ctx, votes); err != nil {
+				t.Fatalf("failed to insert votes: %v", err)
+			}
+
+			retrievedVotes, err := store.GetVotesByTxHashes(ctx, append(txHashes, "tx_vote_missing"))
+			if err != nil {
+				t.Fatalf("failed to get votes by tx hashes: %v", err)
+			}
+			slices.SortFunc(retrievedVotes, func(a, b *governor.Vote) int { return strings.Compare(a.TxHash, b.TxHash) })
+			if diff := cmp.Diff(want, retrievedVotes); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDelegationsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	votesId := "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW"
+	alice := "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q"
+	bob := "GAAQEAYEAUDAOCAJBIFQYDIOB4IBCEQTCQKRMFYYDENBWHA5DYPSABOV"
+	carol := "GCTPD6NPDPZ7YKZ7FOR5IPBUPDJJRHIU2DIHQK6QZEG6ZNPLFOZHBMPJ"
+
+	newDelegation := func(eventId string, contractId string, delegator string, from string, to string) *governor.Delegation {
+		return &governor.Delegation{
+			EventId:         eventId,
+			ContractId:      contractId,
+			Delegator:       delegator,
+			FromDelegate:    from,
+			Delegate:        to,
+			TxHash:          "tx_" + eventId,
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+		}
+	}
+	delegations := []*governor.Delegation{
+		newDelegation("0005025687261941760-0000000000", votesId, alice, alice, bob),
+		newDelegation("0005025687261941760-0000000001", votesId, carol, carol, bob),
+		newDelegation("0005025695851872256-0000000000", votesId, alice, bob, carol),
+		newDelegation("0005025695851872256-0000000001", "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", alice, alice, bob),
+	}
+	// insert out of order to verify sorting
+	for _, i := range []int{2, 0, 3, 1} {
+		if err := store.InsertDelegation(ctx, delegations[i]); err != nil {
+			t.Fatalf("failed to insert delegation: %v", err)
+		}
+	}
+	if err := store.InsertDelegation(ctx, delegations[0]); err != nil {
+		t.Fatalf("failed to insert duplicate delegation: %v", err)
+	}
+
+	// alice moved her delegation from bob to carol
+	delegators, err := store.GetDelegators(ctx, votesId, bob)
+	if err != nil {
+		t.Fatalf("failed to get delegators: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.Delegation{delegations[1]}, delegators); diff != "" {
+		t.Errorf("bob delegators mismatch (-want +got):\n%s", diff)
+	}
+	delegators, err = store.GetDelegators(ctx, votesId, carol)
+	if err != nil {
+		t.Fatalf("failed to get delegators: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.Delegation{delegations[2]}, delegators); diff != "" {
+		t.Errorf("carol delegators mismatch (-want +got):\n%s", diff)
+	}
+	delegators, err = store.GetDelegators(ctx, votesId, alice)
+	if err != nil {
+		t.Fatalf("failed to get delegators: %v", err)
+	}
+	if len(delegators) != 0 {
+		t.Errorf("expected no delegators for alice, got %d", len(delegators))
+	}
+
+	history, err := store.GetDelegationHistory(ctx, votesId, bob)
+	if err != nil {
+		t.Fatalf("failed to get delegation history: %v", err)
+	}
+	if diff := cmp.Diff(delegations[:3], history); diff != "" {
+		t.Errorf("history mismatch (-want +got):\n%s", diff)
+	}
+
+	// votes contracts
+	isVotes, err := store.IsVotesContract(ctx, votesId)
+	if err != nil {
+		t.Fatalf("failed to check votes contract: %v", err)
+	}
+	if isVotes {
+		t.Errorf("expected %s not to be a votes contract", votesId)
+	}
+	if err := store.UpsertVotesContract(ctx, "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", votesId, 1170134); err != nil {
+		t.Fatalf("failed to upsert votes contract: %v", err)
+	}
+	if err := store.UpsertVotesContract(ctx, "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", votesId, 1170136); err != nil {
+		t.Fatalf("failed to upsert votes contract twice: %v", err)
+	}
+	isVotes, err = store.IsVotesContract(ctx, votesId)
+	if err != nil {
+		t.Fatalf("failed to check votes contract: %v", err)
+	}
+	if !isVotes {
+		t.Errorf("expected %s to be a votes contract", votesId)
+	}
+}
+
+func TestGovernorSettings(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+	governorId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+
+	if _, err := store.GetGovernorSettings(ctx, governorId, 1170136); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before settings are recorded, got %v", err)
+	}
+	first := &governor.Settings{GovernorId: governorId, CountingType: 0b110, Quorum: 500, VoteThreshold: 5100, LedgerSeq: 1170136}
+	updated := &governor.Settings{GovernorId: governorId, CountingType: 0b111, Quorum: 1000, VoteThreshold: 5000, LedgerSeq: 1170140}
+	// settings read again at the same ledger replace the earlier ones
+	replaced := *updated
+	replaced.Quorum = 2000
+	for _, settings := range []*governor.Settings{updated, first, &replaced} {
+		if err := store.UpsertGovernorSettings(ctx, settings); err != nil {
+			t.Fatalf("failed to upsert settings: %v", err)
+		}
+	}
+
+	// the settings in effect at a ledger are the latest read at or before it, or the first recorded before those
+	tests := []struct {
+		ledgerSeq uint32
+		want      *governor.Settings
+	}{
+		{ledgerSeq: 1170100, want: first},
+		{ledgerSeq: 1170136, want: first},
+		{ledgerSeq: 1170139, want: first},
+		{ledgerSeq: 1170140, want: &replaced},
+		{ledgerSeq: 1170200, want: &replaced},
+	}
+	for _, tt := range tests {
+		got, err := store.GetGovernorSettings(ctx, governorId, tt.ledgerSeq)
+		if err != nil {
+			t.Fatalf("failed to get settings at ledger %d: %v", tt.ledgerSeq, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("settings at ledger %d mismatch (-want +got):\n%s", tt.ledgerSeq, diff)
+		}
+	}
+
+	deleted, err := store.DeleteGovernorSettings(ctx, governorId)
+	if err != nil {
+		t.Fatalf("failed to delete settings: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d settings, want 2", deleted)
+	}
+	if _, err := store.GetGovernorSettings(ctx, governorId, 1170200); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after deleting settings, got %v", err)
+	}
+}
+
+func TestGetTrackedContracts(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	governorId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherGovernorId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	votesId := "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW"
+
+	proposal := &governor.Proposal{
+		ProposalKey:  governor.EncodeProposalKey(governorId, 1),
+		ContractId:   governorId,
+		ProposalId:   1,
+		VotesFor:     "0",
+		VotesAgainst: "0",
+		VotesAbstain: "0",
+	}
+	if err := store.UpsertProposal(ctx, proposal); err != nil {
+		t.Fatalf("failed to insert proposal: %v", err)
+	}
+	if err := store.UpsertVotesContract(ctx, otherGovernorId, votesId, 1170134); err != nil {
+		t.Fatalf("failed to upsert votes contract: %v", err)
+	}
+
+	got, err := store.GetTrackedContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get tracked contracts: %v", err)
+	}
+	slices.Sort(got)
+	want := []string{votesId, governorId, otherGovernorId}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("tracked contracts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContractsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	governorId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	blockedId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+
+	contracts, err := store.GetContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get contracts: %v", err)
+	}
+	if len(contracts) != 0 {
+		t.Errorf("expected no contracts, got %d", len(contracts))
+	}
+
+	for _, proposalId := range []uint32{1, 2} {
+		proposal := &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(governorId, proposalId),
+			ContractId:   governorId,
+			ProposalId:   proposalId,
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+	}
+	activity := []struct {
+		contractId string
+		ledgerSeq  uint32
+		closeTime  int64
+		reviewed   bool
+	}{
+		{governorId, 1170100, 1761052000, true},
+		// the reviewed flag is only set when the contract is registered
+		{governorId, 1170200, 1761052500, false},
+		// replayed events don't move the last activity back
+		{governorId, 1170150, 1761052250, false},
+		{blockedId, 1170000, 1761051500, false},
+	}
+	for _, a := range activity {
+		eventId := mustEncodeEventId(t, int64(a.ledgerSeq)<<32, 0)
+		if err := store.UpsertContractActivity(ctx, a.contractId, eventId, a.ledgerSeq, a.closeTime, a.reviewed); err != nil {
+			t.Fatalf("failed to upsert contract activity: %v", err)
+		}
+	}
+	// a later event in the same ledger moves the last event id
+	laterEventId := mustEncodeEventId(t, 1170200<<32, 1)
+	if err := store.UpsertContractActivity(ctx, governorId, laterEventId, 1170200, 1761052500, true); err != nil {
+		t.Fatalf("failed to upsert contract activity: %v", err)
+	}
+	lastEventId, err := store.GetContractLastEventId(ctx, governorId)
+	if err != nil {
+		t.Fatalf("GetContractLastEventId() error = %v", err)
+	}
+	if lastEventId != laterEventId {
+		t.Errorf("GetContractLastEventId() = %s, want %s", lastEventId, laterEventId)
+	}
+	if _, err := store.GetContractLastEventId(ctx, "CUNKNOWN"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContractLastEventId() for an unregistered contract error = %v, want ErrNotFound", err)
+	}
+	if err := store.BlockContract(ctx, blockedId, "spam", 1761052600); err != nil {
+		t.Fatalf("failed to block contract: %v", err)
+	}
+	// event counts are added to, and are dropped for contracts that are not registered
+	for _, counts := range []EventCounts{{Seen: 3, Parsed: 3}, {Seen: 2, Parsed: 1, Failed: 1}} {
+		registered, err := store.AddContractEventCounts(ctx, governorId, counts)
+		if err != nil {
+			t.Fatalf("failed to add contract event counts: %v", err)
+		}
+		if !registered {
+			t.Errorf("AddContractEventCounts() = false for a registered contract, want true")
+		}
+	}
+	registered, err := store.AddContractEventCounts(ctx, "CUNKNOWN", EventCounts{Seen: 1, Failed: 1})
+	if err != nil {
+		t.Fatalf("failed to add contract event counts: %v", err)
+	}
+	if registered {
+		t.Errorf("AddContractEventCounts() = true for an unregistered contract, want false")
+	}
+
+	contracts, err = store.GetContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get contracts: %v", err)
+	}
+	want := []*Contract{
+		{ContractId: governorId, LastEventLedger: 1170200, LastEventCloseTime: 1761052500, ProposalCount: 2, Reviewed: true, Events: EventCounts{Seen: 5, Parsed: 4, Failed: 1}},
+		{ContractId: blockedId, LastEventLedger: 1170000, LastEventCloseTime: 1761051500, Blocked: true},
+	}
+	if diff := cmp.Diff(want, contracts); diff != "" {
+		t.Errorf("contracts mismatch (-want +got):\n%s", diff)
+	}
+
+	unreviewed, err := store.GetUnreviewedContractIds(ctx)
+	if err != nil {
+		t.Fatalf("GetUnreviewedContractIds() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{blockedId}, unreviewed); diff != "" {
+		t.Errorf("unreviewed contracts mismatch (-want +got):\n%s", diff)
+	}
+	if err := store.SetContractReviewed(ctx, blockedId, true); err != nil {
+		t.Fatalf("SetContractReviewed() error = %v", err)
+	}
+	if unreviewed, err = store.GetUnreviewedContractIds(ctx); err != nil || len(unreviewed) != 0 {
+		t.Errorf("GetUnreviewedContractIds() after review = %v, %v, want no contracts", unreviewed, err)
+	}
+	if err := store.SetContractReviewed(ctx, "CUNKNOWN", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetContractReviewed() for an unregistered contract error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestContractMetadataTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	governorId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	for _, contractId := range []string{governorId, otherId} {
+		if err := store.UpsertContractActivity(ctx, contractId, mustEncodeEventId(t, 1170000<<32, 0), 1170000, 1761051500, true); err != nil {
+			t.Fatalf("failed to upsert contract activity: %v", err)
+		}
+	}
+	if _, err := store.GetContract(ctx, "CUNKNOWN"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContract() for an unregistered contract error = %v, want ErrNotFound", err)
+	}
+
+	metadata := &ContractMetadata{
+		ContractId:  governorId,
+		Name:        "Blend DAO",
+		Description: "Governs the Blend protocol",
+		IconUrl:     "https://blend.capital/icon.png",
+		Website:     "https://blend.capital",
+		Links:       map[string]string{"x": "https://x.com/blend", "discord": "https://discord.gg/blend"},
+		UpdatedAt:   1761052000,
+	}
+	if err := store.UpsertContractMetadata(ctx, metadata, true); err != nil {
+		t.Fatalf("failed to upsert contract metadata: %v", err)
+	}
+	// metadata can be set before a contract is registered
+	if err := store.UpsertContractMetadata(ctx, &ContractMetadata{ContractId: "CUNKNOWN", Name: "Unknown"}, true); err != nil {
+		t.Fatalf("failed to upsert contract metadata: %v", err)
+	}
+	// without replace, existing metadata is kept
+	if err := store.UpsertContractMetadata(ctx, &ContractMetadata{ContractId: governorId, Name: "Bootstrap"}, false); err != nil {
+		t.Fatalf("failed to upsert contract metadata: %v", err)
+	}
+
+	contracts, err := store.GetContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get contracts: %v", err)
+	}
+	want := []*Contract{
+		{ContractId: governorId, LastEventLedger: 1170000, LastEventCloseTime: 1761051500, Reviewed: true, Metadata: metadata},
+		{ContractId: otherId, LastEventLedger: 1170000, LastEventCloseTime: 1761051500, Reviewed: true},
+	}
+	if diff := cmp.Diff(want, contracts); diff != "" {
+		t.Errorf("contracts mismatch (-want +got):\n%s", diff)
+	}
+	contract, err := store.GetContract(ctx, governorId)
+	if err != nil {
+		t.Fatalf("failed to get contract: %v", err)
+	}
+	if diff := cmp.Diff(want[0], contract); diff != "" {
+		t.Errorf("contract mismatch (-want +got):\n%s", diff)
+	}
+
+	// replacing overwrites every field
+	replaced := &ContractMetadata{ContractId: governorId, Name: "Blend", UpdatedAt: 1761053000}
+	if err := store.UpsertContractMetadata(ctx, replaced, true); err != nil {
+		t.Fatalf("failed to upsert contract metadata: %v", err)
+	}
+	contract, err = store.GetContract(ctx, governorId)
+	if err != nil {
+		t.Fatalf("failed to get contract: %v", err)
+	}
+	if diff := cmp.Diff(replaced, contract.Metadata); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := store.DeleteContractMetadata(ctx, governorId); err != nil {
+		t.Fatalf("failed to delete contract metadata: %v", err)
+	}
+	if err := store.DeleteContractMetadata(ctx, governorId); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteContractMetadata() for a contract without metadata error = %v, want ErrNotFound", err)
+	}
+	contract, err = store.GetContract(ctx, governorId)
+	if err != nil {
+		t.Fatalf("failed to get contract: %v", err)
+	}
+	if contract.Metadata != nil {
+		t.Errorf("got metadata %+v after deleting it, want nil", contract.Metadata)
+	}
+}
+
+func TestVoteTokens(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	governorId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	votesId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	// a registered governor whose votes contract is unknown has no token to fetch
+	otherId := "CCYQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAADQ6"
+	for _, contractId := range []string{governorId, otherId} {
+		if err := store.UpsertContractActivity(ctx, contractId, mustEncodeEventId(t, 1170000<<32, 0), 1170000, 1761051500, true); err != nil {
+			t.Fatalf("failed to upsert contract activity: %v", err)
+		}
+	}
+	if err := store.UpsertVotesContract(ctx, governorId, votesId, 1170000); err != nil {
+		t.Fatalf("failed to upsert votes contract: %v", err)
+	}
+
+	toFetch, err := store.GetVoteTokensToFetch(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to get vote tokens to fetch: %v", err)
+	}
+	if diff := cmp.Diff([]*VoteToken{{ContractId: governorId, VotesId: votesId}}, toFetch); diff != "" {
+		t.Errorf("vote tokens to fetch mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := store.SetVoteToken(ctx, governorId, "BLND", 7); err != nil {
+		t.Fatalf("failed to set vote token: %v", err)
+	}
+	if err := store.SetVoteToken(ctx, votesId, "BLND", 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetVoteToken() for an unregistered contract error = %v, want ErrNotFound", err)
+	}
+
+	toFetch, err = store.GetVoteTokensToFetch(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to get vote tokens to fetch: %v", err)
+	}
+	if len(toFetch) != 0 {
+		t.Errorf("got %d vote tokens to fetch after setting the token, want 0", len(toFetch))
+	}
+	want := &VoteToken{ContractId: governorId, Symbol: "BLND", Decimals: 7}
+	tokens, err := store.GetVoteTokens(ctx)
+	if err != nil {
+		t.Fatalf("failed to get vote tokens: %v", err)
+	}
+	if diff := cmp.Diff([]*VoteToken{want}, tokens); diff != "" {
+		t.Errorf("vote tokens mismatch (-want +got):\n%s", diff)
+	}
+	contract, err := store.GetContract(ctx, governorId)
+	if err != nil {
+		t.Fatalf("failed to get contract: %v", err)
+	}
+	if diff := cmp.Diff(want, contract.Token); diff != "" {
+		t.Errorf("contract token mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProposalContentTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	descriptions := []string{
+		"ipfs://bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy",
+		"plz",
+		"ipfs://QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX/proposal.md",
+		"ipfs://QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG",
+	}
+	for i, description := range descriptions {
+		proposal := &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(contractId, uint32(i)),
+			ContractId:   contractId,
+			ProposalId:   uint32(i),
+			Description:  description,
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+	}
+
+	_, err := store.GetProposalContent(ctx, governor.EncodeProposalKey(contractId, 0))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// proposal 0 was fetched, proposal 2 is waiting for a retry, and proposal 3 is due for a retry
+	contents := []*governor.ProposalContent{
+		{
+			ProposalKey: governor.EncodeProposalKey(contractId, 0),
+			ContractId:  contractId,
+			ProposalId:  0,
+			Uri:         descriptions[0],
+			Status:      governor.CONTENT_STATUS_FETCHED,
+			Content:     "# Unicorns are real",
+			ContentType: "text/markdown",
+			FetchedAt:   1761053046,
+		},
+		{
+			ProposalKey:   governor.EncodeProposalKey(contractId, 2),
+			ContractId:    contractId,
+			ProposalId:    2,
+			Uri:           descriptions[2],
+			Status:        governor.CONTENT_STATUS_PENDING,
+			Attempts:      2,
+			NextAttemptAt: 1761053100,
+			LastError:     "gateway returned status 504",
+		},
+		{
+			ProposalKey:   governor.EncodeProposalKey(contractId, 3),
+			ContractId:    contractId,
+			ProposalId:    3,
+			Uri:           descriptions[3],
+			Status:        governor.CONTENT_STATUS_PENDING,
+			Attempts:      1,
+			NextAttemptAt: 1761053000,
+			LastError:     "gateway returned status 504",
+		},
+	}
+	for _, content := range contents {
+		if err := store.UpsertProposalContent(ctx, content); err != nil {
+			t.Fatalf("failed to upsert proposal content: %v", err)
+		}
+	}
+
+	got, err := store.GetProposalContent(ctx, contents[0].ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal content: %v", err)
+	}
+	if diff := cmp.Diff(contents[0], got); diff != "" {
+		t.Errorf("check 1: mismatch (-want +got):\n%s", diff)
+	}
+
+	toFetch, err := store.GetProposalContentToFetch(ctx, 1761053046, 10)
+	if err != nil {
+		t.Fatalf("failed to get proposal content to fetch: %v", err)
+	}
+	if diff := cmp.Diff([]*governor.ProposalContent{contents[2]}, toFetch); diff != "" {
+		t.Errorf("check 2: mismatch (-want +got):\n%s", diff)
+	}
+
+	// a proposal without any content is due immediately
+	if _, err := store.DeleteProposalContentByContractId(ctx, contractId); err != nil {
+		t.Fatalf("failed to delete proposal content: %v", err)
+	}
+	toFetch, err = store.GetProposalContentToFetch(ctx, 1761053046, 2)
+	if err != nil {
+		t.Fatalf("failed to get proposal content to fetch: %v", err)
+	}
+	want := []*governor.ProposalContent{
+		{ProposalKey: contents[0].ProposalKey, ContractId: contractId, ProposalId: 0, Uri: descriptions[0], Status: governor.CONTENT_STATUS_PENDING},
+		{ProposalKey: contents[1].ProposalKey, ContractId: contractId, ProposalId: 2, Uri: descriptions[2], Status: governor.CONTENT_STATUS_PENDING},
+	}
+	if diff := cmp.Diff(want, toFetch); diff != "" {
+		t.Errorf("check 3: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSnapshotsTable(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherContractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+
+	// 1. no snapshots
+	if _, err := store.GetLatestSnapshot(ctx, contractId, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetLatestSnapshot() error = %v, want ErrNotFound", err)
+	}
+	if ledger, err := store.GetSnapshotLedger(ctx); err != nil || ledger != 0 {
+		t.Errorf("GetSnapshotLedger() = %d, %v, want 0", ledger, err)
+	}
+
+	// 2. insert snapshots at several ledgers
+	newSnapshot := func(id string, ledger uint32, votesFor string) *Snapshot {
+		return &Snapshot{
+			ContractId: id,
+			LedgerSeq:  ledger,
+			EventId:    mustEncodeEventId(t, int64(ledger)<<32, 0),
+			Proposals: []*governor.Proposal{{
+				ProposalKey:    governor.EncodeProposalKey(id, 0),
+				ContractId:     id,
+				ProposalId:     0,
+				Proposer:       "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Title:          "Test",
+				VotesFor:       votesFor,
+				VotesAgainst:   "0",
+				VotesAbstain:   "0",
+				UpdatedEventId: mustEncodeEventId(t, int64(ledger)<<32, 0),
+				UpdatedAt:      1761053041,
+			}},
+			CreatedAt: 1761053041,
+		}
+	}
+	snapshots := []*Snapshot{
+		newSnapshot(contractId, 1170100, "100"),
+		newSnapshot(contractId, 1170200, "200"),
+		newSnapshot(contractId, 1170300, "300"),
+		newSnapshot(contractId, 1170400, "400"),
+		newSnapshot(otherContractId, 1170500, "500"),
+	}
+	for _, snapshot := range snapshots {
+		if err := store.InsertSnapshot(ctx, snapshot); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+	}
+	// re-taking a snapshot at the same ledger replaces it
+	snapshots[3] = newSnapshot(contractId, 1170400, "401")
+	if err := store.InsertSnapshot(ctx, snapshots[3]); err != nil {
+		t.Fatalf("failed to replace snapshot: %v", err)
+	}
+
+	// 3. get the latest snapshot, optionally before a ledger
+	for _, tt := range []struct {
+		beforeLedger uint32
+		want         *Snapshot
+	}{
+		{0, snapshots[3]},
+		{1170400, snapshots[2]},
+		{1170301, snapshots[2]},
+		{1170101, snapshots[0]},
+	} {
+		got, err := store.GetLatestSnapshot(ctx, contractId, tt.beforeLedger)
+		if err != nil {
+			t.Fatalf("GetLatestSnapshot(%d) error = %v", tt.beforeLedger, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("GetLatestSnapshot(%d) mismatch (-want +got):\n%s", tt.beforeLedger, diff)
+		}
+	}
+	if _, err := store.GetLatestSnapshot(ctx, contractId, 1170100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetLatestSnapshot() before the first snapshot error = %v, want ErrNotFound", err)
+	}
+	if ledger, err := store.GetSnapshotLedger(ctx); err != nil || ledger != 1170500 {
+		t.Errorf("GetSnapshotLedger() = %d, %v, want 1170500", ledger, err)
+	}
+
+	// 4. prune all but the latest 2 snapshots
+	pruned, err := store.PruneSnapshots(ctx, contractId, 2)
+	if err != nil {
+		t.Fatalf("failed to prune snapshots: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned %d snapshots, want 2", pruned)
+	}
+	if pruned, err := store.PruneSnapshots(ctx, otherContractId, 2); err != nil || pruned != 0 {
+		t.Errorf("PruneSnapshots() of a contract with 1 snapshot = %d, %v, want 0", pruned, err)
+	}
+	if _, err := store.GetLatestSnapshot(ctx, contractId, 1170300); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetLatestSnapshot() of a pruned snapshot error = %v, want ErrNotFound", err)
+	}
+
+	// 5. last event ids by contract within a ledger range
+	for i, event := range []struct {
+		contractId string
+		ledger     uint32
+	}{{contractId, 1170600}, {contractId, 1170601}, {otherContractId, 1170601}, {contractId, 1170602}} {
+		err := store.InsertEvent(ctx, &governor.GovernorEvent{
+			EventId:    mustEncodeEventId(t, int64(event.ledger)<<32, int32(i)),
+			ContractId: event.contractId,
+			EventType:  "vote_cast",
+			EventData:  `{}`,
+			TxHash:     fmt.Sprintf("tx_%d", i),
+			LedgerSeq:  event.ledger,
+		})
+		if err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+	}
+	eventIds, err := store.GetLastEventIds(ctx, 1170600, 1170601)
+	if err != nil {
+		t.Fatalf("failed to get last event ids: %v", err)
+	}
+	want := map[string]string{
+		contractId:      mustEncodeEventId(t, int64(1170601)<<32, 1),
+		otherContractId: mustEncodeEventId(t, int64(1170601)<<32, 2),
+	}
+	if diff := cmp.Diff(want, eventIds); diff != "" {
+		t.Errorf("last event ids mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteAfterLedger(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherContractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+
+	for i, row := range []struct {
+		contractId string
+		ledger     uint32
+	}{{contractId, 1170100}, {contractId, 1170101}, {contractId, 1170102}, {otherContractId, 1170102}} {
+		vote := &governor.Vote{TxHash: fmt.Sprintf("tx_vote_%d", i), ContractId: row.contractId, Amount: "1", LedgerSeq: row.ledger}
+		if err := store.InsertVote(ctx, vote); err != nil {
+			t.Fatalf("failed to insert vote: %v", err)
+		}
+		delegation := &governor.Delegation{EventId: mustEncodeEventId(t, int64(i), 0), ContractId: row.contractId, TxHash: "tx", LedgerSeq: row.ledger}
+		if err := store.InsertDelegation(ctx, delegation); err != nil {
+			t.Fatalf("failed to insert delegation: %v", err)
+		}
+	}
+
+	deleted, err := store.DeleteVotesAfterLedger(ctx, contractId, 1170100)
+	if err != nil {
+		t.Fatalf("failed to delete votes: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d votes, want 2", deleted)
+	}
+	deleted, err = store.DeleteDelegationsAfterLedger(ctx, contractId, 1170101)
+	if err != nil {
+		t.Fatalf("failed to delete delegations: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d delegations, want 1", deleted)
+	}
+
+	votes, err := store.GetVotesByProposal(ctx, contractId, 0, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if len(votes) != 1 || votes[0].LedgerSeq != 1170100 {
+		t.Errorf("remaining votes = %+v, want the vote at ledger 1170100", votes)
+	}
+	votes, err = store.GetVotesByProposal(ctx, otherContractId, 0, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if len(votes) != 1 {
+		t.Errorf("expected other contract votes to remain, got %d", len(votes))
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	_, err := store.GetEvent(ctx, "0005025687261941760-0000000000")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEvent() expected ErrNotFound, got %v", err)
+	}
+	_, err = store.GetProposal(ctx, "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB-3")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetProposal() expected ErrNotFound, got %v", err)
+	}
+	_, err = store.GetVote(ctx, "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVote() expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+	store.readTimeout = time.Nanosecond
+	store.writeTimeout = time.Nanosecond
+
+	_, err := store.GetEvent(ctx, "0005025687261941760-0000000000")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetEvent() expected ErrTimeout, got %v", err)
+	}
+	err = store.UpsertStatus(ctx, "indexer", 1, 1)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("UpsertStatus() expected ErrTimeout, got %v", err)
+	}
+
+	// operations that complete within the timeout succeed
+	store.readTimeout = time.Second
+	store.writeTimeout = time.Second
+	err = store.UpsertStatus(ctx, "indexer", 1, 1)
+	if err != nil {
+		t.Fatalf("UpsertStatus() error = %v", err)
+	}
+	_, _, err = store.GetStatus(ctx, "indexer")
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	vote := &governor.Vote{
+		TxHash:          "tx_vote_001",
+		ContractId:      "contract_123",
+		ProposalId:      1,
+		Voter:           "user_abc",
+		Support:         1,
+		Amount:          "1000",
+		LedgerSeq:       5000,
+		LedgerCloseTime: 1761053046,
+	}
+
+	// verify a failed transaction is rolled back
+	errRollback := errors.New("rollback")
+	err := store.WithTx(ctx, func(ctx context.Context) error {
+		if err := store.InsertVote(ctx, vote); err != nil {
+			t.Fatalf("failed to insert vote in tx: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	retrievedVote, err := store.GetVote(ctx, vote.TxHash)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected vote to be rolled back, got %v, err %v", retrievedVote, err)
+	}
+
+	// verify a successful transaction is committed
+	err = store.WithTx(ctx, func(ctx context.Context) error {
+		return store.InsertVote(ctx, vote)
+	})
+	if err != nil {
+		t.Fatalf("failed to commit tx: %v", err)
+	}
+	retrievedVote, err = store.GetVote(ctx, vote.TxHash)
+	if err != nil {
+		t.Fatalf("failed to get vote: %v", err)
+	}
+	if diff := cmp.Diff(vote, retrievedVote); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// verify delete by contract id
+	deleted, err := store.DeleteVotesByContractId(ctx, vote.ContractId)
+	if err != nil {
+		t.Fatalf("failed to delete votes: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted vote, got %d", deleted)
+	}
+	retrievedVote, err = store.GetVote(ctx, vote.TxHash)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected vote to be deleted, got %v, err %v", retrievedVote, err)
+	}
+}
+
+func TestAfterTx(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	vote := &governor.Vote{
+		TxHash:          "tx_vote_001",
+		ContractId:      "contract_123",
+		ProposalId:      1,
+		Voter:           "user_abc",
+		Support:         1,
+		Amount:          "1000",
+		LedgerSeq:       5000,
+		LedgerCloseTime: 1761053046,
+	}
+
+	// fn runs once a failed transaction is rolled back, outside of it, so its writes are kept
+	errRollback := errors.New("rollback")
+	ran := false
+	err := store.WithTx(ctx, func(ctx context.Context) error {
+		store.AfterTx(ctx, func(ctx context.Context) {
+			ran = true
+			if err := store.InsertVote(ctx, vote); err != nil {
+				t.Errorf("failed to insert vote after tx: %v", err)
+			}
+		})
+		if ran {
+			t.Error("expected fn to run once the transaction ends")
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if _, err := store.GetVote(ctx, vote.TxHash); err != nil {
+		t.Errorf("expected the vote written after the transaction, got %v", err)
+	}
+
+	// outside a transaction, fn runs immediately
+	ran = false
+	store.AfterTx(ctx, func(ctx context.Context) { ran = true })
+	if !ran {
+		t.Error("expected fn to run outside a transaction")
+	}
+}
+
+func TestWithSavepoint(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	newVote := func(txHash string) *governor.Vote {
+		return &governor.Vote{
+			TxHash:          txHash,
+			ContractId:      "contract_123",
+			ProposalId:      1,
+			Voter:           "user_" + txHash,
+			Support:         1,
+			Amount:          "1000",
+			LedgerSeq:       5000,
+			LedgerCloseTime: 1761053046,
+		}
+	}
+	first, poison, last := newVote("tx_vote_001"), newVote("tx_vote_002"), newVote("tx_vote_003")
+
+	if _, err := store.BeginSavepoint(ctx); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("BeginSavepoint() outside a transaction error = %v, want ErrNoTransaction", err)
+	}
+
+	// a failed savepoint is rolled back, and the transaction continues
+	errPoison := errors.New("poison")
+	err := store.WithTx(ctx, func(ctx context.Context) error {
+		if err := store.WithSavepoint(ctx, func(ctx context.Context) error { return store.InsertVote(ctx, first) }); err != nil {
+			t.Fatalf("WithSavepoint() error = %v", err)
+		}
+		err := store.WithSavepoint(ctx, func(ctx context.Context) error {
+			if err := store.InsertVote(ctx, poison); err != nil {
+				t.Fatalf("failed to insert vote in savepoint: %v", err)
+			}
+			return errPoison
+		})
+		if !errors.Is(err, errPoison) {
+			t.Fatalf("WithSavepoint() error = %v, want poison", err)
+		}
+		// a statement that fails aborts a postgres transaction until it is rolled back to a savepoint
+		err = store.WithSavepoint(ctx, func(ctx context.Context) error {
+			_, err := store.exec(ctx, "INSERT INTO missing_table (id) VALUES (1)")
+			return err
+		})
+		if err == nil {
+			t.Fatal("expected a statement on a missing table to fail")
+		}
+		return store.WithSavepoint(ctx, func(ctx context.Context) error { return store.InsertVote(ctx, last) })
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	for _, vote := range []*governor.Vote{first, last} {
+		if _, err := store.GetVote(ctx, vote.TxHash); err != nil {
+			t.Errorf("expected vote %s to be committed, got %v", vote.TxHash, err)
+		}
+	}
+	if _, err := store.GetVote(ctx, poison.TxHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the failed savepoint's vote to be rolled back, got %v", err)
+	}
+
+	// outside a transaction, fn runs in its own transaction
+	poison.TxHash = "tx_vote_004"
+	err = store.WithSavepoint(ctx, func(ctx context.Context) error {
+		if err := store.InsertVote(ctx, poison); err != nil {
+			t.Fatalf("failed to insert vote: %v", err)
+		}
+		return errPoison
+	})
+	if !errors.Is(err, errPoison) {
+		t.Fatalf("WithSavepoint() error = %v, want poison", err)
+	}
+	if _, err := store.GetVote(ctx, poison.TxHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the vote to be rolled back, got %v", err)
+	}
+}
+
+// queryMetrics returns the number of queries named query recorded, and the rows they returned or affected
+func queryMetrics(t *testing.T, query string) (uint64, float64) {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	var count uint64
+	var rows float64
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if len(metric.GetLabel()) != 1 || metric.GetLabel()[0].GetValue() != query {
+				continue
+			}
+			switch family.GetName() {
+			case "governor_db_query_duration_seconds":
+				count = metric.GetHistogram().GetSampleCount()
+			case "governor_db_query_rows_total":
+				rows = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return count, rows
+}
+
+func TestQueryMetrics(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	newVote := func(txHash string, proposalId uint32) *governor.Vote {
+		return &governor.Vote{
+			TxHash:          txHash,
+			ContractId:      "contract_123",
+			ProposalId:      proposalId,
+			Voter:           "user_" + txHash,
+			Support:         1,
+			Amount:          "1000",
+			LedgerSeq:       5000,
+			LedgerCloseTime: 1761053046,
+		}
+	}
+	assertMetrics := func(query string, wantCount uint64, wantRows float64, fn func()) {
+		t.Helper()
+		count, rows := queryMetrics(t, query)
+		fn()
+		gotCount, gotRows := queryMetrics(t, query)
+		if gotCount-count != wantCount || gotRows-rows != wantRows {
+			t.Errorf("%s recorded %d queries with %v rows, want %d with %v", query, gotCount-count, gotRows-rows, wantCount, wantRows)
+		}
+	}
+
+	// rows affected by writes are counted
+	assertMetrics(QUERY_INSERT_VOTES, 1, 3, func() {
+		votes := []*governor.Vote{newVote("tx_vote_001", 1), newVote("tx_vote_002", 1), newVote("tx_vote_003", 2)}
+		if err := store.InsertVotes(ctx, votes); err != nil {
+			t.Fatalf("InsertVotes() error = %v", err)
+		}
+	})
+	// rows returned by lists are counted, but not the count used to size the list
+	assertMetrics(QUERY_GET_VOTES_BY_PROPOSAL, 1, 2, func() {
+		if _, err := store.GetVotesByProposal(ctx, "contract_123", 1, Sort{}); err != nil {
+			t.Fatalf("GetVotesByProposal() error = %v", err)
+		}
+	})
+	assertMetrics(QUERY_EACH_VOTE_BY_PROPOSAL, 1, 1, func() {
+		err := store.EachVoteByProposal(ctx, "contract_123", 2, Sort{}, func(vote *governor.Vote) error { return nil })
+		if err != nil {
+			t.Fatalf("EachVoteByProposal() error = %v", err)
+		}
+	})
+	// single rows are counted if found
+	assertMetrics(QUERY_GET_VOTE, 2, 1, func() {
+		if _, err := store.GetVote(ctx, "tx_vote_001"); err != nil {
+			t.Fatalf("GetVote() error = %v", err)
+		}
+		if _, err := store.GetVote(ctx, "tx_vote_missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetVote() error = %v, want ErrNotFound", err)
+		}
+	})
+	// queries sharing SQL are recorded by the method that ran them
+	assertMetrics(QUERY_FILTER_EVENTS_BY_CONTRACT_ID, 0, 0, func() {
+		assertMetrics(QUERY_GET_EVENTS_BY_CONTRACT_ID, 1, 0, func() {
+			if _, err := store.GetEventsByContractId(ctx, "contract_123", Sort{}); err != nil {
+				t.Fatalf("GetEventsByContractId() error = %v", err)
+			}
+		})
+	})
+	// a method calling another records both, with the rows counted by the query that read them
+	assertMetrics(QUERY_GET_VOTE_SUMMARY, 1, 0, func() {
+		assertMetrics(QUERY_GET_VOTES_BY_PROPOSAL, 1, 2, func() {
+			if _, err := store.GetVoteSummary(ctx, "contract_123", 1); err != nil {
+				t.Fatalf("GetVoteSummary() error = %v", err)
+			}
+		})
+	})
+}
+
+func TestDeleteContractData(t *testing.T) {
+	store := setupStore(t)
+	ctx := t.Context()
+
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	otherContractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+
+	for i, id := range []string{contractId, contractId, otherContractId} {
+		event := &governor.GovernorEvent{
+			EventId:    mustEncodeEventId(t, int64(i), 0),
+			ContractId: id,
+			EventType:  "proposal_canceled",
+			EventData:  `{}`,
+			TxHash:     "tx",
+		}
+		if err := store.InsertEvent(ctx, event); err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+		failedEvent := &governor.FailedEvent{
+			EventId:    mustEncodeEventId(t, int64(i), 1),
+			ContractId: id,
+			EventType:  "vote_cast",
+			Reason:     governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+			TxHash:     "tx",
+		}
+		if err := store.InsertFailedEvent(ctx, failedEvent); err != nil {
+			t.Fatalf("failed to insert failed event: %v", err)
+		}
+		failedTxEvent := &governor.FailedTxEvent{
+			EventId:    mustEncodeEventId(t, int64(i), 3),
+			ContractId: id,
+			EventType:  "vote_cast",
+			EventData:  `{}`,
+			TxHash:     "tx",
+			ResultCode: "TxFailed",
+		}
+		if err := store.InsertFailedTxEvent(ctx, failedTxEvent); err != nil {
+			t.Fatalf("failed to insert failed tx event: %v", err)
+		}
+		proposal := &governor.Proposal{
+			ProposalKey:  governor.EncodeProposalKey(id, uint32(i)),
+			ContractId:   id,
+			ProposalId:   uint32(i),
+			VotesFor:     "0",
+			VotesAgainst: "0",
+			VotesAbstain: "0",
+		}
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatalf("failed to insert proposal: %v", err)
+		}
+		vote := &governor.Vote{
+			TxHash:     fmt.Sprintf("tx_vote_%d", i),
+			ContractId: id,
+			ProposalId: uint32(i),
+		}
+		if err := store.InsertVote(ctx, vote); err != nil {
+			t.Fatalf("failed to insert vote: %v", err)
+		}
+		delegation := &governor.Delegation{
+			EventId:    mustEncodeEventId(t, int64(i), 2),
+			ContractId: id,
+			Delegator:  "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			TxHash:     "tx",
+		}
+		if err := store.InsertDelegation(ctx, delegation); err != nil {
+			t.Fatalf("failed to insert delegation: %v", err)
+		}
+		content := &governor.ProposalContent{
+			ProposalKey: proposal.ProposalKey,
+			ContractId:  id,
+			ProposalId:  uint32(i),
+			Status:      governor.CONTENT_STATUS_PENDING,
+		}
+		if err := store.UpsertProposalContent(ctx, content); err != nil {
+			t.Fatalf("failed to insert proposal content: %v", err)
+		}
+		snapshot := &Snapshot{ContractId: id, LedgerSeq: uint32(i), Proposals: []*governor.Proposal{proposal}}
+		if err := store.InsertSnapshot(ctx, snapshot); err != nil {
+			t.Fatalf("failed to insert snapshot: %v", err)
+		}
+		settings := &governor.Settings{GovernorId: id, CountingType: 0b111, LedgerSeq: uint32(i)}
+		if err := store.UpsertGovernorSettings(ctx, settings); err != nil {
+			t.Fatalf("failed to insert governor settings: %v", err)
+		}
+		if err := store.UpsertContractActivity(ctx, id, event.EventId, uint32(i), int64(i), true); err != nil {
+			t.Fatalf("failed to register contract: %v", err)
+		}
+		if err := store.UpsertContractMetadata(ctx, &ContractMetadata{ContractId: id, Name: "Governor"}, true); err != nil {
+			t.Fatalf("failed to set contract metadata: %v", err)
+		}
+	}
+	// the contract's votes contract, and a governor using the contract as its votes contract
+	votesContracts := map[string]string{
+		contractId: "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW",
+		"CAS3J7GYLGXMF6TDJBBYYSE3HQ6BBSMLNUQ34T6TZMYMW2EVH34XOWMA": contractId,
+		otherContractId: "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW",
+	}
+	for governorId, votesId := range votesContracts {
+		if err := store.UpsertVotesContract(ctx, governorId, votesId, 1); err != nil {
+			t.Fatalf("failed to upsert votes contract: %v", err)
+		}
+	}
+
+	deleted, err := store.DeleteContractData(ctx, contractId)
+	if err != nil {
+		t.Fatalf("failed to delete contract data: %v", err)
+	}
+	wantDeleted := map[string]int64{"history": 2, "failed_events": 2, "failed_tx_events": 2, "proposals": 2, "votes": 2, "delegations": 2, "proposal_content": 2, "snapshots": 2, "governor_settings": 2, "votes_contracts": 2, "contract_metadata": 1, "contracts": 1}
+	if diff := cmp.Diff(wantDeleted, deleted); diff != "" {
+		t.Errorf("deleted mismatch (-want +got):\n%s", diff)
+	}
+
+	// the contract is no longer registered, but the other contract is
+	contracts, err := store.GetContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get contracts: %v", err)
+	}
+	var contractIds []string
+	for _, contract := range contracts {
+		contractIds = append(contractIds, contract.ContractId)
+	}
+	if diff := cmp.Diff([]string{otherContractId}, contractIds); diff != "" {
+		t.Errorf("contracts mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := store.GetContract(ctx, contractId); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContract() error = %v, want ErrNotFound", err)
+	}
+
+	events, err := store.GetEventsByContractId(ctx, contractId, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events, got %d", len(events))
+	}
+	otherEvents, err := store.GetEventsByContractId(ctx, otherContractId, Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(otherEvents) != 1 {
+		t.Errorf("expected other contract events to remain, got %d", len(otherEvents))
+	}
+	// the votes contract shared with the other governor is still tracked
+	if tracked, err := store.IsVotesContract(ctx, votesContracts[otherContractId]); err != nil || !tracked {
+		t.Errorf("IsVotesContract() = %v, %v, want the other governor's votes contract kept", tracked, err)
+	}
+	if tracked, err := store.IsVotesContract(ctx, contractId); err != nil || tracked {
+		t.Errorf("IsVotesContract() = %v, %v, want the contract no longer a votes contract", tracked, err)
+	}
+
+	// verify blocklist
+	blocked, err := store.IsContractBlocked(ctx, contractId)
+	if err != nil {
+		t.Fatalf("failed to check blocklist: %v", err)
+	}
+	if blocked {
+		t.Errorf("expected contract not to be blocked")
+	}
+	if err := store.BlockContract(ctx, contractId, "spam", 1761053046); err != nil {
+		t.Fatalf("failed to block contract: %v", err)
+	}
+	if err := store.BlockContract(ctx, contractId, "spam again", 1761053047); err != nil {
+		t.Fatalf("failed to block contract twice: %v", err)
+	}
+	blocked, err = store.IsContractBlocked(ctx, contractId)
+	if err != nil {
+		t.Fatalf("failed to check blocklist: %v", err)
+	}
+	if !blocked {
+		t.Errorf("expected contract to be blocked")
+	}
+
+	gotBlocked, err := store.GetBlockedContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get blocked contracts: %v", err)
+	}
+	if diff := cmp.Diff([]*BlockedContract{{ContractId: contractId, Reason: "spam", CreatedAt: 1761053046}}, gotBlocked); diff != "" {
+		t.Errorf("blocked contracts mismatch (-want +got):\n%s", diff)
+	}
+	if err := store.UnblockContract(ctx, contractId); err != nil {
+		t.Fatalf("failed to unblock contract: %v", err)
+	}
+	if err := store.UnblockContract(ctx, contractId); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UnblockContract() for a contract that is not blocked error = %v, want ErrNotFound", err)
+	}
+	gotBlocked, err = store.GetBlockedContracts(ctx)
+	if err != nil {
+		t.Fatalf("failed to get blocked contracts: %v", err)
+	}
+	if len(gotBlocked) != 0 {
+		t.Errorf("got %d blocked contracts after unblocking, want 0", len(gotBlocked))
+	}
 }
@@ -0,0 +1,150 @@
+package governor
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// ACTION_TYPE_CALLDATA is a proposal calling a function on a contract
+	ACTION_TYPE_CALLDATA = "calldata"
+	// ACTION_TYPE_UPGRADE is a proposal upgrading the governor's contract code
+	ACTION_TYPE_UPGRADE = "upgrade"
+	// ACTION_TYPE_SETTINGS is a proposal changing the governor's settings
+	ACTION_TYPE_SETTINGS = "settings"
+	// ACTION_TYPE_COUNCIL is a proposal changing the governor's security council
+	ACTION_TYPE_COUNCIL = "council"
+	// ACTION_TYPE_SNAPSHOT is a proposal without an action, only recording the vote
+	ACTION_TYPE_SNAPSHOT = "snapshot"
+	// ACTION_TYPE_UNKNOWN is a proposal whose action could not be decoded, such as an oversized action or a variant
+	// added by a newer contract release
+	ACTION_TYPE_UNKNOWN = "unknown"
+)
+
+// ACTION_TYPES are the classifications of proposal actions
+var ACTION_TYPES = []string{
+	ACTION_TYPE_CALLDATA,
+	ACTION_TYPE_UPGRADE,
+	ACTION_TYPE_SETTINGS,
+	ACTION_TYPE_COUNCIL,
+	ACTION_TYPE_SNAPSHOT,
+	ACTION_TYPE_UNKNOWN,
+}
+
+// ProposalAction is the classification of a proposal's action
+type ProposalAction struct {
+	// One of ACTION_TYPES
+	Type string
+	// Contract and function called by a calldata action, empty for other types
+	ContractId string
+	Function   string
+}
+
+// ClassifyAction classifies a proposal action, as a base64-encoded XDR string. Actions that can't be decoded are
+// classified as ACTION_TYPE_UNKNOWN, so an unexpected action never fails ingestion.
+func ClassifyAction(action string) ProposalAction {
+	var val xdr.ScVal
+	if err := xdr.SafeUnmarshalBase64(action, &val); err != nil {
+		return ProposalAction{Type: ACTION_TYPE_UNKNOWN}
+	}
+	classified, err := decodeAction(val)
+	if err != nil {
+		return ProposalAction{Type: ACTION_TYPE_UNKNOWN}
+	}
+	return classified
+}
+
+// decodeAction decodes the governor's ProposalAction enum, a vec of the variant symbol followed by its value
+func decodeAction(val xdr.ScVal) (ProposalAction, error) {
+	vec, ok := val.GetVec()
+	if !ok || vec == nil || len(*vec) == 0 {
+		return ProposalAction{}, fmt.Errorf("action is not an enum")
+	}
+	variant, ok := (*vec)[0].GetSym()
+	if !ok {
+		return ProposalAction{}, fmt.Errorf("action variant is not a symbol")
+	}
+	values := (*vec)[1:]
+
+	switch string(variant) {
+	case "Calldata":
+		if len(values) != 1 {
+			return ProposalAction{}, fmt.Errorf("calldata action has %d values", len(values))
+		}
+		return decodeCalldata(values[0])
+	case "Upgrade":
+		if len(values) != 1 {
+			return ProposalAction{}, fmt.Errorf("upgrade action has %d values", len(values))
+		}
+		if hash, ok := values[0].GetBytes(); !ok || len(hash) != 32 {
+			return ProposalAction{}, fmt.Errorf("upgrade action wasm hash is not 32 bytes")
+		}
+		return ProposalAction{Type: ACTION_TYPE_UPGRADE}, nil
+	case "Settings":
+		if len(values) != 1 {
+			return ProposalAction{}, fmt.Errorf("settings action has %d values", len(values))
+		}
+		if _, ok := values[0].GetMap(); !ok {
+			return ProposalAction{}, fmt.Errorf("settings action is not a map")
+		}
+		return ProposalAction{Type: ACTION_TYPE_SETTINGS}, nil
+	case "Council":
+		if len(values) != 1 {
+			return ProposalAction{}, fmt.Errorf("council action has %d values", len(values))
+		}
+		if _, ok := values[0].GetAddress(); !ok {
+			return ProposalAction{}, fmt.Errorf("council action is not an address")
+		}
+		return ProposalAction{Type: ACTION_TYPE_COUNCIL}, nil
+	case "Snapshot":
+		if len(values) != 0 {
+			return ProposalAction{}, fmt.Errorf("snapshot action has %d values", len(values))
+		}
+		return ProposalAction{Type: ACTION_TYPE_SNAPSHOT}, nil
+	default:
+		return ProposalAction{}, fmt.Errorf("unknown action variant %s", variant)
+	}
+}
+
+// decodeCalldata decodes the contract and function of a calldata map. Its args and auths are not decoded.
+func decodeCalldata(val xdr.ScVal) (ProposalAction, error) {
+	mapData, ok := val.GetMap()
+	if !ok || mapData == nil {
+		return ProposalAction{}, fmt.Errorf("calldata is not a map")
+	}
+	action := ProposalAction{Type: ACTION_TYPE_CALLDATA}
+	for _, entry := range *mapData {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return ProposalAction{}, fmt.Errorf("calldata key is not a symbol")
+		}
+		switch string(key) {
+		case "contract_id":
+			address, ok := entry.Val.GetAddress()
+			if !ok {
+				return ProposalAction{}, fmt.Errorf("calldata contract_id is not an address")
+			}
+			id, ok := address.GetContractId()
+			if !ok {
+				return ProposalAction{}, fmt.Errorf("calldata contract_id is not a contract")
+			}
+			contractId, err := strkey.Encode(strkey.VersionByteContract, id[:])
+			if err != nil {
+				return ProposalAction{}, fmt.Errorf("calldata contract_id: %w", err)
+			}
+			action.ContractId = contractId
+		case "function":
+			function, ok := entry.Val.GetSym()
+			if !ok {
+				return ProposalAction{}, fmt.Errorf("calldata function is not a symbol")
+			}
+			action.Function = string(function)
+		}
+	}
+	if action.ContractId == "" || action.Function == "" {
+		return ProposalAction{}, fmt.Errorf("missing required fields in calldata")
+	}
+	return action, nil
+}
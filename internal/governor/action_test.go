@@ -0,0 +1,80 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestClassifyAction(t *testing.T) {
+	sym := func(s string) xdr.ScVal {
+		v := xdr.ScSymbol(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &v}
+	}
+	enum := func(vals ...xdr.ScVal) xdr.ScVal {
+		vec := xdr.ScVec(vals)
+		p := &vec
+		return xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &p}
+	}
+	scMap := func(entries ...xdr.ScMapEntry) xdr.ScVal {
+		m := xdr.ScMap(entries)
+		p := &m
+		return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &p}
+	}
+	contract := func(b byte) xdr.ScVal {
+		id := xdr.ContractId{b}
+		return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id}}
+	}
+	bytesVal := func(n int) xdr.ScVal {
+		b := xdr.ScBytes(make([]byte, n))
+		return xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &b}
+	}
+	calldata := func(contractId xdr.ScVal, function xdr.ScVal) xdr.ScVal {
+		return enum(sym("Calldata"), scMap(
+			xdr.ScMapEntry{Key: sym("args"), Val: enum()},
+			xdr.ScMapEntry{Key: sym("auths"), Val: enum()},
+			xdr.ScMapEntry{Key: sym("contract_id"), Val: contractId},
+			xdr.ScMapEntry{Key: sym("function"), Val: function},
+		))
+	}
+	encode := func(val xdr.ScVal) string {
+		s, err := xdr.MarshalBase64(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	council := "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl"
+
+	tests := []struct {
+		name   string
+		action string
+		want   ProposalAction
+	}{
+		{
+			name:   "calldata",
+			action: encode(calldata(contract(0xb1), sym("transfer"))),
+			want:   ProposalAction{Type: ACTION_TYPE_CALLDATA, ContractId: "CCYQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAADQ6", Function: "transfer"},
+		},
+		{name: "upgrade", action: encode(enum(sym("Upgrade"), bytesVal(32))), want: ProposalAction{Type: ACTION_TYPE_UPGRADE}},
+		{name: "settings", action: encode(enum(sym("Settings"), scMap())), want: ProposalAction{Type: ACTION_TYPE_SETTINGS}},
+		{name: "council", action: council, want: ProposalAction{Type: ACTION_TYPE_COUNCIL}},
+		{name: "snapshot", action: encode(enum(sym("Snapshot"))), want: ProposalAction{Type: ACTION_TYPE_SNAPSHOT}},
+		{name: "empty", action: "", want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "truncated", action: council[:40], want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "not an enum", action: encode(sym("Council")), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "unknown variant", action: encode(enum(sym("Migrate"), bytesVal(32))), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "upgrade hash too short", action: encode(enum(sym("Upgrade"), bytesVal(31))), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "council without address", action: encode(enum(sym("Council"))), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "calldata function not a symbol", action: encode(calldata(contract(0xb1), bytesVal(1))), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+		{name: "calldata missing contract", action: encode(enum(sym("Calldata"), scMap(xdr.ScMapEntry{Key: sym("function"), Val: sym("transfer")}))), want: ProposalAction{Type: ACTION_TYPE_UNKNOWN}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, ClassifyAction(tt.action)); diff != "" {
+				t.Errorf("ClassifyAction() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
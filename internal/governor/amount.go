@@ -0,0 +1,22 @@
+package governor
+
+import (
+	"fmt"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+	"github.com/stellar/go-stellar-sdk/amount"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ErrInvalidAmount is bigmath.ErrInvalidAmount. It is returned for negative vote amounts, and for vote tallies that are
+// not an i128 between 0 and bigmath.MAX_I128. A contract emitting these is buggy or malicious, so they are never applied.
+var ErrInvalidAmount = bigmath.ErrInvalidAmount
+
+// parseAmount returns a non-negative i128 amount as a decimal string, or ErrInvalidAmount if it is negative
+func parseAmount(field string, val xdr.Int128Parts) (string, error) {
+	str := amount.String128Raw(val)
+	if val.Hi < 0 {
+		return "", fmt.Errorf("%s %s is negative: %w", field, str, ErrInvalidAmount)
+	}
+	return str, nil
+}
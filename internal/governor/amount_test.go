@@ -0,0 +1,56 @@
+package governor
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestNewGovernorEventFromContractEventNegativeAmount(t *testing.T) {
+	// -1 as an i128
+	negative := xdr.Int128Parts{Hi: -1, Lo: math.MaxUint64}
+	negativeVal := xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &negative}
+
+	voteCast := mustDecodeEvent(t, voteCastXdr)
+	(*voteCast.Body.V0.Data.MustVec())[1] = negativeVal
+
+	votingClosed := mustDecodeEvent(t, proposalVotingClosedXdr)
+	(*votingClosed.Body.V0.Data.MustMap())[1].Val = negativeVal
+
+	voteCastV2 := withSchemaVersion(t, voteCastXdr, "v2", "support", "amount")
+	(*voteCastV2.Body.V0.Data.MustMap())[1].Val = negativeVal
+
+	zero := xdr.Int128Parts{}
+	delegateVotesChanged := newDelegationEvent(t, "delegate_votes_changed", mustScAddress(t, testDelegate),
+		xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &zero}, negativeVal)
+
+	tests := []struct {
+		name  string
+		event xdr.ContractEvent
+	}{
+		{name: "vote_cast", event: voteCast},
+		{name: "proposal_voting_closed", event: votingClosed},
+		{name: "vote_cast v2", event: voteCastV2},
+		{name: "delegate_votes_changed", event: delegateVotesChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 0)
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidAmount)
+			}
+		})
+	}
+}
+
+func mustDecodeEvent(t *testing.T, eventXdr string) xdr.ContractEvent {
+	t.Helper()
+	var event xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(eventXdr, &event); err != nil {
+		t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+	}
+	return event
+}
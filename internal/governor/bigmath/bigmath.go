@@ -0,0 +1,121 @@
+// Package bigmath implements arithmetic on vote amounts and tallies, which are non-negative i128s stored as decimal
+// strings. Every result is checked to be an i128 between 0 and MAX_I128, so a tally can't overflow or go negative.
+package bigmath
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MAX_I128 is the largest i128, which bounds every vote amount and vote tally
+var MAX_I128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+
+// ErrInvalidAmount is returned for amounts that are not an integer between 0 and MAX_I128, and for arithmetic whose
+// result is not
+var ErrInvalidAmount = errors.New("invalid vote amount")
+
+// MAX_DECIMALS is the largest number of decimals an amount is formatted with, as an i128 has at most 39 digits
+const MAX_DECIMALS = 38
+
+// PERCENT_DECIMALS is the number of decimals a percentage is formatted with
+const PERCENT_DECIMALS = 4
+
+// ParseAmount parses a decimal vote amount or tally, and returns ErrInvalidAmount if it is not an integer between 0
+// and MAX_I128. Leading zeros are allowed.
+func ParseAmount(str string) (*big.Int, error) {
+	val, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an integer: %w", str, ErrInvalidAmount)
+	}
+	if val.Sign() < 0 || val.Cmp(MAX_I128) > 0 {
+		return nil, fmt.Errorf("%s is not a non-negative i128: %w", str, ErrInvalidAmount)
+	}
+	return val, nil
+}
+
+// AddAmount returns total plus delta, or ErrInvalidAmount if either is invalid or the sum overflows an i128
+func AddAmount(total string, delta string) (string, error) {
+	a, b, err := parsePair(total, delta)
+	if err != nil {
+		return "", err
+	}
+	sum := a.Add(a, b)
+	if sum.Cmp(MAX_I128) > 0 {
+		return "", fmt.Errorf("%s + %s overflows i128: %w", total, delta, ErrInvalidAmount)
+	}
+	return sum.String(), nil
+}
+
+// SubAmount returns total minus delta, or ErrInvalidAmount if either is invalid or the difference is negative
+func SubAmount(total string, delta string) (string, error) {
+	a, b, err := parsePair(total, delta)
+	if err != nil {
+		return "", err
+	}
+	diff := a.Sub(a, b)
+	if diff.Sign() < 0 {
+		return "", fmt.Errorf("%s - %s is negative: %w", total, delta, ErrInvalidAmount)
+	}
+	return diff.String(), nil
+}
+
+// CompareAmounts returns -1 if a is less than b, 0 if they are equal, and 1 if a is greater, or ErrInvalidAmount if
+// either is invalid. Amounts with leading zeros are equal to the same amount without.
+func CompareAmounts(a string, b string) (int, error) {
+	x, y, err := parsePair(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return x.Cmp(y), nil
+}
+
+// FormatAmount returns an amount in the token's smallest unit as a decimal number of tokens with decimals places,
+// without trailing zeros in the fraction, for example "20000000000" with 7 decimals is "2000". The amount is divided
+// with integer arithmetic, so it is exact. Returns ErrInvalidAmount if the amount is invalid, or an error if decimals
+// is over MAX_DECIMALS.
+func FormatAmount(amount string, decimals uint32) (string, error) {
+	val, err := ParseAmount(amount)
+	if err != nil {
+		return "", err
+	}
+	if decimals > MAX_DECIMALS {
+		return "", fmt.Errorf("%d decimals is over the maximum of %d", decimals, MAX_DECIMALS)
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, fraction := new(big.Int).QuoRem(val, unit, new(big.Int))
+	if fraction.Sign() == 0 {
+		return whole.String(), nil
+	}
+	digits := fmt.Sprintf("%0*s", decimals, fraction.String())
+	return whole.String() + "." + strings.TrimRight(digits, "0"), nil
+}
+
+// FormatPercent returns part as a percentage of total with PERCENT_DECIMALS decimals, rounded down, for example 1 of 3
+// is "33.3333". The division is done with integer arithmetic, so the result doesn't depend on float rounding. A total
+// of 0 is "0.0000". part and total must not be negative.
+func FormatPercent(part *big.Int, total *big.Int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(PERCENT_DECIMALS), nil)
+	if total.Sign() == 0 {
+		return fmt.Sprintf("0.%0*d", PERCENT_DECIMALS, 0)
+	}
+	scaled := new(big.Int).Mul(part, big.NewInt(100))
+	scaled.Mul(scaled, unit)
+	scaled.Quo(scaled, total)
+	whole, fraction := scaled.QuoRem(scaled, unit, new(big.Int))
+	return fmt.Sprintf("%s.%0*s", whole.String(), PERCENT_DECIMALS, fraction.String())
+}
+
+// parsePair parses two amounts with ParseAmount
+func parsePair(a string, b string) (*big.Int, *big.Int, error) {
+	x, err := ParseAmount(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err := ParseAmount(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, y, nil
+}
@@ -0,0 +1,190 @@
+package bigmath
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+const (
+	maxI128     = "170141183460469231731687303715884105727"
+	maxI128Plus = "170141183460469231731687303715884105728"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    string
+		wantErr error
+	}{
+		{amount: "0", want: "0"},
+		{amount: "20000000000", want: "20000000000"},
+		{amount: "000123", want: "123"},
+		{amount: "000", want: "0"},
+		{amount: maxI128, want: maxI128},
+		{amount: "0" + maxI128, want: maxI128},
+		{amount: maxI128Plus, wantErr: ErrInvalidAmount},
+		{amount: "-1", wantErr: ErrInvalidAmount},
+		{amount: "", wantErr: ErrInvalidAmount},
+		{amount: " 1", wantErr: ErrInvalidAmount},
+		{amount: "1.5", wantErr: ErrInvalidAmount},
+		{amount: "1e3", wantErr: ErrInvalidAmount},
+		{amount: "0x10", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseAmount(%q) error = %v, want %v", tt.amount, err, tt.wantErr)
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestAddAmount(t *testing.T) {
+	tests := []struct {
+		total   string
+		delta   string
+		want    string
+		wantErr error
+	}{
+		{total: "0", delta: "0", want: "0"},
+		{total: "1234123412434", delta: "20000000000", want: "1254123412434"},
+		{total: "007", delta: "0003", want: "10"},
+		{total: "170141183460469231731687303715884105720", delta: "7", want: maxI128},
+		{total: maxI128, delta: "0", want: maxI128},
+		{total: maxI128, delta: "1", wantErr: ErrInvalidAmount},
+		{total: maxI128, delta: maxI128, wantErr: ErrInvalidAmount},
+		{total: "", delta: "1", wantErr: ErrInvalidAmount},
+		{total: "1", delta: "", wantErr: ErrInvalidAmount},
+		{total: "1", delta: "-1", wantErr: ErrInvalidAmount},
+		{total: maxI128Plus, delta: "0", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := AddAmount(tt.total, tt.delta)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("AddAmount(%q, %q) error = %v, want %v", tt.total, tt.delta, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("AddAmount(%q, %q) = %q, want %q", tt.total, tt.delta, got, tt.want)
+		}
+	}
+}
+
+func TestSubAmount(t *testing.T) {
+	tests := []struct {
+		total   string
+		delta   string
+		want    string
+		wantErr error
+	}{
+		{total: "0", delta: "0", want: "0"},
+		{total: "1254123412434", delta: "20000000000", want: "1234123412434"},
+		{total: "010", delta: "003", want: "7"},
+		{total: maxI128, delta: maxI128, want: "0"},
+		{total: maxI128, delta: "1", want: "170141183460469231731687303715884105726"},
+		{total: "0", delta: "1", wantErr: ErrInvalidAmount},
+		{total: "1", delta: maxI128, wantErr: ErrInvalidAmount},
+		{total: "", delta: "0", wantErr: ErrInvalidAmount},
+		{total: "1", delta: "", wantErr: ErrInvalidAmount},
+		{total: "1", delta: "-1", wantErr: ErrInvalidAmount},
+		{total: maxI128Plus, delta: "1", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := SubAmount(tt.total, tt.delta)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SubAmount(%q, %q) error = %v, want %v", tt.total, tt.delta, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("SubAmount(%q, %q) = %q, want %q", tt.total, tt.delta, got, tt.want)
+		}
+	}
+}
+
+func TestCompareAmounts(t *testing.T) {
+	tests := []struct {
+		a       string
+		b       string
+		want    int
+		wantErr error
+	}{
+		{a: "0", b: "0", want: 0},
+		{a: "1", b: "2", want: -1},
+		{a: "10", b: "9", want: 1},
+		{a: "0010", b: "10", want: 0},
+		{a: maxI128, b: "170141183460469231731687303715884105726", want: 1},
+		{a: "", b: "0", wantErr: ErrInvalidAmount},
+		{a: "0", b: "", wantErr: ErrInvalidAmount},
+		{a: "-1", b: "0", wantErr: ErrInvalidAmount},
+		{a: maxI128Plus, b: maxI128, wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := CompareAmounts(tt.a, tt.b)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CompareAmounts(%q, %q) error = %v, want %v", tt.a, tt.b, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("CompareAmounts(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint32
+		want     string
+		wantErr  bool
+	}{
+		{amount: "20000000000", decimals: 7, want: "2000"},
+		{amount: "20000000001", decimals: 7, want: "2000.0000001"},
+		{amount: "12345678", decimals: 7, want: "1.2345678"},
+		{amount: "1500", decimals: 7, want: "0.00015"},
+		{amount: "0", decimals: 7, want: "0"},
+		{amount: "0012", decimals: 0, want: "12"},
+		{amount: maxI128, decimals: 18, want: "170141183460469231731.687303715884105727"},
+		{amount: maxI128, decimals: MAX_DECIMALS, want: "1.70141183460469231731687303715884105727"},
+		{amount: "1", decimals: MAX_DECIMALS + 1, wantErr: true},
+		{amount: maxI128Plus, decimals: 7, wantErr: true},
+		{amount: "-1", decimals: 7, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := FormatAmount(tt.amount, tt.decimals)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FormatAmount(%q, %d) error = %v, want error %v", tt.amount, tt.decimals, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("FormatAmount(%q, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	i128, _ := new(big.Int).SetString(maxI128, 10)
+	tests := []struct {
+		part  *big.Int
+		total *big.Int
+		want  string
+	}{
+		{part: big.NewInt(521734), total: big.NewInt(1000000), want: "52.1734"},
+		{part: big.NewInt(1), total: big.NewInt(3), want: "33.3333"},
+		{part: big.NewInt(2), total: big.NewInt(3), want: "66.6666"},
+		{part: big.NewInt(5), total: big.NewInt(5), want: "100.0000"},
+		{part: big.NewInt(0), total: big.NewInt(5), want: "0.0000"},
+		{part: big.NewInt(0), total: big.NewInt(0), want: "0.0000"},
+		{part: big.NewInt(1), total: new(big.Int).Add(i128, i128), want: "0.0000"},
+		{part: i128, total: new(big.Int).Add(i128, big.NewInt(1)), want: "99.9999"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatPercent(tt.part, tt.total); got != tt.want {
+			t.Errorf("FormatPercent(%s, %s) = %q, want %q", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
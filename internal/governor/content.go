@@ -0,0 +1,68 @@
+package governor
+
+import "strings"
+
+// IPFS_URI_PREFIX is the prefix of proposal descriptions hosted on IPFS, as ipfs://<cid>[/path]
+const IPFS_URI_PREFIX = "ipfs://"
+
+// Proposal content fetch statuses
+const (
+	// CONTENT_STATUS_PENDING is used for content that has not been fetched yet, and will be retried
+	CONTENT_STATUS_PENDING = "pending"
+	// CONTENT_STATUS_FETCHED is used for content that was fetched and stored
+	CONTENT_STATUS_FETCHED = "fetched"
+	// CONTENT_STATUS_FAILED is used for content that could not be fetched, and will not be retried
+	CONTENT_STATUS_FAILED = "failed"
+)
+
+// ProposalContent is the off-chain content of a proposal whose description is an IPFS URI. The proposal
+// description itself is never modified.
+type ProposalContent struct {
+	// Unique identifier of the proposal, see EncodeProposalKey
+	ProposalKey string
+	// StrKey address of the governor contract
+	ContractId string
+	// The proposal id
+	ProposalId uint32
+	// The proposal description the content was fetched from
+	Uri string
+	// One of the CONTENT_STATUS constants
+	Status string
+	// The fetched content, if Status is CONTENT_STATUS_FETCHED
+	Content string
+	// The Content-Type of the fetched content, as reported by the gateway
+	ContentType string
+	// Number of failed fetch attempts
+	Attempts int
+	// Time (in seconds since epoch) of the next fetch attempt, if Status is CONTENT_STATUS_PENDING
+	NextAttemptAt int64
+	// The error of the last failed fetch attempt
+	LastError string
+	// Time (in seconds since epoch) the content was fetched
+	FetchedAt int64
+}
+
+// IpfsPath returns the "<cid>[/path]" of an ipfs:// proposal description. ok is false if the description is not
+// a single IPFS URI.
+func IpfsPath(description string) (path string, ok bool) {
+	path, ok = strings.CutPrefix(strings.TrimSpace(description), IPFS_URI_PREFIX)
+	if !ok || path == "" || strings.ContainsAny(path, " \t\r\n?#") {
+		return "", false
+	}
+	cid, rest, _ := strings.Cut(path, "/")
+	if cid == "" {
+		return "", false
+	}
+	// the path is appended to the gateway URL, so must not escape the content
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == "." || segment == ".." {
+			return "", false
+		}
+	}
+	for _, c := range cid {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return "", false
+		}
+	}
+	return path, true
+}
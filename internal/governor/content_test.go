@@ -0,0 +1,28 @@
+package governor
+
+import "testing"
+
+func TestIpfsPath(t *testing.T) {
+	tests := []struct {
+		description string
+		wantPath    string
+		wantOk      bool
+	}{
+		{description: "ipfs://bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy", wantPath: "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy", wantOk: true},
+		{description: " ipfs://QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX/proposal.md\n", wantPath: "QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX/proposal.md", wantOk: true},
+		{description: "plz"},
+		{description: "ipfs://"},
+		{description: "ipfs:///proposal.md"},
+		{description: "ipfs://../admin"},
+		{description: "ipfs://QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX/../../admin"},
+		{description: "ipfs://QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX?filename=a"},
+		{description: "See ipfs://QmT5NvUtoM5nWFfrQdVrFtvGfKFmG7AHE8P34isapyhCxX for details"},
+	}
+
+	for _, tt := range tests {
+		path, ok := IpfsPath(tt.description)
+		if path != tt.wantPath || ok != tt.wantOk {
+			t.Errorf("IpfsPath(%q) = (%q, %v), want (%q, %v)", tt.description, path, ok, tt.wantPath, tt.wantOk)
+		}
+	}
+}
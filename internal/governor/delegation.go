@@ -0,0 +1,214 @@
+package governor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// VOTES_INSTANCE_KEY is the governor's instance storage key holding the address of its votes contract
+const VOTES_INSTANCE_KEY = "Votes"
+
+// IsDelegationEventType returns true if the event type is a delegation event emitted by a votes contract.
+// Delegation events have no proposal id.
+func IsDelegationEventType(eventType string) bool {
+	return eventType == "delegate_changed" || eventType == "delegate_votes_changed"
+}
+
+// Event data emitted by a votes contract when an account changes its delegate
+//
+// Topics are ["delegate_changed", delegator: Address], and data is [from_delegate: Address, to_delegate: Address].
+type DelegateChangedData struct {
+	// Address of the account delegating its votes
+	Delegator string `json:"delegator"`
+	// Address of the previous delegate
+	FromDelegate string `json:"from_delegate"`
+	// Address of the new delegate
+	ToDelegate string `json:"to_delegate"`
+}
+
+// NewDelegateChangedDataFromEventBody parses the data of a delegate_changed event. Topics and data fields appended
+// by newer contract releases are logged and ignored.
+func NewDelegateChangedDataFromEventBody(body xdr.ContractEventV0) (*DelegateChangedData, error) {
+	if len(body.Topics) < 2 {
+		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("delegate_changed", "topics", body.Topics[2:])
+
+	delegator, err := addressFromScVal(body.Topics[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegator in event topic: %w", ErrInvalidEventFormat)
+	}
+
+	vecData, ok := body.Data.GetVec()
+	if !ok || vecData == nil {
+		return nil, fmt.Errorf("event data is not a vec %w", ErrInvalidEventFormat)
+	}
+	if len(*vecData) < 2 {
+		return nil, fmt.Errorf("unexpected number of fields in event data: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("delegate_changed", "data", (*vecData)[2:])
+
+	fromDelegate, err := addressFromScVal((*vecData)[0])
+	if err != nil {
+		return nil, fmt.Errorf("from_delegate is not an address %w", ErrEventParsingFailed)
+	}
+	toDelegate, err := addressFromScVal((*vecData)[1])
+	if err != nil {
+		return nil, fmt.Errorf("to_delegate is not an address %w", ErrEventParsingFailed)
+	}
+	return &DelegateChangedData{
+		Delegator:    delegator,
+		FromDelegate: fromDelegate,
+		ToDelegate:   toDelegate,
+	}, nil
+}
+
+// Event data emitted by a votes contract when the votes delegated to an account change
+//
+// Topics are ["delegate_votes_changed", delegate: Address], and data is [previous_votes: i128, new_votes: i128].
+type DelegateVotesChangedData struct {
+	// Address of the delegate
+	Delegate string `json:"delegate"`
+	// Votes delegated before the change
+	PreviousVotes string `json:"previous_votes"`
+	// Votes delegated after the change
+	NewVotes string `json:"new_votes"`
+}
+
+// NewDelegateVotesChangedDataFromEventBody parses the data of a delegate_votes_changed event. Topics and data fields
+// appended by newer contract releases are logged and ignored.
+func NewDelegateVotesChangedDataFromEventBody(body xdr.ContractEventV0) (*DelegateVotesChangedData, error) {
+	if len(body.Topics) < 2 {
+		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("delegate_votes_changed", "topics", body.Topics[2:])
+
+	delegate, err := addressFromScVal(body.Topics[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegate in event topic: %w", ErrInvalidEventFormat)
+	}
+
+	vecData, ok := body.Data.GetVec()
+	if !ok || vecData == nil {
+		return nil, fmt.Errorf("event data is not a vec %w", ErrInvalidEventFormat)
+	}
+	if len(*vecData) < 2 {
+		return nil, fmt.Errorf("unexpected number of fields in event data: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("delegate_votes_changed", "data", (*vecData)[2:])
+
+	previousVotes, ok := (*vecData)[0].GetI128()
+	if !ok {
+		return nil, fmt.Errorf("previous_votes is not an i128 %w", ErrEventParsingFailed)
+	}
+	newVotes, ok := (*vecData)[1].GetI128()
+	if !ok {
+		return nil, fmt.Errorf("new_votes is not an i128 %w", ErrEventParsingFailed)
+	}
+	previousVotesStr, err := parseAmount("previous_votes", previousVotes)
+	if err != nil {
+		return nil, err
+	}
+	newVotesStr, err := parseAmount("new_votes", newVotes)
+	if err != nil {
+		return nil, err
+	}
+	return &DelegateVotesChangedData{
+		Delegate:      delegate,
+		PreviousVotes: previousVotesStr,
+		NewVotes:      newVotesStr,
+	}, nil
+}
+
+// newDelegationEventData parses and JSON encodes the data of a delegation event
+func newDelegationEventData(eventType string, body xdr.ContractEventV0) (string, error) {
+	var data any
+	var err error
+	switch eventType {
+	case "delegate_changed":
+		data, err = NewDelegateChangedDataFromEventBody(body)
+	case "delegate_votes_changed":
+		data, err = NewDelegateVotesChangedDataFromEventBody(body)
+	default:
+		return "", fmt.Errorf("invalid event type %s: %w", eventType, ErrInvalidEventFormat)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal %s event data: %w", eventType, ErrEventParsingFailed)
+	}
+	return string(dataBytes), nil
+}
+
+// Delegation is a change of delegate by an account, as recorded in the delegations table
+type Delegation struct {
+	// Unique identifier of the delegate_changed event
+	EventId string
+	// StrKey address of the votes contract
+	ContractId string
+	// Address of the account delegating its votes
+	Delegator string
+	// Address of the previous delegate
+	FromDelegate string
+	// Address of the new delegate
+	Delegate        string
+	TxHash          string
+	LedgerSeq       uint32
+	LedgerCloseTime int64
+}
+
+func NewDelegationFromDelegateChangedEvent(event *GovernorEvent) (*Delegation, error) {
+	if event.EventType != "delegate_changed" {
+		return nil, fmt.Errorf("invalid event type %s", event.EventType)
+	}
+
+	var data *DelegateChangedData
+	err := json.Unmarshal([]byte(event.EventData), &data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal delegate_changed event data: %w", err)
+	}
+
+	delegation := &Delegation{
+		EventId:         event.EventId,
+		ContractId:      event.ContractId,
+		Delegator:       data.Delegator,
+		FromDelegate:    data.FromDelegate,
+		Delegate:        data.ToDelegate,
+		TxHash:          event.TxHash,
+		LedgerSeq:       event.LedgerSeq,
+		LedgerCloseTime: event.LedgerCloseTime,
+	}
+	return delegation, nil
+}
+
+// VotesContractFromLedgerEntry returns the governor and votes contract addresses from a governor's contract
+// instance ledger entry. ok is false if the entry is not a contract instance with a votes contract in its
+// storage.
+//
+// The governor stores its settings, including the votes contract, in instance storage, and its instance is
+// written whenever a proposal is created.
+func VotesContractFromLedgerEntry(entry *xdr.LedgerEntry) (governorId string, votesId string, ok bool) {
+	governorId, val, ok := instanceValue(entry, VOTES_INSTANCE_KEY)
+	if !ok {
+		return "", "", false
+	}
+	votesId, err := addressFromScVal(val)
+	if err != nil {
+		return "", "", false
+	}
+	return governorId, votesId, true
+}
+
+// addressFromScVal returns the StrKey address of an account or contract address ScVal
+func addressFromScVal(val xdr.ScVal) (string, error) {
+	address, ok := val.GetAddress()
+	if !ok {
+		return "", fmt.Errorf("not an address")
+	}
+	return address.String()
+}
@@ -0,0 +1,150 @@
+package governor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	testVotesId   = "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW"
+	testDelegator = "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q"
+	testDelegate  = "GAAQEAYEAUDAOCAJBIFQYDIOB4IBCEQTCQKRMFYYDENBWHA5DYPSABOV"
+)
+
+func mustScAddress(t *testing.T, address string) xdr.ScVal {
+	t.Helper()
+	var scAddress xdr.ScAddress
+	switch address[0] {
+	case 'G':
+		accountId := xdr.MustAddress(address)
+		scAddress = xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}
+	case 'C':
+		decoded := strkey.MustDecode(strkey.VersionByteContract, address)
+		var contractId xdr.ContractId
+		copy(contractId[:], decoded)
+		scAddress = xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId}
+	default:
+		t.Fatalf("unsupported address %s", address)
+	}
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &scAddress}
+}
+
+// newDelegationEvent creates a delegation event emitted by the votes contract
+func newDelegationEvent(t *testing.T, eventType string, topic xdr.ScVal, data ...xdr.ScVal) xdr.ContractEvent {
+	t.Helper()
+	decoded := strkey.MustDecode(strkey.VersionByteContract, testVotesId)
+	var contractId xdr.ContractId
+	copy(contractId[:], decoded)
+	sym := xdr.ScSymbol(eventType)
+	vec := xdr.ScVec(data)
+	vecPtr := &vec
+	return xdr.ContractEvent{
+		Type:       xdr.ContractEventTypeContract,
+		ContractId: &contractId,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, topic},
+				Data:   xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &vecPtr},
+			},
+		},
+	}
+}
+
+func TestNewGovernorEventFromContractEventDelegation(t *testing.T) {
+	previousVotes := xdr.Int128Parts{Lo: 100}
+	newVotes := xdr.Int128Parts{Lo: 250}
+	tests := []struct {
+		name     string
+		event    xdr.ContractEvent
+		wantType string
+		wantData string
+	}{
+		{
+			name:     "delegate_changed",
+			event:    newDelegationEvent(t, "delegate_changed", mustScAddress(t, testDelegator), mustScAddress(t, testDelegator), mustScAddress(t, testDelegate)),
+			wantType: "delegate_changed",
+			wantData: `{"delegator":"` + testDelegator + `","from_delegate":"` + testDelegator + `","to_delegate":"` + testDelegate + `"}`,
+		},
+		{
+			name:     "delegate_changed to contract",
+			event:    newDelegationEvent(t, "delegate_changed", mustScAddress(t, testDelegator), mustScAddress(t, testDelegate), mustScAddress(t, testVotesId)),
+			wantType: "delegate_changed",
+			wantData: `{"delegator":"` + testDelegator + `","from_delegate":"` + testDelegate + `","to_delegate":"` + testVotesId + `"}`,
+		},
+		{
+			name: "delegate_votes_changed",
+			event: newDelegationEvent(t, "delegate_votes_changed", mustScAddress(t, testDelegate),
+				xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &previousVotes}, xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &newVotes}),
+			wantType: "delegate_votes_changed",
+			wantData: `{"delegate":"` + testDelegate + `","previous_votes":"100","new_votes":"250"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 2)
+			if err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			want := &GovernorEvent{
+				EventId:         "0005025695851872256-0000000002",
+				ContractId:      testVotesId,
+				EventType:       tt.wantType,
+				EventData:       tt.wantData,
+				TxHash:          "hash",
+				LedgerSeq:       1170136,
+				LedgerCloseTime: 1761053046,
+				SchemaVersion:   SCHEMA_V1,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// the delegate must be an address
+	invalid := newDelegationEvent(t, "delegate_changed", mustScAddress(t, testDelegator), mustScAddress(t, testDelegator), xdr.ScVal{Type: xdr.ScValTypeScvVoid})
+	if _, err := NewGovernorEventFromContractEvent(&invalid, "hash", 1170136, 1761053046, 5025695851872256, 2); !errors.Is(err, ErrEventParsingFailed) {
+		t.Errorf("error = %v, want %v", err, ErrEventParsingFailed)
+	}
+}
+
+func TestVotesContractFromLedgerEntry(t *testing.T) {
+	governor, votes, ok := VotesContractFromLedgerEntry(newInstanceEntry(VOTES_INSTANCE_KEY, mustScAddress(t, testVotesId)))
+	if !ok || governor != "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB" || votes != testVotesId {
+		t.Errorf("got (%s, %s, %v), want (CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB, %s, true)", governor, votes, ok, testVotesId)
+	}
+	if _, _, ok := VotesContractFromLedgerEntry(newInstanceEntry("Settings", mustScAddress(t, testVotesId))); ok {
+		t.Errorf("got a votes contract from an instance without one")
+	}
+}
+
+// newInstanceEntry returns the contract instance ledger entry of the governor CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB
+// with key set to val in its instance storage
+func newInstanceEntry(key string, val xdr.ScVal) *xdr.LedgerEntry {
+	decoded := strkey.MustDecode(strkey.VersionByteContract, "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB")
+	var governorId xdr.ContractId
+	copy(governorId[:], decoded)
+	sym := xdr.ScSymbol(key)
+	storage := xdr.ScMap{{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, Val: val}}
+	instance := xdr.ScContractInstance{
+		Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &xdr.Hash{}},
+		Storage:    &storage,
+	}
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &governorId},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+				Durability: xdr.ContractDataDurabilityPersistent,
+				Val:        xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &instance},
+			},
+		},
+	}
+}
@@ -1,11 +1,14 @@
 package governor
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 
-	"github.com/stellar/go-stellar-sdk/amount"
 	"github.com/stellar/go-stellar-sdk/strkey"
 	"github.com/stellar/go-stellar-sdk/xdr"
 )
@@ -13,16 +16,69 @@ import (
 var (
 	ErrInvalidEventFormat = errors.New("event format is not valid")
 	ErrEventParsingFailed = errors.New("governor event parsing failed")
+	// ErrUnknownEventType is returned for events laid out like a governor event, with an event type this indexer
+	// does not know. These may be emitted by a newer governor release, or by an unrelated contract.
+	ErrUnknownEventType = errors.New("unknown governor event type")
+	// ErrInvalidEventData is returned for event data that doesn't decode into the data of its event type
+	ErrInvalidEventData = errors.New("invalid governor event data")
+	// ErrInvalidEventId is returned when encoding an event id from a negative toid or event index, which would not
+	// sort in event order
+	ErrInvalidEventId = errors.New("invalid event id")
 )
 
+// Errors for events that are rejected before parsing. These are created once, as they are returned
+// for nearly every event on the network.
+var (
+	errNotContractEvent  = fmt.Errorf("not contract event: %w", ErrInvalidEventFormat)
+	errNotGovernorEvent  = fmt.Errorf("not governor event: %w", ErrInvalidEventFormat)
+	errInvalidEventTopic = fmt.Errorf("invalid event topic: %w", ErrInvalidEventFormat)
+	errUnreadableBody    = fmt.Errorf("unable to read body: %w", ErrEventParsingFailed)
+)
+
+// logger traces parsed governor events at debug level. It is nil unless set with SetLogger.
+var logger *slog.Logger
+
+// SetLogger sets the logger used to trace parsed governor events. Events are logged at debug level,
+// one line per governor event. Pass nil to disable tracing.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
 // Construct a unique eventId for an event, using the eventId pattern from the Stellar RPC.
 //
+// Event ids are compared as strings for ordering and cursor pagination, so each part is zero-padded to a fixed width:
+// 19 digits for the toid and 10 for the event index, which hold any non-negative int64 and int32. A negative toid or
+// event index would be encoded with a sign and break the ordering, so is rejected with ErrInvalidEventId.
+//
 // Ref: https://developers.stellar.org/docs/data/apis/rpc/api-reference/methods/getEvents
-func EncodeEventId(toid int64, eventIndex int32) string {
+func EncodeEventId(toid int64, eventIndex int32) (string, error) {
+	if toid < 0 {
+		return "", fmt.Errorf("%w: negative toid %d", ErrInvalidEventId, toid)
+	}
+	if eventIndex < 0 {
+		return "", fmt.Errorf("%w: negative event index %d", ErrInvalidEventId, eventIndex)
+	}
 	opToidString := fmt.Sprintf("%019d", toid)
 	eventIndexString := fmt.Sprintf("%010d", eventIndex)
 
-	return opToidString + "-" + eventIndexString
+	return opToidString + "-" + eventIndexString, nil
+}
+
+// DecodeEventId returns the operation toid and event index of an event id created by EncodeEventId
+func DecodeEventId(eventId string) (int64, int32, error) {
+	opToidString, eventIndexString, ok := strings.Cut(eventId, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid event id %q", eventId)
+	}
+	opToid, err := strconv.ParseInt(opToidString, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id %q: %w", eventId, err)
+	}
+	eventIndex, err := strconv.ParseInt(eventIndexString, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid event id %q: %w", eventId, err)
+	}
+	return opToid, int32(eventIndex), nil
 }
 
 type GovernorEvent struct {
@@ -38,52 +94,98 @@ type GovernorEvent struct {
 	EventData string
 	// Transaction hash that triggered the event
 	TxHash string
+	// Source account of the transaction that triggered the event, as a G address. For fee bump transactions, this is
+	// the source account of the inner transaction, which submitted it, not the fee payer. Set by the indexer from the
+	// transaction envelope, and empty for events indexed before it was recorded.
+	SourceAccount string
 	// Ledger sequence when the event was emitted
 	LedgerSeq uint32
 	// Ledger close time (in seconds since epoch) for the ledger the event was emitted
 	LedgerCloseTime int64
+	// Schema version of the event layout the event was parsed with
+	SchemaVersion uint32
+	// The contract event, as a base64-encoded XDR string. Empty for events indexed before it was stored, and omitted
+	// from the API unless requested.
+	EventXdr string `json:",omitempty"`
 }
 
+// NewGovernorEventFromContractEvent parses a governor event from a contract event. Events that are not
+// governor events are rejected with an error wrapping ErrInvalidEventFormat, and governor events with a
+// schema version newer than this indexer supports are rejected with ErrUnknownSchemaVersion. Events with an
+// unknown event type, but a proposal id or version topic like a governor event, are rejected with
+// ErrUnknownEventType.
+//
+// Delegation events emitted by votes contracts are also parsed, with a ProposalId of 0. Any contract can emit
+// events with these names, so callers must check they were emitted by a governor's votes contract.
+//
+// Most events on the network are not governor events, so they are rejected before any allocation.
 func NewGovernorEventFromContractEvent(ce *xdr.ContractEvent, txHash string, ledgerSeq uint32, ledgerCloseTime int64, toid int64, eventIndex int32) (*GovernorEvent, error) {
 	if ce.Type != xdr.ContractEventTypeContract ||
 		ce.ContractId == nil ||
 		ce.Body.V != 0 {
-		return nil, fmt.Errorf("not contract event: %w", ErrInvalidEventFormat)
-	}
-
-	contractId, err := strkey.Encode(strkey.VersionByteContract, ce.ContractId[:])
-	if err != nil {
-		return nil, fmt.Errorf("unable to encode contractId: %w", ErrEventParsingFailed)
+		return nil, errNotContractEvent
 	}
 
 	eventBody, ok := ce.Body.GetV0()
 	if !ok {
-		return nil, fmt.Errorf("unable to read body: %w", ErrEventParsingFailed)
+		return nil, errUnreadableBody
 	}
 
-	eventId := EncodeEventId(toid, eventIndex)
-
 	if len(eventBody.Topics) < 2 {
-		return nil, fmt.Errorf("not governor event: %w", ErrInvalidEventFormat)
+		return nil, errNotGovernorEvent
 	}
 
 	// all events have topic[0] = event type and topic[1] = proposal id
 	eventTypeXdr, ok := eventBody.Topics[0].GetSym()
 	if !ok {
-		return nil, fmt.Errorf("not governor event: %w", ErrInvalidEventFormat)
+		return nil, errNotGovernorEvent
 	}
 	eventType := string(eventTypeXdr)
+	if !isGovernorEventType(eventType) {
+		if hasGovernorTopics(eventBody) {
+			return nil, ErrUnknownEventType
+		}
+		return nil, errNotGovernorEvent
+	}
 
-	proposalIdXdr, ok := eventBody.Topics[1].GetU32()
-	if !ok {
-		return nil, fmt.Errorf("invalid event topic: %w", ErrInvalidEventFormat)
+	// delegation events are emitted by the votes contract, and have no version or proposal id topic
+	version := SCHEMA_V1
+	var proposalId uint32
+	if !IsDelegationEventType(eventType) {
+		var proposalIdTopic int
+		var err error
+		version, proposalIdTopic, err = parseSchemaVersion(eventBody)
+		if err != nil {
+			return nil, err
+		}
+		if len(eventBody.Topics) <= proposalIdTopic {
+			return nil, errInvalidEventTopic
+		}
+		proposalIdXdr, ok := eventBody.Topics[proposalIdTopic].GetU32()
+		if !ok {
+			return nil, errInvalidEventTopic
+		}
+		proposalId = uint32(proposalIdXdr)
+	}
+
+	contractId, err := strkey.Encode(strkey.VersionByteContract, ce.ContractId[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode contractId: %w", ErrEventParsingFailed)
+	}
+	eventId, err := EncodeEventId(toid, eventIndex)
+	if err != nil {
+		return nil, err
 	}
-	proposalId := uint32(proposalIdXdr)
 
 	var eventData string
 	switch eventType {
 	case "proposal_created":
-		proposalCreatedData, err := NewProposalCreatedDataFromEventBody(eventBody)
+		var proposalCreatedData *ProposalCreatedData
+		if version == SCHEMA_V2 {
+			proposalCreatedData, err = NewProposalCreatedDataFromEventBodyV2(eventBody)
+		} else {
+			proposalCreatedData, err = NewProposalCreatedDataFromEventBody(eventBody)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -98,7 +200,12 @@ func NewGovernorEventFromContractEvent(ce *xdr.ContractEvent, txHash string, led
 		// no additional data
 		eventData = "{}"
 	case "proposal_voting_closed":
-		votingClosedData, err := NewProposalVotingClosedDataFromEventBody(eventBody)
+		var votingClosedData *ProposalVotingClosedData
+		if version == SCHEMA_V2 {
+			votingClosedData, err = NewProposalVotingClosedDataFromEventBodyV2(eventBody)
+		} else {
+			votingClosedData, err = NewProposalVotingClosedDataFromEventBody(eventBody)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -116,7 +223,12 @@ func NewGovernorEventFromContractEvent(ce *xdr.ContractEvent, txHash string, led
 		// no additional data
 		eventData = "{}"
 	case "vote_cast":
-		voteCastData, err := NewVoteCastDataFromEventBody(eventBody)
+		var voteCastData *VoteCastData
+		if version == SCHEMA_V2 {
+			voteCastData, err = NewVoteCastDataFromEventBodyV2(eventBody)
+		} else {
+			voteCastData, err = NewVoteCastDataFromEventBody(eventBody)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -127,6 +239,11 @@ func NewGovernorEventFromContractEvent(ce *xdr.ContractEvent, txHash string, led
 		}
 
 		eventData = string(dataBytes)
+	case "delegate_changed", "delegate_votes_changed":
+		eventData, err = newDelegationEventData(eventType, eventBody)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("invalid event type %s: %w", eventType, ErrInvalidEventFormat)
 	}
@@ -140,10 +257,111 @@ func NewGovernorEventFromContractEvent(ce *xdr.ContractEvent, txHash string, led
 		TxHash:          txHash,
 		LedgerSeq:       ledgerSeq,
 		LedgerCloseTime: ledgerCloseTime,
+		SchemaVersion:   version,
+	}
+	if logger != nil && logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug("Parsed governor event", "ledger", ledgerSeq, "hash", txHash, "eventId", eventId, "contract", contractId, "type", eventType, "proposal", proposalId, "version", version)
 	}
 	return &ge, nil
 }
 
+// warnExtraContent logs trailing topics or data fields that this version of the indexer does not know about.
+// Newer contract releases may append content to an event, which is ignored so the known fields can still be indexed.
+func warnExtraContent(eventType string, field string, extra []xdr.ScVal) {
+	if len(extra) == 0 {
+		return
+	}
+	extraXdr := make([]string, 0, len(extra))
+	for _, val := range extra {
+		valXdr, err := xdr.MarshalBase64(val)
+		if err != nil {
+			valXdr = fmt.Sprintf("unable to marshal xdr: %v", err)
+		}
+		extraXdr = append(extraXdr, valXdr)
+	}
+	slog.Warn("Ignoring unknown trailing content in governor event", "type", eventType, "field", field, "extra", extraXdr)
+}
+
+// isGovernorEventType returns true if the event type is emitted by the governor or its votes contract
+func isGovernorEventType(eventType string) bool {
+	switch eventType {
+	case "proposal_created", "proposal_canceled", "proposal_voting_closed", "proposal_executed", "proposal_expired", "vote_cast",
+		"delegate_changed", "delegate_votes_changed":
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateEventData returns ErrInvalidEventData if the JSON encoded data of an event is not an object that decodes
+// into the data of its event type, so a corrupted payload is rejected when it is written rather than when the event
+// is applied. Unknown fields are allowed, as with the data of newer releases.
+func ValidateEventData(eventType string, eventData string) error {
+	var data any
+	switch eventType {
+	case "proposal_created":
+		data = &ProposalCreatedData{}
+	case "proposal_voting_closed":
+		data = &ProposalVotingClosedData{}
+	case "vote_cast":
+		data = &VoteCastData{}
+	case "delegate_changed":
+		data = &DelegateChangedData{}
+	case "delegate_votes_changed":
+		data = &DelegateVotesChangedData{}
+	case "proposal_canceled", "proposal_executed", "proposal_expired":
+		data = &struct{}{}
+	default:
+		return fmt.Errorf("unknown event type %s: %w", eventType, ErrInvalidEventData)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(eventData), &fields); err != nil || fields == nil {
+		return fmt.Errorf("%s data is not a JSON object: %w", eventType, ErrInvalidEventData)
+	}
+	if containsNul(fields) {
+		return fmt.Errorf("%s data contains a NUL character: %w", eventType, ErrInvalidEventData)
+	}
+	if err := json.Unmarshal([]byte(eventData), data); err != nil {
+		return fmt.Errorf("%s data: %w: %w", eventType, ErrInvalidEventData, err)
+	}
+	return nil
+}
+
+// containsNul returns true if a decoded JSON value holds a NUL character in any key or string. Postgres can't store
+// the \u0000 escape in a JSONB column.
+func containsNul(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.ContainsRune(v, 0)
+	case []any:
+		for _, item := range v {
+			if containsNul(item) {
+				return true
+			}
+		}
+	case map[string]any:
+		for key, item := range v {
+			if strings.ContainsRune(key, 0) || containsNul(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scString returns a contract string with any NUL characters removed. Contracts can emit them in free-form fields,
+// but they can't be stored in the event history.
+func scString(val xdr.ScString) string {
+	return strings.ReplaceAll(string(val), "\x00", "")
+}
+
+// hasGovernorTopics returns true if topic[1] is a proposal id or a version topic, as in every governor event
+func hasGovernorTopics(body xdr.ContractEventV0) bool {
+	_, _, err := parseSchemaVersion(body)
+	return err == nil || errors.Is(err, ErrUnknownSchemaVersion)
+}
+
 // Event data emitted when a proposal is created
 type ProposalCreatedData struct {
 	// Address of the proposer
@@ -158,43 +376,49 @@ type ProposalCreatedData struct {
 	VoteStart uint32 `json:"vote_start"`
 	// Ledger sequence when voting ends
 	VoteEnd uint32 `json:"vote_end"`
+	// True if the title or description was truncated, or the action dropped, as it was over the field limits
+	Truncated bool `json:"truncated,omitempty"`
 }
 
+// NewProposalCreatedDataFromEventBody parses the data of a proposal_created event. Topics and data fields appended
+// by newer contract releases are logged and ignored. Fields over the limits set with SetFieldLimits are truncated.
 func NewProposalCreatedDataFromEventBody(body xdr.ContractEventV0) (*ProposalCreatedData, error) {
-	if len(body.Topics) != 3 {
+	if len(body.Topics) < 3 {
 		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
 	}
+	warnExtraContent("proposal_created", "topics", body.Topics[3:])
 
-	proposerXdr, ok := body.Topics[2].GetAddress()
-	if !ok {
+	// the proposer may be an account or a contract, such as a smart wallet or multisig
+	proposer, err := addressFromScVal(body.Topics[2])
+	if err != nil {
 		return nil, fmt.Errorf("invalid proposer in event topic: %w", ErrInvalidEventFormat)
 	}
-	proposer := proposerXdr.AccountId.Address()
 
 	vecData, ok := body.Data.GetVec()
 	if !ok {
 		return nil, fmt.Errorf("event data is not a vec %w", ErrInvalidEventFormat)
 	}
-	if len(*vecData) != 5 {
+	if len(*vecData) < 5 {
 		return nil, fmt.Errorf("unexpected number of fields in event data: %w", ErrInvalidEventFormat)
 	}
+	warnExtraContent("proposal_created", "data", (*vecData)[5:])
 
 	var data ProposalCreatedData
 	data.Proposer = proposer
-	for i, entry := range *vecData {
+	for i, entry := range (*vecData)[:5] {
 		switch i {
 		case 0:
 			val, ok := entry.GetStr()
 			if !ok {
 				return nil, fmt.Errorf("title is not a str %w", ErrEventParsingFailed)
 			}
-			data.Title = string(val)
+			data.Title = scString(val)
 		case 1:
 			val, ok := entry.GetStr()
 			if !ok {
 				return nil, fmt.Errorf("desc is not a str  %w", ErrEventParsingFailed)
 			}
-			data.Desc = string(val)
+			data.Desc = scString(val)
 		case 2:
 			valXdr, xdrErr := xdr.MarshalBase64(entry)
 			if xdrErr != nil {
@@ -213,10 +437,9 @@ func NewProposalCreatedDataFromEventBody(body xdr.ContractEventV0) (*ProposalCre
 				return nil, fmt.Errorf("vote_end is not a u32 %w", ErrEventParsingFailed)
 			}
 			data.VoteEnd = uint32(val)
-		default:
-			return nil, fmt.Errorf("too many entries %d %w", i, ErrEventParsingFailed)
 		}
 	}
+	data.applyFieldLimits()
 	return &data, nil
 }
 
@@ -230,10 +453,13 @@ type ProposalVotingClosedData struct {
 	FinalVotes VoteCount `json:"final_votes"`
 }
 
+// NewProposalVotingClosedDataFromEventBody parses the data of a proposal_voting_closed event. Topics appended by
+// newer contract releases are logged and ignored.
 func NewProposalVotingClosedDataFromEventBody(body xdr.ContractEventV0) (*ProposalVotingClosedData, error) {
-	if len(body.Topics) != 4 {
+	if len(body.Topics) < 4 {
 		return nil, fmt.Errorf("unexpected number of topics %d in event: %w", len(body.Topics), ErrInvalidEventFormat)
 	}
+	warnExtraContent("proposal_voting_closed", "topics", body.Topics[4:])
 
 	status, ok := body.Topics[2].GetU32()
 	if !ok {
@@ -246,7 +472,9 @@ func NewProposalVotingClosedDataFromEventBody(body xdr.ContractEventV0) (*Propos
 	}
 
 	finalVotes, err := NewVoteCountFromXDR(body.Data)
-	if err != nil {
+	if errors.Is(err, ErrInvalidAmount) {
+		return nil, fmt.Errorf("unable to parse final votes: %w", err)
+	} else if err != nil {
 		return nil, fmt.Errorf("unable to parse final votes: %w", ErrEventParsingFailed)
 	}
 	data := ProposalVotingClosedData{
@@ -265,30 +493,36 @@ type VoteCastData struct {
 	Support uint32 `json:"support"`
 	// Vote count
 	Amount string `json:"amount"`
+	// Reason given by the voter, empty if the event has none
+	Reason string `json:"reason,omitempty"`
 }
 
+// NewVoteCastDataFromEventBody parses the data of a vote_cast event. The data is the support and amount, optionally
+// followed by the voter's reason as a string. Topics and other data fields appended by newer contract releases are
+// logged and ignored.
 func NewVoteCastDataFromEventBody(body xdr.ContractEventV0) (*VoteCastData, error) {
-	if len(body.Topics) != 3 {
+	if len(body.Topics) < 3 {
 		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
 	}
+	warnExtraContent("vote_cast", "topics", body.Topics[3:])
 
-	voterXdr, ok := body.Topics[2].GetAddress()
-	if !ok {
-		return nil, fmt.Errorf("invalid proposer in event topic: %w", ErrInvalidEventFormat)
+	// the voter may be an account or a contract, such as a smart wallet or multisig
+	voter, err := addressFromScVal(body.Topics[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid voter in event topic: %w", ErrInvalidEventFormat)
 	}
-	voter := voterXdr.AccountId.Address()
 
 	vecData, ok := body.Data.GetVec()
 	if !ok {
 		return nil, fmt.Errorf("event data is not a vec %w", ErrInvalidEventFormat)
 	}
-	if len(*vecData) != 2 {
+	if len(*vecData) < 2 {
 		return nil, fmt.Errorf("unexpected number of fields in event data: %w", ErrInvalidEventFormat)
 	}
 
 	var data VoteCastData
 	data.Voter = voter
-	for i, entry := range *vecData {
+	for i, entry := range (*vecData)[:2] {
 		switch i {
 		case 0:
 			val, ok := entry.GetU32()
@@ -301,10 +535,23 @@ func NewVoteCastDataFromEventBody(body xdr.ContractEventV0) (*VoteCastData, erro
 			if !ok {
 				return nil, fmt.Errorf("amount is not an i128 %w", ErrEventParsingFailed)
 			}
-			data.Amount = amount.String128Raw(val)
-		default:
-			return nil, fmt.Errorf("too many entries %d %w", i, ErrEventParsingFailed)
+			amountStr, err := parseAmount("amount", val)
+			if err != nil {
+				return nil, err
+			}
+			data.Amount = amountStr
+		}
+	}
+
+	// a third string field is the voter's reason. Reasons are free-form like descriptions, so are bounded by the
+	// same limit.
+	extra := (*vecData)[2:]
+	if len(extra) > 0 {
+		if reason, ok := extra[0].GetStr(); ok {
+			data.Reason, _ = truncate(scString(reason), fieldLimits.Description)
+			extra = extra[1:]
 		}
 	}
+	warnExtraContent("vote_cast", "data", extra)
 	return &data, nil
 }
@@ -1,9 +1,18 @@
 package governor
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/toid"
 	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
@@ -13,6 +22,7 @@ func TestEncodeEventId(t *testing.T) {
 		opToid     int64
 		eventIndex int32
 		want       string
+		wantErr    error
 	}{
 		{
 			name:       "all zeros",
@@ -26,11 +36,32 @@ func TestEncodeEventId(t *testing.T) {
 			eventIndex: 999999,
 			want:       "0004752467212378112-0000999999",
 		},
+		{
+			name:       "largest toid and event index",
+			opToid:     math.MaxInt64,
+			eventIndex: math.MaxInt32,
+			want:       "9223372036854775807-2147483647",
+		},
+		{
+			name:       "negative toid",
+			opToid:     -1,
+			eventIndex: 0,
+			wantErr:    ErrInvalidEventId,
+		},
+		{
+			name:       "negative event index",
+			opToid:     4752467212378112,
+			eventIndex: -1,
+			wantErr:    ErrInvalidEventId,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := EncodeEventId(tt.opToid, tt.eventIndex)
+			got, err := EncodeEventId(tt.opToid, tt.eventIndex)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EncodeEventId() error = %v, want %v", err, tt.wantErr)
+			}
 			if got != tt.want {
 				t.Errorf("\nResult = %v\nWant = %v\n", got, tt.want)
 			}
@@ -38,6 +69,65 @@ func TestEncodeEventId(t *testing.T) {
 	}
 }
 
+// TestEncodeEventIdOrder checks that event ids sort as strings in the order of the events' ledger, transaction,
+// operation, and event index, as cursor pagination relies on it
+func TestEncodeEventIdOrder(t *testing.T) {
+	type position struct {
+		ledger, tx, op, index int32
+	}
+	compare := func(a, b position) int {
+		return slices.Compare([]int32{a.ledger, a.tx, a.op, a.index}, []int32{b.ledger, b.tx, b.op, b.index})
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	// each part is drawn from a small range half of the time, so positions often share their leading parts
+	part := func(n int32) int32 {
+		if rng.IntN(2) == 0 {
+			return rng.Int32N(min(n, 4))
+		}
+		return rng.Int32N(n)
+	}
+	random := func() position {
+		return position{part(math.MaxInt32), part(toid.TransactionMask + 1), part(toid.OperationMask + 1), part(math.MaxInt32)}
+	}
+	encode := func(p position) string {
+		t.Helper()
+		eventId, err := EncodeEventId(toid.New(p.ledger, p.tx, p.op).ToInt64(), p.index)
+		if err != nil {
+			t.Fatalf("EncodeEventId(%+v) error = %v", p, err)
+		}
+		return eventId
+	}
+
+	for range 10000 {
+		a, b := random(), random()
+		want := compare(a, b)
+		got := strings.Compare(encode(a), encode(b))
+		if got != want {
+			t.Fatalf("event ids of %+v and %+v compare %d, want %d", a, b, got, want)
+		}
+	}
+}
+
+func TestDecodeEventId(t *testing.T) {
+	eventId, err := EncodeEventId(4752467212378112, 999999)
+	if err != nil {
+		t.Fatalf("EncodeEventId() error = %v", err)
+	}
+	opToid, eventIndex, err := DecodeEventId(eventId)
+	if err != nil {
+		t.Fatalf("DecodeEventId() error = %v", err)
+	}
+	if opToid != 4752467212378112 || eventIndex != 999999 {
+		t.Errorf("DecodeEventId() = %d, %d, want 4752467212378112, 999999", opToid, eventIndex)
+	}
+
+	for _, eventId := range []string{"", "0004752467212378112", "0004752467212378112-", "abc-0000000001", "0004752467212378112-9999999999"} {
+		if _, _, err := DecodeEventId(eventId); err == nil {
+			t.Errorf("DecodeEventId(%q) error = nil, want an error", eventId)
+		}
+	}
+}
+
 func TestNewGovernorEventFromContractEvent(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -66,6 +156,7 @@ func TestNewGovernorEventFromContractEvent(t *testing.T) {
 				TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
 				LedgerSeq:       1170134,
 				LedgerCloseTime: 1761053041,
+				SchemaVersion:   1,
 			},
 		},
 		{
@@ -85,6 +176,7 @@ func TestNewGovernorEventFromContractEvent(t *testing.T) {
 				TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
 				LedgerSeq:       1170136,
 				LedgerCloseTime: 1761053046,
+				SchemaVersion:   1,
 			},
 		},
 		{
@@ -104,6 +196,7 @@ func TestNewGovernorEventFromContractEvent(t *testing.T) {
 				TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
 				LedgerSeq:       1170136,
 				LedgerCloseTime: 1761053046,
+				SchemaVersion:   1,
 			},
 		},
 		{
@@ -123,6 +216,7 @@ func TestNewGovernorEventFromContractEvent(t *testing.T) {
 				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
 				LedgerSeq:       1170137,
 				LedgerCloseTime: 1761053050,
+				SchemaVersion:   1,
 			},
 		},
 	}
@@ -144,3 +238,315 @@ func TestNewGovernorEventFromContractEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestNewGovernorEventFromContractEventExtraContent(t *testing.T) {
+	extra := xdr.Uint32(7)
+	extraVal := xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &extra}
+	appendTopic := func(body *xdr.ContractEventV0) {
+		body.Topics = append(body.Topics, extraVal)
+	}
+	appendData := func(body *xdr.ContractEventV0) {
+		vec := append(*body.Data.MustVec(), extraVal)
+		vecPtr := &vec
+		body.Data.Vec = &vecPtr
+	}
+	appendReason := func(body *xdr.ContractEventV0) {
+		reason := xdr.ScString("too risky")
+		vec := append(*body.Data.MustVec(), xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &reason})
+		vecPtr := &vec
+		body.Data.Vec = &vecPtr
+	}
+	tests := []struct {
+		name      string
+		eventXdr  string
+		mutate    func(body *xdr.ContractEventV0)
+		wantData  string
+		wantWarns int
+	}{
+		{
+			name:      "proposal_created extra topic",
+			eventXdr:  "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAQcHJvcG9zYWxfY3JlYXRlZAAAAAMAAAADAAAAEgAAAAAAAAAALJ/M6wbqSvh6BcSe5KJD8aWHCTFHGu3YUKtUqAH05uUAAAAQAAAAAQAAAAUAAAAOAAAAGE1ha2UgbWUgc2VjdXJpdHkgY291bmNpbAAAAA4AAAADcGx6AAAAABAAAAABAAAAAgAAAA8AAAAHQ291bmNpbAAAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAAAMAEa9sAAAAAwAR8uw=",
+			mutate:    appendTopic,
+			wantData:  `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+			wantWarns: 1,
+		},
+		{
+			name:      "proposal_created extra data field",
+			eventXdr:  "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAQcHJvcG9zYWxfY3JlYXRlZAAAAAMAAAADAAAAEgAAAAAAAAAALJ/M6wbqSvh6BcSe5KJD8aWHCTFHGu3YUKtUqAH05uUAAAAQAAAAAQAAAAUAAAAOAAAAGE1ha2UgbWUgc2VjdXJpdHkgY291bmNpbAAAAA4AAAADcGx6AAAAABAAAAABAAAAAgAAAA8AAAAHQ291bmNpbAAAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAAAMAEa9sAAAAAwAR8uw=",
+			mutate:    appendData,
+			wantData:  `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+			wantWarns: 1,
+		},
+		{
+			name:      "vote_cast extra topic",
+			eventXdr:  "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate:    appendTopic,
+			wantData:  `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000"}`,
+			wantWarns: 1,
+		},
+		{
+			name:     "vote_cast extra topic and data field",
+			eventXdr: "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate: func(body *xdr.ContractEventV0) {
+				appendTopic(body)
+				appendData(body)
+			},
+			wantData:  `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000"}`,
+			wantWarns: 2,
+		},
+		{
+			name:      "vote_cast reason",
+			eventXdr:  "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate:    appendReason,
+			wantData:  `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000","reason":"too risky"}`,
+			wantWarns: 0,
+		},
+		{
+			name:     "vote_cast reason with NUL characters",
+			eventXdr: "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate: func(body *xdr.ContractEventV0) {
+				reason := xdr.ScString("too\x00 risky\x00")
+				vec := append(*body.Data.MustVec(), xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &reason})
+				vecPtr := &vec
+				body.Data.Vec = &vecPtr
+			},
+			wantData:  `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000","reason":"too risky"}`,
+			wantWarns: 0,
+		},
+		{
+			name:     "vote_cast reason and extra data field",
+			eventXdr: "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate: func(body *xdr.ContractEventV0) {
+				appendReason(body)
+				appendData(body)
+			},
+			wantData:  `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000","reason":"too risky"}`,
+			wantWarns: 1,
+		},
+		{
+			name:      "proposal_voting_closed extra topic",
+			eventXdr:  "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAABAAAAA8AAAAWcHJvcG9zYWxfdm90aW5nX2Nsb3NlZAAAAAAAAwAAAAEAAAADAAAAAgAAAAMAAAAAAAAAEQAAAAEAAAADAAAADwAAAARfZm9yAAAACgAAAAAAAAAAAAAAAElQT4AAAAAPAAAAB2Fic3RhaW4AAAAACgAAAAAAAAAAAAAAAAAAAAAAAAAPAAAAB2FnYWluc3QAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate:    appendTopic,
+			wantData:  `{"status":2,"eta":0,"final_votes":{"for":"1230000000","against":"20000000000","abstain":"0"}}`,
+			wantWarns: 1,
+		},
+		{
+			name:     "proposal_voting_closed extra vote count",
+			eventXdr: "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAABAAAAA8AAAAWcHJvcG9zYWxfdm90aW5nX2Nsb3NlZAAAAAAAAwAAAAEAAAADAAAAAgAAAAMAAAAAAAAAEQAAAAEAAAADAAAADwAAAARfZm9yAAAACgAAAAAAAAAAAAAAAElQT4AAAAAPAAAAB2Fic3RhaW4AAAAACgAAAAAAAAAAAAAAAAAAAAAAAAAPAAAAB2FnYWluc3QAAAAACgAAAAAAAAAAAAAABKgXyAA=",
+			mutate: func(body *xdr.ContractEventV0) {
+				key := xdr.ScSymbol("quorum")
+				m := append(*body.Data.MustMap(), xdr.ScMapEntry{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &key}, Val: extraVal})
+				mp := &m
+				body.Data.Map = &mp
+			},
+			wantData:  `{"status":2,"eta":0,"final_votes":{"for":"1230000000","against":"20000000000","abstain":"0"}}`,
+			wantWarns: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ce xdr.ContractEvent
+			if err := xdr.SafeUnmarshalBase64(tt.eventXdr, &ce); err != nil {
+				t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+			}
+			tt.mutate(ce.Body.V0)
+
+			var buf bytes.Buffer
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+			t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+			got, err := NewGovernorEventFromContractEvent(&ce, "hash", 1170136, 1761053046, 5025695851872256, 0)
+			if err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantData, got.EventData); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if warns := strings.Count(buf.String(), "level=WARN"); warns != tt.wantWarns {
+				t.Errorf("logged %d warnings, want %d: %q", warns, tt.wantWarns, buf.String())
+			}
+		})
+	}
+}
+
+// unrelatedEvents creates n contract events that are not governor events, similar to the token transfers
+// that make up most events on the network
+func unrelatedEvents(n int) []xdr.ContractEvent {
+	contractId := xdr.ContractId{1, 2, 3}
+	from := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId}
+	symbol := xdr.ScSymbol("transfer")
+	amount := xdr.Int128Parts{Lo: 100}
+	events := make([]xdr.ContractEvent, n)
+	for i := range events {
+		events[i] = xdr.ContractEvent{
+			Type:       xdr.ContractEventTypeContract,
+			ContractId: &contractId,
+			Body: xdr.ContractEventBody{
+				V: 0,
+				V0: &xdr.ContractEventV0{
+					Topics: []xdr.ScVal{
+						{Type: xdr.ScValTypeScvSymbol, Sym: &symbol},
+						{Type: xdr.ScValTypeScvAddress, Address: &from},
+						{Type: xdr.ScValTypeScvAddress, Address: &from},
+					},
+					Data: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &amount},
+				},
+			},
+		}
+	}
+	return events
+}
+
+func TestNewGovernorEventFromContractEventUnrelated(t *testing.T) {
+	events := unrelatedEvents(1)
+	event := &events[0]
+
+	_, err := NewGovernorEventFromContractEvent(event, "hash", 100, 1000, 1, 0)
+	if !errors.Is(err, ErrInvalidEventFormat) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidEventFormat)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		NewGovernorEventFromContractEvent(event, "hash", 100, 1000, 1, 0)
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestNewGovernorEventFromContractEventUnknownType(t *testing.T) {
+	// withEventType returns a copy of the event with its event type topic replaced
+	withEventType := func(ce xdr.ContractEvent, eventType string) xdr.ContractEvent {
+		body := *ce.Body.V0
+		sym := xdr.ScSymbol(eventType)
+		body.Topics = append([]xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &sym}}, body.Topics[1:]...)
+		ce.Body.V0 = &body
+		return ce
+	}
+	var canceled xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(proposalCanceledXdr, &canceled); err != nil {
+		t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		event   xdr.ContractEvent
+		wantErr error
+	}{
+		{
+			name:    "v1",
+			event:   withEventType(canceled, "proposal_queued"),
+			wantErr: ErrUnknownEventType,
+		},
+		{
+			name:    "v2",
+			event:   withEventType(withSchemaVersion(t, proposalCanceledXdr, "v2"), "proposal_queued"),
+			wantErr: ErrUnknownEventType,
+		},
+		{
+			name:    "unknown version",
+			event:   withEventType(withSchemaVersion(t, proposalCanceledXdr, "v3"), "proposal_queued"),
+			wantErr: ErrUnknownEventType,
+		},
+		{
+			name:    "unrelated",
+			event:   unrelatedEvents(1)[0],
+			wantErr: ErrInvalidEventFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEventData(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		eventData string
+		wantErr   bool
+	}{
+		{name: "vote_cast", eventType: "vote_cast", eventData: `{"voter":"GA","support":1,"amount":"100","reason":"yes"}`},
+		{name: "unknown fields", eventType: "vote_cast", eventData: `{"voter":"GA","support":1,"amount":"100","weight":2}`},
+		{name: "proposal_created", eventType: "proposal_created", eventData: `{"proposer":"GA","title":"t","vote_start":1,"vote_end":2}`},
+		{name: "no data", eventType: "proposal_executed", eventData: `{}`},
+		{name: "delegate_changed", eventType: "delegate_changed", eventData: `{"delegator":"GA","from_delegate":"GB","to_delegate":"GC"}`},
+		{name: "wrong field type", eventType: "vote_cast", eventData: `{"voter":"GA","support":"for","amount":"100"}`, wantErr: true},
+		{name: "not an object", eventType: "proposal_canceled", eventData: `[]`, wantErr: true},
+		{name: "null", eventType: "proposal_expired", eventData: `null`, wantErr: true},
+		{name: "empty", eventType: "proposal_executed", eventData: ``, wantErr: true},
+		{name: "not json", eventType: "delegate_votes_changed", eventData: `{"delegate":`, wantErr: true},
+		{name: "unknown event type", eventType: "proposal_vetoed", eventData: `{}`, wantErr: true},
+		{name: "NUL character", eventType: "vote_cast", eventData: `{"voter":"GA","support":1,"amount":"100","reason":"a\u0000b"}`, wantErr: true},
+		{name: "NUL character in unknown field", eventType: "proposal_executed", eventData: `{"notes":["\u0000"]}`, wantErr: true},
+		{name: "escaped backslash", eventType: "vote_cast", eventData: `{"voter":"GA","support":1,"amount":"100","reason":"a\\u0000b"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEventData(tt.eventType, tt.eventData)
+			if tt.wantErr && !errors.Is(err, ErrInvalidEventData) {
+				t.Errorf("ValidateEventData() error = %v, want ErrInvalidEventData", err)
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("ValidateEventData() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	var ce xdr.ContractEvent
+	err := xdr.SafeUnmarshalBase64("AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAgAAAA8AAAARcHJvcG9zYWxfY2FuY2VsZWQAAAAAAAADAAAAAwAAAAE=", &ce)
+	if err != nil {
+		t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+	}
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	for _, event := range append(unrelatedEvents(3), ce) {
+		NewGovernorEventFromContractEvent(&event, "hash", 1170136, 1761053046, 5025695851872256, 0)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("failed to parse log line %q: %v", lines[0], err)
+	}
+	delete(got, "time")
+	want := map[string]any{
+		"level":    "DEBUG",
+		"msg":      "Parsed governor event",
+		"ledger":   float64(1170136),
+		"hash":     "hash",
+		"eventId":  "0005025695851872256-0000000000",
+		"contract": "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+		"type":     "proposal_canceled",
+		"proposal": float64(3),
+		"version":  float64(1),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// BenchmarkNewGovernorEventFromContractEventUnrelated parses a ledger's worth of events that are not governor events
+func BenchmarkNewGovernorEventFromContractEventUnrelated(b *testing.B) {
+	events := unrelatedEvents(500)
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := range events {
+			NewGovernorEventFromContractEvent(&events[i], "hash", 100, 1000, 1, int32(i))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package governor
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// Reasons a governor event could not be indexed
+const (
+	// FAILED_REASON_UNKNOWN_SCHEMA_VERSION is used for events with a schema version newer than the indexer supports
+	FAILED_REASON_UNKNOWN_SCHEMA_VERSION = "unknown_schema_version"
+	// FAILED_REASON_UNKNOWN_EVENT_TYPE is used for events from a tracked contract with an event type the indexer
+	// does not know
+	FAILED_REASON_UNKNOWN_EVENT_TYPE = "unknown_event_type"
+	// FAILED_REASON_INVALID_AMOUNT is used for events with a negative vote amount
+	FAILED_REASON_INVALID_AMOUNT = "invalid_amount"
+	// FAILED_REASON_INVALID_EVENT_DATA is used for parsed events whose data was rejected when written to the event
+	// history, as it doesn't decode into the data of the event type
+	FAILED_REASON_INVALID_EVENT_DATA = "invalid_event_data"
+	// FAILED_REASON_PROPOSAL_CONFLICT is used for proposal_created events for an existing proposal with different
+	// content
+	FAILED_REASON_PROPOSAL_CONFLICT = "proposal_conflict"
+	// FAILED_REASON_APPLY_FAILED is used for events that failed to apply in a batch, and were rolled back so the rest
+	// of the batch could be applied
+	FAILED_REASON_APPLY_FAILED = "apply_failed"
+)
+
+// FailedEvent is a governor event that could not be indexed. The raw event is kept, so it can be inspected
+// and replayed once the indexer supports it.
+type FailedEvent struct {
+	// Unique identifier for the event
+	EventId string
+	// StrKey address of the contract emitting the event
+	ContractId string
+	// The event type
+	EventType string
+	// Why the event could not be indexed, one of the FAILED_REASON constants
+	Reason string
+	// The error returned when parsing the event
+	Error string
+	// The contract event, as a base64-encoded XDR string
+	EventXdr string
+	// Transaction hash that triggered the event
+	TxHash string
+	// Ledger sequence when the event was emitted
+	LedgerSeq uint32
+	// Ledger close time (in seconds since epoch) for the ledger the event was emitted
+	LedgerCloseTime int64
+	// The number of times reprocessing the event has failed
+	Attempts int
+	// The time (in seconds since epoch) the event was reprocessed and applied, or 0 if it has not been
+	ProcessedAt int64
+}
+
+// NewFailedEvent creates a FailedEvent for a contract event that was rejected with parseErr
+func NewFailedEvent(ce *xdr.ContractEvent, reason string, parseErr error, txHash string, ledgerSeq uint32, ledgerCloseTime int64, toid int64, eventIndex int32) (*FailedEvent, error) {
+	if ce.ContractId == nil || ce.Body.V0 == nil || len(ce.Body.V0.Topics) == 0 {
+		return nil, fmt.Errorf("not a contract event: %w", ErrInvalidEventFormat)
+	}
+	contractId, err := strkey.Encode(strkey.VersionByteContract, ce.ContractId[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode contractId: %w", err)
+	}
+	eventXdr, err := xdr.MarshalBase64(ce)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal event xdr: %w", err)
+	}
+	eventType, _ := ce.Body.V0.Topics[0].GetSym()
+	eventId, err := EncodeEventId(toid, eventIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FailedEvent{
+		EventId:         eventId,
+		ContractId:      contractId,
+		EventType:       string(eventType),
+		Reason:          reason,
+		Error:           parseErr.Error(),
+		EventXdr:        eventXdr,
+		TxHash:          txHash,
+		LedgerSeq:       ledgerSeq,
+		LedgerCloseTime: ledgerCloseTime,
+	}, nil
+}
+
+// NewFailedEventFromGovernorEvent creates a FailedEvent for a parsed governor event that was rejected with err
+func NewFailedEventFromGovernorEvent(event *GovernorEvent, reason string, err error) *FailedEvent {
+	return &FailedEvent{
+		EventId:         event.EventId,
+		ContractId:      event.ContractId,
+		EventType:       event.EventType,
+		Reason:          reason,
+		Error:           err.Error(),
+		EventXdr:        event.EventXdr,
+		TxHash:          event.TxHash,
+		LedgerSeq:       event.LedgerSeq,
+		LedgerCloseTime: event.LedgerCloseTime,
+	}
+}
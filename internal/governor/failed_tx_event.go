@@ -0,0 +1,46 @@
+package governor
+
+// FailedTxEvent is a governor event emitted by a transaction that failed, such as a vote rejected by the contract.
+// The transaction's changes were rolled back, so the event is only kept for analytics, and is a distinct type from
+// GovernorEvent so it can never be applied to proposals or votes.
+type FailedTxEvent struct {
+	// Unique identifier for the event
+	EventId string
+	// StrKey address of the contract emitting the event
+	ContractId string
+	// Associated proposal ID, if applicable
+	ProposalId uint32
+	// The event type
+	EventType string
+	// Additional data payload, JSON encoded
+	EventData string
+	// Hash of the failed transaction
+	TxHash string
+	// Source account of the failed transaction, as a G address
+	SourceAccount string
+	// The transaction's result code, such as "TxFailed"
+	ResultCode string
+	// Ledger sequence when the event was emitted
+	LedgerSeq uint32
+	// Ledger close time (in seconds since epoch) for the ledger the event was emitted
+	LedgerCloseTime int64
+	// The contract event, as a base64-encoded XDR string
+	EventXdr string
+}
+
+// NewFailedTxEvent creates a FailedTxEvent for a governor event parsed from a failed transaction with resultCode
+func NewFailedTxEvent(event *GovernorEvent, resultCode string) *FailedTxEvent {
+	return &FailedTxEvent{
+		EventId:         event.EventId,
+		ContractId:      event.ContractId,
+		ProposalId:      event.ProposalId,
+		EventType:       event.EventType,
+		EventData:       event.EventData,
+		TxHash:          event.TxHash,
+		SourceAccount:   event.SourceAccount,
+		ResultCode:      resultCode,
+		LedgerSeq:       event.LedgerSeq,
+		LedgerCloseTime: event.LedgerCloseTime,
+		EventXdr:        event.EventXdr,
+	}
+}
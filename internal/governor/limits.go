@@ -0,0 +1,62 @@
+package governor
+
+import (
+	"log/slog"
+	"unicode/utf8"
+)
+
+// FieldLimits are the maximum lengths, in bytes, of the free-form proposal fields stored at ingest time. Any
+// contract can emit a proposal_created event, so these bound the size of a single proposal.
+type FieldLimits struct {
+	// Maximum length of a proposal title
+	Title int
+	// Maximum length of a proposal description
+	Description int
+	// Maximum length of a proposal action, as a base64-encoded XDR string. Longer actions are dropped rather than
+	// truncated, as part of an encoded value can't be decoded.
+	Action int
+}
+
+// DEFAULT_FIELD_LIMITS are the field limits used unless set with SetFieldLimits
+var DEFAULT_FIELD_LIMITS = FieldLimits{
+	Title:       256,
+	Description: 16 * 1024,
+	Action:      8 * 1024,
+}
+
+// fieldLimits are the limits applied when parsing proposal_created events
+var fieldLimits = DEFAULT_FIELD_LIMITS
+
+// SetFieldLimits sets the limits applied when parsing proposal_created events. Events already ingested are not
+// affected, so changing the limits doesn't change the result of reindexing a contract.
+func SetFieldLimits(limits FieldLimits) {
+	fieldLimits = limits
+}
+
+// applyFieldLimits truncates the title or description of the proposal if over its limit, blanks the action if over
+// its limit, and flags the data as truncated
+func (data *ProposalCreatedData) applyFieldLimits() {
+	var title, desc bool
+	data.Title, title = truncate(data.Title, fieldLimits.Title)
+	data.Desc, desc = truncate(data.Desc, fieldLimits.Description)
+	action := len(data.Action) > fieldLimits.Action
+	if action {
+		data.Action = ""
+	}
+	if title || desc || action {
+		slog.Warn("Truncated oversized proposal_created event fields", "proposer", data.Proposer, "title", title, "desc", desc, "action", action)
+		data.Truncated = true
+	}
+}
+
+// truncate returns s cut to at most max bytes without splitting a UTF-8 encoded character, and whether it was cut
+func truncate(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
@@ -0,0 +1,93 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name          string
+		s             string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{name: "under limit", s: "plz", max: 4, want: "plz"},
+		{name: "at limit", s: "plz", max: 3, want: "plz"},
+		{name: "over limit", s: "plz", max: 2, want: "pl", wantTruncated: true},
+		{name: "multibyte character at limit", s: "a€b", max: 4, want: "a€", wantTruncated: true},
+		{name: "multibyte character over limit", s: "a€b", max: 3, want: "a", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncate(tt.s, tt.max)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncate(%q, %d) = (%q, %v), want (%q, %v)", tt.s, tt.max, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestNewGovernorEventFromContractEventFieldLimits(t *testing.T) {
+	t.Cleanup(func() { SetFieldLimits(DEFAULT_FIELD_LIMITS) })
+
+	tests := []struct {
+		name     string
+		limits   FieldLimits
+		event    xdr.ContractEvent
+		wantData string
+		// an action over its limit is dropped, so it is unknown
+		wantActionType string
+	}{
+		{
+			name:           "under limits",
+			limits:         DEFAULT_FIELD_LIMITS,
+			event:          mustDecodeEvent(t, proposalCreatedXdr),
+			wantData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+			wantActionType: ACTION_TYPE_COUNCIL,
+		},
+		{
+			name:           "title and desc over limits",
+			limits:         FieldLimits{Title: 7, Description: 2, Action: 1024},
+			event:          mustDecodeEvent(t, proposalCreatedXdr),
+			wantData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me","desc":"pl","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300,"truncated":true}`,
+			wantActionType: ACTION_TYPE_COUNCIL,
+		},
+		{
+			name:           "v2 action over limit",
+			limits:         FieldLimits{Title: 256, Description: 1024, Action: 8},
+			event:          withSchemaVersion(t, proposalCreatedXdr, "v2", "title", "desc", "action", "vote_start", "vote_end"),
+			wantData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"","vote_start":1159020,"vote_end":1176300,"truncated":true}`,
+			wantActionType: ACTION_TYPE_UNKNOWN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetFieldLimits(tt.limits)
+			got, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 1)
+			if err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantData, got.EventData); diff != "" {
+				t.Errorf("EventData mismatch (-want +got):\n%s", diff)
+			}
+
+			proposal, err := NewProposalFromProposalCreatedEvent(got)
+			if err != nil {
+				t.Fatalf("NewProposalFromProposalCreatedEvent returned error: %v", err)
+			}
+			wantTruncated := tt.limits != DEFAULT_FIELD_LIMITS
+			if proposal.Truncated != wantTruncated {
+				t.Errorf("Truncated = %v, want %v", proposal.Truncated, wantTruncated)
+			}
+			if proposal.ActionType != tt.wantActionType {
+				t.Errorf("ActionType = %s, want %s", proposal.ActionType, tt.wantActionType)
+			}
+		})
+	}
+}
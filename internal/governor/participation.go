@@ -0,0 +1,54 @@
+package governor
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+// ParticipationThreshold is the participation below which a proposal is flagged as low participation when its voting
+// closes. Anyone can create a governor, so this lets clients hide spam proposals that nobody engaged with.
+type ParticipationThreshold struct {
+	// Proposals with fewer distinct voters than MinVoters, and...
+	MinVoters int
+	// ...a total amount voted for, against, and abstaining below MinAmount are flagged
+	MinAmount string
+}
+
+// DEFAULT_PARTICIPATION_THRESHOLD flags proposals that closed without any votes
+var DEFAULT_PARTICIPATION_THRESHOLD = ParticipationThreshold{
+	MinVoters: 1,
+	MinAmount: "1",
+}
+
+// participationThreshold is the threshold applied when a proposal's voting closes
+var participationThreshold = DEFAULT_PARTICIPATION_THRESHOLD
+
+// SetParticipationThreshold sets the threshold applied when a proposal's voting closes. Proposals already closed are
+// not affected until their contract is reindexed.
+func SetParticipationThreshold(threshold ParticipationThreshold) {
+	participationThreshold = threshold
+}
+
+// IsLowParticipation returns true if a proposal whose voting closed with voters distinct voters is below the
+// threshold set with SetParticipationThreshold. The proposal's tallies must be its final votes.
+func IsLowParticipation(proposal *Proposal, voters int) (bool, error) {
+	if voters >= participationThreshold.MinVoters {
+		return false, nil
+	}
+	minAmount, err := bigmath.ParseAmount(participationThreshold.MinAmount)
+	if err != nil {
+		return false, fmt.Errorf("min participation amount: %w", err)
+	}
+	// the total of three i128 tallies may exceed an i128, so it is summed without bigmath.AddAmount
+	total := new(big.Int)
+	for _, tally := range []string{proposal.VotesFor, proposal.VotesAgainst, proposal.VotesAbstain} {
+		val, err := bigmath.ParseAmount(tally)
+		if err != nil {
+			return false, fmt.Errorf("proposal %s tally: %w", proposal.ProposalKey, err)
+		}
+		total.Add(total, val)
+	}
+	return total.Cmp(minAmount) < 0, nil
+}
@@ -0,0 +1,39 @@
+package governor
+
+import "testing"
+
+func TestIsLowParticipation(t *testing.T) {
+	t.Cleanup(func() { SetParticipationThreshold(DEFAULT_PARTICIPATION_THRESHOLD) })
+
+	tests := []struct {
+		name      string
+		threshold ParticipationThreshold
+		tallies   [3]string
+		voters    int
+		want      bool
+		wantErr   bool
+	}{
+		{name: "default without votes", threshold: DEFAULT_PARTICIPATION_THRESHOLD, tallies: [3]string{"0", "0", "0"}, want: true},
+		{name: "default with a vote", threshold: DEFAULT_PARTICIPATION_THRESHOLD, tallies: [3]string{"0", "5", "0"}, voters: 1},
+		{name: "few voters with a large amount", threshold: ParticipationThreshold{MinVoters: 3, MinAmount: "100"}, tallies: [3]string{"60", "0", "40"}, voters: 2},
+		{name: "few voters and a small amount", threshold: ParticipationThreshold{MinVoters: 3, MinAmount: "100"}, tallies: [3]string{"60", "0", "39"}, voters: 2, want: true},
+		{name: "enough voters with a small amount", threshold: ParticipationThreshold{MinVoters: 3, MinAmount: "100"}, tallies: [3]string{"1", "1", "1"}, voters: 3},
+		{name: "disabled", threshold: ParticipationThreshold{MinVoters: 0, MinAmount: "100"}, tallies: [3]string{"0", "0", "0"}},
+		{name: "tallies over an i128", threshold: ParticipationThreshold{MinVoters: 1, MinAmount: "170141183460469231731687303715884105727"}, tallies: [3]string{"170141183460469231731687303715884105727", "1", "0"}},
+		{name: "invalid tally", threshold: DEFAULT_PARTICIPATION_THRESHOLD, tallies: [3]string{"0", "-1", "0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetParticipationThreshold(tt.threshold)
+			proposal := &Proposal{ProposalKey: "C-1", VotesFor: tt.tallies[0], VotesAgainst: tt.tallies[1], VotesAbstain: tt.tallies[2]}
+			got, err := IsLowParticipation(proposal, tt.voters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsLowParticipation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsLowParticipation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,25 +2,76 @@ package governor
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+var (
+	// ErrProposalNotCreated is returned when replaying a proposal at a ledger before it was created
+	ErrProposalNotCreated = errors.New("proposal not created")
+	// ErrIncompleteHistory is returned when replaying a proposal whose events do not include its proposal_created
+	// event, such as after the history was pruned
+	ErrIncompleteHistory = errors.New("incomplete proposal history")
+	// ErrProposalConflict is returned for a proposal_created event for an existing proposal with different content,
+	// which means the proposal id was reused, or the indexed history no longer matches the chain
+	ErrProposalConflict = errors.New("proposal_created event conflicts with existing proposal")
 )
 
 type Proposal struct {
-	ProposalKey     string
-	ContractId      string
-	ProposalId      uint32
-	Proposer        string
-	Status          uint32
-	Title           string
-	Description     string
-	Action          string
-	VoteStart       uint32
-	VoteEnd         uint32
-	VotesFor        string
-	VotesAgainst    string
-	VotesAbstain    string
-	ExecutionUnlock uint32
-	ExecutionTxHash string
+	ProposalKey string
+	ContractId  string
+	ProposalId  uint32
+	Proposer    string
+	Status      uint32
+	Title       string
+	Description string
+	Action      string
+	// Classification of the action, one of ACTION_TYPES, and the contract and function called by calldata actions
+	ActionType       string
+	ActionContractId string
+	ActionFunction   string
+	VoteStart        uint32
+	VoteEnd          uint32
+	VotesFor         string
+	VotesAgainst     string
+	VotesAbstain     string
+	ExecutionUnlock  uint32
+	ExecutionTxHash  string
+	// True if the title or description was truncated, or the action dropped, at ingest time, as it was over the
+	// field limits
+	Truncated bool
+	// True if voting closed with participation below the threshold set with SetParticipationThreshold. Set by the
+	// indexer, as it counts the proposal's distinct voters. Not reproduced by ReplayProposal.
+	FlaggedLowParticipation bool
+	// Ledger of the proposal_created event
+	CreatedLedger uint32
+	// Transaction of the proposal_created event, and its source account. The proposer may be a contract, such as a
+	// multisig, while the source account is the wallet that submitted the proposal.
+	CreatedTxHash string
+	SourceAccount string
+	// Ledger and ID of the last event that changed the proposal
+	UpdatedLedger  uint32
+	UpdatedEventId string
+	// Unix seconds of when the indexer last wrote the proposal. Not reproduced by ReplayProposal.
+	UpdatedAt int64
+}
+
+// Validate returns ErrInvalidAmount if a vote tally is not an integer between 0 and bigmath.MAX_I128. Proposals are
+// validated before they are written, so corrupted tallies are never persisted.
+func (p *Proposal) Validate() error {
+	for _, tally := range []struct{ name, val string }{
+		{"votes_for", p.VotesFor},
+		{"votes_against", p.VotesAgainst},
+		{"votes_abstain", p.VotesAbstain},
+	} {
+		if _, err := bigmath.ParseAmount(tally.val); err != nil {
+			return fmt.Errorf("%s: %w", tally.name, err)
+		}
+	}
+	return nil
 }
 
 // EncodeProposalKey generates a unique key for a proposal based on contractId and proposalId
@@ -39,24 +90,211 @@ func NewProposalFromProposalCreatedEvent(event *GovernorEvent) (*Proposal, error
 	if err != nil {
 		return nil, fmt.Errorf("unable to unmarshal proposal_created event data: %w", err)
 	}
+	action := ClassifyAction(proposalCreatedData.Action)
 
 	proposal := &Proposal{
-		ProposalKey:     EncodeProposalKey(event.ContractId, event.ProposalId),
-		ContractId:      event.ContractId,
-		ProposalId:      event.ProposalId,
-		Proposer:        proposalCreatedData.Proposer,
-		Status:          0,
-		Title:           proposalCreatedData.Title,
-		Description:     proposalCreatedData.Desc,
-		Action:          proposalCreatedData.Action,
-		VoteStart:       proposalCreatedData.VoteStart,
-		VoteEnd:         proposalCreatedData.VoteEnd,
-		VotesFor:        "0",
-		VotesAgainst:    "0",
-		VotesAbstain:    "0",
-		ExecutionUnlock: 0,
-		ExecutionTxHash: "",
+		ProposalKey:      EncodeProposalKey(event.ContractId, event.ProposalId),
+		ContractId:       event.ContractId,
+		ProposalId:       event.ProposalId,
+		Proposer:         proposalCreatedData.Proposer,
+		Status:           0,
+		Title:            proposalCreatedData.Title,
+		Description:      proposalCreatedData.Desc,
+		Action:           proposalCreatedData.Action,
+		ActionType:       action.Type,
+		ActionContractId: action.ContractId,
+		ActionFunction:   action.Function,
+		VoteStart:        proposalCreatedData.VoteStart,
+		VoteEnd:          proposalCreatedData.VoteEnd,
+		VotesFor:         "0",
+		VotesAgainst:     "0",
+		VotesAbstain:     "0",
+		ExecutionUnlock:  0,
+		ExecutionTxHash:  "",
+		Truncated:        proposalCreatedData.Truncated,
+		CreatedLedger:    event.LedgerSeq,
+		CreatedTxHash:    event.TxHash,
+		SourceAccount:    event.SourceAccount,
 	}
 
 	return proposal, nil
 }
+
+// proposalContentDiff returns the names of the proposal_created event fields that differ between two proposals
+func proposalContentDiff(existing *Proposal, created *Proposal) []string {
+	var fields []string
+	for _, field := range []struct {
+		name    string
+		changed bool
+	}{
+		{"proposer", existing.Proposer != created.Proposer},
+		{"title", existing.Title != created.Title},
+		{"desc", existing.Description != created.Description},
+		{"action", existing.Action != created.Action},
+		{"vote_start", existing.VoteStart != created.VoteStart},
+		{"vote_end", existing.VoteEnd != created.VoteEnd},
+	} {
+		if field.changed {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+// ApplyEventToProposal applies a proposal event to a proposal's state, and returns the vote cast by a vote_cast
+// event. proposal must not be nil; a zero Proposal is a proposal that has not been created yet, and is filled in by
+// its proposal_created event.
+//
+// changed is false, and proposal is not modified, if the event does not apply to the proposal's current status,
+// such as a vote cast after voting closed, or a status change CanTransition does not allow. A proposal_created event
+// for an existing proposal also does not apply if its content matches the proposal, and returns an error wrapping
+// ErrProposalConflict if it doesn't. proposal is also not modified if an error is returned.
+//
+// If the event applies, it is recorded as the proposal's last update. UpdatedAt is left to the caller.
+//
+// Only the proposal is read and modified, so events can be replayed from the event history. Votes are not
+// de-duplicated, so callers must skip votes that were already counted.
+func ApplyEventToProposal(proposal *Proposal, event *GovernorEvent) (vote *Vote, changed bool, err error) {
+	exists := proposal.ProposalKey != ""
+	notExists := func() error {
+		return fmt.Errorf("%s event for non-existing proposal %s-%d", event.EventType, event.ContractId, event.ProposalId)
+	}
+	canTransition := func(to ProposalStatus) bool {
+		return CanTransition(ProposalStatus(proposal.Status), to)
+	}
+
+	switch event.EventType {
+	case "proposal_created":
+		created, err := NewProposalFromProposalCreatedEvent(event)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create proposal from event: %w", err)
+		}
+		if exists {
+			// the same proposal_created event, such as one replayed from the event history, does not apply
+			if fields := proposalContentDiff(proposal, created); len(fields) > 0 {
+				return nil, false, fmt.Errorf("%w %s: %s differ", ErrProposalConflict, proposal.ProposalKey, strings.Join(fields, ", "))
+			}
+			return nil, false, nil
+		}
+		*proposal = *created
+	case "proposal_canceled":
+		if !exists {
+			return nil, false, notExists()
+		} else if !canTransition(PROPOSAL_STATUS_CANCELED) {
+			return nil, false, nil
+		}
+		proposal.Status = uint32(PROPOSAL_STATUS_CANCELED)
+	case "proposal_voting_closed":
+		if !exists {
+			return nil, false, notExists()
+		}
+		var votingClosedData *ProposalVotingClosedData
+		err = json.Unmarshal([]byte(event.EventData), &votingClosedData)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to unmarshal proposal_voting_closed event data: %w", err)
+		}
+		if !canTransition(ProposalStatus(votingClosedData.Status)) {
+			return nil, false, nil
+		}
+		proposal.Status = votingClosedData.Status
+		proposal.VotesFor = votingClosedData.FinalVotes.For
+		proposal.VotesAgainst = votingClosedData.FinalVotes.Against
+		proposal.VotesAbstain = votingClosedData.FinalVotes.Abstain
+		proposal.ExecutionUnlock = votingClosedData.Eta
+	case "proposal_executed":
+		if !exists {
+			return nil, false, notExists()
+		} else if !canTransition(PROPOSAL_STATUS_EXECUTED) {
+			return nil, false, nil
+		}
+		proposal.Status = uint32(PROPOSAL_STATUS_EXECUTED)
+		proposal.ExecutionTxHash = event.TxHash
+	case "proposal_expired":
+		if !exists {
+			return nil, false, notExists()
+		} else if !canTransition(PROPOSAL_STATUS_EXPIRED) {
+			return nil, false, nil
+		}
+		proposal.Status = uint32(PROPOSAL_STATUS_EXPIRED)
+	case "vote_cast":
+		if !exists {
+			return nil, false, notExists()
+		} else if ProposalStatus(proposal.Status) != PROPOSAL_STATUS_OPEN {
+			// votes don't change the status, but are only counted while the proposal is open
+			return nil, false, nil
+		}
+		var voteCastData *VoteCastData
+		err = json.Unmarshal([]byte(event.EventData), &voteCastData)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to unmarshal vote_cast event data: %w", err)
+		}
+		var tally *string
+		switch VoteSupport(voteCastData.Support) {
+		case VOTE_SUPPORT_AGAINST:
+			tally = &proposal.VotesAgainst
+		case VOTE_SUPPORT_FOR:
+			tally = &proposal.VotesFor
+		case VOTE_SUPPORT_ABSTAIN:
+			tally = &proposal.VotesAbstain
+		default:
+			return nil, false, fmt.Errorf("invalid support value %d in vote_cast event", voteCastData.Support)
+		}
+		total, err := bigmath.AddAmount(*tally, voteCastData.Amount)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to add vote_cast amount to proposal %s: %w", proposal.ProposalKey, err)
+		}
+
+		vote, err = NewVoteFromVoteCastEvent(event)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create vote from event: %w", err)
+		}
+		*tally = total
+	default:
+		return nil, false, fmt.Errorf("invalid event type %s", event.EventType)
+	}
+	proposal.UpdatedLedger = event.LedgerSeq
+	proposal.UpdatedEventId = event.EventId
+	return vote, true, nil
+}
+
+// ReplayProposal reconstructs the state of a proposal at the end of ledger atLedger from the proposal's events,
+// which must be in the order they were applied. Events emitted after atLedger are ignored, and events that
+// ApplyEventToProposal rejects are skipped, as the indexer skips them.
+//
+// ErrProposalNotCreated is returned if the proposal was created after atLedger, and ErrIncompleteHistory if the
+// events do not include the proposal_created event, such as after the history was pruned.
+func ReplayProposal(events []*GovernorEvent, atLedger uint32) (*Proposal, error) {
+	proposal := &Proposal{}
+	created := false
+	// the indexer counts one vote per transaction
+	votes := make(map[string]bool)
+	for _, event := range events {
+		if event.EventType == "proposal_created" {
+			created = true
+		}
+		if event.LedgerSeq > atLedger {
+			continue
+		}
+		if proposal.ProposalKey == "" && event.EventType != "proposal_created" {
+			return nil, fmt.Errorf("%s event %s before proposal_created: %w", event.EventType, event.EventId, ErrIncompleteHistory)
+		}
+		if event.EventType == "vote_cast" && votes[event.TxHash] {
+			continue
+		}
+		vote, _, err := ApplyEventToProposal(proposal, event)
+		if err != nil {
+			// the indexer skips events that can't be applied, such as a conflicting proposal_created event
+			continue
+		}
+		if vote != nil {
+			votes[vote.TxHash] = true
+		}
+	}
+	if proposal.ProposalKey == "" {
+		if !created {
+			return nil, ErrIncompleteHistory
+		}
+		return nil, ErrProposalNotCreated
+	}
+	return proposal, nil
+}
@@ -0,0 +1,496 @@
+package governor
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyEventToProposal(t *testing.T) {
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	txHash := "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5"
+	sourceAccount := "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+	newProposal := func(status uint32) *Proposal {
+		return &Proposal{
+			ProposalKey:     EncodeProposalKey(contractId, 3),
+			ContractId:      contractId,
+			ProposalId:      3,
+			Proposer:        "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
+			Status:          status,
+			Title:           "Unicorns are real",
+			Description:     "They live in the clouds",
+			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:      ACTION_TYPE_COUNCIL,
+			VoteStart:       1160234,
+			VoteEnd:         1170234,
+			VotesFor:        "12314122341234",
+			VotesAgainst:    "1234123412434",
+			VotesAbstain:    "1923114243",
+			ExecutionUnlock: 0,
+			ExecutionTxHash: "",
+		}
+	}
+	// withProposal returns a proposal with the given status, modified by fn
+	withProposal := func(status uint32, fn func(p *Proposal)) *Proposal {
+		proposal := newProposal(status)
+		fn(proposal)
+		return proposal
+	}
+	// updatedProposal returns a proposal with the given status, modified by fn if set, and updated by a test event
+	updatedProposal := func(status uint32, fn func(p *Proposal)) *Proposal {
+		return withProposal(status, func(p *Proposal) {
+			if fn != nil {
+				fn(p)
+			}
+			p.UpdatedLedger = 1170234
+			p.UpdatedEventId = "0005025687261941760-0000000000"
+		})
+	}
+	newEvent := func(eventType string, eventData string) *GovernorEvent {
+		return &GovernorEvent{
+			EventId:         "0005025687261941760-0000000000",
+			ContractId:      contractId,
+			EventType:       eventType,
+			ProposalId:      3,
+			EventData:       eventData,
+			TxHash:          txHash,
+			SourceAccount:   sourceAccount,
+			LedgerSeq:       1170234,
+			LedgerCloseTime: 1761053041,
+		}
+	}
+	createdData := `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1171234,"vote_end":1191234}`
+	// createdProposal returns the proposal created by createdData, with the given status
+	createdProposal := func(status uint32) *Proposal {
+		return &Proposal{
+			ProposalKey:   EncodeProposalKey(contractId, 3),
+			ContractId:    contractId,
+			ProposalId:    3,
+			Proposer:      "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			Status:        status,
+			Title:         "Make me security council",
+			Description:   "plz",
+			Action:        "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:    ACTION_TYPE_COUNCIL,
+			VoteStart:     1171234,
+			VoteEnd:       1191234,
+			VotesFor:      "100",
+			VotesAgainst:  "0",
+			VotesAbstain:  "0",
+			CreatedLedger: 1160234,
+			CreatedTxHash: "other",
+		}
+	}
+	votingClosedData := `{"status":1,"eta":1120234,"final_votes":{"for":"50230000000","against":"20000000000","abstain":"123"}}`
+	voteData := func(support int, amount string) string {
+		return `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":` + strconv.Itoa(support) + `,"amount":"` + amount + `"}`
+	}
+
+	tests := []struct {
+		name         string
+		proposal     *Proposal
+		event        *GovernorEvent
+		wantProposal *Proposal
+		wantVote     *Vote
+		wantChanged  bool
+		wantErr      bool
+	}{
+		{
+			name:     "proposal_created happy path",
+			proposal: &Proposal{},
+			event:    newEvent("proposal_created", createdData),
+			wantProposal: &Proposal{
+				ProposalKey:    EncodeProposalKey(contractId, 3),
+				ContractId:     contractId,
+				ProposalId:     3,
+				Proposer:       "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Status:         0,
+				Title:          "Make me security council",
+				Description:    "plz",
+				Action:         "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				ActionType:     ACTION_TYPE_COUNCIL,
+				VoteStart:      1171234,
+				VoteEnd:        1191234,
+				VotesFor:       "0",
+				VotesAgainst:   "0",
+				VotesAbstain:   "0",
+				CreatedLedger:  1170234,
+				CreatedTxHash:  txHash,
+				SourceAccount:  sourceAccount,
+				UpdatedLedger:  1170234,
+				UpdatedEventId: "0005025687261941760-0000000000",
+			},
+			wantChanged: true,
+		},
+		{
+			name:         "proposal_created for existing proposal with the same content does not apply",
+			proposal:     createdProposal(1),
+			event:        newEvent("proposal_created", createdData),
+			wantProposal: createdProposal(1),
+		},
+		{
+			name:         "proposal_created for existing proposal with different content fails",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_created", createdData),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_created invalid data fails",
+			proposal:     &Proposal{},
+			event:        newEvent("proposal_created", `bad`),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_canceled happy path",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_canceled", `{}`),
+			wantProposal: updatedProposal(5, nil),
+			wantChanged:  true,
+		},
+		{
+			name:         "proposal_canceled no proposal fails",
+			proposal:     &Proposal{},
+			event:        newEvent("proposal_canceled", `{}`),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_canceled invalid status does not apply",
+			proposal:     newProposal(2),
+			event:        newEvent("proposal_canceled", `{}`),
+			wantProposal: newProposal(2),
+		},
+		{
+			name:     "proposal_voting_closed happy path",
+			proposal: newProposal(0),
+			event:    newEvent("proposal_voting_closed", votingClosedData),
+			wantProposal: updatedProposal(1, func(p *Proposal) {
+				p.VotesFor = "50230000000"
+				p.VotesAgainst = "20000000000"
+				p.VotesAbstain = "123"
+				p.ExecutionUnlock = 1120234
+			}),
+			wantChanged: true,
+		},
+		{
+			name:         "proposal_voting_closed no proposal fails",
+			proposal:     &Proposal{},
+			event:        newEvent("proposal_voting_closed", votingClosedData),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_voting_closed invalid status does not apply",
+			proposal:     newProposal(2),
+			event:        newEvent("proposal_voting_closed", votingClosedData),
+			wantProposal: newProposal(2),
+		},
+		{
+			name:         "proposal_voting_closed to a status open proposals can't change to does not apply",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_voting_closed", `{"status":4,"eta":0,"final_votes":{"for":"0","against":"0","abstain":"0"}}`),
+			wantProposal: newProposal(0),
+		},
+		{
+			name:         "proposal_voting_closed invalid data fails",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_voting_closed", `bad`),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_executed happy path",
+			proposal:     newProposal(1),
+			event:        newEvent("proposal_executed", ""),
+			wantProposal: updatedProposal(4, func(p *Proposal) { p.ExecutionTxHash = txHash }),
+			wantChanged:  true,
+		},
+		{
+			name:         "proposal_executed no proposal fails",
+			proposal:     &Proposal{},
+			event:        newEvent("proposal_executed", ""),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name: "proposal_executed already executed does not apply",
+			proposal: withProposal(4, func(p *Proposal) {
+				p.ExecutionTxHash = "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db"
+			}),
+			event: newEvent("proposal_executed", ""),
+			wantProposal: withProposal(4, func(p *Proposal) {
+				p.ExecutionTxHash = "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db"
+			}),
+		},
+		{
+			name:         "proposal_executed canceled proposal does not apply",
+			proposal:     newProposal(5),
+			event:        newEvent("proposal_executed", ""),
+			wantProposal: newProposal(5),
+		},
+		{
+			name:         "proposal_executed open proposal does not apply",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_executed", ""),
+			wantProposal: newProposal(0),
+		},
+		{
+			name:         "proposal_expired happy path",
+			proposal:     newProposal(0),
+			event:        newEvent("proposal_expired", `{}`),
+			wantProposal: updatedProposal(3, nil),
+			wantChanged:  true,
+		},
+		{
+			name:         "proposal_expired queued proposal",
+			proposal:     newProposal(1),
+			event:        newEvent("proposal_expired", `{}`),
+			wantProposal: updatedProposal(3, nil),
+			wantChanged:  true,
+		},
+		{
+			name:         "proposal_expired no proposal fails",
+			proposal:     &Proposal{},
+			event:        newEvent("proposal_expired", `{}`),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name:         "proposal_expired invalid status does not apply",
+			proposal:     newProposal(2),
+			event:        newEvent("proposal_expired", `{}`),
+			wantProposal: newProposal(2),
+		},
+		{
+			name:         "vote_cast happy path",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(1, "20000000000")),
+			wantProposal: updatedProposal(0, func(p *Proposal) { p.VotesFor = "12334122341234" }),
+			wantVote: &Vote{
+				TxHash:          txHash,
+				ContractId:      contractId,
+				ProposalId:      3,
+				Voter:           "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Support:         1,
+				Amount:          "20000000000",
+				LedgerSeq:       1170234,
+				LedgerCloseTime: 1761053041,
+			},
+			wantChanged: true,
+		},
+		{
+			name:         "vote_cast against",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(0, "1")),
+			wantProposal: updatedProposal(0, func(p *Proposal) { p.VotesAgainst = "1234123412435" }),
+			wantVote: &Vote{
+				TxHash:          txHash,
+				ContractId:      contractId,
+				ProposalId:      3,
+				Voter:           "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Support:         0,
+				Amount:          "1",
+				LedgerSeq:       1170234,
+				LedgerCloseTime: 1761053041,
+			},
+			wantChanged: true,
+		},
+		{
+			name:         "vote_cast abstain",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(2, "7")),
+			wantProposal: updatedProposal(0, func(p *Proposal) { p.VotesAbstain = "1923114250" }),
+			wantVote: &Vote{
+				TxHash:          txHash,
+				ContractId:      contractId,
+				ProposalId:      3,
+				Voter:           "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+				Support:         2,
+				Amount:          "7",
+				LedgerSeq:       1170234,
+				LedgerCloseTime: 1761053041,
+			},
+			wantChanged: true,
+		},
+		{
+			name:         "vote_cast no proposal fails",
+			proposal:     &Proposal{},
+			event:        newEvent("vote_cast", voteData(1, "20000000000")),
+			wantProposal: &Proposal{},
+			wantErr:      true,
+		},
+		{
+			name:         "vote_cast invalid status does not apply",
+			proposal:     newProposal(2),
+			event:        newEvent("vote_cast", voteData(1, "20000000000")),
+			wantProposal: newProposal(2),
+		},
+		{
+			name:         "vote_cast negative amount fails",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(0, "-1234123412434")),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+		{
+			name:         "vote_cast overflowing tally fails",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(1, "170141183460469231731687303715884105727")),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+		{
+			name:         "vote_cast invalid support fails",
+			proposal:     newProposal(0),
+			event:        newEvent("vote_cast", voteData(3, "1")),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+		{
+			name:         "vote_cast invalid tally fails",
+			proposal:     withProposal(0, func(p *Proposal) { p.VotesFor = "bad" }),
+			event:        newEvent("vote_cast", voteData(1, "1")),
+			wantProposal: withProposal(0, func(p *Proposal) { p.VotesFor = "bad" }),
+			wantErr:      true,
+		},
+		{
+			name:         "delegation event fails",
+			proposal:     newProposal(0),
+			event:        newEvent("delegate_changed", `{}`),
+			wantProposal: newProposal(0),
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vote, changed, err := ApplyEventToProposal(tt.proposal, tt.event)
+			if err != nil && !tt.wantErr {
+				t.Fatalf("ApplyEventToProposal() error = %v", err)
+			} else if err == nil && tt.wantErr {
+				t.Fatalf("ApplyEventToProposal() expected error but got none")
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if diff := cmp.Diff(tt.wantProposal, tt.proposal); diff != "" {
+				t.Errorf("proposal mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantVote, vote); diff != "" {
+				t.Errorf("vote mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyEventToProposalConflict(t *testing.T) {
+	proposal := &Proposal{
+		ProposalKey: EncodeProposalKey("CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", 3),
+		Proposer:    "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+		Title:       "Make me security council",
+		VoteStart:   1171234,
+		VoteEnd:     1191234,
+	}
+	event := &GovernorEvent{
+		ContractId: "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+		EventType:  "proposal_created",
+		ProposalId: 3,
+		EventData:  `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me the admin","desc":"","action":"","vote_start":1171234,"vote_end":1201234}`,
+	}
+
+	_, _, err := ApplyEventToProposal(proposal, event)
+	if !errors.Is(err, ErrProposalConflict) {
+		t.Fatalf("ApplyEventToProposal() error = %v, want ErrProposalConflict", err)
+	}
+	if want := "title, vote_end differ"; !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("ApplyEventToProposal() error = %q, want suffix %q", err, want)
+	}
+}
+
+func TestReplayProposal(t *testing.T) {
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	newEvent := func(eventId string, eventType string, eventData string, txHash string, ledgerSeq uint32) *GovernorEvent {
+		return &GovernorEvent{
+			EventId:    eventId,
+			ContractId: contractId,
+			EventType:  eventType,
+			ProposalId: 3,
+			EventData:  eventData,
+			TxHash:     txHash,
+			LedgerSeq:  ledgerSeq,
+		}
+	}
+	events := []*GovernorEvent{
+		newEvent("0000000429496729600-0000000000", "proposal_created", `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"","vote_start":100,"vote_end":130}`, "tx1", 100),
+		newEvent("0000000472446402560-0000000000", "vote_cast", `{"voter":"GA","support":0,"amount":"5"}`, "tx2", 110),
+		// a second vote in the same transaction is not counted by the indexer
+		newEvent("0000000472446402560-0000000001", "vote_cast", `{"voter":"GA","support":0,"amount":"5"}`, "tx2", 110),
+		newEvent("0000000511101108224-0000000000", "vote_cast", `{"voter":"GB","support":2,"amount":"7"}`, "tx3", 119),
+		newEvent("0000000558345748480-0000000000", "proposal_voting_closed", `{"status":1,"eta":140,"final_votes":{"for":"0","against":"5","abstain":"7"}}`, "tx4", 130),
+		// votes after voting closed do not apply
+		newEvent("0000000601295421440-0000000000", "vote_cast", `{"voter":"GC","support":1,"amount":"9"}`, "tx5", 140),
+		newEvent("0000000644245094400-0000000000", "proposal_executed", `{}`, "tx6", 150),
+	}
+	// the indexer skips a proposal_created event that conflicts with the proposal
+	conflict := newEvent("0000000481036337152-0000000000", "proposal_created", `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me the admin","desc":"","action":"","vote_start":100,"vote_end":130}`, "tx7", 112)
+	withConflict := slices.Concat(events[:3], []*GovernorEvent{conflict}, events[3:])
+	// newProposal returns the replayed proposal, last updated by events[updatedBy]
+	newProposal := func(status uint32, votesAgainst string, votesAbstain string, executionUnlock uint32, executionTxHash string, updatedBy int) *Proposal {
+		return &Proposal{
+			ProposalKey:     EncodeProposalKey(contractId, 3),
+			ContractId:      contractId,
+			ProposalId:      3,
+			Proposer:        "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			Status:          status,
+			Title:           "Make me security council",
+			Description:     "plz",
+			ActionType:      ACTION_TYPE_UNKNOWN,
+			VoteStart:       100,
+			VoteEnd:         130,
+			VotesFor:        "0",
+			VotesAgainst:    votesAgainst,
+			VotesAbstain:    votesAbstain,
+			ExecutionUnlock: executionUnlock,
+			ExecutionTxHash: executionTxHash,
+			CreatedLedger:   100,
+			CreatedTxHash:   "tx1",
+			UpdatedLedger:   events[updatedBy].LedgerSeq,
+			UpdatedEventId:  events[updatedBy].EventId,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		events   []*GovernorEvent
+		atLedger uint32
+		want     *Proposal
+		wantErr  error
+	}{
+		{name: "at creation", events: events, atLedger: 100, want: newProposal(0, "0", "0", 0, "", 0)},
+		{name: "duplicate vote in transaction", events: events, atLedger: 118, want: newProposal(0, "5", "0", 0, "", 1)},
+		// the vote after voting closed is not the last update
+		{name: "voting closed", events: events, atLedger: 145, want: newProposal(1, "5", "7", 140, "", 4)},
+		{name: "executed", events: events, atLedger: 150, want: newProposal(4, "5", "7", 140, "tx6", 6)},
+		{name: "before creation", events: events, atLedger: 99, wantErr: ErrProposalNotCreated},
+		{name: "conflicting proposal_created", events: withConflict, atLedger: 150, want: newProposal(4, "5", "7", 140, "tx6", 6)},
+		{name: "pruned history", events: events[1:], atLedger: 150, wantErr: ErrIncompleteHistory},
+		{name: "pruned history before first event", events: events[1:], atLedger: 100, wantErr: ErrIncompleteHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReplayProposal(tt.events, tt.atLedger)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
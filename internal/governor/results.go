@@ -0,0 +1,53 @@
+package governor
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+)
+
+// ProposalResults are a proposal's tallies with its governor's counting type applied. Abstaining votes never count
+// toward the outcome, so the percentages are of the votes for and against. The counting type decides which votes
+// count toward quorum. Percentages are fixed-point strings with bigmath.PERCENT_DECIMALS decimals.
+type ProposalResults struct {
+	ForPercent                 string `json:"for_percent"`
+	AgainstPercent             string `json:"against_percent"`
+	AbstainCountedTowardQuorum bool   `json:"abstain_counted_toward_quorum"`
+	// QuorumVotes is the total of the votes that count toward quorum
+	QuorumVotes string `json:"quorum_votes"`
+	// Margin is the votes for minus the votes against, negative if more votes are against
+	Margin string `json:"margin"`
+}
+
+// NewProposalResults returns the results of a proposal under a governor's counting type
+func NewProposalResults(proposal *Proposal, countingType uint32) (*ProposalResults, error) {
+	votesFor, err := bigmath.ParseAmount(proposal.VotesFor)
+	if err != nil {
+		return nil, fmt.Errorf("proposal %s votes for: %w", proposal.ProposalKey, err)
+	}
+	votesAgainst, err := bigmath.ParseAmount(proposal.VotesAgainst)
+	if err != nil {
+		return nil, fmt.Errorf("proposal %s votes against: %w", proposal.ProposalKey, err)
+	}
+	votesAbstain, err := bigmath.ParseAmount(proposal.VotesAbstain)
+	if err != nil {
+		return nil, fmt.Errorf("proposal %s votes abstaining: %w", proposal.ProposalKey, err)
+	}
+
+	// the total of several i128 tallies may exceed an i128, so they are summed without bigmath.AddAmount
+	decided := new(big.Int).Add(votesFor, votesAgainst)
+	quorumVotes := new(big.Int)
+	for bit, votes := range map[uint32]*big.Int{COUNTING_TYPE_FOR: votesFor, COUNTING_TYPE_AGAINST: votesAgainst, COUNTING_TYPE_ABSTAIN: votesAbstain} {
+		if countingType&bit != 0 {
+			quorumVotes.Add(quorumVotes, votes)
+		}
+	}
+	return &ProposalResults{
+		ForPercent:                 bigmath.FormatPercent(votesFor, decided),
+		AgainstPercent:             bigmath.FormatPercent(votesAgainst, decided),
+		AbstainCountedTowardQuorum: countingType&COUNTING_TYPE_ABSTAIN != 0,
+		QuorumVotes:                quorumVotes.String(),
+		Margin:                     new(big.Int).Sub(votesFor, votesAgainst).String(),
+	}, nil
+}
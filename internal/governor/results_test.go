@@ -0,0 +1,108 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewProposalResults(t *testing.T) {
+	tests := []struct {
+		name         string
+		countingType uint32
+		tallies      [3]string
+		want         *ProposalResults
+		wantErr      bool
+	}{
+		{
+			name:         "nothing counted",
+			countingType: 0,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", QuorumVotes: "0", Margin: "43468"},
+		},
+		{
+			name:         "abstain",
+			countingType: COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", AbstainCountedTowardQuorum: true, QuorumVotes: "250000", Margin: "43468"},
+		},
+		{
+			name:         "against",
+			countingType: COUNTING_TYPE_AGAINST,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", QuorumVotes: "478266", Margin: "43468"},
+		},
+		{
+			name:         "for",
+			countingType: COUNTING_TYPE_FOR,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", QuorumVotes: "521734", Margin: "43468"},
+		},
+		{
+			name:         "for and against",
+			countingType: COUNTING_TYPE_FOR | COUNTING_TYPE_AGAINST,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", QuorumVotes: "1000000", Margin: "43468"},
+		},
+		{
+			name:         "for and abstain",
+			countingType: COUNTING_TYPE_FOR | COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", AbstainCountedTowardQuorum: true, QuorumVotes: "771734", Margin: "43468"},
+		},
+		{
+			name:         "against and abstain",
+			countingType: COUNTING_TYPE_AGAINST | COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", AbstainCountedTowardQuorum: true, QuorumVotes: "728266", Margin: "43468"},
+		},
+		{
+			name:         "all",
+			countingType: COUNTING_TYPE_FOR | COUNTING_TYPE_AGAINST | COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", AbstainCountedTowardQuorum: true, QuorumVotes: "1250000", Margin: "43468"},
+		},
+		{
+			name:         "bits past the counting type are ignored",
+			countingType: 0b1000 | COUNTING_TYPE_FOR,
+			tallies:      [3]string{"521734", "478266", "250000"},
+			want:         &ProposalResults{ForPercent: "52.1734", AgainstPercent: "47.8266", QuorumVotes: "521734", Margin: "43468"},
+		},
+		{
+			name:         "more against",
+			countingType: COUNTING_TYPE_FOR | COUNTING_TYPE_AGAINST | COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"1230000000", "20000000000", "0"},
+			want:         &ProposalResults{ForPercent: "5.7936", AgainstPercent: "94.2063", AbstainCountedTowardQuorum: true, QuorumVotes: "21230000000", Margin: "-18770000000"},
+		},
+		{
+			name:         "only abstaining",
+			countingType: COUNTING_TYPE_ABSTAIN,
+			tallies:      [3]string{"0", "0", "100"},
+			want:         &ProposalResults{ForPercent: "0.0000", AgainstPercent: "0.0000", AbstainCountedTowardQuorum: true, QuorumVotes: "100", Margin: "0"},
+		},
+		{
+			name:         "tallies over an i128",
+			countingType: COUNTING_TYPE_FOR | COUNTING_TYPE_AGAINST,
+			tallies:      [3]string{"170141183460469231731687303715884105727", "170141183460469231731687303715884105727", "0"},
+			want:         &ProposalResults{ForPercent: "50.0000", AgainstPercent: "50.0000", QuorumVotes: "340282366920938463463374607431768211454", Margin: "0"},
+		},
+		{
+			name:    "invalid tally",
+			tallies: [3]string{"0", "0", "-1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposal := &Proposal{ProposalKey: "C-1", VotesFor: tt.tallies[0], VotesAgainst: tt.tallies[1], VotesAbstain: tt.tallies[2]}
+			got, err := NewProposalResults(proposal, tt.countingType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProposalResults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewProposalResults() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// Governor event schema versions
+//
+// v1 events have no version topic: topic[0] is the event type and topic[1] is the proposal id, and event data
+// is a vec of fields in a fixed order.
+//
+// v2 events add a version symbol topic "v2" after the event type, so topic[2] is the proposal id. The remaining
+// topics are unchanged, and event data that was a vec is a map keyed by field name.
+const (
+	SCHEMA_V1 uint32 = 1
+	SCHEMA_V2 uint32 = 2
+)
+
+// ErrUnknownSchemaVersion is returned for governor events with a schema version this indexer cannot parse.
+// These events are not mis-parsed with an older layout.
+var ErrUnknownSchemaVersion = errors.New("unknown governor event schema version")
+
+// errInvalidVersionTopic is returned if topic[1] is neither a proposal id nor a version
+var errInvalidVersionTopic = fmt.Errorf("invalid version topic: %w", ErrInvalidEventFormat)
+
+// parseSchemaVersion returns the schema version of a governor event, and the index of its proposal id topic.
+// The body must have at least 2 topics.
+func parseSchemaVersion(body xdr.ContractEventV0) (uint32, int, error) {
+	if body.Topics[1].Type == xdr.ScValTypeScvU32 {
+		// no version topic
+		return SCHEMA_V1, 1, nil
+	}
+
+	sym, ok := body.Topics[1].GetSym()
+	if !ok || len(sym) < 2 || sym[0] != 'v' {
+		return 0, 0, errInvalidVersionTopic
+	}
+	version, err := strconv.ParseUint(string(sym[1:]), 10, 32)
+	if err != nil {
+		return 0, 0, errInvalidVersionTopic
+	}
+	switch uint32(version) {
+	case SCHEMA_V2:
+		return SCHEMA_V2, 2, nil
+	default:
+		return uint32(version), 0, fmt.Errorf("schema version %d: %w", version, ErrUnknownSchemaVersion)
+	}
+}
+
+// NewProposalCreatedDataFromEventBodyV2 parses the data of a v2 proposal_created event. Fields over the limits set
+// with SetFieldLimits are truncated.
+func NewProposalCreatedDataFromEventBodyV2(body xdr.ContractEventV0) (*ProposalCreatedData, error) {
+	if len(body.Topics) < 4 {
+		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("proposal_created", "topics", body.Topics[4:])
+
+	// the proposer may be an account or a contract, such as a smart wallet or multisig
+	proposer, err := addressFromScVal(body.Topics[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid proposer in event topic: %w", ErrInvalidEventFormat)
+	}
+
+	fields, err := readDataMap("proposal_created", body.Data, "title", "desc", "action", "vote_start", "vote_end")
+	if err != nil {
+		return nil, err
+	}
+
+	var data ProposalCreatedData
+	data.Proposer = proposer
+	title, ok := fields[0].GetStr()
+	if !ok {
+		return nil, fmt.Errorf("title is not a str %w", ErrEventParsingFailed)
+	}
+	data.Title = scString(title)
+	desc, ok := fields[1].GetStr()
+	if !ok {
+		return nil, fmt.Errorf("desc is not a str %w", ErrEventParsingFailed)
+	}
+	data.Desc = scString(desc)
+	action, err := xdr.MarshalBase64(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal action data %w", ErrEventParsingFailed)
+	}
+	data.Action = action
+	voteStart, ok := fields[3].GetU32()
+	if !ok {
+		return nil, fmt.Errorf("vote_start is not a u32 %w", ErrEventParsingFailed)
+	}
+	data.VoteStart = uint32(voteStart)
+	voteEnd, ok := fields[4].GetU32()
+	if !ok {
+		return nil, fmt.Errorf("vote_end is not a u32 %w", ErrEventParsingFailed)
+	}
+	data.VoteEnd = uint32(voteEnd)
+	data.applyFieldLimits()
+	return &data, nil
+}
+
+// NewProposalVotingClosedDataFromEventBodyV2 parses the data of a v2 proposal_voting_closed event
+func NewProposalVotingClosedDataFromEventBodyV2(body xdr.ContractEventV0) (*ProposalVotingClosedData, error) {
+	if len(body.Topics) < 5 {
+		return nil, fmt.Errorf("unexpected number of topics %d in event: %w", len(body.Topics), ErrInvalidEventFormat)
+	}
+	warnExtraContent("proposal_voting_closed", "topics", body.Topics[5:])
+
+	status, ok := body.Topics[3].GetU32()
+	if !ok {
+		return nil, fmt.Errorf("invalid event topic: %w", ErrInvalidEventFormat)
+	}
+	eta, ok := body.Topics[4].GetU32()
+	if !ok {
+		return nil, fmt.Errorf("invalid event topic: %w", ErrInvalidEventFormat)
+	}
+
+	// the final votes were already a map in v1
+	finalVotes, err := NewVoteCountFromXDR(body.Data)
+	if errors.Is(err, ErrInvalidAmount) {
+		return nil, fmt.Errorf("unable to parse final votes: %w", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to parse final votes: %w", ErrEventParsingFailed)
+	}
+	return &ProposalVotingClosedData{
+		Status:     uint32(status),
+		Eta:        uint32(eta),
+		FinalVotes: *finalVotes,
+	}, nil
+}
+
+// NewVoteCastDataFromEventBodyV2 parses the data of a v2 vote_cast event
+func NewVoteCastDataFromEventBodyV2(body xdr.ContractEventV0) (*VoteCastData, error) {
+	if len(body.Topics) < 4 {
+		return nil, fmt.Errorf("unexpected number of topics in event: %w", ErrInvalidEventFormat)
+	}
+	warnExtraContent("vote_cast", "topics", body.Topics[4:])
+
+	// the voter may be an account or a contract, such as a smart wallet or multisig
+	voter, err := addressFromScVal(body.Topics[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid voter in event topic: %w", ErrInvalidEventFormat)
+	}
+
+	fields, err := readDataMap("vote_cast", body.Data, "support", "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	var data VoteCastData
+	data.Voter = voter
+	support, ok := fields[0].GetU32()
+	if !ok {
+		return nil, fmt.Errorf("support is not a u32 %w", ErrEventParsingFailed)
+	}
+	data.Support = uint32(support)
+	amountXdr, ok := fields[1].GetI128()
+	if !ok {
+		return nil, fmt.Errorf("amount is not an i128 %w", ErrEventParsingFailed)
+	}
+	data.Amount, err = parseAmount("amount", amountXdr)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// readDataMap returns the values of the given keys from map encoded event data, in the order of keys. Every key is
+// required. Unknown keys are logged and ignored.
+func readDataMap(eventType string, data xdr.ScVal, keys ...string) ([]xdr.ScVal, error) {
+	mapData, ok := data.GetMap()
+	if !ok || mapData == nil {
+		return nil, fmt.Errorf("event data is not a map %w", ErrInvalidEventFormat)
+	}
+
+	vals := make([]xdr.ScVal, len(keys))
+	found := make([]bool, len(keys))
+	for _, entry := range *mapData {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return nil, fmt.Errorf("event data key is not a symbol %w", ErrEventParsingFailed)
+		}
+		known := false
+		for i, k := range keys {
+			if string(key) == k {
+				vals[i] = entry.Val
+				found[i] = true
+				known = true
+				break
+			}
+		}
+		if !known {
+			valXdr, _ := xdr.MarshalBase64(entry.Val)
+			slog.Warn("Ignoring unknown key in governor event data", "type", eventType, "key", string(key), "val", valXdr)
+		}
+	}
+	for i, k := range keys {
+		if !found[i] {
+			return nil, fmt.Errorf("missing %s in event data %w", k, ErrEventParsingFailed)
+		}
+	}
+	return vals, nil
+}
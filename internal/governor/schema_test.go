@@ -0,0 +1,241 @@
+package governor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	proposalCreatedXdr      = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAQcHJvcG9zYWxfY3JlYXRlZAAAAAMAAAADAAAAEgAAAAAAAAAALJ/M6wbqSvh6BcSe5KJD8aWHCTFHGu3YUKtUqAH05uUAAAAQAAAAAQAAAAUAAAAOAAAAGE1ha2UgbWUgc2VjdXJpdHkgY291bmNpbAAAAA4AAAADcGx6AAAAABAAAAABAAAAAgAAAA8AAAAHQ291bmNpbAAAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAAAMAEa9sAAAAAwAR8uw="
+	proposalCanceledXdr     = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAgAAAA8AAAARcHJvcG9zYWxfY2FuY2VsZWQAAAAAAAADAAAAAwAAAAE="
+	voteCastXdr             = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA="
+	proposalVotingClosedXdr = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAABAAAAA8AAAAWcHJvcG9zYWxfdm90aW5nX2Nsb3NlZAAAAAAAAwAAAAEAAAADAAAAAgAAAAMAAAAAAAAAEQAAAAEAAAADAAAADwAAAARfZm9yAAAACgAAAAAAAAAAAAAAAElQT4AAAAAPAAAAB2Fic3RhaW4AAAAACgAAAAAAAAAAAAAAAAAAAAAAAAAPAAAAB2FnYWluc3QAAAAACgAAAAAAAAAAAAAABKgXyAA="
+)
+
+// withSchemaVersion converts a v1 event to a later schema version, by adding the version topic and converting
+// vec data to a map with the given keys
+func withSchemaVersion(t *testing.T, eventXdr string, version string, keys ...string) xdr.ContractEvent {
+	t.Helper()
+	var ce xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(eventXdr, &ce); err != nil {
+		t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+	}
+	body := ce.Body.V0
+	versionSym := xdr.ScSymbol(version)
+	versionTopic := xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &versionSym}
+	body.Topics = append([]xdr.ScVal{body.Topics[0], versionTopic}, body.Topics[1:]...)
+
+	if len(keys) > 0 {
+		vec := *body.Data.MustVec()
+		m := make(xdr.ScMap, len(keys))
+		for i, key := range keys {
+			sym := xdr.ScSymbol(key)
+			m[i] = xdr.ScMapEntry{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, Val: vec[i]}
+		}
+		mp := &m
+		body.Data = xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &mp}
+	}
+	return ce
+}
+
+func TestNewGovernorEventFromContractEventV2(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      xdr.ContractEvent
+		wantType   string
+		proposalId uint32
+		wantData   string
+	}{
+		{
+			name:       "proposal_created",
+			event:      withSchemaVersion(t, proposalCreatedXdr, "v2", "title", "desc", "action", "vote_start", "vote_end"),
+			wantType:   "proposal_created",
+			proposalId: 3,
+			wantData:   `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+		},
+		{
+			name:       "proposal_canceled",
+			event:      withSchemaVersion(t, proposalCanceledXdr, "v2"),
+			wantType:   "proposal_canceled",
+			proposalId: 3,
+			wantData:   `{}`,
+		},
+		{
+			name:       "vote_cast",
+			event:      withSchemaVersion(t, voteCastXdr, "v2", "support", "amount"),
+			wantType:   "vote_cast",
+			proposalId: 2,
+			wantData:   `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":0,"amount":"20000000000"}`,
+		},
+		{
+			name:       "proposal_voting_closed",
+			event:      withSchemaVersion(t, proposalVotingClosedXdr, "v2"),
+			wantType:   "proposal_voting_closed",
+			proposalId: 1,
+			wantData:   `{"status":2,"eta":0,"final_votes":{"for":"1230000000","against":"20000000000","abstain":"0"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 1)
+			if err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			want := &GovernorEvent{
+				EventId:         "0005025695851872256-0000000001",
+				ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+				ProposalId:      tt.proposalId,
+				EventType:       tt.wantType,
+				EventData:       tt.wantData,
+				TxHash:          "hash",
+				LedgerSeq:       1170136,
+				LedgerCloseTime: 1761053046,
+				SchemaVersion:   SCHEMA_V2,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewGovernorEventFromContractEventContractAddress(t *testing.T) {
+	// smart wallets and multisigs propose and vote with a contract address
+	const wallet = "CCQKDIVDUSS2NJ5IVGVKXLFNV2X3BMNSWO2LLNVXXC43VO54XW7L65UW"
+	withAddress := func(event xdr.ContractEvent, topic int) xdr.ContractEvent {
+		body := *event.Body.V0
+		body.Topics = append([]xdr.ScVal{}, body.Topics...)
+		body.Topics[topic] = mustScAddress(t, wallet)
+		event.Body.V0 = &body
+		return event
+	}
+
+	tests := []struct {
+		name     string
+		event    xdr.ContractEvent
+		wantData string
+	}{
+		{
+			name:     "v1 proposal_created",
+			event:    withAddress(mustUnmarshalEvent(t, proposalCreatedXdr), 2),
+			wantData: `{"proposer":"` + wallet + `","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+		},
+		{
+			name:     "v1 vote_cast",
+			event:    withAddress(mustUnmarshalEvent(t, voteCastXdr), 2),
+			wantData: `{"voter":"` + wallet + `","support":0,"amount":"20000000000"}`,
+		},
+		{
+			name:     "v2 proposal_created",
+			event:    withAddress(withSchemaVersion(t, proposalCreatedXdr, "v2", "title", "desc", "action", "vote_start", "vote_end"), 3),
+			wantData: `{"proposer":"` + wallet + `","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1159020,"vote_end":1176300}`,
+		},
+		{
+			name:     "v2 vote_cast",
+			event:    withAddress(withSchemaVersion(t, voteCastXdr, "v2", "support", "amount"), 3),
+			wantData: `{"voter":"` + wallet + `","support":0,"amount":"20000000000"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 1)
+			if err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			if got.EventData != tt.wantData {
+				t.Errorf("EventData = %s, want %s", got.EventData, tt.wantData)
+			}
+		})
+	}
+
+	// an address topic that is neither an account nor a contract is rejected, rather than panicking
+	invalid := withAddress(mustUnmarshalEvent(t, voteCastXdr), 2)
+	invalid.Body.V0.Topics[2] = xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+	if _, err := NewGovernorEventFromContractEvent(&invalid, "hash", 1170136, 1761053046, 5025695851872256, 1); !errors.Is(err, ErrInvalidEventFormat) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidEventFormat)
+	}
+}
+
+// mustUnmarshalEvent decodes a base64 contract event
+func mustUnmarshalEvent(t *testing.T, eventXdr string) xdr.ContractEvent {
+	t.Helper()
+	var ce xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(eventXdr, &ce); err != nil {
+		t.Fatalf("Setup Failed: Unable to unmarshal contract event xdr: %v", err)
+	}
+	return ce
+}
+
+func TestNewGovernorEventFromContractEventInvalidVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   xdr.ContractEvent
+		wantErr error
+	}{
+		{
+			name:    "unknown version",
+			event:   withSchemaVersion(t, voteCastXdr, "v3", "support", "amount"),
+			wantErr: ErrUnknownSchemaVersion,
+		},
+		{
+			name:    "v1 layout with a v2 version topic",
+			event:   withSchemaVersion(t, voteCastXdr, "v2"),
+			wantErr: ErrInvalidEventFormat,
+		},
+		{
+			name:    "v2 data missing a field",
+			event:   withSchemaVersion(t, voteCastXdr, "v2", "support"),
+			wantErr: ErrEventParsingFailed,
+		},
+		{
+			name:    "invalid version topic",
+			event:   withSchemaVersion(t, voteCastXdr, "version", "support", "amount"),
+			wantErr: ErrInvalidEventFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGovernorEventFromContractEvent(&tt.event, "hash", 1170136, 1761053046, 5025695851872256, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFailedEvent(t *testing.T) {
+	event := withSchemaVersion(t, voteCastXdr, "v3", "support", "amount")
+	_, parseErr := NewGovernorEventFromContractEvent(&event, "hash", 1170136, 1761053046, 5025695851872256, 1)
+	if !errors.Is(parseErr, ErrUnknownSchemaVersion) {
+		t.Fatalf("error = %v, want %v", parseErr, ErrUnknownSchemaVersion)
+	}
+
+	got, err := NewFailedEvent(&event, FAILED_REASON_UNKNOWN_SCHEMA_VERSION, parseErr, "hash", 1170136, 1761053046, 5025695851872256, 1)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	eventXdr, err := xdr.MarshalBase64(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	want := &FailedEvent{
+		EventId:         "0005025695851872256-0000000001",
+		ContractId:      "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB",
+		EventType:       "vote_cast",
+		Reason:          FAILED_REASON_UNKNOWN_SCHEMA_VERSION,
+		Error:           "schema version 3: unknown governor event schema version",
+		EventXdr:        eventXdr,
+		TxHash:          "hash",
+		LedgerSeq:       1170136,
+		LedgerCloseTime: 1761053046,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
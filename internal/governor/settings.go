@@ -0,0 +1,112 @@
+package governor
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// SETTINGS_INSTANCE_KEY is the governor's instance storage key holding its settings
+const SETTINGS_INSTANCE_KEY = "Settings"
+
+// The bits of a governor's counting type, each set if the votes with that support count toward quorum. Only the last
+// 3 bits are used, as MSB...{for}{against}{abstain}.
+const (
+	COUNTING_TYPE_ABSTAIN uint32 = 1 << iota
+	COUNTING_TYPE_AGAINST
+	COUNTING_TYPE_FOR
+)
+
+// Settings are the parts of a governor's settings that decide how a proposal's votes are counted. The quorum and vote
+// threshold are in basis points.
+type Settings struct {
+	GovernorId string
+	// CountingType has a COUNTING_TYPE bit set for each support that counts toward quorum
+	CountingType uint32
+	// Quorum is the share of the vote token's supply that must count toward quorum for a proposal to pass
+	Quorum uint32
+	// VoteThreshold is the share of the votes for and against that must be for a proposal to pass
+	VoteThreshold uint32
+	// LedgerSeq is the ledger the settings were read from
+	LedgerSeq uint32
+}
+
+// NewSettingsFromXDR parses the counting type, quorum, and vote threshold of a governor settings map. Other keys are
+// ignored.
+func NewSettingsFromXDR(data xdr.ScVal) (*Settings, error) {
+	mapData, ok := data.GetMap()
+	if !ok || mapData == nil {
+		return nil, fmt.Errorf("settings is not a map")
+	}
+	var settings Settings
+	found := 0
+	for _, entry := range *mapData {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return nil, fmt.Errorf("settings key is not a symbol")
+		}
+		var field *uint32
+		switch string(key) {
+		case "counting_type":
+			field = &settings.CountingType
+		case "quorum":
+			field = &settings.Quorum
+		case "vote_threshold":
+			field = &settings.VoteThreshold
+		default:
+			continue
+		}
+		val, ok := entry.Val.GetU32()
+		if !ok {
+			return nil, fmt.Errorf("settings %s is not a u32", key)
+		}
+		*field = uint32(val)
+		found++
+	}
+	if found != 3 {
+		return nil, fmt.Errorf("missing required fields in settings")
+	}
+	return &settings, nil
+}
+
+// SettingsFromLedgerEntry returns the settings of a governor from its contract instance ledger entry. ok is false if
+// the entry is not a contract instance with valid settings in its storage. The settings' ledger is not set.
+func SettingsFromLedgerEntry(entry *xdr.LedgerEntry) (*Settings, bool) {
+	governorId, val, ok := instanceValue(entry, SETTINGS_INSTANCE_KEY)
+	if !ok {
+		return nil, false
+	}
+	settings, err := NewSettingsFromXDR(val)
+	if err != nil {
+		return nil, false
+	}
+	settings.GovernorId = governorId
+	return settings, true
+}
+
+// instanceValue returns the governor address of a contract instance ledger entry, and the value of key in its
+// instance storage. ok is false if the entry is not a contract instance with key in its storage.
+func instanceValue(entry *xdr.LedgerEntry, key string) (governorId string, val xdr.ScVal, ok bool) {
+	contractData, ok := entry.Data.GetContractData()
+	if !ok || contractData.Key.Type != xdr.ScValTypeScvLedgerKeyContractInstance {
+		return "", xdr.ScVal{}, false
+	}
+	instance, ok := contractData.Val.GetInstance()
+	if !ok || instance.Storage == nil || contractData.Contract.ContractId == nil {
+		return "", xdr.ScVal{}, false
+	}
+
+	for _, entry := range *instance.Storage {
+		sym, ok := entry.Key.GetSym()
+		if !ok || string(sym) != key {
+			continue
+		}
+		governorId, err := strkey.Encode(strkey.VersionByteContract, contractData.Contract.ContractId[:])
+		if err != nil {
+			return "", xdr.ScVal{}, false
+		}
+		return governorId, entry.Val, true
+	}
+	return "", xdr.ScVal{}, false
+}
@@ -0,0 +1,88 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// newSettingsVal returns a governor settings map with every field of the contract's settings, with the u32 fields
+// overridden by fields. A field set to a void ScVal is removed.
+func newSettingsVal(fields map[string]xdr.ScVal) xdr.ScVal {
+	u32 := func(val uint32) xdr.ScVal {
+		v := xdr.Uint32(val)
+		return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+	}
+	threshold := xdr.Int128Parts{Lo: 10000000}
+	vals := map[string]xdr.ScVal{
+		"counting_type":      u32(0b110),
+		"grace_period":       u32(17280),
+		"proposal_threshold": {Type: xdr.ScValTypeScvI128, I128: &threshold},
+		"quorum":             u32(500),
+		"timelock":           u32(0),
+		"vote_delay":         u32(0),
+		"vote_period":        u32(17280),
+		"vote_threshold":     u32(5100),
+	}
+	for key, val := range fields {
+		vals[key] = val
+	}
+	// maps are sorted by key, as the contract writes them
+	var settings xdr.ScMap
+	for _, key := range []string{"counting_type", "grace_period", "proposal_threshold", "quorum", "timelock", "vote_delay", "vote_period", "vote_threshold"} {
+		val := vals[key]
+		if val.Type == xdr.ScValTypeScvVoid {
+			continue
+		}
+		sym := xdr.ScSymbol(key)
+		settings = append(settings, xdr.ScMapEntry{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, Val: val})
+	}
+	settingsPtr := &settings
+	return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &settingsPtr}
+}
+
+func TestNewSettingsFromXDR(t *testing.T) {
+	abstainOnly := xdr.Uint32(COUNTING_TYPE_ABSTAIN)
+	tests := []struct {
+		name    string
+		data    xdr.ScVal
+		want    *Settings
+		wantErr bool
+	}{
+		{name: "settings", data: newSettingsVal(nil), want: &Settings{CountingType: 0b110, Quorum: 500, VoteThreshold: 5100}},
+		{name: "counting type", data: newSettingsVal(map[string]xdr.ScVal{"counting_type": {Type: xdr.ScValTypeScvU32, U32: &abstainOnly}}), want: &Settings{CountingType: COUNTING_TYPE_ABSTAIN, Quorum: 500, VoteThreshold: 5100}},
+		{name: "missing quorum", data: newSettingsVal(map[string]xdr.ScVal{"quorum": {Type: xdr.ScValTypeScvVoid}}), wantErr: true},
+		{name: "vote threshold not a u32", data: newSettingsVal(map[string]xdr.ScVal{"vote_threshold": mustScAddress(t, testVotesId)}), wantErr: true},
+		{name: "not a map", data: mustScAddress(t, testVotesId), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSettingsFromXDR(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSettingsFromXDR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewSettingsFromXDR() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSettingsFromLedgerEntry(t *testing.T) {
+	settings, ok := SettingsFromLedgerEntry(newInstanceEntry(SETTINGS_INSTANCE_KEY, newSettingsVal(nil)))
+	want := &Settings{GovernorId: "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", CountingType: 0b110, Quorum: 500, VoteThreshold: 5100}
+	if !ok {
+		t.Fatalf("expected settings from the governor instance")
+	}
+	if diff := cmp.Diff(want, settings); diff != "" {
+		t.Errorf("SettingsFromLedgerEntry() mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := SettingsFromLedgerEntry(newInstanceEntry(VOTES_INSTANCE_KEY, mustScAddress(t, testVotesId))); ok {
+		t.Errorf("got settings from an instance without them")
+	}
+	if _, ok := SettingsFromLedgerEntry(newInstanceEntry(SETTINGS_INSTANCE_KEY, mustScAddress(t, testVotesId))); ok {
+		t.Errorf("got settings from an instance with invalid settings")
+	}
+}
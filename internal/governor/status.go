@@ -0,0 +1,29 @@
+package governor
+
+import "slices"
+
+// ProposalStatus is the status of a proposal, as stored in Proposal.Status
+type ProposalStatus uint32
+
+const (
+	PROPOSAL_STATUS_OPEN       ProposalStatus = 0
+	PROPOSAL_STATUS_SUCCESSFUL ProposalStatus = 1
+	PROPOSAL_STATUS_DEFEATED   ProposalStatus = 2
+	PROPOSAL_STATUS_EXPIRED    ProposalStatus = 3
+	PROPOSAL_STATUS_EXECUTED   ProposalStatus = 4
+	PROPOSAL_STATUS_CANCELED   ProposalStatus = 5
+)
+
+// proposalTransitions are the statuses each status can change to. Open proposals are closed as successful,
+// defeated, or expired, or are canceled before voting starts. Successful proposals are executed, or expire if they
+// aren't executed in time. Defeated, expired, executed, and canceled proposals are final.
+var proposalTransitions = map[ProposalStatus][]ProposalStatus{
+	PROPOSAL_STATUS_OPEN:       {PROPOSAL_STATUS_SUCCESSFUL, PROPOSAL_STATUS_DEFEATED, PROPOSAL_STATUS_EXPIRED, PROPOSAL_STATUS_CANCELED},
+	PROPOSAL_STATUS_SUCCESSFUL: {PROPOSAL_STATUS_EXPIRED, PROPOSAL_STATUS_EXECUTED},
+}
+
+// CanTransition returns true if a proposal with status from can change to status to. A status can't change to
+// itself, and unknown statuses can't change.
+func CanTransition(from ProposalStatus, to ProposalStatus) bool {
+	return slices.Contains(proposalTransitions[from], to)
+}
@@ -0,0 +1,34 @@
+package governor
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	const (
+		open       = PROPOSAL_STATUS_OPEN
+		successful = PROPOSAL_STATUS_SUCCESSFUL
+		defeated   = PROPOSAL_STATUS_DEFEATED
+		expired    = PROPOSAL_STATUS_EXPIRED
+		executed   = PROPOSAL_STATUS_EXECUTED
+		canceled   = PROPOSAL_STATUS_CANCELED
+		unknown    = ProposalStatus(6)
+	)
+	statuses := []ProposalStatus{open, successful, defeated, expired, executed, canceled, unknown}
+	// allowed lists every allowed transition, every other pair of statuses is rejected
+	allowed := map[[2]ProposalStatus]bool{
+		{open, successful}:     true,
+		{open, defeated}:       true,
+		{open, expired}:        true,
+		{open, canceled}:       true,
+		{successful, expired}:  true,
+		{successful, executed}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]ProposalStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%d, %d) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
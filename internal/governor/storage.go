@@ -0,0 +1,186 @@
+package governor
+
+import (
+	"fmt"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// PROPOSAL_CONFIG_KEY is the variant of the governor's storage key holding a proposal's title, description, and
+	// action, keyed by proposal id
+	PROPOSAL_CONFIG_KEY = "Config"
+	// PROPOSAL_DATA_KEY is the variant of the governor's storage key holding a proposal's status and execution
+	// unlock, keyed by proposal id
+	PROPOSAL_DATA_KEY = "Data"
+	// PROPOSAL_VOTE_COUNT_KEY is the variant of the governor's storage key holding a proposal's vote counts, keyed
+	// by proposal id
+	PROPOSAL_VOTE_COUNT_KEY = "VoteCount"
+)
+
+// ProposalData is the part of a proposal's on-chain data compared against indexed proposals
+type ProposalData struct {
+	Status uint32
+	// Ledger the proposal can be executed from, or 0 if it is not executable
+	Eta uint32
+}
+
+// ProposalStorageKey returns the ledger key of a governor's contract data entry for a proposal, where variant is
+// PROPOSAL_CONFIG_KEY, PROPOSAL_DATA_KEY, or PROPOSAL_VOTE_COUNT_KEY. The key is the contract type enum variant with the proposal id.
+func ProposalStorageKey(contractId string, variant string, proposalId uint32, durability xdr.ContractDataDurability) (xdr.LedgerKey, error) {
+	decoded, err := strkey.Decode(strkey.VersionByteContract, contractId)
+	if err != nil {
+		return xdr.LedgerKey{}, fmt.Errorf("invalid contract id %s: %w", contractId, err)
+	}
+	var id xdr.ContractId
+	copy(id[:], decoded)
+
+	sym := xdr.ScSymbol(variant)
+	u32 := xdr.Uint32(proposalId)
+	vec := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &sym},
+		{Type: xdr.ScValTypeScvU32, U32: &u32},
+	}
+	return xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id},
+			Key:        xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &vec},
+			Durability: durability,
+		},
+	}, nil
+}
+
+// NewProposalDataFromXDR parses the status and eta of a proposal data map. Other keys are ignored.
+func NewProposalDataFromXDR(data xdr.ScVal) (*ProposalData, error) {
+	mapData, ok := data.GetMap()
+	if !ok || mapData == nil {
+		return nil, fmt.Errorf("proposal data is not a map")
+	}
+	var proposalData ProposalData
+	found := 0
+	for _, entry := range *mapData {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return nil, fmt.Errorf("proposal data key is not a symbol")
+		}
+		switch string(key) {
+		case "status":
+			val, ok := entry.Val.GetU32()
+			if !ok {
+				return nil, fmt.Errorf("proposal data status is not a u32")
+			}
+			proposalData.Status = uint32(val)
+			found++
+		case "eta":
+			val, ok := entry.Val.GetU32()
+			if !ok {
+				return nil, fmt.Errorf("proposal data eta is not a u32")
+			}
+			proposalData.Eta = uint32(val)
+			found++
+		}
+	}
+	if found != 2 {
+		return nil, fmt.Errorf("missing required fields in proposal data")
+	}
+	return &proposalData, nil
+}
+
+// NewProposalFromStorage builds a proposal from its config and data entries in the governor's storage, and its vote
+// count entry, if any votes were cast. It is used to serve proposals the indexer hasn't seen yet, so the fields only
+// known from events, such as the creation ledger and transaction, are empty. Fields over the limits set with
+// SetFieldLimits are truncated, as for proposal_created events.
+func NewProposalFromStorage(contractId string, proposalId uint32, config xdr.ScVal, data xdr.ScVal, voteCount *VoteCount) (*Proposal, error) {
+	proposalData, err := NewProposalDataFromXDR(data)
+	if err != nil {
+		return nil, err
+	}
+	created := ProposalCreatedData{}
+
+	configMap, ok := config.GetMap()
+	if !ok || configMap == nil {
+		return nil, fmt.Errorf("proposal config is not a map")
+	}
+	for _, entry := range *configMap {
+		key, ok := entry.Key.GetSym()
+		if !ok {
+			return nil, fmt.Errorf("proposal config key is not a symbol")
+		}
+		switch string(key) {
+		case "title":
+			val, ok := entry.Val.GetStr()
+			if !ok {
+				return nil, fmt.Errorf("proposal config title is not a str")
+			}
+			created.Title = scString(val)
+		case "description":
+			val, ok := entry.Val.GetStr()
+			if !ok {
+				return nil, fmt.Errorf("proposal config description is not a str")
+			}
+			created.Desc = scString(val)
+		case "action":
+			created.Action, err = xdr.MarshalBase64(entry.Val)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal proposal config action: %w", err)
+			}
+		}
+	}
+
+	dataMap, _ := data.GetMap()
+	for _, entry := range *dataMap {
+		key, _ := entry.Key.GetSym()
+		switch string(key) {
+		case "creator":
+			val, ok := entry.Val.GetAddress()
+			if !ok {
+				return nil, fmt.Errorf("proposal data creator is not an address")
+			}
+			created.Proposer, err = val.String()
+			if err != nil {
+				return nil, fmt.Errorf("invalid proposal data creator: %w", err)
+			}
+		case "vote_start":
+			val, ok := entry.Val.GetU32()
+			if !ok {
+				return nil, fmt.Errorf("proposal data vote_start is not a u32")
+			}
+			created.VoteStart = uint32(val)
+		case "vote_end":
+			val, ok := entry.Val.GetU32()
+			if !ok {
+				return nil, fmt.Errorf("proposal data vote_end is not a u32")
+			}
+			created.VoteEnd = uint32(val)
+		}
+	}
+	created.applyFieldLimits()
+
+	// the vote count is only written once a vote is cast
+	if voteCount == nil {
+		voteCount = &VoteCount{For: "0", Against: "0", Abstain: "0"}
+	}
+	action := ClassifyAction(created.Action)
+	return &Proposal{
+		ProposalKey:      EncodeProposalKey(contractId, proposalId),
+		ContractId:       contractId,
+		ProposalId:       proposalId,
+		Proposer:         created.Proposer,
+		Status:           proposalData.Status,
+		Title:            created.Title,
+		Description:      created.Desc,
+		Action:           created.Action,
+		ActionType:       action.Type,
+		ActionContractId: action.ContractId,
+		ActionFunction:   action.Function,
+		VoteStart:        created.VoteStart,
+		VoteEnd:          created.VoteEnd,
+		VotesFor:         voteCount.For,
+		VotesAgainst:     voteCount.Against,
+		VotesAbstain:     voteCount.Abstain,
+		ExecutionUnlock:  proposalData.Eta,
+		Truncated:        created.Truncated,
+	}, nil
+}
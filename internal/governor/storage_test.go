@@ -0,0 +1,185 @@
+package governor
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestProposalStorageKey(t *testing.T) {
+	key, err := ProposalStorageKey("CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB", PROPOSAL_DATA_KEY, 7, xdr.ContractDataDurabilityPersistent)
+	if err != nil {
+		t.Fatalf("ProposalStorageKey() error = %v", err)
+	}
+	contractId, err := key.ContractData.Contract.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contractId != "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB" {
+		t.Errorf("contract = %s", contractId)
+	}
+	vec, ok := key.ContractData.Key.GetVec()
+	if !ok || vec == nil || len(*vec) != 2 {
+		t.Fatalf("key is not a vec of 2 values: %v", key.ContractData.Key)
+	}
+	if sym, _ := (*vec)[0].GetSym(); string(sym) != PROPOSAL_DATA_KEY {
+		t.Errorf("variant = %s, want %s", sym, PROPOSAL_DATA_KEY)
+	}
+	if id, _ := (*vec)[1].GetU32(); id != 7 {
+		t.Errorf("proposal id = %d, want 7", id)
+	}
+
+	if _, err := ProposalStorageKey("GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q", PROPOSAL_DATA_KEY, 7, xdr.ContractDataDurabilityPersistent); err == nil {
+		t.Errorf("ProposalStorageKey() with an account id error = nil, want error")
+	}
+}
+
+func TestNewProposalDataFromXDR(t *testing.T) {
+	sym := func(s string) xdr.ScVal {
+		v := xdr.ScSymbol(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &v}
+	}
+	u32 := func(n uint32) xdr.ScVal {
+		v := xdr.Uint32(n)
+		return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+	}
+	scMap := func(entries ...xdr.ScMapEntry) xdr.ScVal {
+		m := xdr.ScMap(entries)
+		pm := &m
+		return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &pm}
+	}
+
+	tests := []struct {
+		name    string
+		data    xdr.ScVal
+		want    *ProposalData
+		wantErr bool
+	}{
+		{
+			name: "successful",
+			data: scMap(
+				xdr.ScMapEntry{Key: sym("creator"), Val: sym("ignored")},
+				xdr.ScMapEntry{Key: sym("eta"), Val: u32(1171234)},
+				xdr.ScMapEntry{Key: sym("status"), Val: u32(1)},
+			),
+			want: &ProposalData{Status: 1, Eta: 1171234},
+		},
+		{name: "missing eta", data: scMap(xdr.ScMapEntry{Key: sym("status"), Val: u32(1)}), wantErr: true},
+		{name: "status not u32", data: scMap(xdr.ScMapEntry{Key: sym("status"), Val: sym("Open")}, xdr.ScMapEntry{Key: sym("eta"), Val: u32(0)}), wantErr: true},
+		{name: "not a map", data: u32(1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewProposalDataFromXDR(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProposalDataFromXDR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewProposalFromStorage(t *testing.T) {
+	sym := func(s string) xdr.ScVal {
+		v := xdr.ScSymbol(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &v}
+	}
+	str := func(s string) xdr.ScVal {
+		v := xdr.ScString(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &v}
+	}
+	u32 := func(n uint32) xdr.ScVal {
+		v := xdr.Uint32(n)
+		return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+	}
+	scMap := func(entries ...xdr.ScMapEntry) xdr.ScVal {
+		m := xdr.ScMap(entries)
+		pm := &m
+		return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &pm}
+	}
+	creator := func(address string) xdr.ScVal {
+		addr := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: xdr.MustAddressPtr(address)}
+		return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &addr}
+	}
+	contractId := "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
+	proposer := "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q"
+
+	action := xdr.ScVec{sym("Snapshot")}
+	pAction := &action
+	actionVal := xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &pAction}
+	actionXdr, err := xdr.MarshalBase64(actionVal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := scMap(
+		xdr.ScMapEntry{Key: sym("action"), Val: actionVal},
+		xdr.ScMapEntry{Key: sym("description"), Val: str("A description")},
+		xdr.ScMapEntry{Key: sym("title"), Val: str("A title")},
+	)
+	data := scMap(
+		xdr.ScMapEntry{Key: sym("creator"), Val: creator(proposer)},
+		xdr.ScMapEntry{Key: sym("eta"), Val: u32(0)},
+		xdr.ScMapEntry{Key: sym("executable"), Val: xdr.ScVal{Type: xdr.ScValTypeScvBool, B: new(bool)}},
+		xdr.ScMapEntry{Key: sym("status"), Val: u32(0)},
+		xdr.ScMapEntry{Key: sym("vote_end"), Val: u32(2000)},
+		xdr.ScMapEntry{Key: sym("vote_start"), Val: u32(1000)},
+	)
+
+	got, err := NewProposalFromStorage(contractId, 3, config, data, &VoteCount{For: "100", Against: "0", Abstain: "5"})
+	if err != nil {
+		t.Fatalf("NewProposalFromStorage() error = %v", err)
+	}
+	classified := ClassifyAction(actionXdr)
+	want := &Proposal{
+		ProposalKey:      EncodeProposalKey(contractId, 3),
+		ContractId:       contractId,
+		ProposalId:       3,
+		Proposer:         proposer,
+		Title:            "A title",
+		Description:      "A description",
+		Action:           actionXdr,
+		ActionType:       classified.Type,
+		ActionContractId: classified.ContractId,
+		ActionFunction:   classified.Function,
+		VoteStart:        1000,
+		VoteEnd:          2000,
+		VotesFor:         "100",
+		VotesAgainst:     "0",
+		VotesAbstain:     "5",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// without a vote count entry, no votes were cast
+	got, err = NewProposalFromStorage(contractId, 3, config, data, nil)
+	if err != nil {
+		t.Fatalf("NewProposalFromStorage() error = %v", err)
+	}
+	if got.VotesFor != "0" || got.VotesAgainst != "0" || got.VotesAbstain != "0" {
+		t.Errorf("got tallies %s/%s/%s without a vote count, want 0/0/0", got.VotesFor, got.VotesAgainst, got.VotesAbstain)
+	}
+
+	invalid := []struct {
+		name   string
+		config xdr.ScVal
+		data   xdr.ScVal
+	}{
+		{name: "config not a map", config: u32(1), data: data},
+		{name: "title not a str", config: scMap(xdr.ScMapEntry{Key: sym("title"), Val: u32(1)}), data: data},
+		{name: "data missing status", config: config, data: scMap(xdr.ScMapEntry{Key: sym("eta"), Val: u32(0)})},
+		{name: "vote_end not a u32", config: config, data: scMap(
+			xdr.ScMapEntry{Key: sym("eta"), Val: u32(0)},
+			xdr.ScMapEntry{Key: sym("status"), Val: u32(0)},
+			xdr.ScMapEntry{Key: sym("vote_end"), Val: str("soon")},
+		)},
+	}
+	for _, tt := range invalid {
+		if _, err := NewProposalFromStorage(contractId, 3, tt.config, tt.data, nil); err == nil {
+			t.Errorf("%s: NewProposalFromStorage() error = nil, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package governor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VoteSupport is the support of a vote, as stored in Vote.Support
+type VoteSupport uint32
+
+const (
+	VOTE_SUPPORT_AGAINST VoteSupport = 0
+	VOTE_SUPPORT_FOR     VoteSupport = 1
+	VOTE_SUPPORT_ABSTAIN VoteSupport = 2
+)
+
+// VOTE_SUPPORT_LABEL_OTHER is the label of support values other than against, for, and abstain, which a contract
+// with custom support semantics may emit
+const VOTE_SUPPORT_LABEL_OTHER = "other"
+
+// voteSupportLabels are the labels of the known support values
+var voteSupportLabels = map[VoteSupport]string{
+	VOTE_SUPPORT_AGAINST: "against",
+	VOTE_SUPPORT_FOR:     "for",
+	VOTE_SUPPORT_ABSTAIN: "abstain",
+}
+
+// Known returns true if the support is against, for, or abstain
+func (s VoteSupport) Known() bool {
+	_, ok := voteSupportLabels[s]
+	return ok
+}
+
+// Label returns "against", "for", or "abstain", or VOTE_SUPPORT_LABEL_OTHER for unknown support values
+func (s VoteSupport) Label() string {
+	if label, ok := voteSupportLabels[s]; ok {
+		return label
+	}
+	return VOTE_SUPPORT_LABEL_OTHER
+}
+
+// ParseVoteSupport parses a support value, either its numeric value or the label of a known support, case
+// insensitive. Numeric values that aren't known are accepted, so votes with custom support semantics can be matched.
+func ParseVoteSupport(value string) (VoteSupport, error) {
+	if support, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return VoteSupport(support), nil
+	}
+	for support, label := range voteSupportLabels {
+		if strings.EqualFold(value, label) {
+			return support, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid support %q, must be a number or one of against, for, abstain", value)
+}
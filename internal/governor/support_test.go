@@ -0,0 +1,53 @@
+package governor
+
+import "testing"
+
+func TestVoteSupportLabel(t *testing.T) {
+	tests := []struct {
+		support VoteSupport
+		want    string
+		known   bool
+	}{
+		{support: VOTE_SUPPORT_AGAINST, want: "against", known: true},
+		{support: VOTE_SUPPORT_FOR, want: "for", known: true},
+		{support: VOTE_SUPPORT_ABSTAIN, want: "abstain", known: true},
+		{support: 3, want: "other", known: false},
+		{support: 4294967295, want: "other", known: false},
+	}
+	for _, tt := range tests {
+		if got := tt.support.Label(); got != tt.want {
+			t.Errorf("VoteSupport(%d).Label() = %q, want %q", tt.support, got, tt.want)
+		}
+		if got := tt.support.Known(); got != tt.known {
+			t.Errorf("VoteSupport(%d).Known() = %v, want %v", tt.support, got, tt.known)
+		}
+	}
+}
+
+func TestParseVoteSupport(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    VoteSupport
+		wantErr bool
+	}{
+		{value: "0", want: VOTE_SUPPORT_AGAINST},
+		{value: "1", want: VOTE_SUPPORT_FOR},
+		{value: "abstain", want: VOTE_SUPPORT_ABSTAIN},
+		{value: "For", want: VOTE_SUPPORT_FOR},
+		{value: "7", want: 7},
+		{value: "other", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "4294967296", wantErr: true},
+		{value: "yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVoteSupport(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVoteSupport(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseVoteSupport(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package governor
 
 import (
 	"fmt"
+	"log/slog"
 
-	"github.com/stellar/go-stellar-sdk/amount"
 	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
@@ -14,12 +14,15 @@ type VoteCount struct {
 	Abstain string `json:"abstain"`
 }
 
+// NewVoteCountFromXDR parses a vote count map. Unknown keys are logged and ignored, but every known count is required.
+// Negative counts are rejected with ErrInvalidAmount.
 func NewVoteCountFromXDR(data xdr.ScVal) (*VoteCount, error) {
 	mapData, ok := data.GetMap()
 	if !ok {
 		return nil, fmt.Errorf("vote_count is not a map")
 	}
 	var voteCount VoteCount
+	var err error
 	for _, entry := range *mapData {
 		key, ok := entry.Key.GetSym()
 		if !ok {
@@ -31,23 +34,33 @@ func NewVoteCountFromXDR(data xdr.ScVal) (*VoteCount, error) {
 			if !ok {
 				return nil, fmt.Errorf("vote_count _for is not an i128")
 			}
-			voteCount.For = amount.String128Raw(val)
+			voteCount.For, err = parseAmount("vote_count _for", val)
+			if err != nil {
+				return nil, err
+			}
 		case "against":
 			val, ok := entry.Val.GetI128()
 			if !ok {
 				return nil, fmt.Errorf("vote_count against is not an i128")
 			}
-			voteCount.Against = amount.String128Raw(val)
+			voteCount.Against, err = parseAmount("vote_count against", val)
+			if err != nil {
+				return nil, err
+			}
 		case "abstain":
 			val, ok := entry.Val.GetI128()
 			if !ok {
 				return nil, fmt.Errorf("vote_count abstain is not an i128")
 			}
-			voteCount.Abstain = amount.String128Raw(val)
+			voteCount.Abstain, err = parseAmount("vote_count abstain", val)
+			if err != nil {
+				return nil, err
+			}
 		default:
-			return nil, fmt.Errorf("unknown vote_count key: %s", string(key))
+			// newer contract releases may add vote counts, which are ignored
+			valXdr, _ := xdr.MarshalBase64(entry.Val)
+			slog.Warn("Ignoring unknown vote_count key", "key", string(key), "val", valXdr)
 		}
-
 	}
 	if voteCount.For == "" || voteCount.Against == "" || voteCount.Abstain == "" {
 		return nil, fmt.Errorf("missing required fields in event data")
@@ -2,7 +2,11 @@ package governor
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
 )
 
 type Vote struct {
@@ -14,6 +18,8 @@ type Vote struct {
 	Amount          string
 	LedgerSeq       uint32
 	LedgerCloseTime int64
+	// Reason given by the voter, empty if the vote_cast event has none
+	Reason string
 }
 
 func NewVoteFromVoteCastEvent(event *GovernorEvent) (*Vote, error) {
@@ -36,6 +42,210 @@ func NewVoteFromVoteCastEvent(event *GovernorEvent) (*Vote, error) {
 		Amount:          voteCastData.Amount,
 		LedgerSeq:       event.LedgerSeq,
 		LedgerCloseTime: event.LedgerCloseTime,
+		Reason:          voteCastData.Reason,
 	}
 	return vote, nil
 }
+
+// VoteBucket aggregates the votes cast with the same support
+type VoteBucket struct {
+	// Number of distinct voters
+	Voters int `json:"voters"`
+	// Sum of the vote amounts
+	Total string `json:"total"`
+	// Largest single vote amount
+	Max string `json:"max"`
+}
+
+// VoteSummary aggregates the votes on a proposal by support
+type VoteSummary struct {
+	For     VoteBucket `json:"for"`
+	Against VoteBucket `json:"against"`
+	Abstain VoteBucket `json:"abstain"`
+}
+
+// NewVoteSummary aggregates votes by support. Only the latest vote of each voter, by ledger sequence, is counted, so
+// a voter that changed their vote is counted once with their latest support and amount.
+//
+// Amounts are i128s, which sqlite can't sum exactly, so are summed here. On postgres, the same summary is computed in
+// SQL from NUMERIC columns.
+func NewVoteSummary(votes []*Vote) (*VoteSummary, error) {
+	latest := make(map[string]*Vote, len(votes))
+	for _, vote := range votes {
+		if prev, ok := latest[vote.Voter]; !ok || vote.LedgerSeq > prev.LedgerSeq {
+			latest[vote.Voter] = vote
+		}
+	}
+
+	var totals, maxes [3]*big.Int
+	var voters [3]int
+	for i := range totals {
+		totals[i] = new(big.Int)
+		maxes[i] = new(big.Int)
+	}
+	for _, vote := range latest {
+		if !VoteSupport(vote.Support).Known() {
+			return nil, fmt.Errorf("invalid support value %d in vote %s", vote.Support, vote.TxHash)
+		}
+		amount, err := bigmath.ParseAmount(vote.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("vote %s: %w", vote.TxHash, err)
+		}
+		voters[vote.Support]++
+		totals[vote.Support].Add(totals[vote.Support], amount)
+		if amount.Cmp(maxes[vote.Support]) > 0 {
+			maxes[vote.Support] = amount
+		}
+	}
+
+	bucket := func(support VoteSupport) VoteBucket {
+		return VoteBucket{Voters: voters[support], Total: totals[support].String(), Max: maxes[support].String()}
+	}
+	return &VoteSummary{
+		For:     bucket(VOTE_SUPPORT_FOR),
+		Against: bucket(VOTE_SUPPORT_AGAINST),
+		Abstain: bucket(VOTE_SUPPORT_ABSTAIN),
+	}, nil
+}
+
+// MAX_VOTE_SERIES_BUCKETS is the maximum number of buckets in a vote series
+const MAX_VOTE_SERIES_BUCKETS = 10000
+
+// ErrTooManyBuckets is returned when a vote series would have more than MAX_VOTE_SERIES_BUCKETS buckets
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// VoteSeriesTotals are the votes cast with the same support in a time bucket, and up to the end of the bucket
+type VoteSeriesTotals struct {
+	// Number of votes cast in the bucket
+	Count int `json:"count"`
+	// Sum of the amounts of votes cast in the bucket
+	Amount string `json:"amount"`
+	// Number of votes cast up to the end of the bucket
+	CumulativeCount int `json:"cumulative_count"`
+	// Sum of the amounts of votes cast up to the end of the bucket
+	CumulativeAmount string `json:"cumulative_amount"`
+}
+
+// VoteSeriesPoint is the voting activity in a time bucket
+type VoteSeriesPoint struct {
+	// Start of the bucket (in seconds since epoch)
+	Start   int64            `json:"start"`
+	For     VoteSeriesTotals `json:"for"`
+	Against VoteSeriesTotals `json:"against"`
+	Abstain VoteSeriesTotals `json:"abstain"`
+}
+
+// VoteSeriesBucket is the number and sum of the votes cast with the same support in the bucket starting at Start,
+// for building a vote series from totals aggregated by the database
+type VoteSeriesBucket struct {
+	Start   int64
+	Support uint32
+	Count   int
+	Amount  string
+}
+
+// NewVoteSeries groups votes into buckets of bucketSize seconds by ledger close time, from the bucket of the first
+// vote to the bucket of the last vote. Buckets without votes are included, so the series is continuous. Series with
+// more than MAX_VOTE_SERIES_BUCKETS buckets are rejected with ErrTooManyBuckets.
+func NewVoteSeries(votes []*Vote, bucketSize int64) ([]*VoteSeriesPoint, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("invalid bucket size %d", bucketSize)
+	}
+	buckets := make([]voteSeriesBucket, len(votes))
+	for i, vote := range votes {
+		if !VoteSupport(vote.Support).Known() {
+			return nil, fmt.Errorf("invalid support value %d in vote %s", vote.Support, vote.TxHash)
+		}
+		amount, err := bigmath.ParseAmount(vote.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("vote %s: %w", vote.TxHash, err)
+		}
+		buckets[i] = voteSeriesBucket{
+			start:   vote.LedgerCloseTime - vote.LedgerCloseTime%bucketSize,
+			support: vote.Support,
+			count:   1,
+			amount:  amount,
+		}
+	}
+	return newVoteSeries(buckets, bucketSize)
+}
+
+// NewVoteSeriesFromBuckets builds a vote series, as NewVoteSeries, from votes already totalled by bucket and
+// support. Each bucket must start at a multiple of bucketSize. Amounts are summed, so don't need to fit in an i128.
+func NewVoteSeriesFromBuckets(buckets []*VoteSeriesBucket, bucketSize int64) ([]*VoteSeriesPoint, error) {
+	if bucketSize <= 0 {
+		return nil, fmt.Errorf("invalid bucket size %d", bucketSize)
+	}
+	parsed := make([]voteSeriesBucket, len(buckets))
+	for i, bucket := range buckets {
+		if !VoteSupport(bucket.Support).Known() {
+			return nil, fmt.Errorf("invalid support value %d in bucket %d", bucket.Support, bucket.Start)
+		}
+		if bucket.Start%bucketSize != 0 {
+			return nil, fmt.Errorf("bucket %d does not start at a multiple of %ds", bucket.Start, bucketSize)
+		}
+		amount, ok := new(big.Int).SetString(bucket.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q in bucket %d", bucket.Amount, bucket.Start)
+		}
+		parsed[i] = voteSeriesBucket{start: bucket.Start, support: bucket.Support, count: bucket.Count, amount: amount}
+	}
+	return newVoteSeries(parsed, bucketSize)
+}
+
+type voteSeriesBucket struct {
+	start   int64
+	support uint32
+	count   int
+	amount  *big.Int
+}
+
+// newVoteSeries builds a vote series from validated buckets. The same bucket and support may appear more than once.
+func newVoteSeries(buckets []voteSeriesBucket, bucketSize int64) ([]*VoteSeriesPoint, error) {
+	if len(buckets) == 0 {
+		return []*VoteSeriesPoint{}, nil
+	}
+
+	start, last := buckets[0].start, buckets[0].start
+	for _, bucket := range buckets {
+		start = min(start, bucket.start)
+		last = max(last, bucket.start)
+	}
+	n := (last-start)/bucketSize + 1
+	if n > MAX_VOTE_SERIES_BUCKETS {
+		return nil, fmt.Errorf("%d buckets of %ds: %w", n, bucketSize, ErrTooManyBuckets)
+	}
+	counts := make([][3]int, n)
+	amounts := make([][3]*big.Int, len(counts))
+	for i := range amounts {
+		amounts[i] = [3]*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	for _, bucket := range buckets {
+		i := (bucket.start - start) / bucketSize
+		counts[i][bucket.support] += bucket.count
+		amounts[i][bucket.support].Add(amounts[i][bucket.support], bucket.amount)
+	}
+
+	series := make([]*VoteSeriesPoint, len(counts))
+	var cumulativeCounts [3]int
+	cumulativeAmounts := [3]*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+	totals := func(i int, support VoteSupport) VoteSeriesTotals {
+		cumulativeCounts[support] += counts[i][support]
+		cumulativeAmounts[support].Add(cumulativeAmounts[support], amounts[i][support])
+		return VoteSeriesTotals{
+			Count:            counts[i][support],
+			Amount:           amounts[i][support].String(),
+			CumulativeCount:  cumulativeCounts[support],
+			CumulativeAmount: cumulativeAmounts[support].String(),
+		}
+	}
+	for i := range series {
+		series[i] = &VoteSeriesPoint{
+			Start:   start + int64(i)*bucketSize,
+			For:     totals(i, VOTE_SUPPORT_FOR),
+			Against: totals(i, VOTE_SUPPORT_AGAINST),
+			Abstain: totals(i, VOTE_SUPPORT_ABSTAIN),
+		}
+	}
+	return series, nil
+}
@@ -0,0 +1,124 @@
+package governor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewVoteSummary(t *testing.T) {
+	votes := []*Vote{
+		{TxHash: "tx1", Voter: "GA", Support: 1, Amount: "100", LedgerSeq: 10},
+		{TxHash: "tx2", Voter: "GB", Support: 1, Amount: "170141183460469231731687303715884105727", LedgerSeq: 11},
+		{TxHash: "tx3", Voter: "GC", Support: 0, Amount: "50", LedgerSeq: 12},
+		// GD changed their vote from for to abstain
+		{TxHash: "tx5", Voter: "GD", Support: 2, Amount: "30", LedgerSeq: 14},
+		{TxHash: "tx4", Voter: "GD", Support: 1, Amount: "20", LedgerSeq: 13},
+	}
+
+	got, err := NewVoteSummary(votes)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	want := &VoteSummary{
+		For:     VoteBucket{Voters: 2, Total: "170141183460469231731687303715884105827", Max: "170141183460469231731687303715884105727"},
+		Against: VoteBucket{Voters: 1, Total: "50", Max: "50"},
+		Abstain: VoteBucket{Voters: 1, Total: "30", Max: "30"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	empty, err := NewVoteSummary(nil)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	zero := VoteBucket{Voters: 0, Total: "0", Max: "0"}
+	if diff := cmp.Diff(&VoteSummary{For: zero, Against: zero, Abstain: zero}, empty); diff != "" {
+		t.Errorf("empty mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = NewVoteSummary([]*Vote{{TxHash: "tx1", Voter: "GA", Support: 1, Amount: "-1"}})
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidAmount)
+	}
+}
+
+func TestNewVoteSeries(t *testing.T) {
+	votes := []*Vote{
+		{TxHash: "tx3", Voter: "GC", Support: 0, Amount: "50", LedgerCloseTime: 10900},
+		{TxHash: "tx1", Voter: "GA", Support: 1, Amount: "100", LedgerCloseTime: 3700},
+		{TxHash: "tx2", Voter: "GB", Support: 1, Amount: "20", LedgerCloseTime: 3800},
+	}
+
+	got, err := NewVoteSeries(votes, 3600)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	none := VoteSeriesTotals{Amount: "0", CumulativeAmount: "0"}
+	want := []*VoteSeriesPoint{
+		{Start: 3600, For: VoteSeriesTotals{Count: 2, Amount: "120", CumulativeCount: 2, CumulativeAmount: "120"}, Against: none, Abstain: none},
+		{Start: 7200, For: VoteSeriesTotals{Amount: "0", CumulativeCount: 2, CumulativeAmount: "120"}, Against: none, Abstain: none},
+		{Start: 10800, For: VoteSeriesTotals{Amount: "0", CumulativeCount: 2, CumulativeAmount: "120"}, Against: VoteSeriesTotals{Count: 1, Amount: "50", CumulativeCount: 1, CumulativeAmount: "50"}, Abstain: none},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	empty, err := NewVoteSeries(nil, 3600)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("NewVoteSeries(nil) = (%v, %v), want no points", empty, err)
+	}
+
+	late := &Vote{TxHash: "tx4", Voter: "GD", Support: 2, Amount: "1", LedgerCloseTime: 3700 + MAX_VOTE_SERIES_BUCKETS*60}
+	_, err = NewVoteSeries(append(votes, late), 60)
+	if !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("error = %v, want %v", err, ErrTooManyBuckets)
+	}
+}
+
+func TestNewVoteSeriesFromBuckets(t *testing.T) {
+	votes := []*Vote{
+		{TxHash: "tx3", Voter: "GC", Support: 0, Amount: "50", LedgerCloseTime: 10900},
+		{TxHash: "tx1", Voter: "GA", Support: 1, Amount: "100", LedgerCloseTime: 3700},
+		{TxHash: "tx2", Voter: "GB", Support: 1, Amount: "20", LedgerCloseTime: 3800},
+	}
+	want, err := NewVoteSeries(votes, 3600)
+	if err != nil {
+		t.Fatalf("NewVoteSeries returned error: %v", err)
+	}
+
+	buckets := []*VoteSeriesBucket{
+		{Start: 10800, Support: 0, Count: 1, Amount: "50"},
+		{Start: 3600, Support: 1, Count: 2, Amount: "120"},
+	}
+	got, err := NewVoteSeriesFromBuckets(buckets, 3600)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// sums of many votes can exceed an i128
+	overflow := []*VoteSeriesBucket{{Start: 0, Support: 1, Count: 2, Amount: "340282366920938463463374607431768211454"}}
+	got, err = NewVoteSeriesFromBuckets(overflow, 3600)
+	if err != nil {
+		t.Fatalf("returned error: %v", err)
+	}
+	if got[0].For.Amount != overflow[0].Amount {
+		t.Errorf("amount = %s, want %s", got[0].For.Amount, overflow[0].Amount)
+	}
+
+	invalid := []*VoteSeriesBucket{
+		{Start: 3600, Support: 3, Count: 1, Amount: "1"},
+		{Start: 3601, Support: 1, Count: 1, Amount: "1"},
+		{Start: 3600, Support: 1, Count: 1, Amount: "1.5"},
+	}
+	for _, bucket := range invalid {
+		if _, err := NewVoteSeriesFromBuckets([]*VoteSeriesBucket{bucket}, 3600); err == nil {
+			t.Errorf("expected error for bucket %+v", bucket)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// BackfillEventXdr sets the event XDR of events indexed before it was stored, by re-reading ledgers from through to
+// (inclusive) from the configured ledger backend. If from is 0, the range of ledgers with events missing their XDR
+// is read from the store. Events that already have their XDR are not changed, so a backfill can be restarted.
+//
+// BackfillEventXdr returns the number of events updated.
+func BackfillEventXdr(ctx context.Context, store *db.Store, config *Config, from uint32, to uint32) (int, error) {
+	if from == 0 {
+		var err error
+		from, to, err = store.GetMissingEventXdrRange(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if from == 0 {
+			slog.Info("No events are missing their event XDR.")
+			return 0, nil
+		}
+	}
+	if to < from {
+		return 0, fmt.Errorf("invalid ledger range %d to %d", from, to)
+	}
+	networkPassphrase := networkPassphrase(config)
+
+	backend, err := newLedgerBackend(config, networkPassphrase)
+	if err != nil {
+		return 0, err
+	}
+	defer backend.Close()
+
+	slog.Info("Backfilling event XDR...", "from", from, "to", to)
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(from, to)); err != nil {
+		return 0, fmt.Errorf("failed to prepare ledger range: %w", err)
+	}
+
+	// the backfill isn't recorded for the API, as it doesn't move the indexer's last processed ledger
+	progress := newProgressTracker(nil, "", from, config.ProgressLogLedgers, func(context.Context) (uint32, error) { return to, nil })
+	var total LedgerStats
+	start := time.Now()
+	defer func() {
+		slog.Info("Event XDR backfill summary.", summaryAttrs(total, time.Since(start))...)
+	}()
+
+	updated := 0
+	for seq := from; seq <= to; seq++ {
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			return updated, fmt.Errorf("failed to get ledger %d: %w", seq, err)
+		}
+		count, err := backfillLedgerEventXdr(ctx, store, networkPassphrase, ledger)
+		updated += count
+		if err != nil {
+			return updated, err
+		}
+		slog.Debug("Ledger backfilled.", "ledger", seq, "updated", count)
+		// the events counted are the events updated
+		stats := LedgerStats{Ledgers: 1, GovernorEvents: count}
+		total.Add(stats)
+		progress.add(seq, stats)
+		progress.report(ctx)
+	}
+	return updated, nil
+}
+
+// backfillLedgerEventXdr sets the event XDR of the governor events in a ledger that are missing it, and returns the
+// number of events updated
+func backfillLedgerEventXdr(ctx context.Context, store Store, networkPassphrase string, ledger xdr.LedgerCloseMeta) (int, error) {
+	txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(networkPassphrase, ledger)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction reader for ledger %d: %w", ledger.LedgerSequence(), err)
+	}
+	defer txReader.Close()
+
+	updated := 0
+	var stats LedgerStats
+	for {
+		tx, err := txReader.Read()
+		if err == io.EOF {
+			return updated, nil
+		} else if err != nil {
+			return updated, fmt.Errorf("failed to read ledger transaction in ledger %d: %w", ledger.LedgerSequence(), err)
+		}
+
+		govEvents, _ := ParseTransaction(tx, ledger.LedgerSequence(), ledger.LedgerCloseTime(), &stats)
+		for _, govEvent := range govEvents {
+			if govEvent.EventXdr == "" {
+				continue
+			}
+			ok, err := store.SetEventXdr(ctx, govEvent.EventId, govEvent.EventXdr)
+			if err != nil {
+				return updated, err
+			}
+			if ok {
+				updated++
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// applyBatch applies the governor events of a ledger, in order, with the same result as applying each with
+// ApplyEvent. Delegation events are applied one at a time. The events of each proposal are applied together: the
+// proposal is read once, the events are applied to it in memory, and the proposal and its new votes are written once,
+// in a single transaction. If that transaction fails, the proposal's events are applied one at a time instead, each in
+// a savepoint of a single transaction, so only the events that fail to apply are rolled back. See applyEventsIsolated.
+//
+// The order of every event is checked, in ledger order, before any is applied, since applying a proposal's events
+// advances the last event of the contract past the events of the proposals applied after it.
+//
+// Only store errors, so the ledger is retried, and ErrEventOutOfOrder are returned; errors caused by the events are
+// logged. The effects of the applied events are added to stats.
+func (idx *Indexer) applyBatch(ctx context.Context, events []*governor.GovernorEvent, stats *LedgerStats) error {
+	for _, event := range events {
+		if err := idx.checkLedgerEventOrder(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	var proposalKeys []string
+	byProposal := make(map[string][]*governor.GovernorEvent)
+	for _, event := range events {
+		if governor.IsDelegationEventType(event.EventType) {
+			if err := idx.applyLedgerEvent(ctx, event, stats); err != nil {
+				return err
+			}
+			continue
+		}
+		proposalKey := governor.EncodeProposalKey(event.ContractId, event.ProposalId)
+		if _, ok := byProposal[proposalKey]; !ok {
+			proposalKeys = append(proposalKeys, proposalKey)
+		}
+		byProposal[proposalKey] = append(byProposal[proposalKey], event)
+	}
+
+	for _, proposalKey := range proposalKeys {
+		if err := idx.applyProposalEvents(ctx, proposalKey, byProposal[proposalKey], stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyProposalEvents applies the events of a proposal in a ledger together, as described by applyBatch
+func (idx *Indexer) applyProposalEvents(ctx context.Context, proposalKey string, events []*governor.GovernorEvent, stats *LedgerStats) error {
+	slog.Info("Applying proposal events", "ledger", events[0].LedgerSeq, "proposal", proposalKey, "events", len(events))
+	// store the events into the event history, even if applying them fails, as ApplyEvent does
+	recorded, err := idx.insertEvents(ctx, events, stats)
+	if err != nil {
+		return err
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	var effects []eventEffects
+	err = idx.withConflictRetry(ctx, recorded[0].EventId, func(ctx context.Context) error {
+		var err error
+		effects, err = idx.applyEventsToProposal(ctx, proposalKey, recorded)
+		return err
+	})
+	if errors.Is(err, db.ErrTimeout) {
+		return fmt.Errorf("failed applying events to proposal %s: %w", proposalKey, err)
+	} else if err != nil {
+		slog.Warn("Failed applying proposal events together, applying them one at a time", "ledger", recorded[0].LedgerSeq, "proposal", proposalKey, "err", err)
+		return idx.applyEventsIsolated(ctx, proposalKey, recorded, stats)
+	}
+
+	for _, e := range effects {
+		stats.addEffects(e)
+	}
+	return nil
+}
+
+// applyEventsIsolated applies events one at a time in a single transaction, each in its own savepoint. An event that
+// fails to apply is rolled back to its savepoint and recorded as a failed event, for ReprocessFailedEvents, and the
+// rest of the events are still applied, so one bad event doesn't undo the others. proposal_created events that
+// conflict with the proposal are recorded as failed events, as ApplyLedger does.
+//
+// If the transaction itself fails, the events are applied one at a time in their own transactions with
+// applyLedgerEvent instead. Only database timeouts and the store errors of applyLedgerEvent are returned.
+func (idx *Indexer) applyEventsIsolated(ctx context.Context, proposalKey string, events []*governor.GovernorEvent, stats *LedgerStats) error {
+	// the stats and failures of the last attempt at the transaction, only kept once it commits
+	var txStats LedgerStats
+	var failures []eventFailure
+	err := idx.withConflictRetry(ctx, events[0].EventId, func(ctx context.Context) error {
+		txStats, failures = LedgerStats{}, nil
+		for _, event := range events {
+			var effects eventEffects
+			err := idx.store.WithSavepoint(ctx, func(ctx context.Context) error {
+				var err error
+				effects, err = idx.applyEventActivity(ctx, event)
+				return err
+			})
+			// the whole transaction is retried, or fails over to applyLedgerEvent on other store errors
+			if errors.Is(err, db.ErrTimeout) || errors.Is(err, db.ErrConflict) {
+				return err
+			} else if err != nil && !isEventError(err) && !errors.Is(err, governor.ErrProposalConflict) {
+				return fmt.Errorf("failed applying event %s: %w", event.EventId, err)
+			}
+
+			reason := governor.FAILED_REASON_APPLY_FAILED
+			if errors.Is(err, governor.ErrProposalConflict) {
+				idx.warnProposalConflict(event, err)
+				txStats.ProposalConflicts++
+				reason = governor.FAILED_REASON_PROPOSAL_CONFLICT
+			} else if err != nil {
+				err = fmt.Errorf("failed applying event %s: %w", event.EventId, err)
+				slog.Error("Failed applying event to db, rolled back to its savepoint", "ledger", event.LedgerSeq, "hash", event.TxHash, "event", event, "err", err)
+				failures = append(failures, eventFailure{event: event, err: err})
+			} else {
+				txStats.addEffects(effects)
+				continue
+			}
+
+			txStats.FailedEvents++
+			failedEvent := governor.NewFailedEventFromGovernorEvent(event, reason, err)
+			err = idx.store.WithSavepoint(ctx, func(ctx context.Context) error {
+				return idx.store.InsertFailedEvent(ctx, failedEvent)
+			})
+			if err != nil {
+				return fmt.Errorf("failed recording failed event %s: %w", event.EventId, err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, db.ErrTimeout) {
+		return fmt.Errorf("failed applying events to proposal %s: %w", proposalKey, err)
+	} else if err != nil {
+		slog.Warn("Failed applying proposal events in savepoints, applying them in their own transactions", "ledger", events[0].LedgerSeq, "proposal", proposalKey, "err", err)
+		for _, event := range events {
+			if err := idx.applyLedgerEvent(ctx, event, stats); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	stats.Add(txStats)
+	// recorded once the transaction commits, as the error log is best-effort and a failed write would abort it
+	for _, failure := range failures {
+		idx.recordError(ctx, failure.event.LedgerSeq, failure.event.TxHash, failure.err)
+	}
+	return nil
+}
+
+// eventFailure is an event that failed to apply, with its error
+type eventFailure struct {
+	event *governor.GovernorEvent
+	err   error
+}
+
+// insertEvents stores events into the event history with a multi-row insert, and returns the events stored. If the
+// insert fails, the events are inserted one at a time instead. Events with invalid event data are recorded as failed
+// events. Store errors are returned, so the ledger is retried, as ApplyLedger does.
+func (idx *Indexer) insertEvents(ctx context.Context, events []*governor.GovernorEvent, stats *LedgerStats) ([]*governor.GovernorEvent, error) {
+	err := idx.store.InsertEvents(ctx, events)
+	if errors.Is(err, db.ErrTimeout) {
+		return nil, fmt.Errorf("failed inserting %d events: %w", len(events), err)
+	} else if err == nil {
+		return events, nil
+	}
+	slog.Warn("Failed inserting events together, inserting them one at a time", "ledger", events[0].LedgerSeq, "events", len(events), "err", err)
+
+	recorded := make([]*governor.GovernorEvent, 0, len(events))
+	for _, event := range events {
+		err := idx.store.InsertEvent(ctx, event)
+		if errors.Is(err, governor.ErrInvalidEventData) {
+			slog.Warn("Governor event has invalid event data", "ledger", event.LedgerSeq, "hash", event.TxHash, "eventId", event.EventId, "type", event.EventType, "err", err)
+			if err := idx.insertRejectedEvent(ctx, event, governor.FAILED_REASON_INVALID_EVENT_DATA, err, stats); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed applying event %s: failed to insert event into history: %w", event.EventId, err)
+		}
+		recorded = append(recorded, event)
+	}
+	return recorded, nil
+}
+
+// applyEventsToProposal applies the events of a proposal to the aggregated tables, reading and writing the proposal
+// once, and returns the changes made by each event applied without error. Events that fail to apply are logged and
+// skipped, as ApplyLedger does, and proposal_created events that conflict with the proposal are recorded as failed
+// events.
+func (idx *Indexer) applyEventsToProposal(ctx context.Context, proposalKey string, events []*governor.GovernorEvent) ([]eventEffects, error) {
+	proposal, version, err := idx.store.GetProposalVersion(ctx, proposalKey)
+	exists := true
+	if errors.Is(err, db.ErrNotFound) {
+		proposal = &governor.Proposal{}
+		exists = false
+	} else if err != nil {
+		return nil, fmt.Errorf("error when attempting to get proposal from store: %w", err)
+	}
+
+	// votes already inserted, by an earlier attempt at the ledger or an earlier event in the ledger
+	var txHashes []string
+	for _, event := range events {
+		if event.EventType == "vote_cast" {
+			txHashes = append(txHashes, event.TxHash)
+		}
+	}
+	inserted := make(map[string]bool)
+	if len(txHashes) > 0 {
+		existing, err := idx.store.GetVotesByTxHashes(ctx, txHashes)
+		if err != nil {
+			return nil, fmt.Errorf("error when attempting to get votes from store: %w", err)
+		}
+		for _, vote := range existing {
+			inserted[vote.TxHash] = true
+		}
+	}
+
+	var effects []eventEffects
+	var votes []*governor.Vote
+	var lastApplied *governor.GovernorEvent
+	mutated := false
+	closed := false
+	for _, event := range events {
+		// apply to a copy, so an event that fails or is already applied leaves the proposal unchanged
+		next := *proposal
+		vote, changed, err := governor.ApplyEventToProposal(&next, event)
+		if errors.Is(err, governor.ErrProposalConflict) {
+			// recorded in the same transaction, so a retried attempt records it once
+			idx.warnProposalConflict(event, err)
+			if err := idx.insertFailedEvent(ctx, governor.NewFailedEventFromGovernorEvent(event, governor.FAILED_REASON_PROPOSAL_CONFLICT, err)); err != nil {
+				return nil, err
+			}
+			effects = append(effects, eventEffects{proposalConflict: true})
+			continue
+		} else if err != nil {
+			slog.Error("Failed applying event to db", "ledger", event.LedgerSeq, "hash", event.TxHash, "event", event, "err", err)
+			idx.recordError(ctx, event.LedgerSeq, event.TxHash, fmt.Errorf("failed applying event %s: %w", event.EventId, err))
+			continue
+		}
+		lastApplied = event
+		if !changed {
+			slog.Info(event.EventType+" event does not apply to proposal status", "ledger", event.LedgerSeq, "hash", event.TxHash, "proposal", proposalKey, "current_status", proposal.Status)
+			effects = append(effects, eventEffects{})
+			continue
+		}
+		if vote != nil {
+			if inserted[vote.TxHash] {
+				slog.Info("vote_cast event already applied", "ledger", event.LedgerSeq, "hash", event.TxHash, "proposal", proposalKey, "current_status", proposal.Status)
+				effects = append(effects, eventEffects{})
+				continue
+			}
+			inserted[vote.TxHash] = true
+			votes = append(votes, vote)
+		}
+		next.UpdatedAt = idx.now().Unix()
+		*proposal = next
+		mutated = true
+		closed = closed || event.EventType == "proposal_voting_closed"
+		effects = append(effects, eventEffects{proposalMutated: true, voteInserted: vote != nil})
+	}
+
+	if len(votes) > 0 {
+		if err := idx.store.InsertVotes(ctx, votes); err != nil {
+			return nil, fmt.Errorf("failed to insert votes into store: %w", err)
+		}
+	}
+	// voters are counted once the votes of the ledger are inserted
+	if closed {
+		if err := idx.flagLowParticipation(ctx, proposal); err != nil {
+			return nil, err
+		}
+	}
+	if mutated && !exists {
+		if err := idx.store.InsertProposal(ctx, proposal); err != nil {
+			return nil, fmt.Errorf("failed to insert new proposal into store: %w", err)
+		}
+		idx.tracked.add(proposal.ContractId)
+	} else if mutated {
+		if err := idx.store.UpdateProposal(ctx, proposal, version); err != nil {
+			return nil, fmt.Errorf("failed to update proposal in store: %w", err)
+		}
+	}
+	if lastApplied != nil {
+		if err := idx.store.UpsertContractActivity(ctx, lastApplied.ContractId, lastApplied.EventId, lastApplied.LedgerSeq, lastApplied.LedgerCloseTime, !idx.contractDiscovery); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("Proposal events applied successfully", "ledger", events[0].LedgerSeq, "proposal", proposalKey, "applied", len(effects), "votes", len(votes))
+	return effects, nil
+}
@@ -0,0 +1,380 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// indexedState is everything indexed into a store, compared across application modes
+type indexedState struct {
+	Events    []*governor.GovernorEvent
+	Contracts []*db.Contract
+	Proposals [][]*governor.Proposal
+	Votes     [][][]*governor.Vote
+}
+
+// getIndexedState reads the event history, contracts registry, and the proposals and votes of every contract
+func getIndexedState(t *testing.T, store *db.Store) indexedState {
+	t.Helper()
+	var state indexedState
+	var err error
+	state.Events, err = store.GetEventsAfter(t.Context(), "", "", 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Contracts, err = store.GetContracts(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, contract := range state.Contracts {
+		proposals, votes := contractState(t, store, contract.ContractId)
+		state.Proposals = append(state.Proposals, proposals)
+		state.Votes = append(state.Votes, votes)
+	}
+	return state
+}
+
+// applyFixtureLedger applies a generated ledger and returns its stats
+func applyFixtureLedger(t testing.TB, idx *Indexer, ledger xdr.LedgerCloseMeta) LedgerStats {
+	t.Helper()
+	txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, ledger)
+	if err != nil {
+		t.Fatalf("failed to create transaction reader for ledger %d: %v", ledger.LedgerSequence(), err)
+	}
+	stats, err := idx.ApplyLedger(t.Context(), txReader, ledger.LedgerSequence(), ledger.LedgerCloseTime())
+	if err != nil {
+		t.Fatalf("ApplyLedger(%d) error = %v", ledger.LedgerSequence(), err)
+	}
+	return stats
+}
+
+// newVoteCastEvent creates a vote_cast event on proposalId from the voter numbered voter
+func newVoteCastEvent(t testing.TB, proposalId uint32, voter int, support uint32, amount uint64) xdr.ContractEvent {
+	t.Helper()
+	event := withProposalId(t, mustDecodeEvent(t, voteCastXdr), proposalId)
+	var key xdr.Uint256
+	key[0], key[1] = byte(voter>>8), byte(voter)
+	accountId := xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &key}
+	address := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}
+	event.Body.V0.Topics[2] = xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &address}
+
+	supportVal := xdr.Uint32(support)
+	amountVal := xdr.Int128Parts{Lo: xdr.Uint64(amount)}
+	data := xdr.ScVec{
+		{Type: xdr.ScValTypeScvU32, U32: &supportVal},
+		{Type: xdr.ScValTypeScvI128, I128: &amountVal},
+	}
+	dataPtr := &data
+	event.Body.V0.Data = xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &dataPtr}
+	return event
+}
+
+// newVoteLedgers builds a ledger creating proposals 1 and 2, followed by a ledger with votes on proposal 1 from
+// voters, a vote on a proposal that does not exist, and a vote on proposal 2 after it is canceled
+func newVoteLedgers(t testing.TB, voters int) []xdr.LedgerCloseMeta {
+	t.Helper()
+	var votes []fixtureTx
+	for voter := range voters {
+		votes = append(votes, fixtureTx{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, voter, uint32(voter%3), uint64(voter+1)*10000000)}})
+	}
+	votes = append(votes,
+		// both votes share the transaction hash, so the second is already applied
+		fixtureTx{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, voters, 1, 50000000), newVoteCastEvent(t, 1, voters+1, 0, 70000000)}},
+		fixtureTx{events: []xdr.ContractEvent{newVoteCastEvent(t, 9, 0, 1, 10000000)}},
+		fixtureTx{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalCanceledXdr), 2)}},
+		fixtureTx{events: []xdr.ContractEvent{newVoteCastEvent(t, 2, 0, 1, 10000000)}},
+		fixtureTx{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, voters+2, 2, 90000000)}, failed: true},
+	)
+	return []xdr.LedgerCloseMeta{
+		newFixtureLedger(t, 1170134, 1761052641, []fixtureTx{
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1)}},
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 2)}},
+		}),
+		newFixtureLedger(t, 1170136, 1761052651, votes),
+	}
+}
+
+// TestApplyLedgerBatched verifies applying the events of each proposal in a ledger together indexes the same
+// state, with the same stats, as applying them one at a time
+func TestApplyLedgerBatched(t *testing.T) {
+	now := time.Unix(1761053041, 0)
+	newIndexers := func(t *testing.T) (*db.Store, *Indexer, *db.Store, *Indexer) {
+		wantStore, gotStore := newFixtureStore(t), newFixtureStore(t)
+		want, got := NewIndexer(wantStore), NewIndexer(gotStore)
+		want.now = func() time.Time { return now }
+		got.now = func() time.Time { return now }
+		got.batched = true
+		return wantStore, want, gotStore, got
+	}
+	check := func(t *testing.T, wantStore *db.Store, wantStats LedgerStats, gotStore *db.Store, gotStats LedgerStats) {
+		t.Helper()
+		if diff := cmp.Diff(wantStats, gotStats); diff != "" {
+			t.Errorf("stats mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(getIndexedState(t, wantStore), getIndexedState(t, gotStore)); diff != "" {
+			t.Errorf("indexed state mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	for _, dir := range []string{FIXTURE_DIR, FEE_BUMP_FIXTURE_DIR, SCHEMA_FIXTURE_DIR, DELEGATION_FIXTURE_DIR, UNKNOWN_TYPE_FIXTURE_DIR} {
+		t.Run(dir, func(t *testing.T) {
+			wantStore, want, gotStore, got := newIndexers(t)
+			wantStats := replayFixtures(t, want, dir)
+			gotStats := replayFixtures(t, got, dir)
+			check(t, wantStore, wantStats, gotStore, gotStats)
+		})
+	}
+
+	t.Run("votes", func(t *testing.T) {
+		wantStore, want, gotStore, got := newIndexers(t)
+		ledgers := newVoteLedgers(t, 300)
+		// the vote ledger is applied twice, as when a ledger is retried
+		ledgers = append(ledgers, ledgers[1])
+		var wantStats, gotStats LedgerStats
+		for _, ledger := range ledgers {
+			wantStats.Add(applyFixtureLedger(t, want, ledger))
+			gotStats.Add(applyFixtureLedger(t, got, ledger))
+		}
+		check(t, wantStore, wantStats, gotStore, gotStats)
+
+		proposal, err := gotStore.GetProposal(t.Context(), governor.EncodeProposalKey(testContractId, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes, err := gotStore.GetVotesByProposal(t.Context(), testContractId, 1, db.Sort{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(votes) != 301 || proposal.UpdatedAt != now.Unix() {
+			t.Errorf("got %d votes on a proposal updated at %d, want 301 updated at %d", len(votes), proposal.UpdatedAt, now.Unix())
+		}
+	})
+}
+
+// TestApplyLedgerBatchedInterleaved verifies a ledger with the events of proposals interleaved is not out of order
+// when the events of each proposal are applied together
+func TestApplyLedgerBatchedInterleaved(t *testing.T) {
+	store := newFixtureStore(t)
+	idx := NewIndexer(store)
+	idx.batched = true
+	idx.strictEventOrder = true
+	ledgers := newVoteLedgers(t, 0)
+	applyFixtureLedger(t, idx, ledgers[0])
+
+	ledger := newFixtureLedger(t, 1170136, 1761052651, []fixtureTx{
+		{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, 0, 1, 10000000)}},
+		{events: []xdr.ContractEvent{newVoteCastEvent(t, 2, 0, 1, 20000000)}},
+		{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, 1, 0, 30000000)}},
+	})
+	// applied twice, as when a ledger is retried
+	for range 2 {
+		applyFixtureLedger(t, idx, ledger)
+	}
+	for proposalId, want := range map[uint32]int{1: 2, 2: 1} {
+		votes, err := store.GetVotesByProposal(t.Context(), testContractId, proposalId, db.Sort{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(votes) != want {
+			t.Errorf("got %d votes on proposal %d, want %d", len(votes), proposalId, want)
+		}
+	}
+}
+
+// BenchmarkApplyLedgerVotes measures applying a ledger with 500 votes on a proposal
+func BenchmarkApplyLedgerVotes(b *testing.B) {
+	ledgers := newVoteLedgers(b, 500)
+	for _, batched := range []bool{false, true} {
+		name := "one at a time"
+		if batched {
+			name = "batched"
+		}
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				idx := NewIndexer(newFixtureStore(b))
+				idx.batched = batched
+				applyFixtureLedger(b, idx, ledgers[0])
+				b.StartTimer()
+				applyFixtureLedger(b, idx, ledgers[1])
+			}
+		})
+	}
+}
+
+// TestInsertEventsFallback verifies events are inserted one at a time if they can't be inserted together, and a store
+// failure inserting an event fails the ledger rather than skipping the event
+func TestInsertEventsFallback(t *testing.T) {
+	events := []*governor.GovernorEvent{
+		{EventId: "0005025695851876452-0000000000", LedgerSeq: ledgerSeq},
+		{EventId: "0005025695851876452-0000000001", LedgerSeq: ledgerSeq},
+		{EventId: "0005025695851876452-0000000002", LedgerSeq: ledgerSeq},
+	}
+	storeErr := errors.New("disk I/O error")
+	var failing *governor.GovernorEvent
+	store := &mockStore{
+		insertEvents: func(ctx context.Context, events []*governor.GovernorEvent) error { return errors.New("invalid event") },
+		insertEvent: func(ctx context.Context, event *governor.GovernorEvent) error {
+			if event == failing {
+				return storeErr
+			}
+			return nil
+		},
+	}
+
+	recorded, err := NewIndexer(store).insertEvents(t.Context(), events, &LedgerStats{})
+	if err != nil {
+		t.Fatalf("insertEvents() error = %v", err)
+	}
+	if diff := cmp.Diff(events, recorded); diff != "" {
+		t.Errorf("recorded events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"InsertEvents", "InsertEvent", "InsertEvent", "InsertEvent"}, store.calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// the events after the one that fails are not inserted
+	store.calls, failing = nil, events[1]
+	if _, err := NewIndexer(store).insertEvents(t.Context(), events, &LedgerStats{}); !errors.Is(err, storeErr) {
+		t.Errorf("insertEvents() error = %v, want %v", err, storeErr)
+	}
+	if diff := cmp.Diff([]string{"InsertEvents", "InsertEvent", "InsertEvent"}, store.calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+
+	store.insertEvents = func(ctx context.Context, events []*governor.GovernorEvent) error { return db.ErrTimeout }
+	if _, err := NewIndexer(store).insertEvents(t.Context(), events, &LedgerStats{}); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("insertEvents() error = %v, want ErrTimeout", err)
+	}
+}
+
+// TestInsertInvalidEventData verifies events with invalid event data are recorded as failed events, both when
+// inserted one at a time and when inserted together
+func TestInsertInvalidEventData(t *testing.T) {
+	invalid := &governor.GovernorEvent{EventId: "0005025695851876452-0000000001", ContractId: testContractId, EventType: "vote_cast", TxHash: "hash", LedgerSeq: ledgerSeq}
+	valid := &governor.GovernorEvent{EventId: "0005025695851876452-0000000000", LedgerSeq: ledgerSeq}
+	invalidErr := fmt.Errorf("insert event %s: %w", invalid.EventId, governor.ErrInvalidEventData)
+	var failed []*governor.FailedEvent
+	store := &mockStore{
+		insertEvents: func(ctx context.Context, events []*governor.GovernorEvent) error { return invalidErr },
+		insertEvent: func(ctx context.Context, event *governor.GovernorEvent) error {
+			if event == invalid {
+				return invalidErr
+			}
+			return nil
+		},
+		insertFailedEvent: func(ctx context.Context, event *governor.FailedEvent) error {
+			failed = append(failed, event)
+			return nil
+		},
+	}
+	idx := NewIndexer(store)
+
+	var stats LedgerStats
+	recorded, err := idx.insertEvents(t.Context(), []*governor.GovernorEvent{valid, invalid}, &stats)
+	if err != nil {
+		t.Fatalf("insertEvents() error = %v", err)
+	}
+	if diff := cmp.Diff([]*governor.GovernorEvent{valid}, recorded); diff != "" {
+		t.Errorf("recorded events mismatch (-want +got):\n%s", diff)
+	}
+	if err := idx.applyLedgerEvent(t.Context(), invalid, &stats); err != nil {
+		t.Fatalf("applyLedgerEvent() error = %v", err)
+	}
+
+	wantFailed := &governor.FailedEvent{
+		EventId:    invalid.EventId,
+		ContractId: testContractId,
+		EventType:  "vote_cast",
+		Reason:     governor.FAILED_REASON_INVALID_EVENT_DATA,
+		Error:      invalidErr.Error(),
+		TxHash:     "hash",
+		LedgerSeq:  ledgerSeq,
+	}
+	wantApplied := *wantFailed
+	wantApplied.Error = "failed to insert event into history: " + invalidErr.Error()
+	if diff := cmp.Diff([]*governor.FailedEvent{wantFailed, &wantApplied}, failed); diff != "" {
+		t.Errorf("failed events mismatch (-want +got):\n%s", diff)
+	}
+	if stats.FailedEvents != 2 {
+		t.Errorf("FailedEvents = %d, want 2", stats.FailedEvents)
+	}
+}
+
+// poisonStore fails the multi-row vote insert of applying proposal events together, and rejects the tally of the
+// proposal written by the poison-th event applied after, once its vote is written, as the store does for a tally
+// made invalid by an event
+type poisonStore struct {
+	Store
+	poison     int
+	applied    int
+	poisonedId string
+}
+
+func (s *poisonStore) InsertVotes(ctx context.Context, votes []*governor.Vote) error {
+	return errors.New("failed to insert votes")
+}
+
+func (s *poisonStore) UpdateProposal(ctx context.Context, proposal *governor.Proposal, version int64) error {
+	s.applied++
+	if s.applied == s.poison {
+		s.poisonedId = proposal.UpdatedEventId
+		return fmt.Errorf("poison event: %w", governor.ErrInvalidAmount)
+	}
+	return s.Store.UpdateProposal(ctx, proposal, version)
+}
+
+// TestApplyBatchSavepoints verifies an event that fails to apply in a batch is rolled back to its savepoint and
+// recorded as a failed event, while the rest of the batch is applied in the same transaction
+func TestApplyBatchSavepoints(t *testing.T) {
+	ctx := t.Context()
+	ledgers := newVoteLedgers(t, 50)
+	store := newFixtureStore(t)
+	applyFixtureLedger(t, NewIndexer(store), ledgers[0])
+
+	poison := &poisonStore{Store: store, poison: 10}
+	idx := NewIndexer(poison)
+	idx.batched = true
+	stats := applyFixtureLedger(t, idx, ledgers[1])
+	if poison.poisonedId == "" {
+		t.Fatal("expected an event to be poisoned")
+	}
+
+	// exactly one of the 51 votes on proposal 1 is missing
+	votes, err := store.GetVotesByProposal(ctx, testContractId, 1, db.Sort{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 50 {
+		t.Errorf("got %d votes, want 50", len(votes))
+	}
+	poisoned, err := store.GetEvent(ctx, poison.poisonedId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the vote was written before the event failed, and rolled back with it
+	if _, err := store.GetVote(ctx, poisoned.TxHash); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the poisoned event's vote to be rolled back, got %v", err)
+	}
+
+	failed, err := store.GetFailedEvents(ctx, db.FailedEventFilter{}, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].EventId != poison.poisonedId || failed[0].Reason != governor.FAILED_REASON_APPLY_FAILED {
+		t.Errorf("got failed events %+v, want the poisoned event %s", failed, poison.poisonedId)
+	}
+	if stats.FailedEvents != 1 {
+		t.Errorf("FailedEvents = %d, want 1", stats.FailedEvents)
+	}
+}
@@ -0,0 +1,100 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// DEFAULT_BLOCKLIST_REFRESH_INTERVAL is how often a Blocklist is reloaded if no interval is configured
+const DEFAULT_BLOCKLIST_REFRESH_INTERVAL = 30 * time.Second
+
+// BlocklistStore is the subset of db.Store used to load the blocklist
+type BlocklistStore interface {
+	GetBlockedContracts(ctx context.Context) ([]*db.BlockedContract, error)
+}
+
+// Blocklist is an in-memory copy of the contract blocklist, so contracts can be checked without a database read. It
+// is reloaded by Refresh at most once every interval, so contracts blocked by another process are picked up within
+// the interval. A Blocklist is safe for concurrent use.
+type Blocklist struct {
+	store    BlocklistStore
+	interval time.Duration
+	now      func() time.Time
+
+	mu sync.RWMutex
+	// contracts are the raw ids of the blocked contracts, so contract events can be checked without encoding their id
+	contracts   map[xdr.ContractId]bool
+	refreshedAt time.Time
+}
+
+// NewBlocklist creates a Blocklist loaded from store, reloaded at most once every interval. It is empty until the
+// first Refresh.
+func NewBlocklist(store BlocklistStore, interval time.Duration) *Blocklist {
+	return &Blocklist{store: store, interval: interval, now: time.Now}
+}
+
+// Refresh reloads the blocklist if it was last loaded more than interval ago. If reloading fails, the previous
+// blocklist is kept and the error is returned.
+func (b *Blocklist) Refresh(ctx context.Context) error {
+	b.mu.RLock()
+	stale := b.contracts == nil || !b.now().Before(b.refreshedAt.Add(b.interval))
+	b.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	blocked, err := b.store.GetBlockedContracts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load contract blocklist: %w", err)
+	}
+	contracts := make(map[xdr.ContractId]bool, len(blocked))
+	for _, contract := range blocked {
+		raw, err := strkey.Decode(strkey.VersionByteContract, contract.ContractId)
+		if err != nil {
+			// only contract ids can match contract events
+			slog.Warn("Ignoring invalid contract id on the blocklist", "contract", contract.ContractId, "err", err)
+			continue
+		}
+		contracts[xdr.ContractId(raw)] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.contracts = contracts
+	b.refreshedAt = b.now()
+	return nil
+}
+
+// Invalidate reloads the blocklist on the next Refresh, so a change made by this process is applied immediately
+func (b *Blocklist) Invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshedAt = time.Time{}
+}
+
+// Contains returns true if the contract, as a StrKey address, is blocked
+func (b *Blocklist) Contains(contractId string) bool {
+	raw, err := strkey.Decode(strkey.VersionByteContract, contractId)
+	if err != nil {
+		return false
+	}
+	return b.containsId(xdr.ContractId(raw))
+}
+
+// containsEvent returns true if the contract event was emitted by a blocked contract
+func (b *Blocklist) containsEvent(event *xdr.ContractEvent) bool {
+	return event.ContractId != nil && b.containsId(*event.ContractId)
+}
+
+func (b *Blocklist) containsId(contractId xdr.ContractId) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.contracts[contractId]
+}
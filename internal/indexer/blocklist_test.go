@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestBlocklist(t *testing.T) {
+	ctx := t.Context()
+	blocked := []*db.BlockedContract{{ContractId: testContractId, Reason: "spam"}, {ContractId: "not-a-contract"}}
+	var loadErr error
+	loads := 0
+	store := &mockStore{
+		getBlockedContracts: func(ctx context.Context) ([]*db.BlockedContract, error) {
+			loads++
+			return blocked, loadErr
+		},
+	}
+	now := testNow
+	b := NewBlocklist(store, time.Minute)
+	b.now = func() time.Time { return now }
+
+	if b.Contains(testContractId) {
+		t.Errorf("Contains() = true before the first refresh")
+	}
+	if err := b.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if !b.Contains(testContractId) {
+		t.Errorf("Contains(%s) = false, want true", testContractId)
+	}
+	if b.Contains("not-a-contract") {
+		t.Errorf("Contains() = true for an invalid contract id")
+	}
+	event := mustDecodeEvent(t, proposalCreatedXdr)
+	if !b.containsEvent(&event) {
+		t.Errorf("containsEvent() = false for an event from %s", testContractId)
+	}
+	event.ContractId = &xdr.ContractId{0xb0}
+	if b.containsEvent(&event) {
+		t.Errorf("containsEvent() = true for an event from a contract that is not blocked")
+	}
+
+	// the blocklist is only reloaded once the interval has passed, or once it is invalidated
+	blocked = nil
+	now = now.Add(59 * time.Second)
+	if err := b.Refresh(ctx); err != nil || loads != 1 || !b.Contains(testContractId) {
+		t.Errorf("Refresh() within the interval reloaded the blocklist, loads = %d, error = %v", loads, err)
+	}
+	b.Invalidate()
+	if err := b.Refresh(ctx); err != nil || loads != 2 || b.Contains(testContractId) {
+		t.Errorf("Refresh() after Invalidate() did not reload the blocklist, loads = %d, error = %v", loads, err)
+	}
+
+	// a failed reload keeps the previous blocklist, and is retried on the next refresh
+	blocked = []*db.BlockedContract{{ContractId: testContractId}}
+	loadErr = db.ErrTimeout
+	now = now.Add(time.Minute)
+	if err := b.Refresh(ctx); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("Refresh() error = %v, want ErrTimeout", err)
+	}
+	if b.Contains(testContractId) {
+		t.Errorf("Contains() = true after a failed reload")
+	}
+	loadErr = nil
+	if err := b.Refresh(ctx); err != nil || loads != 4 || !b.Contains(testContractId) {
+		t.Errorf("Refresh() after a failed reload did not reload the blocklist, loads = %d, error = %v", loads, err)
+	}
+}
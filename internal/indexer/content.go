@@ -0,0 +1,166 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+const (
+	// CONTENT_POLL_INTERVAL is how often the content fetcher checks for proposal content to fetch
+	CONTENT_POLL_INTERVAL = 10 * time.Second
+	// CONTENT_BATCH_SIZE is the maximum number of proposals fetched per poll
+	CONTENT_BATCH_SIZE = 10
+	// CONTENT_RETRY_BASE_DELAY is the delay before retrying a failed fetch, doubled after each failed attempt
+	CONTENT_RETRY_BASE_DELAY = time.Minute
+	// CONTENT_RETRY_MAX_DELAY is the maximum delay between fetch attempts
+	CONTENT_RETRY_MAX_DELAY = 6 * time.Hour
+	// CONTENT_MAX_ATTEMPTS is the number of failed fetch attempts before the content is marked as failed
+	CONTENT_MAX_ATTEMPTS = 10
+)
+
+var (
+	// errContentTooLarge is returned when fetched content is larger than the configured maximum
+	errContentTooLarge = errors.New("content too large")
+	// errContentNotText is returned when fetched content is not valid UTF-8 text
+	errContentNotText = errors.New("content is not text")
+)
+
+// ContentFetcher fetches the content of proposals whose description is an IPFS URI from an IPFS HTTP gateway, and
+// stores it in the proposal content table. It runs separately from ledger ingestion, so a slow or unavailable
+// gateway never delays applying events.
+type ContentFetcher struct {
+	store      Store
+	client     *http.Client
+	gatewayUrl string
+	maxBytes   int
+}
+
+// NewContentFetcher creates a ContentFetcher using the IPFS settings of the config
+func NewContentFetcher(store Store, config *Config) *ContentFetcher {
+	return &ContentFetcher{
+		store:      store,
+		client:     &http.Client{Timeout: time.Duration(config.IpfsFetchTimeout) * time.Second},
+		gatewayUrl: strings.TrimRight(config.IpfsGatewayUrl, "/"),
+		maxBytes:   config.IpfsMaxContentBytes,
+	}
+}
+
+// Run fetches due proposal content every CONTENT_POLL_INTERVAL until ctx is cancelled
+func (f *ContentFetcher) Run(ctx context.Context) {
+	slog.Info("Proposal content fetcher started", "gateway", f.gatewayUrl)
+	for {
+		if _, err := f.FetchDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to fetch proposal content", "err", err)
+		}
+		if !sleepCtx(ctx, CONTENT_POLL_INTERVAL) {
+			return
+		}
+	}
+}
+
+// FetchDue fetches up to CONTENT_BATCH_SIZE proposal contents due at time now, and returns the number fetched.
+// Failed fetches are rescheduled with exponential backoff, until CONTENT_MAX_ATTEMPTS attempts have failed.
+func (f *ContentFetcher) FetchDue(ctx context.Context, now time.Time) (int, error) {
+	contents, err := f.store.GetProposalContentToFetch(ctx, now.Unix(), CONTENT_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	fetched := 0
+	for _, content := range contents {
+		if ctx.Err() != nil {
+			return fetched, nil
+		}
+		path, ok := governor.IpfsPath(content.Uri)
+		if !ok {
+			f.fail(content, fmt.Errorf("invalid ipfs uri %q", content.Uri), now, false)
+		} else if body, contentType, err := f.fetch(ctx, path); err != nil {
+			// oversized or binary content won't change, so is not retried
+			retry := !errors.Is(err, errContentTooLarge) && !errors.Is(err, errContentNotText)
+			f.fail(content, err, now, retry)
+		} else {
+			content.Status = governor.CONTENT_STATUS_FETCHED
+			content.Content = body
+			content.ContentType = contentType
+			content.NextAttemptAt = 0
+			content.LastError = ""
+			content.FetchedAt = now.Unix()
+			fetched++
+			metrics.ContentFetched.Inc()
+			slog.Info("Fetched proposal content", "proposal_key", content.ProposalKey, "uri", content.Uri, "bytes", len(body))
+		}
+
+		if err := f.store.UpsertProposalContent(ctx, content); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, nil
+}
+
+// fail records a failed fetch attempt, and schedules a retry if retry is true and attempts remain
+func (f *ContentFetcher) fail(content *governor.ProposalContent, err error, now time.Time, retry bool) {
+	content.Attempts++
+	content.LastError = err.Error()
+	metrics.ContentFetchFailures.Inc()
+	if !retry || content.Attempts >= CONTENT_MAX_ATTEMPTS {
+		content.Status = governor.CONTENT_STATUS_FAILED
+		content.NextAttemptAt = 0
+		slog.Warn("Giving up fetching proposal content", "proposal_key", content.ProposalKey, "uri", content.Uri, "attempts", content.Attempts, "err", err)
+		return
+	}
+	delay := retryDelay(content.Attempts)
+	content.Status = governor.CONTENT_STATUS_PENDING
+	content.NextAttemptAt = now.Add(delay).Unix()
+	slog.Warn("Failed to fetch proposal content, retrying", "proposal_key", content.ProposalKey, "uri", content.Uri, "attempts", content.Attempts, "retry_in", delay, "err", err)
+}
+
+// fetch returns the content and Content-Type served by the gateway for an IPFS path
+func (f *ContentFetcher) fetch(ctx context.Context, path string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.gatewayUrl+"/ipfs/"+path, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("gateway returned status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > int64(f.maxBytes) {
+		return "", "", fmt.Errorf("%d bytes: %w", resp.ContentLength, errContentTooLarge)
+	}
+	// read one byte past the limit to detect oversized content without a Content-Length
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(f.maxBytes)+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > f.maxBytes {
+		return "", "", fmt.Errorf("over %d bytes: %w", f.maxBytes, errContentTooLarge)
+	}
+	if !utf8.Valid(body) {
+		return "", "", errContentNotText
+	}
+	return string(body), resp.Header.Get("Content-Type"), nil
+}
+
+// retryDelay returns the delay before the next fetch attempt after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := CONTENT_RETRY_BASE_DELAY
+	for i := 1; i < attempts && delay < CONTENT_RETRY_MAX_DELAY; i++ {
+		delay *= 2
+	}
+	return min(delay, CONTENT_RETRY_MAX_DELAY)
+}
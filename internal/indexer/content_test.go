@@ -0,0 +1,158 @@
+package indexer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestContentFetcherFetchDue(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ipfs/QmFetched/proposal.md":
+			w.Header().Set("Content-Type", "text/markdown")
+			w.Write([]byte("# Make me security council"))
+		case "/ipfs/QmTooLarge":
+			w.Write([]byte(strings.Repeat("a", 65)))
+		case "/ipfs/QmBinary":
+			w.Write([]byte{0xff, 0xfe})
+		default:
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer gateway.Close()
+
+	now := time.Unix(1761053046, 0)
+	newContent := func(id uint32, uri string, attempts int) *governor.ProposalContent {
+		return &governor.ProposalContent{
+			ProposalKey: governor.EncodeProposalKey(testContractId, id),
+			ContractId:  testContractId,
+			ProposalId:  id,
+			Uri:         uri,
+			Status:      governor.CONTENT_STATUS_PENDING,
+			Attempts:    attempts,
+		}
+	}
+	tests := []struct {
+		name    string
+		content *governor.ProposalContent
+		want    *governor.ProposalContent
+	}{
+		{
+			name:    "fetched",
+			content: newContent(1, "ipfs://QmFetched/proposal.md", 1),
+			want: &governor.ProposalContent{
+				ProposalKey: governor.EncodeProposalKey(testContractId, 1),
+				ContractId:  testContractId,
+				ProposalId:  1,
+				Uri:         "ipfs://QmFetched/proposal.md",
+				Status:      governor.CONTENT_STATUS_FETCHED,
+				Content:     "# Make me security council",
+				ContentType: "text/markdown",
+				Attempts:    1,
+				FetchedAt:   now.Unix(),
+			},
+		},
+		{
+			name:    "gateway error is retried",
+			content: newContent(2, "ipfs://QmUnavailable", 2),
+			want: &governor.ProposalContent{
+				ProposalKey:   governor.EncodeProposalKey(testContractId, 2),
+				ContractId:    testContractId,
+				ProposalId:    2,
+				Uri:           "ipfs://QmUnavailable",
+				Status:        governor.CONTENT_STATUS_PENDING,
+				Attempts:      3,
+				NextAttemptAt: now.Add(4 * time.Minute).Unix(),
+				LastError:     "gateway returned status 504",
+			},
+		},
+		{
+			name:    "gateway error after max attempts",
+			content: newContent(3, "ipfs://QmUnavailable", CONTENT_MAX_ATTEMPTS-1),
+			want: &governor.ProposalContent{
+				ProposalKey: governor.EncodeProposalKey(testContractId, 3),
+				ContractId:  testContractId,
+				ProposalId:  3,
+				Uri:         "ipfs://QmUnavailable",
+				Status:      governor.CONTENT_STATUS_FAILED,
+				Attempts:    CONTENT_MAX_ATTEMPTS,
+				LastError:   "gateway returned status 504",
+			},
+		},
+		{
+			name:    "too large is not retried",
+			content: newContent(4, "ipfs://QmTooLarge", 0),
+			want: &governor.ProposalContent{
+				ProposalKey: governor.EncodeProposalKey(testContractId, 4),
+				ContractId:  testContractId,
+				ProposalId:  4,
+				Uri:         "ipfs://QmTooLarge",
+				Status:      governor.CONTENT_STATUS_FAILED,
+				Attempts:    1,
+				LastError:   "65 bytes: content too large",
+			},
+		},
+		{
+			name:    "binary is not retried",
+			content: newContent(5, "ipfs://QmBinary", 0),
+			want: &governor.ProposalContent{
+				ProposalKey: governor.EncodeProposalKey(testContractId, 5),
+				ContractId:  testContractId,
+				ProposalId:  5,
+				Uri:         "ipfs://QmBinary",
+				Status:      governor.CONTENT_STATUS_FAILED,
+				Attempts:    1,
+				LastError:   "content is not text",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upserted []*governor.ProposalContent
+			store := &mockStore{
+				getProposalContentToFetch: func(ctx context.Context, now int64, limit int) ([]*governor.ProposalContent, error) {
+					return []*governor.ProposalContent{tt.content}, nil
+				},
+				upsertProposalContent: func(ctx context.Context, content *governor.ProposalContent) error {
+					upserted = append(upserted, content)
+					return nil
+				},
+			}
+			fetcher := NewContentFetcher(store, &Config{IpfsGatewayUrl: gateway.URL + "/", IpfsFetchTimeout: 5, IpfsMaxContentBytes: 64})
+
+			_, err := fetcher.FetchDue(t.Context(), now)
+			if err != nil {
+				t.Fatalf("FetchDue returned error: %v", err)
+			}
+			if diff := cmp.Diff([]*governor.ProposalContent{tt.want}, upserted); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 5, want: 16 * time.Minute},
+		{attempts: 9, want: 256 * time.Minute},
+		{attempts: 10, want: CONTENT_RETRY_MAX_DELAY},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/clients/stellarcore"
+)
+
+const (
+	// CAPTIVE_CORE_STATUS_SOURCE is the status table source recording the ledger captive core is applying
+	CAPTIVE_CORE_STATUS_SOURCE = "captive_core"
+	// CAPTIVE_CORE_STATUS_INTERVAL is how often the captive core status is recorded
+	CAPTIVE_CORE_STATUS_INTERVAL = 10 * time.Second
+	// CAPTIVE_CORE_DIR is the directory captive core keeps its state in, under CORE_STORAGE_PATH
+	CAPTIVE_CORE_DIR = "captive-core"
+)
+
+// checkStorageDir returns an error if path is not a writable directory
+func checkStorageDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid CORE_STORAGE_PATH: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid CORE_STORAGE_PATH: %s is not a directory", path)
+	}
+	file, err := os.CreateTemp(path, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("invalid CORE_STORAGE_PATH: %s is not writable: %w", path, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// logCatchupStrategy logs how captive core will reach the start ledger, based on the state in the storage path
+func logCatchupStrategy(storagePath string) {
+	dir := filepath.Join(storagePath, CAPTIVE_CORE_DIR)
+	if storagePath == "" {
+		slog.Warn("CORE_STORAGE_PATH not set, captive core state is kept in the working directory and may not survive restarts", "dir", dir)
+	}
+	if _, err := os.Stat(dir); err == nil {
+		slog.Info("Captive core state found, resuming from its last closed ledger if it is before the start ledger, otherwise catching up from history archives", "dir", dir)
+	} else {
+		slog.Info("No captive core state found, catching up from history archives", "dir", dir)
+	}
+}
+
+// newCaptiveCoreClient returns a client for the HTTP server of captive core, or nil if the server is disabled
+func newCaptiveCoreClient(httpPort uint) *stellarcore.Client {
+	if httpPort == 0 {
+		return nil
+	}
+	return &stellarcore.Client{
+		HTTP: &http.Client{Timeout: 2 * time.Second},
+		URL:  fmt.Sprintf("http://localhost:%d", httpPort),
+	}
+}
+
+// watchCaptiveCore records the status of captive core every CAPTIVE_CORE_STATUS_INTERVAL until ctx is cancelled, so
+// operators can tell captive core warming up from being stuck
+func watchCaptiveCore(ctx context.Context, store Store, client *stellarcore.Client) {
+	ticker := time.NewTicker(CAPTIVE_CORE_STATUS_INTERVAL)
+	defer ticker.Stop()
+	for {
+		if err := recordCaptiveCoreStatus(ctx, store, client, time.Now()); err != nil && ctx.Err() == nil {
+			// captive core doesn't serve its status while it catches up offline, so this is expected on startup
+			slog.Debug("Failed to record captive core status", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordCaptiveCoreStatus records the state of captive core and the ledger it is applying
+func recordCaptiveCoreStatus(ctx context.Context, store Store, client *stellarcore.Client, now time.Time) error {
+	info, err := client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get captive core info: %w", err)
+	}
+	if info == nil {
+		return errors.New("failed to get captive core info: empty response")
+	}
+	return store.UpsertSourceStatus(ctx, CAPTIVE_CORE_STATUS_SOURCE, db.SourceStatus{
+		LedgerSeq:       uint32(info.Info.Ledger.Num),
+		LedgerCloseTime: int64(info.Info.Ledger.CloseTime),
+		State:           info.Info.State,
+		UpdatedAt:       now.Unix(),
+	})
+}
@@ -0,0 +1,88 @@
+package indexer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/clients/stellarcore"
+)
+
+func TestCheckStorageDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "writable directory", path: dir},
+		{name: "missing directory", path: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "file", path: file, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStorageDir(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkStorageDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("checkStorageDir() left %d entries in the directory, want 1", len(entries))
+	}
+}
+
+func TestRecordCaptiveCoreStatus(t *testing.T) {
+	core := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"info": {"state": "Catching up", "ledger": {"num": 1170134, "closeTime": 1761053046}}}`))
+	}))
+	defer core.Close()
+
+	var got []db.SourceStatus
+	store := &mockStore{
+		upsertSourceStatus: func(ctx context.Context, source string, status db.SourceStatus) error {
+			if source != CAPTIVE_CORE_STATUS_SOURCE {
+				t.Errorf("UpsertSourceStatus() source = %q, want %q", source, CAPTIVE_CORE_STATUS_SOURCE)
+			}
+			got = append(got, status)
+			return nil
+		},
+	}
+	now := time.Unix(1761053050, 0)
+	if err := recordCaptiveCoreStatus(t.Context(), store, &stellarcore.Client{URL: core.URL}, now); err != nil {
+		t.Fatalf("recordCaptiveCoreStatus() error = %v", err)
+	}
+	want := []db.SourceStatus{{LedgerSeq: 1170134, LedgerCloseTime: 1761053046, State: "Catching up", UpdatedAt: now.Unix()}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("recorded status mismatch (-want +got):\n%s", diff)
+	}
+
+	// captive core is unreachable while it catches up offline, and nothing is recorded
+	core.Close()
+	if err := recordCaptiveCoreStatus(t.Context(), store, &stellarcore.Client{URL: core.URL}, now); err == nil {
+		t.Errorf("recordCaptiveCoreStatus() error = nil, want error")
+	}
+	if len(got) != 1 {
+		t.Errorf("recorded %d statuses, want 1", len(got))
+	}
+}
@@ -0,0 +1,44 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+const (
+	// DEFAULT_ERROR_LOG_SIZE is the number of processing errors kept in the indexer_errors table, unless set with
+	// INDEXER_ERROR_LOG_SIZE
+	DEFAULT_ERROR_LOG_SIZE = 1000
+	// MAX_ERROR_LOG_MESSAGE_BYTES is the maximum length of an error message recorded in the indexer_errors table.
+	// Longer messages are truncated.
+	MAX_ERROR_LOG_MESSAGE_BYTES = 2048
+)
+
+// recordError records an error hit while processing a ledger, or an event of the transaction txHash, in the
+// indexer_errors table, so operators can see it through the API. Recording is best-effort: failures are logged and
+// never returned, so they can't fail ingestion. Nothing is recorded if the error log is disabled, or once ctx is
+// done, as errors of a stopping indexer are only cancellations.
+//
+// If ctx is bound to a transaction, the error is recorded once the transaction ends, outside of it, so it isn't
+// rolled back with the failure it describes.
+func (idx *Indexer) recordError(ctx context.Context, ledgerSeq uint32, txHash string, err error) {
+	if idx.errorLogSize <= 0 {
+		return
+	}
+	message := err.Error()
+	if len(message) > MAX_ERROR_LOG_MESSAGE_BYTES {
+		message = strings.ToValidUTF8(message[:MAX_ERROR_LOG_MESSAGE_BYTES], "")
+	}
+	indexerError := &db.IndexerError{LedgerSeq: ledgerSeq, TxHash: txHash, Message: message, CreatedAt: idx.now().Unix()}
+	idx.store.AfterTx(ctx, func(ctx context.Context) {
+		if ctx.Err() != nil {
+			return
+		}
+		if recordErr := idx.store.InsertIndexerError(ctx, indexerError, idx.errorLogSize); recordErr != nil {
+			slog.Warn("Failed recording indexer error", "ledger", ledgerSeq, "hash", txHash, "err", recordErr)
+		}
+	})
+}
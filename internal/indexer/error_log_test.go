@@ -0,0 +1,97 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestRecordError(t *testing.T) {
+	var recorded []*db.IndexerError
+	var keeps []int
+	store := &mockStore{
+		insertIndexerError: func(ctx context.Context, indexerError *db.IndexerError, keep int) error {
+			recorded = append(recorded, indexerError)
+			keeps = append(keeps, keep)
+			return nil
+		},
+	}
+	indexer := NewIndexer(store)
+	indexer.now = func() time.Time { return time.Unix(1761053046, 0) }
+
+	indexer.recordError(t.Context(), 1000, "tx_1", errors.New("failed applying event"))
+	indexer.recordError(t.Context(), 1001, "", errors.New(strings.Repeat("x", MAX_ERROR_LOG_MESSAGE_BYTES+1)))
+	want := []*db.IndexerError{
+		{LedgerSeq: 1000, TxHash: "tx_1", Message: "failed applying event", CreatedAt: 1761053046},
+		{LedgerSeq: 1001, Message: strings.Repeat("x", MAX_ERROR_LOG_MESSAGE_BYTES), CreatedAt: 1761053046},
+	}
+	if diff := cmp.Diff(want, recorded); diff != "" {
+		t.Errorf("recorded errors mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{DEFAULT_ERROR_LOG_SIZE, DEFAULT_ERROR_LOG_SIZE}, keeps); diff != "" {
+		t.Errorf("kept sizes mismatch (-want +got):\n%s", diff)
+	}
+
+	// nothing is recorded if the error log is disabled, or once the context is done
+	recorded = nil
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	indexer.recordError(ctx, 1002, "", errors.New("context canceled"))
+	indexer.errorLogSize = 0
+	indexer.recordError(t.Context(), 1003, "", errors.New("failed applying event"))
+	if len(recorded) != 0 {
+		t.Errorf("expected no recorded errors, got %d", len(recorded))
+	}
+
+	// failing to record an error doesn't change the error returned
+	errDb := errors.New("db error")
+	store = &mockStore{
+		insertEvent: func(ctx context.Context, event *governor.GovernorEvent) error { return errDb },
+		insertIndexerError: func(ctx context.Context, indexerError *db.IndexerError, keep int) error {
+			return errors.New("disk full")
+		},
+		getContractLastEventId: func(ctx context.Context, contractId string) (string, error) { return "", db.ErrNotFound },
+	}
+	if err := NewIndexer(store).ApplyEvent(t.Context(), &governor.GovernorEvent{EventId: "0005025695851876452-0000000000"}); !errors.Is(err, errDb) {
+		t.Errorf("ApplyEvent() error = %v, want the db error", err)
+	}
+}
+
+// TestRecordErrorInFailedTx verifies an error recorded inside a transaction is kept when the transaction fails
+func TestRecordErrorInFailedTx(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+	indexer := NewIndexer(store)
+	indexer.now = func() time.Time { return time.Unix(1761053046, 0) }
+
+	errApply := errors.New("failed applying event")
+	err := store.WithTx(ctx, func(ctx context.Context) error {
+		if err := store.InsertEvent(ctx, &governor.GovernorEvent{EventId: "0005025695851876452-0000000000", EventType: "proposal_executed", EventData: "{}"}); err != nil {
+			return err
+		}
+		indexer.recordError(ctx, 1000, "tx_1", errApply)
+		return errApply
+	})
+	if !errors.Is(err, errApply) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errApply)
+	}
+
+	// the transaction is rolled back, but the error is recorded
+	if _, err := store.GetEvent(ctx, "0005025695851876452-0000000000"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the event to be rolled back, got %v", err)
+	}
+	recorded, err := store.GetIndexerErrors(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*db.IndexerError{{Seq: 1, LedgerSeq: 1000, TxHash: "tx_1", Message: "failed applying event", CreatedAt: 1761053046}}
+	if diff := cmp.Diff(want, recorded); diff != "" {
+		t.Errorf("recorded errors mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,125 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+const (
+	// EXPORT_PAGE_SIZE is the number of events read from the history table at a time when exporting
+	EXPORT_PAGE_SIZE = 1000
+	// IMPORT_BATCH_SIZE is the number of events inserted in each transaction when importing
+	IMPORT_BATCH_SIZE = 1000
+)
+
+// ExportEvents writes the history table events of a contract to w as JSON lines, in event order, in the same format
+// as Inspect. If contractId is empty, the events of every contract are written. Events are read a page at a time,
+// so the history table is never loaded into memory.
+//
+// ExportEvents returns the number of events written.
+func ExportEvents(ctx context.Context, store *db.Store, contractId string, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	after := ""
+	for {
+		events, err := store.GetEventsAfter(ctx, contractId, after, EXPORT_PAGE_SIZE)
+		if err != nil {
+			return exported, err
+		}
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return exported, fmt.Errorf("failed to write event %s: %w", event.EventId, err)
+			}
+			exported++
+		}
+		if len(events) < EXPORT_PAGE_SIZE {
+			return exported, nil
+		}
+		after = events[len(events)-1].EventId
+		slog.Debug("Exported events.", "events", exported, "last_event", after)
+	}
+}
+
+// ImportStats are the results of ImportEvents
+type ImportStats struct {
+	// Events is the number of events read. Events already in the history table are left unchanged.
+	Events int
+	// Contracts are the contracts of the events read, sorted
+	Contracts []string
+}
+
+// ImportEvents inserts the events in r, as JSON lines written by ExportEvents or Inspect, into the history table.
+// Events already in the history table are left unchanged, so an import can be restarted, or run against a database
+// that already has some of the events. Events are inserted in batches of IMPORT_BATCH_SIZE, each in a transaction.
+//
+// Only the history table is changed. Reindex the imported contracts to rebuild their proposals, votes, and
+// delegations.
+func ImportEvents(ctx context.Context, store *db.Store, r io.Reader) (ImportStats, error) {
+	reader := bufio.NewReader(r)
+	contracts := make(map[string]bool)
+	var stats ImportStats
+	batch := make([]*governor.GovernorEvent, 0, IMPORT_BATCH_SIZE)
+	insert := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			return store.InsertEvents(ctx, batch)
+		})
+		if err != nil {
+			return err
+		}
+		stats.Events += len(batch)
+		batch = batch[:0]
+		slog.Debug("Imported events.", "events", stats.Events)
+		return nil
+	}
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return stats, fmt.Errorf("failed to read line %d: %w", line, err)
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			event := &governor.GovernorEvent{}
+			if err := json.Unmarshal(data, event); err != nil {
+				return stats, fmt.Errorf("invalid event on line %d: %w", line, err)
+			}
+			if event.EventId == "" || event.ContractId == "" || event.EventType == "" {
+				return stats, fmt.Errorf("invalid event on line %d: missing event id, contract id, or event type", line)
+			}
+			if err := governor.ValidateEventData(event.EventType, event.EventData); err != nil {
+				return stats, fmt.Errorf("invalid event on line %d: %w", line, err)
+			}
+			contracts[event.ContractId] = true
+			batch = append(batch, event)
+			if len(batch) == IMPORT_BATCH_SIZE {
+				if err := insert(); err != nil {
+					return stats, err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if err := insert(); err != nil {
+		return stats, err
+	}
+
+	for contractId := range contracts {
+		stats.Contracts = append(stats.Contracts, contractId)
+	}
+	slices.Sort(stats.Contracts)
+	return stats, nil
+}
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportImportEvents(t *testing.T) {
+	ctx := t.Context()
+	source := setupStore(t, ctx)
+
+	var buf bytes.Buffer
+	exported, err := ExportEvents(ctx, source, testContractId, &buf)
+	if err != nil {
+		t.Fatalf("ExportEvents() error = %v", err)
+	}
+	if exported != len(initHistory) {
+		t.Errorf("ExportEvents() = %d, want %d", exported, len(initHistory))
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != exported {
+		t.Errorf("exported %d lines, want %d", lines, exported)
+	}
+
+	// importing twice leaves the events unchanged
+	dest := newFixtureStore(t)
+	for range 2 {
+		stats, err := ImportEvents(ctx, dest, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ImportEvents() error = %v", err)
+		}
+		if diff := cmp.Diff(ImportStats{Events: exported, Contracts: []string{testContractId}}, stats); diff != "" {
+			t.Errorf("import stats mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	want, err := source.GetEventsAfter(ctx, "", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dest.GetEventsAfter(ctx, "", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("imported events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImportEventsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "not json", input: "{\"EventId\":\"1\",\"ContractId\":\"C\",\"EventType\":\"proposal_canceled\",\"EventData\":\"{}\"}\nnope\n", wantErr: "invalid event on line 2"},
+		{name: "missing contract", input: `{"EventId":"1","EventType":"vote_cast"}`, wantErr: "invalid event on line 1"},
+		{name: "invalid event data", input: `{"EventId":"1","ContractId":"C","EventType":"vote_cast","EventData":"{\"amount\":1}"}`, wantErr: "invalid event on line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportEvents(t.Context(), newFixtureStore(t), strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ImportEvents() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
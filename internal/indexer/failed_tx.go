@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// parseFailedTransaction returns the governor events emitted by a failed InvokeHostFunction transaction before it
+// failed. Its changes were rolled back, so these are only read from the transaction's diagnostic events, which are
+// only included in the ledger metadata if the node that closed the ledger records them. Events that fail to parse are
+// skipped, as are events for which skip, if not nil, returns true.
+//
+// Event ids use the toid of the transaction's operation followed by the index of the event in the diagnostic events,
+// so they never match the id of an event in the history.
+func parseFailedTransaction(tx ingest.LedgerTransaction, ledgerSeq uint32, ledgerCloseTime int64, skip func(event *xdr.ContractEvent) bool) []*governor.FailedTxEvent {
+	if tx.Successful() {
+		return nil
+	}
+	op_0, ok := tx.GetOperation(0)
+	if !ok || op_0.Body.Type != xdr.OperationTypeInvokeHostFunction {
+		return nil
+	}
+	diagnosticEvents, err := tx.GetDiagnosticEvents()
+	if err != nil {
+		slog.Error("Failed getting diagnostic events for failed tx", "ledger", ledgerSeq, "hash", tx.Hash, "err", err)
+		return nil
+	}
+
+	opToid := toid.New(int32(ledgerSeq), int32(tx.Index), 0).ToInt64()
+	txHash := tx.Hash.HexString()
+	sourceAccount := tx.Envelope.SourceAccount().ToAccountId().Address()
+	// the result code's name without its type prefix, such as "TxFailed"
+	resultCode := strings.TrimPrefix(tx.Result.Result.Result.Code.String(), "TransactionResultCode")
+
+	var failedTxEvents []*governor.FailedTxEvent
+	for eventIndex, diagnosticEvent := range diagnosticEvents {
+		// diagnostic events also hold the host's function calls and errors, which are not contract events
+		event := diagnosticEvent.Event
+		if event.Type != xdr.ContractEventTypeContract {
+			continue
+		}
+		if skip != nil && skip(&event) {
+			continue
+		}
+		govEvent, err := governor.NewGovernorEventFromContractEvent(&event, txHash, ledgerSeq, ledgerCloseTime, opToid, int32(eventIndex))
+		if err != nil {
+			continue
+		}
+		govEvent.SourceAccount = sourceAccount
+		if govEvent.EventXdr, err = xdr.MarshalBase64(event); err != nil {
+			slog.Error("Failed marshalling failed tx event xdr", "ledger", ledgerSeq, "hash", txHash, "eventId", govEvent.EventId, "err", err)
+		}
+		failedTxEvents = append(failedTxEvents, governor.NewFailedTxEvent(govEvent, resultCode))
+	}
+	return failedTxEvents
+}
+
+// recordFailedTxEvents records the governor events of tracked contracts emitted by a failed transaction, and adds
+// them to stats. They are written to the failed tx events table only, so they never change proposals or votes. Only
+// database timeouts are returned, so the ledger is retried; other errors are logged.
+func (idx *Indexer) recordFailedTxEvents(ctx context.Context, tx ingest.LedgerTransaction, ledgerSeq uint32, ledgerCloseTime int64, stats *LedgerStats) error {
+	for _, event := range parseFailedTransaction(tx, ledgerSeq, ledgerCloseTime, idx.blocklist.containsEvent) {
+		// any contract can emit events laid out like governor events, so only keep those of tracked contracts
+		if !idx.tracked.contains(event.ContractId) {
+			continue
+		}
+
+		err := idx.store.InsertFailedTxEvent(ctx, event)
+		if errors.Is(err, db.ErrTimeout) {
+			return fmt.Errorf("failed recording failed tx event %s: %w", event.EventId, err)
+		} else if err != nil {
+			slog.Error("Failed recording failed tx event", "ledger", ledgerSeq, "hash", event.TxHash, "eventId", event.EventId, "err", err)
+			continue
+		}
+		stats.FailedTxEvents++
+	}
+	return nil
+}
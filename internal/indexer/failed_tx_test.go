@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func TestApplyLedgerFailedTxEvents(t *testing.T) {
+	created1 := withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1)
+	vote1 := withProposalId(t, mustDecodeEvent(t, voteCastXdr), 1)
+	untrackedVote := vote1
+	otherId := xdr.ContractId{0xb0}
+	untrackedVote.ContractId = &otherId
+
+	ledgers := []xdr.LedgerCloseMeta{
+		newFixtureLedger(t, 1170134, 1761053041, []fixtureTx{{events: []xdr.ContractEvent{created1}}}),
+		newFixtureLedger(t, 1170136, 1761053046, []fixtureTx{
+			{events: []xdr.ContractEvent{vote1}, failed: true},
+			{events: []xdr.ContractEvent{untrackedVote}, failed: true},
+		}),
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    int
+	}{
+		{name: "disabled", enabled: false, want: 0},
+		{name: "enabled", enabled: true, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			store := newFixtureStore(t)
+			idx := NewIndexer(store)
+			idx.failedTxEvents = tt.enabled
+
+			var total LedgerStats
+			for _, ledger := range ledgers {
+				stats, err := idx.processLedger(ctx, network.TestNetworkPassphrase, ledger)
+				if err != nil {
+					t.Fatalf("processLedger() error = %v", err)
+				}
+				total.Add(stats)
+			}
+			if total.FailedTxEvents != tt.want {
+				t.Errorf("got %d failed tx events in stats, want %d", total.FailedTxEvents, tt.want)
+			}
+
+			events, err := store.GetFailedTxEvents(ctx, "", true, "", 10)
+			if err != nil {
+				t.Fatalf("failed to get failed tx events: %v", err)
+			}
+			if len(events) != tt.want {
+				t.Fatalf("got %d failed tx events, want %d", len(events), tt.want)
+			}
+			if tt.want > 0 {
+				got := events[0]
+				if got.ContractId != testContractId || got.EventType != "vote_cast" || got.ProposalId != 1 || got.ResultCode != "TxFailed" {
+					t.Errorf("got failed tx event %s %s %d %s, want %s vote_cast 1 TxFailed", got.ContractId, got.EventType, got.ProposalId, got.ResultCode, testContractId)
+				}
+				if got.SourceAccount != fixtureSourceAccount {
+					t.Errorf("got source account %s, want %s", got.SourceAccount, fixtureSourceAccount)
+				}
+			}
+
+			// the aggregated tables and history are never changed by a failed transaction
+			votes, err := store.GetVotesByProposal(ctx, testContractId, 1, db.Sort{})
+			if err != nil {
+				t.Fatalf("failed to get votes: %v", err)
+			}
+			if len(votes) != 0 {
+				t.Errorf("got %d votes from a failed transaction, want 0", len(votes))
+			}
+			history, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+			if err != nil {
+				t.Fatalf("failed to get events: %v", err)
+			}
+			if len(history) != 1 || history[0].EventType != "proposal_created" {
+				t.Errorf("got %d history events, want only proposal_created", len(history))
+			}
+		})
+	}
+}
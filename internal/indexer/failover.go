@@ -0,0 +1,229 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// RPC_FAILOVER_ERRORS is the number of consecutive errors from an RPC server before failing over to the next one
+	RPC_FAILOVER_ERRORS = 3
+	// RPC_RETRY_DELAY is how long to wait before retrying a ledger after an error from an RPC server
+	RPC_RETRY_DELAY = 2 * time.Second
+	// RPC_UNHEALTHY_COOLDOWN is how long an RPC server that was failed over from is considered unhealthy. Once it is
+	// healthy again, the indexer switches back to it if it is preferred over the current server.
+	RPC_UNHEALTHY_COOLDOWN = 5 * time.Minute
+)
+
+// rpcEndpoint is the health of an RPC server
+type rpcEndpoint struct {
+	url string
+	// errors is the number of consecutive errors from the server
+	errors         int
+	unhealthyUntil time.Time
+}
+
+// failoverBackend is a ledger backend that reads ledgers from the first healthy of a list of RPC servers. After
+// RPC_FAILOVER_ERRORS consecutive errors, the server is marked unhealthy for RPC_UNHEALTHY_COOLDOWN, and the range
+// is prepared on the next server from the ledger that failed, so callers don't see the switch. Servers earlier in the
+// list are preferred, so the backend switches back to them once they are healthy again.
+//
+// A failoverBackend is not safe for concurrent use.
+type failoverBackend struct {
+	endpoints  []*rpcEndpoint
+	newBackend func(url string) ledgerbackend.LedgerBackend
+
+	// backend is the backend of the current endpoint, and is nil until a range is prepared
+	backend     ledgerbackend.LedgerBackend
+	current     int
+	ledgerRange *ledgerbackend.Range
+
+	retryDelay time.Duration
+	cooldown   time.Duration
+	now        func() time.Time
+}
+
+// newFailoverBackend creates a backend failing over between urls, in order of preference. newBackend creates the
+// backend of a single server, and is called again for each switch, as a range can only be prepared once.
+func newFailoverBackend(urls []string, newBackend func(url string) ledgerbackend.LedgerBackend) *failoverBackend {
+	endpoints := make([]*rpcEndpoint, len(urls))
+	for i, url := range urls {
+		endpoints[i] = &rpcEndpoint{url: url}
+	}
+	return &failoverBackend{
+		endpoints:  endpoints,
+		newBackend: newBackend,
+		retryDelay: RPC_RETRY_DELAY,
+		cooldown:   RPC_UNHEALTHY_COOLDOWN,
+		now:        time.Now,
+	}
+}
+
+func (f *failoverBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	if f.backend == nil {
+		return 0, errors.New("failover backend must be prepared before calling GetLatestLedgerSequence")
+	}
+	return f.backend.GetLatestLedgerSequence(ctx)
+}
+
+// GetLedger gets a ledger from the current server, retrying errors, and failing over to the next healthy server after
+// RPC_FAILOVER_ERRORS consecutive errors. An error is returned once every server has been failed over from.
+func (f *failoverBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if f.backend == nil {
+		return xdr.LedgerCloseMeta{}, errors.New("failover backend must be prepared before calling GetLedger")
+	}
+
+	failovers := 0
+	for {
+		if preferred := f.firstHealthy(); preferred < f.current {
+			if err := f.switchTo(ctx, preferred, f.rangeFrom(sequence)); err != nil && ctx.Err() == nil {
+				f.markUnhealthy(preferred, err)
+			}
+		}
+
+		endpoint := f.endpoints[f.current]
+		ledger, err := f.backend.GetLedger(ctx, sequence)
+		if err == nil {
+			endpoint.errors = 0
+			return ledger, nil
+		}
+		if ctx.Err() != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+
+		endpoint.errors++
+		slog.Warn("Failed to get ledger from RPC server", "url", endpoint.url, "ledger", sequence, "errors", endpoint.errors, "err", err)
+		if endpoint.errors < RPC_FAILOVER_ERRORS {
+			if !sleepCtx(ctx, f.retryDelay) {
+				return xdr.LedgerCloseMeta{}, ctx.Err()
+			}
+			continue
+		}
+
+		f.markUnhealthy(f.current, err)
+		failovers++
+		if failovers >= len(f.endpoints) {
+			return xdr.LedgerCloseMeta{}, fmt.Errorf("every RPC server failed to get ledger %d: %w", sequence, err)
+		}
+		if err := f.failover(ctx, f.rangeFrom(sequence)); err != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+	}
+}
+
+// PrepareRange prepares the range on the first healthy server that can prepare it
+func (f *failoverBackend) PrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	if f.ledgerRange != nil {
+		return fmt.Errorf("failover backend is already prepared with range %s", f.ledgerRange)
+	}
+	if err := f.failover(ctx, ledgerRange); err != nil {
+		return err
+	}
+	f.ledgerRange = &ledgerRange
+	return nil
+}
+
+func (f *failoverBackend) IsPrepared(ctx context.Context, ledgerRange ledgerbackend.Range) (bool, error) {
+	return f.ledgerRange != nil && *f.ledgerRange == ledgerRange, nil
+}
+
+func (f *failoverBackend) Close() error {
+	if f.backend == nil {
+		return nil
+	}
+	return f.backend.Close()
+}
+
+// failover prepares the range on the first server that can prepare it, trying healthy servers first, in order of
+// preference, then unhealthy ones. The current server is only tried if no other server can prepare the range.
+func (f *failoverBackend) failover(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	var lastErr error
+	for _, i := range f.candidates() {
+		err := f.switchTo(ctx, i, ledgerRange)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		f.markUnhealthy(i, err)
+		lastErr = err
+	}
+	return fmt.Errorf("no RPC server could prepare ledger range %s: %w", ledgerRange, lastErr)
+}
+
+// candidates returns the indexes of the servers to fail over to, in the order they are tried
+func (f *failoverBackend) candidates() []int {
+	now := f.now()
+	var healthy, unhealthy []int
+	for i, endpoint := range f.endpoints {
+		if f.backend != nil && i == f.current {
+			continue
+		}
+		if now.Before(endpoint.unhealthyUntil) {
+			unhealthy = append(unhealthy, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	candidates := append(healthy, unhealthy...)
+	if f.backend != nil {
+		candidates = append(candidates, f.current)
+	}
+	return candidates
+}
+
+// switchTo prepares the range on a new backend for the server at index i, and makes it the current backend. The
+// current backend is only replaced if the range is prepared.
+func (f *failoverBackend) switchTo(ctx context.Context, i int, ledgerRange ledgerbackend.Range) error {
+	backend := f.newBackend(f.endpoints[i].url)
+	if err := backend.PrepareRange(ctx, ledgerRange); err != nil {
+		backend.Close()
+		return err
+	}
+	if f.backend != nil {
+		f.backend.Close()
+		if i != f.current {
+			metrics.RPCFailovers.Inc()
+			slog.Warn("Switched RPC server", "from", f.endpoints[f.current].url, "to", f.endpoints[i].url, "ledger", ledgerRange.From())
+		}
+	}
+	f.backend, f.current = backend, i
+	f.endpoints[i].errors = 0
+	metrics.RPCEndpoint.Set(float64(i))
+	return nil
+}
+
+// markUnhealthy marks the server at index i unhealthy for the cooldown
+func (f *failoverBackend) markUnhealthy(i int, err error) {
+	endpoint := f.endpoints[i]
+	endpoint.errors = 0
+	endpoint.unhealthyUntil = f.now().Add(f.cooldown)
+	slog.Warn("RPC server marked unhealthy", "url", endpoint.url, "until", endpoint.unhealthyUntil.Format(time.RFC3339), "err", err)
+}
+
+// firstHealthy returns the index of the first healthy server, or the number of servers if none are healthy
+func (f *failoverBackend) firstHealthy() int {
+	now := f.now()
+	for i, endpoint := range f.endpoints {
+		if !now.Before(endpoint.unhealthyUntil) {
+			return i
+		}
+	}
+	return len(f.endpoints)
+}
+
+// rangeFrom returns the prepared range, starting from sequence
+func (f *failoverBackend) rangeFrom(sequence uint32) ledgerbackend.Range {
+	if f.ledgerRange.Bounded() {
+		return ledgerbackend.BoundedRange(sequence, f.ledgerRange.To())
+	}
+	return ledgerbackend.UnboundedRange(sequence)
+}
@@ -0,0 +1,178 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// fakeRPCServer records the ranges prepared on it. While failing is set, every request fails, and while
+// failGetLedger is set, only ledger requests fail.
+type fakeRPCServer struct {
+	failing       bool
+	failGetLedger bool
+	prepares      []string
+}
+
+// fakeRPCBackend is the ledger backend of a fakeRPCServer. Ledgers are empty LedgerCloseMeta with the sequence set.
+type fakeRPCBackend struct {
+	server *fakeRPCServer
+	next   uint32
+}
+
+func (b *fakeRPCBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	return b.next, nil
+}
+
+func (b *fakeRPCBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if b.server.failing || b.server.failGetLedger {
+		return xdr.LedgerCloseMeta{}, errors.New("502 bad gateway")
+	}
+	if sequence != b.next {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("requested ledger %d is not the expected ledger %d", sequence, b.next)
+	}
+	b.next++
+	return xdr.LedgerCloseMeta{V: 1, V1: &xdr.LedgerCloseMetaV1{
+		LedgerHeader: xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)}},
+	}}, nil
+}
+
+func (b *fakeRPCBackend) PrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	if b.server.failing {
+		return errors.New("503 service unavailable")
+	}
+	b.server.prepares = append(b.server.prepares, ledgerRange.String())
+	b.next = ledgerRange.From()
+	return nil
+}
+
+func (b *fakeRPCBackend) IsPrepared(ctx context.Context, ledgerRange ledgerbackend.Range) (bool, error) {
+	return false, nil
+}
+
+func (b *fakeRPCBackend) Close() error {
+	return nil
+}
+
+func newTestFailoverBackend(servers map[string]*fakeRPCServer, urls ...string) (*failoverBackend, *time.Time) {
+	f := newFailoverBackend(urls, func(url string) ledgerbackend.LedgerBackend {
+		return &fakeRPCBackend{server: servers[url]}
+	})
+	now := time.Unix(1761053046, 0)
+	f.retryDelay = 0
+	f.now = func() time.Time { return now }
+	return f, &now
+}
+
+func getLedgers(t *testing.T, f *failoverBackend, from uint32, to uint32) {
+	t.Helper()
+	for seq := from; seq <= to; seq++ {
+		ledger, err := f.GetLedger(context.Background(), seq)
+		if err != nil {
+			t.Fatalf("GetLedger(%d) error = %v", seq, err)
+		}
+		if ledger.LedgerSequence() != seq {
+			t.Fatalf("GetLedger(%d) got ledger %d", seq, ledger.LedgerSequence())
+		}
+	}
+}
+
+func TestFailoverBackend(t *testing.T) {
+	ctx := context.Background()
+	a, b, c := &fakeRPCServer{}, &fakeRPCServer{failing: true}, &fakeRPCServer{}
+	servers := map[string]*fakeRPCServer{"https://a": a, "https://b": b, "https://c": c}
+	f, now := newTestFailoverBackend(servers, "https://a", "https://b", "https://c")
+
+	if err := f.PrepareRange(ctx, ledgerbackend.UnboundedRange(100)); err != nil {
+		t.Fatalf("PrepareRange() error = %v", err)
+	}
+	getLedgers(t, f, 100, 101)
+
+	// a fails, and b can't prepare the range, so the range is prepared on c from the failed ledger
+	a.failing = true
+	getLedgers(t, f, 102, 103)
+	if f.current != 2 {
+		t.Errorf("current = %d, want 2 after failing over", f.current)
+	}
+
+	// a is preferred once its cooldown has passed, but it is still failing, so c is kept
+	*now = now.Add(RPC_UNHEALTHY_COOLDOWN)
+	getLedgers(t, f, 104, 104)
+	if f.current != 2 {
+		t.Errorf("current = %d, want 2 while a is failing", f.current)
+	}
+
+	// a recovers, and is switched back to once its cooldown has passed again
+	a.failing = false
+	getLedgers(t, f, 105, 105)
+	if f.current != 2 {
+		t.Errorf("current = %d, want 2 during cooldown", f.current)
+	}
+	*now = now.Add(RPC_UNHEALTHY_COOLDOWN)
+	getLedgers(t, f, 106, 107)
+	if f.current != 0 {
+		t.Errorf("current = %d, want 0 after switching back", f.current)
+	}
+
+	want := map[string][]string{
+		"https://a": {"[100,latest)", "[106,latest)"},
+		"https://c": {"[102,latest)"},
+	}
+	got := map[string][]string{"https://a": a.prepares, "https://c": c.prepares}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("prepared ranges mismatch (-want +got):\n%s", diff)
+	}
+	if len(b.prepares) != 0 {
+		t.Errorf("b prepared ranges %v, want none", b.prepares)
+	}
+}
+
+func TestFailoverBackendPrepareRange(t *testing.T) {
+	ctx := context.Background()
+	a, b := &fakeRPCServer{failing: true}, &fakeRPCServer{}
+	f, _ := newTestFailoverBackend(map[string]*fakeRPCServer{"https://a": a, "https://b": b}, "https://a", "https://b")
+
+	if err := f.PrepareRange(ctx, ledgerbackend.BoundedRange(100, 110)); err != nil {
+		t.Fatalf("PrepareRange() error = %v", err)
+	}
+	if f.current != 1 {
+		t.Errorf("current = %d, want 1", f.current)
+	}
+	if ok, _ := f.IsPrepared(ctx, ledgerbackend.BoundedRange(100, 110)); !ok {
+		t.Errorf("IsPrepared() = false, want true")
+	}
+	if err := f.PrepareRange(ctx, ledgerbackend.BoundedRange(100, 110)); err == nil {
+		t.Errorf("PrepareRange() twice error = nil, want error")
+	}
+
+	b.failing = true
+	f2, _ := newTestFailoverBackend(map[string]*fakeRPCServer{"https://a": a, "https://b": b}, "https://a", "https://b")
+	if err := f2.PrepareRange(ctx, ledgerbackend.BoundedRange(100, 110)); err == nil {
+		t.Errorf("PrepareRange() with every server failing error = nil, want error")
+	}
+}
+
+func TestFailoverBackendEveryServerFailing(t *testing.T) {
+	ctx := context.Background()
+	a, b := &fakeRPCServer{}, &fakeRPCServer{}
+	f, _ := newTestFailoverBackend(map[string]*fakeRPCServer{"https://a": a, "https://b": b}, "https://a", "https://b")
+	if err := f.PrepareRange(ctx, ledgerbackend.BoundedRange(100, 110)); err != nil {
+		t.Fatalf("PrepareRange() error = %v", err)
+	}
+
+	// b can prepare the range, but can't get ledgers either
+	a.failing = true
+	b.failGetLedger = true
+	if _, err := f.GetLedger(ctx, 100); err == nil {
+		t.Errorf("GetLedger() error = nil, want error")
+	}
+	if diff := cmp.Diff([]string{"[100,110]"}, b.prepares); diff != "" {
+		t.Errorf("b prepared ranges mismatch (-want +got):\n%s", diff)
+	}
+}
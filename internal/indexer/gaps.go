@@ -0,0 +1,34 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+const (
+	// GAP_REASON_RPC_RETENTION is used for ledgers skipped with INDEXER_ALLOW_GAP, as the RPC server no longer
+	// retained them
+	GAP_REASON_RPC_RETENTION = "rpc_retention"
+	// GAP_REASON_START_SEQ is used for ledgers skipped by raising LEDGER_BACKEND_START_SEQ past the last processed
+	// ledger
+	GAP_REASON_START_SEQ = "start_seq"
+	// GAP_REASON_VERIFY is used for ledgers in which the verifier found a proposal changed on-chain without the change
+	// being indexed
+	GAP_REASON_VERIFY = "verify"
+)
+
+// RecordCoverageGaps records ranges of ledgers that were not indexed, created at now. Ranges already recorded are
+// left unchanged.
+func RecordCoverageGaps(ctx context.Context, store *db.Store, gaps []*db.CoverageGap, now time.Time) error {
+	for _, gap := range gaps {
+		gap.CreatedAt = now.Unix()
+		if err := store.InsertCoverageGap(ctx, gap); err != nil {
+			return err
+		}
+		slog.Warn("Recorded coverage gap", "from", gap.FromLedger, "to", gap.ToLedger, "reason", gap.Reason)
+	}
+	return nil
+}
@@ -2,231 +2,622 @@ package indexer
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"math/big"
+	"slices"
+	"time"
 
 	"github.com/script3/soroban-governor-backend/internal/db"
 	"github.com/script3/soroban-governor-backend/internal/governor"
 	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/strkey"
 	"github.com/stellar/go-stellar-sdk/toid"
 	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
+// APPLY_CONFLICT_RETRIES is the number of times an event is applied before giving up on conflicting proposal updates
+const APPLY_CONFLICT_RETRIES = 5
+
+// Errors returned by ApplyLedger, telling whether the ledger can be retried
+var (
+	// ErrLedgerRetryable is wrapped by errors that are transient, such as database timeouts and other store
+	// failures. The ledger can be applied again, as applying events is idempotent.
+	ErrLedgerRetryable = errors.New("retryable ledger error")
+	// ErrLedgerInvalid is wrapped by errors for a ledger that can't be read, which fails the same way each time it is
+	// applied
+	ErrLedgerInvalid = errors.New("invalid ledger")
+)
+
+// errEventNotApplicable is wrapped by errors applying an event that are caused by the event rather than the store,
+// such as a vote on a proposal that doesn't exist. Applying the event again fails the same way, so it is skipped.
+var errEventNotApplicable = errors.New("event not applicable")
+
 type Indexer struct {
-	store *db.Store
+	store Store
+	// batched is true if ApplyLedger applies the events of each proposal in a ledger together, rather than one at a
+	// time
+	batched bool
+	// now returns the time recorded as a proposal's UpdatedAt
+	now func() time.Time
+	// blocklist is checked for the contract of each event before it is parsed
+	blocklist *Blocklist
+	// tracked are the contracts whose failed transactions and unknown event types are recorded
+	tracked trackedContracts
+	// parseFailureWarnPercent is the percentage of a contract's recent governor events that must fail before a
+	// warning is logged by RecordContractEvents. If 0, no warning is logged.
+	parseFailureWarnPercent int
+	// parseWindows are the recent governor events of each registered contract, by contract id
+	parseWindows map[string]*parseWindow
+	// failedTxEvents is true if ApplyLedger records the governor events of tracked contracts emitted by failed
+	// transactions. See recordFailedTxEvents.
+	failedTxEvents bool
+	// errorLogSize is the number of processing errors kept in the indexer_errors table. If 0, errors are only
+	// logged. See recordError.
+	errorLogSize int
+	// strictEventOrder is true if events older than the last event applied for their contract fail to apply,
+	// instead of only being logged and counted. See checkEventOrder.
+	strictEventOrder bool
+	// contractDiscovery is true if contracts registered by the indexer are unreviewed until an operator approves
+	// them, so the API hides them
+	contractDiscovery bool
+	// retryDelay is how long ingest waits before processing a ledger again after a retryable error
+	retryDelay time.Duration
 }
 
-func NewIndexer(store *db.Store) *Indexer {
-	return &Indexer{store: store}
+func NewIndexer(store Store) *Indexer {
+	return &Indexer{store: store, now: time.Now, blocklist: NewBlocklist(store, DEFAULT_BLOCKLIST_REFRESH_INTERVAL), errorLogSize: DEFAULT_ERROR_LOG_SIZE, retryDelay: DB_RETRY_DELAY}
 }
 
-// ApplyLedger processes all transactions in a ledger and applies relevant governor events to the db
-func (idx *Indexer) ApplyLedger(ctx context.Context, txReader *ingest.LedgerTransactionReader, ledgerSeq uint32, ledgerCloseTime int64) (int, error) {
-	txCount := 0
+// ApplyLedger processes all transactions in a ledger and applies relevant governor events to the db. The returned
+// stats count the work done, even if an error is returned.
+//
+// Events that can't be applied are logged, recorded in the indexer_errors table, and skipped. Store failures,
+// including database timeouts, fail the ledger with an error wrapping ErrLedgerRetryable, so the ledger is never
+// recorded as processed without all of its events. A ledger whose transactions can't be read fails with an error
+// wrapping ErrLedgerInvalid. If the indexer enforces event order, an event older than the last event applied
+// for its contract fails the ledger with an error wrapping ErrEventOutOfOrder.
+//
+// If the indexer is batched, the events are applied once every transaction has been read, with the events of each
+// proposal applied together. See applyBatch.
+//
+// Failed transactions are skipped, unless the indexer records the events they emitted for analytics, in which case
+// those are written apart from the history. See recordFailedTxEvents.
+//
+// Events from contracts on the blocklist are skipped before they are parsed. The blocklist is cached in memory, and
+// reloaded at most once every refresh interval, so a newly blocked contract may have events applied until then.
+func (idx *Indexer) ApplyLedger(ctx context.Context, txReader *ingest.LedgerTransactionReader, ledgerSeq uint32, ledgerCloseTime int64) (LedgerStats, error) {
+	stats, err := idx.applyLedger(ctx, txReader, ledgerSeq, ledgerCloseTime)
+	if err != nil {
+		idx.recordError(ctx, ledgerSeq, "", err)
+	}
+	if err != nil && !errors.Is(err, ErrLedgerInvalid) && !errors.Is(err, ErrEventOutOfOrder) {
+		return stats, fmt.Errorf("%w: %w", ErrLedgerRetryable, err)
+	}
+	return stats, err
+}
+
+// applyLedger applies a ledger as described by ApplyLedger. Only store errors, ErrLedgerInvalid, and
+// ErrEventOutOfOrder are returned.
+func (idx *Indexer) applyLedger(ctx context.Context, txReader *ingest.LedgerTransactionReader, ledgerSeq uint32, ledgerCloseTime int64) (LedgerStats, error) {
+	stats := LedgerStats{Ledgers: 1}
+	if err := idx.blocklist.Refresh(ctx); errors.Is(err, db.ErrTimeout) {
+		return stats, err
+	} else if err != nil {
+		slog.Error("Failed refreshing contract blocklist, using the last loaded blocklist", "ledger", ledgerSeq, "err", err)
+	}
+	if err := idx.tracked.load(ctx, idx.store); err != nil {
+		return stats, err
+	}
+	// the events to apply together at the end of the ledger, if batched
+	var batch []*governor.GovernorEvent
+	// the contracts that emitted governor events earlier in the ledger, which may become tracked once they are applied
+	var ledgerContracts map[xdr.ContractId]bool
+	mayBeTracked := func(event *xdr.ContractEvent) bool {
+		return idx.tracked.containsEvent(event) || (event.ContractId != nil && ledgerContracts[*event.ContractId])
+	}
 	for {
 		tx, err := txReader.Read()
 		if err != nil {
 			if err == io.EOF {
 				break
 			} else {
-				return txCount, fmt.Errorf("failed to read ledger transaction: %w", err)
+				return stats, fmt.Errorf("%w: failed to read ledger transaction: %w", ErrLedgerInvalid, err)
 			}
 		}
-		txCount++
+		stats.Transactions++
 
 		if !tx.Successful() {
+			if idx.failedTxEvents {
+				if err := idx.recordFailedTxEvents(ctx, tx, ledgerSeq, ledgerCloseTime, &stats); err != nil {
+					return stats, err
+				}
+			}
 			continue
 		}
-
-		// currently, only process events from InvokeHostFunction operations, which must be the one and only operation
-		op_0, ok := tx.GetOperation(0)
-		if !ok {
-			continue
+		govEvents, failedEvents := parseTransaction(tx, ledgerSeq, ledgerCloseTime, &stats, idx.blocklist.containsEvent, mayBeTracked)
+		for _, govEvent := range govEvents {
+			if raw, err := strkey.Decode(strkey.VersionByteContract, govEvent.ContractId); err == nil {
+				if ledgerContracts == nil {
+					ledgerContracts = make(map[xdr.ContractId]bool)
+				}
+				ledgerContracts[xdr.ContractId(raw)] = true
+			}
 		}
-		if op_0.Body.Type != xdr.OperationTypeInvokeHostFunction {
-			continue
+		if len(govEvents) > 0 {
+			// record votes contract and settings changes before applying events, so delegation events in the same
+			// ledger are kept
+			if err := idx.updateGovernorInstances(ctx, tx, ledgerSeq, govEvents); err != nil {
+				return stats, err
+			}
+		}
+		for _, failedEvent := range failedEvents {
+			if failedEvent.Reason == governor.FAILED_REASON_UNKNOWN_EVENT_TYPE {
+				// the contract may become tracked by the events before it, so apply them first
+				if len(batch) > 0 {
+					if err := idx.applyBatch(ctx, batch, &stats); err != nil {
+						return stats, err
+					}
+					batch = nil
+				}
+				if !idx.tracked.contains(failedEvent.ContractId) {
+					continue
+				}
+				slog.Warn("Tracked contract emitted an unknown event type", "ledger", ledgerSeq, "hash", failedEvent.TxHash, "contract", failedEvent.ContractId, "type", failedEvent.EventType)
+				stats.UnknownEventTypes++
+				stats.FailedEvents++
+				stats.countContractEvent(failedEvent.ContractId, false)
+			}
+			if err := idx.insertFailedEvent(ctx, failedEvent); err != nil {
+				return stats, err
+			}
 		}
+		for _, govEvent := range govEvents {
+			if governor.IsDelegationEventType(govEvent.EventType) {
+				// any contract can emit delegation events, so only keep those from a governor's votes contract
+				watched, err := idx.store.IsVotesContract(ctx, govEvent.ContractId)
+				if err != nil {
+					return stats, fmt.Errorf("failed checking votes contracts: %w", err)
+				}
+				if !watched {
+					slog.Debug("Skipping delegation event from unknown votes contract", "ledger", ledgerSeq, "hash", govEvent.TxHash, "contract", govEvent.ContractId)
+					continue
+				}
+			}
 
-		events, err := tx.GetContractEvents()
-		if err != nil {
-			slog.Error("Failed getting events for tx", "ledger", ledgerSeq, "hash", tx.Hash, "err", err)
-			continue
+			if idx.batched {
+				batch = append(batch, govEvent)
+				continue
+			}
+			if err := idx.applyLedgerEvent(ctx, govEvent, &stats); err != nil {
+				return stats, err
+			}
 		}
+	}
+	if len(batch) > 0 {
+		if err := idx.applyBatch(ctx, batch, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
 
-		toidInt := toid.New(int32(ledgerSeq), int32(tx.Index), 0).ToInt64()
+// applyLedgerEvent applies a governor event of a ledger with ApplyEvent, and adds its effects to stats. Store errors
+// are returned, so the ledger is retried; errors caused by the event are logged, and the event is skipped.
+func (idx *Indexer) applyLedgerEvent(ctx context.Context, govEvent *governor.GovernorEvent, stats *LedgerStats) error {
+	if err := idx.checkLedgerEventOrder(ctx, govEvent); err != nil {
+		return err
+	}
+	effects, err := idx.apply(ctx, govEvent)
+	if errors.Is(err, governor.ErrInvalidEventData) {
+		slog.Warn("Governor event has invalid event data", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId, "type", govEvent.EventType, "err", err)
+		return idx.insertRejectedEvent(ctx, govEvent, governor.FAILED_REASON_INVALID_EVENT_DATA, err, stats)
+	} else if errors.Is(err, governor.ErrProposalConflict) {
+		idx.warnProposalConflict(govEvent, err)
+		stats.ProposalConflicts++
+		return idx.insertRejectedEvent(ctx, govEvent, governor.FAILED_REASON_PROPOSAL_CONFLICT, err, stats)
+	} else if isEventError(err) {
+		slog.Error("Failed applying event to db", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "event", govEvent, "err", err)
+		idx.recordError(ctx, govEvent.LedgerSeq, govEvent.TxHash, fmt.Errorf("failed applying event %s: %w", govEvent.EventId, err))
+		return nil
+	} else if err != nil {
+		// the store failed, so fail the ledger so it is retried. ApplyEvent is idempotent.
+		return fmt.Errorf("failed applying event %s: %w", govEvent.EventId, err)
+	}
+	stats.addEffects(effects)
+	return nil
+}
 
-		for event_index, event := range events {
-			govEvent, err := governor.NewGovernorEventFromContractEvent(&event, tx.Hash.HexString(), ledgerSeq, int64(ledgerCloseTime), toidInt, int32(event_index))
-			if err != nil {
-				// only log failures for events if we think it is a governor event
-				if errors.Is(err, governor.ErrEventParsingFailed) {
-					eventStr, xdrErr := xdr.MarshalBase64(event)
-					if xdrErr != nil {
-						slog.Error("Failed parsing and unable to marshal xdr", "ledger", ledgerSeq, "hash", tx.Hash.HexString(), "xdrErr", xdrErr)
-					} else {
-						slog.Error("Failed parsing event", "ledger", ledgerSeq, "hash", tx.Hash.HexString(), "event", eventStr, "err", err)
-					}
-				}
+// isEventError returns true if an error applying an event is caused by the event rather than the store. Proposals
+// with invalid vote tallies are rejected by the store, but only an event can make a tally invalid.
+func isEventError(err error) bool {
+	return errors.Is(err, errEventNotApplicable) || errors.Is(err, governor.ErrInvalidAmount)
+}
+
+// ParseTransaction returns the governor events emitted by a transaction, and the governor events that can't be
+// indexed, such as events with an unknown schema version. Other events that fail to parse are logged and skipped.
+// The contract events seen, governor events parsed, failed events, and parse failures are added to stats, with the
+// governor events of each contract.
+//
+// Events with an unknown event type are also returned as failed events, but are not counted in stats. Any contract
+// can emit them, so they are only recorded by ApplyLedger if the contract is tracked.
+//
+// Fee bump transactions are parsed from their inner transaction, which holds the operations that were applied.
+// Their events are recorded with the fee bump (outer) transaction hash, as that is the hash included in the
+// ledger and the one reported by Stellar RPC's getEvents and getTransaction. The inner hash is never stored.
+// Events are recorded with the source account of the inner transaction, which submitted it, rather than the fee payer.
+func ParseTransaction(tx ingest.LedgerTransaction, ledgerSeq uint32, ledgerCloseTime int64, stats *LedgerStats) ([]*governor.GovernorEvent, []*governor.FailedEvent) {
+	return parseTransaction(tx, ledgerSeq, ledgerCloseTime, stats, nil, nil)
+}
+
+// operationEvent is a contract event with the toid of the operation that emitted it, and its index in the operation
+type operationEvent struct {
+	event xdr.ContractEvent
+	toid  int64
+	index int32
+}
+
+// parseTransaction parses a transaction as described by ParseTransaction. Events for which skip, if not nil, returns
+// true are skipped before they are parsed. Events with an unknown event type are only returned if mayBeTracked, if
+// not nil, returns true for them, or their contract emitted a governor event earlier in the transaction, so events
+// from the rest of the network are dropped without being recorded. Parse failures of events from the rest of the
+// network are only logged at debug level, as any contract can emit events named like governor events.
+func parseTransaction(tx ingest.LedgerTransaction, ledgerSeq uint32, ledgerCloseTime int64, stats *LedgerStats, skip func(event *xdr.ContractEvent) bool, mayBeTracked func(event *xdr.ContractEvent) bool) ([]*governor.GovernorEvent, []*governor.FailedEvent) {
+	if !tx.Successful() {
+		return nil, nil
+	}
+
+	// currently, only process events from InvokeHostFunction operations, which must be the one and only operation.
+	// For fee bumps, this reads the operations of the inner transaction.
+	op_0, ok := tx.GetOperation(0)
+	if !ok {
+		return nil, nil
+	}
+	if op_0.Body.Type != xdr.OperationTypeInvokeHostFunction {
+		return nil, nil
+	}
+
+	txEvents, err := tx.GetTransactionEvents()
+	if err != nil {
+		slog.Error("Failed getting events for tx", "ledger", ledgerSeq, "hash", tx.Hash, "err", err)
+		return nil, nil
+	}
+
+	// event ids match Stellar RPC, which uses the toid of the operation that emitted the event, from the
+	// transaction's application order in the ledger and the operation's index in the transaction, followed by the
+	// event's index within the operation. A fee bump and its inner transaction share a single position, so this is
+	// the same for both.
+	var events []operationEvent
+	for opIndex, opEvents := range txEvents.OperationEvents {
+		opToid := toid.New(int32(ledgerSeq), int32(tx.Index), int32(opIndex)).ToInt64()
+		for eventIndex, event := range opEvents {
+			events = append(events, operationEvent{event: event, toid: opToid, index: int32(eventIndex)})
+		}
+	}
+	// tx.Hash is the outer hash for fee bump transactions
+	txHash := tx.Hash.HexString()
+	// the inner transaction's source account for fee bump transactions, which is the account that submitted it
+	sourceAccount := tx.Envelope.SourceAccount().ToAccountId().Address()
+	stats.ContractEvents += len(events)
+
+	var govEvents []*governor.GovernorEvent
+	var failedEvents []*governor.FailedEvent
+	var unknownEvents []*governor.FailedEvent
+	// isTracked returns true if the contract that emitted event may be tracked
+	isTracked := func(event *xdr.ContractEvent) bool {
+		return mayBeTracked == nil || mayBeTracked(event) || emittedGovernorEvent(govEvents, event)
+	}
+	for _, opEvent := range events {
+		event := opEvent.event
+		if skip != nil && skip(&event) {
+			slog.Debug("Skipping event from blocked contract", "ledger", ledgerSeq, "hash", txHash, "toid", opEvent.toid, "event_index", opEvent.index)
+			continue
+		}
+		govEvent, err := governor.NewGovernorEventFromContractEvent(&event, txHash, ledgerSeq, ledgerCloseTime, opEvent.toid, opEvent.index)
+		if errors.Is(err, governor.ErrUnknownSchemaVersion) {
+			// keep the raw event, so it can be replayed once the schema version is supported
+			failedEvent, failedErr := governor.NewFailedEvent(&event, governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION, err, txHash, ledgerSeq, ledgerCloseTime, opEvent.toid, opEvent.index)
+			if failedErr != nil {
+				slog.Error("Failed recording event with unknown schema version", "ledger", ledgerSeq, "hash", txHash, "err", failedErr)
 				continue
 			}
-
-			applyErr := idx.ApplyEvent(ctx, govEvent)
-			if applyErr != nil {
-				slog.Error("Failed applying event to db", "ledger", ledgerSeq, "hash", tx.Hash.HexString(), "event", govEvent, "err", applyErr)
+			slog.Warn("Governor event has an unknown schema version", "ledger", ledgerSeq, "hash", txHash, "eventId", failedEvent.EventId, "err", err)
+			stats.countContractEvent(failedEvent.ContractId, false)
+			failedEvents = append(failedEvents, failedEvent)
+			continue
+		} else if errors.Is(err, governor.ErrInvalidAmount) {
+			// negative amounts are never applied, but are kept to investigate the contract
+			failedEvent, failedErr := governor.NewFailedEvent(&event, governor.FAILED_REASON_INVALID_AMOUNT, err, txHash, ledgerSeq, ledgerCloseTime, opEvent.toid, opEvent.index)
+			if failedErr != nil {
+				slog.Error("Failed recording event with invalid amount", "ledger", ledgerSeq, "hash", txHash, "err", failedErr)
 				continue
 			}
+			slog.Warn("Governor event has an invalid amount", "ledger", ledgerSeq, "hash", txHash, "eventId", failedEvent.EventId, "err", err)
+			stats.countContractEvent(failedEvent.ContractId, false)
+			failedEvents = append(failedEvents, failedEvent)
+			continue
+		} else if errors.Is(err, governor.ErrUnknownEventType) {
+			// the contract may be a governor with a newer release. The caller checks the contract is tracked
+			// before recording the event.
+			if !isTracked(&event) {
+				continue
+			}
+			failedEvent, failedErr := governor.NewFailedEvent(&event, governor.FAILED_REASON_UNKNOWN_EVENT_TYPE, err, txHash, ledgerSeq, ledgerCloseTime, opEvent.toid, opEvent.index)
+			if failedErr != nil {
+				slog.Error("Failed recording event with unknown type", "ledger", ledgerSeq, "hash", txHash, "err", failedErr)
+				continue
+			}
+			unknownEvents = append(unknownEvents, failedEvent)
+			continue
+		} else if errors.Is(err, governor.ErrInvalidEventId) {
+			// the event's position can't be encoded in an id that sorts in event order, so it can't be indexed
+			slog.Error("Failed encoding event id", "ledger", ledgerSeq, "hash", txHash, "toid", opEvent.toid, "event_index", opEvent.index, "err", err)
+			continue
+		} else if err != nil {
+			// only log failures for events if we think it is a governor event
+			if errors.Is(err, governor.ErrEventParsingFailed) {
+				stats.ParseFailures++
+				if contractId, err := strkey.Encode(strkey.VersionByteContract, event.ContractId[:]); err == nil {
+					stats.countContractEvent(contractId, false)
+				}
+				level := slog.LevelError
+				if !isTracked(&event) {
+					level = slog.LevelDebug
+				}
+				eventStr, xdrErr := xdr.MarshalBase64(event)
+				if xdrErr != nil {
+					slog.Log(context.Background(), level, "Failed parsing and unable to marshal xdr", "ledger", ledgerSeq, "hash", txHash, "xdrErr", xdrErr)
+				} else {
+					slog.Log(context.Background(), level, "Failed parsing event", "ledger", ledgerSeq, "hash", txHash, "event", eventStr, "err", err)
+				}
+			}
+			continue
+		}
+		stats.countContractEvent(govEvent.ContractId, true)
+		govEvent.SourceAccount = sourceAccount
+		// keep the raw event, for clients that parse events themselves. The event is still indexed without it.
+		if govEvent.EventXdr, err = xdr.MarshalBase64(event); err != nil {
+			slog.Error("Failed marshalling governor event xdr", "ledger", ledgerSeq, "hash", txHash, "eventId", govEvent.EventId, "err", err)
 		}
+		govEvents = append(govEvents, govEvent)
+	}
+	stats.GovernorEvents += len(govEvents)
+	stats.FailedEvents += len(failedEvents)
+	return govEvents, append(failedEvents, unknownEvents...)
+}
+
+// emittedGovernorEvent returns true if the contract that emitted event also emitted one of govEvents
+func emittedGovernorEvent(govEvents []*governor.GovernorEvent, event *xdr.ContractEvent) bool {
+	if len(govEvents) == 0 || event.ContractId == nil {
+		return false
 	}
-	return txCount, nil
+	contractId, err := strkey.Encode(strkey.VersionByteContract, event.ContractId[:])
+	return err == nil && slices.ContainsFunc(govEvents, func(e *governor.GovernorEvent) bool { return e.ContractId == contractId })
+}
+
+// updateGovernorInstances records the votes contract and settings of each governor that emitted an event in the
+// transaction, read from its contract instance. Store errors are returned, so the ledger is retried; a transaction
+// whose changes can't be read is logged.
+func (idx *Indexer) updateGovernorInstances(ctx context.Context, tx ingest.LedgerTransaction, ledgerSeq uint32, govEvents []*governor.GovernorEvent) error {
+	changes, err := tx.GetChanges()
+	if err != nil {
+		slog.Error("Failed getting ledger entry changes for tx", "ledger", ledgerSeq, "hash", tx.Hash, "err", err)
+		return nil
+	}
+	emittedEvents := func(governorId string) bool {
+		return slices.ContainsFunc(govEvents, func(e *governor.GovernorEvent) bool {
+			return e.ContractId == governorId && !governor.IsDelegationEventType(e.EventType)
+		})
+	}
+	for _, change := range changes {
+		if change.Post == nil || change.Type != xdr.LedgerEntryTypeContractData {
+			continue
+		}
+		if governorId, votesId, ok := governor.VotesContractFromLedgerEntry(change.Post); ok && emittedEvents(governorId) {
+			if err := idx.store.UpsertVotesContract(ctx, governorId, votesId, ledgerSeq); err != nil {
+				return fmt.Errorf("failed recording votes contract for %s: %w", governorId, err)
+			}
+			idx.tracked.add(governorId)
+			idx.tracked.add(votesId)
+		}
+		if settings, ok := governor.SettingsFromLedgerEntry(change.Post); ok && emittedEvents(settings.GovernorId) {
+			settings.LedgerSeq = ledgerSeq
+			if err := idx.store.UpsertGovernorSettings(ctx, settings); err != nil {
+				return fmt.Errorf("failed recording settings for %s: %w", settings.GovernorId, err)
+			}
+		}
+	}
+	return nil
+}
+
+// insertFailedEvent records a governor event that could not be indexed. Events from blocked contracts are skipped
+// before they are parsed, so are never recorded. Store errors are returned, so the ledger is retried rather than
+// losing the event.
+func (idx *Indexer) insertFailedEvent(ctx context.Context, failedEvent *governor.FailedEvent) error {
+	if err := idx.store.InsertFailedEvent(ctx, failedEvent); err != nil {
+		return fmt.Errorf("failed recording failed event %s: %w", failedEvent.EventId, err)
+	}
+	return nil
+}
+
+// insertRejectedEvent records a parsed governor event that was rejected with err as a failed event, and adds it to
+// stats. Store errors are returned, as with insertFailedEvent.
+func (idx *Indexer) insertRejectedEvent(ctx context.Context, govEvent *governor.GovernorEvent, reason string, err error, stats *LedgerStats) error {
+	stats.FailedEvents++
+	return idx.insertFailedEvent(ctx, governor.NewFailedEventFromGovernorEvent(govEvent, reason, err))
+}
+
+// warnProposalConflict logs a proposal_created event that conflicts with an existing proposal. Either the contract
+// reused a proposal id, or the indexed history no longer matches the chain, so it needs to be investigated.
+func (idx *Indexer) warnProposalConflict(govEvent *governor.GovernorEvent, err error) {
+	slog.Warn("Proposal created event conflicts with existing proposal", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId, "contract", govEvent.ContractId, "proposal", govEvent.ProposalId, "err", err)
 }
 
 // ApplyEvent processes a GovernorEvent and applies changes to aggregated tables
 //
-// It is assumed that the event already exists in the event history table
+// The event is always recorded in the event history table, even if applying it fails. Changes to the aggregated
+// tables, and the contract's last activity in the contracts registry, are made in a single transaction, so a failed
+// event leaves no partial changes behind. Failures are also recorded in the indexer_errors table.
+//
+// Events older than the last event applied for their contract, that are not in the history yet, are logged and
+// counted, or fail with ErrEventOutOfOrder if the indexer enforces event order. See checkEventOrder.
 func (idx *Indexer) ApplyEvent(ctx context.Context, govEvent *governor.GovernorEvent) error {
+	err := idx.checkEventOrder(ctx, govEvent)
+	if err == nil {
+		_, err = idx.apply(ctx, govEvent)
+	}
+	if err != nil {
+		idx.recordError(ctx, govEvent.LedgerSeq, govEvent.TxHash, fmt.Errorf("failed applying event %s: %w", govEvent.EventId, err))
+	}
+	return err
+}
+
+// apply applies a GovernorEvent as described by ApplyEvent, and returns the changes made to the aggregated tables
+func (idx *Indexer) apply(ctx context.Context, govEvent *governor.GovernorEvent) (eventEffects, error) {
 	slog.Info("Applying event", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId)
 	// store the event into the event history
 	// this (eventually) should be functional to replay / rehydrate the aggregated db services
 	// its also dupe safe, so running this for an event that already exists is a no-op
 	err := idx.store.InsertEvent(ctx, govEvent)
 	if err != nil {
-		return fmt.Errorf("failed to insert event into history: %w", err)
+		return eventEffects{}, fmt.Errorf("failed to insert event into history: %w", err)
 	}
 
-	// check if the proposal exists
-	proposal, err := idx.store.GetProposal(ctx, governor.EncodeProposalKey(govEvent.ContractId, govEvent.ProposalId))
+	var effects eventEffects
+	err = idx.withConflictRetry(ctx, govEvent.EventId, func(ctx context.Context) error {
+		effects, err = idx.applyEventActivity(ctx, govEvent)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error when attempting to get proposal from store: %w", err)
+		return eventEffects{}, err
 	}
+	return effects, nil
+}
 
-	switch govEvent.EventType {
-	case "proposal_created":
-		if proposal == nil {
-			proposal, err = governor.NewProposalFromProposalCreatedEvent(govEvent)
-			if err != nil {
-				return fmt.Errorf("failed to create proposal from event: %w", err)
-			}
-		} else {
-			return fmt.Errorf("proposal_created event for existing proposal %v status: %d", proposal.ProposalKey, proposal.Status)
-		}
-	case "proposal_canceled":
-		if proposal == nil {
-			return fmt.Errorf("proposal_canceled event for non-existing proposal %s-%d", govEvent.ContractId, govEvent.ProposalId)
-		} else if proposal.Status != 0 {
-			slog.Info("proposal_canceled event for proposal not in active state", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", proposal.Status)
-			return nil
-		}
-		proposal.Status = 5
-	case "proposal_voting_closed":
-		if proposal == nil {
-			return fmt.Errorf("proposal_voting_closed event for non-existing proposal %s-%d", govEvent.ContractId, govEvent.ProposalId)
-		} else if proposal.Status != 0 {
-			slog.Info("proposal_voting_closed event for proposal not in active state", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", proposal.Status)
-			return nil
-		}
-		var votingClosedData *governor.ProposalVotingClosedData
-		err = json.Unmarshal([]byte(govEvent.EventData), &votingClosedData)
-		if err != nil {
-			return fmt.Errorf("unable to unmarshal proposal_voting_closed event data: %w", err)
-		}
-		proposal.Status = votingClosedData.Status
-		proposal.VotesFor = votingClosedData.FinalVotes.For
-		proposal.VotesAgainst = votingClosedData.FinalVotes.Against
-		proposal.VotesAbstain = votingClosedData.FinalVotes.Abstain
-		proposal.ExecutionUnlock = votingClosedData.Eta
-	case "proposal_executed":
-		if proposal == nil {
-			return fmt.Errorf("proposal_executed event for non-existing proposal %s-%d", govEvent.ContractId, govEvent.ProposalId)
-		} else if proposal.Status == 4 {
-			slog.Info("proposal_executed event for proposal that has already been executed", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "execution_tx_hash", proposal.ExecutionTxHash)
-			return nil
-		}
-		proposal.Status = 4
-		proposal.ExecutionTxHash = govEvent.TxHash
-	case "proposal_expired":
-		if proposal == nil {
-			return fmt.Errorf("proposal_expired event for non-existing proposal %s-%d", govEvent.ContractId, govEvent.ProposalId)
-		} else if proposal.Status != 0 && proposal.Status != 1 {
-			slog.Info("proposal_expired event for proposal not in active state", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", proposal.Status)
-			return nil
-		}
-		proposal.Status = 3
-	case "vote_cast":
-		if proposal == nil {
-			return fmt.Errorf("vote_cast event for non-existing proposal %s-%d", govEvent.ContractId, govEvent.ProposalId)
-		} else if proposal.Status != 0 {
-			slog.Info("vote_cast event for proposal not in active state", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", proposal.Status)
-			return nil
-		}
-		var voteCastData *governor.VoteCastData
-		err = json.Unmarshal([]byte(govEvent.EventData), &voteCastData)
-		if err != nil {
-			return fmt.Errorf("unable to unmarshal vote_cast event data: %w", err)
+// applyEventActivity applies a GovernorEvent to the aggregated tables with applyEvent, then records the contract's
+// last activity. Delegation events are emitted by votes contracts, so only governor events are recorded as activity.
+func (idx *Indexer) applyEventActivity(ctx context.Context, govEvent *governor.GovernorEvent) (eventEffects, error) {
+	effects, err := idx.applyEvent(ctx, govEvent)
+	if err != nil || governor.IsDelegationEventType(govEvent.EventType) {
+		return effects, err
+	}
+	return effects, idx.store.UpsertContractActivity(ctx, govEvent.ContractId, govEvent.EventId, govEvent.LedgerSeq, govEvent.LedgerCloseTime, !idx.contractDiscovery)
+}
+
+// withConflictRetry runs fn in a transaction. If a proposal read by fn was modified by a concurrent writer before fn
+// wrote it, fn is run again in a new transaction, up to APPLY_CONFLICT_RETRIES times in total.
+func (idx *Indexer) withConflictRetry(ctx context.Context, eventId string, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := idx.store.WithTx(ctx, fn)
+		// the proposal was modified by a concurrent writer after it was read, so re-read and apply again
+		if errors.Is(err, db.ErrConflict) && attempt < APPLY_CONFLICT_RETRIES {
+			slog.Warn("Conflict applying event, retrying", "eventId", eventId, "attempt", attempt, "err", err)
+			continue
 		}
+		return err
+	}
+}
 
-		curVote, err := idx.store.GetVote(ctx, govEvent.TxHash)
+// applyEvent applies a GovernorEvent to the aggregated tables, and returns the changes made
+func (idx *Indexer) applyEvent(ctx context.Context, govEvent *governor.GovernorEvent) (eventEffects, error) {
+	if governor.IsDelegationEventType(govEvent.EventType) {
+		return idx.applyDelegationEvent(ctx, govEvent)
+	}
+
+	// check if the proposal exists
+	proposal, version, err := idx.store.GetProposalVersion(ctx, governor.EncodeProposalKey(govEvent.ContractId, govEvent.ProposalId))
+	exists := true
+	if errors.Is(err, db.ErrNotFound) {
+		proposal = &governor.Proposal{}
+		exists = false
+	} else if err != nil {
+		return eventEffects{}, fmt.Errorf("error when attempting to get proposal from store: %w", err)
+	}
+	status := proposal.Status
+
+	vote, changed, err := governor.ApplyEventToProposal(proposal, govEvent)
+	if err != nil {
+		return eventEffects{}, fmt.Errorf("%w: %w", errEventNotApplicable, err)
+	}
+	if !changed {
+		slog.Info(govEvent.EventType+" event does not apply to proposal status", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", status)
+		return eventEffects{}, nil
+	}
+	proposal.UpdatedAt = idx.now().Unix()
+
+	if vote != nil {
+		_, err = idx.store.GetVote(ctx, vote.TxHash)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return eventEffects{}, fmt.Errorf("error when attempting to get vote from store: %w", err)
+		}
+		if err == nil {
+			slog.Info("vote_cast event already applied", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", status)
+			return eventEffects{}, nil
+		}
+		err = idx.store.InsertVote(ctx, vote)
 		if err != nil {
-			return fmt.Errorf("error when attempting to get vote from store: %w", err)
+			return eventEffects{}, fmt.Errorf("failed to insert vote into store: %w", err)
 		}
-		if curVote != nil {
-			slog.Info("vote_cast event already applied", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "proposal", proposal.ProposalKey, "current_status", proposal.Status)
-			return nil
+	}
+	if govEvent.EventType == "proposal_voting_closed" {
+		if err := idx.flagLowParticipation(ctx, proposal); err != nil {
+			return eventEffects{}, err
 		}
-
-		amountBig, ok := new(big.Int).SetString(voteCastData.Amount, 10)
-		if !ok {
-			return fmt.Errorf("invalid amount string %s in vote_cast event", voteCastData.Amount)
+	}
+	if !exists {
+		err = idx.store.InsertProposal(ctx, proposal)
+		if err != nil {
+			return eventEffects{}, fmt.Errorf("failed to insert new proposal into store: %w", err)
 		}
-
-		switch voteCastData.Support {
-		case 0:
-			// against
-			totalAgainst, ok := new(big.Int).SetString(proposal.VotesAgainst, 10)
-			if !ok {
-				return fmt.Errorf("invalid votes_against string %s in proposal %s", proposal.VotesAgainst, proposal.ProposalKey)
-			}
-			totalAgainst.Add(totalAgainst, amountBig)
-			proposal.VotesAgainst = totalAgainst.String()
-
-		case 1:
-			// for
-			totalFor, ok := new(big.Int).SetString(proposal.VotesFor, 10)
-			if !ok {
-				return fmt.Errorf("invalid votes_for string %s in proposal %s", proposal.VotesFor, proposal.ProposalKey)
-			}
-			totalFor.Add(totalFor, amountBig)
-			proposal.VotesFor = totalFor.String()
-		case 2:
-			// abstain
-			totalAbstain, ok := new(big.Int).SetString(proposal.VotesAbstain, 10)
-			if !ok {
-				return fmt.Errorf("invalid votes_abstain string %s in proposal %s", proposal.VotesAbstain, proposal.ProposalKey)
-			}
-			totalAbstain.Add(totalAbstain, amountBig)
-			proposal.VotesAbstain = totalAbstain.String()
-		default:
-			return fmt.Errorf("invalid support value %d in vote_cast event", voteCastData.Support)
+		idx.tracked.add(proposal.ContractId)
+	} else {
+		err = idx.store.UpdateProposal(ctx, proposal, version)
+		if err != nil {
+			return eventEffects{}, fmt.Errorf("failed to update proposal in store: %w", err)
 		}
+	}
+	slog.Info("Event applied successfully", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId)
+	return eventEffects{proposalMutated: true, voteInserted: vote != nil}, nil
+}
+
+// flagLowParticipation flags a proposal whose voting closed if its participation is below the threshold, see
+// governor.IsLowParticipation. Voters are counted from the votes table, so the proposal's votes must be inserted
+// first.
+func (idx *Indexer) flagLowParticipation(ctx context.Context, proposal *governor.Proposal) error {
+	voters, err := idx.store.CountVoters(ctx, proposal.ContractId, proposal.ProposalId)
+	if err != nil {
+		return fmt.Errorf("failed to count voters: %w", err)
+	}
+	flagged, err := governor.IsLowParticipation(proposal, voters)
+	if err != nil {
+		return fmt.Errorf("%w: failed to check participation: %w", errEventNotApplicable, err)
+	}
+	if flagged {
+		slog.Info("Flagging proposal with low participation", "proposal", proposal.ProposalKey, "voters", voters)
+	}
+	proposal.FlaggedLowParticipation = flagged
+	return nil
+}
 
-		vote, err := governor.NewVoteFromVoteCastEvent(govEvent)
+// applyDelegationEvent applies a delegation event to the delegations table. Changes in delegated votes are
+// only kept in the event history.
+func (idx *Indexer) applyDelegationEvent(ctx context.Context, govEvent *governor.GovernorEvent) (eventEffects, error) {
+	switch govEvent.EventType {
+	case "delegate_changed":
+		delegation, err := governor.NewDelegationFromDelegateChangedEvent(govEvent)
 		if err != nil {
-			return fmt.Errorf("failed to create vote from event: %w", err)
+			return eventEffects{}, fmt.Errorf("%w: failed to create delegation from event: %w", errEventNotApplicable, err)
 		}
-		err = idx.store.InsertVote(ctx, vote)
+		err = idx.store.InsertDelegation(ctx, delegation)
 		if err != nil {
-			return fmt.Errorf("failed to insert vote into store: %w", err)
+			return eventEffects{}, fmt.Errorf("failed to insert delegation into store: %w", err)
 		}
+	case "delegate_votes_changed":
+		// no aggregated data
 	default:
-		return fmt.Errorf("invalid event type %s", govEvent.EventType)
-	}
-	err = idx.store.UpsertProposal(ctx, proposal)
-	if err != nil {
-		return fmt.Errorf("failed to insert new proposal into store: %w", err)
+		return eventEffects{}, fmt.Errorf("%w: invalid event type %s", errEventNotApplicable, govEvent.EventType)
 	}
 	slog.Info("Event applied successfully", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId)
-	return nil
+	return eventEffects{}, nil
 }
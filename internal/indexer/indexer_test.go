@@ -3,14 +3,22 @@ package indexer
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/script3/soroban-governor-backend/internal/db"
 	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/xdr"
 )
 
 // the DB's initial state. Placed at global scope so it can be reused across tests.
@@ -20,7 +28,9 @@ var (
 	ledgerSeq       = uint32(1170234)
 	ledgerCloseTime = int64(1761053041)
 	testContractId  = "CDAO6Q5MAFH2A5PMQORP5G56UWDDJ5THCHU2GXWEJ6V75VXCPU2PZYPB"
-	initHistory     = []*governor.GovernorEvent{
+	// testNow is the indexer's clock in tests that check UpdatedAt
+	testNow     = time.Unix(1761053100, 0)
+	initHistory = []*governor.GovernorEvent{
 		{
 			EventId:         "0005025695851876451-0000000042",
 			ContractId:      testContractId,
@@ -42,6 +52,7 @@ var (
 			Title:           "Unicorns are real",
 			Description:     "They live in the clouds",
 			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:      governor.ACTION_TYPE_COUNCIL,
 			VoteStart:       ledgerSeq - 10000,
 			VoteEnd:         ledgerSeq,
 			VotesFor:        "12314122341234",
@@ -59,6 +70,7 @@ var (
 			Title:           "Unicorns are fake",
 			Description:     "They don't live anywhere",
 			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:      governor.ACTION_TYPE_COUNCIL,
 			VoteStart:       ledgerSeq - 30000,
 			VoteEnd:         ledgerSeq - 20000,
 			VotesFor:        "123141223412",
@@ -76,6 +88,7 @@ var (
 			Title:           "Unicorns need more research",
 			Description:     "They could exist somewhere",
 			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:      governor.ACTION_TYPE_COUNCIL,
 			VoteStart:       ledgerSeq - 40000,
 			VoteEnd:         ledgerSeq - 30000,
 			VotesFor:        "123141223412",
@@ -93,6 +106,7 @@ var (
 			Title:           "Unicorns are magical",
 			Description:     "They sparkle",
 			Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:      governor.ACTION_TYPE_COUNCIL,
 			VoteStart:       ledgerSeq - 50000,
 			VoteEnd:         ledgerSeq - 40000,
 			VotesFor:        "123141223412",
@@ -116,6 +130,16 @@ var (
 	}
 )
 
+// mustEncodeEventId encodes an event id, failing the test if it can't be encoded
+func mustEncodeEventId(t testing.TB, toid int64, eventIndex int32) string {
+	t.Helper()
+	eventId, err := governor.EncodeEventId(toid, eventIndex)
+	if err != nil {
+		t.Fatalf("failed to encode event id: %v", err)
+	}
+	return eventId
+}
+
 // setupStore creates an in-memory SQLite database for testing
 // also initializes the in-memory DB with the test data
 func setupStore(t *testing.T, ctx context.Context) *db.Store {
@@ -164,6 +188,8 @@ func setupStore(t *testing.T, ctx context.Context) *db.Store {
 	return store
 }
 
+// TestApplyEvent checks events are persisted by ApplyEvent. The state transitions are tested by
+// governor.TestApplyEventToProposal.
 func TestApplyEvent(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -197,6 +223,7 @@ func TestApplyEvent(t *testing.T) {
 				Title:           "Make me security council",
 				Description:     "plz",
 				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				ActionType:      governor.ACTION_TYPE_COUNCIL,
 				VoteStart:       ledgerSeq + 1000,
 				VoteEnd:         ledgerSeq + 21000,
 				VotesFor:        "0",
@@ -204,12 +231,17 @@ func TestApplyEvent(t *testing.T) {
 				VotesAbstain:    "0",
 				ExecutionUnlock: 0,
 				ExecutionTxHash: "",
+				CreatedLedger:   ledgerSeq,
+				CreatedTxHash:   "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
+				UpdatedLedger:   ledgerSeq,
+				UpdatedEventId:  "0005025687261941760-0000000000",
+				UpdatedAt:       testNow.Unix(),
 			},
 			wantVote: nil,
 			wantErr:  false,
 		},
 		{
-			name: "proposal_created duplicate does nothing",
+			name: "proposal_created for existing proposal with different content fails",
 			event: &governor.GovernorEvent{
 				EventId:    "0005025687261941760-0000000000",
 				ContractId: testContractId,
@@ -228,54 +260,6 @@ func TestApplyEvent(t *testing.T) {
 			wantVote:     nil,
 			wantErr:      true,
 		},
-		{
-			name: "proposal_canceled happy path",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_canceled",
-				ProposalId:      3,
-				EventData:       "{}",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: &governor.Proposal{
-				ProposalKey:     fmt.Sprintf("%s-3", testContractId),
-				ContractId:      testContractId,
-				ProposalId:      3,
-				Proposer:        "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
-				Status:          5,
-				Title:           "Unicorns are real",
-				Description:     "They live in the clouds",
-				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
-				VoteStart:       ledgerSeq - 10000,
-				VoteEnd:         ledgerSeq,
-				VotesFor:        "12314122341234",
-				VotesAgainst:    "1234123412434",
-				VotesAbstain:    "1923114243",
-				ExecutionUnlock: 0,
-				ExecutionTxHash: "",
-			},
-			wantVote: nil,
-			wantErr:  false,
-		},
-		{
-			name: "proposal_canceled no proposal fails",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_canceled",
-				ProposalId:      4,
-				EventData:       "{}",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: nil,
-			wantVote:     nil,
-			wantErr:      true,
-		},
 		{
 			name: "proposal_canceled invalid status does not apply",
 			event: &governor.GovernorEvent{
@@ -313,6 +297,7 @@ func TestApplyEvent(t *testing.T) {
 				Title:           "Unicorns are real",
 				Description:     "They live in the clouds",
 				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				ActionType:      governor.ACTION_TYPE_COUNCIL,
 				VoteStart:       ledgerSeq - 10000,
 				VoteEnd:         ledgerSeq,
 				VotesFor:        "50230000000",
@@ -320,42 +305,13 @@ func TestApplyEvent(t *testing.T) {
 				VotesAbstain:    "123",
 				ExecutionUnlock: 1120234,
 				ExecutionTxHash: "",
+				UpdatedLedger:   ledgerSeq,
+				UpdatedEventId:  "0005025687261941760-0000000000",
+				UpdatedAt:       testNow.Unix(),
 			},
 			wantVote: nil,
 			wantErr:  false,
 		},
-		{
-			name: "proposal_voting_closed no proposal fails",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_voting_closed",
-				ProposalId:      4,
-				EventData:       `{"status":1,"eta":1120234,"final_votes":{"for":"50230000000","against":"20000000000","abstain":"123"}}`,
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: nil,
-			wantVote:     nil,
-			wantErr:      true,
-		},
-		{
-			name: "proposal_voting_closed invalid status does not apply",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_voting_closed",
-				ProposalId:      2,
-				EventData:       `{"status":1,"eta":1120234,"final_votes":{"for":"50230000000","against":"20000000000","abstain":"123"}}`,
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: initProposals[1],
-			wantVote:     nil,
-			wantErr:      false,
-		},
 		{
 			name: "proposal_executed happy path",
 			event: &governor.GovernorEvent{
@@ -363,7 +319,7 @@ func TestApplyEvent(t *testing.T) {
 				ContractId:      testContractId,
 				EventType:       "proposal_executed",
 				ProposalId:      1,
-				EventData:       "",
+				EventData:       "{}",
 				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
 				LedgerSeq:       ledgerSeq,
 				LedgerCloseTime: ledgerCloseTime,
@@ -377,6 +333,7 @@ func TestApplyEvent(t *testing.T) {
 				Title:           "Unicorns need more research",
 				Description:     "They could exist somewhere",
 				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				ActionType:      governor.ACTION_TYPE_COUNCIL,
 				VoteStart:       ledgerSeq - 40000,
 				VoteEnd:         ledgerSeq - 30000,
 				VotesFor:        "123141223412",
@@ -384,106 +341,13 @@ func TestApplyEvent(t *testing.T) {
 				VotesAbstain:    "594114243",
 				ExecutionUnlock: ledgerSeq - 1000,
 				ExecutionTxHash: "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
+				UpdatedLedger:   ledgerSeq,
+				UpdatedEventId:  "0005025687261941760-0000000000",
+				UpdatedAt:       testNow.Unix(),
 			},
 			wantVote: nil,
 			wantErr:  false,
 		},
-		{
-			name: "proposal_executed no proposal fails",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_executed",
-				ProposalId:      4,
-				EventData:       "",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: nil,
-			wantVote:     nil,
-			wantErr:      true,
-		},
-		{
-			name: "proposal_executed invalid status does not apply",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_executed",
-				ProposalId:      0,
-				EventData:       "",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: initProposals[3],
-			wantVote:     nil,
-			wantErr:      false,
-		},
-		{
-			name: "proposal_expired happy path",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_expired",
-				ProposalId:      3,
-				EventData:       "{}",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: &governor.Proposal{
-				ProposalKey:     fmt.Sprintf("%s-3", testContractId),
-				ContractId:      testContractId,
-				ProposalId:      3,
-				Proposer:        "GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO",
-				Status:          3,
-				Title:           "Unicorns are real",
-				Description:     "They live in the clouds",
-				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
-				VoteStart:       ledgerSeq - 10000,
-				VoteEnd:         ledgerSeq,
-				VotesFor:        "12314122341234",
-				VotesAgainst:    "1234123412434",
-				VotesAbstain:    "1923114243",
-				ExecutionUnlock: 0,
-				ExecutionTxHash: "",
-			},
-			wantVote: nil,
-			wantErr:  false,
-		},
-		{
-			name: "proposal_expired no proposal fails",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_expired",
-				ProposalId:      4,
-				EventData:       "{}",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: nil,
-			wantVote:     nil,
-			wantErr:      true,
-		},
-		{
-			name: "proposal_expired invalid status does not apply",
-			event: &governor.GovernorEvent{
-				EventId:         "0005025687261941760-0000000000",
-				ContractId:      testContractId,
-				EventType:       "proposal_expired",
-				ProposalId:      2,
-				EventData:       "{}",
-				TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
-				LedgerSeq:       ledgerSeq,
-				LedgerCloseTime: ledgerCloseTime,
-			},
-			wantProposal: initProposals[1],
-			wantVote:     nil,
-			wantErr:      false,
-		},
 		{
 			name: "vote_cast happy path",
 			event: &governor.GovernorEvent{
@@ -505,6 +369,7 @@ func TestApplyEvent(t *testing.T) {
 				Title:           "Unicorns are real",
 				Description:     "They live in the clouds",
 				Action:          "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+				ActionType:      governor.ACTION_TYPE_COUNCIL,
 				VoteStart:       ledgerSeq - 10000,
 				VoteEnd:         ledgerSeq,
 				VotesFor:        "12334122341234",
@@ -512,6 +377,9 @@ func TestApplyEvent(t *testing.T) {
 				VotesAbstain:    "1923114243",
 				ExecutionUnlock: 0,
 				ExecutionTxHash: "",
+				UpdatedLedger:   ledgerSeq,
+				UpdatedEventId:  "0005025687261941760-0000000000",
+				UpdatedAt:       testNow.Unix(),
 			},
 			wantVote: &governor.Vote{
 				TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
@@ -542,20 +410,36 @@ func TestApplyEvent(t *testing.T) {
 			wantErr:      true,
 		},
 		{
-			name: "vote_cast invalid status does not apply",
+			name: "vote_cast already applied does nothing",
 			event: &governor.GovernorEvent{
 				EventId:         "0005025687261941760-0000000000",
 				ContractId:      testContractId,
 				EventType:       "vote_cast",
-				ProposalId:      2,
-				EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"20000000000"}`,
+				ProposalId:      3,
+				EventData:       `{"voter":"GAQ3OLLBLCO2DZZJHKB2GJNDI445NYNIOP7SMPRDYRUMWWR7YRF2CYVO","support":0,"amount":"123450000000"}`,
+				TxHash:          initVotes[0].TxHash,
+				LedgerSeq:       ledgerSeq,
+				LedgerCloseTime: ledgerCloseTime,
+			},
+			wantProposal: initProposals[0],
+			wantVote:     initVotes[0],
+			wantErr:      false,
+		},
+		{
+			name: "vote_cast overflowing tally fails",
+			event: &governor.GovernorEvent{
+				EventId:         "0005025687261941760-0000000000",
+				ContractId:      testContractId,
+				EventType:       "vote_cast",
+				ProposalId:      3,
+				EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"170141183460469231731687303715884105727"}`,
 				TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
 				LedgerSeq:       ledgerSeq,
 				LedgerCloseTime: ledgerCloseTime,
 			},
-			wantProposal: initProposals[1],
+			wantProposal: initProposals[0],
 			wantVote:     nil,
-			wantErr:      false,
+			wantErr:      true,
 		},
 	}
 
@@ -565,6 +449,7 @@ func TestApplyEvent(t *testing.T) {
 			store := setupStore(t, ctx)
 
 			indexer := NewIndexer(store)
+			indexer.now = func() time.Time { return testNow }
 
 			err := indexer.ApplyEvent(ctx, tt.event)
 			if err != nil && !tt.wantErr {
@@ -604,3 +489,649 @@ func TestApplyEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestReindexContract(t *testing.T) {
+	ctx := t.Context()
+	store := setupStore(t, ctx)
+	indexer := NewIndexer(store)
+
+	contractId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	history := []*governor.GovernorEvent{
+		{
+			EventId:         "0005025687261941760-0000000000",
+			ContractId:      contractId,
+			EventType:       "proposal_created",
+			ProposalId:      0,
+			EventData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1000,"vote_end":2000}`,
+			TxHash:          "cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970",
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+		},
+		{
+			EventId:         "0005025695851876451-0000000000",
+			ContractId:      contractId,
+			EventType:       "vote_cast",
+			ProposalId:      0,
+			EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"20000000000"}`,
+			TxHash:          "caa081584805c84f4e74b904b201fe765c16f7e3ed784d87e8dd531c621c62db",
+			LedgerSeq:       1170136,
+			LedgerCloseTime: 1761053046,
+		},
+	}
+	for _, event := range history {
+		if err := indexer.ApplyEvent(ctx, event); err != nil {
+			t.Fatalf("failed to apply initial event: %v", err)
+		}
+	}
+
+	// corrupt the proposal tallies
+	proposalKey := governor.EncodeProposalKey(contractId, 0)
+	proposal, err := store.GetProposal(ctx, proposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	wantProposal := *proposal
+	proposal.VotesFor = "999"
+	proposal.VotesAgainst = "123"
+	if err := store.UpsertProposal(ctx, proposal); err != nil {
+		t.Fatalf("failed to corrupt proposal: %v", err)
+	}
+
+	var replayed, total int
+	flags := &rebuildingFlagStore{Store: store}
+	err = NewIndexer(flags).ReindexContract(ctx, contractId, func(r int, tot int) {
+		replayed = r
+		total = tot
+	})
+	if err != nil {
+		t.Fatalf("ReindexContract() error = %v", err)
+	}
+	if replayed != 2 || total != 2 {
+		t.Errorf("expected progress 2/2, got %d/%d", replayed, total)
+	}
+	// the contract is flagged before the reindex, and the flag cleared after it
+	if diff := cmp.Diff([]string{contractId, ""}, flags.states); diff != "" {
+		t.Errorf("rebuilding flags mismatch (-want +got):\n%s", diff)
+	}
+	status, err := store.GetSourceStatus(ctx, REBUILDING_STATUS_SOURCE)
+	if err != nil {
+		t.Fatalf("failed to get rebuilding status: %v", err)
+	}
+	if status.State != "" {
+		t.Errorf("got rebuilding flag %q after reindex, want it cleared", status.State)
+	}
+
+	proposal, err = store.GetProposal(ctx, proposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal after reindex: %v", err)
+	}
+	if diff := cmp.Diff(&wantProposal, proposal); diff != "" {
+		t.Errorf("proposal mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = store.GetVote(ctx, history[1].TxHash)
+	if err != nil {
+		t.Fatalf("expected vote to be replayed: %v", err)
+	}
+
+	// a store failure fails the reindex, and rolls it back
+	storeErr := errors.New("disk I/O error")
+	failing := &failingStore{Store: store, err: storeErr}
+	if err := NewIndexer(failing).ReindexContract(ctx, contractId, nil); !errors.Is(err, storeErr) {
+		t.Fatalf("ReindexContract() error = %v, want %v", err, storeErr)
+	}
+	proposal, err = store.GetProposal(ctx, proposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal after failed reindex: %v", err)
+	}
+	if diff := cmp.Diff(&wantProposal, proposal); diff != "" {
+		t.Errorf("proposal mismatch after failed reindex (-want +got):\n%s", diff)
+	}
+	if _, err := store.GetVote(ctx, history[1].TxHash); err != nil {
+		t.Errorf("expected vote to be kept after failed reindex: %v", err)
+	}
+
+	// other contracts are not affected
+	otherProposal, err := store.GetProposal(ctx, initProposals[0].ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get other proposal: %v", err)
+	}
+	if diff := cmp.Diff(initProposals[0], otherProposal); diff != "" {
+		t.Errorf("other proposal mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	ctx := t.Context()
+	store := setupStore(t, ctx)
+	indexer := NewIndexer(store)
+
+	for i := range 2500 {
+		event := &governor.GovernorEvent{
+			EventId:         mustEncodeEventId(t, int64(i), 0),
+			ContractId:      testContractId,
+			EventType:       "proposal_canceled",
+			ProposalId:      99,
+			EventData:       "{}",
+			TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
+			LedgerSeq:       uint32(i),
+			LedgerCloseTime: ledgerCloseTime,
+		}
+		if err := store.InsertEvent(ctx, event); err != nil {
+			t.Fatalf("failed to insert event: %v", err)
+		}
+	}
+
+	// retention disabled does nothing
+	deleted, err := indexer.PruneHistory(ctx, 2500, 0)
+	if err != nil {
+		t.Fatalf("PruneHistory() error = %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("expected 0 deleted events with retention disabled, got %d", deleted)
+	}
+
+	// prunes events before ledger 2200 across multiple batches
+	deleted, err = indexer.PruneHistory(ctx, 2300, 100)
+	if err != nil {
+		t.Fatalf("PruneHistory() error = %v", err)
+	}
+	if deleted != 2200 {
+		t.Errorf("expected 2200 deleted events, got %d", deleted)
+	}
+
+	retainedLedger, _, err := store.GetStatus(ctx, RETENTION_STATUS_SOURCE)
+	if err != nil {
+		t.Fatalf("failed to get retention status: %v", err)
+	}
+	if retainedLedger != 2200 {
+		t.Errorf("expected retained ledger 2200, got %d", retainedLedger)
+	}
+
+	// events after the cutoff are retained, and proposals are untouched
+	_, err = store.GetEvent(ctx, initHistory[0].EventId)
+	if err != nil {
+		t.Fatalf("expected event after cutoff to be retained: %v", err)
+	}
+	proposal, err := store.GetProposal(ctx, initProposals[0].ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if diff := cmp.Diff(initProposals[0], proposal); diff != "" {
+		t.Errorf("proposal mismatch (-want +got):\n%s", diff)
+	}
+
+	// reindexing is refused once history has been pruned, and the rebuilding flag is cleared
+	err = indexer.ReindexContract(ctx, testContractId, nil)
+	if !errors.Is(err, ErrHistoryPruned) {
+		t.Errorf("expected ErrHistoryPruned, got %v", err)
+	}
+	status, err := store.GetSourceStatus(ctx, REBUILDING_STATUS_SOURCE)
+	if err != nil {
+		t.Fatalf("failed to get rebuilding status: %v", err)
+	}
+	if status.State != "" {
+		t.Errorf("got rebuilding flag %q after a failed reindex, want it cleared", status.State)
+	}
+}
+
+func TestApplyEventStoreFailures(t *testing.T) {
+	errDb := errors.New("db down")
+	voteEvent := &governor.GovernorEvent{
+		EventId:         "0005025695851876452-0000000000",
+		ContractId:      testContractId,
+		EventType:       "vote_cast",
+		ProposalId:      3,
+		EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"20000000000"}`,
+		TxHash:          "4c2b2b5e8b2a0d5a7f1e9f6e8f1a3b2c4d5e6f708192a3b4c5d6e7f8091a2b3c",
+		LedgerSeq:       ledgerSeq,
+		LedgerCloseTime: ledgerCloseTime,
+	}
+	createdEvent := &governor.GovernorEvent{
+		EventId:         "0005025695851876453-0000000000",
+		ContractId:      testContractId,
+		EventType:       "proposal_created",
+		ProposalId:      4,
+		EventData:       `{"proposer":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","title":"Make me security council","desc":"plz","action":"AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl","vote_start":1000,"vote_end":2000}`,
+		TxHash:          "5d3c3c6f9c3b1e6b8f2fa07f9f2b4c3d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+		LedgerSeq:       ledgerSeq,
+		LedgerCloseTime: ledgerCloseTime,
+	}
+	activeProposal := func(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) {
+		proposal := *initProposals[0]
+		return &proposal, 1, nil
+	}
+	noProposal := func(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) {
+		return nil, 0, db.ErrNotFound
+	}
+	noVote := func(ctx context.Context, txHash string) (*governor.Vote, error) {
+		return nil, db.ErrNotFound
+	}
+
+	tests := []struct {
+		name      string
+		event     *governor.GovernorEvent
+		store     *mockStore
+		wantCalls []string
+	}{
+		{
+			name:  "insert event fails",
+			event: voteEvent,
+			store: &mockStore{
+				insertEvent: func(ctx context.Context, event *governor.GovernorEvent) error { return errDb },
+			},
+			wantCalls: []string{"InsertEvent"},
+		},
+		{
+			name:  "get proposal fails",
+			event: voteEvent,
+			store: &mockStore{
+				insertEvent:        func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+				getProposalVersion: func(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) { return nil, 0, errDb },
+			},
+			wantCalls: []string{"InsertEvent", "WithTx", "GetProposalVersion"},
+		},
+		{
+			name:  "get vote fails",
+			event: voteEvent,
+			store: &mockStore{
+				insertEvent:        func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+				getProposalVersion: activeProposal,
+				getVote:            func(ctx context.Context, txHash string) (*governor.Vote, error) { return nil, errDb },
+			},
+			wantCalls: []string{"InsertEvent", "WithTx", "GetProposalVersion", "GetVote"},
+		},
+		{
+			name:  "insert vote fails",
+			event: voteEvent,
+			store: &mockStore{
+				insertEvent:        func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+				getProposalVersion: activeProposal,
+				getVote:            noVote,
+				insertVote:         func(ctx context.Context, vote *governor.Vote) error { return errDb },
+			},
+			wantCalls: []string{"InsertEvent", "WithTx", "GetProposalVersion", "GetVote", "InsertVote"},
+		},
+		{
+			name:  "upsert proposal fails after insert vote succeeded",
+			event: voteEvent,
+			store: &mockStore{
+				insertEvent:        func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+				getProposalVersion: activeProposal,
+				getVote:            noVote,
+				insertVote:         func(ctx context.Context, vote *governor.Vote) error { return nil },
+				updateProposal:     func(ctx context.Context, proposal *governor.Proposal, version int64) error { return errDb },
+			},
+			wantCalls: []string{"InsertEvent", "WithTx", "GetProposalVersion", "GetVote", "InsertVote", "UpdateProposal"},
+		},
+		{
+			name:  "upsert new proposal fails",
+			event: createdEvent,
+			store: &mockStore{
+				insertEvent:        func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+				getProposalVersion: noProposal,
+				insertProposal:     func(ctx context.Context, proposal *governor.Proposal) error { return errDb },
+			},
+			wantCalls: []string{"InsertEvent", "WithTx", "GetProposalVersion", "InsertProposal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.store.getContractLastEventId = func(ctx context.Context, contractId string) (string, error) { return "", db.ErrNotFound }
+			indexer := NewIndexer(tt.store)
+			err := indexer.ApplyEvent(t.Context(), tt.event)
+			if !errors.Is(err, errDb) {
+				t.Errorf("ApplyEvent() expected db error, got %v", err)
+			}
+			// the event order is checked first, and the failure is recorded in the error log
+			wantCalls := append(append([]string{"GetContractLastEventId"}, tt.wantCalls...), "InsertIndexerError")
+			if diff := cmp.Diff(wantCalls, tt.store.calls); diff != "" {
+				t.Errorf("calls mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyEventConflictRetry(t *testing.T) {
+	voteEvent := &governor.GovernorEvent{
+		EventId:         "0005025695851876452-0000000000",
+		ContractId:      testContractId,
+		EventType:       "vote_cast",
+		ProposalId:      3,
+		EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"20000000000"}`,
+		TxHash:          "4c2b2b5e8b2a0d5a7f1e9f6e8f1a3b2c4d5e6f708192a3b4c5d6e7f8091a2b3c",
+		LedgerSeq:       ledgerSeq,
+		LedgerCloseTime: ledgerCloseTime,
+	}
+
+	updates := 0
+	store := &mockStore{
+		insertEvent: func(ctx context.Context, event *governor.GovernorEvent) error { return nil },
+		getProposalVersion: func(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) {
+			proposal := *initProposals[0]
+			return &proposal, int64(updates + 1), nil
+		},
+		getVote:    func(ctx context.Context, txHash string) (*governor.Vote, error) { return nil, db.ErrNotFound },
+		insertVote: func(ctx context.Context, vote *governor.Vote) error { return nil },
+		updateProposal: func(ctx context.Context, proposal *governor.Proposal, version int64) error {
+			updates++
+			if updates == 1 {
+				return db.ErrConflict
+			}
+			return nil
+		},
+		upsertContractActivity: func(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error {
+			return nil
+		},
+		getContractLastEventId: func(ctx context.Context, contractId string) (string, error) { return "", nil },
+	}
+
+	err := NewIndexer(store).ApplyEvent(t.Context(), voteEvent)
+	if err != nil {
+		t.Fatalf("ApplyEvent() error = %v", err)
+	}
+	wantCalls := []string{
+		"GetContractLastEventId", "InsertEvent",
+		"WithTx", "GetProposalVersion", "GetVote", "InsertVote", "UpdateProposal",
+		"WithTx", "GetProposalVersion", "GetVote", "InsertVote", "UpdateProposal", "UpsertContractActivity",
+	}
+	if diff := cmp.Diff(wantCalls, store.calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestApplyProposalCreatedConflict verifies a proposal_created event for an existing proposal is a no-op if its
+// content matches, and is recorded as a failed event if it doesn't, whether or not events are batched
+func TestApplyProposalCreatedConflict(t *testing.T) {
+	existing := initProposals[0]
+	createdEvent := func(eventId string, title string) *governor.GovernorEvent {
+		data, err := json.Marshal(governor.ProposalCreatedData{
+			Proposer:  existing.Proposer,
+			Title:     title,
+			Desc:      existing.Description,
+			Action:    existing.Action,
+			VoteStart: existing.VoteStart,
+			VoteEnd:   existing.VoteEnd,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &governor.GovernorEvent{
+			EventId:         eventId,
+			ContractId:      testContractId,
+			EventType:       "proposal_created",
+			ProposalId:      existing.ProposalId,
+			EventData:       string(data),
+			TxHash:          "e65cfb5071126dc0a21b9d77f6d26a9d5788edf1cb6aac8de6e478273c1957f5",
+			LedgerSeq:       ledgerSeq,
+			LedgerCloseTime: ledgerCloseTime,
+		}
+	}
+	same := createdEvent("0005025695851876452-0000000000", existing.Title)
+	conflicting := createdEvent("0005025695851876452-0000000001", "Unicorns are fake")
+
+	for _, batched := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batched=%v", batched), func(t *testing.T) {
+			ctx := t.Context()
+			store := setupStore(t, ctx)
+			idx := NewIndexer(store)
+			idx.now = func() time.Time { return testNow }
+
+			var stats LedgerStats
+			for _, event := range []*governor.GovernorEvent{same, conflicting} {
+				var err error
+				if batched {
+					err = idx.applyBatch(ctx, []*governor.GovernorEvent{event}, &stats)
+				} else {
+					err = idx.applyLedgerEvent(ctx, event, &stats)
+				}
+				if err != nil {
+					t.Fatalf("failed applying event %s: %v", event.EventId, err)
+				}
+			}
+
+			proposal, err := store.GetProposal(ctx, existing.ProposalKey)
+			if err != nil {
+				t.Fatalf("failed to get proposal: %v", err)
+			}
+			if diff := cmp.Diff(existing, proposal); diff != "" {
+				t.Errorf("proposal mismatch (-want +got):\n%s", diff)
+			}
+			failed, err := store.GetFailedEventsByContractId(ctx, testContractId)
+			if err != nil {
+				t.Fatalf("failed to get failed events: %v", err)
+			}
+			if len(failed) != 1 || failed[0].EventId != conflicting.EventId || failed[0].Reason != governor.FAILED_REASON_PROPOSAL_CONFLICT {
+				t.Fatalf("failed events = %+v, want the conflicting event", failed)
+			}
+			if !strings.HasSuffix(failed[0].Error, "title differ") {
+				t.Errorf("failed event error = %q, want the differing fields", failed[0].Error)
+			}
+			if stats.ProposalConflicts != 1 || stats.FailedEvents != 1 {
+				t.Errorf("ProposalConflicts = %d, FailedEvents = %d, want 1 and 1", stats.ProposalConflicts, stats.FailedEvents)
+			}
+		})
+	}
+}
+
+// TestApplyEventContractActivity verifies applied events keep the contracts registry current
+func TestApplyEventContractActivity(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+	stats, err := Seed(ctx, store, SeedOptions{Contracts: 2, Proposals: 3, Seed: 3, Ledger: 1170234, Now: time.Unix(1761053041, 0)})
+	if err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	events, err := store.GetEventsAfter(ctx, "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := make(map[string]*governor.GovernorEvent)
+	for _, event := range events {
+		last[event.ContractId] = event
+	}
+
+	var want []*db.Contract
+	for _, contractId := range stats.Contracts {
+		want = append(want, &db.Contract{ContractId: contractId, LastEventLedger: last[contractId].LedgerSeq, LastEventCloseTime: last[contractId].LedgerCloseTime, ProposalCount: 3, Reviewed: true})
+	}
+	slices.SortFunc(want, func(a, b *db.Contract) int { return strings.Compare(a.ContractId, b.ContractId) })
+	got, err := store.GetContracts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("contracts mismatch (-want +got):\n%s", diff)
+	}
+
+	// replaying an older event doesn't move the last activity back
+	if err := NewIndexer(store).ReindexContract(ctx, stats.Contracts[0], nil); err != nil {
+		t.Fatalf("ReindexContract() error = %v", err)
+	}
+	got, err = store.GetContracts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("contracts after reindex mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestApplyEventContractDiscovery verifies contracts registered with contract discovery are unreviewed, and that
+// applying events never changes a registered contract's review
+func TestApplyEventContractDiscovery(t *testing.T) {
+	ctx := t.Context()
+	store := setupStore(t, ctx)
+	indexer := NewIndexer(store)
+	indexer.contractDiscovery = true
+	voteEvent := func(eventId string, txHash string) *governor.GovernorEvent {
+		return &governor.GovernorEvent{
+			EventId:         eventId,
+			ContractId:      testContractId,
+			EventType:       "vote_cast",
+			ProposalId:      3,
+			EventData:       `{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"1000"}`,
+			TxHash:          txHash,
+			LedgerSeq:       ledgerSeq,
+			LedgerCloseTime: ledgerCloseTime,
+		}
+	}
+
+	if err := indexer.ApplyEvent(ctx, voteEvent("0005025695851876452-0000000000", "tx_first")); err != nil {
+		t.Fatalf("ApplyEvent() error = %v", err)
+	}
+	contract, err := store.GetContract(ctx, testContractId)
+	if err != nil {
+		t.Fatalf("GetContract() error = %v", err)
+	}
+	if contract.Reviewed {
+		t.Errorf("expected a contract registered by contract discovery to be unreviewed")
+	}
+
+	if err := store.SetContractReviewed(ctx, testContractId, true); err != nil {
+		t.Fatalf("SetContractReviewed() error = %v", err)
+	}
+	if err := indexer.ApplyEvent(ctx, voteEvent("0005025695851876452-0000000001", "tx_second")); err != nil {
+		t.Fatalf("ApplyEvent() error = %v", err)
+	}
+	if contract, err = store.GetContract(ctx, testContractId); err != nil {
+		t.Fatalf("GetContract() error = %v", err)
+	}
+	if !contract.Reviewed {
+		t.Errorf("expected an approved contract to stay reviewed")
+	}
+}
+
+// TestApplyEventConcurrentVotes applies votes for the same proposal concurrently, and verifies no votes are lost
+func TestApplyEventConcurrentVotes(t *testing.T) {
+	ctx := t.Context()
+	dsn := "file:" + filepath.Join(t.TempDir(), "gov.db")
+	store, err := db.Open(ctx, db.Config{Type: "sqlite", ConnectionString: dsn, MaxOpenConns: 10, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := db.RunMigrations(store.DB()); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	proposal := *initProposals[0]
+	proposal.VotesFor = "0"
+	if err := store.UpsertProposal(ctx, &proposal); err != nil {
+		t.Fatalf("failed to insert proposal: %v", err)
+	}
+
+	indexer := NewIndexer(store)
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			eventId, err := governor.EncodeEventId(int64(i), 0)
+			if err != nil {
+				errs <- err
+				return
+			}
+			event := &governor.GovernorEvent{
+				EventId:         eventId,
+				ContractId:      proposal.ContractId,
+				EventType:       "vote_cast",
+				ProposalId:      proposal.ProposalId,
+				EventData:       fmt.Sprintf(`{"voter":"GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q","support":1,"amount":"%d"}`, i+1),
+				TxHash:          fmt.Sprintf("%064d", i),
+				LedgerSeq:       ledgerSeq,
+				LedgerCloseTime: ledgerCloseTime,
+			}
+			if err := indexer.ApplyEvent(ctx, event); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("ApplyEvent() error = %v", err)
+	}
+
+	result, err := store.GetProposal(ctx, proposal.ProposalKey)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	// sum of 1..100
+	if result.VotesFor != "5050" {
+		t.Errorf("expected votes for 5050, got %s", result.VotesFor)
+	}
+	votes, err := store.GetVotesByProposal(ctx, proposal.ContractId, proposal.ProposalId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if len(votes) != 100 {
+		t.Errorf("expected 100 votes, got %d", len(votes))
+	}
+}
+
+func TestFlagLowParticipation(t *testing.T) {
+	governor.SetParticipationThreshold(governor.ParticipationThreshold{MinVoters: 2, MinAmount: "100000000000"})
+	t.Cleanup(func() { governor.SetParticipationThreshold(governor.DEFAULT_PARTICIPATION_THRESHOLD) })
+	// proposal 1 closes with one voter, and proposal 2 with two. The final votes of both are below the min amount.
+	ledgers := []xdr.LedgerCloseMeta{
+		newFixtureLedger(t, 1170134, 1761052641, []fixtureTx{
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1)}},
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 2)}},
+		}),
+		newFixtureLedger(t, 1170136, 1761052651, []fixtureTx{
+			{events: []xdr.ContractEvent{newVoteCastEvent(t, 1, 0, 1, 10000000)}},
+			{events: []xdr.ContractEvent{newVoteCastEvent(t, 2, 0, 1, 10000000)}},
+			{events: []xdr.ContractEvent{newVoteCastEvent(t, 2, 1, 0, 10000000)}},
+		}),
+		newFixtureLedger(t, 1170140, 1761052671, []fixtureTx{
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalVotingClosedXdr), 1)}},
+			{events: []xdr.ContractEvent{withProposalId(t, mustDecodeEvent(t, proposalVotingClosedXdr), 2)}},
+		}),
+	}
+	checkFlags := func(t *testing.T, store *db.Store) {
+		t.Helper()
+		for proposalId, want := range map[uint32]bool{1: true, 2: false} {
+			proposal, err := store.GetProposal(t.Context(), governor.EncodeProposalKey(testContractId, proposalId))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proposal.FlaggedLowParticipation != want {
+				t.Errorf("proposal %d flagged = %v, want %v", proposalId, proposal.FlaggedLowParticipation, want)
+			}
+		}
+	}
+
+	for _, batched := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batched %v", batched), func(t *testing.T) {
+			store := newFixtureStore(t)
+			idx := NewIndexer(store)
+			idx.batched = batched
+			for _, ledger := range ledgers {
+				applyFixtureLedger(t, idx, ledger)
+			}
+			checkFlags(t, store)
+
+			// replaying the history flags the same proposals
+			if err := idx.ReindexContract(t.Context(), testContractId, nil); err != nil {
+				t.Fatalf("ReindexContract() error = %v", err)
+			}
+			checkFlags(t, store)
+		})
+	}
+}
+
+// rebuildingFlagStore records the rebuilding flags written to the status table
+type rebuildingFlagStore struct {
+	*db.Store
+	states []string
+}
+
+func (s *rebuildingFlagStore) UpsertSourceStatus(ctx context.Context, source string, status db.SourceStatus) error {
+	if source == REBUILDING_STATUS_SOURCE {
+		s.states = append(s.states, status.State)
+	}
+	return s.Store.UpsertSourceStatus(ctx, source, status)
+}
@@ -0,0 +1,82 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/ledgerfixture"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+)
+
+// Inspect parses the governor events in ledgers from through to (inclusive) from the configured ledger backend,
+// and writes each event to w as a line of JSON. It never reads or writes the database, and is intended for
+// debugging new contract deployments. Governor events with an unknown schema version are logged instead.
+//
+// If recordDir is set, each ledger containing a governor event is also saved there as a ledger fixture,
+// for use in tests.
+//
+// Inspect returns the stats of the inspected ledgers. Events are not applied, so only the parsing stats are set.
+func Inspect(ctx context.Context, config *Config, from uint32, to uint32, w io.Writer, recordDir string) (LedgerStats, error) {
+	if from == 0 || to < from {
+		return LedgerStats{}, fmt.Errorf("invalid ledger range %d to %d", from, to)
+	}
+	networkPassphrase := networkPassphrase(config)
+
+	backend, err := newLedgerBackend(config, networkPassphrase)
+	if err != nil {
+		return LedgerStats{}, err
+	}
+	defer backend.Close()
+
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(from, to)); err != nil {
+		return LedgerStats{}, fmt.Errorf("failed to prepare ledger range: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	var stats LedgerStats
+	for seq := from; seq <= to; seq++ {
+		ledgerStats := LedgerStats{Ledgers: 1}
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			return stats, fmt.Errorf("failed to get ledger %d: %w", seq, err)
+		}
+
+		txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(networkPassphrase, ledger)
+		if err != nil {
+			return stats, fmt.Errorf("failed to create transaction reader for ledger %d: %w", seq, err)
+		}
+		for {
+			tx, err := txReader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				txReader.Close()
+				return stats, fmt.Errorf("failed to read ledger transaction in ledger %d: %w", seq, err)
+			}
+
+			ledgerStats.Transactions++
+			govEvents, _ := ParseTransaction(tx, ledger.LedgerSequence(), ledger.LedgerCloseTime(), &ledgerStats)
+			for _, govEvent := range govEvents {
+				if err := encoder.Encode(govEvent); err != nil {
+					txReader.Close()
+					return stats, fmt.Errorf("failed to write event %s: %w", govEvent.EventId, err)
+				}
+			}
+		}
+		txReader.Close()
+		stats.Add(ledgerStats)
+		slog.Debug("Ledger inspected.", append([]any{"ledger", seq}, ledgerStats.LogAttrs()...)...)
+
+		if recordDir != "" && ledgerStats.GovernorEvents+ledgerStats.FailedEvents > 0 {
+			if err := ledgerfixture.Write(recordDir, ledger); err != nil {
+				return stats, err
+			}
+			slog.Info("Recorded ledger fixture.", "ledger", seq, "path", ledgerfixture.Path(recordDir, seq))
+		}
+	}
+	return stats, nil
+}
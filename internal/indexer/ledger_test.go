@@ -0,0 +1,1512 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/ledgerfixture"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+var generateFixtures = flag.Bool("generate-fixtures", false, "regenerate the ledger fixtures in testdata")
+
+var (
+	// FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerFixtures
+	FIXTURE_DIR = filepath.Join("testdata", "ledgers")
+	// FEE_BUMP_FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerFeeBumpFixtures
+	FEE_BUMP_FIXTURE_DIR = filepath.Join("testdata", "feebump")
+	// SCHEMA_FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerSchemaFixtures
+	SCHEMA_FIXTURE_DIR = filepath.Join("testdata", "schema")
+	// DELEGATION_FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerDelegationFixtures
+	DELEGATION_FIXTURE_DIR = filepath.Join("testdata", "delegation")
+	// UNKNOWN_TYPE_FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerUnknownTypeFixtures
+	UNKNOWN_TYPE_FIXTURE_DIR = filepath.Join("testdata", "unknowntype")
+	// MULTI_OP_FIXTURE_DIR contains the ledger fixtures replayed by TestApplyLedgerMultiOpFixtures
+	MULTI_OP_FIXTURE_DIR = filepath.Join("testdata", "multiop")
+)
+
+// fixtureSourceAccount is the source account of the first 256 transactions of each generated fixture ledger
+const fixtureSourceAccount = "GAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAHV4"
+
+// Contract event XDR captured from testnet, used as the basis of the generated ledger fixtures
+const (
+	proposalCreatedXdr      = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAQcHJvcG9zYWxfY3JlYXRlZAAAAAMAAAADAAAAEgAAAAAAAAAALJ/M6wbqSvh6BcSe5KJD8aWHCTFHGu3YUKtUqAH05uUAAAAQAAAAAQAAAAUAAAAOAAAAGE1ha2UgbWUgc2VjdXJpdHkgY291bmNpbAAAAA4AAAADcGx6AAAAABAAAAABAAAAAgAAAA8AAAAHQ291bmNpbAAAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAAAMAEa9sAAAAAwAR8uw="
+	proposalCanceledXdr     = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAgAAAA8AAAARcHJvcG9zYWxfY2FuY2VsZWQAAAAAAAADAAAAAwAAAAE="
+	voteCastXdr             = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAAAwAAAA8AAAAJdm90ZV9jYXN0AAAAAAAAAwAAAAIAAAASAAAAAAAAAAAsn8zrBupK+HoFxJ7kokPxpYcJMUca7dhQq1SoAfTm5QAAABAAAAABAAAAAgAAAAMAAAAAAAAACgAAAAAAAAAAAAAABKgXyAA="
+	proposalVotingClosedXdr = "AAAAAAAAAAHA70OsAU+gdeyDov6bvqWGNPZnEemjXsRPq/7W4n00/AAAAAEAAAAAAAAABAAAAA8AAAAWcHJvcG9zYWxfdm90aW5nX2Nsb3NlZAAAAAAAAwAAAAEAAAADAAAAAgAAAAMAAAAAAAAAEQAAAAEAAAADAAAADwAAAARfZm9yAAAACgAAAAAAAAAAAAAAAElQT4AAAAAPAAAAB2Fic3RhaW4AAAAACgAAAAAAAAAAAAAAAAAAAAAAAAAPAAAAB2FnYWluc3QAAAAACgAAAAAAAAAAAAAABKgXyAA="
+)
+
+// fixtureTx describes a transaction in a generated ledger fixture
+type fixtureTx struct {
+	events []xdr.ContractEvent
+	// the events of each operation, for a transaction with more than one operation, which is recorded with v4
+	// transaction meta. events is ignored if set.
+	ops     [][]xdr.ContractEvent
+	changes xdr.LedgerEntryChanges
+	failed  bool
+	feeBump bool
+}
+
+// fixtureLedger describes a generated ledger fixture
+type fixtureLedger struct {
+	seq       uint32
+	closeTime int64
+	txs       []fixtureTx
+}
+
+// TestGenerateLedgerFixtures writes the ledger fixtures replayed by TestApplyLedgerFixtures. It only runs with
+// -generate-fixtures.
+//
+// The ledgers are built around contract events captured from testnet, so one short proposal lifecycle covers every
+// governor event type. Ledgers captured with `inspect -record` can be replayed the same way.
+func TestGenerateLedgerFixtures(t *testing.T) {
+	if !*generateFixtures {
+		t.Skip("run with -generate-fixtures to regenerate the ledger fixtures")
+	}
+
+	created1 := withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1)
+	created2 := withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 2)
+	created3 := mustDecodeEvent(t, proposalCreatedXdr)
+	canceled3 := mustDecodeEvent(t, proposalCanceledXdr)
+	vote1 := withProposalId(t, mustDecodeEvent(t, voteCastXdr), 1)
+	vote2 := mustDecodeEvent(t, voteCastXdr)
+	closed1 := mustDecodeEvent(t, proposalVotingClosedXdr)
+	// mark the proposal as successful, so it can be executed
+	status := xdr.Uint32(1)
+	closed1.Body.V0.Topics[2] = xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &status}
+	expired2 := newGovernorEvent(created1, "proposal_expired", 2)
+	executed1 := newGovernorEvent(created1, "proposal_executed", 1)
+
+	ledgers := []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{created1}},
+				{events: []xdr.ContractEvent{created2}},
+				{events: []xdr.ContractEvent{newTransferEvent(created1)}},
+				{events: []xdr.ContractEvent{created3}},
+			},
+		},
+		{
+			seq:       1170136,
+			closeTime: 1761053046,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{vote1}},
+				// failed transactions must be skipped, even if they have events
+				{events: []xdr.ContractEvent{vote2}, failed: true},
+				{events: []xdr.ContractEvent{canceled3}},
+			},
+		},
+		{
+			seq:       1170137,
+			closeTime: 1761053050,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{closed1, expired2}},
+			},
+		},
+		{
+			seq:       1170140,
+			closeTime: 1761053065,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{executed1}},
+			},
+		},
+	}
+
+	writeFixtureLedgers(t, FIXTURE_DIR, ledgers)
+
+	// a proposal voted on by a fee bump transaction, after an unrelated transaction so the vote is not first
+	// in the ledger
+	writeFixtureLedgers(t, FEE_BUMP_FIXTURE_DIR, []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{created1}},
+				{events: []xdr.ContractEvent{newTransferEvent(created1)}},
+				{events: []xdr.ContractEvent{newTransferEvent(created1), vote1}, feeBump: true},
+			},
+		},
+	})
+
+	// v2 events, and a vote from a future schema version that must be recorded as a failed event
+	writeFixtureLedgers(t, SCHEMA_FIXTURE_DIR, []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{withSchemaVersion(created1, "v2", "title", "desc", "action", "vote_start", "vote_end")}},
+				{events: []xdr.ContractEvent{withSchemaVersion(vote1, "v2", "support", "amount")}},
+				{events: []xdr.ContractEvent{withSchemaVersion(vote1, "v3", "support", "amount")}},
+			},
+		},
+	})
+
+	// delegations from the governor's votes contract, which is read from the governor instance written when the
+	// proposal is created along with its settings, and a delegation from an unrelated contract that must be skipped
+	votesId := xdr.ContractId{0xa0}
+	otherId := xdr.ContractId{0xb0}
+	alice := newAccountAddress(0x01)
+	bob := newAccountAddress(0x02)
+	writeFixtureLedgers(t, DELEGATION_FIXTURE_DIR, []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{newDelegateChangedEvent(votesId, alice, alice, bob)}},
+				{events: []xdr.ContractEvent{created1}, changes: newGovernorInstanceChanges(*created1.ContractId, votesId)},
+			},
+		},
+		{
+			seq:       1170136,
+			closeTime: 1761053046,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{
+					newDelegateChangedEvent(votesId, alice, alice, bob),
+					newDelegateVotesChangedEvent(votesId, bob, 0, 100),
+				}},
+				{events: []xdr.ContractEvent{newDelegateChangedEvent(otherId, bob, bob, alice)}},
+			},
+		},
+	})
+
+	// an event type added by a newer governor release, emitted by the tracked governor and by an unrelated contract
+	queued := newGovernorEvent(created1, "proposal_queued", 1)
+	untrackedQueued := newGovernorEvent(created1, "proposal_queued", 1)
+	untrackedQueued.ContractId = &otherId
+	writeFixtureLedgers(t, UNKNOWN_TYPE_FIXTURE_DIR, []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{created1}},
+				{events: []xdr.ContractEvent{queued}},
+				{events: []xdr.ContractEvent{untrackedQueued}},
+			},
+		},
+	})
+
+	// a transaction with two operations each emitting a governor event, which must get distinct event ids in
+	// operation order, so the proposal is created before it is canceled
+	writeFixtureLedgers(t, MULTI_OP_FIXTURE_DIR, []fixtureLedger{
+		{
+			seq:       1170134,
+			closeTime: 1761053041,
+			txs: []fixtureTx{
+				{events: []xdr.ContractEvent{created1}},
+				{ops: [][]xdr.ContractEvent{
+					{created2},
+					{newTransferEvent(created1), withProposalId(t, canceled3, 2)},
+				}},
+			},
+		},
+	})
+}
+
+func TestApplyLedgerFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	indexer := NewIndexer(store)
+	indexer.now = func() time.Time { return testNow }
+	total := replayFixtures(t, indexer, FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:          4,
+		Transactions:     9,
+		ContractEvents:   9,
+		GovernorEvents:   8,
+		EventsApplied:    8,
+		VotesInserted:    1,
+		ProposalsMutated: 8,
+		ParseFailures:    0,
+		Contracts:        map[string]db.EventCounts{testContractId: {Seen: 8, Parsed: 8}},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	proposals, err := store.GetProposalsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get proposals: %v", err)
+	}
+	// newProposal returns a fixture proposal created in createdTxHash, last updated by updatedEventId at updatedLedger
+	newProposal := func(id uint32, status uint32, createdTxHash string, updatedLedger uint32, updatedEventId string) *governor.Proposal {
+		return &governor.Proposal{
+			ProposalKey:    governor.EncodeProposalKey(testContractId, id),
+			ContractId:     testContractId,
+			ProposalId:     id,
+			Proposer:       "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			Status:         status,
+			Title:          "Make me security council",
+			Description:    "plz",
+			Action:         "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl",
+			ActionType:     governor.ACTION_TYPE_COUNCIL,
+			VoteStart:      1159020,
+			VoteEnd:        1176300,
+			VotesFor:       "0",
+			VotesAgainst:   "0",
+			VotesAbstain:   "0",
+			CreatedLedger:  1170134,
+			CreatedTxHash:  createdTxHash,
+			SourceAccount:  fixtureSourceAccount,
+			UpdatedLedger:  updatedLedger,
+			UpdatedEventId: updatedEventId,
+			UpdatedAt:      testNow.Unix(),
+		}
+	}
+	executed := newProposal(1, 4, "18663550f9c9ea1375de182b044073b7b65d2277c280b78a04ddc26fbe9a264e", 1170140, "0005025713031745536-0000000000")
+	executed.VotesFor = "1230000000"
+	executed.VotesAgainst = "20000000000"
+	executed.ExecutionTxHash = "8172628e3b2da329cd1f43854ebe1badb4b91330d35038dd103ae14188bcad5a"
+	wantProposals := []*governor.Proposal{
+		newProposal(3, 5, "7cddc74d0eb76e765767ea06718c52f5d218216fd463a6d8faeff32de1239db0", 1170136, "0005025695851884544-0000000000"),
+		newProposal(2, 3, "f4acaca732d89ec24932d0274a04f6330f2845e5af4782bd12d834d23f7fae49", 1170137, "0005025700146843648-0000000001"),
+		executed,
+	}
+	if diff := cmp.Diff(wantProposals, proposals); diff != "" {
+		t.Errorf("proposals mismatch (-want +got):\n%s", diff)
+	}
+
+	votes, err := store.GetVotesByProposal(ctx, testContractId, 1, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	wantVotes := []*governor.Vote{
+		{
+			TxHash:          "90b9fcd255ca8d9a8682d3bbf1a1c1afcf3c2b5a5704cfb87a3b1b92e5c017ee",
+			ContractId:      testContractId,
+			ProposalId:      1,
+			Voter:           "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			Support:         0,
+			Amount:          "20000000000",
+			LedgerSeq:       1170136,
+			LedgerCloseTime: 1761053046,
+		},
+	}
+	if diff := cmp.Diff(wantVotes, votes); diff != "" {
+		t.Errorf("votes mismatch (-want +got):\n%s", diff)
+	}
+	votes, err = store.GetVotesByProposal(ctx, testContractId, 2, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if len(votes) != 0 {
+		t.Errorf("got %d votes for proposal 2 from a failed transaction, want 0", len(votes))
+	}
+
+	events, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	var eventTypes []string
+	for _, event := range events {
+		eventTypes = append(eventTypes, event.EventType)
+	}
+	wantEventTypes := []string{
+		"proposal_created", "proposal_created", "proposal_created",
+		"vote_cast", "proposal_canceled",
+		"proposal_voting_closed", "proposal_expired",
+		"proposal_executed",
+	}
+	if diff := cmp.Diff(wantEventTypes, eventTypes); diff != "" {
+		t.Errorf("event types mismatch (-want +got):\n%s", diff)
+	}
+	// each event keeps the contract event it was parsed from
+	for _, event := range events {
+		var ce xdr.ContractEvent
+		if err := xdr.SafeUnmarshalBase64(event.EventXdr, &ce); err != nil {
+			t.Fatalf("event %s has invalid event xdr %q: %v", event.EventId, event.EventXdr, err)
+		}
+		parsed, err := governor.NewGovernorEventFromContractEvent(&ce, event.TxHash, event.LedgerSeq, event.LedgerCloseTime, 0, 0)
+		if err != nil || parsed.EventType != event.EventType || parsed.EventData != event.EventData {
+			t.Errorf("event %s xdr parsed as %+v (err %v), want event type %s", event.EventId, parsed, err, event.EventType)
+		}
+	}
+}
+
+func TestBackfillLedgerEventXdr(t *testing.T) {
+	ctx := t.Context()
+	backend, err := ledgerfixture.NewBackend(FIXTURE_DIR)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	defer backend.Close()
+	seqs := backend.Sequences()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seqs[0], seqs[len(seqs)-1])); err != nil {
+		t.Fatalf("failed to prepare range: %v", err)
+	}
+
+	// the first event already has its xdr, so is not counted
+	set := map[string]string{}
+	store := &mockStore{
+		setEventXdr: func(ctx context.Context, eventId string, eventXdr string) (bool, error) {
+			_, done := set[eventId]
+			set[eventId] = eventXdr
+			return len(set) > 1 && !done, nil
+		},
+	}
+	updated := 0
+	for _, seq := range seqs {
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatalf("failed to get ledger %d: %v", seq, err)
+		}
+		count, err := backfillLedgerEventXdr(ctx, store, network.TestNetworkPassphrase, ledger)
+		if err != nil {
+			t.Fatalf("backfillLedgerEventXdr(%d) error = %v", seq, err)
+		}
+		updated += count
+	}
+
+	// every governor event in the fixtures is backfilled, with the xdr it is indexed with
+	if len(set) != 8 || updated != 7 {
+		t.Errorf("set %d events and updated %d, want 8 and 7", len(set), updated)
+	}
+	fixtureStore := newFixtureStore(t)
+	replayFixtures(t, NewIndexer(fixtureStore), FIXTURE_DIR)
+	events, err := fixtureStore.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	for _, event := range events {
+		if set[event.EventId] != event.EventXdr {
+			t.Errorf("event %s backfilled with %q, want %q", event.EventId, set[event.EventId], event.EventXdr)
+		}
+	}
+
+	failing := &mockStore{
+		setEventXdr: func(ctx context.Context, eventId string, eventXdr string) (bool, error) { return false, db.ErrTimeout },
+	}
+	ledger, err := backend.GetLedger(ctx, seqs[0])
+	if err != nil {
+		t.Fatalf("failed to get ledger %d: %v", seqs[0], err)
+	}
+	if _, err := backfillLedgerEventXdr(ctx, failing, network.TestNetworkPassphrase, ledger); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestApplyLedgerFeeBumpFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	total := replayFixtures(t, NewIndexer(store), FEE_BUMP_FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:          1,
+		Transactions:     3,
+		ContractEvents:   4,
+		GovernorEvents:   2,
+		EventsApplied:    2,
+		VotesInserted:    1,
+		ProposalsMutated: 2,
+		ParseFailures:    0,
+		Contracts:        map[string]db.EventCounts{testContractId: {Seen: 2, Parsed: 2}},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	// the vote is stored with the fee bump (outer) hash, which is the hash Stellar RPC reports for the transaction
+	ledger, err := ledgerfixture.Read(ledgerfixture.Path(FEE_BUMP_FIXTURE_DIR, 1170134))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	envelope := ledger.TransactionEnvelopes()[2]
+	if envelope.Type != xdr.EnvelopeTypeEnvelopeTypeTxFeeBump {
+		t.Fatalf("got envelope type %v, want a fee bump", envelope.Type)
+	}
+	outerHash, err := network.HashTransactionInEnvelope(envelope, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("failed to hash fee bump transaction: %v", err)
+	}
+	innerHash, err := network.HashTransaction(envelope.FeeBump.Tx.InnerTx.V1.Tx, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("failed to hash inner transaction: %v", err)
+	}
+	wantTxHash := "3bd97004a5a4fe16d8f5c786097ad056dfe1777b89b917383973471ffc781a5f"
+	if got := hex.EncodeToString(outerHash[:]); got != wantTxHash {
+		t.Fatalf("got fee bump hash %s, want %s", got, wantTxHash)
+	}
+	if hex.EncodeToString(innerHash[:]) == wantTxHash {
+		t.Fatalf("inner and fee bump hashes are both %s", wantTxHash)
+	}
+
+	votes, err := store.GetVotesByProposal(ctx, testContractId, 1, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	wantVotes := []*governor.Vote{
+		{
+			TxHash:          wantTxHash,
+			ContractId:      testContractId,
+			ProposalId:      1,
+			Voter:           "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q",
+			Support:         0,
+			Amount:          "20000000000",
+			LedgerSeq:       1170134,
+			LedgerCloseTime: 1761053041,
+		},
+	}
+	if diff := cmp.Diff(wantVotes, votes); diff != "" {
+		t.Errorf("votes mismatch (-want +got):\n%s", diff)
+	}
+
+	events, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	// Stellar RPC's getEvents id for the second event of the third transaction in ledger 1170134, which is
+	// the toid of (ledger 1170134, transaction 3, operation 0), then the event index
+	wantEvent := "0005025687261949952-0000000001"
+	if events[1].EventId != wantEvent || events[1].TxHash != wantTxHash {
+		t.Errorf("got vote event %s in tx %s, want %s in tx %s", events[1].EventId, events[1].TxHash, wantEvent, wantTxHash)
+	}
+	// the event is recorded with the inner transaction's source account, which submitted the vote, not the fee payer
+	if events[1].SourceAccount != fixtureSourceAccount {
+		t.Errorf("got vote event source account %s, want %s", events[1].SourceAccount, fixtureSourceAccount)
+	}
+}
+
+func TestApplyLedgerSchemaFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	total := replayFixtures(t, NewIndexer(store), SCHEMA_FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:          1,
+		Transactions:     3,
+		ContractEvents:   3,
+		GovernorEvents:   2,
+		EventsApplied:    2,
+		VotesInserted:    1,
+		ProposalsMutated: 2,
+		ParseFailures:    0,
+		FailedEvents:     1,
+		Contracts:        map[string]db.EventCounts{testContractId: {Seen: 3, Parsed: 2, Failed: 1}},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	events, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	var versions []uint32
+	for _, event := range events {
+		versions = append(versions, event.SchemaVersion)
+	}
+	if diff := cmp.Diff([]uint32{governor.SCHEMA_V2, governor.SCHEMA_V2}, versions); diff != "" {
+		t.Errorf("schema versions mismatch (-want +got):\n%s", diff)
+	}
+
+	proposal, _, err := store.GetProposalVersion(ctx, governor.EncodeProposalKey(testContractId, 1))
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if proposal.Title != "Make me security council" || proposal.VotesAgainst != "20000000000" {
+		t.Errorf("got proposal %q with %s votes against, want the v2 proposal with one vote", proposal.Title, proposal.VotesAgainst)
+	}
+
+	// the v3 vote is not applied
+	failedEvents, err := store.GetFailedEventsByContractId(ctx, testContractId)
+	if err != nil {
+		t.Fatalf("failed to get failed events: %v", err)
+	}
+	if len(failedEvents) != 1 {
+		t.Fatalf("got %d failed events, want 1", len(failedEvents))
+	}
+	got := failedEvents[0]
+	if got.EventId != "0005025687261949952-0000000000" || got.EventType != "vote_cast" || got.Reason != governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION {
+		t.Errorf("got failed event %s %s %s, want 0005025687261949952-0000000000 vote_cast %s", got.EventId, got.EventType, got.Reason, governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION)
+	}
+	var event xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(got.EventXdr, &event); err != nil {
+		t.Errorf("failed to unmarshal failed event xdr: %v", err)
+	}
+}
+
+func TestApplyLedgerDelegationFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	total := replayFixtures(t, NewIndexer(store), DELEGATION_FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:          2,
+		Transactions:     4,
+		ContractEvents:   5,
+		GovernorEvents:   5,
+		EventsApplied:    3,
+		VotesInserted:    0,
+		ProposalsMutated: 1,
+		ParseFailures:    0,
+		// delegations from the unrelated contract are counted, but are dropped when recorded as it is not registered
+		Contracts: map[string]db.EventCounts{
+			testContractId: {Seen: 1, Parsed: 1},
+			"CCQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABDMD": {Seen: 3, Parsed: 3},
+			"CCYAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAASCH": {Seen: 1, Parsed: 1},
+		},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	votesId, err := strkey.Encode(strkey.VersionByteContract, []byte{0xa0, 31: 0})
+	if err != nil {
+		t.Fatalf("failed to encode votes contract id: %v", err)
+	}
+	alice, err := newAccountAddress(0x01).String()
+	if err != nil {
+		t.Fatalf("failed to encode address: %v", err)
+	}
+	bob, err := newAccountAddress(0x02).String()
+	if err != nil {
+		t.Fatalf("failed to encode address: %v", err)
+	}
+
+	// the delegation before the votes contract was known is skipped
+	delegators, err := store.GetDelegators(ctx, votesId, bob)
+	if err != nil {
+		t.Fatalf("failed to get delegators: %v", err)
+	}
+	wantDelegators := []*governor.Delegation{
+		{
+			EventId:         "0005025695851876352-0000000000",
+			ContractId:      votesId,
+			Delegator:       alice,
+			FromDelegate:    alice,
+			Delegate:        bob,
+			TxHash:          "e059d33555b711aca81e8ed32053561c660b50fc168a3270651a603ec8fe5904",
+			LedgerSeq:       1170136,
+			LedgerCloseTime: 1761053046,
+		},
+	}
+	if diff := cmp.Diff(wantDelegators, delegators); diff != "" {
+		t.Errorf("delegators mismatch (-want +got):\n%s", diff)
+	}
+
+	events, err := store.GetEventsByContractId(ctx, votesId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	var eventTypes []string
+	for _, event := range events {
+		eventTypes = append(eventTypes, event.EventType)
+	}
+	if diff := cmp.Diff([]string{"delegate_changed", "delegate_votes_changed"}, eventTypes); diff != "" {
+		t.Errorf("event types mismatch (-want +got):\n%s", diff)
+	}
+
+	// the governor's settings are read from the same instance
+	settings, err := store.GetGovernorSettings(ctx, testContractId, 1170134)
+	if err != nil {
+		t.Fatalf("failed to get governor settings: %v", err)
+	}
+	wantSettings := &governor.Settings{
+		GovernorId:    testContractId,
+		CountingType:  governor.COUNTING_TYPE_FOR | governor.COUNTING_TYPE_ABSTAIN,
+		Quorum:        500,
+		VoteThreshold: 5100,
+		LedgerSeq:     1170134,
+	}
+	if diff := cmp.Diff(wantSettings, settings); diff != "" {
+		t.Errorf("settings mismatch (-want +got):\n%s", diff)
+	}
+
+	// the unrelated contract is not a votes contract
+	otherId, err := strkey.Encode(strkey.VersionByteContract, []byte{0xb0, 31: 0})
+	if err != nil {
+		t.Fatalf("failed to encode contract id: %v", err)
+	}
+	history, err := store.GetDelegationHistory(ctx, otherId, bob)
+	if err != nil {
+		t.Fatalf("failed to get delegation history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("got %d delegations from an unknown votes contract, want 0", len(history))
+	}
+}
+
+func TestApplyLedgerUnknownTypeFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	total := replayFixtures(t, NewIndexer(store), UNKNOWN_TYPE_FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:           1,
+		Transactions:      3,
+		ContractEvents:    3,
+		GovernorEvents:    1,
+		EventsApplied:     1,
+		VotesInserted:     0,
+		ProposalsMutated:  1,
+		ParseFailures:     0,
+		FailedEvents:      1,
+		UnknownEventTypes: 1,
+		Contracts:         map[string]db.EventCounts{testContractId: {Seen: 2, Parsed: 1, Failed: 1}},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	failedEvents, err := store.GetFailedEventsByContractId(ctx, testContractId)
+	if err != nil {
+		t.Fatalf("failed to get failed events: %v", err)
+	}
+	if len(failedEvents) != 1 {
+		t.Fatalf("got %d failed events, want 1", len(failedEvents))
+	}
+	got := failedEvents[0]
+	if got.EventId != "0005025687261945856-0000000000" || got.EventType != "proposal_queued" || got.Reason != governor.FAILED_REASON_UNKNOWN_EVENT_TYPE {
+		t.Errorf("got failed event %s %s %s, want 0005025687261945856-0000000000 proposal_queued %s", got.EventId, got.EventType, got.Reason, governor.FAILED_REASON_UNKNOWN_EVENT_TYPE)
+	}
+
+	// the event from the untracked contract is dropped
+	otherId, err := strkey.Encode(strkey.VersionByteContract, []byte{0xb0, 31: 0})
+	if err != nil {
+		t.Fatalf("failed to encode contract id: %v", err)
+	}
+	failedEvents, err = store.GetFailedEventsByContractId(ctx, otherId)
+	if err != nil {
+		t.Fatalf("failed to get failed events: %v", err)
+	}
+	if len(failedEvents) != 0 {
+		t.Errorf("got %d failed events from an untracked contract, want 0", len(failedEvents))
+	}
+}
+
+// TestApplyLedgerUntrackedUnknownType verifies unknown event types from untracked contracts are dropped without a
+// database read, as any contract on the network can emit them
+func TestApplyLedgerUntrackedUnknownType(t *testing.T) {
+	otherId := xdr.ContractId{0xb0}
+	queued := newGovernorEvent(withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1), "proposal_queued", 1)
+	queued.ContractId = &otherId
+	ledger := newFixtureLedger(t, 1170134, 1761053041, []fixtureTx{{events: []xdr.ContractEvent{queued}}, {events: []xdr.ContractEvent{queued}}})
+
+	store := &mockStore{
+		getBlockedContracts: func(ctx context.Context) ([]*db.BlockedContract, error) { return nil, nil },
+		getTrackedContracts: func(ctx context.Context) ([]string, error) { return []string{testContractId}, nil },
+	}
+	idx := NewIndexer(store)
+	stats := applyFixtureLedger(t, idx, ledger)
+	if stats.FailedEvents != 0 || stats.UnknownEventTypes != 0 {
+		t.Errorf("got %d failed events and %d unknown event types, want 0", stats.FailedEvents, stats.UnknownEventTypes)
+	}
+	// the tracked contracts are only loaded once
+	applyFixtureLedger(t, idx, newFixtureLedger(t, 1170135, 1761053046, []fixtureTx{{events: []xdr.ContractEvent{queued}}}))
+	if diff := cmp.Diff([]string{"GetBlockedContracts", "GetTrackedContracts"}, store.calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestApplyLedgerParseFailureLogLevel verifies events that fail to parse are logged as errors if their contract is
+// tracked, and only at debug level otherwise, as any contract can emit events named like governor events
+func TestApplyLedgerParseFailureLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	tracked := newVoteCastEvent(t, 1, 0, 0, 10)
+	sym := xdr.ScSymbol("for")
+	data := xdr.ScVec{{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, {Type: xdr.ScValTypeScvVoid}}
+	dataVec := &data
+	tracked.Body.V0.Data = xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &dataVec}
+	untracked := tracked
+	otherId := xdr.ContractId{0xb0}
+	untracked.ContractId = &otherId
+
+	tests := []struct {
+		name      string
+		event     xdr.ContractEvent
+		wantLevel string
+	}{
+		{name: "tracked contract", event: tracked, wantLevel: "level=ERROR"},
+		{name: "untracked contract", event: untracked, wantLevel: "level=DEBUG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{
+				getBlockedContracts: func(ctx context.Context) ([]*db.BlockedContract, error) { return nil, nil },
+				getTrackedContracts: func(ctx context.Context) ([]string, error) { return []string{testContractId}, nil },
+			}
+			buf.Reset()
+			stats := applyFixtureLedger(t, NewIndexer(store), newFixtureLedger(t, 1170134, 1761053041, []fixtureTx{{events: []xdr.ContractEvent{tt.event}}}))
+			if stats.ParseFailures != 1 {
+				t.Fatalf("got %d parse failures, want 1", stats.ParseFailures)
+			}
+			var line string
+			for l := range strings.SplitSeq(buf.String(), "\n") {
+				if strings.Contains(l, "Failed parsing event") {
+					line = l
+				}
+			}
+			if !strings.Contains(line, tt.wantLevel) {
+				t.Errorf("got parse failure log %q, want %s", line, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestApplyLedgerMultiOpFixtures(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+
+	total := replayFixtures(t, NewIndexer(store), MULTI_OP_FIXTURE_DIR)
+	wantStats := LedgerStats{
+		Ledgers:          1,
+		Transactions:     2,
+		ContractEvents:   4,
+		GovernorEvents:   3,
+		EventsApplied:    3,
+		ProposalsMutated: 3,
+		Contracts:        map[string]db.EventCounts{testContractId: {Seen: 3, Parsed: 3}},
+	}
+	if diff := cmp.Diff(wantStats, total); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	events, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	// each event id is the toid of the operation that emitted it, then the event's index in the operation
+	wantIds := []string{
+		mustEncodeEventId(t, toid.New(1170134, 1, 0).ToInt64(), 0),
+		mustEncodeEventId(t, toid.New(1170134, 2, 0).ToInt64(), 0),
+		mustEncodeEventId(t, toid.New(1170134, 2, 1).ToInt64(), 1),
+	}
+	gotIds := make([]string, len(events))
+	for i, event := range events {
+		gotIds[i] = event.EventId
+	}
+	if diff := cmp.Diff(wantIds, gotIds); diff != "" {
+		t.Errorf("event ids mismatch (-want +got):\n%s", diff)
+	}
+	if events[1].TxHash != events[2].TxHash {
+		t.Errorf("got events in txs %s and %s, want the same transaction", events[1].TxHash, events[2].TxHash)
+	}
+
+	// the cancel is applied after the proposal is created, and is its last update
+	proposal, err := store.GetProposal(ctx, governor.EncodeProposalKey(testContractId, 2))
+	if err != nil {
+		t.Fatalf("failed to get proposal: %v", err)
+	}
+	if proposal.Status != uint32(governor.PROPOSAL_STATUS_CANCELED) || proposal.UpdatedEventId != wantIds[2] {
+		t.Errorf("got proposal status %d updated by %s, want %d updated by %s", proposal.Status, proposal.UpdatedEventId, governor.PROPOSAL_STATUS_CANCELED, wantIds[2])
+	}
+}
+
+// failingStore is a Store whose writes fail with err once failAfter writes have succeeded
+type failingStore struct {
+	Store
+	writes    int
+	failAfter int
+	err       error
+}
+
+func (s *failingStore) write() error {
+	s.writes++
+	if s.writes > s.failAfter {
+		return s.err
+	}
+	return nil
+}
+
+func (s *failingStore) InsertEvent(ctx context.Context, event *governor.GovernorEvent) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.InsertEvent(ctx, event)
+}
+
+func (s *failingStore) InsertEvents(ctx context.Context, events []*governor.GovernorEvent) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.InsertEvents(ctx, events)
+}
+
+func (s *failingStore) InsertProposal(ctx context.Context, proposal *governor.Proposal) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.InsertProposal(ctx, proposal)
+}
+
+func (s *failingStore) UpdateProposal(ctx context.Context, proposal *governor.Proposal, version int64) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.UpdateProposal(ctx, proposal, version)
+}
+
+func (s *failingStore) InsertVote(ctx context.Context, vote *governor.Vote) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.InsertVote(ctx, vote)
+}
+
+func (s *failingStore) UpsertContractActivity(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.UpsertContractActivity(ctx, contractId, eventId, ledgerSeq, ledgerCloseTime, reviewed)
+}
+
+func (s *failingStore) UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	return s.Store.UpsertStatus(ctx, source, ledgerSeq, ledgerCloseTime)
+}
+
+// TestProcessLedgerStoreFailure verifies a ledger that fails part way through is not recorded as processed, and
+// applies the same changes when it is retried
+func TestProcessLedgerStoreFailure(t *testing.T) {
+	ctx := t.Context()
+	backend, err := ledgerfixture.NewBackend(FIXTURE_DIR)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	defer backend.Close()
+	seqs := backend.Sequences()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seqs[0], seqs[len(seqs)-1])); err != nil {
+		t.Fatalf("failed to prepare range: %v", err)
+	}
+
+	store := newFixtureStore(t)
+	failing := &failingStore{Store: store}
+	idx := NewIndexer(failing)
+	lastLedger := func() uint32 {
+		t.Helper()
+		seq, _, err := store.GetStatus(ctx, STATUS_SOURCE)
+		if err != nil {
+			t.Fatalf("failed to get last processed ledger: %v", err)
+		}
+		return seq
+	}
+
+	storeErr := errors.New("disk I/O error")
+	failures := make(map[error]int)
+	for i, seq := range seqs {
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatalf("failed to get ledger %d: %v", seq, err)
+		}
+		before := lastLedger()
+
+		// fail the second write, part way through applying the ledger's events, with a timeout or another store error
+		failing.writes, failing.failAfter, failing.err = 0, 1, db.ErrTimeout
+		if i%2 == 1 {
+			failing.err = storeErr
+		}
+		if _, err := idx.processLedger(ctx, network.TestNetworkPassphrase, ledger); err != nil {
+			if !errors.Is(err, ErrLedgerRetryable) {
+				t.Fatalf("processLedger(%d) error = %v, want ErrLedgerRetryable", seq, err)
+			}
+			if got := lastLedger(); got != before {
+				t.Fatalf("last processed ledger = %d after failing ledger %d, want %d", got, seq, before)
+			}
+			failures[failing.err]++
+
+			failing.writes, failing.failAfter = 0, math.MaxInt
+			if _, err := idx.processLedger(ctx, network.TestNetworkPassphrase, ledger); err != nil {
+				t.Fatalf("processLedger(%d) retry error = %v", seq, err)
+			}
+		}
+		if got := lastLedger(); got != seq {
+			t.Fatalf("last processed ledger = %d, want %d", got, seq)
+		}
+	}
+	if len(failures) != 2 {
+		t.Fatalf("got failures %v, want ledgers failed by a timeout and another store error", failures)
+	}
+
+	// the retried ledgers are applied as if they never failed
+	want := newFixtureStore(t)
+	replayFixtures(t, NewIndexer(want), FIXTURE_DIR)
+	wantProposals, err := want.GetProposalsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get proposals: %v", err)
+	}
+	proposals, err := store.GetProposalsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get proposals: %v", err)
+	}
+	ignoreUpdatedAt := cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".UpdatedAt" }, cmp.Ignore())
+	if diff := cmp.Diff(wantProposals, proposals, ignoreUpdatedAt); diff != "" {
+		t.Errorf("proposals mismatch (-want +got):\n%s", diff)
+	}
+	for _, proposal := range wantProposals {
+		wantVotes, err := want.GetVotesByProposal(ctx, testContractId, proposal.ProposalId, db.Sort{})
+		if err != nil {
+			t.Fatalf("failed to get votes: %v", err)
+		}
+		votes, err := store.GetVotesByProposal(ctx, testContractId, proposal.ProposalId, db.Sort{})
+		if err != nil {
+			t.Fatalf("failed to get votes: %v", err)
+		}
+		if diff := cmp.Diff(wantVotes, votes); diff != "" {
+			t.Errorf("proposal %d votes mismatch (-want +got):\n%s", proposal.ProposalId, diff)
+		}
+	}
+
+	// a ledger that can't be read stops the indexer
+	ledger, err := backend.GetLedger(ctx, seqs[0])
+	if err != nil {
+		t.Fatalf("failed to get ledger %d: %v", seqs[0], err)
+	}
+	// the transaction result no longer matches a transaction in the ledger
+	ledger.V1.TxProcessing[0].Result.TransactionHash = xdr.Hash{}
+	failing.writes, failing.failAfter = 0, math.MaxInt
+	if _, err := idx.processLedger(ctx, network.TestNetworkPassphrase, ledger); !errors.Is(err, ErrLedgerInvalid) {
+		t.Errorf("processLedger() error = %v, want ErrLedgerInvalid", err)
+	}
+	if got := lastLedger(); got != seqs[len(seqs)-1] {
+		t.Errorf("last processed ledger = %d after invalid ledger, want %d", got, seqs[len(seqs)-1])
+	}
+}
+
+// sequentialBackend is a ledger backend that, like the RPC backend, only returns the ledger after the last one it
+// returned. Ledgers between the fixtures are returned empty, and the run is cancelled once every fixture has been
+// returned.
+type sequentialBackend struct {
+	ledgerbackend.LedgerBackend
+	next   uint32
+	last   uint32
+	cancel context.CancelFunc
+}
+
+func (b *sequentialBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if sequence != b.next {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("ledger %d requested, next ledger is %d", sequence, b.next)
+	}
+	if sequence > b.last {
+		b.cancel()
+		return xdr.LedgerCloseMeta{}, ctx.Err()
+	}
+	b.next++
+	ledger, err := b.LedgerBackend.GetLedger(ctx, sequence)
+	if errors.Is(err, ledgerfixture.ErrMissingLedger) {
+		header := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)}}
+		return xdr.LedgerCloseMeta{V: 0, V0: &xdr.LedgerCloseMetaV0{LedgerHeader: header}}, nil
+	}
+	return ledger, err
+}
+
+// flakyStatusStore fails to record each ledger as processed the first time, with a timeout
+type flakyStatusStore struct {
+	Store
+	failed map[uint32]bool
+}
+
+func (s *flakyStatusStore) UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error {
+	if !s.failed[ledgerSeq] {
+		s.failed[ledgerSeq] = true
+		return db.ErrTimeout
+	}
+	return s.Store.UpsertStatus(ctx, source, ledgerSeq, ledgerCloseTime)
+}
+
+// TestIngestRetryable verifies a ledger that fails with a retryable error is processed again without being fetched
+// again, as the RPC backend only returns the next ledger
+func TestIngestRetryable(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	fixtures, err := ledgerfixture.NewBackend(FIXTURE_DIR)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	defer fixtures.Close()
+	seqs := fixtures.Sequences()
+	if err := fixtures.PrepareRange(ctx, ledgerbackend.BoundedRange(seqs[0], seqs[len(seqs)-1])); err != nil {
+		t.Fatalf("failed to prepare range: %v", err)
+	}
+	backend := &sequentialBackend{LedgerBackend: fixtures, next: seqs[0], last: seqs[len(seqs)-1], cancel: cancel}
+
+	store := newFixtureStore(t)
+	flaky := &flakyStatusStore{Store: store, failed: make(map[uint32]bool)}
+	idx := NewIndexer(flaky)
+	idx.retryDelay = time.Millisecond
+	progress := newProgressTracker(nil, STATUS_SOURCE, seqs[0], 0, nil)
+
+	if err := idx.ingest(ctx, backend, nil, &Config{}, network.TestNetworkPassphrase, seqs[0], 0, progress); err != nil {
+		t.Fatalf("ingest() error = %v", err)
+	}
+	if want := int(seqs[len(seqs)-1]-seqs[0]) + 1; len(flaky.failed) != want {
+		t.Errorf("got %d ledgers failed, want %d", len(flaky.failed), want)
+	}
+	lastLedger, _, err := store.GetStatus(t.Context(), STATUS_SOURCE)
+	if err != nil {
+		t.Fatalf("failed to get last processed ledger: %v", err)
+	}
+	if lastLedger != seqs[len(seqs)-1] {
+		t.Errorf("last processed ledger = %d, want %d", lastLedger, seqs[len(seqs)-1])
+	}
+
+	want := newFixtureStore(t)
+	replayFixtures(t, NewIndexer(want), FIXTURE_DIR)
+	wantProposals, err := want.GetProposalsByContractId(t.Context(), testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get proposals: %v", err)
+	}
+	proposals, err := store.GetProposalsByContractId(t.Context(), testContractId, db.Sort{})
+	if err != nil {
+		t.Fatalf("failed to get proposals: %v", err)
+	}
+	ignoreUpdatedAt := cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".UpdatedAt" }, cmp.Ignore())
+	if diff := cmp.Diff(wantProposals, proposals, ignoreUpdatedAt); diff != "" {
+		t.Errorf("proposals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// newFixtureStore opens an empty in memory store
+func newFixtureStore(t testing.TB) *db.Store {
+	t.Helper()
+	sqlDb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDb.Close() })
+	if err := db.RunMigrations(sqlDb); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db.NewStore(sqlDb)
+}
+
+// replayFixtures applies every ledger fixture in dir, in order, and returns the total stats
+func replayFixtures(t *testing.T, idx *Indexer, dir string) LedgerStats {
+	t.Helper()
+	ctx := t.Context()
+
+	backend, err := ledgerfixture.NewBackend(dir)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	defer backend.Close()
+	seqs := backend.Sequences()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seqs[0], seqs[len(seqs)-1])); err != nil {
+		t.Fatalf("failed to prepare range: %v", err)
+	}
+
+	var total LedgerStats
+	for _, seq := range seqs {
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatalf("failed to get ledger %d: %v", seq, err)
+		}
+		txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, ledger)
+		if err != nil {
+			t.Fatalf("failed to create transaction reader for ledger %d: %v", seq, err)
+		}
+		stats, err := idx.ApplyLedger(ctx, txReader, ledger.LedgerSequence(), ledger.LedgerCloseTime())
+		if err != nil {
+			t.Fatalf("ApplyLedger(%d) error = %v", seq, err)
+		}
+		total.Add(stats)
+	}
+	return total
+}
+
+// writeFixtureLedgers builds and writes the ledger fixtures to dir
+func writeFixtureLedgers(t *testing.T, dir string, ledgers []fixtureLedger) {
+	t.Helper()
+	for _, l := range ledgers {
+		ledger := newFixtureLedger(t, l.seq, l.closeTime, l.txs)
+		if err := ledgerfixture.Write(dir, ledger); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+}
+
+func mustDecodeEvent(t testing.TB, eventXdr string) xdr.ContractEvent {
+	t.Helper()
+	var event xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(eventXdr, &event); err != nil {
+		t.Fatalf("failed to unmarshal contract event xdr: %v", err)
+	}
+	return event
+}
+
+// withProposalId returns the event with its proposal id topic replaced
+func withProposalId(t testing.TB, event xdr.ContractEvent, proposalId uint32) xdr.ContractEvent {
+	t.Helper()
+	id := xdr.Uint32(proposalId)
+	event.Body.V0.Topics[1] = xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &id}
+	return event
+}
+
+// withSchemaVersion returns a copy of a v1 event in a later schema version, with the version topic added and vec data
+// converted to a map with the given keys
+func withSchemaVersion(event xdr.ContractEvent, version string, keys ...string) xdr.ContractEvent {
+	body := *event.Body.V0
+	versionSym := xdr.ScSymbol(version)
+	versionTopic := xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &versionSym}
+	body.Topics = append([]xdr.ScVal{body.Topics[0], versionTopic}, body.Topics[1:]...)
+
+	if len(keys) > 0 {
+		vec := *body.Data.MustVec()
+		m := make(xdr.ScMap, len(keys))
+		for i, key := range keys {
+			sym := xdr.ScSymbol(key)
+			m[i] = xdr.ScMapEntry{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, Val: vec[i]}
+		}
+		mp := &m
+		body.Data = xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &mp}
+	}
+	event.Body.V0 = &body
+	return event
+}
+
+// newGovernorEvent creates a governor event with no data, emitted by the same contract as base
+func newGovernorEvent(base xdr.ContractEvent, eventType string, proposalId uint32) xdr.ContractEvent {
+	sym := xdr.ScSymbol(eventType)
+	id := xdr.Uint32(proposalId)
+	return xdr.ContractEvent{
+		Type:       xdr.ContractEventTypeContract,
+		ContractId: base.ContractId,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{
+					{Type: xdr.ScValTypeScvSymbol, Sym: &sym},
+					{Type: xdr.ScValTypeScvU32, U32: &id},
+				},
+				Data: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+			},
+		},
+	}
+}
+
+// newTransferEvent creates a token transfer event, which the indexer must ignore
+func newTransferEvent(base xdr.ContractEvent) xdr.ContractEvent {
+	sym := xdr.ScSymbol("transfer")
+	address := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: base.ContractId}
+	amount := xdr.Int128Parts{Lo: 100}
+	return xdr.ContractEvent{
+		Type:       xdr.ContractEventTypeContract,
+		ContractId: base.ContractId,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{
+					{Type: xdr.ScValTypeScvSymbol, Sym: &sym},
+					{Type: xdr.ScValTypeScvAddress, Address: &address},
+					{Type: xdr.ScValTypeScvAddress, Address: &address},
+				},
+				Data: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &amount},
+			},
+		},
+	}
+}
+
+// newAccountAddress creates an account address with an ed25519 key of b repeated
+func newAccountAddress(b byte) xdr.ScAddress {
+	var key xdr.Uint256
+	for i := range key {
+		key[i] = b
+	}
+	accountId := xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &key}
+	return xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &accountId}
+}
+
+// newDelegationEvent creates an event emitted by a votes contract with an address topic and vec data
+func newDelegationEvent(contractId xdr.ContractId, eventType string, topic xdr.ScAddress, data ...xdr.ScVal) xdr.ContractEvent {
+	sym := xdr.ScSymbol(eventType)
+	vec := xdr.ScVec(data)
+	vecPtr := &vec
+	return xdr.ContractEvent{
+		Type:       xdr.ContractEventTypeContract,
+		ContractId: &contractId,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{
+					{Type: xdr.ScValTypeScvSymbol, Sym: &sym},
+					{Type: xdr.ScValTypeScvAddress, Address: &topic},
+				},
+				Data: xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &vecPtr},
+			},
+		},
+	}
+}
+
+// newDelegateChangedEvent creates a delegate_changed event emitted by a votes contract
+func newDelegateChangedEvent(contractId xdr.ContractId, delegator xdr.ScAddress, from xdr.ScAddress, to xdr.ScAddress) xdr.ContractEvent {
+	return newDelegationEvent(contractId, "delegate_changed", delegator,
+		xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &from},
+		xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &to},
+	)
+}
+
+// newDelegateVotesChangedEvent creates a delegate_votes_changed event emitted by a votes contract
+func newDelegateVotesChangedEvent(contractId xdr.ContractId, delegate xdr.ScAddress, previousVotes uint64, newVotes uint64) xdr.ContractEvent {
+	previous := xdr.Int128Parts{Lo: xdr.Uint64(previousVotes)}
+	next := xdr.Int128Parts{Lo: xdr.Uint64(newVotes)}
+	return newDelegationEvent(contractId, "delegate_votes_changed", delegate,
+		xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &previous},
+		xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &next},
+	)
+}
+
+// newGovernorInstanceChanges creates the ledger entry change writing a governor's contract instance, with its votes
+// contract and settings in instance storage
+func newGovernorInstanceChanges(governorId xdr.ContractId, votesId xdr.ContractId) xdr.LedgerEntryChanges {
+	votesKey := xdr.ScSymbol(governor.VOTES_INSTANCE_KEY)
+	votes := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &votesId}
+	settingsKey := xdr.ScSymbol(governor.SETTINGS_INSTANCE_KEY)
+	u32 := func(val uint32) xdr.ScVal {
+		v := xdr.Uint32(val)
+		return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+	}
+	sym := func(val string) xdr.ScVal {
+		v := xdr.ScSymbol(val)
+		return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &v}
+	}
+	proposalThreshold := xdr.Int128Parts{Lo: 10000000}
+	settings := &xdr.ScMap{
+		{Key: sym("counting_type"), Val: u32(governor.COUNTING_TYPE_FOR | governor.COUNTING_TYPE_ABSTAIN)},
+		{Key: sym("grace_period"), Val: u32(17280)},
+		{Key: sym("proposal_threshold"), Val: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &proposalThreshold}},
+		{Key: sym("quorum"), Val: u32(500)},
+		{Key: sym("timelock"), Val: u32(0)},
+		{Key: sym("vote_delay"), Val: u32(0)},
+		{Key: sym("vote_period"), Val: u32(17280)},
+		{Key: sym("vote_threshold"), Val: u32(5100)},
+	}
+	storage := xdr.ScMap{
+		{
+			Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &settingsKey},
+			Val: xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &settings},
+		},
+		{
+			Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &votesKey},
+			Val: xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &votes},
+		},
+	}
+	instance := xdr.ScContractInstance{
+		Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &xdr.Hash{}},
+		Storage:    &storage,
+	}
+	return xdr.LedgerEntryChanges{
+		{
+			Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+			Created: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeContractData,
+					ContractData: &xdr.ContractDataEntry{
+						Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &governorId},
+						Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+						Durability: xdr.ContractDataDurabilityPersistent,
+						Val:        xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &instance},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newFixtureLedger builds a testnet LedgerCloseMeta containing a soroban transaction for each fixtureTx
+func newFixtureLedger(t testing.TB, seq uint32, closeTime int64, txs []fixtureTx) xdr.LedgerCloseMeta {
+	t.Helper()
+
+	envelopes := make([]xdr.TransactionEnvelope, len(txs))
+	processing := make([]xdr.TransactionResultMeta, len(txs))
+	for i, tx := range txs {
+		// transactions must have unique hashes, so ledgers with more than 256 transactions vary the source account
+		source := xdr.MuxedAccount{Type: xdr.CryptoKeyTypeKeyTypeEd25519, Ed25519: &xdr.Uint256{1, byte(i >> 8)}}
+		opEvents := tx.ops
+		if opEvents == nil {
+			opEvents = [][]xdr.ContractEvent{tx.events}
+		}
+		contract := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: opEvents[0][0].ContractId}
+		operations := make([]xdr.Operation, len(opEvents))
+		for j := range operations {
+			operations[j] = xdr.Operation{
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypeInvokeHostFunction,
+					InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+						HostFunction: xdr.HostFunction{
+							Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+							InvokeContract: &xdr.InvokeContractArgs{
+								ContractAddress: contract,
+								FunctionName:    "fixture",
+								Args:            []xdr.ScVal{},
+							},
+						},
+					},
+				},
+			}
+		}
+		envelopes[i] = xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: source,
+					Fee:           100,
+					// transactions must have unique hashes
+					SeqNum:     xdr.SequenceNumber(int64(seq)<<8 | int64(i)),
+					Cond:       xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+					Memo:       xdr.Memo{Type: xdr.MemoTypeMemoNone},
+					Operations: operations,
+					Ext:        xdr.TransactionExt{V: 1, SorobanData: &xdr.SorobanTransactionData{}},
+				},
+			},
+		}
+
+		resultCode := xdr.TransactionResultCodeTxSuccess
+		opResult := xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess, Success: &xdr.Hash{}}
+		if tx.failed {
+			resultCode = xdr.TransactionResultCodeTxFailed
+			opResult = xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionTrapped}
+		}
+		opResults := make([]xdr.OperationResult, len(operations))
+		for j := range opResults {
+			opResults[j] = xdr.OperationResult{
+				Code: xdr.OperationResultCodeOpInner,
+				Tr:   &xdr.OperationResultTr{Type: xdr.OperationTypeInvokeHostFunction, InvokeHostFunctionResult: &opResult},
+			}
+		}
+		result := xdr.TransactionResult{
+			FeeCharged: 100,
+			Result:     xdr.TransactionResultResult{Code: resultCode, Results: &opResults},
+		}
+
+		if tx.feeBump {
+			// wrap the transaction in a fee bump paid by another account. The result holds the inner result.
+			innerHash, err := network.HashTransactionInEnvelope(envelopes[i], network.TestNetworkPassphrase)
+			if err != nil {
+				t.Fatalf("failed to hash inner transaction: %v", err)
+			}
+			innerResultCode := xdr.TransactionResultCodeTxFeeBumpInnerSuccess
+			if tx.failed {
+				innerResultCode = xdr.TransactionResultCodeTxFeeBumpInnerFailed
+			}
+			result = xdr.TransactionResult{
+				FeeCharged: 200,
+				Result: xdr.TransactionResultResult{
+					Code: innerResultCode,
+					InnerResultPair: &xdr.InnerTransactionResultPair{
+						TransactionHash: innerHash,
+						Result: xdr.InnerTransactionResult{
+							FeeCharged: 100,
+							Result:     xdr.InnerTransactionResultResult{Code: resultCode, Results: &opResults},
+						},
+					},
+				},
+			}
+			envelopes[i] = xdr.TransactionEnvelope{
+				Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+				FeeBump: &xdr.FeeBumpTransactionEnvelope{
+					Tx: xdr.FeeBumpTransaction{
+						FeeSource: xdr.MuxedAccount{Type: xdr.CryptoKeyTypeKeyTypeEd25519, Ed25519: &xdr.Uint256{2}},
+						Fee:       200,
+						InnerTx: xdr.FeeBumpTransactionInnerTx{
+							Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+							V1:   envelopes[i].V1,
+						},
+					},
+				},
+			}
+		}
+
+		hash, err := network.HashTransactionInEnvelope(envelopes[i], network.TestNetworkPassphrase)
+		if err != nil {
+			t.Fatalf("failed to hash transaction: %v", err)
+		}
+		meta := xdr.TransactionMeta{
+			V: 3,
+			V3: &xdr.TransactionMetaV3{
+				Operations: []xdr.OperationMeta{{Changes: tx.changes}},
+				SorobanMeta: &xdr.SorobanTransactionMeta{
+					Events:      tx.events,
+					ReturnValue: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+				},
+			},
+		}
+		if tx.failed {
+			// the contract events of a failed transaction are rolled back, but are kept in its diagnostic events
+			for _, event := range tx.events {
+				meta.V3.SorobanMeta.DiagnosticEvents = append(meta.V3.SorobanMeta.DiagnosticEvents, xdr.DiagnosticEvent{Event: event})
+			}
+		}
+		if tx.ops != nil {
+			// v4 meta records the events of each operation
+			operationMeta := make([]xdr.OperationMetaV2, len(tx.ops))
+			for j, events := range tx.ops {
+				operationMeta[j] = xdr.OperationMetaV2{Events: events}
+			}
+			operationMeta[0].Changes = tx.changes
+			void := xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+			meta = xdr.TransactionMeta{
+				V: 4,
+				V4: &xdr.TransactionMetaV4{
+					Operations:  operationMeta,
+					SorobanMeta: &xdr.SorobanTransactionMetaV2{ReturnValue: &void},
+				},
+			}
+		}
+		processing[i] = xdr.TransactionResultMeta{
+			Result: xdr.TransactionResultPair{
+				TransactionHash: hash,
+				Result:          result,
+			},
+			TxApplyProcessing: meta,
+		}
+	}
+
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerVersion: 23,
+					LedgerSeq:     xdr.Uint32(seq),
+					ScpValue:      xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime)},
+				},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &xdr.TransactionSetV1{
+					Phases: []xdr.TransactionPhase{
+						{
+							V: 0,
+							V0Components: &[]xdr.TxSetComponent{
+								{
+									Type:                  xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+									TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{Txs: envelopes},
+								},
+							},
+						},
+					},
+				},
+			},
+			TxProcessing: processing,
+		},
+	}
+}
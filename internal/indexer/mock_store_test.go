@@ -0,0 +1,482 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+var errUnexpectedCall = errors.New("unexpected store call")
+
+// mockStore is a Store whose methods are set per test. It records the name of each method called,
+// and methods that are not set return errUnexpectedCall. WithTx and WithSavepoint run fn directly unless set, and
+// AfterTx runs fn directly without being recorded.
+type mockStore struct {
+	calls []string
+
+	withTx                        func(ctx context.Context, fn func(ctx context.Context) error) error
+	withSavepoint                 func(ctx context.Context, fn func(ctx context.Context) error) error
+	insertEvent                   func(ctx context.Context, event *governor.GovernorEvent) error
+	insertEvents                  func(ctx context.Context, events []*governor.GovernorEvent) error
+	getEvent                      func(ctx context.Context, eventId string) (*governor.GovernorEvent, error)
+	getEventsByContractId         func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.GovernorEvent, error)
+	pruneHistory                  func(ctx context.Context, beforeLedger uint32, batchSize int) (int64, error)
+	setEventXdr                   func(ctx context.Context, eventId string, eventXdr string) (bool, error)
+	insertFailedEvent             func(ctx context.Context, event *governor.FailedEvent) error
+	getFailedEvents               func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error)
+	markFailedEventProcessed      func(ctx context.Context, eventId string, processedAt int64) error
+	recordFailedEventAttempt      func(ctx context.Context, eventId string, attemptErr string) error
+	insertFailedTxEvent           func(ctx context.Context, event *governor.FailedTxEvent) error
+	insertIndexerError            func(ctx context.Context, indexerError *db.IndexerError, keep int) error
+	upsertStatus                  func(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error
+	getStatus                     func(ctx context.Context, source string) (uint32, int64, error)
+	upsertSourceStatus            func(ctx context.Context, source string, status db.SourceStatus) error
+	upsertIngestionProgress       func(ctx context.Context, progress *db.IngestionProgress) error
+	insertProposal                func(ctx context.Context, proposal *governor.Proposal) error
+	updateProposal                func(ctx context.Context, proposal *governor.Proposal, version int64) error
+	getProposalVersion            func(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error)
+	deleteProposalsByContractId   func(ctx context.Context, contractId string) (int64, error)
+	insertVote                    func(ctx context.Context, vote *governor.Vote) error
+	getVote                       func(ctx context.Context, txHash string) (*governor.Vote, error)
+	countVoters                   func(ctx context.Context, contractId string, proposalId uint32) (int, error)
+	insertVotes                   func(ctx context.Context, votes []*governor.Vote) error
+	getVotesByTxHashes            func(ctx context.Context, txHashes []string) ([]*governor.Vote, error)
+	deleteVotesByContractId       func(ctx context.Context, contractId string) (int64, error)
+	insertDelegation              func(ctx context.Context, delegation *governor.Delegation) error
+	deleteDelegationsByContractId func(ctx context.Context, contractId string) (int64, error)
+	upsertVotesContract           func(ctx context.Context, governorId string, votesId string, ledgerSeq uint32) error
+	isVotesContract               func(ctx context.Context, contractId string) (bool, error)
+	getTrackedContracts           func(ctx context.Context) ([]string, error)
+	upsertGovernorSettings        func(ctx context.Context, settings *governor.Settings) error
+	isContractBlocked             func(ctx context.Context, contractId string) (bool, error)
+	getBlockedContracts           func(ctx context.Context) ([]*db.BlockedContract, error)
+	upsertContractActivity        func(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error
+	getContractLastEventId        func(ctx context.Context, contractId string) (string, error)
+	addContractEventCounts        func(ctx context.Context, contractId string, counts db.EventCounts) (bool, error)
+	getProposalContentToFetch     func(ctx context.Context, now int64, limit int) ([]*governor.ProposalContent, error)
+	upsertProposalContent         func(ctx context.Context, content *governor.ProposalContent) error
+	getVoteTokensToFetch          func(ctx context.Context, limit int) ([]*db.VoteToken, error)
+	setVoteToken                  func(ctx context.Context, contractId string, symbol string, decimals uint32) error
+	getEventsAfter                func(ctx context.Context, contractId string, afterEventId string, limit int) ([]*governor.GovernorEvent, error)
+	getLastEventIds               func(ctx context.Context, afterLedger uint32, toLedger uint32) (map[string]string, error)
+	getProposalsByContractId      func(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error)
+	deleteVotesAfterLedger        func(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error)
+	deleteDelegationsAfterLedger  func(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error)
+	insertSnapshot                func(ctx context.Context, snapshot *db.Snapshot) error
+	getLatestSnapshot             func(ctx context.Context, contractId string, beforeLedger uint32) (*db.Snapshot, error)
+	pruneSnapshots                func(ctx context.Context, contractId string, keep int) (int64, error)
+}
+
+func (m *mockStore) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls = append(m.calls, "WithTx")
+	if m.withTx == nil {
+		return fn(ctx)
+	}
+	return m.withTx(ctx, fn)
+}
+
+func (m *mockStore) WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls = append(m.calls, "WithSavepoint")
+	if m.withSavepoint == nil {
+		return fn(ctx)
+	}
+	return m.withSavepoint(ctx, fn)
+}
+
+func (m *mockStore) AfterTx(ctx context.Context, fn func(ctx context.Context)) {
+	fn(ctx)
+}
+
+func (m *mockStore) InsertEvent(ctx context.Context, event *governor.GovernorEvent) error {
+	m.calls = append(m.calls, "InsertEvent")
+	if m.insertEvent == nil {
+		return errUnexpectedCall
+	}
+	return m.insertEvent(ctx, event)
+}
+
+func (m *mockStore) InsertEvents(ctx context.Context, events []*governor.GovernorEvent) error {
+	m.calls = append(m.calls, "InsertEvents")
+	if m.insertEvents == nil {
+		return errUnexpectedCall
+	}
+	return m.insertEvents(ctx, events)
+}
+
+func (m *mockStore) GetEvent(ctx context.Context, eventId string) (*governor.GovernorEvent, error) {
+	m.calls = append(m.calls, "GetEvent")
+	if m.getEvent == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getEvent(ctx, eventId)
+}
+
+func (m *mockStore) GetEventsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.GovernorEvent, error) {
+	m.calls = append(m.calls, "GetEventsByContractId")
+	if m.getEventsByContractId == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getEventsByContractId(ctx, contractId, sort)
+}
+
+func (m *mockStore) PruneHistory(ctx context.Context, beforeLedger uint32, batchSize int) (int64, error) {
+	m.calls = append(m.calls, "PruneHistory")
+	if m.pruneHistory == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.pruneHistory(ctx, beforeLedger, batchSize)
+}
+
+func (m *mockStore) SetEventXdr(ctx context.Context, eventId string, eventXdr string) (bool, error) {
+	m.calls = append(m.calls, "SetEventXdr")
+	if m.setEventXdr == nil {
+		return false, errUnexpectedCall
+	}
+	return m.setEventXdr(ctx, eventId, eventXdr)
+}
+
+func (m *mockStore) InsertFailedEvent(ctx context.Context, event *governor.FailedEvent) error {
+	m.calls = append(m.calls, "InsertFailedEvent")
+	if m.insertFailedEvent == nil {
+		return errUnexpectedCall
+	}
+	return m.insertFailedEvent(ctx, event)
+}
+
+func (m *mockStore) GetFailedEvents(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+	m.calls = append(m.calls, "GetFailedEvents")
+	if m.getFailedEvents == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getFailedEvents(ctx, filter, afterEventId, limit)
+}
+
+func (m *mockStore) MarkFailedEventProcessed(ctx context.Context, eventId string, processedAt int64) error {
+	m.calls = append(m.calls, "MarkFailedEventProcessed")
+	if m.markFailedEventProcessed == nil {
+		return errUnexpectedCall
+	}
+	return m.markFailedEventProcessed(ctx, eventId, processedAt)
+}
+
+func (m *mockStore) RecordFailedEventAttempt(ctx context.Context, eventId string, attemptErr string) error {
+	m.calls = append(m.calls, "RecordFailedEventAttempt")
+	if m.recordFailedEventAttempt == nil {
+		return errUnexpectedCall
+	}
+	return m.recordFailedEventAttempt(ctx, eventId, attemptErr)
+}
+
+func (m *mockStore) InsertFailedTxEvent(ctx context.Context, event *governor.FailedTxEvent) error {
+	m.calls = append(m.calls, "InsertFailedTxEvent")
+	if m.insertFailedTxEvent == nil {
+		return errUnexpectedCall
+	}
+	return m.insertFailedTxEvent(ctx, event)
+}
+
+func (m *mockStore) InsertIndexerError(ctx context.Context, indexerError *db.IndexerError, keep int) error {
+	m.calls = append(m.calls, "InsertIndexerError")
+	if m.insertIndexerError == nil {
+		return errUnexpectedCall
+	}
+	return m.insertIndexerError(ctx, indexerError, keep)
+}
+
+func (m *mockStore) UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error {
+	m.calls = append(m.calls, "UpsertStatus")
+	if m.upsertStatus == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertStatus(ctx, source, ledgerSeq, ledgerCloseTime)
+}
+
+func (m *mockStore) GetStatus(ctx context.Context, source string) (uint32, int64, error) {
+	m.calls = append(m.calls, "GetStatus")
+	if m.getStatus == nil {
+		return 0, 0, errUnexpectedCall
+	}
+	return m.getStatus(ctx, source)
+}
+
+func (m *mockStore) UpsertSourceStatus(ctx context.Context, source string, status db.SourceStatus) error {
+	m.calls = append(m.calls, "UpsertSourceStatus")
+	if m.upsertSourceStatus == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertSourceStatus(ctx, source, status)
+}
+
+func (m *mockStore) UpsertIngestionProgress(ctx context.Context, progress *db.IngestionProgress) error {
+	m.calls = append(m.calls, "UpsertIngestionProgress")
+	if m.upsertIngestionProgress == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertIngestionProgress(ctx, progress)
+}
+
+func (m *mockStore) InsertProposal(ctx context.Context, proposal *governor.Proposal) error {
+	m.calls = append(m.calls, "InsertProposal")
+	if m.insertProposal == nil {
+		return errUnexpectedCall
+	}
+	return m.insertProposal(ctx, proposal)
+}
+
+func (m *mockStore) UpdateProposal(ctx context.Context, proposal *governor.Proposal, version int64) error {
+	m.calls = append(m.calls, "UpdateProposal")
+	if m.updateProposal == nil {
+		return errUnexpectedCall
+	}
+	return m.updateProposal(ctx, proposal, version)
+}
+
+func (m *mockStore) GetProposalVersion(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error) {
+	m.calls = append(m.calls, "GetProposalVersion")
+	if m.getProposalVersion == nil {
+		return nil, 0, errUnexpectedCall
+	}
+	return m.getProposalVersion(ctx, proposalKey)
+}
+
+func (m *mockStore) DeleteProposalsByContractId(ctx context.Context, contractId string) (int64, error) {
+	m.calls = append(m.calls, "DeleteProposalsByContractId")
+	if m.deleteProposalsByContractId == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.deleteProposalsByContractId(ctx, contractId)
+}
+
+func (m *mockStore) InsertVote(ctx context.Context, vote *governor.Vote) error {
+	m.calls = append(m.calls, "InsertVote")
+	if m.insertVote == nil {
+		return errUnexpectedCall
+	}
+	return m.insertVote(ctx, vote)
+}
+
+func (m *mockStore) GetVote(ctx context.Context, txHash string) (*governor.Vote, error) {
+	m.calls = append(m.calls, "GetVote")
+	if m.getVote == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVote(ctx, txHash)
+}
+
+func (m *mockStore) CountVoters(ctx context.Context, contractId string, proposalId uint32) (int, error) {
+	m.calls = append(m.calls, "CountVoters")
+	if m.countVoters == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.countVoters(ctx, contractId, proposalId)
+}
+
+func (m *mockStore) InsertVotes(ctx context.Context, votes []*governor.Vote) error {
+	m.calls = append(m.calls, "InsertVotes")
+	if m.insertVotes == nil {
+		return errUnexpectedCall
+	}
+	return m.insertVotes(ctx, votes)
+}
+
+func (m *mockStore) GetVotesByTxHashes(ctx context.Context, txHashes []string) ([]*governor.Vote, error) {
+	m.calls = append(m.calls, "GetVotesByTxHashes")
+	if m.getVotesByTxHashes == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVotesByTxHashes(ctx, txHashes)
+}
+
+func (m *mockStore) DeleteVotesByContractId(ctx context.Context, contractId string) (int64, error) {
+	m.calls = append(m.calls, "DeleteVotesByContractId")
+	if m.deleteVotesByContractId == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.deleteVotesByContractId(ctx, contractId)
+}
+
+func (m *mockStore) UpsertGovernorSettings(ctx context.Context, settings *governor.Settings) error {
+	m.calls = append(m.calls, "UpsertGovernorSettings")
+	if m.upsertGovernorSettings == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertGovernorSettings(ctx, settings)
+}
+
+func (m *mockStore) IsContractBlocked(ctx context.Context, contractId string) (bool, error) {
+	m.calls = append(m.calls, "IsContractBlocked")
+	if m.isContractBlocked == nil {
+		return false, errUnexpectedCall
+	}
+	return m.isContractBlocked(ctx, contractId)
+}
+
+func (m *mockStore) GetBlockedContracts(ctx context.Context) ([]*db.BlockedContract, error) {
+	m.calls = append(m.calls, "GetBlockedContracts")
+	if m.getBlockedContracts == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getBlockedContracts(ctx)
+}
+
+func (m *mockStore) UpsertContractActivity(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error {
+	m.calls = append(m.calls, "UpsertContractActivity")
+	if m.upsertContractActivity == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertContractActivity(ctx, contractId, eventId, ledgerSeq, ledgerCloseTime, reviewed)
+}
+
+func (m *mockStore) GetContractLastEventId(ctx context.Context, contractId string) (string, error) {
+	m.calls = append(m.calls, "GetContractLastEventId")
+	if m.getContractLastEventId == nil {
+		return "", errUnexpectedCall
+	}
+	return m.getContractLastEventId(ctx, contractId)
+}
+
+func (m *mockStore) AddContractEventCounts(ctx context.Context, contractId string, counts db.EventCounts) (bool, error) {
+	m.calls = append(m.calls, "AddContractEventCounts")
+	if m.addContractEventCounts == nil {
+		return false, errUnexpectedCall
+	}
+	return m.addContractEventCounts(ctx, contractId, counts)
+}
+
+func (m *mockStore) InsertDelegation(ctx context.Context, delegation *governor.Delegation) error {
+	m.calls = append(m.calls, "InsertDelegation")
+	if m.insertDelegation == nil {
+		return errUnexpectedCall
+	}
+	return m.insertDelegation(ctx, delegation)
+}
+
+func (m *mockStore) DeleteDelegationsByContractId(ctx context.Context, contractId string) (int64, error) {
+	m.calls = append(m.calls, "DeleteDelegationsByContractId")
+	if m.deleteDelegationsByContractId == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.deleteDelegationsByContractId(ctx, contractId)
+}
+
+func (m *mockStore) UpsertVotesContract(ctx context.Context, governorId string, votesId string, ledgerSeq uint32) error {
+	m.calls = append(m.calls, "UpsertVotesContract")
+	if m.upsertVotesContract == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertVotesContract(ctx, governorId, votesId, ledgerSeq)
+}
+
+func (m *mockStore) IsVotesContract(ctx context.Context, contractId string) (bool, error) {
+	m.calls = append(m.calls, "IsVotesContract")
+	if m.isVotesContract == nil {
+		return false, errUnexpectedCall
+	}
+	return m.isVotesContract(ctx, contractId)
+}
+
+func (m *mockStore) GetTrackedContracts(ctx context.Context) ([]string, error) {
+	m.calls = append(m.calls, "GetTrackedContracts")
+	if m.getTrackedContracts == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getTrackedContracts(ctx)
+}
+
+func (m *mockStore) GetProposalContentToFetch(ctx context.Context, now int64, limit int) ([]*governor.ProposalContent, error) {
+	m.calls = append(m.calls, "GetProposalContentToFetch")
+	if m.getProposalContentToFetch == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalContentToFetch(ctx, now, limit)
+}
+
+func (m *mockStore) UpsertProposalContent(ctx context.Context, content *governor.ProposalContent) error {
+	m.calls = append(m.calls, "UpsertProposalContent")
+	if m.upsertProposalContent == nil {
+		return errUnexpectedCall
+	}
+	return m.upsertProposalContent(ctx, content)
+}
+
+func (m *mockStore) GetVoteTokensToFetch(ctx context.Context, limit int) ([]*db.VoteToken, error) {
+	m.calls = append(m.calls, "GetVoteTokensToFetch")
+	if m.getVoteTokensToFetch == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getVoteTokensToFetch(ctx, limit)
+}
+
+func (m *mockStore) SetVoteToken(ctx context.Context, contractId string, symbol string, decimals uint32) error {
+	m.calls = append(m.calls, "SetVoteToken")
+	if m.setVoteToken == nil {
+		return errUnexpectedCall
+	}
+	return m.setVoteToken(ctx, contractId, symbol, decimals)
+}
+
+func (m *mockStore) GetEventsAfter(ctx context.Context, contractId string, afterEventId string, limit int) ([]*governor.GovernorEvent, error) {
+	m.calls = append(m.calls, "GetEventsAfter")
+	if m.getEventsAfter == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getEventsAfter(ctx, contractId, afterEventId, limit)
+}
+
+func (m *mockStore) GetLastEventIds(ctx context.Context, afterLedger uint32, toLedger uint32) (map[string]string, error) {
+	m.calls = append(m.calls, "GetLastEventIds")
+	if m.getLastEventIds == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getLastEventIds(ctx, afterLedger, toLedger)
+}
+
+func (m *mockStore) GetProposalsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error) {
+	m.calls = append(m.calls, "GetProposalsByContractId")
+	if m.getProposalsByContractId == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getProposalsByContractId(ctx, contractId, sort)
+}
+
+func (m *mockStore) DeleteVotesAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error) {
+	m.calls = append(m.calls, "DeleteVotesAfterLedger")
+	if m.deleteVotesAfterLedger == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.deleteVotesAfterLedger(ctx, contractId, ledgerSeq)
+}
+
+func (m *mockStore) DeleteDelegationsAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error) {
+	m.calls = append(m.calls, "DeleteDelegationsAfterLedger")
+	if m.deleteDelegationsAfterLedger == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.deleteDelegationsAfterLedger(ctx, contractId, ledgerSeq)
+}
+
+func (m *mockStore) InsertSnapshot(ctx context.Context, snapshot *db.Snapshot) error {
+	m.calls = append(m.calls, "InsertSnapshot")
+	if m.insertSnapshot == nil {
+		return errUnexpectedCall
+	}
+	return m.insertSnapshot(ctx, snapshot)
+}
+
+func (m *mockStore) GetLatestSnapshot(ctx context.Context, contractId string, beforeLedger uint32) (*db.Snapshot, error) {
+	m.calls = append(m.calls, "GetLatestSnapshot")
+	if m.getLatestSnapshot == nil {
+		return nil, errUnexpectedCall
+	}
+	return m.getLatestSnapshot(ctx, contractId, beforeLedger)
+}
+
+func (m *mockStore) PruneSnapshots(ctx context.Context, contractId string, keep int) (int64, error) {
+	m.calls = append(m.calls, "PruneSnapshots")
+	if m.pruneSnapshots == nil {
+		return 0, errUnexpectedCall
+	}
+	return m.pruneSnapshots(ctx, contractId, keep)
+}
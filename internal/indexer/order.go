@@ -0,0 +1,61 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+// ErrEventOutOfOrder is returned by ApplyEvent and ApplyLedger, if the indexer enforces event order, for a governor
+// event older than the latest event applied for its contract that is not in the history yet
+var ErrEventOutOfOrder = errors.New("event out of order")
+
+// checkEventOrder checks that a governor event is not older, by event id, than the latest event applied for its
+// contract. Events already in the history pass, as they are applied again when a ledger is retried or history is
+// replayed. An event out of order is logged and counted, and ErrEventOutOfOrder is returned if the indexer enforces
+// event order. Delegation events are not checked, as votes contracts are not registered.
+func (idx *Indexer) checkEventOrder(ctx context.Context, govEvent *governor.GovernorEvent) error {
+	if governor.IsDelegationEventType(govEvent.EventType) {
+		return nil
+	}
+	lastEventId, err := idx.store.GetContractLastEventId(ctx, govEvent.ContractId)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check event order: %w", err)
+	}
+	if govEvent.EventId >= lastEventId {
+		return nil
+	}
+	_, err = idx.store.GetEvent(ctx, govEvent.EventId)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("failed to check event order: %w", err)
+	}
+
+	metrics.EventsOutOfOrder.Inc()
+	slog.Warn("Applying event older than the last event applied for its contract", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId, "contract", govEvent.ContractId, "last_event_id", lastEventId, "strict", idx.strictEventOrder)
+	if idx.strictEventOrder {
+		return fmt.Errorf("%w: event %s is older than event %s of contract %s", ErrEventOutOfOrder, govEvent.EventId, lastEventId, govEvent.ContractId)
+	}
+	return nil
+}
+
+// checkLedgerEventOrder checks the order of an event of a ledger with checkEventOrder. Only database timeouts and
+// ErrEventOutOfOrder are returned, so the indexer stops rather than apply a ledger out of order; other errors are
+// logged, and the event is applied.
+func (idx *Indexer) checkLedgerEventOrder(ctx context.Context, govEvent *governor.GovernorEvent) error {
+	err := idx.checkEventOrder(ctx, govEvent)
+	if errors.Is(err, db.ErrTimeout) || errors.Is(err, ErrEventOutOfOrder) {
+		return err
+	} else if err != nil {
+		slog.Error("Failed checking event order", "ledger", govEvent.LedgerSeq, "hash", govEvent.TxHash, "eventId", govEvent.EventId, "err", err)
+	}
+	return nil
+}
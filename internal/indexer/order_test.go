@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+func TestCheckEventOrder(t *testing.T) {
+	ctx := t.Context()
+	voteEvent := func(eventId string, txHash string, voter string) *governor.GovernorEvent {
+		return &governor.GovernorEvent{
+			EventId:         eventId,
+			ContractId:      testContractId,
+			EventType:       "vote_cast",
+			ProposalId:      3,
+			EventData:       `{"voter":"` + voter + `","support":1,"amount":"1000"}`,
+			TxHash:          txHash,
+			LedgerSeq:       ledgerSeq,
+			LedgerCloseTime: ledgerCloseTime,
+		}
+	}
+	newer := voteEvent("0005025695851876452-0000000002", "tx_newer", "GAWJ7THLA3VEV6D2AXCJ5ZFCIPY2LBYJGFDRV3OYKCVVJKAB6TTOLZ5Q")
+	older := voteEvent("0005025695851876452-0000000001", "tx_older", "GA5XIGA5C7QTPTWXQHY6MCJRMTRZDOSHR6EFIBNDQTCQHG262N4GGKTM")
+	oldest := voteEvent("0005025695851876452-0000000000", "tx_oldest", "GDZ4LFL3JWLBMOJ5BQ7FXG2VMHOYSRNFHSGTQKI2MZP4WRQ77UQ4SF4S")
+
+	store := setupStore(t, ctx)
+	indexer := NewIndexer(store)
+	if err := indexer.ApplyEvent(ctx, newer); err != nil {
+		t.Fatalf("ApplyEvent() error = %v", err)
+	}
+
+	// an event older than the last event of the contract is only logged by default
+	if err := indexer.ApplyEvent(ctx, older); err != nil {
+		t.Fatalf("ApplyEvent() out of order error = %v", err)
+	}
+	if _, err := store.GetVote(ctx, older.TxHash); err != nil {
+		t.Errorf("expected the out of order vote to be applied, got %v", err)
+	}
+
+	// events already in the history are applied again without failing, as when a ledger is retried
+	indexer.strictEventOrder = true
+	for _, event := range []*governor.GovernorEvent{newer, older} {
+		if err := indexer.ApplyEvent(ctx, event); err != nil {
+			t.Errorf("ApplyEvent() of event %s in history error = %v", event.EventId, err)
+		}
+	}
+
+	// with strict event order, the event fails and isn't recorded
+	if err := indexer.ApplyEvent(ctx, oldest); !errors.Is(err, ErrEventOutOfOrder) {
+		t.Fatalf("expected ErrEventOutOfOrder, got %v", err)
+	}
+	if _, err := store.GetEvent(ctx, oldest.EventId); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the out of order event not to be in the history, got %v", err)
+	}
+	if _, err := store.GetVote(ctx, oldest.TxHash); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the out of order vote not to be applied, got %v", err)
+	}
+
+	// the ledger path stops at the event, batched or not
+	for _, batched := range []bool{false, true} {
+		indexer.batched = batched
+		if err := indexer.applyLedgerEvent(ctx, oldest, &LedgerStats{}); !errors.Is(err, ErrEventOutOfOrder) {
+			t.Errorf("applyLedgerEvent() batched=%t expected ErrEventOutOfOrder, got %v", batched, err)
+		}
+		if err := indexer.applyBatch(ctx, []*governor.GovernorEvent{oldest}, &LedgerStats{}); !errors.Is(err, ErrEventOutOfOrder) {
+			t.Errorf("applyBatch() batched=%t expected ErrEventOutOfOrder, got %v", batched, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+const (
+	// PARSE_FAILURE_WINDOW is the number of a contract's most recent governor events its failure ratio is computed
+	// over
+	PARSE_FAILURE_WINDOW = 100
+	// PARSE_FAILURE_MIN_EVENTS is the number of governor events a contract must have in its window before its
+	// failure ratio is checked, so a single failed event doesn't warn
+	PARSE_FAILURE_MIN_EVENTS = 10
+)
+
+// parseWindow is whether each of a contract's most recent governor events failed, oldest first
+type parseWindow struct {
+	failed []bool
+	// warned is true if the failure ratio was over the threshold when last checked, so the warning is logged once
+	// each time the threshold is crossed
+	warned bool
+}
+
+// RecordContractEvents adds the governor event counts of each contract to the contracts registry and the
+// per-contract metrics, and logs a warning when the failure ratio of a contract's last PARSE_FAILURE_WINDOW events
+// rises over the threshold. Contracts that are not registered are skipped, as any contract can emit events laid out
+// like governor events. The counts only inform operators, so errors are logged rather than returned.
+func (idx *Indexer) RecordContractEvents(ctx context.Context, contracts map[string]db.EventCounts) {
+	for _, contractId := range slices.Sorted(maps.Keys(contracts)) {
+		counts := contracts[contractId]
+		registered, err := idx.store.AddContractEventCounts(ctx, contractId, counts)
+		if err != nil {
+			slog.Error("Failed recording contract event counts", "contract", contractId, "err", err)
+			continue
+		}
+		if !registered {
+			continue
+		}
+		metrics.ContractGovernorEventsSeen.WithLabelValues(contractId).Add(float64(counts.Seen))
+		metrics.ContractGovernorEventsParsed.WithLabelValues(contractId).Add(float64(counts.Parsed))
+		metrics.ContractGovernorEventsFailed.WithLabelValues(contractId).Add(float64(counts.Failed))
+		idx.checkFailureRatio(contractId, counts)
+	}
+}
+
+// checkFailureRatio adds the counts to the contract's window, and logs a warning if the percentage of failed events
+// in the window rose over parseFailureWarnPercent
+func (idx *Indexer) checkFailureRatio(contractId string, counts db.EventCounts) {
+	if idx.parseFailureWarnPercent == 0 {
+		return
+	}
+	if idx.parseWindows == nil {
+		idx.parseWindows = make(map[string]*parseWindow)
+	}
+	window := idx.parseWindows[contractId]
+	if window == nil {
+		window = &parseWindow{}
+		idx.parseWindows[contractId] = window
+	}
+	for range counts.Parsed {
+		window.failed = append(window.failed, false)
+	}
+	for range counts.Failed {
+		window.failed = append(window.failed, true)
+	}
+	if len(window.failed) > PARSE_FAILURE_WINDOW {
+		window.failed = window.failed[len(window.failed)-PARSE_FAILURE_WINDOW:]
+	}
+
+	failed := 0
+	for _, f := range window.failed {
+		if f {
+			failed++
+		}
+	}
+	over := len(window.failed) >= PARSE_FAILURE_MIN_EVENTS && failed*100 > idx.parseFailureWarnPercent*len(window.failed)
+	if over && !window.warned {
+		slog.Warn("Contract's governor events are failing to parse, it may have been upgraded", "contract", contractId, "failed", failed, "events", len(window.failed), "threshold_percent", idx.parseFailureWarnPercent)
+	}
+	window.warned = over
+}
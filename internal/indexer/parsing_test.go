@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+func TestRecordContractEvents(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	recorded := make(map[string]db.EventCounts)
+	store := &mockStore{
+		addContractEventCounts: func(ctx context.Context, contractId string, counts db.EventCounts) (bool, error) {
+			if contractId != testContractId {
+				return false, nil
+			}
+			total := recorded[contractId]
+			recorded[contractId] = db.EventCounts{Seen: total.Seen + counts.Seen, Parsed: total.Parsed + counts.Parsed, Failed: total.Failed + counts.Failed}
+			return true, nil
+		},
+	}
+	idx := NewIndexer(store)
+	idx.parseFailureWarnPercent = 10
+	unregistered := "CCYAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAASCH"
+
+	tests := []struct {
+		name      string
+		contracts map[string]db.EventCounts
+		wantWarns int
+	}{
+		{
+			name:      "too few events to check",
+			contracts: map[string]db.EventCounts{testContractId: {Seen: 5, Failed: 5}},
+			wantWarns: 0,
+		},
+		{
+			// 6 of the last 15 events failed
+			name:      "failure ratio over the threshold",
+			contracts: map[string]db.EventCounts{testContractId: {Seen: 10, Parsed: 9, Failed: 1}},
+			wantWarns: 1,
+		},
+		{
+			name:      "still over the threshold",
+			contracts: map[string]db.EventCounts{testContractId: {Seen: 1, Failed: 1}},
+			wantWarns: 0,
+		},
+		{
+			// the failed events fall out of the window
+			name:      "back under the threshold",
+			contracts: map[string]db.EventCounts{testContractId: {Seen: PARSE_FAILURE_WINDOW, Parsed: PARSE_FAILURE_WINDOW}},
+			wantWarns: 0,
+		},
+		{
+			name:      "over the threshold again",
+			contracts: map[string]db.EventCounts{testContractId: {Seen: 20, Failed: 20}},
+			wantWarns: 1,
+		},
+		{
+			name:      "unregistered contract",
+			contracts: map[string]db.EventCounts{unregistered: {Seen: 20, Failed: 20}},
+			wantWarns: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			idx.RecordContractEvents(t.Context(), tt.contracts)
+			if warns := strings.Count(buf.String(), "level=WARN"); warns != tt.wantWarns {
+				t.Errorf("logged %d warnings, want %d: %q", warns, tt.wantWarns, buf.String())
+			}
+		})
+	}
+
+	want := map[string]db.EventCounts{testContractId: {Seen: 136, Parsed: 109, Failed: 27}}
+	if diff := cmp.Diff(want, recorded); diff != "" {
+		t.Errorf("recorded counts mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := idx.parseWindows[unregistered]; ok {
+		t.Errorf("unregistered contract has a parse window")
+	}
+}
@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+const (
+	// PROGRESS_RATE_WINDOW is the period over which the recent rate of ingestion is measured
+	PROGRESS_RATE_WINDOW = 5 * time.Minute
+	// PROGRESS_SAMPLE_INTERVAL is the minimum time between the samples the recent rate is measured from, so the
+	// number of samples kept doesn't grow with the rate
+	PROGRESS_SAMPLE_INTERVAL = 5 * time.Second
+	// PROGRESS_RECORD_INTERVAL is how often the progress is recorded for the API, so it stays current at the tip of
+	// the network, where progress is logged rarely
+	PROGRESS_RECORD_INTERVAL = 30 * time.Second
+	// PROGRESS_TARGET_TIMEOUT is the maximum duration of a call for the target ledger, so an unresponsive RPC server
+	// doesn't stall ingestion
+	PROGRESS_TARGET_TIMEOUT = 2 * time.Second
+)
+
+// progressSample is the number of ledgers and events processed at a point in time
+type progressSample struct {
+	at      time.Time
+	ledgers int64
+	events  int64
+}
+
+// progressTracker measures the progress of processing ledgers from a start ledger, with the rate over the last
+// PROGRESS_RATE_WINDOW, and the percent complete and ETA if the ledger processing is heading for is known. It is not
+// safe for concurrent use.
+type progressTracker struct {
+	// store records the progress for the API. If nil, progress is only logged.
+	store Store
+	// logInterval is the number of ledgers between progress logs. If 0, progress is never logged.
+	logInterval int
+	// targetLedger returns the ledger processing is heading for, such as the latest ledger of the network. If nil,
+	// the target ledger is unknown.
+	targetLedger func(ctx context.Context) (uint32, error)
+	now          func() time.Time
+
+	progress db.IngestionProgress
+	// samples are taken at least PROGRESS_SAMPLE_INTERVAL apart, oldest first, and cover PROGRESS_RATE_WINDOW
+	samples    []progressSample
+	recordedAt time.Time
+}
+
+// newProgressTracker creates a tracker of the progress of source processing ledgers from startLedger
+func newProgressTracker(store Store, source string, startLedger uint32, logInterval int, targetLedger func(ctx context.Context) (uint32, error)) *progressTracker {
+	now := time.Now()
+	return &progressTracker{
+		store:        store,
+		logInterval:  logInterval,
+		targetLedger: targetLedger,
+		now:          time.Now,
+		progress:     db.IngestionProgress{Source: source, StartLedger: startLedger, StartedAt: now.Unix()},
+		samples:      []progressSample{{at: now}},
+		recordedAt:   now,
+	}
+}
+
+// add counts a processed ledger and its stats, and updates the recent rate
+func (p *progressTracker) add(ledger uint32, stats LedgerStats) {
+	now := p.now()
+	p.progress.Ledger = ledger
+	p.progress.Ledgers += int64(stats.Ledgers)
+	p.progress.Events += int64(stats.GovernorEvents)
+
+	if now.Sub(p.samples[len(p.samples)-1].at) >= PROGRESS_SAMPLE_INTERVAL {
+		p.samples = append(p.samples, progressSample{at: now, ledgers: p.progress.Ledgers, events: p.progress.Events})
+	}
+	// keep the newest sample outside the window, so the rate covers the whole window
+	for len(p.samples) > 1 && now.Sub(p.samples[1].at) >= PROGRESS_RATE_WINDOW {
+		p.samples = p.samples[1:]
+	}
+	oldest := p.samples[0]
+	if elapsed := now.Sub(oldest.at).Seconds(); elapsed > 0 {
+		p.progress.LedgersPerSecond = float64(p.progress.Ledgers-oldest.ledgers) / elapsed
+		p.progress.EventsPerSecond = float64(p.progress.Events-oldest.events) / elapsed
+	}
+}
+
+// report logs the progress every logInterval ledgers, and updates the progress metrics and records the progress for
+// the API when it is logged or at least every PROGRESS_RECORD_INTERVAL. The target ledger is read with a
+// PROGRESS_TARGET_TIMEOUT, and the last known target is kept if it can't be read. Errors are logged, as progress is
+// only informational.
+func (p *progressTracker) report(ctx context.Context) {
+	now := p.now()
+	logDue := p.logInterval > 0 && p.progress.Ledgers%int64(p.logInterval) == 0
+	if !logDue && now.Sub(p.recordedAt) < PROGRESS_RECORD_INTERVAL {
+		return
+	}
+	p.recordedAt = now
+
+	if p.targetLedger != nil {
+		targetCtx, cancel := context.WithTimeout(ctx, PROGRESS_TARGET_TIMEOUT)
+		target, err := p.targetLedger(targetCtx)
+		cancel()
+		if err != nil {
+			// keep the last known target
+			slog.Debug("Failed to get the target ledger", "err", err)
+		} else {
+			p.progress.TargetLedger = target
+		}
+	}
+	p.progress.UpdatedAt = now.Unix()
+
+	metrics.LedgersPerSecond.Set(p.progress.LedgersPerSecond)
+	metrics.GovernorEventsPerSecond.Set(p.progress.EventsPerSecond)
+	metrics.TargetLedger.Set(float64(p.progress.TargetLedger))
+	percent, _ := p.progress.PercentComplete()
+	metrics.PercentComplete.Set(percent)
+	eta, _ := p.progress.ETA()
+	metrics.ETASeconds.Set(eta.Seconds())
+
+	if logDue {
+		slog.Info("Ingestion progress.", p.logAttrs(now)...)
+	}
+	if p.store != nil {
+		if err := p.store.UpsertIngestionProgress(ctx, &p.progress); err != nil {
+			slog.Warn("Failed to record ingestion progress", "ledger", p.progress.Ledger, "err", err)
+		}
+	}
+}
+
+// logAttrs returns the progress as slog key value pairs. The target ledger, percent complete and ETA are only
+// included if the target ledger is known.
+func (p *progressTracker) logAttrs(now time.Time) []any {
+	attrs := []any{
+		"ledger", p.progress.Ledger,
+		"ledgers", p.progress.Ledgers,
+		"governor_events", p.progress.Events,
+		"ledgers_per_second", formatRate(p.progress.LedgersPerSecond),
+		"events_per_second", formatRate(p.progress.EventsPerSecond),
+		"elapsed", now.Sub(time.Unix(p.progress.StartedAt, 0)).Round(time.Second).String(),
+	}
+	if percent, ok := p.progress.PercentComplete(); ok {
+		attrs = append(attrs, "target", p.progress.TargetLedger, "percent", fmt.Sprintf("%.1f", percent))
+	}
+	if eta, ok := p.progress.ETA(); ok {
+		attrs = append(attrs, "eta", eta.Round(time.Second).String())
+	}
+	return attrs
+}
+
+// summaryAttrs returns the stats of a run as slog key value pairs, with its wall time and average rates
+func summaryAttrs(total LedgerStats, elapsed time.Duration) []any {
+	var ledgersPerSecond, eventsPerSecond float64
+	if elapsed > 0 {
+		ledgersPerSecond = float64(total.Ledgers) / elapsed.Seconds()
+		eventsPerSecond = float64(total.GovernorEvents) / elapsed.Seconds()
+	}
+	return append(total.LogAttrs(),
+		"elapsed", elapsed.Round(time.Second).String(),
+		"ledgers_per_second", formatRate(ledgersPerSecond),
+		"events_per_second", formatRate(eventsPerSecond),
+	)
+}
+
+// formatRate formats a rate per second for logs
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.2f", rate)
+}
@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+func TestProgressTracker(t *testing.T) {
+	ctx := t.Context()
+	start := time.Unix(1761053000, 0)
+	now := start
+
+	var recorded []db.IngestionProgress
+	store := &mockStore{
+		upsertIngestionProgress: func(ctx context.Context, progress *db.IngestionProgress) error {
+			recorded = append(recorded, *progress)
+			return nil
+		},
+	}
+	targetErr := error(nil)
+	progress := newProgressTracker(store, STATUS_SOURCE, 1000, 100, func(ctx context.Context) (uint32, error) {
+		// a hung RPC server must not stall ingestion
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the target ledger to be read with a deadline")
+		}
+		return 1999, targetErr
+	})
+	progress.now = func() time.Time { return now }
+	progress.progress.StartedAt = start.Unix()
+	progress.samples = []progressSample{{at: start}}
+	progress.recordedAt = start
+
+	// process ledgers at 10 a second, each with 2 events
+	process := func(ledgers int) {
+		for range ledgers {
+			now = now.Add(100 * time.Millisecond)
+			progress.add(1000+uint32(progress.progress.Ledgers), LedgerStats{Ledgers: 1, GovernorEvents: 2})
+			progress.report(ctx)
+		}
+	}
+
+	// progress is recorded every 100 ledgers
+	process(99)
+	if len(recorded) != 0 {
+		t.Fatalf("expected no progress recorded before 100 ledgers, got %d", len(recorded))
+	}
+	process(1)
+	want := db.IngestionProgress{
+		Source:           STATUS_SOURCE,
+		StartLedger:      1000,
+		Ledger:           1099,
+		TargetLedger:     1999,
+		Ledgers:          100,
+		Events:           200,
+		LedgersPerSecond: 10,
+		EventsPerSecond:  20,
+		StartedAt:        start.Unix(),
+		UpdatedAt:        start.Add(10 * time.Second).Unix(),
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("expected progress recorded after 100 ledgers, got %d", len(recorded))
+	}
+	if diff := cmp.Diff(want, recorded[0]); diff != "" {
+		t.Errorf("progress mismatch (-want +got):\n%s", diff)
+	}
+	if percent, _ := recorded[0].PercentComplete(); percent != 10 {
+		t.Errorf("PercentComplete() = %v, want 10", percent)
+	}
+	if eta, _ := recorded[0].ETA(); eta != 90*time.Second {
+		t.Errorf("ETA() = %v, want 90s", eta)
+	}
+
+	// the rate only covers the rate window, so slowing down lowers it within the window
+	now = now.Add(PROGRESS_RATE_WINDOW)
+	process(100)
+	got := recorded[len(recorded)-1]
+	if got.Ledgers != 200 || got.LedgersPerSecond >= 1 {
+		t.Errorf("expected 200 ledgers at under 1 ledger a second after a pause, got %d at %v", got.Ledgers, got.LedgersPerSecond)
+	}
+
+	// a failure getting the target ledger keeps the last known target
+	targetErr = errors.New("rpc unavailable")
+	process(100)
+	if got := recorded[len(recorded)-1]; got.TargetLedger != 1999 || got.Ledgers != 300 {
+		t.Errorf("expected the last known target at 300 ledgers, got target %d at %d", got.TargetLedger, got.Ledgers)
+	}
+
+	// progress is recorded between logs every PROGRESS_RECORD_INTERVAL, so it stays current at the tip
+	count := len(recorded)
+	now = now.Add(PROGRESS_RECORD_INTERVAL)
+	process(1)
+	if len(recorded) != count+1 {
+		t.Errorf("expected progress recorded after %v, got %d records", PROGRESS_RECORD_INTERVAL, len(recorded)-count)
+	}
+}
+
+func TestSummaryAttrs(t *testing.T) {
+	attrs := summaryAttrs(LedgerStats{Ledgers: 600, GovernorEvents: 30}, time.Minute)
+	got := map[string]any{}
+	for i := 0; i < len(attrs); i += 2 {
+		got[attrs[i].(string)] = attrs[i+1]
+	}
+	want := map[string]any{"elapsed": "1m0s", "ledgers_per_second": "10.00", "events_per_second": "0.50"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("summaryAttrs() %s = %v, want %v", key, got[key], value)
+		}
+	}
+}
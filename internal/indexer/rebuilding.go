@@ -0,0 +1,29 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+// REBUILDING_STATUS_SOURCE is the status table source flagging a contract being rebuilt by a reindex or replay. Its
+// state is the id of the contract while it is rebuilt, and empty once the rebuild is done, and its updated_at is the
+// unix time the rebuild started.
+const REBUILDING_STATUS_SOURCE = "rebuilding"
+
+// withRebuilding flags contractId as being rebuilt while fn runs, so the API can tell clients, and clears the flag
+// once fn returns, even if it failed. The flag is written outside of fn's transaction, so it is visible while fn runs.
+func (idx *Indexer) withRebuilding(ctx context.Context, contractId string, fn func() error) error {
+	if err := idx.store.UpsertSourceStatus(ctx, REBUILDING_STATUS_SOURCE, db.SourceStatus{State: contractId, UpdatedAt: idx.now().Unix()}); err != nil {
+		return err
+	}
+	defer func() {
+		// cleared even if ctx was cancelled, so a cancelled rebuild doesn't leave the flag set
+		err := idx.store.UpsertSourceStatus(context.WithoutCancel(ctx), REBUILDING_STATUS_SOURCE, db.SourceStatus{UpdatedAt: idx.now().Unix()})
+		if err != nil {
+			slog.Error("Failed clearing the rebuilding flag", "contract", contractId, "err", err)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,83 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// ReindexContract rebuilds the proposals, votes, and delegations for a contract by replaying its history table entries
+// through ApplyEvent, in event order. All changes are made in a single transaction, so readers see either
+// the old or the rebuilt state.
+//
+// Reindexing requires the full event history, so ErrHistoryPruned is returned if the history table has been pruned.
+// ReindexContractFromSnapshot only replays the events after a snapshot.
+//
+// onProgress, if not nil, is called after each event is replayed with the number of events replayed so far
+// and the total number of events to replay. The contract is flagged as rebuilding in the status table until the
+// reindex is done.
+func (idx *Indexer) ReindexContract(ctx context.Context, contractId string, onProgress func(replayed int, total int)) error {
+	return idx.withRebuilding(ctx, contractId, func() error {
+		return idx.reindexContract(ctx, contractId, onProgress)
+	})
+}
+
+// reindexContract is ReindexContract without the rebuilding flag
+func (idx *Indexer) reindexContract(ctx context.Context, contractId string, onProgress func(replayed int, total int)) error {
+	retainedLedger, _, err := idx.store.GetStatus(ctx, RETENTION_STATUS_SOURCE)
+	if err != nil {
+		return fmt.Errorf("failed to get history retention status: %w", err)
+	}
+	if retainedLedger != 0 {
+		return fmt.Errorf("unable to reindex contract %s, events before ledger %d were deleted: %w", contractId, retainedLedger, ErrHistoryPruned)
+	}
+
+	return idx.store.WithTx(ctx, func(ctx context.Context) error {
+		events, err := idx.store.GetEventsByContractId(ctx, contractId, db.Sort{})
+		if err != nil {
+			return fmt.Errorf("failed to get events for contract %s: %w", contractId, err)
+		}
+
+		deletedVotes, err := idx.store.DeleteVotesByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("failed to delete votes for contract %s: %w", contractId, err)
+		}
+		deletedProposals, err := idx.store.DeleteProposalsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("failed to delete proposals for contract %s: %w", contractId, err)
+		}
+		deletedDelegations, err := idx.store.DeleteDelegationsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("failed to delete delegations for contract %s: %w", contractId, err)
+		}
+		slog.Info("Reindexing contract", "contract", contractId, "events", len(events), "deleted_proposals", deletedProposals, "deleted_votes", deletedVotes, "deleted_delegations", deletedDelegations)
+
+		return idx.replayEvents(ctx, contractId, events, onProgress)
+	})
+}
+
+// replayEvents applies events in order, reporting progress to onProgress if not nil. Events that fail to apply because
+// of the event are logged and skipped, as ApplyLedger skips them. Other errors, such as store failures, are returned,
+// so the replay is rolled back.
+func (idx *Indexer) replayEvents(ctx context.Context, contractId string, events []*governor.GovernorEvent, onProgress func(replayed int, total int)) error {
+	failed := 0
+	for i, event := range events {
+		// events that failed to apply during ingestion are expected to fail again, so log and continue
+		// to match the behavior of ApplyLedger
+		if err := idx.ApplyEvent(ctx, event); isEventError(err) || errors.Is(err, governor.ErrProposalConflict) {
+			failed++
+			slog.Warn("Failed applying event during reindex", "contract", contractId, "eventId", event.EventId, "err", err)
+		} else if err != nil {
+			return fmt.Errorf("failed applying event %s: %w", event.EventId, err)
+		}
+		if onProgress != nil {
+			onProgress(i+1, len(events))
+		}
+	}
+	slog.Info("Reindex complete", "contract", contractId, "events", len(events), "failed", failed)
+	return nil
+}
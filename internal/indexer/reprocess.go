@@ -0,0 +1,142 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// REPROCESS_BATCH_SIZE is the number of failed events read at a time when reprocessing
+const REPROCESS_BATCH_SIZE = 100
+
+// ReprocessStats are the results of ReprocessFailedEvents
+type ReprocessStats struct {
+	// Reprocessed is the number of failed events parsed and applied
+	Reprocessed int
+	// Failed is the number of failed events that failed to parse or apply again
+	Failed int
+	// Skipped is the number of failed events left unprocessed, as their contract is blocked, or they are delegation
+	// events from a contract that is not a governor's votes contract
+	Skipped int
+}
+
+// ReprocessFailedEvents parses the failed events matching filter again, oldest first, with the current parser, so
+// failed events can be indexed once the indexer supports them. Failed events already reprocessed are skipped.
+//
+// Each event that parses is applied with ApplyEvent and marked processed. Events are applied to the current state of
+// their proposal, so an event that no longer applies, such as a vote on a proposal that has since closed, is recorded
+// in the event history without changing the proposal. Events that fail to parse or apply again have their attempts
+// incremented and their error replaced. Only store errors are returned, with the stats of the events reprocessed
+// before the error.
+//
+// onProgress, if not nil, is called after each failed event is reprocessed with the stats so far.
+func (idx *Indexer) ReprocessFailedEvents(ctx context.Context, filter db.FailedEventFilter, onProgress func(stats ReprocessStats)) (ReprocessStats, error) {
+	filter.Unprocessed = true
+	var stats ReprocessStats
+	// events that fail again stay unprocessed, so page by event id rather than re-reading the first page
+	after := ""
+	for {
+		failedEvents, err := idx.store.GetFailedEvents(ctx, filter, after, REPROCESS_BATCH_SIZE)
+		if err != nil {
+			return stats, fmt.Errorf("failed to get failed events: %w", err)
+		}
+		for _, failedEvent := range failedEvents {
+			if err := idx.reprocessFailedEvent(ctx, failedEvent, &stats); err != nil {
+				return stats, err
+			}
+			if onProgress != nil {
+				onProgress(stats)
+			}
+		}
+		if len(failedEvents) < REPROCESS_BATCH_SIZE {
+			slog.Info("Reprocessed failed events", "contract", filter.ContractId, "reason", filter.Reason, "error", filter.ErrorContains, "reprocessed", stats.Reprocessed, "failed", stats.Failed, "skipped", stats.Skipped)
+			return stats, nil
+		}
+		after = failedEvents[len(failedEvents)-1].EventId
+	}
+}
+
+// reprocessFailedEvent parses and applies a failed event, as described by ReprocessFailedEvents, and adds the result
+// to stats
+func (idx *Indexer) reprocessFailedEvent(ctx context.Context, failedEvent *governor.FailedEvent, stats *ReprocessStats) error {
+	govEvent, err := parseFailedEvent(failedEvent)
+	if err != nil {
+		return idx.recordFailedAttempt(ctx, failedEvent, err, stats)
+	}
+
+	skip, err := idx.skipReprocessedEvent(ctx, govEvent)
+	if err != nil {
+		return err
+	}
+	if skip {
+		slog.Info("Skipping failed event", "eventId", failedEvent.EventId, "contract", failedEvent.ContractId, "type", govEvent.EventType)
+		stats.Skipped++
+		return nil
+	}
+
+	// failed events are older than the events applied since, so they are applied without checking their order
+	_, err = idx.apply(ctx, govEvent)
+	if errors.Is(err, db.ErrTimeout) {
+		return fmt.Errorf("failed applying event %s: %w", govEvent.EventId, err)
+	} else if err != nil {
+		return idx.recordFailedAttempt(ctx, failedEvent, err, stats)
+	}
+	if err := idx.store.MarkFailedEventProcessed(ctx, failedEvent.EventId, idx.now().Unix()); err != nil {
+		return fmt.Errorf("failed marking failed event processed: %w", err)
+	}
+	slog.Info("Reprocessed failed event", "eventId", failedEvent.EventId, "contract", failedEvent.ContractId, "type", govEvent.EventType)
+	stats.Reprocessed++
+	return nil
+}
+
+// parseFailedEvent parses the raw contract event of a failed event into a governor event
+func parseFailedEvent(failedEvent *governor.FailedEvent) (*governor.GovernorEvent, error) {
+	var event xdr.ContractEvent
+	if err := xdr.SafeUnmarshalBase64(failedEvent.EventXdr, &event); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event xdr: %w", err)
+	}
+	opToid, eventIndex, err := governor.DecodeEventId(failedEvent.EventId)
+	if err != nil {
+		return nil, err
+	}
+	govEvent, err := governor.NewGovernorEventFromContractEvent(&event, failedEvent.TxHash, failedEvent.LedgerSeq, failedEvent.LedgerCloseTime, opToid, eventIndex)
+	if err != nil {
+		return nil, err
+	}
+	govEvent.EventXdr = failedEvent.EventXdr
+	return govEvent, nil
+}
+
+// skipReprocessedEvent returns true if a reprocessed event would not be applied by ApplyLedger, as its contract is
+// blocked, or it is a delegation event from a contract that is not a governor's votes contract
+func (idx *Indexer) skipReprocessedEvent(ctx context.Context, govEvent *governor.GovernorEvent) (bool, error) {
+	if governor.IsDelegationEventType(govEvent.EventType) {
+		watched, err := idx.store.IsVotesContract(ctx, govEvent.ContractId)
+		if err != nil {
+			return false, fmt.Errorf("failed checking votes contracts: %w", err)
+		}
+		if !watched {
+			return true, nil
+		}
+	}
+	blocked, err := idx.store.IsContractBlocked(ctx, govEvent.ContractId)
+	if err != nil {
+		return false, fmt.Errorf("failed checking contract blocklist: %w", err)
+	}
+	return blocked, nil
+}
+
+// recordFailedAttempt records a failed attempt at reprocessing a failed event, and adds it to stats
+func (idx *Indexer) recordFailedAttempt(ctx context.Context, failedEvent *governor.FailedEvent, attemptErr error, stats *ReprocessStats) error {
+	slog.Warn("Failed reprocessing failed event", "eventId", failedEvent.EventId, "contract", failedEvent.ContractId, "attempts", failedEvent.Attempts+1, "err", attemptErr)
+	if err := idx.store.RecordFailedEventAttempt(ctx, failedEvent.EventId, attemptErr.Error()); err != nil {
+		return fmt.Errorf("failed recording failed event attempt: %w", err)
+	}
+	stats.Failed++
+	return nil
+}
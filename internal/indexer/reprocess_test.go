@@ -0,0 +1,137 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/toid"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// newTestFailedEvent records event as a failed event emitted by the transaction at txIndex in ledgerSeq
+func newTestFailedEvent(t *testing.T, store *db.Store, event xdr.ContractEvent, txIndex int32) *governor.FailedEvent {
+	t.Helper()
+	failedEvent, err := governor.NewFailedEvent(&event, governor.FAILED_REASON_UNKNOWN_SCHEMA_VERSION, governor.ErrUnknownSchemaVersion,
+		"cb759f7b061992ac79e5f944a08238a24d2999a5ac58eee9fde35dff6404d970", ledgerSeq, ledgerCloseTime, toid.New(int32(ledgerSeq), txIndex, 0).ToInt64(), 0)
+	if err != nil {
+		t.Fatalf("failed to create failed event: %v", err)
+	}
+	if err := store.InsertFailedEvent(t.Context(), failedEvent); err != nil {
+		t.Fatalf("failed to insert failed event: %v", err)
+	}
+	return failedEvent
+}
+
+func TestReprocessFailedEvents(t *testing.T) {
+	ctx := t.Context()
+	store := newFixtureStore(t)
+	idx := NewIndexer(store)
+	idx.now = func() time.Time { return testNow }
+
+	// events recorded as failed by an indexer that could not parse them
+	created := newTestFailedEvent(t, store, withProposalId(t, mustDecodeEvent(t, proposalCreatedXdr), 1), 1)
+	vote := newTestFailedEvent(t, store, newVoteCastEvent(t, 1, 1, 1, 10000000), 2)
+	// a negative amount still fails to parse
+	invalidEvent := newVoteCastEvent(t, 1, 2, 1, 10000000)
+	(**invalidEvent.Body.V0.Data.Vec)[1].I128.Hi = -1
+	invalid := newTestFailedEvent(t, store, invalidEvent, 3)
+	blockedEvent := mustDecodeEvent(t, proposalCreatedXdr)
+	blockedEvent.ContractId = &xdr.ContractId{0xb0}
+	blocked := newTestFailedEvent(t, store, blockedEvent, 4)
+	blockedId, err := strkey.Encode(strkey.VersionByteContract, []byte{0xb0, 31: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.BlockContract(ctx, blockedId, "spam", testNow.Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := idx.ReprocessFailedEvents(ctx, db.FailedEventFilter{}, nil)
+	if err != nil {
+		t.Fatalf("ReprocessFailedEvents() error = %v", err)
+	}
+	if diff := cmp.Diff(ReprocessStats{Reprocessed: 2, Failed: 1, Skipped: 1}, stats); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	proposal, err := store.GetProposal(ctx, governor.EncodeProposalKey(testContractId, 1))
+	if err != nil {
+		t.Fatalf("failed to get reprocessed proposal: %v", err)
+	}
+	if proposal.VotesFor != "10000000" {
+		t.Errorf("got votes for %s on the reprocessed proposal, want 10000000", proposal.VotesFor)
+	}
+	events, err := store.GetEventsByContractId(ctx, testContractId, db.Sort{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].EventId != created.EventId || events[1].EventId != vote.EventId || events[1].EventXdr != vote.EventXdr {
+		t.Errorf("got %d events in the history, want the reprocessed events %s and %s", len(events), created.EventId, vote.EventId)
+	}
+
+	// reprocessing again only retries the events that are still unprocessed
+	stats, err = idx.ReprocessFailedEvents(ctx, db.FailedEventFilter{}, nil)
+	if err != nil {
+		t.Fatalf("ReprocessFailedEvents() error = %v", err)
+	}
+	if diff := cmp.Diff(ReprocessStats{Failed: 1, Skipped: 1}, stats); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := store.GetFailedEvents(ctx, db.FailedEventFilter{}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type state struct {
+		EventId     string
+		Attempts    int
+		ProcessedAt int64
+	}
+	var gotStates []state
+	for _, event := range got {
+		gotStates = append(gotStates, state{event.EventId, event.Attempts, event.ProcessedAt})
+	}
+	want := []state{
+		{created.EventId, 0, testNow.Unix()},
+		{vote.EventId, 0, testNow.Unix()},
+		{invalid.EventId, 2, 0},
+		{blocked.EventId, 0, 0},
+	}
+	if diff := cmp.Diff(want, gotStates); diff != "" {
+		t.Errorf("failed events mismatch (-want +got):\n%s", diff)
+	}
+	if got[2].Error == invalid.Error {
+		t.Errorf("got error %q for the invalid event, want the error of the last attempt", got[2].Error)
+	}
+}
+
+func TestReprocessFailedEventsFilter(t *testing.T) {
+	var filters []db.FailedEventFilter
+	store := &mockStore{
+		getFailedEvents: func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+			filters = append(filters, filter)
+			return nil, nil
+		},
+	}
+	_, err := NewIndexer(store).ReprocessFailedEvents(t.Context(), db.FailedEventFilter{ContractId: testContractId, ErrorContains: "schema"}, nil)
+	if err != nil {
+		t.Fatalf("ReprocessFailedEvents() error = %v", err)
+	}
+	want := []db.FailedEventFilter{{ContractId: testContractId, ErrorContains: "schema", Unprocessed: true}}
+	if diff := cmp.Diff(want, filters); diff != "" {
+		t.Errorf("filters mismatch (-want +got):\n%s", diff)
+	}
+
+	store.getFailedEvents = func(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error) {
+		return nil, db.ErrTimeout
+	}
+	if _, err := NewIndexer(store).ReprocessFailedEvents(t.Context(), db.FailedEventFilter{}, nil); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("ReprocessFailedEvents() error = %v, want ErrTimeout", err)
+	}
+}
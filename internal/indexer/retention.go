@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	// RETENTION_STATUS_SOURCE is the status table source recording the earliest ledger retained in the history table
+	RETENTION_STATUS_SOURCE = "history_retention"
+	// PRUNE_BATCH_SIZE is the maximum number of history rows deleted per statement, to avoid holding long locks
+	PRUNE_BATCH_SIZE = 1000
+)
+
+// ErrHistoryPruned is returned when an operation requires the full event history, but it has been pruned
+var ErrHistoryPruned = errors.New("history has been pruned")
+
+// PruneHistory deletes all events from the history table emitted more than retentionLedgers before currentLedger.
+// Proposals and votes are never touched.
+//
+// The earliest retained ledger is recorded in the status table before any rows are deleted, so an interrupted
+// prune still reports a safe retention horizon.
+func (idx *Indexer) PruneHistory(ctx context.Context, currentLedger uint32, retentionLedgers uint32) (int64, error) {
+	if retentionLedgers == 0 || currentLedger <= retentionLedgers {
+		return 0, nil
+	}
+	cutoff := currentLedger - retentionLedgers
+
+	// the ledger close time of the cutoff ledger is not known, so it is recorded as 0
+	err := idx.store.UpsertStatus(ctx, RETENTION_STATUS_SOURCE, cutoff, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record retention horizon: %w", err)
+	}
+
+	var total int64
+	for {
+		deleted, err := idx.store.PruneHistory(ctx, cutoff, PRUNE_BATCH_SIZE)
+		if err != nil {
+			return total, fmt.Errorf("failed to prune history before ledger %d: %w", cutoff, err)
+		}
+		total += deleted
+		if deleted < PRUNE_BATCH_SIZE {
+			break
+		}
+	}
+
+	slog.Info("Pruned history", "before_ledger", cutoff, "deleted", total)
+	return total, nil
+}
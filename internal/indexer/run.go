@@ -0,0 +1,361 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+	"github.com/sirupsen/logrus"
+
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+	"github.com/stellar/go-stellar-sdk/ingest"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/network"
+	"github.com/stellar/go-stellar-sdk/support/log"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// STATUS_SOURCE is the status table source recording the last ledger processed by the indexer
+	STATUS_SOURCE = "indexer"
+	// DB_RETRY_DELAY is how long to wait before retrying a ledger after a database timeout
+	DB_RETRY_DELAY = 5 * time.Second
+)
+
+// Run acquires the indexer lock, then ingests ledgers from the configured ledger backend into the store,
+// starting after the last processed ledger. It runs until ctx is cancelled, the ledger backend fails,
+// or the indexer lock is lost.
+//
+// Run returns nil if it stopped because ctx was cancelled.
+func Run(ctx context.Context, store *db.Store, config *Config) error {
+	go watchReload(ctx, *config)
+
+	// Only one indexer may write to the database. Standby instances wait here until the leader exits.
+	slog.Info("Acquiring indexer lock...", "key", config.IndexerLockKey)
+	lock, err := store.AcquireIndexerLock(ctx, config.IndexerLockKey, time.Duration(config.IndexerLockPollInterval)*time.Second)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to acquire indexer lock: %w", err)
+	}
+	defer lock.Release(context.Background())
+
+	// Get the last processed ledger, which may have been written by a previous leader
+	lastLedger, _, err := store.GetStatus(ctx, STATUS_SOURCE)
+	if err != nil {
+		return fmt.Errorf("failed to fetch last processed ledger: %w", err)
+	}
+	startSeq, gaps, err := resolveStartSeq(ctx, config, lastLedger)
+	if err != nil {
+		return err
+	}
+	if err := RecordCoverageGaps(ctx, store, gaps, time.Now()); err != nil {
+		return fmt.Errorf("failed to record skipped ledgers: %w", err)
+	}
+	networkPassphrase := networkPassphrase(config)
+
+	backend, err := newLedgerBackend(config, networkPassphrase)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if config.LedgerBackendType == "core" {
+		// preparing the range blocks while captive core catches up, so its status is recorded from the start
+		captiveCoreToml, err := newCaptiveCoreToml(config, networkPassphrase)
+		if err != nil {
+			return err
+		}
+		if client := newCaptiveCoreClient(captiveCoreToml.HTTPPort); client != nil {
+			watchCtx, cancelWatch := context.WithCancel(ctx)
+			defer cancelWatch()
+			go watchCaptiveCore(watchCtx, store, client)
+		}
+	}
+
+	slog.Info("Setting up ledger ingestion service starting", "ledger", startSeq)
+	if err := backend.PrepareRange(ctx, ledgerbackend.UnboundedRange(startSeq)); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to prepare ledger range: %w", err)
+	}
+	slog.Info("Initial ledger range prepared.")
+
+	idx := NewIndexer(store)
+	idx.batched = config.ApplyBatched
+	idx.parseFailureWarnPercent = config.ParseFailureWarnPercent
+	idx.failedTxEvents = config.IndexFailedTxEvents
+	idx.errorLogSize = config.ErrorLogSize
+	idx.strictEventOrder = config.EventOrderStrict
+	idx.contractDiscovery = config.ContractDiscovery
+	idx.blocklist = NewBlocklist(store, time.Duration(config.DB.BlocklistRefreshInterval)*time.Second)
+
+	if config.IpfsGatewayUrl != "" {
+		// the fetcher only writes proposal content, but stops with the run so it never outlives the lock
+		fetchCtx, cancelFetch := context.WithCancel(ctx)
+		defer cancelFetch()
+		go NewContentFetcher(store, config).Run(fetchCtx)
+	}
+	if config.VoteTokenFetch && len(config.RPCUrls) > 0 {
+		client := rpcclient.NewClient(config.RPCUrls[0], nil)
+		defer client.Close()
+		tokenCtx, cancelTokens := context.WithCancel(ctx)
+		defer cancelTokens()
+		go NewTokenFetcher(store, client).Run(tokenCtx)
+	}
+
+	// snapshotLedger is the ledger of the latest snapshot
+	var snapshotLedger uint32
+	if config.SnapshotIntervalEvents > 0 {
+		snapshotLedger, err = store.GetSnapshotLedger(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch latest snapshot ledger: %w", err)
+		}
+	}
+
+	slog.Info("Indexer setup complete!")
+
+	latestLedger, closeLatest := newLatestLedgerFunc(config)
+	defer closeLatest()
+	progress := newProgressTracker(store, STATUS_SOURCE, startSeq, config.ProgressLogLedgers, latestLedger)
+
+	return idx.ingest(ctx, backend, lock.Lost(), config, networkPassphrase, startSeq, snapshotLedger, progress)
+}
+
+// ingest processes ledgers from the prepared backend, starting at startSeq, until ctx is cancelled, the ledger backend
+// fails, or lost is closed. A ledger that fails with a retryable error is processed again after DB_RETRY_DELAY,
+// without being fetched again, as a backend only returns each ledger once.
+//
+// ingest returns nil if it stopped because ctx was cancelled.
+func (idx *Indexer) ingest(ctx context.Context, backend ledgerbackend.LedgerBackend, lost <-chan struct{}, config *Config, networkPassphrase string, startSeq uint32, snapshotLedger uint32, progress *progressTracker) error {
+	// snapshotEvents is the number of governor events applied since the snapshot at snapshotLedger
+	var snapshotEvents int
+
+	// total accumulates the stats of every ledger processed by this run
+	var total LedgerStats
+	runStart := time.Now()
+	defer func() {
+		slog.Info("Indexer run summary.", summaryAttrs(total, time.Since(runStart))...)
+	}()
+
+	// ledger is ledger seq once fetched, kept until it is processed
+	var ledger xdr.LedgerCloseMeta
+	fetched := false
+	seq := startSeq
+	for {
+		select {
+		case <-lost:
+			// another instance may now be writing, so stop immediately
+			return fmt.Errorf("indexer lock lost at ledger %d", seq)
+		default:
+		}
+
+		if !fetched {
+			var err error
+			ledger, err = backend.GetLedger(ctx, seq)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to get ledger %d: %w", seq, err)
+			}
+			fetched = true
+		}
+		startTime := time.Now()
+
+		stats, err := idx.processLedger(ctx, networkPassphrase, ledger)
+		if ctx.Err() != nil {
+			return nil
+		} else if errors.Is(err, ErrLedgerRetryable) {
+			slog.Warn("Failed to process ledger, retrying", "ledger", seq, "retry_in", idx.retryDelay, "err", err)
+			if !sleepCtx(ctx, idx.retryDelay) {
+				return nil
+			}
+			continue
+		} else if err != nil {
+			// retrying won't help, and skipping the ledger would lose its events, so stop at the last processed ledger
+			return fmt.Errorf("failed to process ledger %d: %w", seq, err)
+		}
+
+		if config.HistoryRetentionLedgers > 0 && seq%config.HistoryPruneIntervalLedgers == 0 {
+			if _, err := idx.PruneHistory(ctx, seq, config.HistoryRetentionLedgers); err != nil {
+				slog.Error("Failed to prune history", "ledger", seq, "err", err)
+			}
+		}
+
+		if config.SnapshotIntervalEvents > 0 {
+			snapshotEvents += stats.GovernorEvents
+			if snapshotEvents >= config.SnapshotIntervalEvents {
+				if _, err := idx.SnapshotContracts(ctx, snapshotLedger, seq, config.SnapshotRetain); err != nil {
+					slog.Error("Failed to snapshot contracts", "ledger", seq, "err", err)
+				} else {
+					snapshotLedger, snapshotEvents = seq, 0
+				}
+			}
+		}
+
+		idx.RecordContractEvents(ctx, stats.Contracts)
+		stats.record()
+		metrics.LastLedger.Set(float64(seq))
+		total.Add(stats)
+		progress.add(seq, stats)
+		progress.report(ctx)
+
+		elapsed := time.Since(startTime)
+		metrics.ProcessingSeconds.Add(elapsed.Seconds())
+		slog.Info("Ledger processed.", append([]any{"ledger", ledger.LedgerSequence(), "ms", elapsed.Milliseconds()}, stats.LogAttrs()...)...)
+		fetched = false
+		seq++
+	}
+}
+
+// processLedger applies a ledger with ApplyLedger, then records it as the last processed ledger. The last processed
+// ledger is only moved once the ledger is applied, so a ledger that fails is applied again when it is retried or the
+// indexer restarts. Errors wrap ErrLedgerRetryable if the ledger can be retried.
+func (idx *Indexer) processLedger(ctx context.Context, networkPassphrase string, ledger xdr.LedgerCloseMeta) (LedgerStats, error) {
+	txReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(networkPassphrase, ledger)
+	if err != nil {
+		return LedgerStats{}, fmt.Errorf("%w: failed to create transaction reader: %w", ErrLedgerInvalid, err)
+	}
+	stats, err := idx.ApplyLedger(ctx, txReader, ledger.LedgerSequence(), ledger.LedgerCloseTime())
+	if err != nil {
+		return stats, fmt.Errorf("failed to apply ledger: %w", err)
+	}
+
+	err = idx.store.UpsertStatus(ctx, STATUS_SOURCE, ledger.LedgerSequence(), ledger.LedgerCloseTime())
+	if err != nil {
+		return stats, fmt.Errorf("%w: failed to update last processed ledger: %w", ErrLedgerRetryable, err)
+	}
+	return stats, nil
+}
+
+// networkPassphrase returns the passphrase of the configured network
+func networkPassphrase(config *Config) string {
+	if config.Network == "public" {
+		return network.PublicNetworkPassphrase
+	}
+	return network.TestNetworkPassphrase
+}
+
+// newLedgerBackend creates the ledger backend described by the config
+func newLedgerBackend(config *Config, networkPassphrase string) (ledgerbackend.LedgerBackend, error) {
+	switch config.LedgerBackendType {
+	case "core":
+		captiveCoreToml, err := newCaptiveCoreToml(config, networkPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		if config.CoreStoragePath != "" {
+			if err := checkStorageDir(config.CoreStoragePath); err != nil {
+				return nil, err
+			}
+		}
+		logCatchupStrategy(config.CoreStoragePath)
+		captiveCoreConfig := ledgerbackend.CaptiveCoreConfig{
+			BinaryPath:         config.CoreBinaryPath,
+			NetworkPassphrase:  networkPassphrase,
+			HistoryArchiveURLs: historyArchiveURLs(config),
+			Toml:               captiveCoreToml,
+			StoragePath:        config.CoreStoragePath,
+		}
+		captiveCoreConfig.Log = newCoreLogger(config)
+		backend, err := ledgerbackend.NewCaptive(captiveCoreConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create captive core backend: %w", err)
+		}
+		return backend, nil
+	case "rpc":
+		pollInterval := time.Duration(config.RPCPollInterval) * time.Second
+		newRPCBackend := func(url string) ledgerbackend.LedgerBackend {
+			return newTipBackend(url, pollInterval)
+		}
+		if len(config.RPCUrls) == 1 {
+			return newRPCBackend(config.RPCUrls[0]), nil
+		}
+		return newFailoverBackend(config.RPCUrls, newRPCBackend), nil
+	default:
+		return nil, fmt.Errorf("unsupported LEDGER_BACKEND_TYPE %s", config.LedgerBackendType)
+	}
+}
+
+// newLatestLedgerFunc returns a function getting the latest ledger of the network from the first RPC server, and a
+// function closing its client. The latest ledger is unknown if no RPC server is configured.
+func newLatestLedgerFunc(config *Config) (func(ctx context.Context) (uint32, error), func()) {
+	if len(config.RPCUrls) == 0 {
+		return nil, func() {}
+	}
+	client := rpcclient.NewClient(config.RPCUrls[0], nil)
+	latestLedger := func(ctx context.Context) (uint32, error) {
+		latest, err := client.GetLatestLedger(ctx)
+		return latest.Sequence, err
+	}
+	return latestLedger, func() { client.Close() }
+}
+
+// historyArchiveURLs returns the history archives of the configured network
+func historyArchiveURLs(config *Config) []string {
+	if config.Network == "public" {
+		return network.PublicNetworkhistoryArchiveURLs
+	}
+	return network.TestNetworkhistoryArchiveURLs
+}
+
+// newCaptiveCoreToml loads the captive core config file, with the network's history archives by default
+func newCaptiveCoreToml(config *Config, networkPassphrase string) (*ledgerbackend.CaptiveCoreToml, error) {
+	defaultParams := ledgerbackend.CaptiveCoreTomlParams{
+		NetworkPassphrase:  networkPassphrase,
+		HistoryArchiveURLs: historyArchiveURLs(config),
+	}
+	captiveCoreToml, err := ledgerbackend.NewCaptiveCoreTomlFromFile(config.CoreConfigPath, defaultParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load captive core toml: %w", err)
+	}
+	return captiveCoreToml, nil
+}
+
+// newCoreLogger creates the logrus logger for captive-core output. It logs at CORE_LOG_LEVEL, but never more
+// verbosely than LOG_LEVEL, and in the same format as the rest of the indexer.
+func newCoreLogger(config *Config) *log.Entry {
+	lg := log.New()
+	level, err := logrus.ParseLevel(config.CoreLogLevel)
+	if err != nil {
+		slog.Warn("Invalid CORE_LOG_LEVEL, defaulting to warn", "value", config.CoreLogLevel, "err", err)
+		level = logrus.WarnLevel
+	}
+	// logrus levels increase in verbosity, so the smaller level is the less verbose one
+	lg.SetLevel(min(level, logrusLevel(config.Log.SlogLevel())))
+	if config.Log.Format == "json" {
+		lg.UseJSONFormatter()
+	}
+	return lg
+}
+
+// logrusLevel returns the logrus level equivalent to a slog level
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.DebugLevel
+	}
+}
+
+// sleepCtx waits for the given duration, and returns false if ctx was cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,311 @@
+package indexer
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/toid"
+)
+
+const (
+	// SEED_LEDGER_SECONDS is the time between seeded ledgers
+	SEED_LEDGER_SECONDS = 5
+	// SEED_VOTE_DELAY is the number of ledgers from a seeded proposal's creation to the start of its vote
+	SEED_VOTE_DELAY = 720
+	// SEED_VOTE_PERIOD is the number of ledgers a seeded proposal's vote is open for
+	SEED_VOTE_PERIOD = 17280
+	// SEED_TIMELOCK is the number of ledgers from the end of a seeded proposal's vote until it can be executed
+	SEED_TIMELOCK = 17280
+	// SEED_VOTERS is the number of voters of each seeded contract
+	SEED_VOTERS = 40
+	// seedAction is the action of seeded proposals, a council change
+	seedAction = "AAAAEAAAAAEAAAACAAAADwAAAAdDb3VuY2lsAAAAABIAAAAAAAAAACyfzOsG6kr4egXEnuSiQ/GlhwkxRxrt2FCrVKgB9Obl"
+)
+
+// seedStatuses are the statuses seeded proposals cycle through, so every status is seeded
+var seedStatuses = []uint32{0, 1, 2, 3, 4, 5}
+
+var seedTitles = []string{
+	"Increase the proposal threshold",
+	"Fund the Q3 developer grants program",
+	"Add a backstop module for the USDC pool",
+	"Lower the voting period to five days",
+	"Rotate the security council",
+	"Upgrade the governor contract",
+	"Adjust reserve factors for volatile assets",
+	"Sponsor the community hackathon",
+}
+
+// SeedOptions configures Seed
+type SeedOptions struct {
+	// Contracts is the number of governor contracts to seed
+	Contracts int
+	// Proposals is the number of proposals seeded for each contract
+	Proposals int
+	// Seed makes the generated data deterministic. Seeding twice with the same options generates the same data.
+	Seed uint64
+	// Ledger is the latest seeded ledger, recorded as the last processed ledger
+	Ledger uint32
+	// Now is the close time of Ledger. Earlier ledgers closed SEED_LEDGER_SECONDS apart.
+	Now time.Time
+}
+
+// SeedStats are the results of Seed
+type SeedStats struct {
+	Contracts []string
+	Proposals int
+	Votes     int
+	Events    int
+}
+
+// Seed populates an empty database with generated governor contracts, proposals in every status, and votes, for
+// developing against the API. Generated events are applied with ApplyEvent, like indexed events, so the event history
+// is consistent with the proposals and votes. The status of the indexer is set to opts.Ledger, so health checks pass.
+//
+// Seed returns an error if the database already has an indexed ledger.
+func Seed(ctx context.Context, store Store, opts SeedOptions) (SeedStats, error) {
+	if lastLedger, _, err := store.GetStatus(ctx, STATUS_SOURCE); err != nil {
+		return SeedStats{}, fmt.Errorf("failed to get indexer status: %w", err)
+	} else if lastLedger != 0 {
+		return SeedStats{}, fmt.Errorf("database already has indexed ledgers up to %d, seed an empty database", lastLedger)
+	}
+	// the oldest seeded proposal is created up to 500000 ledgers before opts.Ledger
+	if opts.Ledger < 500000 {
+		return SeedStats{}, fmt.Errorf("seeded ledger %d must be at least 500000", opts.Ledger)
+	}
+
+	s := &seeder{
+		rng:      rand.New(rand.NewPCG(opts.Seed, opts.Seed)),
+		opts:     opts,
+		txCounts: make(map[uint32]int32),
+	}
+	var stats SeedStats
+	var events []*governor.GovernorEvent
+	for range opts.Contracts {
+		contractId := s.address(strkey.VersionByteContract)
+		if err := store.UpsertVotesContract(ctx, contractId, s.address(strkey.VersionByteContract), opts.Ledger-500000); err != nil {
+			return stats, err
+		}
+		voters := make([]string, SEED_VOTERS)
+		for i := range voters {
+			voters[i] = s.address(strkey.VersionByteAccountID)
+		}
+		for proposalId := range uint32(opts.Proposals) {
+			proposalEvents, err := s.proposal(contractId, proposalId, seedStatuses[int(proposalId)%len(seedStatuses)], voters)
+			if err != nil {
+				return stats, err
+			}
+			events = append(events, proposalEvents...)
+			stats.Proposals++
+		}
+		stats.Contracts = append(stats.Contracts, contractId)
+	}
+
+	// apply events in the order the indexer would
+	slices.SortFunc(events, func(a, b *governor.GovernorEvent) int { return cmp.Compare(a.EventId, b.EventId) })
+	idx := NewIndexer(store)
+	idx.now = func() time.Time { return opts.Now }
+	for _, event := range events {
+		if err := idx.ApplyEvent(ctx, event); err != nil {
+			return stats, fmt.Errorf("failed to apply seeded event %s: %w", event.EventId, err)
+		}
+		stats.Events++
+		if event.EventType == "vote_cast" {
+			stats.Votes++
+		}
+	}
+
+	if err := store.UpsertStatus(ctx, STATUS_SOURCE, opts.Ledger, opts.Now.Unix()); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// seeder generates governor events
+type seeder struct {
+	rng  *rand.Rand
+	opts SeedOptions
+	// txCounts is the number of transactions generated in each ledger, so event ids are unique
+	txCounts map[uint32]int32
+}
+
+// proposal generates the events of a proposal ending in status
+func (s *seeder) proposal(contractId string, proposalId uint32, status uint32, voters []string) ([]*governor.GovernorEvent, error) {
+	latest := s.opts.Ledger
+	// open proposals are still voting, others have finished and been executed or expired
+	created := latest - 300000 - uint32(s.rng.IntN(200000))
+	if status == 0 {
+		created = latest - SEED_VOTE_DELAY - uint32(s.rng.IntN(SEED_VOTE_PERIOD-1))
+	}
+	voteStart := created + SEED_VOTE_DELAY
+	voteEnd := voteStart + SEED_VOTE_PERIOD
+
+	var events []*governor.GovernorEvent
+	add := func(eventType string, ledger uint32, data any) error {
+		eventData, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s event data: %w", eventType, err)
+		}
+		events = append(events, s.event(contractId, proposalId, eventType, ledger, string(eventData)))
+		return nil
+	}
+
+	title := seedTitles[s.rng.IntN(len(seedTitles))]
+	err := add("proposal_created", created, governor.ProposalCreatedData{
+		Proposer:  voters[s.rng.IntN(len(voters))],
+		Title:     title,
+		Desc:      fmt.Sprintf("# %s\n\nThis proposal was generated by the seed command.", title),
+		Action:    seedAction,
+		VoteStart: voteStart,
+		VoteEnd:   voteEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status == 5 {
+		return events, add("proposal_canceled", created+uint32(s.rng.IntN(SEED_VOTE_DELAY)), struct{}{})
+	}
+
+	// open proposals are voted on until the latest ledger
+	lastVote := min(voteEnd-1, latest)
+	passed := status == 1 || status == 3 || status == 4
+	votes := s.votes(voters, passed || (status == 0 && s.rng.IntN(2) == 0))
+	tally := governor.VoteCount{For: "0", Against: "0", Abstain: "0"}
+	for _, vote := range votes {
+		if err := add("vote_cast", voteStart+uint32(s.rng.IntN(int(lastVote-voteStart)+1)), vote); err != nil {
+			return nil, err
+		}
+		if err := addTally(&tally, vote); err != nil {
+			return nil, err
+		}
+	}
+	if status == 0 {
+		return events, nil
+	}
+
+	closed := voteEnd + uint32(s.rng.IntN(1000))
+	closedStatus, eta := uint32(2), uint32(0)
+	if passed {
+		closedStatus, eta = 1, voteEnd+SEED_TIMELOCK
+	}
+	if err := add("proposal_voting_closed", closed, governor.ProposalVotingClosedData{Status: closedStatus, Eta: eta, FinalVotes: tally}); err != nil {
+		return nil, err
+	}
+	switch status {
+	case 3:
+		return events, add("proposal_expired", eta+50000+uint32(s.rng.IntN(1000)), struct{}{})
+	case 4:
+		return events, add("proposal_executed", eta+uint32(s.rng.IntN(1000)), struct{}{})
+	}
+	return events, nil
+}
+
+// votes generates votes from a random subset of voters, with amounts spanning several orders of magnitude. The
+// proposal passes if passed is set.
+func (s *seeder) votes(voters []string, passed bool) []governor.VoteCastData {
+	count := 5 + s.rng.IntN(len(voters)-5)
+	var votes []governor.VoteCastData
+	votesFor, against := new(big.Int), new(big.Int)
+	for _, i := range s.rng.Perm(len(voters))[:count] {
+		// most voters support the outcome, with some abstaining
+		support := governor.VOTE_SUPPORT_AGAINST
+		if passed {
+			support = governor.VOTE_SUPPORT_FOR
+		}
+		switch roll := s.rng.Float64(); {
+		case roll < 0.1:
+			support = governor.VOTE_SUPPORT_ABSTAIN
+		case roll < 0.35:
+			support = oppositeSupport(support)
+		}
+		// between 10 and 1 million tokens, with 7 decimals
+		amount := new(big.Int).SetUint64(uint64(math.Pow(10, 1+5*s.rng.Float64()) * 1e7))
+		switch support {
+		case governor.VOTE_SUPPORT_AGAINST:
+			against.Add(against, amount)
+		case governor.VOTE_SUPPORT_FOR:
+			votesFor.Add(votesFor, amount)
+		}
+		votes = append(votes, governor.VoteCastData{Voter: voters[i], Support: uint32(support), Amount: amount.String()})
+	}
+	// swap for and against if the random votes don't match the outcome
+	if (votesFor.Cmp(against) > 0) != passed {
+		for i := range votes {
+			votes[i].Support = uint32(oppositeSupport(governor.VoteSupport(votes[i].Support)))
+		}
+	}
+	return votes
+}
+
+// oppositeSupport swaps for and against. Other support values are unchanged.
+func oppositeSupport(support governor.VoteSupport) governor.VoteSupport {
+	switch support {
+	case governor.VOTE_SUPPORT_FOR:
+		return governor.VOTE_SUPPORT_AGAINST
+	case governor.VOTE_SUPPORT_AGAINST:
+		return governor.VOTE_SUPPORT_FOR
+	}
+	return support
+}
+
+// addTally adds a vote to a vote count
+func addTally(tally *governor.VoteCount, vote governor.VoteCastData) error {
+	counts := []*string{&tally.Against, &tally.For, &tally.Abstain}
+	total, err := bigmath.AddAmount(*counts[vote.Support], vote.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to tally seeded vote: %w", err)
+	}
+	*counts[vote.Support] = total
+	return nil
+}
+
+// event generates an event emitted in its own transaction in ledger
+func (s *seeder) event(contractId string, proposalId uint32, eventType string, ledger uint32, eventData string) *governor.GovernorEvent {
+	txIndex := s.txCounts[ledger] + 1
+	s.txCounts[ledger] = txIndex
+	hash := make([]byte, 32)
+	for i := range hash {
+		hash[i] = byte(s.rng.UintN(256))
+	}
+	eventId, err := governor.EncodeEventId(toid.New(int32(ledger), txIndex, 0).ToInt64(), 0)
+	if err != nil {
+		// only fails for negative toids, and seeded ledgers are positive
+		panic(err)
+	}
+	return &governor.GovernorEvent{
+		EventId:         eventId,
+		ContractId:      contractId,
+		ProposalId:      proposalId,
+		EventType:       eventType,
+		EventData:       eventData,
+		TxHash:          hex.EncodeToString(hash),
+		LedgerSeq:       ledger,
+		LedgerCloseTime: s.opts.Now.Unix() - int64(s.opts.Ledger-ledger)*SEED_LEDGER_SECONDS,
+		SchemaVersion:   governor.SCHEMA_V1,
+	}
+}
+
+// address generates a StrKey address
+func (s *seeder) address(version strkey.VersionByte) string {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(s.rng.UintN(256))
+	}
+	address, err := strkey.Encode(version, key)
+	if err != nil {
+		// only fails for invalid version bytes
+		panic(err)
+	}
+	return address
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+func TestSeed(t *testing.T) {
+	ctx := t.Context()
+	opts := SeedOptions{Contracts: 2, Proposals: 7, Seed: 42, Ledger: 1170234, Now: time.Unix(1761053041, 0)}
+
+	store := newFixtureStore(t)
+	stats, err := Seed(ctx, store, opts)
+	if err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	if len(stats.Contracts) != 2 || stats.Proposals != 14 || stats.Votes == 0 {
+		t.Errorf("Seed() stats = %+v, want 2 contracts, 14 proposals, and votes", stats)
+	}
+
+	statuses := make(map[uint32]int)
+	for _, contractId := range stats.Contracts {
+		proposals, err := store.GetProposalsByContractId(ctx, contractId, db.Sort{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, proposal := range proposals {
+			statuses[proposal.Status]++
+			votesFor, _ := new(big.Int).SetString(proposal.VotesFor, 10)
+			against, _ := new(big.Int).SetString(proposal.VotesAgainst, 10)
+			// open proposals may be leaning either way
+			if passed := proposal.Status == 1 || proposal.Status == 3 || proposal.Status == 4; proposal.Status != 0 && passed != (votesFor.Cmp(against) > 0) {
+				t.Errorf("proposal %s with status %d has %s for and %s against", proposal.ProposalKey, proposal.Status, proposal.VotesFor, proposal.VotesAgainst)
+			}
+			if proposal.Status == 0 && proposal.VoteEnd <= opts.Ledger {
+				t.Errorf("open proposal %s ends at ledger %d, before the seeded ledger", proposal.ProposalKey, proposal.VoteEnd)
+			}
+		}
+	}
+	// the seventh proposal of each contract cycles back to open
+	if diff := cmp.Diff(map[uint32]int{0: 4, 1: 2, 2: 2, 3: 2, 4: 2, 5: 2}, statuses); diff != "" {
+		t.Errorf("proposal statuses mismatch (-want +got):\n%s", diff)
+	}
+	if ledger, closeTime, _ := store.GetStatus(ctx, STATUS_SOURCE); ledger != opts.Ledger || closeTime != opts.Now.Unix() {
+		t.Errorf("status = %d at %d, want %d at %d", ledger, closeTime, opts.Ledger, opts.Now.Unix())
+	}
+
+	// seeding is deterministic
+	other := newFixtureStore(t)
+	if _, err := Seed(ctx, other, opts); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	want, err := store.GetEventsAfter(ctx, "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := other.GetEventsAfter(ctx, "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("seeded events mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Seed(ctx, store, opts); err == nil {
+		t.Errorf("Seed() into a seeded database error = nil, want error")
+	}
+}
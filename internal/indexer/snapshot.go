@@ -0,0 +1,125 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// REPLAY_PAGE_SIZE is the number of events read from the history table at a time when replaying from a snapshot
+const REPLAY_PAGE_SIZE = 1000
+
+// SnapshotContracts snapshots the proposals of each contract with events emitted after afterLedger, up to and
+// including ledgerSeq, and deletes all but the latest retain snapshots of each. It must be called once ledgerSeq
+// has been fully applied, and before any later ledger is, so the snapshots match the event history.
+//
+// Returns the number of contracts snapshotted.
+func (idx *Indexer) SnapshotContracts(ctx context.Context, afterLedger uint32, ledgerSeq uint32, retain int) (int, error) {
+	eventIds, err := idx.store.GetLastEventIds(ctx, afterLedger, ledgerSeq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get contracts to snapshot: %w", err)
+	}
+
+	var pruned int64
+	for contractId, eventId := range eventIds {
+		proposals, err := idx.store.GetProposalsByContractId(ctx, contractId, db.Sort{})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get proposals for contract %s: %w", contractId, err)
+		}
+		snapshot := &db.Snapshot{
+			ContractId: contractId,
+			LedgerSeq:  ledgerSeq,
+			EventId:    eventId,
+			Proposals:  proposals,
+			CreatedAt:  idx.now().Unix(),
+		}
+		if err := idx.store.InsertSnapshot(ctx, snapshot); err != nil {
+			return 0, fmt.Errorf("failed to snapshot contract %s: %w", contractId, err)
+		}
+		deleted, err := idx.store.PruneSnapshots(ctx, contractId, retain)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune snapshots for contract %s: %w", contractId, err)
+		}
+		pruned += deleted
+	}
+
+	slog.Info("Snapshotted contracts", "ledger", ledgerSeq, "contracts", len(eventIds), "pruned", pruned)
+	return len(eventIds), nil
+}
+
+// ReindexContractFromSnapshot rebuilds the proposals, votes, and delegations for a contract like ReindexContract,
+// but starts from the latest snapshot of the contract taken before beforeLedger, or the latest snapshot if
+// beforeLedger is 0. The snapshot's proposals are restored, votes and delegations after the snapshot are deleted,
+// and only the events after the snapshot are replayed. If the contract has no such snapshot, it is fully reindexed.
+//
+// The history table must retain every event after the snapshot, otherwise ErrHistoryPruned is returned.
+//
+// onProgress, if not nil, is called after each event is replayed with the number of events replayed so far
+// and the total number of events to replay. The contract is flagged as rebuilding in the status table until the
+// reindex is done.
+func (idx *Indexer) ReindexContractFromSnapshot(ctx context.Context, contractId string, beforeLedger uint32, onProgress func(replayed int, total int)) error {
+	return idx.withRebuilding(ctx, contractId, func() error {
+		return idx.reindexContractFromSnapshot(ctx, contractId, beforeLedger, onProgress)
+	})
+}
+
+// reindexContractFromSnapshot is ReindexContractFromSnapshot without the rebuilding flag
+func (idx *Indexer) reindexContractFromSnapshot(ctx context.Context, contractId string, beforeLedger uint32, onProgress func(replayed int, total int)) error {
+	snapshot, err := idx.store.GetLatestSnapshot(ctx, contractId, beforeLedger)
+	if errors.Is(err, db.ErrNotFound) {
+		slog.Info("No snapshot to reindex from, reindexing the full history", "contract", contractId, "before_ledger", beforeLedger)
+		return idx.reindexContract(ctx, contractId, onProgress)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot for contract %s: %w", contractId, err)
+	}
+
+	retainedLedger, _, err := idx.store.GetStatus(ctx, RETENTION_STATUS_SOURCE)
+	if err != nil {
+		return fmt.Errorf("failed to get history retention status: %w", err)
+	}
+	if retainedLedger > snapshot.LedgerSeq+1 {
+		return fmt.Errorf("unable to reindex contract %s from the snapshot at ledger %d, events before ledger %d were deleted: %w", contractId, snapshot.LedgerSeq, retainedLedger, ErrHistoryPruned)
+	}
+
+	return idx.store.WithTx(ctx, func(ctx context.Context) error {
+		var events []*governor.GovernorEvent
+		after := snapshot.EventId
+		for {
+			page, err := idx.store.GetEventsAfter(ctx, contractId, after, REPLAY_PAGE_SIZE)
+			if err != nil {
+				return fmt.Errorf("failed to get events for contract %s: %w", contractId, err)
+			}
+			events = append(events, page...)
+			if len(page) < REPLAY_PAGE_SIZE {
+				break
+			}
+			after = page[len(page)-1].EventId
+		}
+
+		deletedVotes, err := idx.store.DeleteVotesAfterLedger(ctx, contractId, snapshot.LedgerSeq)
+		if err != nil {
+			return fmt.Errorf("failed to delete votes for contract %s: %w", contractId, err)
+		}
+		deletedProposals, err := idx.store.DeleteProposalsByContractId(ctx, contractId)
+		if err != nil {
+			return fmt.Errorf("failed to delete proposals for contract %s: %w", contractId, err)
+		}
+		deletedDelegations, err := idx.store.DeleteDelegationsAfterLedger(ctx, contractId, snapshot.LedgerSeq)
+		if err != nil {
+			return fmt.Errorf("failed to delete delegations for contract %s: %w", contractId, err)
+		}
+		for _, proposal := range snapshot.Proposals {
+			if err := idx.store.InsertProposal(ctx, proposal); err != nil {
+				return fmt.Errorf("failed to restore proposal %s: %w", proposal.ProposalKey, err)
+			}
+		}
+		slog.Info("Reindexing contract from snapshot", "contract", contractId, "snapshot_ledger", snapshot.LedgerSeq, "events", len(events), "deleted_proposals", deletedProposals, "restored_proposals", len(snapshot.Proposals), "deleted_votes", deletedVotes, "deleted_delegations", deletedDelegations)
+
+		return idx.replayEvents(ctx, contractId, events, onProgress)
+	})
+}
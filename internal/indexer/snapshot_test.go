@@ -0,0 +1,194 @@
+package indexer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+// contractState returns the proposals of a contract and the votes on each
+func contractState(t *testing.T, store *db.Store, contractId string) ([]*governor.Proposal, [][]*governor.Vote) {
+	t.Helper()
+	ctx := t.Context()
+	proposals, err := store.GetProposalsByContractId(ctx, contractId, db.Sort{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var votes [][]*governor.Vote
+	for _, proposal := range proposals {
+		proposalVotes, err := store.GetVotesByProposal(ctx, contractId, proposal.ProposalId, db.Sort{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		votes = append(votes, proposalVotes)
+	}
+	return proposals, votes
+}
+
+func TestReindexContractFromSnapshot(t *testing.T) {
+	ctx := t.Context()
+	now := time.Unix(1761053041, 0)
+
+	// the full replay of the seeded history is the expected state
+	full := newFixtureStore(t)
+	stats, err := Seed(ctx, full, SeedOptions{Contracts: 2, Proposals: 12, Seed: 7, Ledger: 1170234, Now: now})
+	if err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+	contractId := stats.Contracts[0]
+	fullIndexer := NewIndexer(full)
+	fullIndexer.now = func() time.Time { return now }
+	if err := fullIndexer.ReindexContract(ctx, contractId, nil); err != nil {
+		t.Fatalf("ReindexContract() error = %v", err)
+	}
+	wantProposals, wantVotes := contractState(t, full, contractId)
+	events, err := full.GetEventsAfter(ctx, "", "", 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ingest the same events, snapshotting at two ledgers, partway through the voting of some proposals
+	store := newFixtureStore(t)
+	idx := NewIndexer(store)
+	idx.now = func() time.Time { return now }
+	snapshotLedgers := []uint32{1170234 - 300000, 1170234 - 10000}
+	var snapshotted uint32
+	for _, event := range events {
+		for _, ledger := range snapshotLedgers {
+			if event.LedgerSeq > ledger && snapshotted < ledger {
+				if _, err := idx.SnapshotContracts(ctx, snapshotted, ledger, 3); err != nil {
+					t.Fatalf("SnapshotContracts() error = %v", err)
+				}
+				snapshotted = ledger
+			}
+		}
+		if err := idx.ApplyEvent(ctx, event); err != nil {
+			t.Fatalf("ApplyEvent() error = %v", err)
+		}
+	}
+	gotProposals, gotVotes := contractState(t, store, contractId)
+	if diff := cmp.Diff(wantProposals, gotProposals); diff != "" {
+		t.Fatalf("ingested proposals mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantVotes, gotVotes); diff != "" {
+		t.Fatalf("ingested votes mismatch (-want +got):\n%s", diff)
+	}
+
+	corrupt := func() {
+		t.Helper()
+		proposal, err := store.GetProposal(ctx, governor.EncodeProposalKey(contractId, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		proposal.VotesFor = "999"
+		proposal.Status = 5
+		if err := store.UpsertProposal(ctx, proposal); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.DeleteVotesAfterLedger(ctx, contractId, snapshotLedgers[1]-SEED_VOTE_PERIOD); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		beforeLedger uint32
+		// wantSnapshot is the ledger of the snapshot replayed from, or 0 if the full history is replayed
+		wantSnapshot uint32
+	}{
+		{name: "latest snapshot", wantSnapshot: snapshotLedgers[1]},
+		{name: "snapshot before a ledger", beforeLedger: snapshotLedgers[1], wantSnapshot: snapshotLedgers[0]},
+		{name: "no snapshot before a ledger", beforeLedger: snapshotLedgers[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt()
+			wantReplayed := 0
+			for _, event := range events {
+				if event.ContractId == contractId && event.LedgerSeq > tt.wantSnapshot {
+					wantReplayed++
+				}
+			}
+
+			var replayed, total int
+			err := idx.ReindexContractFromSnapshot(ctx, contractId, tt.beforeLedger, func(r int, tot int) {
+				replayed, total = r, tot
+			})
+			if err != nil {
+				t.Fatalf("ReindexContractFromSnapshot() error = %v", err)
+			}
+			if replayed != wantReplayed || total != wantReplayed {
+				t.Errorf("progress = %d/%d, want %d/%d", replayed, total, wantReplayed, wantReplayed)
+			}
+
+			gotProposals, gotVotes := contractState(t, store, contractId)
+			if diff := cmp.Diff(wantProposals, gotProposals); diff != "" {
+				t.Errorf("proposals mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(wantVotes, gotVotes); diff != "" {
+				t.Errorf("votes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// the events after the snapshot must be retained
+	if err := store.UpsertStatus(ctx, RETENTION_STATUS_SOURCE, snapshotLedgers[1]+1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.ReindexContractFromSnapshot(ctx, contractId, 0, nil); err != nil {
+		t.Errorf("ReindexContractFromSnapshot() with the events after the snapshot retained error = %v", err)
+	}
+	if err := idx.ReindexContractFromSnapshot(ctx, contractId, snapshotLedgers[1], nil); !errors.Is(err, ErrHistoryPruned) {
+		t.Errorf("ReindexContractFromSnapshot() with pruned history error = %v, want ErrHistoryPruned", err)
+	}
+}
+
+func TestSnapshotContracts(t *testing.T) {
+	ctx := t.Context()
+	store := setupStore(t, ctx)
+	idx := NewIndexer(store)
+	idx.now = func() time.Time { return time.Unix(1761053041, 0) }
+
+	// setupStore's events are all before ledgerSeq
+	count, err := idx.SnapshotContracts(ctx, 0, ledgerSeq, 2)
+	if err != nil {
+		t.Fatalf("SnapshotContracts() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("snapshotted %d contracts, want 1", count)
+	}
+	snapshot, err := store.GetLatestSnapshot(ctx, testContractId, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposals, _ := contractState(t, store, testContractId)
+	if diff := cmp.Diff(proposals, snapshot.Proposals); diff != "" {
+		t.Errorf("snapshot proposals mismatch (-want +got):\n%s", diff)
+	}
+	if snapshot.LedgerSeq != ledgerSeq || snapshot.CreatedAt != 1761053041 {
+		t.Errorf("snapshot at ledger %d created at %d, want ledger %d created at 1761053041", snapshot.LedgerSeq, snapshot.CreatedAt, ledgerSeq)
+	}
+
+	// contracts without new events are not snapshotted again
+	count, err = idx.SnapshotContracts(ctx, ledgerSeq, ledgerSeq+100, 2)
+	if err != nil {
+		t.Fatalf("SnapshotContracts() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("snapshotted %d contracts without new events, want 0", count)
+	}
+
+	// only the latest snapshots are retained
+	for _, ledger := range []uint32{ledgerSeq + 1, ledgerSeq + 2} {
+		if _, err := idx.SnapshotContracts(ctx, 0, ledger, 2); err != nil {
+			t.Fatalf("SnapshotContracts() error = %v", err)
+		}
+	}
+	if _, err := store.GetLatestSnapshot(ctx, testContractId, ledgerSeq+1); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("GetLatestSnapshot() of a pruned snapshot error = %v, want ErrNotFound", err)
+	}
+}
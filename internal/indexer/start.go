@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+)
+
+const (
+	// START_SEQ_RETENTION_MARGIN is how many ledgers after the oldest ledger retained by the RPC server indexing
+	// starts when LEDGER_BACKEND_START_SEQ is not set, so the start is still retained once the range is prepared
+	START_SEQ_RETENTION_MARGIN = 60
+	// RPC_HEALTH_TIMEOUT is the maximum duration of a getHealth request made on startup
+	RPC_HEALTH_TIMEOUT = 10 * time.Second
+)
+
+// resolveStartSeq returns the first ledger to index, after the last processed ledger or from
+// LEDGER_BACKEND_START_SEQ. If LEDGER_BACKEND_START_SEQ is not set and no ledger has been processed, indexing starts
+// from the oldest ledger retained by the RPC server. With the rpc backend, resolveStartSeq returns an error if the
+// start is older than the RPC server retains, as the ledgers in between could never be indexed. If
+// INDEXER_ALLOW_GAP is set, indexing starts from the oldest ledger retained instead.
+//
+// The ledgers knowingly skipped are returned as coverage gaps, to be recorded before indexing starts. Besides
+// INDEXER_ALLOW_GAP, ledgers are skipped if LEDGER_BACKEND_START_SEQ is raised past the last processed ledger.
+func resolveStartSeq(ctx context.Context, config *Config, lastLedger uint32) (uint32, []*db.CoverageGap, error) {
+	startSeq := max(lastLedger, config.LedgerBackendStartSeq)
+	var gaps []*db.CoverageGap
+	if lastLedger != 0 && config.LedgerBackendStartSeq > lastLedger+1 {
+		gaps = append(gaps, &db.CoverageGap{FromLedger: lastLedger + 1, ToLedger: config.LedgerBackendStartSeq - 1, Reason: GAP_REASON_START_SEQ})
+		slog.Warn("LEDGER_BACKEND_START_SEQ is after the last processed ledger, skipping the ledgers in between",
+			"ledger", startSeq, "last_processed", lastLedger)
+	}
+	if startSeq != 0 && config.LedgerBackendType != "rpc" {
+		return startSeq, gaps, nil
+	}
+
+	oldest, url, err := rpcOldestLedger(ctx, config.RPCUrls)
+	if err != nil {
+		if startSeq == 0 {
+			return 0, nil, fmt.Errorf("LEDGER_BACKEND_START_SEQ is not set, and the oldest ledger of the RPC server could not be read: %w", err)
+		}
+		// the range can still be prepared, and fails clearly enough if the start isn't retained
+		slog.Warn("Failed to check the start ledger is retained by the RPC server", "ledger", startSeq, "err", err)
+		return startSeq, gaps, nil
+	}
+
+	resuming := lastLedger != 0 && lastLedger >= config.LedgerBackendStartSeq
+	switch {
+	case startSeq == 0:
+		startSeq = oldest + START_SEQ_RETENTION_MARGIN
+		slog.Info("LEDGER_BACKEND_START_SEQ not set, starting from the oldest ledger retained by the RPC server", "ledger", startSeq, "oldest", oldest, "url", url)
+	case startSeq < oldest && config.IndexerAllowGap:
+		// the last processed ledger was indexed, so the gap starts after it
+		gap := &db.CoverageGap{FromLedger: startSeq, ToLedger: oldest + START_SEQ_RETENTION_MARGIN - 1, Reason: GAP_REASON_RPC_RETENTION}
+		if resuming {
+			gap.FromLedger = lastLedger + 1
+		}
+		slog.Warn("Start ledger is older than the RPC server retains, skipping to the oldest ledger retained as INDEXER_ALLOW_GAP is set",
+			"ledger", startSeq, "oldest", oldest, "url", url, "gap_from", gap.FromLedger, "gap_to", gap.ToLedger)
+		return gap.ToLedger + 1, append(gaps, gap), nil
+	case startSeq < oldest && resuming:
+		return 0, nil, fmt.Errorf("checkpoint %d is older than the oldest ledger %d retained by RPC server %s; "+
+			"backfill the ledgers in between with the core backend, reset the checkpoint, or set INDEXER_ALLOW_GAP=true to skip them",
+			lastLedger, oldest, url)
+	case startSeq < oldest:
+		return 0, nil, fmt.Errorf("LEDGER_BACKEND_START_SEQ %d is older than the oldest ledger %d retained by RPC server %s; "+
+			"set it to at least %d, use the core backend, or set INDEXER_ALLOW_GAP=true to skip the ledgers in between",
+			startSeq, oldest, url, oldest)
+	}
+	return startSeq, gaps, nil
+}
+
+// rpcOldestLedger returns the oldest ledger retained by the first of urls that responds to getHealth, and its URL
+func rpcOldestLedger(ctx context.Context, urls []string) (uint32, string, error) {
+	if len(urls) == 0 {
+		return 0, "", errors.New("RPC_URL is not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, RPC_HEALTH_TIMEOUT)
+	defer cancel()
+
+	var errs []error
+	for _, url := range urls {
+		client := rpcclient.NewClient(url, nil)
+		health, err := client.GetHealth(ctx)
+		client.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get health of RPC server %s: %w", url, err))
+			continue
+		}
+		return health.OldestLedger, url, nil
+	}
+	return 0, "", errors.Join(errs...)
+}
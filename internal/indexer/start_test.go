@@ -0,0 +1,123 @@
+package indexer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+)
+
+// newHealthServer returns an RPC server whose getHealth reports oldest as its oldest ledger
+func newHealthServer(t *testing.T, oldest uint32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getHealth" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.Id,
+			"result":  map[string]any{"status": "healthy", "latestLedger": oldest + 120960, "oldestLedger": oldest, "ledgerRetentionWindow": 120960},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveStartSeq(t *testing.T) {
+	rpc := newHealthServer(t, 1000000).URL
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name        string
+		backendType string
+		startSeq    uint32
+		lastLedger  uint32
+		allowGap    bool
+		rpcUrls     []string
+		want        uint32
+		wantGaps    []*db.CoverageGap
+		wantErr     bool
+	}{
+		{name: "rpc unset starts after oldest retained", backendType: "rpc", rpcUrls: []string{rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN},
+		{name: "rpc unset fails over to next server", backendType: "rpc", rpcUrls: []string{down.URL, rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN},
+		{name: "rpc unset with every server down", backendType: "rpc", rpcUrls: []string{down.URL}, wantErr: true},
+		{name: "rpc set within retention", backendType: "rpc", startSeq: 1000000, rpcUrls: []string{rpc}, want: 1000000},
+		{name: "rpc set below retention", backendType: "rpc", startSeq: 999999, rpcUrls: []string{rpc}, wantErr: true},
+		{name: "rpc set with server down", backendType: "rpc", startSeq: 999999, rpcUrls: []string{down.URL}, want: 999999},
+		{name: "rpc resumes within retention", backendType: "rpc", startSeq: 10, lastLedger: 1000500, rpcUrls: []string{rpc}, want: 1000500},
+		{name: "rpc resumes below retention", backendType: "rpc", startSeq: 10, lastLedger: 999000, rpcUrls: []string{rpc}, wantErr: true},
+		{name: "rpc set below retention with gap allowed", backendType: "rpc", startSeq: 999000, allowGap: true, rpcUrls: []string{rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN, wantGaps: []*db.CoverageGap{{FromLedger: 999000, ToLedger: 1000000 + START_SEQ_RETENTION_MARGIN - 1, Reason: GAP_REASON_RPC_RETENTION}}},
+		{name: "rpc resumes below retention with gap allowed", backendType: "rpc", startSeq: 10, lastLedger: 999000, allowGap: true, rpcUrls: []string{rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN, wantGaps: []*db.CoverageGap{{FromLedger: 999001, ToLedger: 1000000 + START_SEQ_RETENTION_MARGIN - 1, Reason: GAP_REASON_RPC_RETENTION}}},
+		{name: "rpc start raised past last processed", backendType: "rpc", startSeq: 1000600, lastLedger: 1000500, rpcUrls: []string{rpc}, want: 1000600, wantGaps: []*db.CoverageGap{{FromLedger: 1000501, ToLedger: 1000599, Reason: GAP_REASON_START_SEQ}}},
+		{name: "rpc start raised below retention with gap allowed", backendType: "rpc", startSeq: 999500, lastLedger: 999000, allowGap: true, rpcUrls: []string{rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN, wantGaps: []*db.CoverageGap{
+			{FromLedger: 999001, ToLedger: 999499, Reason: GAP_REASON_START_SEQ},
+			{FromLedger: 999500, ToLedger: 1000000 + START_SEQ_RETENTION_MARGIN - 1, Reason: GAP_REASON_RPC_RETENTION},
+		}},
+		{name: "rpc resumes within retention with gap allowed", backendType: "rpc", lastLedger: 1000500, allowGap: true, rpcUrls: []string{rpc}, want: 1000500},
+		{name: "core unset starts after oldest retained", backendType: "core", rpcUrls: []string{rpc}, want: 1000000 + START_SEQ_RETENTION_MARGIN},
+		{name: "core unset with server down", backendType: "core", rpcUrls: []string{down.URL}, wantErr: true},
+		{name: "core set below retention", backendType: "core", startSeq: 50457424, rpcUrls: []string{down.URL}, want: 50457424},
+		{name: "core start raised past last processed", backendType: "core", startSeq: 1000000, lastLedger: 999000, rpcUrls: []string{down.URL}, want: 1000000, wantGaps: []*db.CoverageGap{{FromLedger: 999001, ToLedger: 999999, Reason: GAP_REASON_START_SEQ}}},
+		{name: "core resumes", backendType: "core", lastLedger: 999000, rpcUrls: []string{down.URL}, want: 999000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{LedgerBackendType: tt.backendType, LedgerBackendStartSeq: tt.startSeq, IndexerAllowGap: tt.allowGap, RPCUrls: tt.rpcUrls}
+			got, gotGaps, err := resolveStartSeq(t.Context(), config, tt.lastLedger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveStartSeq() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveStartSeq() = %d, want %d", got, tt.want)
+			}
+			if diff := cmp.Diff(tt.wantGaps, gotGaps); diff != "" {
+				t.Errorf("resolveStartSeq() gaps mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecordCoverageGaps(t *testing.T) {
+	store := newFixtureStore(t)
+	gaps := []*db.CoverageGap{
+		{FromLedger: 999001, ToLedger: 1000059, Reason: GAP_REASON_RPC_RETENTION},
+		{FromLedger: 2000, ToLedger: 2000, Reason: GAP_REASON_VERIFY},
+	}
+	if err := RecordCoverageGaps(t.Context(), store, gaps, testNow); err != nil {
+		t.Fatalf("RecordCoverageGaps() error = %v", err)
+	}
+	// recording a range again keeps the first reason
+	later := []*db.CoverageGap{{FromLedger: 2000, ToLedger: 2000, Reason: GAP_REASON_START_SEQ}}
+	if err := RecordCoverageGaps(t.Context(), store, later, testNow.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordCoverageGaps() error = %v", err)
+	}
+
+	got, err := store.GetCoverageGaps(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*db.CoverageGap{
+		{FromLedger: 2000, ToLedger: 2000, Reason: GAP_REASON_VERIFY, CreatedAt: testNow.Unix()},
+		{FromLedger: 999001, ToLedger: 1000059, Reason: GAP_REASON_RPC_RETENTION, CreatedAt: testNow.Unix()},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("coverage gaps mismatch (-want +got):\n%s", diff)
+	}
+	count, err := store.CountCoverageGaps(t.Context())
+	if err != nil || count != 2 {
+		t.Errorf("CountCoverageGaps() = %d, %v, want 2", count, err)
+	}
+}
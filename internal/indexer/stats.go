@@ -0,0 +1,138 @@
+package indexer
+
+import (
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+)
+
+// LedgerStats counts the work done ingesting one or more ledgers
+type LedgerStats struct {
+	// Ledgers is the number of ledgers ingested
+	Ledgers int
+	// Transactions is the number of transactions read
+	Transactions int
+	// ContractEvents is the number of contract events emitted by successful InvokeHostFunction transactions
+	ContractEvents int
+	// GovernorEvents is the number of governor events parsed from the contract events
+	GovernorEvents int
+	// EventsApplied is the number of governor events applied to the database without error
+	EventsApplied int
+	// VotesInserted is the number of votes inserted
+	VotesInserted int
+	// ProposalsMutated is the number of proposals inserted or updated
+	ProposalsMutated int
+	// ParseFailures is the number of governor events that failed to parse
+	ParseFailures int
+	// FailedEvents is the number of governor events that could not be indexed, and are recorded as failed events
+	FailedEvents int
+	// UnknownEventTypes is the number of events from tracked contracts with an unknown event type. These are also
+	// counted in FailedEvents.
+	UnknownEventTypes int
+	// ProposalConflicts is the number of proposal_created events for an existing proposal with different content.
+	// These are also counted in FailedEvents.
+	ProposalConflicts int
+	// FailedTxEvents is the number of governor events of tracked contracts emitted by failed transactions, recorded
+	// for analytics if the indexer records them
+	FailedTxEvents int
+	// Contracts counts the governor events of each contract by whether they parsed. Events that fail to parse,
+	// are recorded as failed events, or are unknown event types from tracked contracts are counted as failed.
+	Contracts map[string]db.EventCounts
+}
+
+// eventEffects describes the changes made to the aggregated tables by applying an event
+type eventEffects struct {
+	proposalMutated bool
+	voteInserted    bool
+	// proposalConflict is set for a proposal_created event that conflicts with the existing proposal, which is
+	// recorded as a failed event rather than applied
+	proposalConflict bool
+}
+
+// Add adds the counts in other to the stats
+func (s *LedgerStats) Add(other LedgerStats) {
+	s.Ledgers += other.Ledgers
+	s.Transactions += other.Transactions
+	s.ContractEvents += other.ContractEvents
+	s.GovernorEvents += other.GovernorEvents
+	s.EventsApplied += other.EventsApplied
+	s.VotesInserted += other.VotesInserted
+	s.ProposalsMutated += other.ProposalsMutated
+	s.ParseFailures += other.ParseFailures
+	s.FailedEvents += other.FailedEvents
+	s.UnknownEventTypes += other.UnknownEventTypes
+	s.ProposalConflicts += other.ProposalConflicts
+	s.FailedTxEvents += other.FailedTxEvents
+	for contractId, counts := range other.Contracts {
+		s.addContractEvents(contractId, counts)
+	}
+}
+
+// countContractEvent counts a governor event of a contract, by whether it parsed
+func (s *LedgerStats) countContractEvent(contractId string, parsed bool) {
+	counts := db.EventCounts{Seen: 1, Parsed: 1}
+	if !parsed {
+		counts = db.EventCounts{Seen: 1, Failed: 1}
+	}
+	s.addContractEvents(contractId, counts)
+}
+
+func (s *LedgerStats) addContractEvents(contractId string, counts db.EventCounts) {
+	if s.Contracts == nil {
+		s.Contracts = make(map[string]db.EventCounts)
+	}
+	total := s.Contracts[contractId]
+	total.Seen += counts.Seen
+	total.Parsed += counts.Parsed
+	total.Failed += counts.Failed
+	s.Contracts[contractId] = total
+}
+
+// LogAttrs returns the stats as slog key value pairs
+func (s LedgerStats) LogAttrs() []any {
+	return []any{
+		"ledgers", s.Ledgers,
+		"txs", s.Transactions,
+		"contract_events", s.ContractEvents,
+		"governor_events", s.GovernorEvents,
+		"applied", s.EventsApplied,
+		"votes", s.VotesInserted,
+		"proposals", s.ProposalsMutated,
+		"parse_failures", s.ParseFailures,
+		"failed_events", s.FailedEvents,
+		"unknown_event_types", s.UnknownEventTypes,
+		"proposal_conflicts", s.ProposalConflicts,
+		"failed_tx_events", s.FailedTxEvents,
+	}
+}
+
+// addEffects counts an applied event, or a conflicting proposal_created event recorded as a failed event
+func (s *LedgerStats) addEffects(effects eventEffects) {
+	if effects.proposalConflict {
+		s.ProposalConflicts++
+		s.FailedEvents++
+		return
+	}
+	s.EventsApplied++
+	if effects.proposalMutated {
+		s.ProposalsMutated++
+	}
+	if effects.voteInserted {
+		s.VotesInserted++
+	}
+}
+
+// record adds the stats to the indexer metrics
+func (s LedgerStats) record() {
+	metrics.LedgersProcessed.Add(float64(s.Ledgers))
+	metrics.TransactionsRead.Add(float64(s.Transactions))
+	metrics.ContractEventsSeen.Add(float64(s.ContractEvents))
+	metrics.GovernorEventsParsed.Add(float64(s.GovernorEvents))
+	metrics.EventsApplied.Add(float64(s.EventsApplied))
+	metrics.VotesInserted.Add(float64(s.VotesInserted))
+	metrics.ProposalsMutated.Add(float64(s.ProposalsMutated))
+	metrics.ParseFailures.Add(float64(s.ParseFailures))
+	metrics.FailedEvents.Add(float64(s.FailedEvents))
+	metrics.UnknownEventTypes.Add(float64(s.UnknownEventTypes))
+	metrics.ProposalConflicts.Add(float64(s.ProposalConflicts))
+	metrics.FailedTxEvents.Add(float64(s.FailedTxEvents))
+}
@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+)
+
+var _ Store = (*db.Store)(nil)
+
+// Store is the subset of db.Store used by the indexer
+type Store interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error
+	AfterTx(ctx context.Context, fn func(ctx context.Context))
+
+	InsertEvent(ctx context.Context, event *governor.GovernorEvent) error
+	InsertEvents(ctx context.Context, events []*governor.GovernorEvent) error
+	GetEvent(ctx context.Context, eventId string) (*governor.GovernorEvent, error)
+	GetEventsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.GovernorEvent, error)
+	PruneHistory(ctx context.Context, beforeLedger uint32, batchSize int) (int64, error)
+	SetEventXdr(ctx context.Context, eventId string, eventXdr string) (bool, error)
+	GetEventsAfter(ctx context.Context, contractId string, afterEventId string, limit int) ([]*governor.GovernorEvent, error)
+	GetLastEventIds(ctx context.Context, afterLedger uint32, toLedger uint32) (map[string]string, error)
+
+	InsertFailedEvent(ctx context.Context, event *governor.FailedEvent) error
+	GetFailedEvents(ctx context.Context, filter db.FailedEventFilter, afterEventId string, limit int) ([]*governor.FailedEvent, error)
+	MarkFailedEventProcessed(ctx context.Context, eventId string, processedAt int64) error
+	RecordFailedEventAttempt(ctx context.Context, eventId string, attemptErr string) error
+
+	InsertFailedTxEvent(ctx context.Context, event *governor.FailedTxEvent) error
+
+	InsertIndexerError(ctx context.Context, indexerError *db.IndexerError, keep int) error
+
+	UpsertStatus(ctx context.Context, source string, ledgerSeq uint32, ledgerCloseTime int64) error
+	GetStatus(ctx context.Context, source string) (uint32, int64, error)
+	UpsertSourceStatus(ctx context.Context, source string, status db.SourceStatus) error
+	UpsertIngestionProgress(ctx context.Context, progress *db.IngestionProgress) error
+
+	InsertProposal(ctx context.Context, proposal *governor.Proposal) error
+	UpdateProposal(ctx context.Context, proposal *governor.Proposal, version int64) error
+	GetProposalVersion(ctx context.Context, proposalKey string) (*governor.Proposal, int64, error)
+	GetProposalsByContractId(ctx context.Context, contractId string, sort db.Sort) ([]*governor.Proposal, error)
+	DeleteProposalsByContractId(ctx context.Context, contractId string) (int64, error)
+
+	InsertVote(ctx context.Context, vote *governor.Vote) error
+	InsertVotes(ctx context.Context, votes []*governor.Vote) error
+	GetVotesByTxHashes(ctx context.Context, txHashes []string) ([]*governor.Vote, error)
+	GetVote(ctx context.Context, txHash string) (*governor.Vote, error)
+	CountVoters(ctx context.Context, contractId string, proposalId uint32) (int, error)
+	DeleteVotesByContractId(ctx context.Context, contractId string) (int64, error)
+	DeleteVotesAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error)
+
+	InsertDelegation(ctx context.Context, delegation *governor.Delegation) error
+	DeleteDelegationsByContractId(ctx context.Context, contractId string) (int64, error)
+	DeleteDelegationsAfterLedger(ctx context.Context, contractId string, ledgerSeq uint32) (int64, error)
+
+	UpsertVotesContract(ctx context.Context, governorId string, votesId string, ledgerSeq uint32) error
+	IsVotesContract(ctx context.Context, contractId string) (bool, error)
+	GetTrackedContracts(ctx context.Context) ([]string, error)
+
+	UpsertGovernorSettings(ctx context.Context, settings *governor.Settings) error
+
+	IsContractBlocked(ctx context.Context, contractId string) (bool, error)
+	GetBlockedContracts(ctx context.Context) ([]*db.BlockedContract, error)
+	UpsertContractActivity(ctx context.Context, contractId string, eventId string, ledgerSeq uint32, ledgerCloseTime int64, reviewed bool) error
+	GetContractLastEventId(ctx context.Context, contractId string) (string, error)
+	AddContractEventCounts(ctx context.Context, contractId string, counts db.EventCounts) (bool, error)
+
+	GetProposalContentToFetch(ctx context.Context, now int64, limit int) ([]*governor.ProposalContent, error)
+	UpsertProposalContent(ctx context.Context, content *governor.ProposalContent) error
+
+	GetVoteTokensToFetch(ctx context.Context, limit int) ([]*db.VoteToken, error)
+	SetVoteToken(ctx context.Context, contractId string, symbol string, decimals uint32) error
+
+	InsertSnapshot(ctx context.Context, snapshot *db.Snapshot) error
+	GetLatestSnapshot(ctx context.Context, contractId string, beforeLedger uint32) (*db.Snapshot, error)
+	PruneSnapshots(ctx context.Context, contractId string, keep int) (int64, error)
+}
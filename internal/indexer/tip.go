@@ -0,0 +1,89 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/script3/soroban-governor-backend/internal/metrics"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// tipBackend is the ledger backend of an RPC server, which waits at the tip of the network for ledgers that have not
+// closed yet. Before getting a ledger beyond the latest ledger of the server, it polls the server every pollInterval
+// until the ledger closes, and records the time spent waiting, so following the tip is not mistaken for an error or
+// for slow processing.
+//
+// A tipBackend is not safe for concurrent use.
+type tipBackend struct {
+	ledgerbackend.LedgerBackend
+	// latestLedger returns the latest ledger of the server
+	latestLedger func(ctx context.Context) (uint32, error)
+	close        func() error
+	pollInterval time.Duration
+
+	// latest is the latest ledger of the server when it was last polled
+	latest uint32
+}
+
+// newTipBackend creates the backend of the RPC server at url, polling for new ledgers every pollInterval
+func newTipBackend(url string, pollInterval time.Duration) *tipBackend {
+	client := rpcclient.NewClient(url, nil)
+	return &tipBackend{
+		LedgerBackend: ledgerbackend.NewRPCLedgerBackend(ledgerbackend.RPCLedgerBackendOptions{RPCServerURL: url}),
+		latestLedger: func(ctx context.Context) (uint32, error) {
+			latest, err := client.GetLatestLedger(ctx)
+			return latest.Sequence, err
+		},
+		close:        client.Close,
+		pollInterval: pollInterval,
+	}
+}
+
+// GetLedger waits for the ledger to close on the server, then gets it
+func (b *tipBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if err := b.waitForLedger(ctx, sequence); err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	return b.LedgerBackend.GetLedger(ctx, sequence)
+}
+
+func (b *tipBackend) Close() error {
+	err := b.LedgerBackend.Close()
+	if b.close != nil {
+		err = errors.Join(err, b.close())
+	}
+	return err
+}
+
+// waitForLedger polls the server until its latest ledger is at least sequence. The server is only polled once the
+// previously polled latest ledger has been read, so catching up doesn't poll for every ledger. Errors polling the
+// server are logged, and left for GetLedger to return if the server is unavailable.
+func (b *tipBackend) waitForLedger(ctx context.Context, sequence uint32) error {
+	if sequence <= b.latest {
+		return nil
+	}
+	start := time.Now()
+	defer func() { metrics.TipWaitSeconds.Add(time.Since(start).Seconds()) }()
+	for {
+		latest, err := b.latestLedger(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Warn("Failed to get the latest ledger of the RPC server", "ledger", sequence, "err", err)
+			return nil
+		}
+		b.latest = latest
+		if sequence <= latest {
+			return nil
+		}
+		slog.Debug("Waiting for ledger to close", "ledger", sequence, "latest", latest, "poll_interval", b.pollInterval)
+		if !sleepCtx(ctx, b.pollInterval) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+)
+
+func TestTipBackend(t *testing.T) {
+	ctx := t.Context()
+	// polls returns the latest ledger of the server on each poll
+	polls := []uint32{100, 101, 101, 102}
+	var polled []uint32
+	b := &tipBackend{
+		LedgerBackend: &fakeRPCBackend{server: &fakeRPCServer{}},
+		latestLedger: func(ctx context.Context) (uint32, error) {
+			if len(polled) == len(polls) {
+				return 0, errors.New("502 bad gateway")
+			}
+			polled = append(polled, polls[len(polled)])
+			return polled[len(polled)-1], nil
+		},
+	}
+	if err := b.PrepareRange(ctx, ledgerbackend.UnboundedRange(98)); err != nil {
+		t.Fatalf("PrepareRange() error = %v", err)
+	}
+
+	// ledgers up to the polled latest ledger are read without polling again, then the backend waits for each ledger
+	// to close
+	for seq := uint32(98); seq <= 103; seq++ {
+		ledger, err := b.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatalf("GetLedger(%d) error = %v", seq, err)
+		}
+		if ledger.LedgerSequence() != seq {
+			t.Errorf("GetLedger(%d) returned ledger %d", seq, ledger.LedgerSequence())
+		}
+		wantPolls := map[uint32]int{98: 1, 99: 1, 100: 1, 101: 2, 102: 4, 103: 4}[seq]
+		if len(polled) != wantPolls {
+			t.Errorf("polled %d times after ledger %d, want %d", len(polled), seq, wantPolls)
+		}
+	}
+	// polling errors are left to the ledger backend
+	if diff := cmp.Diff(polls, polled); diff != "" {
+		t.Errorf("polls mismatch (-want +got):\n%s", diff)
+	}
+
+	// waiting stops when ctx is cancelled
+	b.latestLedger = func(ctx context.Context) (uint32, error) { return 103, nil }
+	b.pollInterval = time.Hour
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := b.GetLedger(cancelled, 104); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetLedger() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,185 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/script3/soroban-governor-backend/internal/governor/bigmath"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/txnbuild"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+const (
+	// TOKEN_POLL_INTERVAL is how often the token fetcher checks for governors whose vote token has not been read
+	TOKEN_POLL_INTERVAL = time.Minute
+	// TOKEN_BATCH_SIZE is the maximum number of vote tokens read per poll
+	TOKEN_BATCH_SIZE = 100
+	// MAX_TOKEN_SYMBOL_BYTES is the maximum length of a vote token's symbol
+	MAX_TOKEN_SYMBOL_BYTES = 32
+	// SIMULATION_SOURCE_ACCOUNT is the source account of the transactions simulated to read vote tokens. Read-only
+	// calls don't need a funded account.
+	SIMULATION_SOURCE_ACCOUNT = "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+)
+
+// Simulator simulates transactions, as rpcclient.Client does with the RPC server's simulateTransaction method
+type Simulator interface {
+	SimulateTransaction(ctx context.Context, request protocol.SimulateTransactionRequest) (protocol.SimulateTransactionResponse, error)
+}
+
+// TokenFetcher reads the symbol and decimals of each governor's vote token from its votes contract, by simulating
+// calls to the contract's symbol and decimals functions, and stores them in the contracts registry. Each token is
+// read once. Like the ContentFetcher, it runs separately from ledger ingestion, so an unavailable RPC server never
+// delays applying events.
+type TokenFetcher struct {
+	store     Store
+	simulator Simulator
+	// retries are the failed attempts of each governor, which are retried with exponential backoff
+	retries map[string]tokenRetry
+}
+
+type tokenRetry struct {
+	attempts      int
+	nextAttemptAt time.Time
+}
+
+// NewTokenFetcher creates a TokenFetcher reading vote tokens with simulator
+func NewTokenFetcher(store Store, simulator Simulator) *TokenFetcher {
+	return &TokenFetcher{store: store, simulator: simulator, retries: make(map[string]tokenRetry)}
+}
+
+// Run reads the vote tokens not read yet every TOKEN_POLL_INTERVAL until ctx is cancelled
+func (f *TokenFetcher) Run(ctx context.Context) {
+	slog.Info("Vote token fetcher started")
+	for {
+		if _, err := f.FetchDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to fetch vote tokens", "err", err)
+		}
+		if !sleepCtx(ctx, TOKEN_POLL_INTERVAL) {
+			return
+		}
+	}
+}
+
+// FetchDue reads up to TOKEN_BATCH_SIZE vote tokens that have not been read, and returns the number read. Governors
+// whose token failed to read are retried with exponential backoff. Only store errors are returned.
+func (f *TokenFetcher) FetchDue(ctx context.Context, now time.Time) (int, error) {
+	tokens, err := f.store.GetVoteTokensToFetch(ctx, TOKEN_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	fetched := 0
+	for _, token := range tokens {
+		if ctx.Err() != nil {
+			return fetched, nil
+		}
+		retry, retrying := f.retries[token.ContractId]
+		if retrying && now.Before(retry.nextAttemptAt) {
+			continue
+		}
+		symbol, decimals, err := f.fetch(ctx, token.VotesId)
+		if err != nil {
+			retry.attempts++
+			delay := retryDelay(retry.attempts)
+			retry.nextAttemptAt = now.Add(delay)
+			f.retries[token.ContractId] = retry
+			slog.Warn("Failed to read vote token, retrying", "contract", token.ContractId, "votes", token.VotesId, "attempts", retry.attempts, "retry_in", delay, "err", err)
+			continue
+		}
+		if err := f.store.SetVoteToken(ctx, token.ContractId, symbol, decimals); err != nil {
+			return fetched, err
+		}
+		delete(f.retries, token.ContractId)
+		fetched++
+		slog.Info("Read vote token", "contract", token.ContractId, "votes", token.VotesId, "symbol", symbol, "decimals", decimals)
+	}
+	return fetched, nil
+}
+
+// fetch reads the symbol and decimals of the token implemented by the votes contract
+func (f *TokenFetcher) fetch(ctx context.Context, votesId string) (string, uint32, error) {
+	symbolVal, err := f.call(ctx, votesId, "symbol")
+	if err != nil {
+		return "", 0, err
+	}
+	symbol, ok := symbolVal.GetStr()
+	if !ok {
+		return "", 0, fmt.Errorf("symbol returned %s, want a string", symbolVal.Type)
+	}
+	if len(symbol) > MAX_TOKEN_SYMBOL_BYTES || !utf8.ValidString(string(symbol)) {
+		return "", 0, fmt.Errorf("symbol %q is not a valid symbol", symbol)
+	}
+
+	decimalsVal, err := f.call(ctx, votesId, "decimals")
+	if err != nil {
+		return "", 0, err
+	}
+	decimals, ok := decimalsVal.GetU32()
+	if !ok {
+		return "", 0, fmt.Errorf("decimals returned %s, want a u32", decimalsVal.Type)
+	}
+	if decimals > bigmath.MAX_DECIMALS {
+		return "", 0, fmt.Errorf("%d decimals is over the maximum of %d", decimals, bigmath.MAX_DECIMALS)
+	}
+	return string(symbol), uint32(decimals), nil
+}
+
+// call simulates calling a function without arguments on a contract, and returns its result
+func (f *TokenFetcher) call(ctx context.Context, contractId string, function string) (xdr.ScVal, error) {
+	envelope, err := newInvokeEnvelope(contractId, function)
+	if err != nil {
+		return xdr.ScVal{}, err
+	}
+	resp, err := f.simulator.SimulateTransaction(ctx, protocol.SimulateTransactionRequest{Transaction: envelope})
+	if err != nil {
+		return xdr.ScVal{}, fmt.Errorf("failed to simulate %s: %w", function, err)
+	}
+	if resp.Error != "" {
+		return xdr.ScVal{}, fmt.Errorf("simulating %s failed: %s", function, resp.Error)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ReturnValueXDR == nil {
+		return xdr.ScVal{}, errors.New("simulating " + function + " returned no result")
+	}
+	var val xdr.ScVal
+	if err := xdr.SafeUnmarshalBase64(*resp.Results[0].ReturnValueXDR, &val); err != nil {
+		return xdr.ScVal{}, fmt.Errorf("failed to decode %s result: %w", function, err)
+	}
+	return val, nil
+}
+
+// newInvokeEnvelope returns an unsigned transaction envelope, as base64 XDR, calling a function without arguments on
+// a contract
+func newInvokeEnvelope(contractId string, function string) (string, error) {
+	raw, err := strkey.Decode(strkey.VersionByteContract, contractId)
+	if err != nil {
+		return "", fmt.Errorf("invalid contract id %s: %w", contractId, err)
+	}
+	id := xdr.ContractId(raw)
+	source := txnbuild.NewSimpleAccount(SIMULATION_SOURCE_ACCOUNT, 0)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &source,
+		IncrementSequenceNum: true,
+		BaseFee:              txnbuild.MinBaseFee,
+		Preconditions:        txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		Operations: []txnbuild.Operation{&txnbuild.InvokeHostFunction{
+			HostFunction: xdr.HostFunction{
+				Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+				InvokeContract: &xdr.InvokeContractArgs{
+					ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id},
+					FunctionName:    xdr.ScSymbol(function),
+					Args:            []xdr.ScVal{},
+				},
+			},
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build %s transaction: %w", function, err)
+	}
+	return tx.Base64()
+}
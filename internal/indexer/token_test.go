@@ -0,0 +1,137 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// fakeSimulator returns the results of functions by the contract they are called on and the function name
+type fakeSimulator struct {
+	results map[string]map[string]xdr.ScVal
+	calls   int
+}
+
+func (s *fakeSimulator) SimulateTransaction(ctx context.Context, request protocol.SimulateTransactionRequest) (protocol.SimulateTransactionResponse, error) {
+	s.calls++
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(request.Transaction, &envelope); err != nil {
+		return protocol.SimulateTransactionResponse{}, err
+	}
+	invoke := envelope.Operations()[0].Body.MustInvokeHostFunctionOp().HostFunction.MustInvokeContract()
+	contractId, err := strkey.Encode(strkey.VersionByteContract, invoke.ContractAddress.ContractId[:])
+	if err != nil {
+		return protocol.SimulateTransactionResponse{}, err
+	}
+	result, ok := s.results[contractId][string(invoke.FunctionName)]
+	if !ok {
+		return protocol.SimulateTransactionResponse{Error: "HostError: Error(WasmVm, MissingValue)"}, nil
+	}
+	resultXdr, err := xdr.MarshalBase64(result)
+	if err != nil {
+		return protocol.SimulateTransactionResponse{}, err
+	}
+	return protocol.SimulateTransactionResponse{Results: []protocol.SimulateHostFunctionResult{{ReturnValueXDR: &resultXdr}}}, nil
+}
+
+func TestTokenFetcherFetchDue(t *testing.T) {
+	votesId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	notTokenId := "CCYQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAADQ6"
+	symbol := xdr.ScString("BLND")
+	decimals := xdr.Uint32(7)
+	simulator := &fakeSimulator{results: map[string]map[string]xdr.ScVal{
+		votesId: {
+			"symbol":   {Type: xdr.ScValTypeScvString, Str: &symbol},
+			"decimals": {Type: xdr.ScValTypeScvU32, U32: &decimals},
+		},
+	}}
+	type set struct {
+		contractId string
+		symbol     string
+		decimals   uint32
+	}
+	var got []set
+	store := &mockStore{
+		getVoteTokensToFetch: func(ctx context.Context, limit int) ([]*db.VoteToken, error) {
+			return []*db.VoteToken{
+				{ContractId: testContractId, VotesId: votesId},
+				{ContractId: "CAQYMBQNMIXB6XKVRUT4FIVSRZWAHU4BBKF4W3O6V3XUGUYCAXNZJFKG", VotesId: notTokenId},
+			}, nil
+		},
+		setVoteToken: func(ctx context.Context, contractId string, symbol string, decimals uint32) error {
+			got = append(got, set{contractId, symbol, decimals})
+			return nil
+		},
+	}
+	fetcher := NewTokenFetcher(store, simulator)
+
+	now := time.Unix(1761053046, 0)
+	fetched, err := fetcher.FetchDue(t.Context(), now)
+	if err != nil {
+		t.Fatalf("FetchDue() error = %v", err)
+	}
+	if fetched != 1 {
+		t.Errorf("FetchDue() = %d, want 1", fetched)
+	}
+	if diff := cmp.Diff([]set{{testContractId, "BLND", 7}}, got, cmp.AllowUnexported(set{})); diff != "" {
+		t.Errorf("set vote tokens mismatch (-want +got):\n%s", diff)
+	}
+
+	// the contract that isn't a token is retried after a backoff
+	calls := simulator.calls
+	if _, err := fetcher.FetchDue(t.Context(), now.Add(time.Second)); err != nil {
+		t.Fatalf("FetchDue() error = %v", err)
+	}
+	if simulator.calls != calls+2 {
+		t.Errorf("simulated %d calls before the retry is due, want the 2 of the fetched token", simulator.calls-calls)
+	}
+	calls = simulator.calls
+	if _, err := fetcher.FetchDue(t.Context(), now.Add(CONTENT_RETRY_BASE_DELAY)); err != nil {
+		t.Fatalf("FetchDue() error = %v", err)
+	}
+	if simulator.calls != calls+3 {
+		t.Errorf("simulated %d calls once the retry is due, want 3", simulator.calls-calls)
+	}
+
+	store.setVoteToken = func(ctx context.Context, contractId string, symbol string, decimals uint32) error {
+		return db.ErrTimeout
+	}
+	if _, err := fetcher.FetchDue(t.Context(), now); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("FetchDue() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestTokenFetcherInvalidToken(t *testing.T) {
+	votesId := "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC"
+	symbol := xdr.ScString("BLND")
+	tests := []struct {
+		name     string
+		decimals xdr.ScVal
+	}{
+		{name: "decimals not a u32", decimals: xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &symbol}},
+		{name: "too many decimals", decimals: xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: func() *xdr.Uint32 { d := xdr.Uint32(39); return &d }()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simulator := &fakeSimulator{results: map[string]map[string]xdr.ScVal{
+				votesId: {"symbol": {Type: xdr.ScValTypeScvString, Str: &symbol}, "decimals": tt.decimals},
+			}}
+			store := &mockStore{
+				getVoteTokensToFetch: func(ctx context.Context, limit int) ([]*db.VoteToken, error) {
+					return []*db.VoteToken{{ContractId: testContractId, VotesId: votesId}}, nil
+				},
+			}
+			fetched, err := NewTokenFetcher(store, simulator).FetchDue(t.Context(), time.Now())
+			if err != nil || fetched != 0 {
+				t.Errorf("FetchDue() = %d, %v, want 0 fetched without error", fetched, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// trackedContracts is an in-memory copy of the contracts the indexer tracks, so events from any contract on the
+// network can be checked without a database read. It is loaded once, and contracts are added as the indexer starts
+// tracking them. Contracts are never removed, so a contract whose data is deleted stays tracked until the indexer
+// restarts, which at worst records a few extra failed events. A trackedContracts is safe for concurrent use.
+type trackedContracts struct {
+	mu sync.RWMutex
+	// contracts are the raw ids of the tracked contracts, so contract events can be checked without encoding their id.
+	// It is nil until loaded.
+	contracts map[xdr.ContractId]bool
+}
+
+// load loads the tracked contracts from store, if they are not loaded yet
+func (t *trackedContracts) load(ctx context.Context, store Store) error {
+	t.mu.RLock()
+	loaded := t.contracts != nil
+	t.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	tracked, err := store.GetTrackedContracts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load tracked contracts: %w", err)
+	}
+	contracts := make(map[xdr.ContractId]bool, len(tracked))
+	for _, contractId := range tracked {
+		raw, err := strkey.Decode(strkey.VersionByteContract, contractId)
+		if err != nil {
+			slog.Warn("Ignoring invalid tracked contract id", "contract", contractId, "err", err)
+			continue
+		}
+		contracts[xdr.ContractId(raw)] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.contracts == nil {
+		t.contracts = contracts
+	}
+	return nil
+}
+
+// add tracks a contract. It is ignored until the tracked contracts are loaded, as they are then read from the store.
+func (t *trackedContracts) add(contractId string) {
+	raw, err := strkey.Decode(strkey.VersionByteContract, contractId)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.contracts != nil {
+		t.contracts[xdr.ContractId(raw)] = true
+	}
+}
+
+// contains returns true if the contract is tracked
+func (t *trackedContracts) contains(contractId string) bool {
+	raw, err := strkey.Decode(strkey.VersionByteContract, contractId)
+	if err != nil {
+		return false
+	}
+	return t.containsId(xdr.ContractId(raw))
+}
+
+// containsEvent returns true if the contract that emitted event is tracked
+func (t *trackedContracts) containsEvent(event *xdr.ContractEvent) bool {
+	return event.ContractId != nil && t.containsId(*event.ContractId)
+}
+
+func (t *trackedContracts) containsId(contractId xdr.ContractId) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.contracts[contractId]
+}
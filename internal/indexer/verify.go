@@ -0,0 +1,202 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/clients/rpcclient"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// VERIFY_MAX_KEYS is the maximum number of ledger keys requested in a single getLedgerEntries request
+const VERIFY_MAX_KEYS = 200
+
+// Mismatch is a difference between an indexed proposal and the governor's storage
+type Mismatch struct {
+	ProposalId uint32 `json:"proposal_id"`
+	// Field is the proposal field that differs, or "entry" if the proposal's data entry is missing
+	Field   string `json:"field"`
+	Indexed string `json:"indexed"`
+	OnChain string `json:"on_chain"`
+}
+
+// VerifyReport is the result of VerifyContract
+type VerifyReport struct {
+	ContractId string `json:"contract_id"`
+	// IndexedLedger is the last ledger processed by the indexer
+	IndexedLedger uint32 `json:"indexed_ledger"`
+	// LatestLedger is the ledger the RPC server read the storage at, or 0 if there were no proposals to check
+	LatestLedger uint32 `json:"latest_ledger"`
+	Checked      int    `json:"checked"`
+	// Skipped are the proposals changed on-chain after the indexed ledger, which can't be compared yet
+	Skipped    []uint32   `json:"skipped"`
+	Mismatches []Mismatch `json:"mismatches"`
+	// MissedLedgers are the ledgers, up to the indexed ledger, in which a mismatched proposal was last changed
+	// on-chain after its last indexed change, so an event in them was likely not indexed
+	MissedLedgers []uint32 `json:"missed_ledgers"`
+}
+
+// CoverageGaps returns the missed ledgers as coverage gaps, to be recorded with RecordCoverageGaps
+func (r *VerifyReport) CoverageGaps() []*db.CoverageGap {
+	gaps := make([]*db.CoverageGap, len(r.MissedLedgers))
+	for i, ledger := range r.MissedLedgers {
+		gaps[i] = &db.CoverageGap{FromLedger: ledger, ToLedger: ledger, Reason: GAP_REASON_VERIFY}
+	}
+	return gaps
+}
+
+// proposalEntry is the storage of a proposal read from the RPC server
+type proposalEntry struct {
+	data      *governor.ProposalData
+	voteCount *governor.VoteCount
+	// lastModified is the last ledger either entry was modified in
+	lastModified uint32
+}
+
+// VerifyContract compares the status, execution unlock, and vote tallies of a contract's indexed proposals against
+// the governor's storage, read from the RPC server at rpcURL with getLedgerEntries. Only open and successful
+// proposals are checked, unless all is set, as the storage of finished proposals may have been archived.
+//
+// Proposals changed on-chain after the last ledger the indexer processed are skipped, as the indexer hasn't seen
+// the change yet. An error is only returned if the verification could not run; differences are reported as
+// mismatches, and the ledgers of on-chain changes the indexer has no event for as missed ledgers.
+func VerifyContract(ctx context.Context, store *db.Store, rpcURL string, contractId string, all bool) (*VerifyReport, error) {
+	indexedLedger, _, err := store.GetStatus(ctx, STATUS_SOURCE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexer status: %w", err)
+	}
+	proposals, err := store.GetProposalsByContractId(ctx, contractId, db.Sort{})
+	if err != nil {
+		return nil, err
+	}
+	var toCheck []*governor.Proposal
+	for _, proposal := range proposals {
+		if all || proposal.Status == 0 || proposal.Status == 1 {
+			toCheck = append(toCheck, proposal)
+		}
+	}
+
+	client := rpcclient.NewClient(rpcURL, nil)
+	defer client.Close()
+	entries, latestLedger, err := getProposalEntries(ctx, client, contractId, toCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{ContractId: contractId, IndexedLedger: indexedLedger, LatestLedger: latestLedger, Skipped: []uint32{}, Mismatches: []Mismatch{}, MissedLedgers: []uint32{}}
+	for _, proposal := range toCheck {
+		entry := entries[proposal.ProposalId]
+		if entry != nil && entry.lastModified > indexedLedger {
+			report.Skipped = append(report.Skipped, proposal.ProposalId)
+			continue
+		}
+		report.Checked++
+		mismatches := compareProposal(proposal, entry)
+		report.Mismatches = append(report.Mismatches, mismatches...)
+		if len(mismatches) > 0 && entry != nil && entry.lastModified > proposal.UpdatedLedger && !slices.Contains(report.MissedLedgers, entry.lastModified) {
+			report.MissedLedgers = append(report.MissedLedgers, entry.lastModified)
+		}
+	}
+	slices.Sort(report.MissedLedgers)
+	return report, nil
+}
+
+// compareProposal returns the differences between an indexed proposal and its storage
+func compareProposal(proposal *governor.Proposal, entry *proposalEntry) []Mismatch {
+	if entry == nil || entry.data == nil {
+		return []Mismatch{{ProposalId: proposal.ProposalId, Field: "entry", Indexed: "present", OnChain: "missing"}}
+	}
+	// the vote count is only written once a vote is cast
+	voteCount := entry.voteCount
+	if voteCount == nil {
+		voteCount = &governor.VoteCount{For: "0", Against: "0", Abstain: "0"}
+	}
+	var mismatches []Mismatch
+	for _, field := range []struct{ name, indexed, onChain string }{
+		{"status", strconv.FormatUint(uint64(proposal.Status), 10), strconv.FormatUint(uint64(entry.data.Status), 10)},
+		{"execution_unlock", strconv.FormatUint(uint64(proposal.ExecutionUnlock), 10), strconv.FormatUint(uint64(entry.data.Eta), 10)},
+		{"votes_for", proposal.VotesFor, voteCount.For},
+		{"votes_against", proposal.VotesAgainst, voteCount.Against},
+		{"votes_abstain", proposal.VotesAbstain, voteCount.Abstain},
+	} {
+		if field.indexed != field.onChain {
+			mismatches = append(mismatches, Mismatch{ProposalId: proposal.ProposalId, Field: field.name, Indexed: field.indexed, OnChain: field.onChain})
+		}
+	}
+	return mismatches
+}
+
+// getProposalEntries reads the data and vote count entries of proposals, by proposal id, and returns them with the
+// latest ledger reported by the RPC server. Both durabilities are requested, so the storage layout of the governor
+// release doesn't matter. Proposals without entries are not in the map.
+func getProposalEntries(ctx context.Context, client *rpcclient.Client, contractId string, proposals []*governor.Proposal) (map[uint32]*proposalEntry, uint32, error) {
+	type keyRef struct {
+		proposalId uint32
+		variant    string
+	}
+	var keys []string
+	refs := make(map[string]keyRef)
+	for _, proposal := range proposals {
+		for _, variant := range []string{governor.PROPOSAL_DATA_KEY, governor.PROPOSAL_VOTE_COUNT_KEY} {
+			for _, durability := range []xdr.ContractDataDurability{xdr.ContractDataDurabilityPersistent, xdr.ContractDataDurabilityTemporary} {
+				key, err := governor.ProposalStorageKey(contractId, variant, proposal.ProposalId, durability)
+				if err != nil {
+					return nil, 0, err
+				}
+				keyXdr, err := xdr.MarshalBase64(key)
+				if err != nil {
+					return nil, 0, fmt.Errorf("failed to encode ledger key: %w", err)
+				}
+				keys = append(keys, keyXdr)
+				refs[keyXdr] = keyRef{proposalId: proposal.ProposalId, variant: variant}
+			}
+		}
+	}
+
+	entries := make(map[uint32]*proposalEntry)
+	var latestLedger uint32
+	for start := 0; start < len(keys); start += VERIFY_MAX_KEYS {
+		batch := keys[start:min(start+VERIFY_MAX_KEYS, len(keys))]
+		resp, err := client.GetLedgerEntries(ctx, protocol.GetLedgerEntriesRequest{Keys: batch})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get ledger entries: %w", err)
+		}
+		latestLedger = max(latestLedger, resp.LatestLedger)
+		for _, result := range resp.Entries {
+			ref, ok := refs[result.KeyXDR]
+			if !ok {
+				return nil, 0, fmt.Errorf("RPC server returned an entry for an unrequested key %s", result.KeyXDR)
+			}
+			var data xdr.LedgerEntryData
+			if err := xdr.SafeUnmarshalBase64(result.DataXDR, &data); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode ledger entry of proposal %d: %w", ref.proposalId, err)
+			}
+			contractData, ok := data.GetContractData()
+			if !ok {
+				return nil, 0, fmt.Errorf("ledger entry of proposal %d is not contract data", ref.proposalId)
+			}
+
+			entry := entries[ref.proposalId]
+			if entry == nil {
+				entry = &proposalEntry{}
+				entries[ref.proposalId] = entry
+			}
+			entry.lastModified = max(entry.lastModified, result.LastModifiedLedger)
+			switch ref.variant {
+			case governor.PROPOSAL_DATA_KEY:
+				entry.data, err = governor.NewProposalDataFromXDR(contractData.Val)
+			case governor.PROPOSAL_VOTE_COUNT_KEY:
+				entry.voteCount, err = governor.NewVoteCountFromXDR(contractData.Val)
+			}
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse %s of proposal %d: %w", ref.variant, ref.proposalId, err)
+			}
+		}
+	}
+	return entries, latestLedger, nil
+}
@@ -0,0 +1,173 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/script3/soroban-governor-backend/internal/db"
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// storageEntry is a contract data entry served by newStorageServer
+type storageEntry struct {
+	val          xdr.ScVal
+	lastModified uint32
+}
+
+// storageKey identifies a proposal's storage entry in newStorageServer
+func storageKey(variant string, proposalId uint32, durability xdr.ContractDataDurability) string {
+	return fmt.Sprintf("%s-%d-%d", variant, proposalId, durability)
+}
+
+// newStorageServer returns an RPC server whose getLedgerEntries serves the governor storage in entries
+func newStorageServer(t *testing.T, latestLedger uint32, entries map[string]storageEntry) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Keys []string `json:"keys"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getLedgerEntries" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results := []map[string]any{}
+		for _, keyXdr := range req.Params.Keys {
+			var key xdr.LedgerKey
+			if err := xdr.SafeUnmarshalBase64(keyXdr, &key); err != nil {
+				t.Errorf("invalid ledger key %s: %v", keyXdr, err)
+				continue
+			}
+			vec := **key.ContractData.Key.Vec
+			entry, ok := entries[storageKey(string(*vec[0].Sym), uint32(*vec[1].U32), key.ContractData.Durability)]
+			if !ok {
+				continue
+			}
+			data, err := xdr.MarshalBase64(xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeContractData,
+				ContractData: &xdr.ContractDataEntry{
+					Contract:   key.ContractData.Contract,
+					Key:        key.ContractData.Key,
+					Durability: key.ContractData.Durability,
+					Val:        entry.val,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, map[string]any{"key": keyXdr, "xdr": data, "lastModifiedLedgerSeq": entry.lastModified})
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.Id,
+			"result":  map[string]any{"entries": results, "latestLedger": latestLedger},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func scMap(entries map[string]xdr.ScVal) xdr.ScVal {
+	m := &xdr.ScMap{}
+	for key, val := range entries {
+		sym := xdr.ScSymbol(key)
+		*m = append(*m, xdr.ScMapEntry{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}, Val: val})
+	}
+	return xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &m}
+}
+
+func scU32(v uint32) xdr.ScVal {
+	u32 := xdr.Uint32(v)
+	return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &u32}
+}
+
+func scI128(v uint64) xdr.ScVal {
+	return xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Lo: xdr.Uint64(v)}}
+}
+
+func proposalDataVal(status uint32, eta uint32) xdr.ScVal {
+	return scMap(map[string]xdr.ScVal{"status": scU32(status), "eta": scU32(eta), "vote_start": scU32(1)})
+}
+
+func voteCountVal(votesFor uint64, against uint64, abstain uint64) xdr.ScVal {
+	return scMap(map[string]xdr.ScVal{"_for": scI128(votesFor), "against": scI128(against), "abstain": scI128(abstain)})
+}
+
+func TestVerifyContract(t *testing.T) {
+	ctx := t.Context()
+	store := setupStore(t, ctx)
+	if err := store.UpsertStatus(ctx, STATUS_SOURCE, ledgerSeq, ledgerCloseTime); err != nil {
+		t.Fatal(err)
+	}
+
+	persistent, temporary := xdr.ContractDataDurabilityPersistent, xdr.ContractDataDurabilityTemporary
+	rpc := newStorageServer(t, ledgerSeq+10, map[string]storageEntry{
+		// open, with a vote the indexer missed
+		storageKey(governor.PROPOSAL_DATA_KEY, 3, persistent):       {val: proposalDataVal(0, 0), lastModified: ledgerSeq - 10000},
+		storageKey(governor.PROPOSAL_VOTE_COUNT_KEY, 3, persistent): {val: voteCountVal(12314122341234, 1234123412435, 1923114243), lastModified: ledgerSeq - 100},
+		// executed after the indexed ledger
+		storageKey(governor.PROPOSAL_DATA_KEY, 1, persistent):       {val: proposalDataVal(4, ledgerSeq-1000), lastModified: ledgerSeq + 5},
+		storageKey(governor.PROPOSAL_VOTE_COUNT_KEY, 1, persistent): {val: voteCountVal(0, 0, 0), lastModified: ledgerSeq - 30000},
+		// executed, in temporary storage and without votes
+		storageKey(governor.PROPOSAL_DATA_KEY, 0, temporary): {val: proposalDataVal(4, ledgerSeq-10000), lastModified: ledgerSeq - 9000},
+	})
+
+	tests := []struct {
+		name string
+		all  bool
+		want *VerifyReport
+	}{
+		{
+			name: "non-terminal proposals",
+			want: &VerifyReport{
+				ContractId: testContractId, IndexedLedger: ledgerSeq, LatestLedger: ledgerSeq + 10, Checked: 1, Skipped: []uint32{1},
+				Mismatches:    []Mismatch{{ProposalId: 3, Field: "votes_against", Indexed: "1234123412434", OnChain: "1234123412435"}},
+				MissedLedgers: []uint32{ledgerSeq - 100},
+			},
+		},
+		{
+			name: "all proposals",
+			all:  true,
+			want: &VerifyReport{
+				ContractId: testContractId, IndexedLedger: ledgerSeq, LatestLedger: ledgerSeq + 10, Checked: 3, Skipped: []uint32{1},
+				Mismatches: []Mismatch{
+					{ProposalId: 3, Field: "votes_against", Indexed: "1234123412434", OnChain: "1234123412435"},
+					{ProposalId: 2, Field: "entry", Indexed: "present", OnChain: "missing"},
+					{ProposalId: 0, Field: "votes_for", Indexed: "123141223412", OnChain: "0"},
+					{ProposalId: 0, Field: "votes_against", Indexed: "984723948572235", OnChain: "0"},
+					{ProposalId: 0, Field: "votes_abstain", Indexed: "594114243", OnChain: "0"},
+				},
+				MissedLedgers: []uint32{ledgerSeq - 9000, ledgerSeq - 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyContract(ctx, store, rpc.URL, testContractId, tt.all)
+			if err != nil {
+				t.Fatalf("VerifyContract() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("report mismatch (-want +got):\n%s", diff)
+			}
+			gaps := got.CoverageGaps()
+			if len(gaps) != len(tt.want.MissedLedgers) {
+				t.Fatalf("CoverageGaps() = %d gaps, want %d", len(gaps), len(tt.want.MissedLedgers))
+			}
+			for i, gap := range gaps {
+				want := &db.CoverageGap{FromLedger: tt.want.MissedLedgers[i], ToLedger: tt.want.MissedLedgers[i], Reason: GAP_REASON_VERIFY}
+				if diff := cmp.Diff(want, gap); diff != "" {
+					t.Errorf("CoverageGaps() mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/script3/soroban-governor-backend/internal/governor"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// LedgerEntryReader reads ledger entries, as rpcclient.Client does with the RPC server's getLedgerEntries method
+type LedgerEntryReader interface {
+	GetLedgerEntries(ctx context.Context, request protocol.GetLedgerEntriesRequest) (protocol.GetLedgerEntriesResponse, error)
+}
+
+// RebuildVotesContract reads a governor's votes contract from its contract instance and records it, so delegations
+// are indexed again after the governor's data was deleted. Votes contracts are otherwise only recorded when the
+// governor writes its instance in a transaction that emits one of its events. It returns the votes contract, or ""
+// if the contract has no instance with a votes contract, such as a contract that is not a governor.
+func (idx *Indexer) RebuildVotesContract(ctx context.Context, reader LedgerEntryReader, governorId string) (string, error) {
+	raw, err := strkey.Decode(strkey.VersionByteContract, governorId)
+	if err != nil {
+		return "", fmt.Errorf("invalid contract id %s: %w", governorId, err)
+	}
+	contractId := xdr.ContractId(raw)
+	key := xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractId},
+			Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+			Durability: xdr.ContractDataDurabilityPersistent,
+		},
+	}
+	keyXdr, err := xdr.MarshalBase64(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ledger key: %w", err)
+	}
+
+	resp, err := reader.GetLedgerEntries(ctx, protocol.GetLedgerEntriesRequest{Keys: []string{keyXdr}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	for _, result := range resp.Entries {
+		var data xdr.LedgerEntryData
+		if err := xdr.SafeUnmarshalBase64(result.DataXDR, &data); err != nil {
+			return "", fmt.Errorf("failed to decode instance of %s: %w", governorId, err)
+		}
+		instanceGovernorId, votesId, ok := governor.VotesContractFromLedgerEntry(&xdr.LedgerEntry{Data: data})
+		if !ok || instanceGovernorId != governorId {
+			continue
+		}
+		if err := idx.store.UpsertVotesContract(ctx, governorId, votesId, result.LastModifiedLedger); err != nil {
+			return "", fmt.Errorf("failed recording votes contract for %s: %w", governorId, err)
+		}
+		idx.tracked.add(governorId)
+		idx.tracked.add(votesId)
+		return votesId, nil
+	}
+	return "", nil
+}
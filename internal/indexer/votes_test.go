@@ -0,0 +1,89 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	protocol "github.com/stellar/go-stellar-sdk/protocols/rpc"
+	"github.com/stellar/go-stellar-sdk/strkey"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// fakeLedgerEntryReader returns entries by the base64 encoded key
+type fakeLedgerEntryReader struct {
+	entries map[string]protocol.LedgerEntryResult
+}
+
+func (r *fakeLedgerEntryReader) GetLedgerEntries(ctx context.Context, request protocol.GetLedgerEntriesRequest) (protocol.GetLedgerEntriesResponse, error) {
+	var resp protocol.GetLedgerEntriesResponse
+	for _, key := range request.Keys {
+		if entry, ok := r.entries[key]; ok {
+			resp.Entries = append(resp.Entries, entry)
+		}
+	}
+	return resp, nil
+}
+
+func TestRebuildVotesContract(t *testing.T) {
+	governorRaw := xdr.ContractId{0xa0}
+	votesRaw := xdr.ContractId{0xa1}
+	governorId := strkey.MustEncode(strkey.VersionByteContract, governorRaw[:])
+	votesId := strkey.MustEncode(strkey.VersionByteContract, votesRaw[:])
+
+	instance := newGovernorInstanceChanges(governorRaw, votesRaw)[0].Created
+	keyXdr, err := xdr.MarshalBase64(xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   instance.Data.ContractData.Contract,
+			Key:        instance.Data.ContractData.Key,
+			Durability: instance.Data.ContractData.Durability,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataXdr, err := xdr.MarshalBase64(instance.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := &fakeLedgerEntryReader{entries: map[string]protocol.LedgerEntryResult{
+		keyXdr: {KeyXDR: keyXdr, DataXDR: dataXdr, LastModifiedLedger: 1170000},
+	}}
+
+	type upsert struct {
+		governorId string
+		votesId    string
+		ledgerSeq  uint32
+	}
+	var got []upsert
+	store := &mockStore{
+		upsertVotesContract: func(ctx context.Context, governorId string, votesId string, ledgerSeq uint32) error {
+			got = append(got, upsert{governorId, votesId, ledgerSeq})
+			return nil
+		},
+	}
+	idx := NewIndexer(store)
+
+	rebuilt, err := idx.RebuildVotesContract(t.Context(), reader, governorId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt != votesId {
+		t.Errorf("got votes contract %s, want %s", rebuilt, votesId)
+	}
+	want := []upsert{{governorId, votesId, 1170000}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(upsert{})); diff != "" {
+		t.Errorf("upserts mismatch (-want +got):\n%s", diff)
+	}
+
+	// a contract without an instance, such as the votes contract itself in this fake, records nothing
+	got = nil
+	rebuilt, err = idx.RebuildVotesContract(t.Context(), reader, votesId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt != "" || len(got) != 0 {
+		t.Errorf("got votes contract %q and upserts %v for a contract without an instance, want none", rebuilt, got)
+	}
+}
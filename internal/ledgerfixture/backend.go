@@ -0,0 +1,108 @@
+package ledgerfixture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+// ErrMissingLedger is returned when a ledger has no fixture
+var ErrMissingLedger = errors.New("no fixture for ledger")
+
+// Backend is a ledgerbackend.LedgerBackend that serves ledgers from fixtures.
+//
+// Fixtures are usually sparse, so ranges are not required to be complete. Requesting a ledger without a fixture
+// returns ErrMissingLedger.
+type Backend struct {
+	ledgers  map[uint32]xdr.LedgerCloseMeta
+	prepared *ledgerbackend.Range
+}
+
+var _ ledgerbackend.LedgerBackend = (*Backend)(nil)
+
+// NewBackend creates a backend serving every fixture in dir
+func NewBackend(dir string) (*Backend, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+FILE_EXT))
+	if err != nil {
+		return nil, fmt.Errorf("list fixtures in %s: %w", dir, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	backend := &Backend{ledgers: make(map[uint32]xdr.LedgerCloseMeta, len(paths))}
+	for _, path := range paths {
+		ledger, err := Read(path)
+		if err != nil {
+			return nil, err
+		}
+		backend.ledgers[ledger.LedgerSequence()] = ledger
+	}
+	return backend, nil
+}
+
+// Sequences returns the sequence of every ledger with a fixture, in ascending order
+func (b *Backend) Sequences() []uint32 {
+	seqs := make([]uint32, 0, len(b.ledgers))
+	for seq := range b.ledgers {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs
+}
+
+// GetLatestLedgerSequence returns the sequence of the last ledger with a fixture
+func (b *Backend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	seqs := b.Sequences()
+	if len(seqs) == 0 {
+		return 0, fmt.Errorf("backend is closed")
+	}
+	return seqs[len(seqs)-1], nil
+}
+
+// GetLedger returns the fixture for a ledger in the prepared range
+func (b *Backend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if b.prepared == nil {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("session is not prepared, call PrepareRange first")
+	}
+	if sequence < b.prepared.From() || (b.prepared.Bounded() && sequence > b.prepared.To()) {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("ledger %d is outside the prepared range %s", sequence, b.prepared)
+	}
+	if err := ctx.Err(); err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	ledger, ok := b.ledgers[sequence]
+	if !ok {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("ledger %d: %w", sequence, ErrMissingLedger)
+	}
+	return ledger, nil
+}
+
+// PrepareRange prepares the backend to serve ledgers in the range
+func (b *Backend) PrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	b.prepared = &ledgerRange
+	return nil
+}
+
+// IsPrepared returns true if the range is within the prepared range
+func (b *Backend) IsPrepared(ctx context.Context, ledgerRange ledgerbackend.Range) (bool, error) {
+	if b.prepared == nil || ledgerRange.From() < b.prepared.From() {
+		return false, nil
+	}
+	if !b.prepared.Bounded() {
+		return true, nil
+	}
+	return ledgerRange.Bounded() && ledgerRange.To() <= b.prepared.To(), nil
+}
+
+// Close releases the fixtures
+func (b *Backend) Close() error {
+	b.ledgers = nil
+	b.prepared = nil
+	return nil
+}
@@ -0,0 +1,77 @@
+package ledgerfixture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stellar/go-stellar-sdk/ingest/ledgerbackend"
+	"github.com/stellar/go-stellar-sdk/xdr"
+)
+
+func newLedger(seq uint32, closeTime int64) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V: 0,
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: xdr.Uint32(seq),
+					ScpValue:  xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime)},
+				},
+			},
+		},
+	}
+}
+
+func TestBackend(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	for _, seq := range []uint32{12, 10, 15} {
+		if err := Write(dir, newLedger(seq, int64(seq)*5)); err != nil {
+			t.Fatalf("Write(%d) error = %v", seq, err)
+		}
+	}
+
+	backend, err := NewBackend(dir)
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	if diff := cmp.Diff([]uint32{10, 12, 15}, backend.Sequences()); diff != "" {
+		t.Errorf("Sequences() mismatch (-want +got):\n%s", diff)
+	}
+	latest, err := backend.GetLatestLedgerSequence(ctx)
+	if err != nil || latest != 15 {
+		t.Errorf("GetLatestLedgerSequence() = %d, %v, want 15", latest, err)
+	}
+
+	if _, err := backend.GetLedger(ctx, 10); err == nil {
+		t.Errorf("GetLedger() before PrepareRange returned no error")
+	}
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(10, 12)); err != nil {
+		t.Fatalf("PrepareRange() error = %v", err)
+	}
+	prepared, err := backend.IsPrepared(ctx, ledgerbackend.BoundedRange(11, 12))
+	if err != nil || !prepared {
+		t.Errorf("IsPrepared() = %v, %v, want true", prepared, err)
+	}
+
+	ledger, err := backend.GetLedger(ctx, 12)
+	if err != nil {
+		t.Fatalf("GetLedger(12) error = %v", err)
+	}
+	if ledger.LedgerSequence() != 12 || ledger.LedgerCloseTime() != 60 {
+		t.Errorf("GetLedger(12) = ledger %d closed at %d, want ledger 12 closed at 60", ledger.LedgerSequence(), ledger.LedgerCloseTime())
+	}
+	if _, err := backend.GetLedger(ctx, 11); !errors.Is(err, ErrMissingLedger) {
+		t.Errorf("GetLedger(11) error = %v, want %v", err, ErrMissingLedger)
+	}
+	if _, err := backend.GetLedger(ctx, 15); err == nil || errors.Is(err, ErrMissingLedger) {
+		t.Errorf("GetLedger(15) outside the prepared range error = %v", err)
+	}
+}
+
+func TestNewBackendEmpty(t *testing.T) {
+	if _, err := NewBackend(t.TempDir()); err == nil {
+		t.Errorf("NewBackend() with no fixtures returned no error")
+	}
+}
@@ -0,0 +1,8 @@
+package metrics
+
+const apiSubsystem = "api"
+
+// API request metrics
+var (
+	HandlerPanics = newCounter(apiSubsystem, "handler_panics_total", "Number of API requests whose handler panicked.")
+)
@@ -0,0 +1,17 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const dbSubsystem = "db"
+
+// Proposal cache metrics, updated by proposal reads outside of a transaction while the cache is enabled
+var (
+	ProposalCacheHits   = newCounter(dbSubsystem, "proposal_cache_hits_total", "Number of proposal reads served from the cache.")
+	ProposalCacheMisses = newCounter(dbSubsystem, "proposal_cache_misses_total", "Number of proposal reads not found in the cache.")
+)
+
+// Store query metrics, labeled by the name of the query, which is named after the store method running it
+var (
+	QueryDuration = newHistogramVec(dbSubsystem, "query_duration_seconds", "Duration of store queries, including scanning their rows.", prometheus.ExponentialBuckets(0.0005, 2, 16), "query")
+	QueryRows     = newCounterVec(dbSubsystem, "query_rows_total", "Number of rows returned or affected by store queries.", "query")
+)
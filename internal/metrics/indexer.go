@@ -0,0 +1,57 @@
+package metrics
+
+const indexerSubsystem = "indexer"
+
+// Ledger ingestion metrics, updated after each ledger is processed
+var (
+	LedgersProcessed     = newCounter(indexerSubsystem, "ledgers_processed_total", "Number of ledgers processed.")
+	LastLedger           = newGauge(indexerSubsystem, "last_ledger", "Sequence of the last ledger processed.")
+	TransactionsRead     = newCounter(indexerSubsystem, "transactions_read_total", "Number of transactions read from processed ledgers.")
+	ContractEventsSeen   = newCounter(indexerSubsystem, "contract_events_seen_total", "Number of contract events emitted by transactions considered for indexing.")
+	GovernorEventsParsed = newCounter(indexerSubsystem, "governor_events_parsed_total", "Number of governor events parsed from contract events.")
+	EventsApplied        = newCounter(indexerSubsystem, "events_applied_total", "Number of governor events applied to the database.")
+	VotesInserted        = newCounter(indexerSubsystem, "votes_inserted_total", "Number of votes inserted.")
+	ProposalsMutated     = newCounter(indexerSubsystem, "proposals_mutated_total", "Number of proposal inserts and updates.")
+	ParseFailures        = newCounter(indexerSubsystem, "parse_failures_total", "Number of governor events that failed to parse.")
+	FailedEvents         = newCounter(indexerSubsystem, "failed_events_total", "Number of governor events recorded as failed events, as they could not be indexed.")
+	UnknownEventTypes    = newCounter(indexerSubsystem, "unknown_event_types_total", "Number of events from tracked contracts with an unknown event type.")
+	ProposalConflicts    = newCounter(indexerSubsystem, "proposal_conflicts_total", "Number of proposal_created events for an existing proposal with different content.")
+	FailedTxEvents       = newCounter(indexerSubsystem, "failed_tx_events_total", "Number of governor events of tracked contracts emitted by failed transactions and recorded for analytics.")
+	ProcessingSeconds    = newCounter(indexerSubsystem, "processing_seconds_total", "Seconds spent processing ledgers, excluding waiting for them to close.")
+)
+
+// Per-contract event metrics, labeled by contract id and updated after each ledger is processed. Only contracts in
+// the contracts registry are labeled, so any contract emitting governor-like events can't add labels.
+var (
+	ContractGovernorEventsSeen   = newCounterVec(indexerSubsystem, "contract_governor_events_seen_total", "Number of events laid out like governor events emitted by a registered contract.", "contract")
+	ContractGovernorEventsParsed = newCounterVec(indexerSubsystem, "contract_governor_events_parsed_total", "Number of governor events of a registered contract that parsed.", "contract")
+	ContractGovernorEventsFailed = newCounterVec(indexerSubsystem, "contract_governor_events_failed_total", "Number of governor events of a registered contract that failed to parse or could not be indexed.", "contract")
+)
+
+// EventsOutOfOrder is updated as governor events are applied
+var EventsOutOfOrder = newCounter(indexerSubsystem, "events_out_of_order_total", "Number of governor events applied that were older than the last event applied for their contract.")
+
+// TipWaitSeconds is updated by the RPC ledger backend while the indexer is caught up to the latest ledger
+var TipWaitSeconds = newCounter(indexerSubsystem, "tip_wait_seconds_total", "Seconds spent waiting at the tip of the network for the next ledger to close.")
+
+// Proposal content metrics, updated by the content fetcher
+var (
+	ContentFetched       = newCounter(indexerSubsystem, "content_fetched_total", "Number of IPFS proposal descriptions fetched.")
+	ContentFetchFailures = newCounter(indexerSubsystem, "content_fetch_failures_total", "Number of failed IPFS proposal description fetch attempts.")
+)
+
+// RPC endpoint metrics, updated when the indexer reads ledgers from more than one RPC server
+var (
+	RPCFailovers = newCounter(indexerSubsystem, "rpc_failovers_total", "Number of times the indexer switched the RPC server it reads ledgers from.")
+	RPCEndpoint  = newGauge(indexerSubsystem, "rpc_endpoint", "Index in RPC_URL of the RPC server the indexer reads ledgers from.")
+)
+
+// Ingestion progress metrics, updated every PROGRESS_LOG_LEDGERS ledgers and at least every 30 seconds. The target
+// ledger, percent complete and ETA are 0 if the indexer doesn't know the latest ledger of the network.
+var (
+	LedgersPerSecond        = newGauge(indexerSubsystem, "ledgers_per_second", "Recent rate of ledgers processed per second.")
+	GovernorEventsPerSecond = newGauge(indexerSubsystem, "governor_events_per_second", "Recent rate of governor events parsed per second.")
+	TargetLedger            = newGauge(indexerSubsystem, "target_ledger", "Latest ledger of the network the indexer is catching up to.")
+	PercentComplete         = newGauge(indexerSubsystem, "percent_complete", "Percentage of the ledgers up to the target ledger processed since the indexer started.")
+	ETASeconds              = newGauge(indexerSubsystem, "eta_seconds", "Estimated seconds until the indexer reaches the target ledger at the recent rate.")
+)
@@ -0,0 +1,93 @@
+// Package metrics defines the Prometheus metrics exported by the indexer and API.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NAMESPACE prefixes the name of every metric
+const NAMESPACE = "governor"
+
+// Registry holds every metric exported by the services
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve exposes the metrics at /metrics on port until ctx is cancelled.
+//
+// Serve returns nil if it stopped because ctx was cancelled.
+func Serve(ctx context.Context, port string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%s", port),
+		Handler:      mux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Metrics server listening", "port", port)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("metrics server forced to shutdown: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
+
+func newCounter(subsystem string, name string, help string) prometheus.Counter {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Namespace: NAMESPACE, Subsystem: subsystem, Name: name, Help: help})
+	Registry.MustRegister(counter)
+	return counter
+}
+
+func newGauge(subsystem string, name string, help string) prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: NAMESPACE, Subsystem: subsystem, Name: name, Help: help})
+	Registry.MustRegister(gauge)
+	return gauge
+}
+
+func newCounterVec(subsystem string, name string, help string, labels ...string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: NAMESPACE, Subsystem: subsystem, Name: name, Help: help}, labels)
+	Registry.MustRegister(counter)
+	return counter
+}
+
+func newHistogramVec(subsystem string, name string, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: NAMESPACE, Subsystem: subsystem, Name: name, Help: help, Buckets: buckets}, labels)
+	Registry.MustRegister(histogram)
+	return histogram
+}